@@ -6,6 +6,9 @@ import (
 	"odomosml/docs"
 	"odomosml/internal/app"
 	"odomosml/pkg/database"
+	"odomosml/pkg/fieldcrypt"
+	"odomosml/pkg/notify"
+	"odomosml/pkg/storage"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -38,14 +41,36 @@ func main() {
 	log.Printf("Starting OdomosML API in %s mode", cfg.Environment)
 	log.Printf("Server will listen on %s", cfg.ServerAddress)
 
+	// Laad de sleutels voor het versleutelen van persoonsgegevens, voordat de database gebruikt wordt
+	keyring, err := fieldcrypt.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	fieldcrypt.SetDefault(keyring)
+	if !keyring.Enabled() {
+		log.Println("Persoonsgegevens worden niet versleuteld: geen ENCRYPTION_KEYS_FILE of ENCRYPTION_KEYS")
+	}
+
 	// Initialiseer database connectie
 	db, err := database.NewPostgresDB(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Initialiseer de bestandsopslag voor bijlagen
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	// Initialiseer het kanaal voor meldingen aan gebruikers
+	notifier, err := notify.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize notifier: %v", err)
+	}
+
 	// Initialiseer en start de applicatie
-	application := app.NewApp(db, cfg)
+	application := app.NewApp(db, store, notifier, cfg)
 
 	// Voeg Swagger route toe
 	docs.SwaggerInfo.BasePath = "/api/v1"
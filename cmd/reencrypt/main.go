@@ -0,0 +1,61 @@
+// Command reencrypt versleutelt alle persoonsgegevens opnieuw met de primaire sleutel en werkt de
+// blind indexes bij. Gebruik het na het toevoegen van een nieuwe sleutel (rotatie), na het wijzigen van
+// ENCRYPTION_INDEX_KEY, of om bestaande onversleutelde gegevens te versleutelen.
+//
+//	go run ./cmd/reencrypt -dry-run
+//	go run ./cmd/reencrypt -batch 1000
+//
+// De configuratie komt uit dezelfde environment variables als de API. Het schema moet al gemigreerd
+// zijn (door de API met deze versie te starten). Na afloop kan een oude sleutel uit de configuratie.
+package main
+
+import (
+	"flag"
+	"log"
+	"odomosml/config"
+	"odomosml/pkg/database"
+	"odomosml/pkg/fieldcrypt"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 500, "aantal rijen per transactie")
+	dryRun := flag.Bool("dry-run", false, "alleen tellen wat er opnieuw versleuteld zou worden")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+
+	keyring, err := fieldcrypt.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	if !keyring.Enabled() {
+		log.Fatal("Geen sleutels geconfigureerd; stel ENCRYPTION_KEYS_FILE of ENCRYPTION_KEYS in")
+	}
+	fieldcrypt.SetDefault(keyring)
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	if *dryRun {
+		log.Printf("Dry run: tellen wat met sleutel '%s' opnieuw versleuteld zou worden", keyring.PrimaryKeyID())
+	} else {
+		log.Printf("Opnieuw versleutelen met sleutel '%s'", keyring.PrimaryKeyID())
+	}
+
+	results, err := database.Reencrypt(db, keyring, *batchSize, *dryRun, func(stats database.ReencryptStats) {
+		log.Printf("%s: %d rijen bekeken, %d bijgewerkt", stats.Table, stats.Rows, stats.Updated)
+	})
+	if err != nil {
+		log.Fatalf("Opnieuw versleutelen mislukt: %v", err)
+	}
+
+	for _, stats := range results {
+		if *dryRun {
+			log.Printf("%s: %d van %d rijen zouden bijgewerkt worden", stats.Table, stats.Updated, stats.Rows)
+		} else {
+			log.Printf("%s: %d van %d rijen bijgewerkt", stats.Table, stats.Updated, stats.Rows)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config bevat alle configuratie-instellingen voor de applicatie
@@ -28,6 +29,68 @@ type Config struct {
 
 	// Logging configuratie
 	LogLevel string // "debug", "info", "warn", "error"
+
+	// Opslag configuratie voor bijlagen
+	StorageDriver    string // "local" of "s3"
+	StorageLocalPath string
+	S3Endpoint       string
+	S3AccessKey      string
+	S3SecretKey      string
+	S3Bucket         string
+	S3Region         string
+	S3UseSSL         bool
+
+	// Bijlagen configuratie
+	AttachmentMaxSizeMB    int
+	AttachmentAllowedTypes []string
+
+	// Import configuratie
+	ImportMaxSizeMB    int
+	ImportSyncRowLimit int // Grotere bestanden worden op de achtergrond verwerkt
+
+	// Optimistic concurrency: verplicht een If-Match header bij het wijzigen of verwijderen van klanten en gebruikers
+	IfMatchRequired bool
+
+	// Bedrijfsgegevens op offertes en facturen
+	CompanyName      string
+	CompanyAddress   string
+	CompanyKvK       string
+	CompanyVATNumber string
+	CompanyIBAN      string
+	CompanyEmail     string
+
+	// Offerte- en factuurconfiguratie
+	InvoiceLogoPath        string // PNG of JPEG; leeg voor geen logo
+	InvoiceTemplatePath    string // JSON met kleur en teksten; leeg voor de standaardteksten
+	InvoicePaymentTermDays int
+	QuoteValidityDays      int
+
+	// Meldingen, zoals herinneringen aan taken
+	NotifierDriver        string // "log" of "webhook"
+	NotifierWebhookURL    string
+	NotifierWebhookSecret string // Ondertekent de body van de webhook met HMAC-SHA256; leeg voor geen handtekening
+
+	// Publiek adres van de API (bijv. https://crm.example.nl), voor links zoals agendafeeds; leeg om het adres uit het verzoek te nemen
+	PublicURL string
+
+	// Achtergrondtaken; met meerdere instanties voert steeds één instantie een job tegelijk uit
+	SchedulerEnabled              bool
+	TaskReminderIntervalSeconds   int
+	InvoiceOverdueIntervalSeconds int // Tijd tussen twee controles op facturen over de vervaldatum
+
+	// Versleuteling van persoonsgegevens; sleutels als <sleutel ID>:<base64 sleutel van 32 bytes>
+	EncryptionKeysFile     string // Bestand met één sleutel per regel
+	EncryptionKeys         string // Komma gescheiden, naast of in plaats van het bestand
+	EncryptionPrimaryKeyID string // Sleutel voor nieuwe waarden; leeg voor de laatst genoemde sleutel
+	EncryptionIndexKey     string // Base64 sleutel van 32 bytes voor blind indexes; niet roteren zonder opnieuw te indexeren
+
+	// Bewaartermijnen in dagen; 0 schakelt het opschonen van dat soort gegevens uit
+	RetentionAuditLogDays        int
+	RetentionLoginEventDays      int
+	RetentionDeletedCustomerDays int  // Verwijderde klanten worden na deze termijn definitief verwijderd
+	RetentionIntervalSeconds     int  // Tijd tussen twee runs van de opschoonjob
+	RetentionBatchSize           int  // Aantal rijen per delete, om lange locks te voorkomen
+	RetentionDryRun              bool // Alleen rapporteren wat opgeschoond zou worden
 }
 
 // LoadConfig laadt configuratie uit environment variables
@@ -41,6 +104,14 @@ func LoadConfig() *Config {
 		log.Println("Stel een sterke JWT_SECRET in via environment variables.")
 	}
 
+	// Waarschuwing voor onversleutelde persoonsgegevens in productie
+	encryptionKeysFile := getEnv("ENCRYPTION_KEYS_FILE", "")
+	encryptionKeys := getEnv("ENCRYPTION_KEYS", "")
+	encryptionIndexKey := getEnv("ENCRYPTION_INDEX_KEY", "")
+	if environment == "production" && encryptionKeysFile == "" && encryptionKeys == "" {
+		log.Println("WAARSCHUWING: Geen ENCRYPTION_KEYS_FILE of ENCRYPTION_KEYS in productie! Persoonsgegevens worden onversleuteld opgeslagen.")
+	}
+
 	// Parse JWT expiration hours
 	jwtExpirationHours, err := strconv.Atoi(getEnv("JWT_EXPIRATION_HOURS", "24"))
 	if err != nil {
@@ -74,9 +145,86 @@ func LoadConfig() *Config {
 
 		// Logging configuratie
 		LogLevel: getEnv("LOG_LEVEL", "info"),
+
+		// Opslag configuratie voor bijlagen
+		StorageDriver:    getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalPath: getEnv("STORAGE_LOCAL_PATH", "./data/bijlagen"),
+		S3Endpoint:       getEnv("S3_ENDPOINT", "localhost:9000"),
+		S3AccessKey:      getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:      getEnv("S3_SECRET_KEY", ""),
+		S3Bucket:         getEnv("S3_BUCKET", "odomosml-bijlagen"),
+		S3Region:         getEnv("S3_REGION", "us-east-1"),
+		S3UseSSL:         getEnvBool("S3_USE_SSL", false),
+
+		// Bijlagen configuratie
+		AttachmentMaxSizeMB:    getEnvInt("ATTACHMENT_MAX_SIZE_MB", 25),
+		AttachmentAllowedTypes: getEnvList("ATTACHMENT_ALLOWED_TYPES", defaultAttachmentTypes),
+
+		// Import configuratie
+		ImportMaxSizeMB:    getEnvInt("IMPORT_MAX_SIZE_MB", 20),
+		ImportSyncRowLimit: getEnvInt("IMPORT_SYNC_ROW_LIMIT", 500),
+
+		// Optimistic concurrency
+		IfMatchRequired: getEnvBool("IF_MATCH_REQUIRED", true),
+
+		// Bedrijfsgegevens
+		CompanyName:      getEnv("COMPANY_NAME", "OdomosML"),
+		CompanyAddress:   getEnv("COMPANY_ADDRESS", ""),
+		CompanyKvK:       getEnv("COMPANY_KVK", ""),
+		CompanyVATNumber: getEnv("COMPANY_VAT_NUMBER", ""),
+		CompanyIBAN:      getEnv("COMPANY_IBAN", ""),
+		CompanyEmail:     getEnv("COMPANY_EMAIL", ""),
+
+		// Offertes en facturen
+		InvoiceLogoPath:        getEnv("INVOICE_LOGO_PATH", ""),
+		InvoiceTemplatePath:    getEnv("INVOICE_TEMPLATE_PATH", ""),
+		InvoicePaymentTermDays: getEnvInt("INVOICE_PAYMENT_TERM_DAYS", 30),
+		QuoteValidityDays:      getEnvInt("QUOTE_VALIDITY_DAYS", 30),
+
+		// Meldingen
+		NotifierDriver:        getEnv("NOTIFIER_DRIVER", "log"),
+		NotifierWebhookURL:    getEnv("NOTIFIER_WEBHOOK_URL", ""),
+		NotifierWebhookSecret: getEnv("NOTIFIER_WEBHOOK_SECRET", ""),
+
+		// Publiek adres
+		PublicURL: getEnv("PUBLIC_URL", ""),
+
+		// Achtergrondtaken
+		SchedulerEnabled:              getEnvBool("SCHEDULER_ENABLED", true),
+		TaskReminderIntervalSeconds:   getEnvInt("TASK_REMINDER_INTERVAL_SECONDS", 60),
+		InvoiceOverdueIntervalSeconds: getEnvInt("INVOICE_OVERDUE_INTERVAL_SECONDS", 3600),
+
+		// Versleuteling
+		EncryptionKeysFile:     encryptionKeysFile,
+		EncryptionKeys:         encryptionKeys,
+		EncryptionPrimaryKeyID: getEnv("ENCRYPTION_PRIMARY_KEY_ID", ""),
+		EncryptionIndexKey:     encryptionIndexKey,
+
+		// Bewaartermijnen
+		RetentionAuditLogDays:        getEnvInt("RETENTION_AUDIT_LOG_DAYS", 2557),
+		RetentionLoginEventDays:      getEnvInt("RETENTION_LOGIN_EVENT_DAYS", 90),
+		RetentionDeletedCustomerDays: getEnvInt("RETENTION_DELETED_CUSTOMER_DAYS", 30),
+		RetentionIntervalSeconds:     getEnvInt("RETENTION_INTERVAL_SECONDS", 3600),
+		RetentionBatchSize:           getEnvInt("RETENTION_BATCH_SIZE", 1000),
+		RetentionDryRun:              getEnvBool("RETENTION_DRY_RUN", false),
 	}
 }
 
+// defaultAttachmentTypes zijn de standaard toegestane MIME types voor bijlagen
+var defaultAttachmentTypes = []string{
+	"application/pdf",
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"text/plain",
+	"text/csv",
+	"application/msword",
+	"application/vnd.ms-excel",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/zip",
+}
+
 // GetDSN geeft de database connection string terug
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
@@ -110,3 +258,28 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		intValue, err := strconv.Atoi(value)
+		if err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists || strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
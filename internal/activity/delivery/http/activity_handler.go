@@ -0,0 +1,262 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"odomosml/internal/activity/model"
+	"odomosml/internal/activity/service"
+	auditModel "odomosml/internal/audit/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityHandler handles HTTP requests for customer activities
+type ActivityHandler struct {
+	service service.ActivityService
+}
+
+// NewActivityHandler maakt een nieuwe ActivityHandler instantie
+func NewActivityHandler(service service.ActivityService) *ActivityHandler {
+	return &ActivityHandler{
+		service: service,
+	}
+}
+
+// Helper functie om integer parameters te parsen
+func parseIntParam(c *gin.Context, param string, defaultValue int) int {
+	valueStr := c.Query(param)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+// @Summary      Activiteiten van een klant ophalen
+// @Description  Haalt de gesprekken, afspraken en notities van een klant op, nieuwste eerst
+// @Tags         activities
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        type query string false "Filter op type (call/meeting/email/note)"
+// @Param        page query int false "Paginanummer (default: 1)"
+// @Param        page_size query int false "Aantal items per pagina (default: 10, max: 100)"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/{id}/activiteiten [get]
+func (h *ActivityHandler) GetAll(c *gin.Context) {
+	filter := model.ActivityFilter{
+		Type:     model.ActivityType(c.Query("type")),
+		Page:     parseIntParam(c, "page", 1),
+		PageSize: parseIntParam(c, "page_size", 10),
+	}
+
+	activities, total, err := h.service.GetActivities(c.Param("id"), filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Bereken paginering (de service past ongeldige waarden aan naar de defaults)
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 10
+	}
+	totalPages := (int(total) + filter.PageSize - 1) / filter.PageSize
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    activities,
+		"pagination": gin.H{
+			"current_page": filter.Page,
+			"page_size":    filter.PageSize,
+			"total_items":  total,
+			"total_pages":  totalPages,
+		},
+	})
+}
+
+// @Summary      Activiteit vastleggen
+// @Description  Legt een gesprek, afspraak, e-mail of notitie vast bij een klant
+// @Tags         activities
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        activity body model.Activity true "Activiteit gegevens"
+// @Success      201  {object}  model.Activity "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/{id}/activiteiten [post]
+func (h *ActivityHandler) Create(c *gin.Context) {
+	var activity model.Activity
+	if err := c.ShouldBindJSON(&activity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	// De auteur is altijd de ingelogde gebruiker
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+	activity.AuthorID, _ = userID.(uint)
+	activity.AuthorName, _ = username.(string)
+
+	created, err := h.service.CreateActivity(c.Param("id"), &activity)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		EntityType:  auditModel.EntityActivity,
+		EntityID:    strconv.FormatUint(uint64(created.ID), 10),
+		Description: fmt.Sprintf("Activiteit (%s) vastgelegd bij klant %d", created.Type, created.CustomerID),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Activiteit bijwerken
+// @Description  Werkt een activiteit van een klant bij
+// @Tags         activities
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        activityId path string true "Activiteit ID"
+// @Param        activity body model.Activity true "Activiteit gegevens"
+// @Success      200  {object}  model.Activity "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/{id}/activiteiten/{activityId} [put]
+func (h *ActivityHandler) Update(c *gin.Context) {
+	activityID, err := strconv.Atoi(c.Param("activityId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldig ID",
+		})
+		return
+	}
+
+	var activity model.Activity
+	if err := c.ShouldBindJSON(&activity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	activity.ID = uint(activityID)
+
+	updated, err := h.service.UpdateActivity(c.Param("id"), &activity)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		EntityType:  auditModel.EntityActivity,
+		EntityID:    strconv.FormatUint(uint64(updated.ID), 10),
+		Description: fmt.Sprintf("Activiteit (ID: %d) bijgewerkt bij klant %d", updated.ID, updated.CustomerID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Activiteit verwijderen
+// @Description  Verwijdert een activiteit van een klant
+// @Tags         activities
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        activityId path string true "Activiteit ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      400  {object}  map[string]string "Ongeldig ID"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/{id}/activiteiten/{activityId} [delete]
+func (h *ActivityHandler) Delete(c *gin.Context) {
+	activityData, err := h.service.DeleteActivity(c.Param("id"), c.Param("activityId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	oldData, _ := json.Marshal(activityData)
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		EntityType:  auditModel.EntityActivity,
+		EntityID:    c.Param("activityId"),
+		Description: fmt.Sprintf("Activiteit (ID: %s) verwijderd bij klant %s", c.Param("activityId"), c.Param("id")),
+		OldData:     string(oldData),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Activiteit succesvol verwijderd",
+	})
+}
+
+// @Summary      Tijdlijn van een klant ophalen
+// @Description  Combineert activiteiten en audit log entries van een klant in chronologische volgorde (nieuwste eerst)
+// @Tags         activities
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        cursor query string false "Cursor van de vorige pagina (next_cursor)"
+// @Param        limit query int false "Aantal items (default: 20, max: 100)"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/{id}/tijdlijn [get]
+func (h *ActivityHandler) Timeline(c *gin.Context) {
+	items, nextCursor, err := h.service.GetTimeline(c.Param("id"), c.Query("cursor"), parseIntParam(c, "limit", 20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"data":        items,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	auditModel "odomosml/internal/audit/model"
+	"time"
+)
+
+// ActivityType definieert het soort activiteit
+type ActivityType string
+
+// Beschikbare activiteit types
+const (
+	ActivityCall    ActivityType = "call"
+	ActivityMeeting ActivityType = "meeting"
+	ActivityEmail   ActivityType = "email"
+	ActivityNote    ActivityType = "note"
+)
+
+// IsValid controleert of het activiteit type bekend is
+func (t ActivityType) IsValid() bool {
+	switch t {
+	case ActivityCall, ActivityMeeting, ActivityEmail, ActivityNote:
+		return true
+	}
+	return false
+}
+
+// Activity representeert een gesprek, afspraak of opmerking bij een klant
+// @Description Een activiteit (gesprek, afspraak, e-mail of notitie) bij een klant
+type Activity struct {
+	ID         uint         `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	CustomerID uint         `json:"customer_id" gorm:"not null;index" example:"1" swaggertype:"integer"`
+	Type       ActivityType `json:"type" gorm:"size:20;not null" binding:"required" example:"call" swaggertype:"string"`
+	Body       string       `json:"body" gorm:"type:text;not null" binding:"required" example:"Offerte besproken" swaggertype:"string"`
+	AuthorID   uint         `json:"author_id" gorm:"not null" example:"1" swaggertype:"integer"`
+	AuthorName string       `json:"author_name" gorm:"size:100" example:"johndoe" swaggertype:"string"`
+	OccurredAt time.Time    `json:"occurred_at" gorm:"not null;index" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	FollowUpAt *time.Time   `json:"follow_up_at" gorm:"index" example:"2024-03-01T09:00:00Z" swaggertype:"string" format:"date-time"`
+	CreatedAt  time.Time    `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt  time.Time    `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Activity) TableName() string {
+	return "activities"
+}
+
+// ActivityFilter definieert filters voor het ophalen van activiteiten
+type ActivityFilter struct {
+	CustomerID uint
+	Type       ActivityType
+	Page       int
+	PageSize   int
+}
+
+// Soorten items in de tijdlijn
+const (
+	TimelineKindActivity = "activity"
+	TimelineKindAudit    = "audit"
+)
+
+// TimelineItem is een activiteit of audit log entry in de tijdlijn van een klant
+// @Description Item in de tijdlijn van een klant
+type TimelineItem struct {
+	Kind       string               `json:"kind" example:"activity" swaggertype:"string"`
+	OccurredAt time.Time            `json:"occurred_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	Activity   *Activity            `json:"activity,omitempty"`
+	AuditLog   *auditModel.AuditLog `json:"audit_log,omitempty"`
+}
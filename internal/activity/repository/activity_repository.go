@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/activity/model"
+	"odomosml/pkg/timeline"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// ActivityRepository definieert de interface voor activity repository
+type ActivityRepository interface {
+	FindAll(filter model.ActivityFilter) ([]model.Activity, int64, error)
+	FindByID(id string) (*model.Activity, error)
+	FindBefore(customerID uint, boundary *timeline.Boundary, limit int) ([]model.Activity, error)
+	Create(activity *model.Activity) (*model.Activity, error)
+	Update(activity *model.Activity) (*model.Activity, error)
+	Delete(id uint) error
+}
+
+// activityRepository implementeert de ActivityRepository interface
+type activityRepository struct {
+	db *gorm.DB
+}
+
+// NewActivityRepository maakt een nieuwe ActivityRepository instantie
+func NewActivityRepository(db *gorm.DB) ActivityRepository {
+	return &activityRepository{
+		db: db,
+	}
+}
+
+// FindAll haalt activiteiten van een klant op, nieuwste eerst
+func (r *activityRepository) FindAll(filter model.ActivityFilter) ([]model.Activity, int64, error) {
+	var activities []model.Activity
+	var total int64
+
+	query := r.db.Model(&model.Activity{}).Where("customer_id = ?", filter.CustomerID)
+
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	// Tel totaal aantal records (voor paginering)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Paginering toepassen
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query = query.Offset(offset).Limit(filter.PageSize)
+	}
+
+	if err := query.Order("occurred_at DESC, id DESC").Find(&activities).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return activities, total, nil
+}
+
+// FindByID haalt een activiteit op op basis van ID
+func (r *activityRepository) FindByID(id string) (*model.Activity, error) {
+	var activity model.Activity
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&activity, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("activiteit niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &activity, nil
+}
+
+// FindBefore haalt maximaal limit activiteiten van een klant op die voor de grens vallen, nieuwste eerst
+func (r *activityRepository) FindBefore(customerID uint, boundary *timeline.Boundary, limit int) ([]model.Activity, error) {
+	var activities []model.Activity
+
+	query := r.db.Where("customer_id = ?", customerID)
+	query = boundary.Apply(query, "occurred_at", "id")
+
+	if err := query.Order("occurred_at DESC, id DESC").Limit(limit).Find(&activities).Error; err != nil {
+		return nil, err
+	}
+
+	return activities, nil
+}
+
+// Create maakt een nieuwe activiteit aan
+func (r *activityRepository) Create(activity *model.Activity) (*model.Activity, error) {
+	if err := r.db.Create(activity).Error; err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// Update werkt type, inhoud en datums van een activiteit bij
+func (r *activityRepository) Update(activity *model.Activity) (*model.Activity, error) {
+	err := r.db.Model(activity).
+		Select("type", "body", "occurred_at", "follow_up_at").
+		Updates(activity).Error
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// Delete verwijdert een activiteit
+func (r *activityRepository) Delete(id uint) error {
+	return r.db.Delete(&model.Activity{}, id).Error
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"errors"
+	"odomosml/internal/activity/model"
+	"odomosml/internal/activity/repository"
+	auditModel "odomosml/internal/audit/model"
+	auditService "odomosml/internal/audit/service"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/pkg/timeline"
+	"strconv"
+	"time"
+)
+
+// Grenzen voor de tijdlijn paginering
+const (
+	defaultTimelineLimit = 20
+	maxTimelineLimit     = 100
+)
+
+// ActivityService definieert de interface voor activity service
+type ActivityService interface {
+	GetActivities(customerID string, filter model.ActivityFilter) ([]model.Activity, int64, error)
+	CreateActivity(customerID string, activity *model.Activity) (*model.Activity, error)
+	UpdateActivity(customerID string, activity *model.Activity) (*model.Activity, error)
+	DeleteActivity(customerID, activityID string) (map[string]interface{}, error)
+	GetTimeline(customerID, cursor string, limit int) ([]model.TimelineItem, string, error)
+}
+
+// activityService implementeert de ActivityService interface
+type activityService struct {
+	repo         repository.ActivityRepository
+	customerRepo customerRepo.CustomerRepository
+	auditService auditService.AuditService
+}
+
+// NewActivityService maakt een nieuwe ActivityService instantie
+func NewActivityService(repo repository.ActivityRepository, customerRepo customerRepo.CustomerRepository, auditService auditService.AuditService) ActivityService {
+	return &activityService{
+		repo:         repo,
+		customerRepo: customerRepo,
+		auditService: auditService,
+	}
+}
+
+// GetActivities haalt de activiteiten van een klant op
+func (s *activityService) GetActivities(customerID string, filter model.ActivityFilter) ([]model.Activity, int64, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Type != "" && !filter.Type.IsValid() {
+		return nil, 0, errors.New("ongeldig activiteit type")
+	}
+
+	// Valideer paginering
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 10 // Default page size
+	}
+
+	filter.CustomerID = customer.ID
+	return s.repo.FindAll(filter)
+}
+
+// CreateActivity legt een nieuwe activiteit vast bij een klant
+func (s *activityService) CreateActivity(customerID string, activity *model.Activity) (*model.Activity, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(activity); err != nil {
+		return nil, err
+	}
+
+	if activity.AuthorID == 0 {
+		return nil, errors.New("auteur is verplicht")
+	}
+
+	activity.ID = 0
+	activity.CustomerID = customer.ID
+	return s.repo.Create(activity)
+}
+
+// UpdateActivity werkt een activiteit van een klant bij
+func (s *activityService) UpdateActivity(customerID string, activity *model.Activity) (*model.Activity, error) {
+	existing, err := s.findForCustomer(customerID, strconv.FormatUint(uint64(activity.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(activity); err != nil {
+		return nil, err
+	}
+
+	// Klant, auteur en aanmaakdatum liggen vast
+	activity.CustomerID = existing.CustomerID
+	activity.AuthorID = existing.AuthorID
+	activity.AuthorName = existing.AuthorName
+	activity.CreatedAt = existing.CreatedAt
+
+	return s.repo.Update(activity)
+}
+
+// DeleteActivity verwijdert een activiteit en retourneert de data voor audit logging
+func (s *activityService) DeleteActivity(customerID, activityID string) (map[string]interface{}, error) {
+	activity, err := s.findForCustomer(customerID, activityID)
+	if err != nil {
+		return nil, err
+	}
+
+	activityData := map[string]interface{}{
+		"id":          activity.ID,
+		"customer_id": activity.CustomerID,
+		"type":        activity.Type,
+		"occurred_at": activity.OccurredAt,
+	}
+
+	if err := s.repo.Delete(activity.ID); err != nil {
+		return nil, err
+	}
+
+	return activityData, nil
+}
+
+// GetTimeline combineert activiteiten en audit logs van een klant in aflopende chronologische volgorde.
+// Retourneert de items en een cursor voor de volgende pagina (leeg als er niets meer is).
+func (s *activityService) GetTimeline(customerID, cursor string, limit int) ([]model.TimelineItem, string, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if limit < 1 || limit > maxTimelineLimit {
+		limit = defaultTimelineLimit
+	}
+
+	var position *timeline.Cursor
+	if cursor != "" {
+		if position, err = timeline.Decode(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Haal uit beide bronnen één item extra op om te bepalen of er een volgende pagina is
+	activities, err := s.repo.FindBefore(customer.ID, position.BoundaryFor(model.TimelineKindActivity), limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	logs, err := s.auditService.GetEntityHistory(auditModel.EntityCustomer, strconv.FormatUint(uint64(customer.ID), 10),
+		position.BoundaryFor(model.TimelineKindAudit), limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Merge de twee aflopend gesorteerde lijsten
+	items := make([]model.TimelineItem, 0, limit+1)
+	i, j := 0, 0
+	for len(items) <= limit && (i < len(activities) || j < len(logs)) {
+		takeActivity := j >= len(logs)
+		if i < len(activities) && j < len(logs) {
+			takeActivity = before(
+				timeline.Cursor{Time: activities[i].OccurredAt, Kind: model.TimelineKindActivity, ID: activities[i].ID},
+				timeline.Cursor{Time: logs[j].CreatedAt, Kind: model.TimelineKindAudit, ID: logs[j].ID},
+			)
+		}
+
+		if takeActivity {
+			items = append(items, model.TimelineItem{
+				Kind:       model.TimelineKindActivity,
+				OccurredAt: activities[i].OccurredAt,
+				Activity:   &activities[i],
+			})
+			i++
+		} else {
+			items = append(items, model.TimelineItem{
+				Kind:       model.TimelineKindAudit,
+				OccurredAt: logs[j].CreatedAt,
+				AuditLog:   &logs[j],
+			})
+			j++
+		}
+	}
+
+	if len(items) <= limit {
+		return items, "", nil
+	}
+
+	items = items[:limit]
+	last := items[limit-1]
+	next := timeline.Cursor{Time: last.OccurredAt, Kind: last.Kind}
+	if last.Activity != nil {
+		next.ID = last.Activity.ID
+	} else {
+		next.ID = last.AuditLog.ID
+	}
+
+	return items, next.Encode(), nil
+}
+
+// findForCustomer haalt een activiteit op en controleert of deze bij de klant hoort
+func (s *activityService) findForCustomer(customerID, activityID string) (*model.Activity, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	activity, err := s.repo.FindByID(activityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if activity.CustomerID != customer.ID {
+		return nil, errors.New("activiteit niet gevonden")
+	}
+
+	return activity, nil
+}
+
+// before bepaalt of a in de aflopende tijdlijn vóór b komt
+func before(a, b timeline.Cursor) bool {
+	if !a.Time.Equal(b.Time) {
+		return a.Time.After(b.Time)
+	}
+	if a.Kind != b.Kind {
+		return a.Kind > b.Kind
+	}
+	return a.ID > b.ID
+}
+
+// validate controleert en normaliseert de velden van een activiteit
+func validate(activity *model.Activity) error {
+	if !activity.Type.IsValid() {
+		return errors.New("type moet call, meeting, email of note zijn")
+	}
+
+	if activity.Body == "" {
+		return errors.New("omschrijving is verplicht")
+	}
+
+	if activity.OccurredAt.IsZero() {
+		activity.OccurredAt = time.Now()
+	}
+
+	if activity.FollowUpAt != nil && activity.FollowUpAt.Before(activity.OccurredAt) {
+		return errors.New("opvolgdatum mag niet voor de datum van de activiteit liggen")
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"odomosml/internal/activity/model"
+	"odomosml/internal/activity/repository"
+	auditModel "odomosml/internal/audit/model"
+	auditService "odomosml/internal/audit/service"
+	customerModel "odomosml/internal/customer/model"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/pkg/timeline"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// afterBoundary doet in het geheugen wat Boundary.Apply in SQL doet
+func afterBoundary(boundary *timeline.Boundary, at time.Time, id uint) bool {
+	switch {
+	case boundary == nil:
+		return true
+	case boundary.ID > 0:
+		return at.Before(boundary.Time) || (at.Equal(boundary.Time) && id < boundary.ID)
+	case boundary.Inclusive:
+		return !at.After(boundary.Time)
+	default:
+		return at.Before(boundary.Time)
+	}
+}
+
+type fakeActivityRepository struct {
+	repository.ActivityRepository
+	activities []model.Activity
+}
+
+func (r *fakeActivityRepository) FindBefore(customerID uint, boundary *timeline.Boundary, limit int) ([]model.Activity, error) {
+	var result []model.Activity
+	for _, activity := range r.activities {
+		if activity.CustomerID == customerID && afterBoundary(boundary, activity.OccurredAt, activity.ID) {
+			result = append(result, activity)
+		}
+	}
+	sort.Slice(result, func(a, b int) bool {
+		if !result[a].OccurredAt.Equal(result[b].OccurredAt) {
+			return result[a].OccurredAt.After(result[b].OccurredAt)
+		}
+		return result[a].ID > result[b].ID
+	})
+	return result[:min(limit, len(result))], nil
+}
+
+type fakeAuditService struct {
+	auditService.AuditService
+	logs []auditModel.AuditLog
+}
+
+func (s *fakeAuditService) GetEntityHistory(entityType auditModel.EntityType, entityID string, boundary *timeline.Boundary, limit int) ([]auditModel.AuditLog, error) {
+	var result []auditModel.AuditLog
+	for _, log := range s.logs {
+		if log.EntityType == entityType && log.EntityID == entityID && afterBoundary(boundary, log.CreatedAt, log.ID) {
+			result = append(result, log)
+		}
+	}
+	sort.Slice(result, func(a, b int) bool {
+		if !result[a].CreatedAt.Equal(result[b].CreatedAt) {
+			return result[a].CreatedAt.After(result[b].CreatedAt)
+		}
+		return result[a].ID > result[b].ID
+	})
+	return result[:min(limit, len(result))], nil
+}
+
+type fakeCustomerRepository struct {
+	customerRepo.CustomerRepository
+}
+
+func (fakeCustomerRepository) FindByID(id string) (*customerModel.Customer, error) {
+	if id != "1" {
+		return nil, errors.New("klant niet gevonden")
+	}
+	return &customerModel.Customer{ID: 1}, nil
+}
+
+// timelineKey geeft een item als "activity:3" of "audit:5" voor het vergelijken van tijdlijnen
+func timelineKey(item model.TimelineItem) string {
+	if item.Activity != nil {
+		return fmt.Sprintf("%s:%d", item.Kind, item.Activity.ID)
+	}
+	return fmt.Sprintf("%s:%d", item.Kind, item.AuditLog.ID)
+}
+
+func TestGetTimeline(t *testing.T) {
+	base := time.Date(2024, 2, 25, 12, 0, 0, 0, time.UTC)
+	minute := func(n int) time.Time { return base.Add(time.Duration(n) * time.Minute) }
+
+	// Meerdere items op hetzelfde moment, uit beide bronnen, en items van een andere klant
+	activities := &fakeActivityRepository{activities: []model.Activity{
+		{ID: 1, CustomerID: 1, OccurredAt: minute(0)},
+		{ID: 2, CustomerID: 1, OccurredAt: minute(5)},
+		{ID: 3, CustomerID: 1, OccurredAt: minute(5)},
+		{ID: 4, CustomerID: 1, OccurredAt: minute(10)},
+		{ID: 5, CustomerID: 2, OccurredAt: minute(7)},
+		{ID: 6, CustomerID: 1, OccurredAt: minute(-30)},
+	}}
+	audit := &fakeAuditService{logs: []auditModel.AuditLog{
+		{ID: 10, EntityType: auditModel.EntityCustomer, EntityID: "1", CreatedAt: minute(5)},
+		{ID: 11, EntityType: auditModel.EntityCustomer, EntityID: "1", CreatedAt: minute(5)},
+		{ID: 12, EntityType: auditModel.EntityCustomer, EntityID: "1", CreatedAt: minute(10)},
+		{ID: 13, EntityType: auditModel.EntityCustomer, EntityID: "1", CreatedAt: minute(1)},
+		{ID: 14, EntityType: auditModel.EntityCustomer, EntityID: "2", CreatedAt: minute(3)},
+		{ID: 15, EntityType: "user", EntityID: "1", CreatedAt: minute(4)},
+	}}
+	service := NewActivityService(activities, fakeCustomerRepository{}, audit)
+
+	// Nieuwste eerst; bij gelijke tijd eerst audit (hoogste soort), dan het hoogste ID
+	want := []string{"audit:12", "activity:4", "audit:11", "audit:10", "activity:3", "activity:2", "audit:13", "activity:1", "activity:6"}
+
+	for _, limit := range []int{1, 2, 3, 4, len(want), 100} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			var got []string
+			cursor := ""
+			for pages := 0; pages <= len(want); pages++ {
+				items, next, err := service.GetTimeline("1", cursor, limit)
+				if err != nil {
+					t.Fatalf("GetTimeline gaf fout: %v", err)
+				}
+				if len(items) > limit {
+					t.Fatalf("%d items, verwacht hoogstens %d", len(items), limit)
+				}
+				for _, item := range items {
+					got = append(got, timelineKey(item))
+				}
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("tijdlijn = %v, verwacht %v", got, want)
+			}
+		})
+	}
+
+	if _, _, err := service.GetTimeline("1", "geen-cursor", 10); err == nil {
+		t.Error("ongeldige cursor gaf geen fout")
+	}
+	if _, _, err := service.GetTimeline("9", "", 10); err == nil {
+		t.Error("onbekende klant gaf geen fout")
+	}
+}
+
+func TestValidateActivity(t *testing.T) {
+	occurred := time.Date(2024, 2, 25, 12, 0, 0, 0, time.UTC)
+	earlier := occurred.Add(-time.Hour)
+	later := occurred.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		activity model.Activity
+		wantErr  string
+	}{
+		{"geldig", model.Activity{Type: model.ActivityCall, Body: "Offerte besproken", OccurredAt: occurred, FollowUpAt: &later}, ""},
+		{"zonder tijdstip", model.Activity{Type: model.ActivityNote, Body: "Notitie"}, ""},
+		{"onbekend type", model.Activity{Type: "fax", Body: "Offerte"}, "type moet call, meeting, email of note zijn"},
+		{"zonder omschrijving", model.Activity{Type: model.ActivityEmail}, "omschrijving is verplicht"},
+		{"opvolging in het verleden", model.Activity{Type: model.ActivityMeeting, Body: "Bezoek", OccurredAt: occurred, FollowUpAt: &earlier}, "opvolgdatum mag niet voor de datum van de activiteit liggen"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activity := tt.activity
+			err := validate(&activity)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate gaf fout: %v", err)
+				}
+				if activity.OccurredAt.IsZero() {
+					t.Error("tijdstip niet ingevuld")
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("validate gaf %v, verwacht %q", err, tt.wantErr)
+			}
+		})
+	}
+}
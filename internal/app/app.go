@@ -1,21 +1,73 @@
 package app
 
 import (
+	"context"
 	"log"
 	"odomosml/config"
+	activityHandler "odomosml/internal/activity/delivery/http"
+	activityRepo "odomosml/internal/activity/repository"
+	activityService "odomosml/internal/activity/service"
+	appointmentHandler "odomosml/internal/appointment/delivery/http"
+	appointmentRepo "odomosml/internal/appointment/repository"
+	appointmentService "odomosml/internal/appointment/service"
+	attachmentHandler "odomosml/internal/attachment/delivery/http"
+	attachmentRepo "odomosml/internal/attachment/repository"
+	attachmentService "odomosml/internal/attachment/service"
 	auditHandler "odomosml/internal/audit/delivery/http"
 	auditRepo "odomosml/internal/audit/repository"
 	auditService "odomosml/internal/audit/service"
 	authHandler "odomosml/internal/auth/delivery/http"
+	authRepo "odomosml/internal/auth/repository"
 	authService "odomosml/internal/auth/service"
 	customerHandler "odomosml/internal/customer/delivery/http"
 	customerRepo "odomosml/internal/customer/repository"
 	customerService "odomosml/internal/customer/service"
+	importHandler "odomosml/internal/customerimport/delivery/http"
+	importRepo "odomosml/internal/customerimport/repository"
+	importService "odomosml/internal/customerimport/service"
+	customerStatusHandler "odomosml/internal/customerstatus/delivery/http"
+	customerStatusRepo "odomosml/internal/customerstatus/repository"
+	customerStatusService "odomosml/internal/customerstatus/service"
+	customFieldHandler "odomosml/internal/customfield/delivery/http"
+	customFieldRepo "odomosml/internal/customfield/repository"
+	customFieldService "odomosml/internal/customfield/service"
+	dealHandler "odomosml/internal/deal/delivery/http"
+	dealRepo "odomosml/internal/deal/repository"
+	dealService "odomosml/internal/deal/service"
+	invoiceHandler "odomosml/internal/invoice/delivery/http"
+	invoiceModel "odomosml/internal/invoice/model"
+	invoiceRepo "odomosml/internal/invoice/repository"
+	invoiceService "odomosml/internal/invoice/service"
 	"odomosml/internal/middleware"
+	privacyHandler "odomosml/internal/privacy/delivery/http"
+	privacyRepo "odomosml/internal/privacy/repository"
+	privacyService "odomosml/internal/privacy/service"
+	productHandler "odomosml/internal/product/delivery/http"
+	productRepo "odomosml/internal/product/repository"
+	productService "odomosml/internal/product/service"
+	retentionHandler "odomosml/internal/retention/delivery/http"
+	retentionRepo "odomosml/internal/retention/repository"
+	retentionService "odomosml/internal/retention/service"
+	savedViewHandler "odomosml/internal/savedview/delivery/http"
+	savedViewRepo "odomosml/internal/savedview/repository"
+	savedViewService "odomosml/internal/savedview/service"
+	searchHandler "odomosml/internal/search/delivery/http"
+	searchRepo "odomosml/internal/search/repository"
+	searchService "odomosml/internal/search/service"
+	tagHandler "odomosml/internal/tag/delivery/http"
+	tagRepo "odomosml/internal/tag/repository"
+	tagService "odomosml/internal/tag/service"
+	taskHandler "odomosml/internal/task/delivery/http"
+	taskRepo "odomosml/internal/task/repository"
+	taskService "odomosml/internal/task/service"
 	userHandler "odomosml/internal/user/delivery/http"
 	userModel "odomosml/internal/user/model"
 	userRepo "odomosml/internal/user/repository"
 	userService "odomosml/internal/user/service"
+	"odomosml/pkg/notify"
+	"odomosml/pkg/scheduler"
+	"odomosml/pkg/storage"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -23,9 +75,12 @@ import (
 
 // App struct bevat de applicatie configuratie
 type App struct {
-	router *gin.Engine
-	db     *gorm.DB
-	config *config.Config
+	router    *gin.Engine
+	db        *gorm.DB
+	storage   storage.Storage
+	notifier  notify.Notifier
+	scheduler *scheduler.Scheduler
+	config    *config.Config
 }
 
 // GetRouter retourneert de gin router instance
@@ -34,7 +89,7 @@ func (a *App) GetRouter() *gin.Engine {
 }
 
 // NewApp maakt een nieuwe applicatie instantie
-func NewApp(db *gorm.DB, cfg *config.Config) *App {
+func NewApp(db *gorm.DB, store storage.Storage, notifier notify.Notifier, cfg *config.Config) *App {
 	// Stel Gin mode in op basis van environment
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
@@ -44,9 +99,18 @@ func NewApp(db *gorm.DB, cfg *config.Config) *App {
 
 	// Maak een nieuwe app instantie
 	app := &App{
-		router: router,
-		db:     db,
-		config: cfg,
+		router:   router,
+		db:       db,
+		storage:  store,
+		notifier: notifier,
+		config:   cfg,
+	}
+
+	// De scheduler neemt zijn advisory locks op de verbindingen van de database pool
+	if sqlDB, err := db.DB(); err != nil {
+		log.Printf("Waarschuwing: Kon scheduler niet initialiseren: %v", err)
+	} else {
+		app.scheduler = scheduler.New(sqlDB)
 	}
 
 	// Initialiseer routes
@@ -61,22 +125,104 @@ func (a *App) setupRoutes() {
 	userRepository := userRepo.NewUserRepository(a.db)
 	customerRepository := customerRepo.NewCustomerRepository(a.db)
 	auditRepository := auditRepo.NewAuditRepository(a.db)
+	tagRepository := tagRepo.NewTagRepository(a.db)
+	customFieldRepository := customFieldRepo.NewCustomFieldRepository(a.db)
+	activityRepository := activityRepo.NewActivityRepository(a.db)
+	attachmentRepository := attachmentRepo.NewAttachmentRepository(a.db)
+	importRepository := importRepo.NewImportJobRepository(a.db)
+	savedViewRepository := savedViewRepo.NewSavedViewRepository(a.db)
+	searchRepository := searchRepo.NewSearchRepository(a.db)
+	customerStatusRepository := customerStatusRepo.NewCustomerStatusRepository(a.db)
+	dealRepository := dealRepo.NewDealRepository(a.db)
+	invoiceRepository := invoiceRepo.NewInvoiceRepository(a.db)
+	productRepository := productRepo.NewProductRepository(a.db)
+	taskRepository := taskRepo.NewTaskRepository(a.db)
+	appointmentRepository := appointmentRepo.NewAppointmentRepository(a.db)
+	privacyRepository := privacyRepo.NewPrivacyRepository(a.db)
+	loginEventRepository := authRepo.NewLoginEventRepository(a.db)
+	retentionRepository := retentionRepo.NewRetentionRepository(a.db)
 
 	// Initialiseer services
 	userSvc := userService.NewUserService(userRepository)
-	customerSvc := customerService.NewCustomerService(customerRepository)
+	customFieldSvc := customFieldService.NewCustomFieldService(customFieldRepository)
+	customerStatusSvc := customerStatusService.NewCustomerStatusService(customerStatusRepository)
+	customerSvc := customerService.NewCustomerService(customerRepository, customFieldSvc, customerStatusSvc)
 	auditSvc := auditService.NewAuditService(auditRepository)
-	authSvc := authService.NewAuthService(userRepository, a.config)
+	tagSvc := tagService.NewTagService(tagRepository)
+	activitySvc := activityService.NewActivityService(activityRepository, customerRepository, auditSvc)
+	attachmentSvc := attachmentService.NewAttachmentService(attachmentRepository, customerRepository, a.storage,
+		a.config.AttachmentMaxSizeMB, a.config.AttachmentAllowedTypes)
+	importSvc := importService.NewImportService(importRepository, customerSvc, customFieldSvc, a.config.ImportSyncRowLimit)
+	authSvc := authService.NewAuthService(userRepository, loginEventRepository, a.config)
+	savedViewSvc := savedViewService.NewSavedViewService(savedViewRepository, userRepository, customerSvc)
+	searchSvc := searchService.NewSearchService(searchRepository)
+	dealSvc := dealService.NewDealService(dealRepository, customerRepository, userRepository)
+	productSvc := productService.NewProductService(productRepository, customerRepository)
+	pdfRenderer := invoiceService.NewPDFRenderer(invoiceService.Company{
+		Name:      a.config.CompanyName,
+		Address:   a.config.CompanyAddress,
+		KvK:       a.config.CompanyKvK,
+		VATNumber: a.config.CompanyVATNumber,
+		IBAN:      a.config.CompanyIBAN,
+		Email:     a.config.CompanyEmail,
+	}, a.config.InvoiceLogoPath, a.config.InvoiceTemplatePath)
+	invoiceSvc := invoiceService.NewInvoiceService(invoiceRepository, customerRepository, productSvc, pdfRenderer,
+		a.config.InvoicePaymentTermDays, a.config.QuoteValidityDays)
+
+	taskSvc := taskService.NewTaskService(taskRepository, customerRepository, userRepository, a.notifier)
+	appointmentSvc := appointmentService.NewAppointmentService(appointmentRepository, customerRepository, userRepository)
+	privacySvc := privacyService.NewPrivacyService(privacyRepository, attachmentRepository, a.storage)
+	retentionSvc := retentionService.NewRetentionService(retentionRepository, customerRepository, a.config)
+
+	// Achtergrondjobs
+	if a.scheduler != nil {
+		a.scheduler.Register(scheduler.Job{
+			Name:     "task-reminders",
+			Interval: time.Duration(a.config.TaskReminderIntervalSeconds) * time.Second,
+			Run:      taskSvc.SendDueReminders,
+		})
+		a.scheduler.Register(scheduler.Job{
+			Name:     "retention-purge",
+			Interval: time.Duration(a.config.RetentionIntervalSeconds) * time.Second,
+			Run:      retentionSvc.Purge,
+		})
+		a.scheduler.Register(scheduler.Job{
+			Name:     "invoice-overdue",
+			Interval: time.Duration(a.config.InvoiceOverdueIntervalSeconds) * time.Second,
+			Run:      invoiceSvc.MarkOverdue,
+		})
+	}
+
+	// Imports die bij een vorige run niet zijn afgerond worden dat ook nooit meer
+	if err := importSvc.FailInterruptedJobs(); err != nil {
+		log.Printf("Waarschuwing: Kon onderbroken imports niet bijwerken: %v", err)
+	}
 
 	// Initialiseer middlewares
 	authMiddleware := middleware.AuthMiddleware(authSvc)
 	auditMiddleware := middleware.NewAuditMiddleware(auditSvc)
 
 	// Initialiseer handlers
-	userHandler := userHandler.NewUserHandler(userSvc)
-	customerHandler := customerHandler.NewCustomerHandler(customerSvc)
+	userHandler := userHandler.NewUserHandler(userSvc, a.config.IfMatchRequired)
+	customerHandler := customerHandler.NewCustomerHandler(customerSvc, savedViewSvc, a.config.IfMatchRequired)
 	auditHandler := auditHandler.NewAuditHandler(auditSvc)
+	tagHandler := tagHandler.NewTagHandler(tagSvc)
+	customFieldHandler := customFieldHandler.NewCustomFieldHandler(customFieldSvc)
+	activityHandler := activityHandler.NewActivityHandler(activitySvc)
+	attachmentHandler := attachmentHandler.NewAttachmentHandler(attachmentSvc)
+	importHandler := importHandler.NewImportHandler(importSvc, a.config.ImportMaxSizeMB)
 	authHandler := authHandler.NewAuthHandler(authSvc)
+	savedViewHandler := savedViewHandler.NewSavedViewHandler(savedViewSvc)
+	searchHandler := searchHandler.NewSearchHandler(searchSvc)
+	customerStatusHandler := customerStatusHandler.NewCustomerStatusHandler(customerStatusSvc)
+	dealHandler := dealHandler.NewDealHandler(dealSvc)
+	quoteHandler := invoiceHandler.NewInvoiceHandler(invoiceSvc, invoiceModel.TypeQuote)
+	invoiceHandler := invoiceHandler.NewInvoiceHandler(invoiceSvc, invoiceModel.TypeInvoice)
+	productHandler := productHandler.NewProductHandler(productSvc, a.config.ImportMaxSizeMB)
+	taskHandler := taskHandler.NewTaskHandler(taskSvc)
+	appointmentHandler := appointmentHandler.NewAppointmentHandler(appointmentSvc, a.config.PublicURL)
+	privacyHandler := privacyHandler.NewPrivacyHandler(privacySvc)
+	retentionHandler := retentionHandler.NewRetentionHandler(retentionSvc)
 
 	// API routes
 	api := a.router.Group("/api")
@@ -97,6 +243,7 @@ func (a *App) setupRoutes() {
 		users.GET("/:id", userHandler.GetByID)
 		users.POST("", userHandler.Create)
 		users.PUT("/:id", userHandler.Update)
+		users.PATCH("/:id", userHandler.PartialUpdate)
 		users.DELETE("/:id", userHandler.Delete)
 	}
 
@@ -105,11 +252,182 @@ func (a *App) setupRoutes() {
 	customers.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), auditMiddleware)
 	{
 		customers.GET("", customerHandler.GetAll)
+		customers.GET("/export", customerHandler.Export)
+		customers.GET("/duplicates", customerHandler.Duplicates)
+		customers.GET("/board", customerHandler.Board)
+		customers.POST("/merge", customerHandler.Merge)
+		customers.POST("/import", importHandler.Import)
+		customers.GET("/import/:jobId", importHandler.GetJob)
+		customers.GET("/import/:jobId/errors", importHandler.ErrorReport)
+		customers.POST("/tags", tagHandler.BulkTag)
+		customers.DELETE("/tags", tagHandler.BulkUntag)
 		customers.GET("/:id", customerHandler.GetByID)
 		customers.POST("", customerHandler.Create)
 		customers.PUT("/:id", customerHandler.Update)
 		customers.PATCH("/:id", customerHandler.PartialUpdate)
 		customers.DELETE("/:id", customerHandler.Delete)
+		customers.GET("/:id/ancestors", customerHandler.Ancestors)
+		customers.GET("/:id/subtree", customerHandler.Subtree)
+		customers.PUT("/:id/status", customerHandler.ChangeStatus)
+		customers.GET("/:id/status-history", customerHandler.StatusHistory)
+		customers.GET("/:id/vcard", customerHandler.VCard)
+
+		// Activiteiten en tijdlijn per klant
+		customers.GET("/:id/activiteiten", activityHandler.GetAll)
+		customers.POST("/:id/activiteiten", activityHandler.Create)
+		customers.PUT("/:id/activiteiten/:activityId", activityHandler.Update)
+		customers.DELETE("/:id/activiteiten/:activityId", activityHandler.Delete)
+		customers.GET("/:id/tijdlijn", activityHandler.Timeline)
+
+		// Bijlagen per klant
+		customers.GET("/:id/bijlagen", attachmentHandler.GetAll)
+		customers.POST("/:id/bijlagen", attachmentHandler.Upload)
+		customers.GET("/:id/bijlagen/:attachmentId", attachmentHandler.Download)
+		customers.DELETE("/:id/bijlagen/:attachmentId", attachmentHandler.Delete)
+
+		// AVG inzage en wissen (alleen admin)
+		customers.GET("/:id/avg-export", middleware.RoleMiddleware(userModel.RoleAdmin), privacyHandler.Export)
+		customers.POST("/:id/anonimiseren", middleware.RoleMiddleware(userModel.RoleAdmin), privacyHandler.Erase)
+		customers.PUT("/:id/legal-hold", middleware.RoleMiddleware(userModel.RoleAdmin), customerHandler.SetLegalHold)
+	}
+
+	// Tag routes (admin en user)
+	tags := api.Group("/tags")
+	tags.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), auditMiddleware)
+	{
+		tags.GET("", tagHandler.GetAll)
+		tags.POST("", tagHandler.Create)
+		tags.PUT("/:id", tagHandler.Update)
+		tags.DELETE("/:id", tagHandler.Delete)
+		tags.POST("/:id/merge", tagHandler.Merge)
+	}
+
+	// Deals (verkoopkansen) bij klanten (admin en user)
+	deals := api.Group("/deals")
+	deals.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), auditMiddleware)
+	{
+		deals.GET("", dealHandler.GetAll)
+		deals.GET("/pipeline", dealHandler.Pipeline)
+		deals.GET("/:id", dealHandler.GetByID)
+		deals.POST("", dealHandler.Create)
+		deals.PUT("/:id", dealHandler.Update)
+		deals.DELETE("/:id", dealHandler.Delete)
+	}
+
+	// Taken en herinneringen (admin en user)
+	tasks := api.Group("/tasks")
+	tasks.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), auditMiddleware)
+	{
+		tasks.GET("", taskHandler.GetAll)
+		tasks.GET("/mine", taskHandler.Mine)
+		tasks.GET("/:id", taskHandler.GetByID)
+		tasks.POST("", taskHandler.Create)
+		tasks.PUT("/:id", taskHandler.Update)
+		tasks.PUT("/:id/status", taskHandler.ChangeStatus)
+		tasks.DELETE("/:id", taskHandler.Delete)
+	}
+
+	// Afspraken en agendafeeds (admin en user)
+	appointments := api.Group("/appointments")
+	appointments.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), auditMiddleware)
+	{
+		appointments.GET("", appointmentHandler.GetAll)
+		appointments.POST("", appointmentHandler.Create)
+		appointments.POST("/import", appointmentHandler.Import)
+		appointments.POST("/feed", appointmentHandler.CreateFeed)
+		appointments.DELETE("/feed", appointmentHandler.RevokeFeed)
+		appointments.GET("/:id", appointmentHandler.GetByID)
+		appointments.PUT("/:id", appointmentHandler.Update)
+		appointments.DELETE("/:id", appointmentHandler.Delete)
+	}
+
+	// Agendafeed (publiek; het geheime token in de URL geeft toegang)
+	api.GET("/calendar/:file", appointmentHandler.Feed)
+
+	// Prijslijst: lezen voor admin en user, beheer alleen admin
+	products := api.Group("/products")
+	products.Use(authMiddleware, auditMiddleware)
+	{
+		readers := middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser)
+		admin := middleware.RoleMiddleware(userModel.RoleAdmin)
+
+		products.GET("", readers, productHandler.GetAll)
+		products.GET("/:id", readers, productHandler.GetByID)
+		products.GET("/:id/price", readers, productHandler.Price)
+		products.GET("/:id/agreements", readers, productHandler.GetAgreements)
+		products.POST("", admin, productHandler.Create)
+		products.POST("/import", admin, productHandler.Import)
+		products.PUT("/:id", admin, productHandler.Update)
+		products.DELETE("/:id", admin, productHandler.Delete)
+		products.POST("/:id/agreements", admin, productHandler.CreateAgreement)
+		products.PUT("/:id/agreements/:agreementId", admin, productHandler.UpdateAgreement)
+		products.DELETE("/:id/agreements/:agreementId", admin, productHandler.DeleteAgreement)
+	}
+
+	// Offertes en facturen (admin en user); verstuurde documenten worden nooit verwijderd
+	quotes := api.Group("/quotes")
+	quotes.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), auditMiddleware)
+	{
+		quotes.GET("", quoteHandler.GetAll)
+		quotes.GET("/:id", quoteHandler.GetByID)
+		quotes.GET("/:id/pdf", quoteHandler.PDF)
+		quotes.POST("", quoteHandler.Create)
+		quotes.PUT("/:id", quoteHandler.Update)
+		quotes.DELETE("/:id", quoteHandler.Delete)
+		quotes.PUT("/:id/status", quoteHandler.ChangeStatus)
+		quotes.POST("/:id/invoice", quoteHandler.CreateFromQuote)
+	}
+
+	invoices := api.Group("/invoices")
+	invoices.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), auditMiddleware)
+	{
+		invoices.GET("", invoiceHandler.GetAll)
+		invoices.GET("/:id", invoiceHandler.GetByID)
+		invoices.GET("/:id/pdf", invoiceHandler.PDF)
+		invoices.POST("", invoiceHandler.Create)
+		invoices.PUT("/:id", invoiceHandler.Update)
+		invoices.DELETE("/:id", invoiceHandler.Delete)
+		invoices.PUT("/:id/status", invoiceHandler.ChangeStatus)
+	}
+
+	// Zoeken in klanten en activiteiten (admin en user)
+	search := api.Group("/search")
+	search.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser))
+	{
+		search.GET("", searchHandler.Search)
+	}
+
+	// Weergaven van de klantenlijst (admin en user, ieder beheert de eigen weergaven)
+	views := api.Group("/views")
+	views.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), auditMiddleware)
+	{
+		views.GET("", savedViewHandler.GetAll)
+		views.POST("", savedViewHandler.Create)
+		views.DELETE("/default", savedViewHandler.ClearDefault)
+		views.GET("/:id", savedViewHandler.GetByID)
+		views.PUT("/:id", savedViewHandler.Update)
+		views.DELETE("/:id", savedViewHandler.Delete)
+		views.PUT("/:id/default", savedViewHandler.SetDefault)
+	}
+
+	// Vrije velden routes (lezen voor admin en user, beheer alleen admin)
+	customFields := api.Group("/custom-fields")
+	customFields.Use(authMiddleware, auditMiddleware)
+	{
+		customFields.GET("", middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), customFieldHandler.GetAll)
+		customFields.POST("", middleware.RoleMiddleware(userModel.RoleAdmin), customFieldHandler.Create)
+		customFields.PUT("/:id", middleware.RoleMiddleware(userModel.RoleAdmin), customFieldHandler.Update)
+		customFields.DELETE("/:id", middleware.RoleMiddleware(userModel.RoleAdmin), customFieldHandler.Delete)
+	}
+
+	// Klantstatussen en hun overgangen (lezen voor admin en user, beheer alleen admin)
+	customerStatuses := api.Group("/customer-statuses")
+	customerStatuses.Use(authMiddleware, auditMiddleware)
+	{
+		customerStatuses.GET("", middleware.RoleMiddleware(userModel.RoleAdmin, userModel.RoleUser), customerStatusHandler.GetAll)
+		customerStatuses.POST("", middleware.RoleMiddleware(userModel.RoleAdmin), customerStatusHandler.Create)
+		customerStatuses.PUT("/:id", middleware.RoleMiddleware(userModel.RoleAdmin), customerStatusHandler.Update)
+		customerStatuses.DELETE("/:id", middleware.RoleMiddleware(userModel.RoleAdmin), customerStatusHandler.Delete)
 	}
 
 	// Audit log routes (alleen admin)
@@ -118,10 +436,25 @@ func (a *App) setupRoutes() {
 	{
 		logs.GET("", auditHandler.GetLogs)
 	}
+
+	// Bewaartermijnen (alleen admin)
+	retention := api.Group("/retention")
+	retention.Use(authMiddleware, middleware.RoleMiddleware(userModel.RoleAdmin))
+	{
+		retention.GET("", retentionHandler.Report)
+	}
 }
 
-// Run start de applicatie
+// Run start de achtergrondjobs en de applicatie
 func (a *App) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if a.scheduler != nil && a.config.SchedulerEnabled {
+		log.Println("Starting background jobs")
+		a.scheduler.Start(ctx)
+	}
+
 	log.Printf("Starting server on %s", a.config.ServerAddress)
 	return a.router.Run(a.config.ServerAddress)
 }
@@ -0,0 +1,465 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"odomosml/internal/appointment/model"
+	"odomosml/internal/appointment/repository"
+	"odomosml/internal/appointment/service"
+	"odomosml/pkg/validation"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize is de maximale grootte van een .ics bestand bij importeren
+const maxImportSize = 1 << 20
+
+// multipartOverhead is de ruimte die naast het bestand voor multipart headers wordt toegestaan
+const multipartOverhead = 64 << 10
+
+// errTooLarge betekent dat het geüploade bestand groter is dan maxImportSize
+var errTooLarge = fmt.Errorf("bestand is groter dan %d KB", maxImportSize>>10)
+
+// AppointmentHandler handles HTTP requests for appointments and calendar feeds
+type AppointmentHandler struct {
+	service   service.AppointmentService
+	publicURL string
+}
+
+// NewAppointmentHandler maakt een nieuwe AppointmentHandler instantie. publicURL is het adres van de
+// API voor de URL van agendafeeds; leeg om het adres uit het verzoek te nemen.
+func NewAppointmentHandler(service service.AppointmentService, publicURL string) *AppointmentHandler {
+	return &AppointmentHandler{
+		service:   service,
+		publicURL: strings.TrimRight(publicURL, "/"),
+	}
+}
+
+// respondError stuurt een fout terug: 409 met de overlappende afspraken in conflicts, 400 met
+// validatiefouten per veld in fields, en anders status
+func respondError(c *gin.Context, status int, err error, conflicts []model.Conflict) {
+	response := gin.H{
+		"success": false,
+		"error":   err.Error(),
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		status = http.StatusConflict
+		response["conflicts"] = conflicts
+	} else if fields, ok := validation.Fields(err); ok {
+		status = http.StatusBadRequest
+		response["fields"] = fields
+	}
+	c.JSON(status, response)
+}
+
+// respondSaved stuurt een opgeslagen afspraak terug, met de overlappende afspraken als die er zijn
+func respondSaved(c *gin.Context, status int, appointment *model.Appointment, conflicts []model.Conflict) {
+	response := gin.H{
+		"success": true,
+		"data":    appointment,
+	}
+	if len(conflicts) > 0 {
+		response["conflicts"] = conflicts
+	}
+	c.JSON(status, response)
+}
+
+// allowConflicts leest de parameter allow_conflicts
+func allowConflicts(c *gin.Context) bool {
+	allow, _ := strconv.ParseBool(c.Query("allow_conflicts"))
+	return allow
+}
+
+// currentUserID geeft het ID van de ingelogde gebruiker
+func currentUserID(c *gin.Context) uint {
+	userID, _ := c.Get("userID")
+	id, _ := userID.(uint)
+	return id
+}
+
+// @Summary      Lijst van afspraken ophalen
+// @Description  Haalt afspraken op, gesorteerd op begin
+// @Tags         afspraken
+// @Accept       json
+// @Produce      json
+// @Param        page query int false "Paginanummer (default: 1)"
+// @Param        page_size query int false "Aantal items per pagina (default: 10, max: 100)"
+// @Param        user_id query int false "Alleen afspraken waarbij deze gebruiker organisator of deelnemer is"
+// @Param        customer_id query int false "Alleen afspraken bij deze klant"
+// @Param        from query string false "Alleen afspraken die na dit moment eindigen (RFC 3339)"
+// @Param        to query string false "Alleen afspraken die voor dit moment beginnen (RFC 3339)"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /appointments [get]
+func (h *AppointmentHandler) GetAll(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	filter := model.AppointmentFilter{
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *uint
+	}{{"user_id", &filter.UserID}, {"customer_id", &filter.CustomerID}} {
+		if value := c.Query(param.name); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || id == 0 {
+				respondError(c, http.StatusBadRequest, fmt.Errorf("%s moet een ID zijn", param.name), nil)
+				return
+			}
+			*param.value = uint(id)
+		}
+	}
+
+	for _, param := range []struct {
+		name  string
+		value **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if value := c.Query(param.name); value != "" {
+			moment, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, fmt.Errorf("%s moet een datum en tijd zijn (RFC 3339), bijv. 2025-03-14T00:00:00Z", param.name), nil)
+				return
+			}
+			*param.value = &moment
+		}
+	}
+
+	appointments, total, err := h.service.GetAppointments(filter)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    appointments,
+		"pagination": gin.H{
+			"current_page": filter.Page,
+			"page_size":    filter.PageSize,
+			"total_items":  total,
+			"total_pages":  (total + int64(filter.PageSize) - 1) / int64(filter.PageSize),
+		},
+	})
+}
+
+// @Summary      Afspraak ophalen op ID
+// @Description  Haalt een afspraak met de deelnemers op
+// @Tags         afspraken
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Afspraak ID"
+// @Success      200  {object}  model.Appointment "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Afspraak niet gevonden"
+// @Security     Bearer
+// @Router       /appointments/{id} [get]
+func (h *AppointmentHandler) GetByID(c *gin.Context) {
+	appointment, err := h.service.GetAppointment(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    appointment,
+	})
+}
+
+// @Summary      Afspraak aanmaken
+// @Description  Maakt een afspraak aan, eventueel bij een klant. Zonder organizer_id organiseert de ingelogde gebruiker de afspraak. Deelnemers zijn gebruikers (user_id) of mensen van buiten (name en email). Overlapt de afspraak met een andere afspraak van de organisator of een deelnemer, dan volgt 409 met de overlappende afspraken, tenzij allow_conflicts=true.
+// @Tags         afspraken
+// @Accept       json
+// @Produce      json
+// @Param        allow_conflicts query bool false "Ook opslaan als de afspraak overlapt met andere afspraken"
+// @Param        appointment body model.Appointment true "Afspraak gegevens"
+// @Success      201  {object}  model.Appointment "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      409  {object}  map[string]interface{} "Overlapt met andere afspraken"
+// @Security     Bearer
+// @Router       /appointments [post]
+func (h *AppointmentHandler) Create(c *gin.Context) {
+	var appointment model.Appointment
+	if err := c.ShouldBindJSON(&appointment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	if appointment.OrganizerID == 0 {
+		appointment.OrganizerID = currentUserID(c)
+	}
+	appointment.UID = ""
+
+	created, conflicts, err := h.service.CreateAppointment(&appointment, allowConflicts(c))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err, conflicts)
+		return
+	}
+
+	respondSaved(c, http.StatusCreated, created, conflicts)
+}
+
+// @Summary      Afspraak bijwerken
+// @Description  Werkt een afspraak met de deelnemers bij; de deelnemers worden in hun geheel vervangen. Bij overlap volgt 409, tenzij allow_conflicts=true.
+// @Tags         afspraken
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Afspraak ID"
+// @Param        allow_conflicts query bool false "Ook opslaan als de afspraak overlapt met andere afspraken"
+// @Param        appointment body model.Appointment true "Afspraak gegevens"
+// @Success      200  {object}  model.Appointment "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Afspraak niet gevonden"
+// @Failure      409  {object}  map[string]interface{} "Overlapt met andere afspraken"
+// @Security     Bearer
+// @Router       /appointments/{id} [put]
+func (h *AppointmentHandler) Update(c *gin.Context) {
+	existing, err := h.service.GetAppointment(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err, nil)
+		return
+	}
+
+	var appointment model.Appointment
+	if err := c.ShouldBindJSON(&appointment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	// Bewaar de huidige gegevens voor de audit diff
+	c.Set("appointmentOldData", existing.ToAuditMap())
+
+	appointment.ID = existing.ID
+	updated, conflicts, err := h.service.UpdateAppointment(&appointment, allowConflicts(c))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err, conflicts)
+		return
+	}
+
+	respondSaved(c, http.StatusOK, updated, conflicts)
+}
+
+// @Summary      Afspraak verwijderen
+// @Description  Verwijdert een afspraak; bij de volgende synchronisatie verdwijnt hij ook uit de agendafeeds
+// @Tags         afspraken
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Afspraak ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Afspraak niet gevonden"
+// @Security     Bearer
+// @Router       /appointments/{id} [delete]
+func (h *AppointmentHandler) Delete(c *gin.Context) {
+	appointmentData, err := h.service.DeleteAppointment(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err, nil)
+		return
+	}
+
+	// Sla appointmentData op in context voor audit logging
+	c.Set("appointmentData", appointmentData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Afspraak succesvol verwijderd",
+	})
+}
+
+// @Summary      Afspraak importeren uit .ics
+// @Description  Importeert één afspraak uit een iCalendar bestand (bijv. een uitnodiging uit Outlook) met de ingelogde gebruiker als organisator. Deelnemers met het e-mailadres van een gebruiker worden aan die gebruiker gekoppeld. Een eerder geïmporteerde afspraak (zelfde UID) wordt bijgewerkt. Terugkerende afspraken worden niet ondersteund.
+// @Tags         afspraken
+// @Accept       multipart/form-data
+// @Accept       text/calendar
+// @Produce      json
+// @Param        file formData file false ".ics bestand (of stuur het bestand als body met Content-Type text/calendar)"
+// @Param        customer_id query int false "Klant bij de afspraak"
+// @Param        allow_conflicts query bool false "Ook opslaan als de afspraak overlapt met andere afspraken"
+// @Success      201  {object}  model.Appointment "Afspraak aangemaakt"
+// @Success      200  {object}  model.Appointment "Bestaande afspraak bijgewerkt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldig bestand"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      409  {object}  map[string]interface{} "Overlapt met andere afspraken"
+// @Failure      413  {object}  map[string]string "Bestand te groot"
+// @Security     Bearer
+// @Router       /appointments/import [post]
+func (h *AppointmentHandler) Import(c *gin.Context) {
+	var customerID *uint
+	if value := c.Query("customer_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			respondError(c, http.StatusBadRequest, errors.New("customer_id moet een ID zijn"), nil)
+			return
+		}
+		customer := uint(id)
+		customerID = &customer
+	}
+
+	content, err := readUpload(c)
+	if errors.Is(err, errTooLarge) {
+		respondError(c, http.StatusRequestEntityTooLarge, err, nil)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	appointment, created, conflicts, err := h.service.ImportEvent(content, currentUserID(c), customerID, allowConflicts(c))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err, conflicts)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	respondSaved(c, status, appointment, conflicts)
+}
+
+// readUpload leest een .ics bestand uit het veld file van een multipart formulier, of anders uit de body.
+// De body wordt begrensd voordat hij gelezen wordt, zodat een groot formulier niet eerst op schijf belandt.
+func readUpload(c *gin.Context) ([]byte, error) {
+	var reader io.Reader
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+multipartOverhead)
+		file, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, errTooLarge
+			}
+			return nil, errors.New("geen bestand ontvangen in het veld file")
+		}
+		opened, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer opened.Close()
+		reader = opened
+	} else {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+1)
+		reader = c.Request.Body
+	}
+
+	content, err := io.ReadAll(io.LimitReader(reader, maxImportSize+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errTooLarge
+		}
+		return nil, err
+	}
+	if len(content) > maxImportSize {
+		return nil, errTooLarge
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, errors.New("bestand is leeg")
+	}
+	return content, nil
+}
+
+// @Summary      Agendafeed aanmaken
+// @Description  Maakt een persoonlijke iCalendar feed van de afspraken van de ingelogde gebruiker, om in Outlook of Google Agenda op te abonneren. De URL bevat een geheim token en is alleen nu te zien; een eerdere URL werkt daarna niet meer.
+// @Tags         afspraken
+// @Produce      json
+// @Success      201  {object}  model.FeedResponse "Feed aangemaakt"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /appointments/feed [post]
+func (h *AppointmentHandler) CreateFeed(c *gin.Context) {
+	token, err := h.service.CreateFeedToken(currentUserID(c))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	base := h.publicURL
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    model.FeedResponse{URL: base + "/api/calendar/" + token + ".ics"},
+	})
+}
+
+// @Summary      Agendafeed intrekken
+// @Description  Trekt de agendafeed van de ingelogde gebruiker in; de URL werkt daarna niet meer
+// @Tags         afspraken
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "Feed ingetrokken"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /appointments/feed [delete]
+func (h *AppointmentHandler) RevokeFeed(c *gin.Context) {
+	if err := h.service.RevokeFeedToken(currentUserID(c)); err != nil {
+		respondError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Agendafeed ingetrokken",
+	})
+}
+
+// @Summary      Agendafeed ophalen
+// @Description  Geeft de afspraken van een gebruiker als iCalendar (RFC 5545): de afgelopen 90 dagen en alle toekomstige afspraken. Publiek; het geheime token in de URL geeft toegang.
+// @Tags         afspraken
+// @Produce      text/calendar
+// @Param        file path string true "Token met .ics"
+// @Success      200  {file}    file "iCalendar feed"
+// @Failure      404  {object}  map[string]string "Feed niet gevonden"
+// @Router       /calendar/{file} [get]
+func (h *AppointmentHandler) Feed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("file"), ".ics")
+
+	var buf bytes.Buffer
+	if err := h.service.RenderFeed(token, &buf); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrFeedNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"time"
+)
+
+// Appointment is een afspraak van een gebruiker, eventueel bij een klant, met andere deelnemers
+// @Description Een afspraak in de agenda
+type Appointment struct {
+	ID            uint       `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	UID           string     `json:"uid" gorm:"column:uid;size:255;not null;uniqueIndex" example:"7f3c2a9e1b@odomosml" swaggertype:"string"` // iCalendar UID; blijft gelijk bij wijzigen
+	Title         string     `json:"title" gorm:"size:200;not null" binding:"required" example:"Bezoek Bakkerij Jansen" swaggertype:"string"`
+	Description   string     `json:"description" gorm:"type:text" example:"Nieuwe oven bespreken" swaggertype:"string"`
+	Location      string     `json:"location" gorm:"size:255" example:"Dorpsstraat 1, Utrecht" swaggertype:"string"`
+	StartAt       time.Time  `json:"start_at" gorm:"not null;index" binding:"required" example:"2025-03-14T10:00:00Z" swaggertype:"string" format:"date-time"`
+	EndAt         time.Time  `json:"end_at" gorm:"not null;index" binding:"required" example:"2025-03-14T11:00:00Z" swaggertype:"string" format:"date-time"`
+	AllDay        bool       `json:"all_day" gorm:"not null;default:false" example:"false" swaggertype:"boolean"` // Hele dag; start en einde vallen dan op middernacht
+	CustomerID    *uint      `json:"customer_id" gorm:"index" example:"1" swaggertype:"integer"`
+	CustomerName  string     `json:"customer_name,omitempty" gorm:"->;-:migration" example:"Bakkerij Jansen" swaggertype:"string"`
+	OrganizerID   uint       `json:"organizer_id" gorm:"not null;index" example:"1" swaggertype:"integer"`
+	OrganizerName string     `json:"organizer_name" gorm:"size:100" example:"johndoe" swaggertype:"string"`
+	Attendees     []Attendee `json:"attendees" gorm:"foreignKey:AppointmentID;constraint:OnDelete:CASCADE"`
+	Sequence      int        `json:"sequence" gorm:"not null;default:0" example:"0" swaggertype:"integer"` // iCalendar SEQUENCE; wordt bij elke wijziging opgehoogd
+	CreatedAt     time.Time  `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt     time.Time  `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Appointment) TableName() string {
+	return "appointments"
+}
+
+// UserIDs geeft de gebruikers die bij de afspraak aanwezig zijn: de organisator en de deelnemers die gebruiker zijn
+func (a *Appointment) UserIDs() []uint {
+	ids := []uint{a.OrganizerID}
+	seen := map[uint]bool{a.OrganizerID: true}
+	for _, attendee := range a.Attendees {
+		if attendee.UserID != nil && !seen[*attendee.UserID] {
+			seen[*attendee.UserID] = true
+			ids = append(ids, *attendee.UserID)
+		}
+	}
+	return ids
+}
+
+// ToAuditMap converteert een afspraak naar een map voor audit logging
+func (a *Appointment) ToAuditMap() map[string]interface{} {
+	attendees := make([]map[string]interface{}, len(a.Attendees))
+	for i, attendee := range a.Attendees {
+		attendees[i] = map[string]interface{}{
+			"user_id": attendee.UserID,
+			"name":    attendee.Name,
+			"email":   attendee.Email,
+		}
+	}
+
+	return map[string]interface{}{
+		"id":           a.ID,
+		"title":        a.Title,
+		"location":     a.Location,
+		"start_at":     a.StartAt,
+		"end_at":       a.EndAt,
+		"all_day":      a.AllDay,
+		"customer_id":  a.CustomerID,
+		"organizer_id": a.OrganizerID,
+		"attendees":    attendees,
+	}
+}
+
+// Attendee is een deelnemer aan een afspraak: een gebruiker, of iemand van buiten zoals een contactpersoon van de klant
+type Attendee struct {
+	ID            uint   `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	AppointmentID uint   `json:"-" gorm:"not null;index"`
+	UserID        *uint  `json:"user_id" gorm:"index" example:"2" swaggertype:"integer"` // Leeg voor een deelnemer van buiten
+	Name          string `json:"name" gorm:"size:100" example:"Piet Jansen" swaggertype:"string"`
+	Email         string `json:"email" gorm:"size:255" example:"piet@bakkerijjansen.nl" swaggertype:"string"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Attendee) TableName() string {
+	return "appointment_attendees"
+}
+
+// Conflict is een andere afspraak van een gebruiker die overlapt met een afspraak
+type Conflict struct {
+	UserID        uint      `json:"user_id" example:"1" swaggertype:"integer"`
+	Username      string    `json:"username" example:"johndoe" swaggertype:"string"`
+	AppointmentID uint      `json:"appointment_id" example:"7" swaggertype:"integer"`
+	Title         string    `json:"title" example:"Kwartaaloverleg" swaggertype:"string"`
+	StartAt       time.Time `json:"start_at" example:"2025-03-14T10:30:00Z" swaggertype:"string" format:"date-time"`
+	EndAt         time.Time `json:"end_at" example:"2025-03-14T11:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// CalendarToken geeft een gebruiker toegang tot de eigen agenda als iCalendar feed. Alleen de
+// SHA-256 hash van het token wordt bewaard; het token zelf is alleen bij het aanmaken te zien.
+type CalendarToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;uniqueIndex"`
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+	CreatedAt time.Time
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (CalendarToken) TableName() string {
+	return "calendar_tokens"
+}
+
+// AppointmentFilter definieert filters voor het ophalen van afspraken
+type AppointmentFilter struct {
+	UserID     uint       // Alleen afspraken waarbij deze gebruiker organisator of deelnemer is
+	CustomerID uint       // Alleen afspraken bij deze klant
+	From       *time.Time // Alleen afspraken die na dit moment eindigen
+	To         *time.Time // Alleen afspraken die voor dit moment beginnen
+	Page       int
+	PageSize   int
+}
+
+// FeedResponse is het antwoord bij het aanmaken van een agendafeed
+// @Description De URL van de persoonlijke agendafeed
+type FeedResponse struct {
+	URL string `json:"url" example:"https://crm.example.nl/api/calendar/3f9a...c1.ics" swaggertype:"string"`
+}
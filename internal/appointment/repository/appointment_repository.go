@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/appointment/model"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrConflict betekent dat de afspraak overlapt met een andere afspraak van een van de gebruikers
+var ErrConflict = errors.New("afspraak overlapt met andere afspraken")
+
+// userLockSpace is de eerste sleutel van de advisory locks op de agenda van een gebruiker
+const userLockSpace = 4600
+
+// AppointmentRepository definieert de interface voor afspraak repository
+type AppointmentRepository interface {
+	FindAll(filter model.AppointmentFilter) ([]model.Appointment, int64, error)
+	FindByID(id string) (*model.Appointment, error)
+	FindByUID(uid string) (*model.Appointment, error)
+	Save(appointment *model.Appointment, allowConflicts bool) ([]model.Conflict, error)
+	Delete(id uint) error
+	FindForUser(userID uint, from time.Time) ([]model.Appointment, error)
+	SaveToken(userID uint, tokenHash string) error
+	DeleteToken(userID uint) error
+	FindTokenUser(tokenHash string) (uint, error)
+}
+
+// appointmentRepository implementeert de AppointmentRepository interface
+type appointmentRepository struct {
+	db *gorm.DB
+}
+
+// NewAppointmentRepository maakt een nieuwe AppointmentRepository instantie
+func NewAppointmentRepository(db *gorm.DB) AppointmentRepository {
+	return &appointmentRepository{
+		db: db,
+	}
+}
+
+// query is de basis voor het ophalen van afspraken
+func (r *appointmentRepository) query() *gorm.DB {
+	return r.db.Model(&model.Appointment{})
+}
+
+// withDetails laadt bij een query de deelnemers en de naam van de klant; pas toepassen na het tellen
+func withDetails(query *gorm.DB) *gorm.DB {
+	return query.
+		Select("appointments.*, customers.name AS customer_name").
+		Joins("LEFT JOIN customers ON customers.id = appointments.customer_id").
+		Preload("Attendees", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id ASC")
+		})
+}
+
+// forUser beperkt een query tot de afspraken waarbij de gebruiker organisator of deelnemer is
+func forUser(query *gorm.DB, userID uint) *gorm.DB {
+	return query.Where("appointments.organizer_id = ? OR EXISTS (SELECT 1 FROM appointment_attendees aa WHERE aa.appointment_id = appointments.id AND aa.user_id = ?)", userID, userID)
+}
+
+// FindAll haalt afspraken op met filters, gesorteerd op begin
+func (r *appointmentRepository) FindAll(filter model.AppointmentFilter) ([]model.Appointment, int64, error) {
+	var appointments []model.Appointment
+	var total int64
+
+	query := r.query()
+
+	if filter.UserID != 0 {
+		query = forUser(query, filter.UserID)
+	}
+
+	if filter.CustomerID != 0 {
+		query = query.Where("appointments.customer_id = ?", filter.CustomerID)
+	}
+
+	if filter.From != nil {
+		query = query.Where("appointments.end_at > ?", *filter.From)
+	}
+
+	if filter.To != nil {
+		query = query.Where("appointments.start_at < ?", *filter.To)
+	}
+
+	// Tel totaal aantal records (voor paginering)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Paginering toepassen
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query = query.Offset(offset).Limit(filter.PageSize)
+	}
+
+	if err := withDetails(query).Order("appointments.start_at ASC, appointments.id ASC").Find(&appointments).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return appointments, total, nil
+}
+
+// FindByID haalt een afspraak op op basis van ID
+func (r *appointmentRepository) FindByID(id string) (*model.Appointment, error) {
+	var appointment model.Appointment
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := withDetails(r.query()).Where("appointments.id = ?", idInt).First(&appointment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("afspraak niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &appointment, nil
+}
+
+// FindByUID haalt een afspraak op op basis van de iCalendar UID, of nil als die niet bestaat
+func (r *appointmentRepository) FindByUID(uid string) (*model.Appointment, error) {
+	var appointment model.Appointment
+
+	if err := withDetails(r.query()).Where("appointments.uid = ?", uid).First(&appointment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &appointment, nil
+}
+
+// Save maakt een afspraak aan (ID 0) of werkt hem bij, met de deelnemers, en geeft de overlappende
+// afspraken van de gebruikers terug. Met overlap en zonder allowConflicts wordt er niets opgeslagen en
+// is de fout ErrConflict. De agenda's van de gebruikers worden tijdens de controle vergrendeld, zodat
+// twee gelijktijdige verzoeken niet allebei een overlappende afspraak kunnen opslaan.
+func (r *appointmentRepository) Save(appointment *model.Appointment, allowConflicts bool) ([]model.Conflict, error) {
+	var conflicts []model.Conflict
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Vergrendel in een vaste volgorde, zodat twee transacties niet op elkaar blijven wachten
+		userIDs := appointment.UserIDs()
+		sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+		for _, userID := range userIDs {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", userLockSpace, int32(userID)).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Raw(`SELECT u.user_id, COALESCE(users.username, '') AS username, a.id AS appointment_id, a.title, a.start_at, a.end_at
+			FROM appointments a
+			JOIN (
+				SELECT id AS appointment_id, organizer_id AS user_id FROM appointments
+				UNION
+				SELECT appointment_id, user_id FROM appointment_attendees WHERE user_id IS NOT NULL
+			) u ON u.appointment_id = a.id
+			LEFT JOIN users ON users.id = u.user_id
+			WHERE u.user_id IN ? AND a.start_at < ? AND a.end_at > ? AND a.id <> ?
+			ORDER BY a.start_at, a.id, u.user_id`,
+			userIDs, appointment.EndAt, appointment.StartAt, appointment.ID).Scan(&conflicts).Error
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 && !allowConflicts {
+			return ErrConflict
+		}
+
+		attendees := appointment.Attendees
+		for i := range attendees {
+			attendees[i].ID = 0
+			attendees[i].AppointmentID = appointment.ID
+		}
+
+		if appointment.ID == 0 {
+			return tx.Create(appointment).Error
+		}
+
+		err = tx.Model(appointment).
+			Select("title", "description", "location", "start_at", "end_at", "all_day", "customer_id",
+				"organizer_id", "organizer_name", "sequence", "updated_at").
+			Omit(clause.Associations).
+			Updates(appointment).Error
+		if err != nil {
+			return err
+		}
+
+		// De deelnemers worden in hun geheel vervangen
+		if err := tx.Where("appointment_id = ?", appointment.ID).Delete(&model.Attendee{}).Error; err != nil {
+			return err
+		}
+		if len(attendees) > 0 {
+			return tx.Create(&attendees).Error
+		}
+		return nil
+	})
+
+	return conflicts, err
+}
+
+// Delete verwijdert een afspraak met de deelnemers
+func (r *appointmentRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("appointment_id = ?", id).Delete(&model.Attendee{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Appointment{}, id).Error
+	})
+}
+
+// FindForUser haalt de afspraken van een gebruiker op die na from eindigen, gesorteerd op begin
+func (r *appointmentRepository) FindForUser(userID uint, from time.Time) ([]model.Appointment, error) {
+	var appointments []model.Appointment
+
+	err := withDetails(forUser(r.query(), userID)).
+		Where("appointments.end_at > ?", from).
+		Order("appointments.start_at ASC, appointments.id ASC").
+		Find(&appointments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return appointments, nil
+}
+
+// SaveToken legt het token van de agendafeed van een gebruiker vast; een eerder token vervalt
+func (r *appointmentRepository) SaveToken(userID uint, tokenHash string) error {
+	token := model.CalendarToken{UserID: userID, TokenHash: tokenHash, CreatedAt: time.Now()}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token_hash", "created_at"}),
+	}).Create(&token).Error
+}
+
+// DeleteToken trekt de agendafeed van een gebruiker in
+func (r *appointmentRepository) DeleteToken(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.CalendarToken{}).Error
+}
+
+// FindTokenUser geeft de gebruiker bij een token van een agendafeed, of 0 als het token niet bestaat
+func (r *appointmentRepository) FindTokenUser(tokenHash string) (uint, error) {
+	var token model.CalendarToken
+
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return token.UserID, nil
+}
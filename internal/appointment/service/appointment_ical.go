@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"odomosml/internal/appointment/model"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// feedRefreshInterval is hoe vaak agenda-apps de feed opnieuw moeten ophalen
+const feedRefreshInterval = "PT15M"
+
+// durationPattern herkent een iCalendar DURATION zoals PT1H30M of P1D
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// renderCalendar schrijft de afspraken als iCalendar (RFC 5545) feed. organizers koppelt het ID van
+// een organisator aan zijn e-mailadres.
+func renderCalendar(w io.Writer, name string, appointments []model.Appointment, organizers map[uint]string) error {
+	calendar := ics.NewCalendarFor("OdomosML")
+	calendar.SetMethod(ics.MethodPublish)
+	calendar.SetName(name)
+	calendar.SetXWRCalName(name)
+	calendar.SetRefreshInterval(feedRefreshInterval)
+	calendar.SetXPublishedTTL(feedRefreshInterval)
+
+	for _, appointment := range appointments {
+		event := calendar.AddEvent(appointment.UID)
+		event.SetDtStampTime(appointment.UpdatedAt)
+		event.SetCreatedTime(appointment.CreatedAt)
+		event.SetModifiedAt(appointment.UpdatedAt)
+		event.SetSequence(appointment.Sequence)
+		event.SetSummary(appointment.Title)
+
+		if appointment.AllDay {
+			event.SetAllDayStartAt(appointment.StartAt.In(time.Local))
+			event.SetAllDayEndAt(appointment.EndAt.In(time.Local))
+		} else {
+			event.SetStartAt(appointment.StartAt)
+			event.SetEndAt(appointment.EndAt)
+		}
+
+		if appointment.Location != "" {
+			event.SetLocation(appointment.Location)
+		}
+
+		description := appointment.Description
+		if appointment.CustomerName != "" {
+			description = strings.TrimSpace("Klant: " + appointment.CustomerName + "\n\n" + description)
+		}
+		if description != "" {
+			event.SetDescription(description)
+		}
+
+		if email := organizers[appointment.OrganizerID]; email != "" {
+			event.SetOrganizer(email, ics.WithCN(appointment.OrganizerName))
+		}
+		for _, attendee := range appointment.Attendees {
+			if attendee.Email == "" {
+				continue
+			}
+			params := []ics.PropertyParameter{ics.ParticipationRoleReqParticipant}
+			if attendee.Name != "" {
+				params = append(params, ics.WithCN(attendee.Name))
+			}
+			event.AddAttendee(attendee.Email, params...)
+		}
+	}
+
+	return calendar.SerializeTo(w, ics.WithNewLineWindows)
+}
+
+// parseEvent leest één afspraak uit een .ics bestand. Het bestand moet precies één niet-terugkerende
+// afspraak bevatten; deelnemers krijgen alleen naam en e-mailadres.
+func parseEvent(content []byte) (*model.Appointment, error) {
+	calendar, err := ics.ParseCalendar(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("ongeldig iCalendar bestand: %v", err)
+	}
+
+	events := calendar.Events()
+	switch {
+	case len(events) == 0:
+		return nil, errors.New("bestand bevat geen afspraak (VEVENT)")
+	case len(events) > 1:
+		return nil, fmt.Errorf("bestand bevat %d afspraken; importeer één afspraak per keer", len(events))
+	}
+	event := events[0]
+
+	if event.GetProperty(ics.ComponentPropertyRrule) != nil || event.GetProperty(ics.ComponentPropertyRdate) != nil {
+		return nil, errors.New("terugkerende afspraken kunnen niet geïmporteerd worden")
+	}
+
+	appointment := &model.Appointment{
+		UID:         strings.TrimSpace(event.Id()),
+		Title:       propertyText(event, ics.ComponentPropertySummary),
+		Description: propertyText(event, ics.ComponentPropertyDescription),
+		Location:    propertyText(event, ics.ComponentPropertyLocation),
+	}
+
+	start := event.GetProperty(ics.ComponentPropertyDtStart)
+	if start == nil {
+		return nil, errors.New("afspraak heeft geen begin (DTSTART)")
+	}
+	appointment.AllDay = isDate(start)
+	if appointment.AllDay {
+		appointment.StartAt, err = event.GetAllDayStartAt()
+	} else {
+		appointment.StartAt, err = event.GetStartAt()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ongeldig begin (DTSTART): %v", err)
+	}
+
+	switch {
+	case event.GetProperty(ics.ComponentPropertyDtEnd) != nil:
+		if appointment.AllDay {
+			appointment.EndAt, err = event.GetAllDayEndAt()
+		} else {
+			appointment.EndAt, err = event.GetEndAt()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ongeldig einde (DTEND): %v", err)
+		}
+	case event.GetProperty(ics.ComponentPropertyDuration) != nil:
+		duration, err := parseDuration(event.GetProperty(ics.ComponentPropertyDuration).Value)
+		if err != nil {
+			return nil, err
+		}
+		appointment.EndAt = appointment.StartAt.Add(duration)
+	case appointment.AllDay:
+		// Zonder einde duurt een afspraak van een hele dag die ene dag (RFC 5545, 3.6.1)
+		appointment.EndAt = appointment.StartAt.AddDate(0, 0, 1)
+	default:
+		appointment.EndAt = appointment.StartAt
+	}
+
+	if appointment.AllDay {
+		// Een datum zonder tijd is een dag in de lokale tijdzone, niet in UTC
+		appointment.StartAt = asLocalDate(appointment.StartAt)
+		appointment.EndAt = asLocalDate(appointment.EndAt)
+	}
+
+	for _, attendee := range event.Attendees() {
+		email := attendee.Value
+		if len(email) >= len("mailto:") && strings.EqualFold(email[:len("mailto:")], "mailto:") {
+			email = email[len("mailto:"):]
+		}
+		var name string
+		if values := attendee.ICalParameters[string(ics.ParameterCn)]; len(values) > 0 {
+			name = strings.Trim(values[0], `"`)
+		}
+		appointment.Attendees = append(appointment.Attendees, model.Attendee{Name: name, Email: strings.TrimSpace(email)})
+	}
+
+	return appointment, nil
+}
+
+// asLocalDate geeft middernacht in de lokale tijdzone op de kalenderdatum van t
+func asLocalDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// propertyText geeft de tekst van een eigenschap van een afspraak, of "" als die ontbreekt
+func propertyText(event *ics.VEvent, property ics.ComponentProperty) string {
+	if p := event.GetProperty(property); p != nil {
+		return strings.TrimSpace(p.Value)
+	}
+	return ""
+}
+
+// isDate geeft aan of een datumeigenschap een datum zonder tijd is (VALUE=DATE)
+func isDate(property *ics.IANAProperty) bool {
+	if values := property.ICalParameters[string(ics.ParameterValue)]; len(values) > 0 {
+		return strings.EqualFold(values[0], string(ics.ValueDataTypeDate))
+	}
+	return len(property.Value) == len("20060102")
+}
+
+// parseDuration zet een iCalendar DURATION (RFC 5545, 3.3.6) om in een time.Duration
+func parseDuration(value string) (time.Duration, error) {
+	trimmed := strings.TrimSpace(value)
+	match := durationPattern.FindStringSubmatch(trimmed)
+	// Het patroon laat alle eenheden weg; een duur heeft er minstens één, en na T volgt een tijd
+	if match == nil || strings.Join(match[2:], "") == "" || strings.HasSuffix(trimmed, "T") {
+		return 0, fmt.Errorf("ongeldige duur (DURATION) '%s'", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if match[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("ongeldige duur (DURATION) '%s'", value)
+		}
+		duration += time.Duration(n) * unit
+	}
+
+	if match[1] == "-" {
+		return 0, fmt.Errorf("duur (DURATION) '%s' mag niet negatief zijn", value)
+	}
+	return duration, nil
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"bytes"
+	"odomosml/internal/appointment/model"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar zet regels om in een .ics bestand met CRLF-regeleinden
+func calendar(lines ...string) []byte {
+	all := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Test//NL"}, lines...)
+	all = append(all, "END:VCALENDAR")
+	return []byte(strings.Join(all, "\r\n") + "\r\n")
+}
+
+// event zet eigenschappen om in een VEVENT
+func event(properties ...string) []string {
+	return append(append([]string{"BEGIN:VEVENT"}, properties...), "END:VEVENT")
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr string
+	}{
+		{value: "PT1H30M", want: 90 * time.Minute},
+		{value: "PT45S", want: 45 * time.Second},
+		{value: "P1D", want: 24 * time.Hour},
+		{value: "P2W", want: 14 * 24 * time.Hour},
+		{value: "P1DT2H", want: 26 * time.Hour},
+		{value: "+PT15M", want: 15 * time.Minute},
+		{value: " PT1H ", want: time.Hour},
+		{value: "P", wantErr: "ongeldige duur (DURATION) 'P'"},
+		{value: "+P", wantErr: "ongeldige duur (DURATION) '+P'"},
+		{value: " P", wantErr: "ongeldige duur (DURATION) ' P'"},
+		{value: "PT", wantErr: "ongeldige duur (DURATION) 'PT'"},
+		{value: "P1DT", wantErr: "ongeldige duur (DURATION) 'P1DT'"},
+		{value: "1H", wantErr: "ongeldige duur (DURATION) '1H'"},
+		{value: "PT1.5H", wantErr: "ongeldige duur (DURATION) 'PT1.5H'"},
+		{value: "-PT1H", wantErr: "duur (DURATION) '-PT1H' mag niet negatief zijn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDuration(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("parseDuration gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDuration gaf fout: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseDuration = %v, verwacht %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEvent(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skipf("tijdzone niet beschikbaar: %v", err)
+	}
+	localDate := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    model.Appointment
+		wantErr string
+	}{
+		{
+			name: "afspraak in UTC",
+			content: calendar(event("UID:abc@example.com", "SUMMARY: Bezoek Bakkerij Jansen ", "LOCATION:Dorpsstraat 1",
+				"DTSTART:20250314T100000Z", "DTEND:20250314T110000Z")...),
+			want: model.Appointment{UID: "abc@example.com", Title: "Bezoek Bakkerij Jansen", Location: "Dorpsstraat 1",
+				StartAt: time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC), EndAt: time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "afspraak met tijdzone",
+			content: calendar(event("UID:tz", "SUMMARY:Overleg", "DTSTART;TZID=Europe/Amsterdam:20250314T100000", "DURATION:PT1H30M")...),
+			want: model.Appointment{UID: "tz", Title: "Overleg",
+				StartAt: time.Date(2025, 3, 14, 10, 0, 0, 0, amsterdam), EndAt: time.Date(2025, 3, 14, 11, 30, 0, 0, amsterdam)},
+		},
+		{
+			name:    "zonder einde",
+			content: calendar(event("UID:kort", "SUMMARY:Bellen", "DTSTART:20250314T100000Z")...),
+			want: model.Appointment{UID: "kort", Title: "Bellen",
+				StartAt: time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC), EndAt: time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "hele dag zonder einde duurt één dag",
+			content: calendar(event("UID:dag", "SUMMARY:Beurs", "DTSTART;VALUE=DATE:20250314")...),
+			want:    model.Appointment{UID: "dag", Title: "Beurs", AllDay: true, StartAt: localDate(2025, 3, 14), EndAt: localDate(2025, 3, 15)},
+		},
+		{
+			name:    "meerdere hele dagen",
+			content: calendar(event("UID:dagen", "SUMMARY:Vakantie", "DTSTART;VALUE=DATE:20250314", "DTEND;VALUE=DATE:20250317")...),
+			want:    model.Appointment{UID: "dagen", Title: "Vakantie", AllDay: true, StartAt: localDate(2025, 3, 14), EndAt: localDate(2025, 3, 17)},
+		},
+		{
+			name: "deelnemers",
+			content: calendar(event("UID:team", "SUMMARY:Teamoverleg", "DTSTART:20250314T100000Z", "DTEND:20250314T110000Z",
+				`ATTENDEE;CN="Piet Jansen";ROLE=REQ-PARTICIPANT:mailto:piet@jansen.nl`, "ATTENDEE:MAILTO:klaas@jansen.nl")...),
+			want: model.Appointment{UID: "team", Title: "Teamoverleg",
+				StartAt: time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC), EndAt: time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC),
+				Attendees: []model.Attendee{{Name: "Piet Jansen", Email: "piet@jansen.nl"}, {Email: "klaas@jansen.nl"}}},
+		},
+		{
+			name:    "terugkerende afspraak",
+			content: calendar(event("UID:wekelijks", "SUMMARY:Stand-up", "DTSTART:20250314T090000Z", "RRULE:FREQ=WEEKLY;COUNT=4")...),
+			wantErr: "terugkerende afspraken kunnen niet geïmporteerd worden",
+		},
+		{
+			name:    "extra datums",
+			content: calendar(event("UID:rdate", "SUMMARY:Stand-up", "DTSTART:20250314T090000Z", "RDATE:20250321T090000Z")...),
+			wantErr: "terugkerende afspraken kunnen niet geïmporteerd worden",
+		},
+		{
+			name:    "geen afspraak",
+			content: calendar("BEGIN:VTODO", "UID:taak", "SUMMARY:Taak", "END:VTODO"),
+			wantErr: "bestand bevat geen afspraak (VEVENT)",
+		},
+		{
+			name: "twee afspraken",
+			content: calendar(append(event("UID:een", "DTSTART:20250314T090000Z"),
+				event("UID:twee", "DTSTART:20250315T090000Z")...)...),
+			wantErr: "bestand bevat 2 afspraken; importeer één afspraak per keer",
+		},
+		{
+			name:    "zonder begin",
+			content: calendar(event("UID:leeg", "SUMMARY:Leeg")...),
+			wantErr: "afspraak heeft geen begin (DTSTART)",
+		},
+		{
+			name:    "negatieve duur",
+			content: calendar(event("UID:terug", "DTSTART:20250314T090000Z", "DURATION:-PT1H")...),
+			wantErr: "duur (DURATION) '-PT1H' mag niet negatief zijn",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEvent(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("parseEvent gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEvent gaf fout: %v", err)
+			}
+			if !got.StartAt.Equal(tt.want.StartAt) || !got.EndAt.Equal(tt.want.EndAt) {
+				t.Errorf("van %v tot %v, verwacht van %v tot %v", got.StartAt, got.EndAt, tt.want.StartAt, tt.want.EndAt)
+			}
+			got.StartAt, got.EndAt = tt.want.StartAt, tt.want.EndAt
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("parseEvent = %+v, verwacht %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseEvent([]byte("geen agenda")); err == nil || !strings.HasPrefix(err.Error(), "ongeldig iCalendar bestand") {
+		t.Errorf("parseEvent zonder agenda gaf %v", err)
+	}
+}
+
+func TestRenderCalendar(t *testing.T) {
+	customerID := uint(5)
+	appointments := []model.Appointment{
+		{
+			UID: "een@odomosml", Title: "Bezoek Bakkerij Jansen", Description: "Nieuwe oven bespreken", Location: "Dorpsstraat 1, Utrecht",
+			StartAt: time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC), EndAt: time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC),
+			CustomerID: &customerID, CustomerName: "Bakkerij Jansen", OrganizerID: 1, OrganizerName: "johndoe", Sequence: 2,
+			Attendees: []model.Attendee{{Name: "Piet Jansen", Email: "piet@jansen.nl"}, {Name: "Zonder adres"}},
+		},
+		{
+			UID: "twee@odomosml", Title: "Beurs", AllDay: true, OrganizerID: 2,
+			StartAt: time.Date(2025, 3, 20, 0, 0, 0, 0, time.Local), EndAt: time.Date(2025, 3, 22, 0, 0, 0, 0, time.Local),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := renderCalendar(&buf, "OdomosML afspraken johndoe", appointments, map[uint]string{1: "john@example.com"}); err != nil {
+		t.Fatalf("renderCalendar gaf fout: %v", err)
+	}
+	feed := buf.String()
+
+	for _, want := range []string{
+		"METHOD:PUBLISH", "X-WR-CALNAME:OdomosML afspraken johndoe", "REFRESH-INTERVAL;VALUE=DURATION:PT15M",
+		"SEQUENCE:2", "ORGANIZER;CN=johndoe:mailto:john@example.com", "DTSTART;VALUE=DATE:20250320", "DTEND;VALUE=DATE:20250322",
+	} {
+		if !strings.Contains(feed, want) {
+			t.Errorf("feed bevat geen %q:\n%s", want, feed)
+		}
+	}
+	if strings.Contains(strings.ReplaceAll(feed, "\r\n", ""), "\n") {
+		t.Error("feed gebruikt geen CRLF-regeleinden")
+	}
+	if strings.Count(feed, "ATTENDEE") != 1 {
+		t.Errorf("deelnemer zonder e-mailadres in de feed:\n%s", feed)
+	}
+	if strings.Count(feed, "ORGANIZER") != 1 {
+		t.Errorf("organisator zonder e-mailadres in de feed:\n%s", feed)
+	}
+
+	// Elke afspraak in de feed is weer te importeren
+	blocks := strings.Split(feed, "BEGIN:VEVENT")[1:]
+	if len(blocks) != len(appointments) {
+		t.Fatalf("%d afspraken in de feed, verwacht %d", len(blocks), len(appointments))
+	}
+	for i, block := range blocks {
+		block = "BEGIN:VEVENT" + strings.Split(block, "END:VEVENT")[0] + "END:VEVENT"
+		got, err := parseEvent(calendar(strings.Split(strings.TrimSpace(block), "\r\n")...))
+		if err != nil {
+			t.Fatalf("parseEvent van afspraak %d gaf fout: %v", i, err)
+		}
+		want := appointments[i]
+		if got.UID != want.UID || got.Title != want.Title || got.Location != want.Location || got.AllDay != want.AllDay ||
+			!got.StartAt.Equal(want.StartAt) || !got.EndAt.Equal(want.EndAt) {
+			t.Errorf("afspraak %d = %+v, verwacht %+v", i, *got, want)
+		}
+	}
+
+	first, _ := parseEvent(calendar(strings.Split(strings.TrimSpace("BEGIN:VEVENT"+strings.Split(blocks[0], "END:VEVENT")[0]+"END:VEVENT"), "\r\n")...))
+	if first.Description != "Klant: Bakkerij Jansen\n\nNieuwe oven bespreken" {
+		t.Errorf("omschrijving = %q", first.Description)
+	}
+	if !reflect.DeepEqual(first.Attendees, []model.Attendee{{Name: "Piet Jansen", Email: "piet@jansen.nl"}}) {
+		t.Errorf("deelnemers = %+v", first.Attendees)
+	}
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"odomosml/internal/appointment/model"
+	"odomosml/internal/appointment/repository"
+	customerRepo "odomosml/internal/customer/repository"
+	userRepo "odomosml/internal/user/repository"
+	"odomosml/pkg/validation"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Grenzen aan de velden van een afspraak
+const (
+	maxTitleLength    = 200
+	maxLocationLength = 255
+	maxUIDLength      = 255
+	maxAttendees      = 100
+)
+
+// feedHistory is hoe ver terug de agendafeed afspraken bevat
+const feedHistory = 90 * 24 * time.Hour
+
+// uidDomain staat achter de UID van afspraken die in de API zijn aangemaakt
+const uidDomain = "odomosml"
+
+// ErrFeedNotFound betekent dat het token van een agendafeed niet (meer) bestaat
+var ErrFeedNotFound = errors.New("agendafeed niet gevonden")
+
+// AppointmentService definieert de interface voor afspraak service
+type AppointmentService interface {
+	GetAppointments(filter model.AppointmentFilter) ([]model.Appointment, int64, error)
+	GetAppointment(id string) (*model.Appointment, error)
+	CreateAppointment(appointment *model.Appointment, allowConflicts bool) (*model.Appointment, []model.Conflict, error)
+	UpdateAppointment(appointment *model.Appointment, allowConflicts bool) (*model.Appointment, []model.Conflict, error)
+	DeleteAppointment(id string) (map[string]interface{}, error)
+	ImportEvent(content []byte, organizerID uint, customerID *uint, allowConflicts bool) (*model.Appointment, bool, []model.Conflict, error)
+	CreateFeedToken(userID uint) (string, error)
+	RevokeFeedToken(userID uint) error
+	RenderFeed(token string, w io.Writer) error
+}
+
+// appointmentService implementeert de AppointmentService interface
+type appointmentService struct {
+	repo         repository.AppointmentRepository
+	customerRepo customerRepo.CustomerRepository
+	userRepo     userRepo.UserRepository
+}
+
+// NewAppointmentService maakt een nieuwe AppointmentService instantie
+func NewAppointmentService(repo repository.AppointmentRepository, customerRepo customerRepo.CustomerRepository, userRepo userRepo.UserRepository) AppointmentService {
+	return &appointmentService{
+		repo:         repo,
+		customerRepo: customerRepo,
+		userRepo:     userRepo,
+	}
+}
+
+// GetAppointments haalt afspraken op met filters
+func (s *appointmentService) GetAppointments(filter model.AppointmentFilter) ([]model.Appointment, int64, error) {
+	// Valideer paginering
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 10 // Default page size
+	}
+
+	return s.repo.FindAll(filter)
+}
+
+// GetAppointment haalt een afspraak op op basis van ID
+func (s *appointmentService) GetAppointment(id string) (*model.Appointment, error) {
+	return s.repo.FindByID(id)
+}
+
+// CreateAppointment maakt een nieuwe afspraak aan. Overlapt de afspraak met een andere afspraak van de
+// organisator of een deelnemer, dan is de fout repository.ErrConflict, tenzij allowConflicts; de
+// overlappende afspraken worden in beide gevallen teruggegeven.
+func (s *appointmentService) CreateAppointment(appointment *model.Appointment, allowConflicts bool) (*model.Appointment, []model.Conflict, error) {
+	appointment.ID = 0
+	appointment.Sequence = 0
+	if appointment.UID == "" {
+		uid, err := randomHex(16)
+		if err != nil {
+			return nil, nil, err
+		}
+		appointment.UID = uid + "@" + uidDomain
+	}
+
+	if err := s.validate(appointment); err != nil {
+		return nil, nil, err
+	}
+
+	return s.save(appointment, allowConflicts)
+}
+
+// UpdateAppointment werkt een bestaande afspraak bij; de UID blijft gelijk en SEQUENCE wordt opgehoogd,
+// zodat agenda-apps de wijziging oppikken
+func (s *appointmentService) UpdateAppointment(appointment *model.Appointment, allowConflicts bool) (*model.Appointment, []model.Conflict, error) {
+	existing, err := s.repo.FindByID(strconv.FormatUint(uint64(appointment.ID), 10))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if appointment.OrganizerID == 0 {
+		appointment.OrganizerID = existing.OrganizerID
+	}
+	appointment.UID = existing.UID
+	appointment.Sequence = existing.Sequence + 1
+	appointment.CreatedAt = existing.CreatedAt
+
+	if err := s.validate(appointment); err != nil {
+		return nil, nil, err
+	}
+
+	return s.save(appointment, allowConflicts)
+}
+
+// DeleteAppointment verwijdert een afspraak en retourneert de data voor audit logging
+func (s *appointmentService) DeleteAppointment(id string) (map[string]interface{}, error) {
+	appointment, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	appointmentData := appointment.ToAuditMap()
+
+	if err := s.repo.Delete(appointment.ID); err != nil {
+		return nil, err
+	}
+
+	return appointmentData, nil
+}
+
+// ImportEvent importeert één afspraak uit een .ics bestand met de gebruiker als organisator. Deelnemers
+// met het e-mailadres van een gebruiker worden aan die gebruiker gekoppeld. Is de afspraak (op UID) al
+// eerder geïmporteerd, dan wordt die bijgewerkt; de tweede waarde geeft aan of de afspraak nieuw is.
+func (s *appointmentService) ImportEvent(content []byte, organizerID uint, customerID *uint, allowConflicts bool) (*model.Appointment, bool, []model.Conflict, error) {
+	appointment, err := parseEvent(content)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	appointment.OrganizerID = organizerID
+	appointment.CustomerID = customerID
+
+	for i, attendee := range appointment.Attendees {
+		if user, err := s.userRepo.FindByEmail(attendee.Email); err == nil && user != nil && user.Active {
+			appointment.Attendees[i].UserID = &user.ID
+		}
+	}
+
+	if appointment.UID == "" {
+		saved, conflicts, err := s.CreateAppointment(appointment, allowConflicts)
+		return saved, true, conflicts, err
+	}
+
+	existing, err := s.repo.FindByUID(appointment.UID)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if existing == nil {
+		saved, conflicts, err := s.CreateAppointment(appointment, allowConflicts)
+		return saved, true, conflicts, err
+	}
+	if existing.OrganizerID != organizerID {
+		return nil, false, nil, validation.New("uid", "de afspraak is al door een andere gebruiker geïmporteerd")
+	}
+
+	if appointment.CustomerID == nil {
+		appointment.CustomerID = existing.CustomerID
+	}
+	appointment.ID = existing.ID
+	saved, conflicts, err := s.UpdateAppointment(appointment, allowConflicts)
+	return saved, false, conflicts, err
+}
+
+// CreateFeedToken maakt een nieuw geheim token voor de agendafeed van een gebruiker; een eerder token
+// werkt daarna niet meer. Alleen de hash wordt bewaard, het token is dus alleen nu te zien.
+func (s *appointmentService) CreateFeedToken(userID uint) (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repo.SaveToken(userID, hashToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeFeedToken trekt de agendafeed van een gebruiker in
+func (s *appointmentService) RevokeFeedToken(userID uint) error {
+	return s.repo.DeleteToken(userID)
+}
+
+// RenderFeed schrijft de afspraken van de gebruiker bij het token als iCalendar feed: de afspraken van
+// de afgelopen 90 dagen en alle toekomstige afspraken. Een inactieve gebruiker heeft geen feed.
+func (s *appointmentService) RenderFeed(token string, w io.Writer) error {
+	userID, err := s.repo.FindTokenUser(hashToken(token))
+	if err != nil {
+		return err
+	}
+	if userID == 0 {
+		return ErrFeedNotFound
+	}
+
+	user, err := s.userRepo.FindByID(strconv.FormatUint(uint64(userID), 10))
+	if err != nil || user == nil || !user.Active {
+		return ErrFeedNotFound
+	}
+
+	appointments, err := s.repo.FindForUser(userID, time.Now().Add(-feedHistory))
+	if err != nil {
+		return err
+	}
+
+	organizers := map[uint]string{user.ID: user.Email}
+	for _, appointment := range appointments {
+		if _, ok := organizers[appointment.OrganizerID]; ok {
+			continue
+		}
+		organizers[appointment.OrganizerID] = ""
+		if organizer, err := s.userRepo.FindByID(strconv.FormatUint(uint64(appointment.OrganizerID), 10)); err == nil && organizer != nil {
+			organizers[appointment.OrganizerID] = organizer.Email
+		}
+	}
+
+	return renderCalendar(w, "OdomosML afspraken "+user.Username, appointments, organizers)
+}
+
+// save slaat een gevalideerde afspraak op en haalt hem opnieuw op, met de naam van de klant
+func (s *appointmentService) save(appointment *model.Appointment, allowConflicts bool) (*model.Appointment, []model.Conflict, error) {
+	conflicts, err := s.repo.Save(appointment, allowConflicts)
+	if err != nil {
+		return nil, conflicts, err
+	}
+
+	saved, err := s.repo.FindByID(strconv.FormatUint(uint64(appointment.ID), 10))
+	if err != nil {
+		return nil, nil, err
+	}
+	return saved, conflicts, nil
+}
+
+// validate controleert en normaliseert de velden van een afspraak. Bij een afspraak van een hele dag
+// vallen begin en einde op middernacht, en eindigt de afspraak op zijn vroegst de dag na het begin.
+func (s *appointmentService) validate(appointment *model.Appointment) error {
+	var problems validation.Errors
+
+	appointment.Title = strings.TrimSpace(appointment.Title)
+	if appointment.Title == "" {
+		problems = append(problems, validation.FieldError{Field: "title", Message: "titel is verplicht"})
+	} else if utf8.RuneCountInString(appointment.Title) > maxTitleLength {
+		problems = append(problems, validation.FieldError{Field: "title", Message: fmt.Sprintf("titel mag maximaal %d tekens bevatten", maxTitleLength)})
+	}
+	appointment.Description = strings.TrimSpace(appointment.Description)
+
+	appointment.Location = strings.TrimSpace(appointment.Location)
+	if utf8.RuneCountInString(appointment.Location) > maxLocationLength {
+		problems = append(problems, validation.FieldError{Field: "location", Message: fmt.Sprintf("locatie mag maximaal %d tekens bevatten", maxLocationLength)})
+	}
+
+	if len(appointment.UID) > maxUIDLength {
+		problems = append(problems, validation.FieldError{Field: "uid", Message: fmt.Sprintf("UID mag maximaal %d tekens bevatten", maxUIDLength)})
+	}
+
+	if appointment.AllDay {
+		appointment.StartAt = startOfDay(appointment.StartAt)
+		appointment.EndAt = startOfDay(appointment.EndAt)
+		if !appointment.EndAt.After(appointment.StartAt) {
+			appointment.EndAt = appointment.StartAt.AddDate(0, 0, 1)
+		}
+	}
+	switch {
+	case appointment.StartAt.IsZero():
+		problems = append(problems, validation.FieldError{Field: "start_at", Message: "begin is verplicht"})
+	case appointment.EndAt.IsZero():
+		problems = append(problems, validation.FieldError{Field: "end_at", Message: "einde is verplicht"})
+	case appointment.EndAt.Before(appointment.StartAt):
+		problems = append(problems, validation.FieldError{Field: "end_at", Message: "einde ligt voor het begin"})
+	}
+
+	if appointment.CustomerID != nil {
+		if customer, err := s.customerRepo.FindByID(strconv.FormatUint(uint64(*appointment.CustomerID), 10)); err != nil || customer == nil {
+			problems = append(problems, validation.FieldError{Field: "customer_id", Message: fmt.Sprintf("klant %d bestaat niet", *appointment.CustomerID)})
+		}
+	}
+
+	organizer, err := s.userRepo.FindByID(strconv.FormatUint(uint64(appointment.OrganizerID), 10))
+	if err != nil || organizer == nil || !organizer.Active {
+		problems = append(problems, validation.FieldError{Field: "organizer_id", Message: fmt.Sprintf("organisator %d is geen actieve gebruiker", appointment.OrganizerID)})
+	} else {
+		appointment.OrganizerName = organizer.Username
+	}
+
+	attendees, attendeeProblems := s.validateAttendees(appointment.Attendees, appointment.OrganizerID)
+	appointment.Attendees = attendees
+	problems = append(problems, attendeeProblems...)
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// validateAttendees controleert de deelnemers en vult naam en e-mailadres van gebruikers aan. Dubbele
+// deelnemers en de organisator zelf worden weggelaten.
+func (s *appointmentService) validateAttendees(attendees []model.Attendee, organizerID uint) ([]model.Attendee, validation.Errors) {
+	var problems validation.Errors
+	if len(attendees) > maxAttendees {
+		return nil, validation.Errors{{Field: "attendees", Message: fmt.Sprintf("een afspraak kan maximaal %d deelnemers hebben", maxAttendees)}}
+	}
+
+	result := make([]model.Attendee, 0, len(attendees))
+	seenUsers := map[uint]bool{organizerID: true}
+	seenEmails := make(map[string]bool)
+
+	for i, attendee := range attendees {
+		field := fmt.Sprintf("attendees[%d]", i)
+		attendee.Name = strings.TrimSpace(attendee.Name)
+		attendee.Email = strings.ToLower(strings.TrimSpace(attendee.Email))
+
+		if attendee.UserID != nil {
+			user, err := s.userRepo.FindByID(strconv.FormatUint(uint64(*attendee.UserID), 10))
+			if err != nil || user == nil || !user.Active {
+				problems = append(problems, validation.FieldError{Field: field + ".user_id", Message: fmt.Sprintf("gebruiker %d is geen actieve gebruiker", *attendee.UserID)})
+				continue
+			}
+			if seenUsers[user.ID] {
+				continue
+			}
+			seenUsers[user.ID] = true
+			attendee.Name = user.Username
+			attendee.Email = strings.ToLower(user.Email)
+		} else {
+			if attendee.Email == "" {
+				problems = append(problems, validation.FieldError{Field: field, Message: "deelnemer heeft een gebruiker of een e-mailadres nodig"})
+				continue
+			}
+			if _, err := mail.ParseAddress(attendee.Email); err != nil {
+				problems = append(problems, validation.FieldError{Field: field + ".email", Message: fmt.Sprintf("'%s' is geen geldig e-mailadres", attendee.Email)})
+				continue
+			}
+		}
+
+		if attendee.Email != "" {
+			if seenEmails[attendee.Email] {
+				continue
+			}
+			seenEmails[attendee.Email] = true
+		}
+		result = append(result, attendee)
+	}
+
+	return result, problems
+}
+
+// startOfDay geeft middernacht aan het begin van de dag, in de lokale tijdzone
+func startOfDay(t time.Time) time.Time {
+	local := t.In(time.Local)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// randomHex geeft n willekeurige bytes als hexadecimale tekst
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken geeft de SHA-256 hash van een token van een agendafeed
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"odomosml/internal/appointment/model"
+	"odomosml/internal/appointment/repository"
+	customerModel "odomosml/internal/customer/model"
+	customerRepo "odomosml/internal/customer/repository"
+	userModel "odomosml/internal/user/model"
+	userRepo "odomosml/internal/user/repository"
+	"odomosml/pkg/validation"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeAppointmentRepository struct {
+	repository.AppointmentRepository
+	appointments map[uint]model.Appointment
+	conflicts    []model.Conflict
+	tokens       map[string]uint
+}
+
+func newFakeAppointmentRepository() *fakeAppointmentRepository {
+	return &fakeAppointmentRepository{appointments: make(map[uint]model.Appointment), tokens: make(map[string]uint)}
+}
+
+func (r *fakeAppointmentRepository) FindByID(id string) (*model.Appointment, error) {
+	n, _ := strconv.ParseUint(id, 10, 64)
+	appointment, ok := r.appointments[uint(n)]
+	if !ok {
+		return nil, errors.New("afspraak niet gevonden")
+	}
+	return &appointment, nil
+}
+
+func (r *fakeAppointmentRepository) FindByUID(uid string) (*model.Appointment, error) {
+	for _, appointment := range r.appointments {
+		if appointment.UID == uid {
+			return &appointment, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAppointmentRepository) Save(appointment *model.Appointment, allowConflicts bool) ([]model.Conflict, error) {
+	if len(r.conflicts) > 0 && !allowConflicts {
+		return r.conflicts, repository.ErrConflict
+	}
+	if appointment.ID == 0 {
+		appointment.ID = uint(len(r.appointments) + 1)
+	}
+	r.appointments[appointment.ID] = *appointment
+	return r.conflicts, nil
+}
+
+func (r *fakeAppointmentRepository) FindForUser(userID uint, from time.Time) ([]model.Appointment, error) {
+	var result []model.Appointment
+	for _, appointment := range r.appointments {
+		for _, id := range appointment.UserIDs() {
+			if id == userID && appointment.EndAt.After(from) {
+				result = append(result, appointment)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeAppointmentRepository) SaveToken(userID uint, tokenHash string) error {
+	for hash, id := range r.tokens {
+		if id == userID {
+			delete(r.tokens, hash)
+		}
+	}
+	r.tokens[tokenHash] = userID
+	return nil
+}
+
+func (r *fakeAppointmentRepository) FindTokenUser(tokenHash string) (uint, error) {
+	return r.tokens[tokenHash], nil
+}
+
+type fakeUserRepository struct {
+	userRepo.UserRepository
+	users []userModel.User
+}
+
+func (r *fakeUserRepository) FindByID(id string) (*userModel.User, error) {
+	for _, user := range r.users {
+		if strconv.FormatUint(uint64(user.ID), 10) == id {
+			return &user, nil
+		}
+	}
+	return nil, errors.New("gebruiker niet gevonden")
+}
+
+func (r *fakeUserRepository) FindByEmail(email string) (*userModel.User, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, errors.New("gebruiker niet gevonden")
+}
+
+type fakeCustomerRepository struct {
+	customerRepo.CustomerRepository
+}
+
+func (fakeCustomerRepository) FindByID(id string) (*customerModel.Customer, error) {
+	if id != "5" {
+		return nil, errors.New("klant niet gevonden")
+	}
+	return &customerModel.Customer{ID: 5, Name: "Bakkerij Jansen"}, nil
+}
+
+func newTestService() (*appointmentService, *fakeAppointmentRepository) {
+	repo := newFakeAppointmentRepository()
+	users := &fakeUserRepository{users: []userModel.User{
+		{ID: 1, Username: "johndoe", Email: "john@example.com", Active: true},
+		{ID: 2, Username: "janedoe", Email: "Jane@Example.com", Active: true},
+		{ID: 3, Username: "oud", Email: "oud@example.com"},
+	}}
+	return &appointmentService{repo: repo, customerRepo: fakeCustomerRepository{}, userRepo: users}, repo
+}
+
+func TestValidateAppointment(t *testing.T) {
+	start := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	customerID, unknownCustomer := uint(5), uint(9)
+	jane, inactive := uint(2), uint(3)
+	organizer := uint(1)
+
+	tests := []struct {
+		name          string
+		appointment   model.Appointment
+		wantFields    []string
+		wantAttendees []model.Attendee
+	}{
+		{
+			name:        "geldig",
+			appointment: model.Appointment{Title: " Bezoek ", StartAt: start, EndAt: end, CustomerID: &customerID, OrganizerID: 1},
+		},
+		{
+			name:        "titel van 200 tekens met accenten",
+			appointment: model.Appointment{Title: strings.Repeat("é", 200), StartAt: start, EndAt: end, OrganizerID: 1},
+		},
+		{
+			name:        "even lang als het begin",
+			appointment: model.Appointment{Title: "Bellen", StartAt: start, EndAt: start, OrganizerID: 1},
+		},
+		{
+			name:        "ontbrekende velden",
+			appointment: model.Appointment{Title: "  ", OrganizerID: 1},
+			wantFields:  []string{"title", "start_at"},
+		},
+		{
+			name:        "te lange titel en locatie",
+			appointment: model.Appointment{Title: strings.Repeat("é", 201), Location: strings.Repeat("a", 256), StartAt: start, EndAt: end, OrganizerID: 1},
+			wantFields:  []string{"title", "location"},
+		},
+		{
+			name:        "zonder einde",
+			appointment: model.Appointment{Title: "Bezoek", StartAt: start, OrganizerID: 1},
+			wantFields:  []string{"end_at"},
+		},
+		{
+			name:        "einde voor het begin",
+			appointment: model.Appointment{Title: "Bezoek", StartAt: end, EndAt: start, OrganizerID: 1},
+			wantFields:  []string{"end_at"},
+		},
+		{
+			name:        "onbekende klant en inactieve organisator",
+			appointment: model.Appointment{Title: "Bezoek", StartAt: start, EndAt: end, CustomerID: &unknownCustomer, OrganizerID: 3},
+			wantFields:  []string{"customer_id", "organizer_id"},
+		},
+		{
+			name: "dubbele deelnemers en de organisator vallen weg",
+			appointment: model.Appointment{Title: "Overleg", StartAt: start, EndAt: end, OrganizerID: 1, Attendees: []model.Attendee{
+				{UserID: &jane}, {UserID: &organizer}, {UserID: &jane}, {Email: "JANE@example.com "}, {Name: " Piet ", Email: "Piet@Jansen.nl"}, {Email: "piet@jansen.nl"},
+			}},
+			wantAttendees: []model.Attendee{{UserID: &jane, Name: "janedoe", Email: "jane@example.com"}, {Name: "Piet", Email: "piet@jansen.nl"}},
+		},
+		{
+			name: "ongeldige deelnemers",
+			appointment: model.Appointment{Title: "Overleg", StartAt: start, EndAt: end, OrganizerID: 1, Attendees: []model.Attendee{
+				{UserID: &inactive}, {Name: "Zonder adres"}, {Email: "geen-adres"},
+			}},
+			wantFields: []string{"attendees[0].user_id", "attendees[1]", "attendees[2].email"},
+		},
+		{
+			name:        "te veel deelnemers",
+			appointment: model.Appointment{Title: "Overleg", StartAt: start, EndAt: end, OrganizerID: 1, Attendees: make([]model.Attendee, maxAttendees+1)},
+			wantFields:  []string{"attendees"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService()
+			appointment := tt.appointment
+			err := service.validate(&appointment)
+
+			var got []string
+			if fields, ok := validation.Fields(err); ok {
+				for _, field := range fields {
+					got = append(got, field.Field)
+				}
+			} else if err != nil {
+				t.Fatalf("validate gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("foute velden %v, verwacht %v (%v)", got, tt.wantFields, err)
+			}
+			if tt.wantAttendees != nil && !reflect.DeepEqual(appointment.Attendees, tt.wantAttendees) {
+				t.Errorf("deelnemers = %+v, verwacht %+v", appointment.Attendees, tt.wantAttendees)
+			}
+			if err == nil && (appointment.Title != strings.TrimSpace(tt.appointment.Title) || appointment.OrganizerName != "johndoe") {
+				t.Errorf("titel %q en organisator %q niet bijgewerkt", appointment.Title, appointment.OrganizerName)
+			}
+		})
+	}
+}
+
+func TestValidateAllDay(t *testing.T) {
+	service, _ := newTestService()
+	day := time.Date(2025, 3, 14, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		wantEnd    time.Time
+	}{
+		{"tijden vallen weg", day.Add(9 * time.Hour), day.AddDate(0, 0, 2).Add(17 * time.Hour), day.AddDate(0, 0, 2)},
+		{"einde op dezelfde dag", day.Add(9 * time.Hour), day.Add(17 * time.Hour), day.AddDate(0, 0, 1)},
+		{"zonder einde", day, time.Time{}, day.AddDate(0, 0, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appointment := model.Appointment{Title: "Beurs", AllDay: true, StartAt: tt.start, EndAt: tt.end, OrganizerID: 1}
+			if err := service.validate(&appointment); err != nil {
+				t.Fatalf("validate gaf fout: %v", err)
+			}
+			if !appointment.StartAt.Equal(day) || !appointment.EndAt.Equal(tt.wantEnd) {
+				t.Errorf("van %v tot %v, verwacht van %v tot %v", appointment.StartAt, appointment.EndAt, day, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestSaveAppointmentConflicts(t *testing.T) {
+	service, repo := newTestService()
+	start := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	repo.conflicts = []model.Conflict{{UserID: 1, Username: "johndoe", AppointmentID: 7, Title: "Kwartaaloverleg"}}
+
+	appointment := &model.Appointment{Title: "Bezoek", StartAt: start, EndAt: start.Add(time.Hour), OrganizerID: 1}
+	saved, conflicts, err := service.CreateAppointment(appointment, false)
+	if !errors.Is(err, repository.ErrConflict) || saved != nil || !reflect.DeepEqual(conflicts, repo.conflicts) {
+		t.Fatalf("CreateAppointment met overlap gaf %v, %v, %v", saved, conflicts, err)
+	}
+	if len(repo.appointments) != 0 {
+		t.Error("afspraak met overlap toch opgeslagen")
+	}
+
+	saved, conflicts, err = service.CreateAppointment(appointment, true)
+	if err != nil {
+		t.Fatalf("CreateAppointment met toegestane overlap gaf fout: %v", err)
+	}
+	if !reflect.DeepEqual(conflicts, repo.conflicts) {
+		t.Errorf("overlap = %+v, verwacht %+v", conflicts, repo.conflicts)
+	}
+	if !strings.HasSuffix(saved.UID, "@"+uidDomain) || len(saved.UID) != 32+len("@"+uidDomain) || saved.Sequence != 0 {
+		t.Errorf("nieuwe afspraak met UID %q en SEQUENCE %d", saved.UID, saved.Sequence)
+	}
+
+	// Bij wijzigen blijven UID en organisator gelijk en gaat SEQUENCE omhoog
+	repo.conflicts = nil
+	update := &model.Appointment{ID: saved.ID, UID: "ander@example.com", Title: "Bezoek verzet", StartAt: start.Add(time.Hour), EndAt: start.Add(2 * time.Hour)}
+	updated, _, err := service.UpdateAppointment(update, false)
+	if err != nil {
+		t.Fatalf("UpdateAppointment gaf fout: %v", err)
+	}
+	if updated.UID != saved.UID || updated.OrganizerID != 1 || updated.Sequence != 1 || updated.Title != "Bezoek verzet" {
+		t.Errorf("gewijzigde afspraak = %+v", *updated)
+	}
+}
+
+func TestImportEvent(t *testing.T) {
+	service, repo := newTestService()
+	content := func(summary string) []byte {
+		return calendar(event("UID:abc@example.com", "SUMMARY:"+summary, "DTSTART:20250314T100000Z", "DTEND:20250314T110000Z",
+			"ATTENDEE;CN=Jane:mailto:jane@example.com", "ATTENDEE;CN=Piet:mailto:piet@jansen.nl")...)
+	}
+	customerID := uint(5)
+
+	created, isNew, _, err := service.ImportEvent(content("Bezoek"), 1, &customerID, false)
+	if err != nil {
+		t.Fatalf("ImportEvent gaf fout: %v", err)
+	}
+	if !isNew || created.UID != "abc@example.com" || created.OrganizerID != 1 {
+		t.Errorf("geïmporteerde afspraak = %+v, nieuw: %v", *created, isNew)
+	}
+	jane := uint(2)
+	wantAttendees := []model.Attendee{{UserID: &jane, Name: "janedoe", Email: "jane@example.com"}, {Name: "Piet", Email: "piet@jansen.nl"}}
+	if !reflect.DeepEqual(created.Attendees, wantAttendees) {
+		t.Errorf("deelnemers = %+v, verwacht %+v", created.Attendees, wantAttendees)
+	}
+
+	// Opnieuw importeren werkt dezelfde afspraak bij en houdt de klant
+	updated, isNew, _, err := service.ImportEvent(content("Bezoek verzet"), 1, nil, false)
+	if err != nil {
+		t.Fatalf("ImportEvent van een bijgewerkte afspraak gaf fout: %v", err)
+	}
+	if isNew || updated.ID != created.ID || updated.Title != "Bezoek verzet" || updated.Sequence != 1 ||
+		updated.CustomerID == nil || *updated.CustomerID != customerID || len(repo.appointments) != 1 {
+		t.Errorf("bijgewerkte afspraak = %+v, nieuw: %v", *updated, isNew)
+	}
+
+	if _, _, _, err := service.ImportEvent(content("Bezoek"), 2, nil, false); err == nil || err.Error() != "de afspraak is al door een andere gebruiker geïmporteerd" {
+		t.Errorf("ImportEvent door een andere gebruiker gaf %v", err)
+	}
+}
+
+func TestRenderFeed(t *testing.T) {
+	service, repo := newTestService()
+	now := time.Now().UTC().Truncate(time.Second)
+	john := uint(1)
+	repo.appointments[1] = model.Appointment{ID: 1, UID: "recent@odomosml", Title: "Recent", StartAt: now.Add(-time.Hour), EndAt: now, OrganizerID: 2,
+		Attendees: []model.Attendee{{UserID: &john, Email: "john@example.com"}}}
+	repo.appointments[2] = model.Appointment{ID: 2, UID: "oud@odomosml", Title: "Oud", StartAt: now.Add(-100 * 24 * time.Hour), EndAt: now.Add(-99 * 24 * time.Hour), OrganizerID: 1}
+	repo.appointments[3] = model.Appointment{ID: 3, UID: "ander@odomosml", Title: "Ander", StartAt: now, EndAt: now.Add(time.Hour), OrganizerID: 2}
+
+	token, err := service.CreateFeedToken(1)
+	if err != nil {
+		t.Fatalf("CreateFeedToken gaf fout: %v", err)
+	}
+	if _, ok := repo.tokens[token]; ok || len(token) != 64 {
+		t.Errorf("token %q onversleuteld opgeslagen of verkeerde lengte", token)
+	}
+
+	var buf bytes.Buffer
+	if err := service.RenderFeed(token, &buf); err != nil {
+		t.Fatalf("RenderFeed gaf fout: %v", err)
+	}
+	feed := buf.String()
+	if !strings.Contains(feed, "UID:recent@odomosml") || strings.Contains(feed, "UID:oud@odomosml") || strings.Contains(feed, "UID:ander@odomosml") {
+		t.Errorf("feed bevat de verkeerde afspraken:\n%s", feed)
+	}
+	if !strings.Contains(feed, "mailto:Jane@Example.com") {
+		t.Errorf("organisator van een ander ontbreekt in de feed:\n%s", feed)
+	}
+
+	// Een nieuw token maakt het oude ongeldig
+	if _, err := service.CreateFeedToken(1); err != nil {
+		t.Fatalf("CreateFeedToken gaf fout: %v", err)
+	}
+	if err := service.RenderFeed(token, &bytes.Buffer{}); !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("RenderFeed met een oud token gaf %v", err)
+	}
+
+	// Een inactieve gebruiker heeft geen feed
+	inactive, _ := service.CreateFeedToken(3)
+	if err := service.RenderFeed(inactive, &bytes.Buffer{}); !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("RenderFeed van een inactieve gebruiker gaf %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"odomosml/internal/attachment/model"
+	"odomosml/internal/attachment/service"
+	auditModel "odomosml/internal/audit/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formFieldFile is de naam van het multipart veld met het bestand
+const formFieldFile = "file"
+
+// multipartOverhead is de ruimte die naast het bestand voor multipart headers wordt toegestaan
+const multipartOverhead = 1 << 20
+
+// AttachmentHandler handles HTTP requests for customer attachments
+type AttachmentHandler struct {
+	service service.AttachmentService
+}
+
+// NewAttachmentHandler maakt een nieuwe AttachmentHandler instantie
+func NewAttachmentHandler(service service.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{
+		service: service,
+	}
+}
+
+// @Summary      Bijlagen van een klant ophalen
+// @Description  Haalt de bijlagen (contracten, offertes, ...) van een klant op, nieuwste eerst
+// @Tags         attachments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/{id}/bijlagen [get]
+func (h *AttachmentHandler) GetAll(c *gin.Context) {
+	attachments, err := h.service.GetAttachments(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    attachments,
+	})
+}
+
+// @Summary      Bijlage uploaden
+// @Description  Uploadt een bestand als bijlage bij een klant (multipart veld "file"). Identieke inhoud wordt niet dubbel opgeslagen; bestaat de bijlage al bij deze klant dan wordt die teruggegeven met duplicate=true.
+// @Tags         attachments
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        file formData file true "Bestand"
+// @Success      201  {object}  model.Attachment "Succesvol geüpload"
+// @Success      200  {object}  model.Attachment "Bijlage bestond al"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      413  {object}  map[string]string "Bestand te groot"
+// @Failure      415  {object}  map[string]string "Bestandstype niet toegestaan"
+// @Security     Bearer
+// @Router       /klanten/{id}/bijlagen [post]
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	// Het bestand wordt gestreamd in plaats van volledig in het geheugen geladen
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.service.MaxSize()+multipartOverhead)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: verwacht multipart/form-data",
+		})
+		return
+	}
+
+	var (
+		attachment *model.Attachment
+		duplicate  bool
+		found      bool
+	)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			h.uploadError(c, err)
+			return
+		}
+		if part.FormName() != formFieldFile || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		// De uploader is altijd de ingelogde gebruiker
+		userID, _ := c.Get("userID")
+		username, _ := c.Get("username")
+		upload := &model.Attachment{FileName: part.FileName()}
+		upload.UploadedByID, _ = userID.(uint)
+		upload.UploadedByName, _ = username.(string)
+
+		attachment, duplicate, err = h.service.Upload(c.Request.Context(), c.Param("id"), upload, part)
+		part.Close()
+		if err != nil {
+			h.uploadError(c, err)
+			return
+		}
+		found = true
+		break
+	}
+
+	if !found {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geen bestand gevonden in veld '" + formFieldFile + "'",
+		})
+		return
+	}
+
+	status := http.StatusCreated
+	if duplicate {
+		status = http.StatusOK
+	} else {
+		c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+			EntityType:  auditModel.EntityAttachment,
+			EntityID:    strconv.FormatUint(uint64(attachment.ID), 10),
+			Description: fmt.Sprintf("Bijlage '%s' (%d bytes) geüpload bij klant %d", attachment.FileName, attachment.Size, attachment.CustomerID),
+		})
+	}
+
+	c.JSON(status, gin.H{
+		"success":   true,
+		"data":      attachment,
+		"duplicate": duplicate,
+	})
+}
+
+// @Summary      Bijlage downloaden
+// @Description  Downloadt de inhoud van een bijlage. Range requests worden ondersteund.
+// @Tags         attachments
+// @Produce      octet-stream
+// @Param        id path string true "Klant ID"
+// @Param        attachmentId path string true "Bijlage ID"
+// @Param        Range header string false "Byte range, bijv. bytes=0-1023"
+// @Success      200  {file}  file "Inhoud van het bestand"
+// @Success      206  {file}  file "Gedeeltelijke inhoud"
+// @Failure      400  {object}  map[string]string "Ongeldig ID"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Bijlage niet gevonden"
+// @Security     Bearer
+// @Router       /klanten/{id}/bijlagen/{attachmentId} [get]
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	attachment, object, err := h.service.Open(c.Request.Context(), c.Param("id"), c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer object.Close()
+
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("ETag", `"`+attachment.Checksum+`"`)
+
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		EntityType:  auditModel.EntityAttachment,
+		EntityID:    strconv.FormatUint(uint64(attachment.ID), 10),
+		Description: fmt.Sprintf("Bijlage '%s' gedownload van klant %d", attachment.FileName, attachment.CustomerID),
+	})
+
+	// ServeContent handelt Range, If-Range en If-None-Match af
+	http.ServeContent(c.Writer, c.Request, attachment.FileName, attachment.CreatedAt, object)
+}
+
+// @Summary      Bijlage verwijderen
+// @Description  Verwijdert een bijlage van een klant. Het bestand wordt uit de opslag verwijderd zodra geen bijlage er meer naar verwijst.
+// @Tags         attachments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        attachmentId path string true "Bijlage ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      400  {object}  map[string]string "Ongeldig ID"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/{id}/bijlagen/{attachmentId} [delete]
+func (h *AttachmentHandler) Delete(c *gin.Context) {
+	attachment, err := h.service.DeleteAttachment(c.Request.Context(), c.Param("id"), c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	oldData, _ := json.Marshal(attachment)
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		EntityType:  auditModel.EntityAttachment,
+		EntityID:    strconv.FormatUint(uint64(attachment.ID), 10),
+		Description: fmt.Sprintf("Bijlage '%s' verwijderd bij klant %d", attachment.FileName, attachment.CustomerID),
+		OldData:     string(oldData),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Bijlage succesvol verwijderd",
+	})
+}
+
+// uploadError vertaalt een upload fout naar de juiste HTTP status
+func (h *AttachmentHandler) uploadError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		status = http.StatusRequestEntityTooLarge
+		err = fmt.Errorf("%w: maximaal %d MB", service.ErrFileTooLarge, h.service.MaxSize()>>20)
+	case errors.Is(err, service.ErrFileTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrFileTypeNotAllowed):
+		status = http.StatusUnsupportedMediaType
+	}
+
+	c.JSON(status, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
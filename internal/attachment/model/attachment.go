@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Attachment representeert een bestand (contract, offerte, ...) dat aan een klant is gekoppeld.
+// Bestanden worden in de opslag op checksum bewaard, zodat identieke bestanden maar één keer worden opgeslagen.
+// @Description Een bijlage bij een klant
+type Attachment struct {
+	ID             uint      `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	CustomerID     uint      `json:"customer_id" gorm:"not null;index" example:"1" swaggertype:"integer"`
+	FileName       string    `json:"file_name" gorm:"size:255;not null" example:"contract.pdf" swaggertype:"string"`
+	ContentType    string    `json:"content_type" gorm:"size:100;not null" example:"application/pdf" swaggertype:"string"`
+	Size           int64     `json:"size" gorm:"not null" example:"102400" swaggertype:"integer"`
+	Checksum       string    `json:"checksum" gorm:"size:64;not null;index" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" swaggertype:"string"`
+	StorageKey     string    `json:"-" gorm:"size:255;not null;index"`
+	UploadedByID   uint      `json:"uploaded_by_id" gorm:"not null" example:"1" swaggertype:"integer"`
+	UploadedByName string    `json:"uploaded_by_name" gorm:"size:100" example:"johndoe" swaggertype:"string"`
+	CreatedAt      time.Time `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Attachment) TableName() string {
+	return "attachments"
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/attachment/model"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// storageLockSpace is de eerste sleutel van de advisory locks op een bestand in de opslag
+const storageLockSpace = 4601
+
+// AttachmentRepository definieert de interface voor attachment repository
+type AttachmentRepository interface {
+	FindByCustomer(customerID uint) ([]model.Attachment, error)
+	FindByID(id string) (*model.Attachment, error)
+	FindByCustomerAndChecksum(customerID uint, checksum string) (*model.Attachment, error)
+	Create(attachment *model.Attachment, store func() error) (*model.Attachment, error)
+	Delete(id uint) error
+	ReleaseStorageKey(storageKey string, release func()) error
+}
+
+// attachmentRepository implementeert de AttachmentRepository interface
+type attachmentRepository struct {
+	db *gorm.DB
+}
+
+// NewAttachmentRepository maakt een nieuwe AttachmentRepository instantie
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &attachmentRepository{
+		db: db,
+	}
+}
+
+// FindByCustomer haalt alle bijlagen van een klant op, nieuwste eerst
+func (r *attachmentRepository) FindByCustomer(customerID uint) ([]model.Attachment, error) {
+	var attachments []model.Attachment
+	if err := r.db.Where("customer_id = ?", customerID).Order("created_at DESC, id DESC").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// FindByID haalt een bijlage op op basis van ID
+func (r *attachmentRepository) FindByID(id string) (*model.Attachment, error) {
+	var attachment model.Attachment
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&attachment, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bijlage niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// FindByCustomerAndChecksum zoekt een bijlage met dezelfde inhoud bij dezelfde klant.
+// Retourneert nil zonder fout als die er niet is.
+func (r *attachmentRepository) FindByCustomerAndChecksum(customerID uint, checksum string) (*model.Attachment, error) {
+	var attachment model.Attachment
+
+	err := r.db.Where("customer_id = ? AND checksum = ?", customerID, checksum).First(&attachment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// Create maakt een nieuwe bijlage aan. store zet eerst het bestand in de opslag; dat gebeurt onder een
+// lock op de storage key, zodat ReleaseStorageKey het bestand niet kan verwijderen tussen het opslaan en
+// het aanmaken van de bijlage die ernaar verwijst.
+func (r *attachmentRepository) Create(attachment *model.Attachment, store func() error) (*model.Attachment, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockStorageKey(tx, attachment.StorageKey); err != nil {
+			return err
+		}
+		if err := store(); err != nil {
+			return err
+		}
+		return tx.Create(attachment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// Delete verwijdert een bijlage
+func (r *attachmentRepository) Delete(id uint) error {
+	return r.db.Delete(&model.Attachment{}, id).Error
+}
+
+// ReleaseStorageKey roept release aan als geen bijlage meer naar een bestand in de opslag verwijst.
+// Tellen en verwijderen gebeuren onder dezelfde lock als in Create, zodat een gelijktijdige upload van
+// dezelfde inhoud het bestand daarna opnieuw opslaat in plaats van naar een verwijderd bestand te verwijzen.
+func (r *attachmentRepository) ReleaseStorageKey(storageKey string, release func()) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockStorageKey(tx, storageKey); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&model.Attachment{}).Where("storage_key = ?", storageKey).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			release()
+		}
+		return nil
+	})
+}
+
+// lockStorageKey neemt tot het einde van de transactie een advisory lock op een bestand in de opslag
+func lockStorageKey(tx *gorm.DB, storageKey string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", storageLockSpace, storageKey).Error
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"odomosml/internal/attachment/model"
+	"odomosml/internal/attachment/repository"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/pkg/storage"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxFileNameLength is de maximale lengte van een bestandsnaam
+const maxFileNameLength = 255
+
+// Fouten die de handler naar een specifieke HTTP status vertaalt
+var (
+	ErrFileTooLarge       = errors.New("bestand is te groot")
+	ErrFileTypeNotAllowed = errors.New("bestandstype is niet toegestaan")
+)
+
+// oleSignature is de header van oude Office bestanden (doc/xls)
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// refinableTypes zijn gesnifte types die te algemeen zijn om op te vertrouwen;
+// voor deze types wordt de extensie gebruikt om het werkelijke type te bepalen
+// (docx/xlsx zijn zip bestanden, doc/xls worden niet herkend, csv is platte tekst)
+var refinableTypes = map[string]bool{
+	"application/zip":          true,
+	"application/octet-stream": true,
+	"text/plain":               true,
+}
+
+// extensionTypes koppelt extensies aan het type dat ze mogen opleveren na het sniffen
+var extensionTypes = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".doc":  "application/msword",
+	".xls":  "application/vnd.ms-excel",
+	".csv":  "text/csv",
+}
+
+// AttachmentService definieert de interface voor attachment service
+type AttachmentService interface {
+	GetAttachments(customerID string) ([]model.Attachment, error)
+	Upload(ctx context.Context, customerID string, attachment *model.Attachment, content io.Reader) (*model.Attachment, bool, error)
+	Open(ctx context.Context, customerID, attachmentID string) (*model.Attachment, storage.Object, error)
+	DeleteAttachment(ctx context.Context, customerID, attachmentID string) (*model.Attachment, error)
+	MaxSize() int64
+}
+
+// attachmentService implementeert de AttachmentService interface
+type attachmentService struct {
+	repo         repository.AttachmentRepository
+	customerRepo customerRepo.CustomerRepository
+	storage      storage.Storage
+	maxSize      int64
+	allowedTypes map[string]bool
+}
+
+// NewAttachmentService maakt een nieuwe AttachmentService instantie
+func NewAttachmentService(repo repository.AttachmentRepository, customerRepo customerRepo.CustomerRepository, store storage.Storage, maxSizeMB int, allowedTypes []string) AttachmentService {
+	allowed := make(map[string]bool, len(allowedTypes))
+	for _, contentType := range allowedTypes {
+		allowed[strings.ToLower(strings.TrimSpace(contentType))] = true
+	}
+
+	return &attachmentService{
+		repo:         repo,
+		customerRepo: customerRepo,
+		storage:      store,
+		maxSize:      int64(maxSizeMB) << 20,
+		allowedTypes: allowed,
+	}
+}
+
+// MaxSize geeft de maximale bestandsgrootte in bytes terug
+func (s *attachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// GetAttachments haalt de bijlagen van een klant op
+func (s *attachmentService) GetAttachments(customerID string) ([]model.Attachment, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindByCustomer(customer.ID)
+}
+
+// Upload slaat een bestand op als bijlage van een klant.
+// Het bestand wordt eerst naar een tijdelijk bestand geschreven om grootte, checksum en type te bepalen.
+// Heeft de klant al een bijlage met dezelfde inhoud, dan wordt die teruggegeven met duplicate=true.
+func (s *attachmentService) Upload(ctx context.Context, customerID string, attachment *model.Attachment, content io.Reader) (*model.Attachment, bool, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	attachment.FileName = cleanFileName(attachment.FileName)
+	if attachment.FileName == "" {
+		return nil, false, errors.New("bestandsnaam is verplicht")
+	}
+
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	// Lees maximaal één byte meer dan toegestaan om een te groot bestand te herkennen
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(content, s.maxSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if size > s.maxSize {
+		return nil, false, fmt.Errorf("%w: maximaal %d MB", ErrFileTooLarge, s.maxSize>>20)
+	}
+	if size == 0 {
+		return nil, false, errors.New("bestand is leeg")
+	}
+
+	contentType, err := detectContentType(tmp, attachment.FileName)
+	if err != nil {
+		return nil, false, err
+	}
+	if !s.allowedTypes[contentType] {
+		return nil, false, fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, contentType)
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	existing, err := s.repo.FindByCustomerAndChecksum(customer.ID, checksum)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, true, nil
+	}
+
+	attachment.ID = 0
+	attachment.CustomerID = customer.ID
+	attachment.ContentType = contentType
+	attachment.Size = size
+	attachment.Checksum = checksum
+	attachment.StorageKey = checksum[:2] + "/" + checksum
+
+	// Identieke inhoud wordt maar één keer opgeslagen, ook als deze bij andere klanten hoort
+	created, err := s.repo.Create(attachment, func() error {
+		exists, err := s.storage.Exists(ctx, attachment.StorageKey)
+		if err != nil || exists {
+			return err
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := s.storage.Put(ctx, attachment.StorageKey, tmp, size, contentType); err != nil {
+			return fmt.Errorf("kan bestand niet opslaan: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return created, false, nil
+}
+
+// Open opent de inhoud van een bijlage; de aanroeper moet het object sluiten
+func (s *attachmentService) Open(ctx context.Context, customerID, attachmentID string) (*model.Attachment, storage.Object, error) {
+	attachment, err := s.findForCustomer(customerID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	object, err := s.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, errors.New("bestand van bijlage ontbreekt in de opslag")
+		}
+		return nil, nil, err
+	}
+
+	return attachment, object, nil
+}
+
+// DeleteAttachment verwijdert een bijlage. Het bestand in de opslag wordt alleen
+// verwijderd als geen andere bijlage er meer naar verwijst.
+func (s *attachmentService) DeleteAttachment(ctx context.Context, customerID, attachmentID string) (*model.Attachment, error) {
+	attachment, err := s.findForCustomer(customerID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Delete(attachment.ID); err != nil {
+		return nil, err
+	}
+
+	// De bijlage is al weg; een achtergebleven bestand is geen reden om te falen
+	err = s.repo.ReleaseStorageKey(attachment.StorageKey, func() {
+		if err := s.storage.Delete(ctx, attachment.StorageKey); err != nil {
+			log.Printf("Kan bestand %s niet uit de opslag verwijderen: %v", attachment.StorageKey, err)
+		}
+	})
+	if err != nil {
+		log.Printf("Kan bestand %s niet vrijgeven: %v", attachment.StorageKey, err)
+	}
+
+	return attachment, nil
+}
+
+// findForCustomer haalt een bijlage op en controleert dat deze bij de klant hoort
+func (s *attachmentService) findForCustomer(customerID, attachmentID string) (*model.Attachment, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.repo.FindByID(attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if attachment.CustomerID != customer.ID {
+		return nil, errors.New("bijlage niet gevonden")
+	}
+
+	return attachment, nil
+}
+
+// detectContentType bepaalt het type op basis van de inhoud; de extensie wordt alleen
+// gebruikt om een te algemeen gesnift type te verfijnen, nooit om het te overschrijven
+func detectContentType(file io.ReadSeeker, fileName string) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	if refinableTypes[contentType] {
+		if extensionType, ok := extensionTypes[strings.ToLower(filepath.Ext(fileName))]; ok {
+			// Een zip is alleen een nieuw Office bestand, onbekende binaire inhoud
+			// alleen een oud Office bestand en platte tekst alleen csv
+			switch {
+			case contentType == "application/zip" && !strings.Contains(extensionType, "openxmlformats"):
+			case contentType == "application/octet-stream" && !bytes.HasPrefix(head[:n], oleSignature):
+			case contentType == "text/plain" && extensionType != "text/csv":
+			default:
+				contentType = extensionType
+			}
+		}
+	}
+
+	return contentType, nil
+}
+
+// cleanFileName haalt padinformatie en stuurtekens uit een bestandsnaam
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "." || name == "/" {
+		return ""
+	}
+	if len(name) > maxFileNameLength {
+		ext := filepath.Ext(name)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:maxFileNameLength-len(ext)], "") + ext
+	}
+	return name
+}
@@ -1,12 +1,15 @@
 package auditHttp
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 	"time"
 
 	"odomosml/internal/audit/model"
 	"odomosml/internal/audit/service"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,9 +24,32 @@ func NewAuditHandler(svc service.AuditService) *AuditHandler {
 
 // GetLogs haalt audit logs op met filters
 func (h *AuditHandler) GetLogs(c *gin.Context) {
+	page, err := pagination.FromQuery(c.Request.URL.Query(), "page", "pageSize", 10, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sort, err := sorting.Parse(c.Query("sort"), model.SortFields...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expression, err := filterExpr.Parse(c.Query("filter"))
+	if err != nil {
+		respondFilterError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	filter := model.AuditLogFilter{
-		Page:     parseIntParam(c.Query("page"), 1),
-		PageSize: parseIntParam(c.Query("pageSize"), 10),
+		Page:       page.Page,
+		PageSize:   page.Size,
+		After:      page.After,
+		Before:     page.Before,
+		Count:      page.Count,
+		Sort:       sort,
+		Expression: expression,
 	}
 
 	// Parse filters
@@ -60,34 +86,52 @@ func (h *AuditHandler) GetLogs(c *gin.Context) {
 	}
 
 	// Haal logs op
-	logs, total, err := h.service.GetAuditLogs(filter)
+	logs, info, err := h.service.GetAuditLogs(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFilterError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	// Bereken totaal aantal pagina's
-	totalPages := (int(total) + filter.PageSize - 1) / filter.PageSize
+	if link := pagination.Links(c.Request.URL, page, info, "page"); link != "" {
+		c.Header("Link", link)
+	}
 
 	// Return met pagination info
+	meta := gin.H{
+		"page_size":   filter.PageSize,
+		"total_items": info.Total,
+		"has_more":    info.HasMore,
+		"next_cursor": info.Next,
+	}
+	if page.Keyset() {
+		meta["prev_cursor"] = info.Prev
+	} else {
+		meta["current_page"] = filter.Page
+	}
+	if info.Total != nil {
+		// Bereken totaal aantal pagina's
+		meta["total_pages"] = (int(*info.Total) + filter.PageSize - 1) / filter.PageSize
+		meta["total_estimated"] = info.Estimated
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"data": logs,
-		"pagination": gin.H{
-			"current_page": filter.Page,
-			"page_size":    filter.PageSize,
-			"total_items":  total,
-			"total_pages":  totalPages,
-		},
+		"data":       logs,
+		"pagination": meta,
 	})
 }
 
-func parseIntParam(param string, defaultValue int) int {
-	if param == "" {
-		return defaultValue
-	}
-	value, err := strconv.Atoi(param)
-	if err != nil {
-		return defaultValue
+// respondFilterError stuurt een fout bij het ophalen van logs terug. Een fout in de filterexpressie
+// of een cursor van een andere sortering is een 400, met bij een filterfout de positie van het foute token.
+func respondFilterError(c *gin.Context, status int, err error) {
+	body := gin.H{"error": err.Error()}
+
+	var expressionErr *filterExpr.Error
+	if errors.As(err, &expressionErr) {
+		status = http.StatusBadRequest
+		body["position"] = expressionErr.Pos + 1
+	} else if errors.Is(err, pagination.ErrSortMismatch) {
+		status = http.StatusBadRequest
 	}
-	return value
+
+	c.JSON(status, body)
 }
@@ -1,6 +1,9 @@
 package model
 
 import (
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
 	"time"
 )
 
@@ -12,10 +15,23 @@ type ActionType string
 
 // Entity types
 const (
-	EntityUser     EntityType = "user"
-	EntityCustomer EntityType = "customer"
-	EntityAuth     EntityType = "auth"
-	EntityUnknown  EntityType = "unknown"
+	EntityUser        EntityType = "user"
+	EntityCustomer    EntityType = "customer"
+	EntityTag         EntityType = "tag"
+	EntityCustomField EntityType = "custom_field"
+	EntityActivity    EntityType = "activity"
+	EntityAttachment  EntityType = "attachment"
+	EntityImport      EntityType = "import"
+	EntityAuth        EntityType = "auth"
+	EntitySavedView   EntityType = "saved_view"
+	EntityStatus      EntityType = "customer_status"
+	EntityDeal        EntityType = "deal"
+	EntityQuote       EntityType = "quote"
+	EntityInvoice     EntityType = "invoice"
+	EntityProduct     EntityType = "product"
+	EntityTask        EntityType = "task"
+	EntityAppointment EntityType = "appointment"
+	EntityUnknown     EntityType = "unknown"
 )
 
 // Action types
@@ -27,6 +43,11 @@ const (
 	ActionOther  ActionType = "other"
 )
 
+// ContextKey is de gin context key waarmee een handler zelf een audit entry kan aanleveren.
+// De audit middleware vult gebruiker, status code en tijdstip aan en logt deze entry
+// in plaats van de automatisch opgebouwde entry.
+const ContextKey = "auditEntry"
+
 // AuditLog representeert een audit log entry
 type AuditLog struct {
 	ID          uint       `json:"id" gorm:"primaryKey"`
@@ -36,21 +57,52 @@ type AuditLog struct {
 	EntityType  EntityType `json:"entity_type" gorm:"size:50;index;not null"`
 	EntityID    string     `json:"entity_id" gorm:"size:50;index"`
 	Description string     `json:"description" gorm:"size:500"`
-	OldData     string     `json:"old_data" gorm:"type:text"`
-	NewData     string     `json:"new_data" gorm:"type:text"`
+	OldData     string     `json:"old_data" gorm:"type:text;serializer:encrypted"` // Versleuteld opgeslagen: bevat kopieën van persoonsgegevens
+	NewData     string     `json:"new_data" gorm:"type:text;serializer:encrypted"`
 	StatusCode  int        `json:"status_code" gorm:"default:200"`
 	CreatedAt   time.Time  `json:"created_at" gorm:"index;not null;default:CURRENT_TIMESTAMP"`
 }
 
 // AuditLogFilter definieert filters voor het ophalen van audit logs
 type AuditLogFilter struct {
-	UserID     uint       `json:"user_id" form:"user_id"`
-	ActionType ActionType `json:"action_type" form:"action_type"`
-	EntityType EntityType `json:"entity_type" form:"entity_type"`
-	StartDate  time.Time  `json:"start_date" form:"start_date"`
-	EndDate    time.Time  `json:"end_date" form:"end_date"`
-	Page       int        `json:"page" form:"page"`
-	PageSize   int        `json:"page_size" form:"page_size"`
+	UserID     uint               `json:"user_id" form:"user_id"`
+	ActionType ActionType         `json:"action_type" form:"action_type"`
+	EntityType EntityType         `json:"entity_type" form:"entity_type"`
+	StartDate  time.Time          `json:"start_date" form:"start_date"`
+	EndDate    time.Time          `json:"end_date" form:"end_date"`
+	Page       int                `json:"page" form:"page"`
+	PageSize   int                `json:"page_size" form:"page_size"`
+	After      *pagination.Cursor `json:"-" form:"-"` // Keyset paginering: de rijen na deze cursor
+	Before     *pagination.Cursor `json:"-" form:"-"` // Keyset paginering: de rijen voor deze cursor
+	Count      string             `json:"-" form:"-"` // pagination.CountExact, CountEstimate of CountNone
+	Sort       []sorting.Field    `json:"-" form:"-"` // Sorteervelden; leeg sorteert nieuwste eerst
+	Expression filterExpr.Node    `json:"-" form:"-"` // Filterexpressie uit de filter parameter
+}
+
+// SortFields zijn de velden waarop audit logs gesorteerd kunnen worden
+var SortFields = []string{"id", "created_at", "user_id", "username", "action_type", "entity_type", "entity_id", "status_code"}
+
+// SortValue geeft de waarde van een sorteerveld (zie SortFields), voor het maken van een cursor
+func (a *AuditLog) SortValue(field string) interface{} {
+	switch field {
+	case "id":
+		return a.ID
+	case "created_at":
+		return a.CreatedAt
+	case "user_id":
+		return a.UserID
+	case "username":
+		return a.Username
+	case "action_type":
+		return string(a.ActionType)
+	case "entity_type":
+		return string(a.EntityType)
+	case "entity_id":
+		return a.EntityID
+	case "status_code":
+		return a.StatusCode
+	}
+	return nil
 }
 
 // TableName specificeert de tabelnaam voor GORM
@@ -2,13 +2,18 @@ package repository
 
 import (
 	"odomosml/internal/audit/model"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
+	"odomosml/pkg/timeline"
 
 	"gorm.io/gorm"
 )
 
 type AuditRepository interface {
 	Create(log *model.AuditLog) error
-	FindAll(filter model.AuditLogFilter) ([]model.AuditLog, int64, error)
+	FindAll(filter model.AuditLogFilter) ([]model.AuditLog, pagination.Info, error)
+	FindEntityHistory(entityType model.EntityType, entityID string, boundary *timeline.Boundary, limit int) ([]model.AuditLog, error)
 }
 
 type auditRepository struct {
@@ -23,9 +28,8 @@ func (r *auditRepository) Create(log *model.AuditLog) error {
 	return r.db.Create(log).Error
 }
 
-func (r *auditRepository) FindAll(filter model.AuditLogFilter) ([]model.AuditLog, int64, error) {
+func (r *auditRepository) FindAll(filter model.AuditLogFilter) ([]model.AuditLog, pagination.Info, error) {
 	var logs []model.AuditLog
-	var total int64
 	query := r.db.Model(&model.AuditLog{})
 
 	if filter.UserID != 0 {
@@ -48,26 +52,76 @@ func (r *auditRepository) FindAll(filter model.AuditLogFilter) ([]model.AuditLog
 		query = query.Where("created_at <= ?", filter.EndDate)
 	}
 
-	// Get total count
-	err := query.Count(&total).Error
-	if err != nil {
-		return nil, 0, err
+	if filter.Expression != nil {
+		condition, vars, err := filterExpr.Compile(filter.Expression, auditFilterColumn)
+		if err != nil {
+			return nil, pagination.Info{}, err
+		}
+		query = query.Where(condition, vars...)
 	}
 
-	// Add pagination
-	if filter.Page > 0 && filter.PageSize > 0 {
-		offset := (filter.Page - 1) * filter.PageSize
-		query = query.Offset(offset).Limit(filter.PageSize)
+	// Get total count (of een schatting, of helemaal niet: COUNT(*) is traag op deze tabel)
+	total, estimated, err := pagination.Count(query, filter.Count)
+	if err != nil {
+		return nil, pagination.Info{}, err
 	}
 
-	// Add default sorting
-	query = query.Order("created_at DESC")
+	// Paginering en sortering, nieuwste eerst als standaard, met het ID als tiebreaker
+	sort := filter.Sort
+	if len(sort) == 0 {
+		sort = []sorting.Field{{Name: "created_at", Desc: true}, {Name: "id", Desc: true}}
+	}
+	request := pagination.Request{Page: filter.Page, Size: filter.PageSize, After: filter.After, Before: filter.Before}
+	column := sorting.Columns(auditColumns)
+	query, err = pagination.Apply(query, request, sort, column, "id")
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
 
 	// Execute the query
-	err = query.Find(&logs).Error
-	if err != nil {
-		return nil, 0, err
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, pagination.Info{}, err
+	}
+
+	logs, info := pagination.Window(logs, request, func(log *model.AuditLog) pagination.Cursor {
+		return pagination.NewCursor(sort, column, log.ID, log.SortValue)
+	})
+	info.Total, info.Estimated = total, estimated
+
+	return logs, info, nil
+}
+
+// auditColumns koppelt de sorteervelden aan hun kolom
+var auditColumns = map[string]sorting.Column{
+	"id":          {SQL: "id", Type: "bigint"},
+	"created_at":  {SQL: "created_at", Type: "timestamptz"},
+	"user_id":     {SQL: "user_id", Type: "bigint"},
+	"username":    {SQL: "username", Type: "text", Nullable: true},
+	"action_type": {SQL: "action_type", Type: "text"},
+	"entity_type": {SQL: "entity_type", Type: "text"},
+	"entity_id":   {SQL: "entity_id", Type: "text", Nullable: true},
+	"status_code": {SQL: "status_code", Type: "integer", Nullable: true},
+}
+
+// auditFilterColumn geeft de kolom van een veld in een filterexpressie: de sorteervelden en de omschrijving
+func auditFilterColumn(name string) (sorting.Column, bool) {
+	if name == "description" {
+		return sorting.Column{SQL: "description", Type: "text", Nullable: true}, true
+	}
+	column, ok := auditColumns[name]
+	return column, ok
+}
+
+// FindEntityHistory haalt de audit logs van een specifieke entiteit op die voor de grens vallen, nieuwste eerst
+func (r *auditRepository) FindEntityHistory(entityType model.EntityType, entityID string, boundary *timeline.Boundary, limit int) ([]model.AuditLog, error) {
+	var logs []model.AuditLog
+
+	query := r.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID)
+	query = boundary.Apply(query, "created_at", "id")
+
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
 	}
 
-	return logs, total, nil
+	return logs, nil
 }
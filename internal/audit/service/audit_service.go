@@ -3,11 +3,14 @@ package service
 import (
 	"odomosml/internal/audit/model"
 	"odomosml/internal/audit/repository"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/timeline"
 )
 
 // AuditService interface definieert de methodes voor audit logging
 type AuditService interface {
-	GetAuditLogs(filter model.AuditLogFilter) ([]model.AuditLog, int64, error)
+	GetAuditLogs(filter model.AuditLogFilter) ([]model.AuditLog, pagination.Info, error)
+	GetEntityHistory(entityType model.EntityType, entityID string, boundary *timeline.Boundary, limit int) ([]model.AuditLog, error)
 	Create(log *model.AuditLog) error
 }
 
@@ -24,7 +27,7 @@ func NewAuditService(repo repository.AuditRepository) AuditService {
 }
 
 // GetAuditLogs haalt audit logs op met filters
-func (s *auditService) GetAuditLogs(filter model.AuditLogFilter) ([]model.AuditLog, int64, error) {
+func (s *auditService) GetAuditLogs(filter model.AuditLogFilter) ([]model.AuditLog, pagination.Info, error) {
 	// Valideer paginering
 	if filter.Page < 1 {
 		filter.Page = 1
@@ -37,6 +40,11 @@ func (s *auditService) GetAuditLogs(filter model.AuditLogFilter) ([]model.AuditL
 	return s.repo.FindAll(filter)
 }
 
+// GetEntityHistory haalt de audit logs van een entiteit op die voor de grens vallen
+func (s *auditService) GetEntityHistory(entityType model.EntityType, entityID string, boundary *timeline.Boundary, limit int) ([]model.AuditLog, error) {
+	return s.repo.FindEntityHistory(entityType, entityID, boundary, limit)
+}
+
 // Create maakt een nieuwe audit log entry aan
 func (s *auditService) Create(log *model.AuditLog) error {
 	// Validatie
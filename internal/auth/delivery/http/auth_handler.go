@@ -41,7 +41,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.service.Login(loginReq.Email, loginReq.Password)
+	token, err := h.service.Login(loginReq.Email, loginReq.Password, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
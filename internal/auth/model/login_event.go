@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Redenen van een mislukte inlogpoging
+const (
+	LoginFailedCredentials = "invalid_credentials"
+	LoginFailedInactive    = "inactive"
+)
+
+// LoginEvent is een inlogpoging. Het e-mailadres wordt niet opgeslagen; bij een bekende gebruiker
+// staat het gebruikers ID erbij. Inlogpogingen worden na RETENTION_LOGIN_EVENT_DAYS opgeschoond.
+type LoginEvent struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        *uint     `json:"user_id" gorm:"index"`
+	Success       bool      `json:"success" gorm:"not null"`
+	FailureReason string    `json:"failure_reason,omitempty" gorm:"size:50;not null;default:''"`
+	IPAddress     string    `json:"ip_address" gorm:"size:45;not null;default:''"`
+	UserAgent     string    `json:"user_agent" gorm:"size:255;not null;default:''"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index;not null"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (LoginEvent) TableName() string {
+	return "login_events"
+}
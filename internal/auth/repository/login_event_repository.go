@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"odomosml/internal/auth/model"
+
+	"gorm.io/gorm"
+)
+
+// LoginEventRepository definieert de interface voor het vastleggen van inlogpogingen
+type LoginEventRepository interface {
+	Create(event *model.LoginEvent) error
+}
+
+// loginEventRepository implementeert de LoginEventRepository interface
+type loginEventRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginEventRepository maakt een nieuwe LoginEventRepository instantie
+func NewLoginEventRepository(db *gorm.DB) LoginEventRepository {
+	return &loginEventRepository{
+		db: db,
+	}
+}
+
+// Create legt een inlogpoging vast
+func (r *loginEventRepository) Create(event *model.LoginEvent) error {
+	return r.db.Create(event).Error
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"errors"
+	"log"
 	"odomosml/config"
 	"odomosml/internal/auth/model"
+	authRepo "odomosml/internal/auth/repository"
 	userModel "odomosml/internal/user/model"
 	"odomosml/internal/user/repository"
 	"time"
@@ -12,41 +14,61 @@ import (
 )
 
 type AuthService interface {
-	Login(email, password string) (*model.TokenResponse, error)
+	Login(email, password, ipAddress, userAgent string) (*model.TokenResponse, error)
 	Register(req model.RegisterRequest) (*model.TokenResponse, error)
 	ValidateToken(tokenString string) (*model.Claims, error)
 	RefreshToken(claims *model.Claims) (*model.TokenResponse, error)
 }
 
 type authService struct {
-	userRepo repository.UserRepository
-	config   *config.Config
+	userRepo    repository.UserRepository
+	loginEvents authRepo.LoginEventRepository
+	config      *config.Config
 }
 
-func NewAuthService(userRepo repository.UserRepository, cfg *config.Config) AuthService {
+func NewAuthService(userRepo repository.UserRepository, loginEvents authRepo.LoginEventRepository, cfg *config.Config) AuthService {
 	return &authService{
-		userRepo: userRepo,
-		config:   cfg,
+		userRepo:    userRepo,
+		loginEvents: loginEvents,
+		config:      cfg,
 	}
 }
 
-func (s *authService) Login(email, password string) (*model.TokenResponse, error) {
+func (s *authService) Login(email, password, ipAddress, userAgent string) (*model.TokenResponse, error) {
+	event := &model.LoginEvent{IPAddress: ipAddress, UserAgent: userAgent}
+	if runes := []rune(event.UserAgent); len(runes) > 255 {
+		event.UserAgent = string(runes[:255])
+	}
+	defer s.recordLogin(event)
+
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
+		event.FailureReason = model.LoginFailedCredentials
 		return nil, errors.New("ongeldige inloggegevens")
 	}
+	event.UserID = &user.ID
 
 	if err := user.ComparePassword(password); err != nil {
+		event.FailureReason = model.LoginFailedCredentials
 		return nil, errors.New("ongeldige inloggegevens")
 	}
 
 	if !user.Active {
+		event.FailureReason = model.LoginFailedInactive
 		return nil, errors.New("account is gedeactiveerd")
 	}
 
+	event.Success = true
 	return s.generateToken(user)
 }
 
+// recordLogin legt een inlogpoging vast; als dat mislukt, gaat het inloggen gewoon door
+func (s *authService) recordLogin(event *model.LoginEvent) {
+	if err := s.loginEvents.Create(event); err != nil {
+		log.Printf("Waarschuwing: Kon inlogpoging niet vastleggen: %v", err)
+	}
+}
+
 func (s *authService) Register(req model.RegisterRequest) (*model.TokenResponse, error) {
 	// Check if email already exists
 	if existing, _ := s.userRepo.FindByEmail(req.Email); existing != nil {
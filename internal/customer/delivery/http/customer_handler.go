@@ -1,24 +1,103 @@
 package http
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	auditModel "odomosml/internal/audit/model"
 	"odomosml/internal/customer/model"
+	"odomosml/internal/customer/repository"
 	"odomosml/internal/customer/service"
+	viewModel "odomosml/internal/savedview/model"
+	viewService "odomosml/internal/savedview/service"
+	userModel "odomosml/internal/user/model"
+	"odomosml/pkg/concurrency"
+	"odomosml/pkg/export"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/patch"
+	"odomosml/pkg/sorting"
+	"odomosml/pkg/validation"
+	"odomosml/pkg/vcard"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// formatVCard is het exportformaat voor vCards
+const formatVCard = "vcf"
+
+// vcardContentType is het MIME type van een .vcf bestand
+const vcardContentType = "text/vcard; charset=utf-8"
+
 // CustomerHandler handles HTTP requests for customers
 type CustomerHandler struct {
-	service service.CustomerService
+	service         service.CustomerService
+	views           viewService.SavedViewService
+	ifMatchRequired bool
 }
 
-// NewCustomerHandler maakt een nieuwe CustomerHandler instantie
-func NewCustomerHandler(service service.CustomerService) *CustomerHandler {
+// NewCustomerHandler maakt een nieuwe CustomerHandler instantie. Met ifMatchRequired moeten wijzigingen
+// en verwijderingen een If-Match header meesturen.
+func NewCustomerHandler(service service.CustomerService, views viewService.SavedViewService, ifMatchRequired bool) *CustomerHandler {
 	return &CustomerHandler{
-		service: service,
+		service:         service,
+		views:           views,
+		ifMatchRequired: ifMatchRequired,
+	}
+}
+
+// checkIfMatch haalt de actuele klant op en controleert de If-Match header daartegen. Bij een fout
+// is de response al verstuurd en is het resultaat nil.
+func (h *CustomerHandler) checkIfMatch(c *gin.Context, id string) *model.Customer {
+	existing, err := h.service.GetCustomerByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return nil
+	}
+
+	if err := concurrency.CheckIfMatch(c.GetHeader("If-Match"), existing.ETag(), h.ifMatchRequired); err != nil {
+		respondWriteError(c, err)
+		return nil
+	}
+
+	// Bewaar de huidige gegevens voor de audit diff
+	c.Set("customerOldData", existing.ToAuditMap())
+	return existing
+}
+
+// respondWriteError vertaalt een fout bij het wijzigen van een klant naar een HTTP status: 412 of 428
+// bij een versieconflict, 409 of 415 bij een patch, anders 400. Validatiefouten komen per veld in fields.
+func respondWriteError(c *gin.Context, err error) {
+	status, ok := concurrency.Status(err)
+	if !ok {
+		status, ok = patch.Status(err)
+	}
+	if !ok && (errors.Is(err, repository.ErrHasDocuments) || errors.Is(err, repository.ErrLegalHold)) {
+		status, ok = http.StatusConflict, true
+	}
+	if !ok {
+		status = http.StatusBadRequest
+	}
+
+	response := gin.H{
+		"success": false,
+		"error":   err.Error(),
 	}
+	if fields, ok := validation.Fields(err); ok {
+		response["fields"] = fields
+	}
+	c.JSON(status, response)
 }
 
 // Helper functie om integer parameters te parsen
@@ -36,6 +115,246 @@ func parseIntParam(c *gin.Context, param string, defaultValue int) int {
 	return value
 }
 
+// parseTagsParam parst de tags parameter in het formaat "any:a,b", "all:a,b" of "a,b"
+func parseTagsParam(value string) ([]string, string, error) {
+	if value == "" {
+		return nil, "", nil
+	}
+
+	match := model.TagMatchAny
+	if mode, rest, found := strings.Cut(value, ":"); found {
+		switch mode {
+		case model.TagMatchAny, model.TagMatchAll:
+			match = mode
+			value = rest
+		default:
+			return nil, "", fmt.Errorf("ongeldige tag filter modus '%s', gebruik 'any' of 'all'", mode)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+
+	return tags, match, nil
+}
+
+// parseCustomFieldParams verzamelt vrije veld filters uit query parameters met het cf. prefix
+func parseCustomFieldParams(query url.Values) map[string]string {
+	customFields := make(map[string]string)
+	for key, values := range query {
+		if strings.HasPrefix(key, model.CustomFieldPrefix) && len(values) > 0 {
+			customFields[strings.TrimPrefix(key, model.CustomFieldPrefix)] = values[0]
+		}
+	}
+	return customFields
+}
+
+// parseFilter leest de zoek-, tag-, vrije veld-, sorteer- en pagineringsparameters van een klantenlijst
+func parseFilter(query url.Values) (model.CustomerFilter, error) {
+	// Beperk page_size tot 100 om database overbelasting te voorkomen
+	page, err := pagination.FromQuery(query, "page", "page_size", 10, 100)
+	if err != nil {
+		return model.CustomerFilter{}, err
+	}
+
+	tags, tagMatch, err := parseTagsParam(query.Get("tags"))
+	if err != nil {
+		return model.CustomerFilter{}, err
+	}
+
+	sort, err := parseSortParam(query)
+	if err != nil {
+		return model.CustomerFilter{}, err
+	}
+
+	expression, err := filterExpr.Parse(query.Get("filter"))
+	if err != nil {
+		return model.CustomerFilter{}, err
+	}
+
+	var groupID uint64
+	if group := query.Get("group"); group != "" {
+		if groupID, err = strconv.ParseUint(group, 10, 32); err != nil || groupID == 0 {
+			return model.CustomerFilter{}, errors.New("group moet het ID van een klant zijn")
+		}
+	}
+
+	minDays, err := parseDaysParam(query, "min_days_in_status")
+	if err != nil {
+		return model.CustomerFilter{}, err
+	}
+	maxDays, err := parseDaysParam(query, "max_days_in_status")
+	if err != nil {
+		return model.CustomerFilter{}, err
+	}
+
+	return model.CustomerFilter{
+		SearchTerm:      query.Get("zoekterm"),
+		Tags:            tags,
+		TagMatch:        tagMatch,
+		CustomFields:    parseCustomFieldParams(query),
+		Page:            page.Page,
+		PageSize:        page.Size,
+		After:           page.After,
+		Before:          page.Before,
+		Count:           page.Count,
+		Sort:            sort,
+		Expression:      expression,
+		GroupID:         uint(groupID),
+		Statuses:        parseListParam(query.Get("status")),
+		MinDaysInStatus: minDays,
+		MaxDaysInStatus: maxDays,
+	}, nil
+}
+
+// parseListParam splitst een komma-gescheiden parameter en laat lege en dubbele waarden weg
+func parseListParam(value string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		values = append(values, item)
+	}
+	return values
+}
+
+// parseDaysParam parst een aantal dagen (0 of meer); een lege parameter geeft 0
+func parseDaysParam(query url.Values, param string) (int, error) {
+	value := query.Get(param)
+	if value == "" {
+		return 0, nil
+	}
+
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("%s moet een aantal dagen zijn", param)
+	}
+	return days, nil
+}
+
+// parseSortParam parst de sort parameter ("-created_at,name"). De oudere sort_by en sort_order
+// parameters worden nog ondersteund en sorteren daarna op naam.
+func parseSortParam(query url.Values) ([]sorting.Field, error) {
+	value := query.Get("sort")
+	if value == "" && query.Get("sort_by") != "" {
+		value = query.Get("sort_by")
+		if strings.EqualFold(query.Get("sort_order"), "desc") {
+			value = "-" + value
+		}
+		if value != "name" && value != "-name" {
+			value += ",name"
+		}
+	}
+
+	return sorting.Parse(value, model.SortFields...)
+}
+
+// listParams zijn de parameters waarmee de klantenlijst gefilterd of gesorteerd wordt (naast cf.<key>);
+// zolang geen daarvan is opgegeven geldt de standaard weergave van de gebruiker
+var listParams = []string{"zoekterm", "tags", "filter", "group", "status", "min_days_in_status", "max_days_in_status", "sort", "sort_by"}
+
+// listQuery geeft de parameters van de klantenlijst of export: die van de weergave uit de view parameter,
+// of zonder filters en sortering die van de standaard weergave, met de parameters uit de request daaroverheen.
+// Met view=none wordt de standaard weergave overgeslagen.
+func (h *CustomerHandler) listQuery(c *gin.Context) (url.Values, *viewModel.SavedView, error) {
+	query := c.Request.URL.Query()
+	id := query.Get("view")
+	if id == "" && hasListParams(query) {
+		return query, nil, nil
+	}
+
+	view, err := h.views.ResolveView(id, currentViewer(c))
+	if err != nil || view == nil {
+		return query, nil, err
+	}
+
+	values := url.Values{}
+	for key, value := range view.Params {
+		values.Set(key, value)
+	}
+	if view.Sort != "" {
+		values.Set("sort", view.Sort)
+	}
+	if len(view.Columns) > 0 {
+		values.Set("columns", strings.Join(view.Columns, ","))
+	}
+	for key, value := range query {
+		values[key] = value
+	}
+	// De oudere sort_by parameter gaat ook voor de sortering van de weergave
+	if query.Get("sort") == "" && query.Get("sort_by") != "" {
+		values.Del("sort")
+	}
+
+	return values, view, nil
+}
+
+// hasListParams geeft aan of de request filters of een sortering voor de klantenlijst bevat
+func hasListParams(query url.Values) bool {
+	for key := range query {
+		if strings.HasPrefix(key, model.CustomFieldPrefix) {
+			return true
+		}
+	}
+	for _, key := range listParams {
+		if query.Get(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// currentViewer geeft de ingelogde gebruiker, voor het toepassen van weergaven
+func currentViewer(c *gin.Context) viewModel.Viewer {
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+	role, _ := c.Get("userRole")
+
+	viewer := viewModel.Viewer{}
+	viewer.UserID, _ = userID.(uint)
+	viewer.Username, _ = username.(string)
+	roleName, _ := role.(string)
+	viewer.Admin = userModel.Role(roleName) == userModel.RoleAdmin
+	return viewer
+}
+
+// respondFilterError stuurt een fout bij het ophalen van klanten terug. Fouten in de filterexpressie
+// of een cursor van een andere sortering zijn altijd een 400; bij een filterfout staat de positie
+// (vanaf 1) van het foute token in de response. Een onbekende weergave is een 404.
+func respondFilterError(c *gin.Context, status int, err error) {
+	body := gin.H{
+		"success": false,
+		"error":   err.Error(),
+	}
+
+	var expressionErr *filterExpr.Error
+	if errors.As(err, &expressionErr) {
+		status = http.StatusBadRequest
+		body["position"] = expressionErr.Pos + 1
+	} else if fields, ok := validation.Fields(err); ok {
+		status = http.StatusBadRequest
+		body["fields"] = fields
+	} else if errors.Is(err, pagination.ErrSortMismatch) {
+		status = http.StatusBadRequest
+	} else if errors.Is(err, viewService.ErrViewNotFound) {
+		status = http.StatusNotFound
+	}
+
+	c.JSON(status, body)
+}
+
 // @Summary      Lijst van klanten ophalen
 // @Description  Haalt een lijst van alle klanten op met optionele filters
 // @Tags         customers
@@ -43,51 +362,78 @@ func parseIntParam(c *gin.Context, param string, defaultValue int) int {
 // @Produce      json
 // @Param        page query int false "Paginanummer (default: 1)"
 // @Param        pageSize query int false "Aantal items per pagina (default: 10, max: 100)"
-// @Param        searchTerm query string false "Zoekterm voor naam of email"
-// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Param        after query string false "Cursor: de pagina na deze cursor (next_cursor)"
+// @Param        before query string false "Cursor: de pagina voor deze cursor (prev_cursor)"
+// @Param        count query string false "Totaal aantal bepalen: exact, estimate of none (default: exact)"
+// @Param        searchTerm query string false "Zoekterm voor naam, of een volledig e-mailadres"
+// @Param        tags query string false "Filter op tags, bijv. any:vip,prospect of all:vip,wholesale"
+// @Param        cf.{key} query string false "Filter op de waarde van een vrij veld, bijv. cf.branche=bouw"
+// @Param        filter query string false "Filterexpressie, bijv. created_at >= 2024-01-01 and name ~ bakkerij and phone is null. Op email, phone en address kan alleen met is null of is not null gefilterd worden"
+// @Param        group query int false "ID van een moederklant: alleen deze klant en alle klanten die (indirect) eronder vallen"
+// @Param        status query string false "Komma-gescheiden status keys, bijv. lead,prospect"
+// @Param        min_days_in_status query int false "Alleen klanten die minstens zoveel dagen in hun huidige status staan"
+// @Param        max_days_in_status query int false "Alleen klanten die hoogstens zoveel dagen in hun huidige status staan"
+// @Param        sort query string false "Komma-gescheiden sorteervelden, - voor aflopend: id, name, kvk_number, status, status_changed_at, created_at, updated_at of cf.<key> (default: name)"
+// @Param        view query string false "ID van een opgeslagen weergave; parameters uit de request gaan voor. none slaat de standaard weergave over"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald, met Link header naar andere pagina's"
 // @Failure      400  {object}  map[string]string "Ongeldige parameters"
 // @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Weergave niet gevonden"
 // @Failure      500  {object}  map[string]string "Server error"
 // @Security     Bearer
 // @Router       /klanten [get]
 func (h *CustomerHandler) GetAll(c *gin.Context) {
-	// Parse filter parameters
-	page := parseIntParam(c, "page", 1)
-	pageSize := parseIntParam(c, "page_size", 10)
-
-	// Beperk pageSize om database overbelasting te voorkomen
-	if pageSize > 100 {
-		pageSize = 100
+	query, view, err := h.listQuery(c)
+	if err != nil {
+		respondFilterError(c, http.StatusInternalServerError, err)
+		return
 	}
 
-	filter := model.CustomerFilter{
-		SearchTerm: c.Query("zoekterm"),
-		Page:       page,
-		PageSize:   pageSize,
+	// Parse filter parameters
+	filter, err := parseFilter(query)
+	if err != nil {
+		respondFilterError(c, http.StatusBadRequest, err)
+		return
 	}
 
-	customers, total, err := h.service.GetAllCustomers(filter)
+	customers, info, err := h.service.GetAllCustomers(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   err.Error(),
-		})
+		respondFilterError(c, http.StatusInternalServerError, err)
 		return
 	}
 
+	request := pagination.Request{Page: filter.Page, Size: filter.PageSize, After: filter.After, Before: filter.Before}
+	if link := pagination.Links(c.Request.URL, request, info, "page"); link != "" {
+		c.Header("Link", link)
+	}
+
 	// Bereken paginering
-	totalPages := (int(total) + filter.PageSize - 1) / filter.PageSize
+	meta := gin.H{
+		"page_size":   filter.PageSize,
+		"total_items": info.Total,
+		"has_more":    info.HasMore,
+		"next_cursor": info.Next,
+	}
+	if request.Keyset() {
+		meta["prev_cursor"] = info.Prev
+	} else {
+		meta["current_page"] = filter.Page
+	}
+	if info.Total != nil {
+		meta["total_pages"] = (int(*info.Total) + filter.PageSize - 1) / filter.PageSize
+		meta["total_estimated"] = info.Estimated
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    customers,
-		"pagination": gin.H{
-			"current_page": filter.Page,
-			"page_size":    filter.PageSize,
-			"total_items":  total,
-			"total_pages":  totalPages,
-		},
-	})
+	response := gin.H{
+		"success":    true,
+		"data":       customers,
+		"pagination": meta,
+	}
+	if view != nil {
+		response["view"] = gin.H{"id": view.ID, "name": view.Name}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // @Summary      Klant ophalen op ID
@@ -96,7 +442,9 @@ func (h *CustomerHandler) GetAll(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Klant ID"
+// @Param        If-None-Match header string false "ETag van een eerder opgehaalde versie"
 // @Success      200  {object}  model.Customer "Succesvol opgehaald"
+// @Success      304  "Niet gewijzigd"
 // @Failure      400  {object}  map[string]string "Ongeldig ID"
 // @Failure      401  {object}  map[string]string "Niet geautoriseerd"
 // @Failure      404  {object}  map[string]string "Klant niet gevonden"
@@ -115,6 +463,13 @@ func (h *CustomerHandler) GetByID(c *gin.Context) {
 		return
 	}
 
+	etag := customer.ETag()
+	c.Header("ETag", etag)
+	if concurrency.Matches(c.GetHeader("If-None-Match"), etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    customer,
@@ -122,15 +477,16 @@ func (h *CustomerHandler) GetByID(c *gin.Context) {
 }
 
 // @Summary      Nieuwe klant aanmaken
-// @Description  Maakt een nieuwe klant aan
+// @Description  Maakt een nieuwe klant aan. Lijkt de klant op een bestaande klant (zelfde email of KvK nummer, of een sterk gelijkende naam), dan volgt een 409 met de kandidaten; met force=true wordt de klant toch aangemaakt.
 // @Tags         customers
 // @Accept       json
 // @Produce      json
 // @Param        customer body model.Customer true "Klant gegevens"
+// @Param        force query bool false "Aanmaken ondanks mogelijke dubbele klanten"
 // @Success      201  {object}  model.Customer "Succesvol aangemaakt"
 // @Failure      400  {object}  map[string]string "Ongeldige invoer"
 // @Failure      401  {object}  map[string]string "Niet geautoriseerd"
-// @Failure      409  {object}  map[string]string "Email bestaat al"
+// @Failure      409  {object}  map[string]interface{} "Mogelijke dubbele klanten"
 // @Failure      500  {object}  map[string]string "Server error"
 // @Security     Bearer
 // @Router       /klanten [post]
@@ -144,6 +500,26 @@ func (h *CustomerHandler) Create(c *gin.Context) {
 		return
 	}
 
+	// Waarschuw voor mogelijke dubbele klanten, tenzij de gebruiker bewust doorzet
+	if c.Query("force") != "true" {
+		candidates, err := h.service.FindDuplicates(&customer)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		if len(candidates) > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"success":    false,
+				"error":      "Er bestaan mogelijk al klanten met deze gegevens; gebruik force=true om toch aan te maken",
+				"duplicates": candidates,
+			})
+			return
+		}
+	}
+
 	created, err := h.service.CreateCustomer(&customer)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -165,11 +541,14 @@ func (h *CustomerHandler) Create(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Klant ID"
+// @Param        If-Match header string false "ETag van de versie die gewijzigd wordt (verplicht tenzij IF_MATCH_REQUIRED=false)"
 // @Param        customer body model.Customer true "Klant gegevens"
 // @Success      200  {object}  model.Customer "Succesvol bijgewerkt"
 // @Failure      400  {object}  map[string]string "Ongeldige invoer"
 // @Failure      401  {object}  map[string]string "Niet geautoriseerd"
 // @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Failure      412  {object}  map[string]string "Klant is intussen gewijzigd"
+// @Failure      428  {object}  map[string]string "If-Match header ontbreekt"
 // @Failure      409  {object}  map[string]string "Email bestaat al"
 // @Failure      500  {object}  map[string]string "Server error"
 // @Security     Bearer
@@ -194,17 +573,21 @@ func (h *CustomerHandler) Update(c *gin.Context) {
 		return
 	}
 
+	existing := h.checkIfMatch(c, id)
+	if existing == nil {
+		return
+	}
+
 	customer.ID = uint(idInt)
+	customer.Version = existing.Version
 
 	updated, err := h.service.UpdateCustomer(&customer)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error":   err.Error(),
-		})
+		respondWriteError(c, err)
 		return
 	}
 
+	c.Header("ETag", updated.ETag())
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    updated,
@@ -212,24 +595,31 @@ func (h *CustomerHandler) Update(c *gin.Context) {
 }
 
 // @Summary      Klant gedeeltelijk bijwerken
-// @Description  Werkt specifieke velden van een klant bij
+// @Description  Werkt velden van een klant bij met een JSON Merge Patch (application/merge-patch+json, ook voor application/json) of een JSON Patch (application/json-patch+json). Alleen name, email, phone, address, kvk_number en custom_fields kunnen gewijzigd worden; de klant wordt daarna gevalideerd als bij bijwerken. Validatiefouten staan per veld in fields.
 // @Tags         customers
 // @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
 // @Produce      json
 // @Param        id path string true "Klant ID"
-// @Param        customer body object true "Klant velden om bij te werken"
+// @Param        If-Match header string false "ETag van de versie die gewijzigd wordt (verplicht tenzij IF_MATCH_REQUIRED=false)"
+// @Param        customer body object true "Merge patch of lijst van JSON Patch operaties"
 // @Success      200  {object}  model.Customer "Succesvol bijgewerkt"
-// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige patch of invoer"
 // @Failure      401  {object}  map[string]string "Niet geautoriseerd"
 // @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Failure      409  {object}  map[string]string "Test operatie mislukt"
+// @Failure      412  {object}  map[string]string "Klant is intussen gewijzigd"
+// @Failure      415  {object}  map[string]string "Onbekend Content-Type"
+// @Failure      428  {object}  map[string]string "If-Match header ontbreekt"
 // @Failure      500  {object}  map[string]string "Server error"
 // @Security     Bearer
 // @Router       /klanten/{id} [patch]
 func (h *CustomerHandler) PartialUpdate(c *gin.Context) {
 	id := c.Param("id")
 
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	body, err := c.GetRawData()
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"error":   "Ongeldige request: " + err.Error(),
@@ -237,15 +627,18 @@ func (h *CustomerHandler) PartialUpdate(c *gin.Context) {
 		return
 	}
 
-	updated, err := h.service.PartialUpdateCustomer(id, updates)
+	existing := h.checkIfMatch(c, id)
+	if existing == nil {
+		return
+	}
+
+	updated, err := h.service.PatchCustomer(id, existing.Version, c.ContentType(), body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error":   err.Error(),
-		})
+		respondWriteError(c, err)
 		return
 	}
 
+	c.Header("ETag", updated.ETag())
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    updated,
@@ -253,35 +646,573 @@ func (h *CustomerHandler) PartialUpdate(c *gin.Context) {
 }
 
 // @Summary      Klant verwijderen
-// @Description  Verwijdert een klant
+// @Description  Verwijdert een klant. De klant is direct niet meer zichtbaar en wordt met activiteiten, taken, afspraken, bijlagen en deals na de bewaartermijn (RETENTION_DELETED_CUSTOMER_DAYS) definitief verwijderd, tenzij de klant een legal hold heeft
 // @Tags         customers
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Klant ID"
+// @Param        If-Match header string false "ETag van de versie die gewijzigd wordt (verplicht tenzij IF_MATCH_REQUIRED=false)"
 // @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
 // @Failure      400  {object}  map[string]string "Ongeldig ID"
 // @Failure      401  {object}  map[string]string "Niet geautoriseerd"
 // @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Failure      409  {object}  map[string]string "Klant heeft offertes of facturen"
+// @Failure      412  {object}  map[string]string "Klant is intussen gewijzigd"
+// @Failure      428  {object}  map[string]string "If-Match header ontbreekt"
 // @Failure      500  {object}  map[string]string "Server error"
 // @Security     Bearer
 // @Router       /klanten/{id} [delete]
 func (h *CustomerHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	customerData, err := h.service.DeleteCustomer(id)
+	existing := h.checkIfMatch(c, id)
+	if existing == nil {
+		return
+	}
+
+	customerData, err := h.service.DeleteCustomer(id, existing.Version)
+	if err != nil {
+		respondWriteError(c, err)
+		return
+	}
+
+	// Sla customerData op in context voor audit logging
+	c.Set("customerData", customerData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Klant succesvol verwijderd",
+	})
+}
+
+// @Summary      Bovenliggende klanten ophalen
+// @Description  Geeft de moederklant van een klant, de moederklant daarvan, enz. tot de bovenste klant van de groep
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Success      200  {array}   model.Ancestor "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Security     Bearer
+// @Router       /klanten/{id}/ancestors [get]
+func (h *CustomerHandler) Ancestors(c *gin.Context) {
+	ancestors, err := h.service.GetAncestors(c.Param("id"))
 	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    ancestors,
+	})
+}
+
+// @Summary      Onderliggende klanten ophalen
+// @Description  Geeft een klant en alle klanten die (indirect) eronder vallen in boomvolgorde, met per klant het aantal directe en indirecte onderliggende klanten en het aantal activiteiten van de klant zelf en van de hele deelboom
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        max_depth query int false "Maximaal aantal niveaus onder de klant (default en max: 20); de tellingen gaan altijd over de hele deelboom"
+// @Success      200  {array}   model.HierarchyNode "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Security     Bearer
+// @Router       /klanten/{id}/subtree [get]
+func (h *CustomerHandler) Subtree(c *gin.Context) {
+	maxDepth := parseIntParam(c, "max_depth", 0)
+
+	nodes, err := h.service.GetSubtree(c.Param("id"), maxDepth)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    nodes,
+	})
+}
+
+// @Summary      Status van een klant wijzigen
+// @Description  Verplaatst een klant naar een andere status, als de workflow die overgang vanuit de huidige status toestaat. De overgang komt met gebruiker en tijdstip in de statusgeschiedenis.
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        If-Match header string false "ETag van de versie die gewijzigd wordt (verplicht tenzij IF_MATCH_REQUIRED=false)"
+// @Param        status body model.StatusChangeRequest true "Nieuwe status en optionele notitie"
+// @Success      200  {object}  model.Customer "Status gewijzigd"
+// @Failure      400  {object}  map[string]interface{} "Onbekende status of overgang niet toegestaan"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Failure      412  {object}  map[string]string "Klant is intussen gewijzigd"
+// @Failure      428  {object}  map[string]string "If-Match header ontbreekt"
+// @Security     Bearer
+// @Router       /klanten/{id}/status [put]
+func (h *CustomerHandler) ChangeStatus(c *gin.Context) {
+	id := c.Param("id")
+
+	var request model.StatusChangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	existing := h.checkIfMatch(c, id)
+	if existing == nil {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+	change := &model.StatusChange{ToStatus: request.Status, Note: request.Note}
+	change.UserID, _ = userID.(uint)
+	change.Username, _ = username.(string)
+
+	updated, err := h.service.ChangeStatus(id, existing.Version, change)
+	if err != nil {
+		respondWriteError(c, err)
+		return
+	}
+
+	c.Header("ETag", updated.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Legal hold van een klant
+// @Description  Zet of verwijdert de legal hold van een klant (alleen admins). Een klant met een legal hold wordt na verwijderen niet opgeschoond, kan niet geanonimiseerd worden en zijn audit log valt buiten de bewaartermijn. Bij het zetten is een reden verplicht.
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        If-Match header string false "ETag van de versie die gewijzigd wordt (verplicht tenzij IF_MATCH_REQUIRED=false)"
+// @Param        hold body model.LegalHoldRequest true "Legal hold aan of uit en de reden"
+// @Success      200  {object}  model.Customer "Legal hold gewijzigd"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Failure      412  {object}  map[string]string "Klant is intussen gewijzigd"
+// @Failure      428  {object}  map[string]string "If-Match header ontbreekt"
+// @Security     Bearer
+// @Router       /klanten/{id}/legal-hold [put]
+func (h *CustomerHandler) SetLegalHold(c *gin.Context) {
+	id := c.Param("id")
+
+	var request model.LegalHoldRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	existing := h.checkIfMatch(c, id)
+	if existing == nil {
+		return
+	}
+
+	updated, err := h.service.SetLegalHold(id, existing.Version, request)
+	if err != nil {
+		respondWriteError(c, err)
+		return
+	}
+
+	c.Header("ETag", updated.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Statusgeschiedenis van een klant
+// @Description  Geeft alle statusovergangen van een klant met tijdstip en gebruiker, nieuwste eerst
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Success      200  {array}   model.StatusChange "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Security     Bearer
+// @Router       /klanten/{id}/status-history [get]
+func (h *CustomerHandler) StatusHistory(c *gin.Context) {
+	changes, err := h.service.GetStatusHistory(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"error":   err.Error(),
 		})
 		return
 	}
 
-	// Sla customerData op in context voor audit logging
-	c.Set("customerData", customerData)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    changes,
+	})
+}
+
+// @Summary      Pipelinebord
+// @Description  Groepeert de klanten per status in de volgorde van de workflow, met per kolom het aantal klanten en de klanten die het langst in de status staan. Accepteert dezelfde filters als de klantenlijst; met een status filter worden alleen die kolommen getoond.
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        limit query int false "Maximaal aantal klanten per kolom (default: 25, max: 100)"
+// @Param        status query string false "Komma-gescheiden status keys"
+// @Param        min_days_in_status query int false "Alleen klanten die minstens zoveel dagen in hun huidige status staan"
+// @Param        max_days_in_status query int false "Alleen klanten die hoogstens zoveel dagen in hun huidige status staan"
+// @Param        view query string false "ID van een opgeslagen weergave; parameters uit de request gaan voor. none slaat de standaard weergave over"
+// @Success      200  {array}   model.BoardColumn "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Weergave niet gevonden"
+// @Security     Bearer
+// @Router       /klanten/board [get]
+func (h *CustomerHandler) Board(c *gin.Context) {
+	query, _, err := h.listQuery(c)
+	if err != nil {
+		respondFilterError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	filter, err := parseFilter(query)
+	if err != nil {
+		respondFilterError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	columns, err := h.service.GetBoard(filter, parseIntParam(c, "limit", 0))
+	if err != nil {
+		respondFilterError(c, http.StatusInternalServerError, err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Klant succesvol verwijderd",
+		"data":    columns,
 	})
 }
+
+// @Summary      Dubbele klanten rapport
+// @Description  Geeft paren van bestaande klanten die waarschijnlijk dezelfde klant zijn (zelfde email of KvK nummer, of een sterk gelijkende naam)
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        threshold query number false "Minimale gelijkenis van namen tussen 0.3 en 1 (default: 0.6)"
+// @Param        page query int false "Paginanummer (default: 1)"
+// @Param        page_size query int false "Aantal paren per pagina (default: 10, max: 100)"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/duplicates [get]
+func (h *CustomerHandler) Duplicates(c *gin.Context) {
+	var threshold float64
+	if value := c.Query("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Ongeldige threshold",
+			})
+			return
+		}
+		threshold = parsed
+	}
+
+	page := parseIntParam(c, "page", 1)
+	pageSize := parseIntParam(c, "page_size", 10)
+
+	pairs, hasMore, err := h.service.GetDuplicatePairs(threshold, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     pairs,
+		"has_more": hasMore,
+	})
+}
+
+// @Summary      Klanten samenvoegen
+// @Description  Voegt de bronklant samen in de doelklant. Per veld (name, email, phone, address, kvk_number of cf.<key>) kan gekozen worden voor "source" of "target"; zonder keuze wint de doelklant tenzij die geen waarde heeft. Activiteiten, bijlagen en tags verhuizen naar de doelklant en de bronklant wordt verwijderd.
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        merge body model.MergeRequest true "Samenvoeg opdracht"
+// @Success      200  {object}  model.Customer "Succesvol samengevoegd"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      409  {object}  map[string]string "Bronklant heeft een legal hold"
+// @Failure      412  {object}  map[string]string "Klant is intussen gewijzigd"
+// @Security     Bearer
+// @Router       /klanten/merge [post]
+func (h *CustomerHandler) Merge(c *gin.Context) {
+	var request model.MergeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	merged, oldData, err := h.service.MergeCustomers(request)
+	if err != nil {
+		respondWriteError(c, err)
+		return
+	}
+
+	// Eén audit entry op de doelklant die naar beide klanten verwijst
+	oldJSON, _ := json.Marshal(oldData)
+	newJSON, _ := json.Marshal(merged.ToAuditMap())
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		ActionType:  auditModel.ActionUpdate,
+		EntityType:  auditModel.EntityCustomer,
+		EntityID:    strconv.FormatUint(uint64(merged.ID), 10),
+		Description: fmt.Sprintf("Klant %d samengevoegd in klant %d (%s)", request.SourceID, merged.ID, merged.Name),
+		OldData:     string(oldJSON),
+		NewData:     string(newJSON),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    merged,
+	})
+}
+
+// @Summary      Klanten exporteren
+// @Description  Exporteert alle klanten die aan de filters voldoen als CSV, XLSX, NDJSON of vCard. De filters zijn dezelfde als bij de lijst van klanten, zonder paginering. De rijen worden gestreamd, ook bij grote aantallen klanten. Met format=vcf volgt één .vcf bestand met een vCard per klant, om in de contacten van een telefoon te importeren.
+// @Tags         customers
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce      application/x-ndjson
+// @Produce      text/vcard
+// @Param        format query string false "csv, xlsx, ndjson of vcf (default: csv)"
+// @Param        version query string false "vCard versie bij format=vcf: 3.0 of 4.0 (default: 3.0)"
+// @Param        columns query string false "Komma-gescheiden kolommen, bijv. name,email,tags,cf.branche (default: alle kolommen en vrije velden; niet bij vcf)"
+// @Param        zoekterm query string false "Zoekterm voor naam, of een volledig e-mailadres"
+// @Param        tags query string false "Filter op tags, bijv. any:vip,prospect of all:vip,wholesale"
+// @Param        cf.{key} query string false "Filter op de waarde van een vrij veld, bijv. cf.branche=bouw"
+// @Param        filter query string false "Filterexpressie, bijv. created_at >= 2024-01-01 and name ~ bakkerij and phone is null. Op email, phone en address kan alleen met is null of is not null gefilterd worden"
+// @Param        group query int false "ID van een moederklant: alleen deze klant en alle klanten die (indirect) eronder vallen"
+// @Param        sort query string false "Komma-gescheiden sorteervelden, - voor aflopend: id, name, kvk_number, created_at, updated_at of cf.<key> (default: name)"
+// @Param        view query string false "ID van een opgeslagen weergave; parameters uit de request gaan voor. none slaat de standaard weergave over"
+// @Success      200  {file}  file "Export"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Weergave niet gevonden"
+// @Security     Bearer
+// @Router       /klanten/export [get]
+func (h *CustomerHandler) Export(c *gin.Context) {
+	query, view, err := h.listQuery(c)
+	if err != nil {
+		respondFilterError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	filter, err := parseFilter(query)
+	if err != nil {
+		respondFilterError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", export.FormatCSV))
+	if format != export.FormatCSV && format != export.FormatXLSX && format != export.FormatNDJSON && format != formatVCard {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   fmt.Sprintf("Onbekend exportformaat '%s', gebruik csv, xlsx, ndjson of vcf", format),
+		})
+		return
+	}
+
+	var (
+		columns []string
+		version string
+	)
+	if format == formatVCard {
+		if version, err = parseVCardVersion(c); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+	} else {
+		var requested []string
+		if value := query.Get("columns"); value != "" {
+			requested = strings.Split(value, ",")
+		}
+		columns, err = h.service.ResolveExportColumns(requested)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	// De response begint pas bij de eerste rij, zodat een ongeldig filter nog een 400 kan geven
+	var writer export.Writer
+	started := false
+	start := func() error {
+		started = true
+		fileName := fmt.Sprintf("klanten-%s.%s", time.Now().Format("20060102-150405"), format)
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+		if format == formatVCard {
+			c.Header("Content-Type", vcardContentType)
+			c.Status(http.StatusOK)
+			return nil
+		}
+		c.Header("Content-Type", export.ContentType(format))
+		c.Status(http.StatusOK)
+
+		var err error
+		if writer, err = export.NewWriter(format, c.Writer); err != nil {
+			return err
+		}
+		return writer.WriteHeader(columns)
+	}
+
+	rows := 0
+	values := make([]interface{}, len(columns))
+	err = h.service.ExportCustomers(filter, func(customer *model.Customer) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		rows++
+		if format == formatVCard {
+			return vcard.Encode(c.Writer, customer.VCard(version), version)
+		}
+		for i, column := range columns {
+			values[i] = customer.ExportValue(column)
+		}
+		return writer.WriteRow(values)
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if writer != nil {
+		// Ook na een fout sluiten, zodat tijdelijke bestanden van de XLSX writer opgeruimd worden
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil && !started {
+		respondFilterError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Leg vast wie welke selectie heeft geëxporteerd, ook als de export halverwege is afgebroken
+	description := fmt.Sprintf("Klanten geëxporteerd als %s: %d rijen", format, rows)
+	if err != nil {
+		log.Printf("Fout bij klantenexport na %d rijen: %v", rows, err)
+		description = fmt.Sprintf("Klantenexport als %s afgebroken na %d rijen", format, rows)
+	}
+	exportData := gin.H{
+		"format":  format,
+		"columns": columns,
+		"filter": gin.H{
+			"zoekterm":      filter.SearchTerm,
+			"tags":          filter.Tags,
+			"tag_match":     filter.TagMatch,
+			"custom_fields": filter.CustomFields,
+			"sort":          sorting.Format(filter.Sort),
+			"expression":    query.Get("filter"),
+		},
+		"rows": rows,
+	}
+	if version != "" {
+		exportData["version"] = version
+	}
+	if view != nil {
+		exportData["view"] = view.ID
+	}
+	newData, _ := json.Marshal(exportData)
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		EntityType:  auditModel.EntityCustomer,
+		Description: description,
+		NewData:     string(newData),
+	})
+}
+
+// parseVCardVersion leest de parameter version: 3.0 (default) of 4.0
+func parseVCardVersion(c *gin.Context) (string, error) {
+	switch c.DefaultQuery("version", vcard.Version3) {
+	case "3", vcard.Version3:
+		return vcard.Version3, nil
+	case "4", vcard.Version4:
+		return vcard.Version4, nil
+	default:
+		return "", fmt.Errorf("onbekende vCard versie '%s', gebruik 3.0 of 4.0", c.Query("version"))
+	}
+}
+
+// @Summary      Klant als vCard
+// @Description  Geeft één klant als vCard (.vcf), om aan de contacten van een telefoon toe te voegen
+// @Tags         customers
+// @Produce      text/vcard
+// @Param        id path string true "Klant ID"
+// @Param        version query string false "vCard versie: 3.0 of 4.0 (default: 3.0)"
+// @Success      200  {file}    file "vCard"
+// @Failure      400  {object}  map[string]string "Ongeldige versie"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Security     Bearer
+// @Router       /klanten/{id}/vcard [get]
+func (h *CustomerHandler) VCard(c *gin.Context) {
+	version, err := parseVCardVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	customer, err := h.service.GetCustomerByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := vcard.Encode(&buf, customer.VCard(version), version); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fileName := fmt.Sprintf("klant-%d.vcf", customer.ID)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Data(http.StatusOK, vcardContentType, buf.Bytes())
+}
@@ -1,21 +1,390 @@
 package model
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	tagModel "odomosml/internal/tag/model"
+	"odomosml/pkg/concurrency"
+	"odomosml/pkg/fieldcrypt"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
+	"odomosml/pkg/vcard"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag match modes voor het filteren op tags
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// CustomFieldPrefix is het prefix voor vrije velden in query parameters en sorteervelden (bijv. cf.branche)
+const CustomFieldPrefix = "cf."
+
+// CustomFields bevat de waarden van door admins gedefinieerde vrije velden, opgeslagen als JSONB
+type CustomFields map[string]interface{}
+
+// Value implementeert driver.Valuer
+func (f CustomFields) Value() (driver.Value, error) {
+	if f == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]interface{}(f))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (f *CustomFields) Scan(value interface{}) error {
+	if value == nil {
+		*f = CustomFields{}
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("kan %T niet converteren naar CustomFields", value)
+	}
+
+	return json.Unmarshal(data, (*map[string]interface{})(f))
+}
 
 type Customer struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" binding:"required"`
-	Email     string    `json:"email" binding:"required,email"`
-	Phone     string    `json:"phone"`
-	Address   string    `json:"address"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Name            string         `json:"name" binding:"required"`
+	Email           string         `json:"email" binding:"required,email" gorm:"serializer:encrypted"` // Versleuteld opgeslagen; zoeken via EmailIndex
+	Phone           string         `json:"phone" gorm:"serializer:encrypted"`
+	Address         string         `json:"address" gorm:"serializer:encrypted"`
+	EmailIndex      string         `json:"-" gorm:"size:64;not null;default:'';index"` // Blind index van het e-mailadres, voor opzoeken en dubbele klanten
+	KvKNumber       string         `json:"kvk_number" gorm:"column:kvk_number;size:8;not null;default:'';index"`
+	VATNumber       string         `json:"vat_number" gorm:"column:vat_number;size:14;not null;default:''"` // BTW-identificatienummer, bijv. NL123456789B01
+	CustomFields    CustomFields   `json:"custom_fields" gorm:"type:jsonb;not null;default:'{}'"`
+	ParentID        *uint          `json:"parent_id" gorm:"index"`                          // Moederklant (groep) waar deze klant onder valt; nil voor een zelfstandige klant
+	Status          string         `json:"status" gorm:"size:50;not null;default:'';index"` // Key van de klantstatus; alleen te wijzigen via een statusovergang
+	StatusChangedAt *time.Time     `json:"status_changed_at"`                               // Moment waarop de klant in de huidige status kwam
+	Tags            []tagModel.Tag `json:"tags" gorm:"many2many:customer_tags;"`
+	Version         uint           `json:"version" gorm:"not null;default:1"`                               // Wordt bij elke wijziging opgehoogd, voor optimistic concurrency
+	ErasedAt        *time.Time     `json:"erased_at,omitempty"`                                             // Moment waarop de persoonsgegevens zijn gewist (AVG); de klant is dan geanonimiseerd
+	LegalHold       bool           `json:"legal_hold" gorm:"not null;default:false"`                        // De gegevens moeten bewaard blijven; de klant wordt niet opgeschoond of geanonimiseerd
+	LegalHoldReason string         `json:"legal_hold_reason,omitempty" gorm:"size:500;not null;default:''"` // Reden van de legal hold, bijv. een lopende procedure
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"` // Moment van verwijderen; na de bewaartermijn wordt de klant definitief verwijderd
+}
+
+// BeforeSave houdt de blind index van het e-mailadres bij; bij een update met Select moet
+// email_index meegeselecteerd worden
+func (c *Customer) BeforeSave(tx *gorm.DB) error {
+	c.EmailIndex = fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, c.Email)
+	return nil
+}
+
+// WritableFields zijn de velden van een klant die met een PATCH gewijzigd kunnen worden
+var WritableFields = []string{"name", "email", "phone", "address", "kvk_number", "vat_number", "custom_fields", "parent_id"}
+
+// ETag geeft de ETag van de klant zoals de API deze teruggeeft; de tags worden op ID gesorteerd,
+// zodat de volgorde waarin de database ze oplevert niet uitmaakt
+func (c *Customer) ETag() string {
+	representation := *c
+	representation.Tags = append([]tagModel.Tag(nil), c.Tags...)
+	sort.Slice(representation.Tags, func(i, j int) bool {
+		return representation.Tags[i].ID < representation.Tags[j].ID
+	})
+	return concurrency.ETag(c.Version, representation)
+}
+
+// ToAuditMap converteert een klant naar een map voor audit logging
+func (c *Customer) ToAuditMap() map[string]interface{} {
+	customFields := make(map[string]interface{}, len(c.CustomFields))
+	for key, value := range c.CustomFields {
+		customFields[key] = value
+	}
+
+	return map[string]interface{}{
+		"id":            c.ID,
+		"name":          c.Name,
+		"email":         c.Email,
+		"phone":         c.Phone,
+		"address":       c.Address,
+		"kvk_number":    c.KvKNumber,
+		"vat_number":    c.VATNumber,
+		"custom_fields": customFields,
+		"parent_id":     c.ParentID,
+		"status":        c.Status,
+		"legal_hold":    c.LegalHold,
+	}
+}
+
+// VCard eigenschappen voor klantgegevens zonder standaard eigenschap in vCard
+const (
+	VCardKvKNumber = "X-KVK-NUMBER"
+	VCardVATNumber = "X-VAT-NUMBER"
+)
+
+// VCard geeft de klant als vCard van een organisatie, in de opgegeven versie (3.0 of 4.0). Het adres
+// staat als één regel in het straatdeel van ADR; KvK en BTW staan in X- eigenschappen.
+func (c *Customer) VCard(version string) *vcard.Card {
+	card := &vcard.Card{Version: version}
+
+	if version == vcard.Version4 {
+		card.Add("KIND", "org")
+		card.Add("UID", fmt.Sprintf("odomosml-klant-%d", c.ID), "VALUE", "text")
+	} else {
+		card.Add("UID", fmt.Sprintf("odomosml-klant-%d", c.ID))
+		// vCard 3.0 vereist N; voor een organisatie blijft die leeg en toont Apple de bedrijfsnaam
+		card.Add("N", vcard.Structured("", "", "", "", ""))
+		card.Add("X-ABSHOWAS", "COMPANY")
+	}
+	card.AddText("FN", c.Name)
+	card.Add("ORG", vcard.Structured(c.Name))
+
+	if c.Email != "" {
+		card.AddText("EMAIL", c.Email, "TYPE", "work")
+	}
+	if c.Phone != "" {
+		if version == vcard.Version4 {
+			card.AddText("TEL", c.Phone, "TYPE", "work,voice", "VALUE", "text")
+		} else {
+			card.AddText("TEL", c.Phone, "TYPE", "work,voice")
+		}
+	}
+	if c.Address != "" {
+		card.Add("ADR", vcard.Structured("", "", c.Address, "", "", "", ""), "TYPE", "work")
+	}
+	if len(c.Tags) > 0 {
+		names := make([]string, len(c.Tags))
+		for i, tag := range c.Tags {
+			names[i] = tag.Name
+		}
+		card.Add("CATEGORIES", vcard.List(names...))
+	}
+	if c.KvKNumber != "" {
+		card.AddText(VCardKvKNumber, c.KvKNumber)
+	}
+	if c.VATNumber != "" {
+		card.AddText(VCardVATNumber, c.VATNumber)
+	}
+	if !c.UpdatedAt.IsZero() {
+		card.Add("REV", c.UpdatedAt.UTC().Format("20060102T150405Z"))
+	}
+
+	return card
+}
+
+// ExportColumns zijn de vaste kolommen die bij een export gekozen kunnen worden, in de standaardvolgorde.
+// Vrije velden worden als cf.<key> opgegeven.
+var ExportColumns = []string{"id", "name", "email", "phone", "address", "kvk_number", "vat_number", "parent_id", "status", "status_changed_at", "tags", "created_at", "updated_at"}
+
+// ExportValue geeft de waarde van een exportkolom; tags worden als komma-gescheiden namen teruggegeven
+func (c *Customer) ExportValue(column string) interface{} {
+	switch column {
+	case "id":
+		return c.ID
+	case "name":
+		return c.Name
+	case "email":
+		return c.Email
+	case "phone":
+		return c.Phone
+	case "address":
+		return c.Address
+	case "kvk_number":
+		return c.KvKNumber
+	case "vat_number":
+		return c.VATNumber
+	case "parent_id":
+		if c.ParentID == nil {
+			return ""
+		}
+		return *c.ParentID
+	case "status":
+		return c.Status
+	case "status_changed_at":
+		if c.StatusChangedAt == nil {
+			return nil
+		}
+		return *c.StatusChangedAt
+	case "tags":
+		names := make([]string, 0, len(c.Tags))
+		for _, tag := range c.Tags {
+			names = append(names, tag.Name)
+		}
+		return strings.Join(names, ",")
+	case "created_at":
+		return c.CreatedAt
+	case "updated_at":
+		return c.UpdatedAt
+	}
+
+	if key, ok := strings.CutPrefix(column, CustomFieldPrefix); ok {
+		return c.CustomFields[key]
+	}
+	return nil
+}
+
+// SortValue geeft de waarde van een sorteerveld (zie SortFields), voor het maken van een cursor
+func (c *Customer) SortValue(field string) interface{} {
+	return c.ExportValue(field)
 }
 
 type CustomerFilter struct {
-	SearchTerm string
-	Page       int
-	PageSize   int
-	SortBy     string
-	SortOrder  string
+	SearchTerm      string
+	Tags            []string          // Tag namen om op te filteren
+	TagMatch        string            // TagMatchAny of TagMatchAll
+	CustomFields    map[string]string // Vrije veld key -> waarde (exacte match), zoals opgegeven in cf.<key>
+	Page            int
+	PageSize        int
+	After           *pagination.Cursor // Keyset paginering: de rijen na deze cursor
+	Before          *pagination.Cursor // Keyset paginering: de rijen voor deze cursor
+	Count           string             // pagination.CountExact, CountEstimate of CountNone
+	Sort            []sorting.Field    // Sorteervelden; leeg sorteert op naam
+	Expression      filterExpr.Node    // Filterexpressie uit de filter parameter, bijv. created_at >= 2024-01-01
+	FieldTypes      map[string]string  // Vrij veld key -> JSON type van de waarde, door de service ingevuld voor de filterexpressie
+	FieldValues     map[string]string  // Vrij veld key -> waarde uit CustomFields als JSON, door de service genormaliseerd
+	GroupID         uint               // Alleen deze klant en alle klanten die (indirect) onder deze klant vallen
+	Statuses        []string           // Status keys om op te filteren (een van deze statussen)
+	MinDaysInStatus int                // Alleen klanten die minstens zoveel dagen in hun huidige status staan
+	MaxDaysInStatus int                // Alleen klanten die hoogstens zoveel dagen in hun huidige status staan
+}
+
+// HierarchyNode is een klant in een deelboom van de hiërarchie. De tellingen met descendant en total
+// gaan over de klant en alle klanten die (indirect) onder de klant vallen.
+type HierarchyNode struct {
+	ID                 uint   `json:"id"`
+	ParentID           *uint  `json:"parent_id"`
+	Name               string `json:"name"`
+	Depth              int    `json:"depth"` // 0 voor de opgevraagde klant
+	ChildCount         int64  `json:"child_count"`
+	DescendantCount    int64  `json:"descendant_count"`
+	ActivityCount      int64  `json:"activity_count"`
+	TotalActivityCount int64  `json:"total_activity_count"`
+}
+
+// Ancestor is een klant boven een klant in de hiërarchie
+type Ancestor struct {
+	ID       uint   `json:"id"`
+	ParentID *uint  `json:"parent_id"`
+	Name     string `json:"name"`
+	Level    int    `json:"level"` // 1 voor de moederklant, 2 voor de moederklant daarvan, enz.
+}
+
+// SortFields zijn de velden waarop klanten gesorteerd kunnen worden; vrije velden als cf.<key>.
+// De EncryptedFields zijn niet sorteerbaar.
+var SortFields = []string{"id", "name", "kvk_number", "status", "status_changed_at", "created_at", "updated_at", CustomFieldPrefix}
+
+// EncryptedFields zijn de velden die versleuteld worden opgeslagen. Een lege waarde wordt niet versleuteld,
+// dus er kan wel op aanwezigheid gefilterd worden (phone is null), maar niet op de waarde of gesorteerd.
+var EncryptedFields = []string{"email", "phone", "address"}
+
+// CheckEncryptedFilter geeft een fout als een filterexpressie een versleuteld veld met iets anders dan
+// is null of is not null gebruikt
+func CheckEncryptedFilter(node filterExpr.Node) error {
+	for _, comparison := range filterExpr.Fields(node) {
+		if !IsEncryptedField(comparison.Field.Text) {
+			continue
+		}
+		if comparison.Op.Text != filterExpr.OpIsNull && comparison.Op.Text != filterExpr.OpIsNotNull {
+			return &filterExpr.Error{Pos: comparison.Op.Pos, Token: comparison.Op.Text,
+				Message: comparison.Field.Text + " wordt versleuteld opgeslagen; gebruik is null of is not null"}
+		}
+	}
+	return nil
+}
+
+// IsEncryptedField geeft aan of een veld versleuteld wordt opgeslagen (zie EncryptedFields)
+func IsEncryptedField(name string) bool {
+	for _, field := range EncryptedFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Redenen waarom een klant als mogelijke dubbele klant wordt gezien
+const (
+	DuplicateReasonEmail = "email"
+	DuplicateReasonKvK   = "kvk"
+	DuplicateReasonName  = "name"
+)
+
+// DuplicateCandidate is een bestaande klant die lijkt op een nieuwe of andere klant
+type DuplicateCandidate struct {
+	Customer   Customer `json:"customer"`
+	Reasons    []string `json:"reasons"`
+	Similarity float64  `json:"similarity"`
+}
+
+// DuplicatePair is een paar bestaande klanten dat waarschijnlijk dezelfde klant is
+type DuplicatePair struct {
+	Customer   Customer `json:"customer"`
+	Duplicate  Customer `json:"duplicate"`
+	Reasons    []string `json:"reasons"`
+	Similarity float64  `json:"similarity"`
+}
+
+// Keuzes per veld bij het samenvoegen van klanten
+const (
+	MergePickTarget = "target"
+	MergePickSource = "source"
+)
+
+// MergeRequest beschrijft het samenvoegen van de bronklant in de doelklant.
+// Fields bepaalt per veld (name, email, phone, address, kvk_number, vat_number of cf.<key>) welke waarde
+// behouden blijft. Zonder keuze wint de doelklant, tenzij die geen waarde heeft.
+type MergeRequest struct {
+	SourceID uint              `json:"source_id" binding:"required"`
+	TargetID uint              `json:"target_id" binding:"required"`
+	Fields   map[string]string `json:"fields"`
+}
+
+// StatusChange is een regel in de statusgeschiedenis van een klant
+type StatusChange struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CustomerID uint      `json:"customer_id" gorm:"not null;index"`
+	FromStatus string    `json:"from_status" gorm:"size:50;not null;default:''"`
+	ToStatus   string    `json:"to_status" gorm:"size:50;not null"`
+	Note       string    `json:"note"`
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username" gorm:"size:100"`
+	ChangedAt  time.Time `json:"changed_at" gorm:"not null;index"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (StatusChange) TableName() string {
+	return "customer_status_changes"
+}
+
+// StatusChangeRequest is het verzoek om een klant naar een andere status te verplaatsen
+type StatusChangeRequest struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note"`
+}
+
+// LegalHoldRequest is het verzoek om een legal hold op een klant te zetten of op te heffen
+type LegalHoldRequest struct {
+	LegalHold *bool  `json:"legal_hold" binding:"required"`
+	Reason    string `json:"reason"` // Verplicht bij het zetten van een legal hold
+}
+
+// BoardColumn is een kolom van het pipelinebord: de klanten in één status. Count is het totaal aantal
+// klanten in de status, Customers bevat hoogstens de gevraagde limiet, langst in de status eerst.
+type BoardColumn struct {
+	Status    string     `json:"status"`
+	Label     string     `json:"label"`
+	Count     int64      `json:"count"`
+	Customers []Customer `json:"customers"`
 }
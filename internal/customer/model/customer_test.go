@@ -0,0 +1,78 @@
+package model
+
+import (
+	tagModel "odomosml/internal/tag/model"
+	"testing"
+	"time"
+)
+
+func TestExportValue(t *testing.T) {
+	created := time.Date(2024, 2, 25, 20, 30, 0, 0, time.UTC)
+	changed := created.Add(time.Hour)
+	parentID := uint(7)
+
+	customer := &Customer{
+		ID: 3, Name: "Bakkerij Jansen", Email: "info@jansen.nl", KvKNumber: "12345678",
+		ParentID: &parentID, Status: "active", StatusChangedAt: &changed,
+		Tags:         []tagModel.Tag{{Name: "vip"}, {Name: "noord"}},
+		CustomFields: CustomFields{"regio": "noord", "omzet": 1000},
+		CreatedAt:    created,
+	}
+	empty := &Customer{}
+
+	tests := []struct {
+		customer *Customer
+		column   string
+		want     interface{}
+	}{
+		{customer, "id", uint(3)},
+		{customer, "name", "Bakkerij Jansen"},
+		{customer, "kvk_number", "12345678"},
+		{customer, "parent_id", uint(7)},
+		{customer, "status_changed_at", changed},
+		{customer, "tags", "vip,noord"},
+		{customer, "created_at", created},
+		{customer, "cf.regio", "noord"},
+		{customer, "cf.omzet", 1000},
+		{customer, "cf.branche", nil},
+		{customer, "website", nil},
+		{empty, "parent_id", ""},
+		{empty, "status_changed_at", nil},
+		{empty, "tags", ""},
+		{empty, "cf.regio", nil},
+	}
+
+	for _, tt := range tests {
+		if got := tt.customer.ExportValue(tt.column); got != tt.want {
+			t.Errorf("ExportValue(%s) = %#v, verwacht %#v", tt.column, got, tt.want)
+		}
+	}
+}
+
+func TestETag(t *testing.T) {
+	customer := &Customer{ID: 3, Name: "Bakkerij Jansen", Version: 2, Tags: []tagModel.Tag{{ID: 1, Name: "vip"}, {ID: 2, Name: "noord"}}}
+	etag := customer.ETag()
+
+	// De volgorde van de tags uit de database maakt niet uit, en de klant zelf blijft ongewijzigd
+	reordered := *customer
+	reordered.Tags = []tagModel.Tag{customer.Tags[1], customer.Tags[0]}
+	if got := reordered.ETag(); got != etag {
+		t.Errorf("ETag met andere tagvolgorde = %s, verwacht %s", got, etag)
+	}
+	if reordered.Tags[0].ID != 2 {
+		t.Error("ETag heeft de tags van de klant gesorteerd")
+	}
+
+	// Een gewijzigde tag geeft een andere ETag, ook als de versie van de klant gelijk blijft
+	retagged := *customer
+	retagged.Tags = []tagModel.Tag{{ID: 1, Name: "vip"}}
+	if retagged.ETag() == etag {
+		t.Error("ETag gelijk na het verwijderen van een tag")
+	}
+
+	bumped := *customer
+	bumped.Version++
+	if bumped.ETag() == etag {
+		t.Error("ETag gelijk bij een nieuwe versie")
+	}
+}
@@ -3,21 +3,60 @@ package repository
 import (
 	"errors"
 	"odomosml/internal/customer/model"
+	customFieldModel "odomosml/internal/customfield/model"
+	tagModel "odomosml/internal/tag/model"
+	"odomosml/pkg/concurrency"
+	"odomosml/pkg/fieldcrypt"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
 	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CustomerRepository definieert de interface voor customer repository
 type CustomerRepository interface {
-	FindAll(filter model.CustomerFilter) ([]model.Customer, int64, error)
+	FindAll(filter model.CustomerFilter) ([]model.Customer, pagination.Info, error)
+	Stream(filter model.CustomerFilter, fn func(customer *model.Customer) error) error
 	FindByID(id string) (*model.Customer, error)
+	FindByEmail(email string) (*model.Customer, error)
+	FindByKvKNumber(kvkNumber string) (*model.Customer, error)
 	Create(customer *model.Customer) (*model.Customer, error)
 	Update(customer *model.Customer) (*model.Customer, error)
-	PartialUpdate(id uint, updates map[string]interface{}) (*model.Customer, error)
-	Delete(id string) error
+	Delete(id string, version uint) error
+	FindDuplicateCandidates(customer *model.Customer, threshold float64, limit int) ([]model.DuplicateCandidate, error)
+	FindDuplicatePairs(threshold float64, offset, limit int) ([]model.DuplicatePair, error)
+	Merge(target *model.Customer, sourceID uint) (*model.Customer, error)
+	FindAncestors(id uint) ([]model.Ancestor, error)
+	FindSubtree(id uint, maxDepth int) ([]model.HierarchyNode, error)
+	ChangeStatus(id uint, version uint, change *model.StatusChange) (*model.Customer, error)
+	FindStatusHistory(customerID uint) ([]model.StatusChange, error)
+	CountByStatus(filter model.CustomerFilter) (map[string]int64, error)
+	FindBoard(filter model.CustomerFilter, limit int) ([]model.Customer, error)
+	SetLegalHold(id uint, version uint, hold bool, reason string) (*model.Customer, error)
+	CountPurgeable(deletedBefore time.Time) (purgeable int64, held int64, err error)
+	PurgeDeleted(deletedBefore time.Time, limit int) (int64, error)
 }
 
+// customerReferences zijn de tabellen met een customer_id kolom. Bij het definitief verwijderen van een
+// klant worden deze rijen verwijderd, bij het samenvoegen verhuizen ze naar de doelklant.
+var customerReferences = []string{"activities", "appointments", "attachments", "customer_status_changes", "deals", "price_agreements", "tasks"}
+
+// customerRetained zijn de tabellen met een customer_id kolom waarvan de rijen bewaard moeten blijven,
+// zoals offertes en facturen. Een klant met zulke rijen kan niet verwijderd worden; bij het samenvoegen
+// verhuizen ze naar de doelklant.
+var customerRetained = []string{"invoices"}
+
+// ErrLegalHold betekent dat een klant een legal hold heeft en daarom niet in een andere klant opgaan mag
+var ErrLegalHold = errors.New("klant heeft een legal hold en kan niet samengevoegd worden")
+
+// ErrHasDocuments betekent dat een klant offertes of facturen heeft en daarom niet verwijderd kan worden
+var ErrHasDocuments = errors.New("klant heeft offertes of facturen en kan niet verwijderd worden")
+
 // customerRepository implementeert de CustomerRepository interface
 type customerRepository struct {
 	db *gorm.DB
@@ -30,38 +69,84 @@ func NewCustomerRepository(db *gorm.DB) CustomerRepository {
 	}
 }
 
-// FindAll haalt alle klanten op met filters
-func (r *customerRepository) FindAll(filter model.CustomerFilter) ([]model.Customer, int64, error) {
+// FindAll haalt alle klanten op met filters, op paginanummer of vanaf een cursor
+func (r *customerRepository) FindAll(filter model.CustomerFilter) ([]model.Customer, pagination.Info, error) {
 	var customers []model.Customer
-	var total int64
 
 	// Bouw query
-	query := r.db.Model(&model.Customer{})
+	query, err := applyFilter(r.db.Model(&model.Customer{}), filter)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
 
-	// Filters toepassen
-	if filter.SearchTerm != "" {
-		searchTerm := "%" + filter.SearchTerm + "%"
-		query = query.Where("name ILIKE ? OR email ILIKE ?", searchTerm, searchTerm)
+	// Tel (of schat) het totaal aantal records
+	total, estimated, err := pagination.Count(query, filter.Count)
+	if err != nil {
+		return nil, pagination.Info{}, err
 	}
 
-	// Tel totaal aantal records (voor paginering)
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	// Paginering en sortering toepassen
+	request := pagination.Request{Page: filter.Page, Size: filter.PageSize, After: filter.After, Before: filter.Before}
+	fields := sortFields(filter)
+	query, err = pagination.Apply(query, request, fields, customerSortColumn, "id")
+	if err != nil {
+		return nil, pagination.Info{}, err
 	}
 
-	// Paginering toepassen
-	offset := (filter.Page - 1) * filter.PageSize
-	query = query.Offset(offset).Limit(filter.PageSize)
+	// Voer query uit
+	if err := query.Preload("Tags").Find(&customers).Error; err != nil {
+		return nil, pagination.Info{}, err
+	}
 
-	// Sorteer op naam
-	query = query.Order("name ASC")
+	customers, info := pagination.Window(customers, request, func(customer *model.Customer) pagination.Cursor {
+		return pagination.NewCursor(fields, customerSortColumn, customer.ID, customer.SortValue)
+	})
+	info.Total, info.Estimated = total, estimated
 
-	// Voer query uit
-	if err := query.Find(&customers).Error; err != nil {
-		return nil, 0, err
+	return customers, info, nil
+}
+
+// exportRow is een klant met de namen van de tags, zodat tags zonder preload meegestreamd kunnen worden
+type exportRow struct {
+	model.Customer
+	TagNames customFieldModel.StringList
+}
+
+// Stream doorloopt alle klanten die aan het filter voldoen via een database cursor,
+// zonder de volledige lijst in het geheugen te laden. Paginering in het filter wordt genegeerd.
+func (r *customerRepository) Stream(filter model.CustomerFilter, fn func(customer *model.Customer) error) error {
+	query, err := applyFilter(r.db.Model(&model.Customer{}), filter)
+	if err != nil {
+		return err
+	}
+	// Als JSON lijst, zodat tagnamen met een komma heel blijven
+	query = query.Select("customers.*, (SELECT json_agg(t.name ORDER BY t.name) FROM customer_tags ct " +
+		"JOIN tags t ON t.id = ct.tag_id WHERE ct.customer_id = customers.id) AS tag_names")
+
+	rows, err := applyOrder(query, filter).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row exportRow
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+
+		customer := row.Customer
+		customer.Tags = nil
+		for _, name := range row.TagNames {
+			customer.Tags = append(customer.Tags, tagModel.Tag{Name: name})
+		}
+
+		if err := fn(&customer); err != nil {
+			return err
+		}
 	}
 
-	return customers, total, nil
+	return rows.Err()
 }
 
 // FindByID haalt een klant op op basis van ID
@@ -75,7 +160,7 @@ func (r *customerRepository) FindByID(id string) (*model.Customer, error) {
 	}
 
 	// Zoek klant
-	if err := r.db.First(&customer, idInt).Error; err != nil {
+	if err := r.db.Preload("Tags").First(&customer, idInt).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("klant niet gevonden")
 		}
@@ -85,9 +170,44 @@ func (r *customerRepository) FindByID(id string) (*model.Customer, error) {
 	return &customer, nil
 }
 
+// FindByEmail haalt de oudste klant met dit email adres op (hoofdletterongevoelig, via de blind index).
+// Retourneert nil zonder fout als die er niet is.
+func (r *customerRepository) FindByEmail(email string) (*model.Customer, error) {
+	index := fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, email)
+	if index == "" {
+		return nil, nil
+	}
+	return r.findFirst("email_index = ?", index)
+}
+
+// FindByKvKNumber haalt de oudste klant met dit KvK nummer op.
+// Retourneert nil zonder fout als die er niet is.
+func (r *customerRepository) FindByKvKNumber(kvkNumber string) (*model.Customer, error) {
+	if kvkNumber == "" {
+		return nil, nil
+	}
+	return r.findFirst("kvk_number = ?", kvkNumber)
+}
+
+// findFirst haalt de eerste klant op die aan de voorwaarde voldoet, of nil
+func (r *customerRepository) findFirst(condition string, args ...interface{}) (*model.Customer, error) {
+	var customer model.Customer
+
+	err := r.db.Preload("Tags").Where(condition, args...).Order("id ASC").First(&customer).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &customer, nil
+}
+
 // Create maakt een nieuwe klant aan
 func (r *customerRepository) Create(customer *model.Customer) (*model.Customer, error) {
-	if err := r.db.Create(customer).Error; err != nil {
+	// Tags worden via de tag endpoints beheerd, niet via de klant zelf
+	if err := r.db.Omit(clause.Associations).Create(customer).Error; err != nil {
 		return nil, err
 	}
 	return customer, nil
@@ -95,40 +215,603 @@ func (r *customerRepository) Create(customer *model.Customer) (*model.Customer,
 
 // Update werkt een bestaande klant bij
 func (r *customerRepository) Update(customer *model.Customer) (*model.Customer, error) {
-	if err := r.db.Save(customer).Error; err != nil {
+	if err := updateVersioned(r.db, customer); err != nil {
 		return nil, err
 	}
-	return customer, nil
+	return r.FindByID(strconv.FormatUint(uint64(customer.ID), 10))
+}
+
+// customerUpdateColumns zijn de kolommen die bij het bijwerken van een volledige klant geschreven worden
+var customerUpdateColumns = []string{"name", "email", "email_index", "phone", "address", "kvk_number", "vat_number", "custom_fields", "parent_id", "version", "updated_at"}
+
+// updateVersioned schrijft een klant alleen als de versie in de database nog customer.Version is en
+// hoogt de versie op; is de klant intussen gewijzigd, dan is het resultaat concurrency.ErrConflict
+func updateVersioned(db *gorm.DB, customer *model.Customer) error {
+	expected := customer.Version
+	customer.Version = expected + 1
+
+	result := db.Model(customer).Omit(clause.Associations).Where("version = ?", expected).
+		Select(customerUpdateColumns).Updates(customer)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = concurrency.ErrConflict
+	}
+	if result.Error != nil {
+		customer.Version = expected
+	}
+	return result.Error
 }
 
-// PartialUpdate werkt een deel van een bestaande klant bij
-func (r *customerRepository) PartialUpdate(id uint, updates map[string]interface{}) (*model.Customer, error) {
-	// Update klant
-	if err := r.db.Model(&model.Customer{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+// Delete verwijdert een klant, als de klant nog de opgegeven versie heeft. De klant wordt als verwijderd
+// gemarkeerd (soft delete) en met gekoppelde gegevens pas na de bewaartermijn door PurgeDeleted opgeruimd.
+func (r *customerRepository) Delete(id string, version uint) error {
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return errors.New("ongeldig ID formaat")
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Vergrendel de klant, zodat er tussen de versiecontrole en het verwijderen niets meer kan wijzigen
+		var current model.Customer
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "version").Take(&current, idInt).Error
+		if err != nil {
+			return err
+		}
+		if current.Version != version {
+			return concurrency.ErrConflict
+		}
+
+		for _, table := range customerRetained {
+			var count int64
+			if err := tx.Table(table).Where("customer_id = ?", idInt).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrHasDocuments
+			}
+		}
+
+		// Onderliggende klanten worden zelfstandig
+		err = tx.Exec("UPDATE customers SET parent_id = NULL, version = version + 1 WHERE parent_id = ?", idInt).Error
+		if err != nil {
+			return err
+		}
+
+		// De klant zelf valt ook niet meer onder zijn moederklant
+		return tx.Model(&model.Customer{ID: uint(idInt)}).Updates(map[string]interface{}{
+			"parent_id":  nil,
+			"deleted_at": time.Now(),
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+	})
+}
+
+// SetLegalHold zet of verwijdert de legal hold van een klant, als de klant nog de opgegeven versie heeft
+func (r *customerRepository) SetLegalHold(id uint, version uint, hold bool, reason string) (*model.Customer, error) {
+	result := r.db.Model(&model.Customer{}).Where("id = ? AND version = ?", id, version).Updates(map[string]interface{}{
+		"legal_hold":        hold,
+		"legal_hold_reason": reason,
+		"version":           gorm.Expr("version + 1"),
+		"updated_at":        time.Now(),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, concurrency.ErrConflict
+	}
+
+	return r.FindByID(strconv.FormatUint(uint64(id), 10))
+}
+
+// purgeable geeft de verwijderde klanten die voor deletedBefore verwijderd zijn en geen legal hold hebben
+func (r *customerRepository) purgeable(tx *gorm.DB, deletedBefore time.Time) *gorm.DB {
+	return tx.Unscoped().Model(&model.Customer{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND NOT legal_hold", deletedBefore)
+}
+
+// CountPurgeable telt de klanten die PurgeDeleted zou verwijderen, en de verwijderde klanten die
+// vanwege een legal hold bewaard blijven
+func (r *customerRepository) CountPurgeable(deletedBefore time.Time) (int64, int64, error) {
+	var purgeable, held int64
+	if err := r.purgeable(r.db, deletedBefore).Count(&purgeable).Error; err != nil {
+		return 0, 0, err
+	}
+	err := r.db.Unscoped().Model(&model.Customer{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND legal_hold", deletedBefore).Count(&held).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return purgeable, held, nil
+}
+
+// PurgeDeleted verwijdert hoogstens limit klanten die voor deletedBefore verwijderd zijn definitief,
+// inclusief gekoppelde gegevens en tag koppelingen, in één transactie. Klanten met een legal hold
+// blijven staan. Bestanden van bijlagen blijven in de opslag staan (zie README).
+func (r *customerRepository) PurgeDeleted(deletedBefore time.Time, limit int) (int64, error) {
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Klanten die een andere instantie op dit moment verwijdert worden overgeslagen
+		var ids []uint
+		err := r.purgeable(tx, deletedBefore).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Order("id ASC").Limit(limit).Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		for _, table := range customerReferences {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE customer_id IN ?", ids).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM customer_tags WHERE customer_id IN ?", ids).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Delete(&model.Customer{}, ids)
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+// duplicateCandidateRow is een klant met de redenen waarom deze op een andere klant lijkt
+type duplicateCandidateRow struct {
+	model.Customer
+	Similarity float64
+	EmailMatch bool
+	KvKMatch   bool `gorm:"column:kvk_match"`
+}
+
+// FindDuplicateCandidates zoekt bestaande klanten met hetzelfde email adres of KvK nummer,
+// of met een naam die minstens threshold op de naam van de klant lijkt (pg_trgm).
+// De threshold moet minstens de pg_trgm standaard (0.3) zijn, zodat de % operator de trigram index kan gebruiken.
+func (r *customerRepository) FindDuplicateCandidates(customer *model.Customer, threshold float64, limit int) ([]model.DuplicateCandidate, error) {
+	var rows []duplicateCandidateRow
+
+	err := r.db.Raw(`
+		SELECT customers.*,
+			similarity(name, @name) AS similarity,
+			(@email <> '' AND email_index = @email) AS email_match,
+			(@kvk <> '' AND kvk_number = @kvk) AS kvk_match
+		FROM customers
+		WHERE id <> @id AND deleted_at IS NULL
+			AND ((@email <> '' AND email_index = @email)
+				OR (@kvk <> '' AND kvk_number = @kvk)
+				OR (name % @name AND similarity(name, @name) >= @threshold))
+		ORDER BY email_match DESC, kvk_match DESC, similarity DESC, id ASC
+		LIMIT @limit`,
+		map[string]interface{}{
+			"id":        customer.ID,
+			"name":      customer.Name,
+			"email":     fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, customer.Email),
+			"kvk":       customer.KvKNumber,
+			"threshold": threshold,
+			"limit":     limit,
+		}).Scan(&rows).Error
+	if err != nil {
 		return nil, err
 	}
 
-	// Haal bijgewerkte klant op
-	var customer model.Customer
-	if err := r.db.First(&customer, id).Error; err != nil {
+	candidates := make([]model.DuplicateCandidate, 0, len(rows))
+	for _, row := range rows {
+		candidates = append(candidates, model.DuplicateCandidate{
+			Customer:   row.Customer,
+			Reasons:    duplicateReasons(row.EmailMatch, row.KvKMatch, row.Similarity, threshold),
+			Similarity: row.Similarity,
+		})
+	}
+
+	return candidates, nil
+}
+
+// duplicatePairRow is een paar klanten uit het dubbele klanten rapport
+type duplicatePairRow struct {
+	AID        uint
+	AName      string
+	AEmail     string `gorm:"serializer:encrypted"`
+	APhone     string `gorm:"serializer:encrypted"`
+	AKvKNumber string
+	ACreatedAt time.Time
+	BID        uint
+	BName      string
+	BEmail     string `gorm:"serializer:encrypted"`
+	BPhone     string `gorm:"serializer:encrypted"`
+	BKvKNumber string
+	BCreatedAt time.Time
+	Similarity float64
+	EmailMatch bool
+	KvKMatch   bool `gorm:"column:kvk_match"`
+}
+
+// FindDuplicatePairs zoekt paren van bestaande klanten die waarschijnlijk dezelfde klant zijn.
+// Elk paar wordt één keer teruggegeven, met de oudste klant eerst.
+func (r *customerRepository) FindDuplicatePairs(threshold float64, offset, limit int) ([]model.DuplicatePair, error) {
+	var rows []duplicatePairRow
+
+	err := r.db.Raw(`
+		SELECT a.id AS a_id, a.name AS a_name, a.email AS a_email, a.phone AS a_phone, a.kvk_number AS a_kvk_number, a.created_at AS a_created_at,
+			b.id AS b_id, b.name AS b_name, b.email AS b_email, b.phone AS b_phone, b.kvk_number AS b_kvk_number, b.created_at AS b_created_at,
+			similarity(a.name, b.name) AS similarity,
+			(a.email_index <> '' AND a.email_index = b.email_index) AS email_match,
+			(a.kvk_number <> '' AND a.kvk_number = b.kvk_number) AS kvk_match
+		FROM customers a
+		JOIN customers b ON a.id < b.id AND b.deleted_at IS NULL
+			AND ((a.email_index <> '' AND a.email_index = b.email_index)
+				OR (a.kvk_number <> '' AND a.kvk_number = b.kvk_number)
+				OR (a.name % b.name AND similarity(a.name, b.name) >= @threshold))
+		WHERE a.deleted_at IS NULL
+		ORDER BY email_match DESC, kvk_match DESC, similarity DESC, a.id ASC, b.id ASC
+		OFFSET @offset LIMIT @limit`,
+		map[string]interface{}{
+			"threshold": threshold,
+			"offset":    offset,
+			"limit":     limit,
+		}).Scan(&rows).Error
+	if err != nil {
 		return nil, err
 	}
 
-	return &customer, nil
+	pairs := make([]model.DuplicatePair, 0, len(rows))
+	for _, row := range rows {
+		pairs = append(pairs, model.DuplicatePair{
+			Customer: model.Customer{
+				ID: row.AID, Name: row.AName, Email: row.AEmail, Phone: row.APhone, KvKNumber: row.AKvKNumber, CreatedAt: row.ACreatedAt,
+			},
+			Duplicate: model.Customer{
+				ID: row.BID, Name: row.BName, Email: row.BEmail, Phone: row.BPhone, KvKNumber: row.BKvKNumber, CreatedAt: row.BCreatedAt,
+			},
+			Reasons:    duplicateReasons(row.EmailMatch, row.KvKMatch, row.Similarity, threshold),
+			Similarity: row.Similarity,
+		})
+	}
+
+	return pairs, nil
 }
 
-// Delete verwijdert een klant
-func (r *customerRepository) Delete(id string) error {
-	// Converteer string ID naar uint
-	idInt, err := strconv.Atoi(id)
+// Merge voegt de bronklant samen in de doelklant: gekoppelde gegevens en tags verhuizen naar
+// de doelklant, de doelklant krijgt de samengevoegde velden en de bronklant wordt verwijderd
+func (r *customerRepository) Merge(target *model.Customer, sourceID uint) (*model.Customer, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// De bronklant wordt definitief verwijderd; met een legal hold moeten zijn gegevens blijven
+		var source model.Customer
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "legal_hold").Take(&source, sourceID).Error
+		if err != nil {
+			return err
+		}
+		if source.LegalHold {
+			return ErrLegalHold
+		}
+
+		// Bijlagen met dezelfde inhoud als een bijlage van de doelklant zijn overbodig;
+		// het bestand in de opslag blijft via de bijlage van de doelklant in gebruik
+		err = tx.Exec(`DELETE FROM attachments s WHERE s.customer_id = ?
+			AND EXISTS (SELECT 1 FROM attachments t WHERE t.customer_id = ? AND t.checksum = s.checksum)`,
+			sourceID, target.ID).Error
+		if err != nil {
+			return err
+		}
+
+		for _, table := range append(customerReferences, customerRetained...) {
+			if err := tx.Exec("UPDATE "+table+" SET customer_id = ? WHERE customer_id = ?", target.ID, sourceID).Error; err != nil {
+				return err
+			}
+		}
+
+		// Onderliggende klanten van de bronklant vallen voortaan onder de doelklant
+		err = tx.Exec("UPDATE customers SET parent_id = ?, version = version + 1 WHERE parent_id = ? AND id <> ?",
+			target.ID, sourceID, target.ID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`INSERT INTO customer_tags (customer_id, tag_id)
+			SELECT ?, tag_id FROM customer_tags WHERE customer_id = ?
+			ON CONFLICT DO NOTHING`, target.ID, sourceID).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Select("Tags").Delete(&model.Customer{ID: sourceID}).Error; err != nil {
+			return err
+		}
+
+		return updateVersioned(tx, target)
+	})
 	if err != nil {
-		return errors.New("ongeldig ID formaat")
+		return nil, err
 	}
 
-	// Verwijder klant
-	if err := r.db.Delete(&model.Customer{}, idInt).Error; err != nil {
-		return err
+	var merged model.Customer
+	if err := r.db.Preload("Tags").First(&merged, target.ID).Error; err != nil {
+		return nil, err
+	}
+
+	return &merged, nil
+}
+
+// duplicateReasons vertaalt de match kolommen naar redenen
+func duplicateReasons(emailMatch, kvkMatch bool, similarity, threshold float64) []string {
+	var reasons []string
+	if emailMatch {
+		reasons = append(reasons, model.DuplicateReasonEmail)
+	}
+	if kvkMatch {
+		reasons = append(reasons, model.DuplicateReasonKvK)
+	}
+	if similarity >= threshold {
+		reasons = append(reasons, model.DuplicateReasonName)
+	}
+	return reasons
+}
+
+// applyFilter past de zoekterm, tag, vrije veld en filterexpressie toe op een klanten query
+func applyFilter(query *gorm.DB, filter model.CustomerFilter) (*gorm.DB, error) {
+	// De zoekterm vindt klanten via de full-text kolom (ook op Nederlandse woordvormen), voor gedeeltelijke
+	// woorden via ILIKE op de naam, en als volledig e-mailadres via de blind index. E-mail, telefoon en
+	// adres zijn versleuteld en daarom niet gedeeltelijk te doorzoeken.
+	if filter.SearchTerm != "" {
+		searchTerm := "%" + filter.SearchTerm + "%"
+		query = query.Where("search_vector @@ websearch_to_tsquery('dutch', ?) OR name ILIKE ? OR email_index = ?",
+			filter.SearchTerm, searchTerm, fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, filter.SearchTerm))
+	}
+
+	if len(filter.Tags) > 0 {
+		query = applyTagFilter(query, filter.Tags, filter.TagMatch)
+	}
+
+	// Filter op vrije velden; keys en waarden zijn door de service gevalideerd en als JSON genormaliseerd,
+	// zodat bijv. 12 ook een opgeslagen 12.0 vindt
+	for key, value := range filter.FieldValues {
+		query = query.Where("customers.custom_fields->? = CAST(? AS jsonb)", key, value)
+	}
+
+	if filter.GroupID != 0 {
+		query = query.Where("customers.id IN ("+subtreeIDs+")", filter.GroupID)
+	}
+
+	if len(filter.Statuses) > 0 {
+		query = query.Where("customers.status IN ?", filter.Statuses)
+	}
+
+	// Tijd in de huidige status; klanten zonder statusmoment vallen buiten beide filters
+	if filter.MinDaysInStatus > 0 {
+		query = query.Where("customers.status_changed_at <= now() - make_interval(days => ?)", filter.MinDaysInStatus)
+	}
+	if filter.MaxDaysInStatus > 0 {
+		query = query.Where("customers.status_changed_at >= now() - make_interval(days => ?)", filter.MaxDaysInStatus)
+	}
+
+	if filter.Expression != nil {
+		if err := model.CheckEncryptedFilter(filter.Expression); err != nil {
+			return nil, err
+		}
+		condition, vars, err := filterExpr.Compile(filter.Expression, customerFilterColumn(filter.FieldTypes))
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(condition, vars...)
+	}
+
+	return query, nil
+}
+
+// customerColumns koppelt de vaste velden waarop gesorteerd (zie model.SortFields) en gefilterd kan worden aan hun kolom
+var customerColumns = map[string]sorting.Column{
+	"id":                {SQL: "customers.id", Type: "bigint"},
+	"name":              {SQL: "customers.name", Type: "text"},
+	"kvk_number":        {SQL: "customers.kvk_number", Type: "text"},
+	"vat_number":        {SQL: "customers.vat_number", Type: "text"},
+	"parent_id":         {SQL: "customers.parent_id", Type: "bigint", Nullable: true},
+	"status":            {SQL: "customers.status", Type: "text"},
+	"status_changed_at": {SQL: "customers.status_changed_at", Type: "timestamptz", Nullable: true},
+	"created_at":        {SQL: "customers.created_at", Type: "timestamptz"},
+	"updated_at":        {SQL: "customers.updated_at", Type: "timestamptz"},
+
+	// Versleuteld: alleen te filteren op is null / is not null (zie model.CheckEncryptedFilter) en niet sorteerbaar
+	"email":   {SQL: "customers.email", Type: "text"},
+	"phone":   {SQL: "customers.phone", Type: "text"},
+	"address": {SQL: "customers.address", Type: "text"},
+}
+
+// customerSortColumn geeft de SQL expressie van een sorteerveld. Vrije velden sorteren op de
+// jsonb waarde; jsonb vergelijkt getallen, datums (ISO) en tekst correct.
+func customerSortColumn(name string) sorting.Column {
+	if key, ok := strings.CutPrefix(name, model.CustomFieldPrefix); ok {
+		return sorting.Column{SQL: "customers.custom_fields->?", Vars: []interface{}{key}, Type: "jsonb", Nullable: true}
+	}
+	return customerColumns[name]
+}
+
+// customerFilterColumn geeft de kolommen van de velden in een filterexpressie; de keys van vrije velden zijn
+// door de service gevalideerd en fieldTypes geeft per key het JSON type van de waarde
+func customerFilterColumn(fieldTypes map[string]string) func(name string) (sorting.Column, bool) {
+	return func(name string) (sorting.Column, bool) {
+		if key, ok := strings.CutPrefix(name, model.CustomFieldPrefix); ok {
+			col := customerSortColumn(name)
+			col.ValueType = fieldTypes[key]
+			return col, true
+		}
+		if _, ok := customerColumns[name]; ok {
+			return customerSortColumn(name), true
+		}
+		return sorting.Column{}, false
+	}
+}
+
+// sortFields geeft de sorteervelden uit het filter, met de naam als standaard
+func sortFields(filter model.CustomerFilter) []sorting.Field {
+	if len(filter.Sort) == 0 {
+		return []sorting.Field{{Name: "name"}}
+	}
+	return filter.Sort
+}
+
+// applyOrder past de sortering uit het filter toe, met het ID als tiebreaker
+func applyOrder(query *gorm.DB, filter model.CustomerFilter) *gorm.DB {
+	return sorting.Apply(query, sortFields(filter), customerSortColumn, "id")
+}
+
+// applyTagFilter beperkt de query tot klanten met een of alle opgegeven tags
+func applyTagFilter(query *gorm.DB, tags []string, match string) *gorm.DB {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, strings.ToLower(tag))
+	}
+
+	subQuery := "SELECT ct.customer_id FROM customer_tags ct JOIN tags t ON t.id = ct.tag_id WHERE LOWER(t.name) IN ?"
+	if match == model.TagMatchAll {
+		subQuery += " GROUP BY ct.customer_id HAVING COUNT(DISTINCT t.id) = ?"
+		return query.Where("customers.id IN ("+subQuery+")", names, len(names))
+	}
+
+	return query.Where("customers.id IN ("+subQuery+")", names)
+}
+
+// subtreeIDs selecteert de ID's van een klant en alle klanten die (indirect) onder de klant vallen.
+// UNION (zonder ALL) stopt ook als de hiërarchie door gelijktijdige wijzigingen toch een kring bevat.
+const subtreeIDs = `WITH RECURSIVE subtree AS (
+		SELECT id FROM customers WHERE id = ? AND deleted_at IS NULL
+		UNION
+		SELECT c.id FROM customers c JOIN subtree s ON c.parent_id = s.id WHERE c.deleted_at IS NULL
+	) SELECT id FROM subtree`
+
+// FindAncestors haalt de klanten boven een klant op, van de moederklant tot de bovenste klant
+func (r *customerRepository) FindAncestors(id uint) ([]model.Ancestor, error) {
+	var ancestors []model.Ancestor
+
+	err := r.db.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT p.id, p.parent_id, p.name, 1 AS level, ARRAY[c.id, p.id] AS path
+			FROM customers c JOIN customers p ON p.id = c.parent_id AND p.deleted_at IS NULL
+			WHERE c.id = ? AND c.deleted_at IS NULL
+			UNION ALL
+			SELECT p.id, p.parent_id, p.name, a.level + 1, a.path || p.id
+			FROM customers p JOIN ancestors a ON p.id = a.parent_id
+			WHERE NOT p.id = ANY(a.path) AND p.deleted_at IS NULL
+		)
+		SELECT id, parent_id, name, level FROM ancestors ORDER BY level`, id).
+		Scan(&ancestors).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ancestors, nil
+}
+
+// FindSubtree haalt een klant en de klanten daaronder op tot maxDepth niveaus diep, in boomvolgorde
+// (elke klant direct gevolgd door de klanten eronder, op naam). De tellingen gaan altijd over de
+// volledige deelboom, ook als maxDepth deze afkapt.
+func (r *customerRepository) FindSubtree(id uint, maxDepth int) ([]model.HierarchyNode, error) {
+	var nodes []model.HierarchyNode
+
+	err := r.db.Raw(`WITH RECURSIVE tree AS (
+			SELECT c.id, c.parent_id, c.name, 0 AS depth, ARRAY[c.id] AS path, ARRAY[lower(c.name) || '/' || c.id] AS sort_path
+			FROM customers c WHERE c.id = @id AND c.deleted_at IS NULL
+			UNION ALL
+			SELECT c.id, c.parent_id, c.name, t.depth + 1, t.path || c.id, t.sort_path || (lower(c.name) || '/' || c.id)
+			FROM customers c JOIN tree t ON c.parent_id = t.id
+			WHERE NOT c.id = ANY(t.path) AND c.deleted_at IS NULL
+		),
+		counts AS (
+			SELECT t.id, t.path, (SELECT count(*) FROM activities a WHERE a.customer_id = t.id) AS activity_count
+			FROM tree t
+		)
+		SELECT t.id, t.parent_id, t.name, t.depth,
+			(SELECT count(*) FROM tree d WHERE d.parent_id = t.id) AS child_count,
+			(SELECT count(*) FROM counts d WHERE t.id = ANY(d.path)) - 1 AS descendant_count,
+			own.activity_count,
+			(SELECT sum(d.activity_count) FROM counts d WHERE t.id = ANY(d.path))::bigint AS total_activity_count
+		FROM tree t JOIN counts own ON own.id = t.id
+		WHERE t.depth <= @max_depth
+		ORDER BY t.sort_path`,
+		map[string]interface{}{"id": id, "max_depth": maxDepth}).
+		Scan(&nodes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// ChangeStatus zet een klant in een andere status en legt de overgang vast in de statusgeschiedenis,
+// als de klant nog de opgegeven versie heeft
+func (r *customerRepository) ChangeStatus(id uint, version uint, change *model.StatusChange) (*model.Customer, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Customer{}).Where("id = ? AND version = ?", id, version).Updates(map[string]interface{}{
+			"status":            change.ToStatus,
+			"status_changed_at": change.ChangedAt,
+			"version":           gorm.Expr("version + 1"),
+			"updated_at":        change.ChangedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return concurrency.ErrConflict
+		}
+
+		change.CustomerID = id
+		return tx.Create(change).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(strconv.FormatUint(uint64(id), 10))
+}
+
+// FindStatusHistory haalt de statusgeschiedenis van een klant op, nieuwste overgang eerst
+func (r *customerRepository) FindStatusHistory(customerID uint) ([]model.StatusChange, error) {
+	var changes []model.StatusChange
+	if err := r.db.Where("customer_id = ?", customerID).Order("changed_at DESC, id DESC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// statusCount is het aantal klanten in een status
+type statusCount struct {
+	Status string
+	Count  int64
+}
+
+// CountByStatus telt per status de klanten die aan het filter voldoen
+func (r *customerRepository) CountByStatus(filter model.CustomerFilter) (map[string]int64, error) {
+	query, err := applyFilter(r.db.Model(&model.Customer{}), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []statusCount
+	if err := query.Select("customers.status AS status, COUNT(*) AS count").Group("customers.status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// FindBoard haalt per status hoogstens limit klanten op die aan het filter voldoen,
+// de klanten die het langst in hun status staan eerst
+func (r *customerRepository) FindBoard(filter model.CustomerFilter, limit int) ([]model.Customer, error) {
+	ranked, err := applyFilter(r.db.Model(&model.Customer{}), filter)
+	if err != nil {
+		return nil, err
+	}
+	ranked = ranked.Select("customers.id, ROW_NUMBER() OVER (PARTITION BY customers.status " +
+		"ORDER BY customers.status_changed_at ASC NULLS FIRST, customers.id) AS board_rank")
+
+	var customers []model.Customer
+	err = r.db.Preload("Tags").
+		Where("customers.id IN (?)", r.db.Table("(?) AS ranked", ranked).Select("id").Where("board_rank <= ?", limit)).
+		Order("customers.status_changed_at ASC NULLS FIRST, customers.id").
+		Find(&customers).Error
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return customers, nil
 }
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"odomosml/internal/customer/model"
+	_ "odomosml/pkg/fieldcrypt" // registreert de serializer voor versleutelde velden
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestApplyTagFilter(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("gorm.Open gaf fout: %v", err)
+	}
+
+	subQuery := "customers.id IN (SELECT ct.customer_id FROM customer_tags ct JOIN tags t ON t.id = ct.tag_id WHERE LOWER(t.name) IN ($1,$2)"
+
+	tests := []struct {
+		name     string
+		match    string
+		want     string
+		wantVars []interface{}
+	}{
+		// Een of meer van de tags; tags worden zonder op hoofdletters te letten vergeleken
+		{name: "any", match: model.TagMatchAny, want: subQuery + ")", wantVars: []interface{}{"vip", "prospect"}},
+		// Alle tags: de klant moet evenveel verschillende tags hebben als er gevraagd zijn
+		{name: "all", match: model.TagMatchAll, want: subQuery + " GROUP BY ct.customer_id HAVING COUNT(DISTINCT t.id) = $3)", wantVars: []interface{}{"vip", "prospect", 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := applyTagFilter(db.Model(&model.Customer{}), []string{"VIP", "Prospect"}, tt.match)
+			stmt := query.Find(&[]map[string]interface{}{}).Statement
+			if sql := stmt.SQL.String(); !strings.Contains(sql, tt.want) {
+				t.Errorf("query bevat geen %q:\n%s", tt.want, sql)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantVars) {
+				t.Errorf("parameters = %v, verwacht %v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
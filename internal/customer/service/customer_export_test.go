@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"odomosml/internal/customer/model"
+	customFieldModel "odomosml/internal/customfield/model"
+	customFieldService "odomosml/internal/customfield/service"
+	"reflect"
+	"testing"
+)
+
+// fakeCustomFields kent alleen de vrije velden "branche" en "regio"
+type fakeCustomFields struct {
+	customFieldService.CustomFieldService
+}
+
+var fakeDefinitions = []customFieldModel.CustomFieldDefinition{{ID: 1, Key: "branche"}, {ID: 2, Key: "regio"}}
+
+func (fakeCustomFields) GetAllDefinitions() ([]customFieldModel.CustomFieldDefinition, error) {
+	return fakeDefinitions, nil
+}
+
+func (fakeCustomFields) GetDefinitionByKey(key string) (*customFieldModel.CustomFieldDefinition, error) {
+	for i := range fakeDefinitions {
+		if fakeDefinitions[i].Key == key {
+			return &fakeDefinitions[i], nil
+		}
+	}
+	return nil, errors.New("velddefinitie niet gevonden")
+}
+
+func TestResolveExportColumns(t *testing.T) {
+	service := NewCustomerService(&fakeCustomerRepository{}, fakeCustomFields{}, nil)
+	exportColumns := append([]string{}, model.ExportColumns...)
+
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name: "zonder kolommen alle vaste kolommen en vrije velden",
+			want: append(append([]string{}, model.ExportColumns...), "cf.branche", "cf.regio"),
+		},
+		{
+			name:      "gevraagde volgorde, ontdubbeld en getrimd",
+			requested: []string{" name", "cf.regio", "id ", "name", "", "cf.regio"},
+			want:      []string{"name", "cf.regio", "id"},
+		},
+		{name: "onbekende kolom", requested: []string{"name", "website"}, wantErr: "onbekende kolom 'website'"},
+		{name: "onbekend vrij veld", requested: []string{"cf.omzet"}, wantErr: "onbekend vrij veld 'omzet'"},
+		{name: "alleen lege kolommen", requested: []string{"", " "}, wantErr: "geen kolommen opgegeven"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.ResolveExportColumns(tt.requested)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ResolveExportColumns gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveExportColumns gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kolommen = %v, verwacht %v", got, tt.want)
+			}
+		})
+	}
+
+	// De standaardkolommen mogen de lijst in het model niet aanpassen
+	if !reflect.DeepEqual(model.ExportColumns, exportColumns) {
+		t.Errorf("model.ExportColumns gewijzigd: %v", model.ExportColumns)
+	}
+}
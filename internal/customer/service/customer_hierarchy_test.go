@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"odomosml/internal/customer/model"
+	"odomosml/pkg/validation"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func (r *fakeCustomerRepository) FindByID(id string) (*model.Customer, error) {
+	r.calls = append(r.calls, "FindByID:"+id)
+	for _, customer := range r.customers {
+		if strconv.FormatUint(uint64(customer.ID), 10) == id {
+			copied := customer
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("klant niet gevonden")
+}
+
+func (r *fakeCustomerRepository) FindAncestors(id uint) ([]model.Ancestor, error) {
+	var ancestors []model.Ancestor
+	for level := 1; ; level++ {
+		customer, err := r.FindByID(strconv.FormatUint(uint64(id), 10))
+		if err != nil || customer.ParentID == nil {
+			return ancestors, nil
+		}
+		parent, _ := r.FindByID(strconv.FormatUint(uint64(*customer.ParentID), 10))
+		ancestors = append(ancestors, model.Ancestor{ID: parent.ID, ParentID: parent.ParentID, Name: parent.Name, Level: level})
+		id = parent.ID
+	}
+}
+
+func (r *fakeCustomerRepository) FindSubtree(id uint, maxDepth int) ([]model.HierarchyNode, error) {
+	r.calls = append(r.calls, "FindSubtree:"+strconv.Itoa(maxDepth))
+	return []model.HierarchyNode{{ID: id}}, nil
+}
+
+func (fakeCustomFields) NormalizeValues(values map[string]interface{}) (map[string]interface{}, error) {
+	return values, nil
+}
+
+func parentID(id uint) *uint {
+	return &id
+}
+
+// hierarchy is Holding (1) met Noord (2) en Zuid (3) eronder, en Filiaal Groningen (4) onder Noord;
+// Jansen (5) hoort niet bij de groep
+func hierarchy() *fakeCustomerRepository {
+	return &fakeCustomerRepository{customers: []model.Customer{
+		{ID: 1, Name: "Holding", Email: "info@holding.nl"},
+		{ID: 2, Name: "Noord", Email: "noord@holding.nl", ParentID: parentID(1)},
+		{ID: 3, Name: "Zuid", Email: "zuid@holding.nl", ParentID: parentID(1)},
+		{ID: 4, Name: "Filiaal Groningen", Email: "groningen@holding.nl", ParentID: parentID(2)},
+		{ID: 5, Name: "Jansen", Email: "info@jansen.nl"},
+	}}
+}
+
+func TestValidateParent(t *testing.T) {
+	tests := []struct {
+		name     string
+		customer model.Customer
+		wantErr  string
+	}{
+		{name: "zonder moederklant", customer: model.Customer{ID: 2}},
+		{name: "nieuwe klant onder een filiaal", customer: model.Customer{ParentID: parentID(4)}},
+		{name: "verhuizen naar een andere tak", customer: model.Customer{ID: 4, ParentID: parentID(3)}},
+		{name: "groep onder een losse klant", customer: model.Customer{ID: 1, ParentID: parentID(5)}},
+		{name: "onder zichzelf", customer: model.Customer{ID: 2, ParentID: parentID(2)}, wantErr: "een klant kan niet onder zichzelf vallen"},
+		{name: "onbekende moederklant", customer: model.Customer{ID: 2, ParentID: parentID(9)}, wantErr: "moederklant 9 bestaat niet"},
+		{name: "onder een eigen dochter", customer: model.Customer{ID: 1, ParentID: parentID(2)}, wantErr: "moederklant 2 valt onder deze klant; dat zou een kring opleveren"},
+		{name: "onder een eigen kleindochter", customer: model.Customer{ID: 1, ParentID: parentID(4)}, wantErr: "moederklant 4 valt onder deze klant; dat zou een kring opleveren"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewCustomerService(hierarchy(), fakeCustomFields{}, nil).(*customerService)
+			customer := tt.customer
+			err := service.validateParent(&customer)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateParent gaf fout: %v", err)
+				}
+				return
+			}
+			fields, _ := validation.Fields(err)
+			if len(fields) != 1 || fields[0].Field != "parent_id" || err.Error() != tt.wantErr {
+				t.Errorf("validateParent gaf %v (%+v), verwacht %q op parent_id", err, fields, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetAncestors(t *testing.T) {
+	service := NewCustomerService(hierarchy(), fakeCustomFields{}, nil)
+
+	ancestors, err := service.GetAncestors("4")
+	if err != nil {
+		t.Fatalf("GetAncestors gaf fout: %v", err)
+	}
+	want := []model.Ancestor{{ID: 2, ParentID: parentID(1), Name: "Noord", Level: 1}, {ID: 1, Name: "Holding", Level: 2}}
+	if !reflect.DeepEqual(ancestors, want) {
+		t.Errorf("GetAncestors = %+v, verwacht %+v", ancestors, want)
+	}
+
+	// Een klant zonder moederklant geeft een lege lijst, zodat de JSON [] is en geen null
+	if ancestors, err := service.GetAncestors("1"); err != nil || ancestors == nil || len(ancestors) != 0 {
+		t.Errorf("GetAncestors(1) = %#v, %v", ancestors, err)
+	}
+	if _, err := service.GetAncestors("9"); err == nil {
+		t.Error("GetAncestors van een onbekende klant gaf geen fout")
+	}
+}
+
+func TestGetSubtreeDepth(t *testing.T) {
+	for _, tt := range []struct{ depth, want int }{{0, 20}, {-1, 20}, {1, 1}, {20, 20}, {21, 20}} {
+		repo := hierarchy()
+		if _, err := NewCustomerService(repo, fakeCustomFields{}, nil).GetSubtree("1", tt.depth); err != nil {
+			t.Fatalf("GetSubtree gaf fout: %v", err)
+		}
+		if want := "FindSubtree:" + strconv.Itoa(tt.want); repo.calls[len(repo.calls)-1] != want {
+			t.Errorf("GetSubtree(%d) riep %v aan, verwacht %s", tt.depth, repo.calls, want)
+		}
+	}
+}
+
+func TestMergeCustomersHierarchy(t *testing.T) {
+	// Een groep samenvoegen in een eigen filiaal zou het filiaal onder zichzelf hangen
+	service := NewCustomerService(hierarchy(), fakeCustomFields{}, nil)
+	_, _, err := service.MergeCustomers(model.MergeRequest{SourceID: 2, TargetID: 4})
+	if err == nil || err.Error() != "de doelklant valt onder de bronklant; voeg de klanten in omgekeerde richting samen" {
+		t.Errorf("MergeCustomers gaf %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"odomosml/internal/customer/model"
+	"odomosml/internal/customer/repository"
+	"reflect"
+	"testing"
+)
+
+// fakeCustomerRepository implementeert alleen de methodes die een test nodig heeft; de rest van de
+// interface is nil en laat een test die er toch bij komt direct falen
+type fakeCustomerRepository struct {
+	repository.CustomerRepository
+	pairs     []model.DuplicatePair
+	customers []model.Customer
+	calls     []string
+}
+
+func (r *fakeCustomerRepository) FindDuplicatePairs(threshold float64, offset, limit int) ([]model.DuplicatePair, error) {
+	r.calls = append(r.calls, "FindDuplicatePairs")
+	if offset >= len(r.pairs) {
+		return nil, nil
+	}
+	return r.pairs[offset:min(offset+limit, len(r.pairs))], nil
+}
+
+func TestMergeCustomerFields(t *testing.T) {
+	source := &model.Customer{ID: 2, Name: "Jansen B.V.", Email: "info@jansen.nl", Phone: "020-1234567", KvKNumber: "12345678",
+		CustomFields: model.CustomFields{"regio": "noord", "omzet": 1000, "contact": "Piet"}}
+	target := &model.Customer{ID: 1, Name: "Bakkerij Jansen", Email: "bakkerij@jansen.nl", Address: "Dorpsstraat 1",
+		CustomFields: model.CustomFields{"regio": "zuid", "klantnummer": "K-1"}}
+
+	tests := []struct {
+		name    string
+		picks   map[string]string
+		want    model.Customer
+		wantErr string
+	}{
+		{
+			name: "zonder keuzes wint de doelklant, lege velden van de bron",
+			want: model.Customer{ID: 1, Name: "Bakkerij Jansen", Email: "bakkerij@jansen.nl", Phone: "020-1234567", Address: "Dorpsstraat 1", KvKNumber: "12345678",
+				CustomFields: model.CustomFields{"regio": "zuid", "klantnummer": "K-1", "omzet": 1000, "contact": "Piet"}},
+		},
+		{
+			name:  "keuze voor de bron",
+			picks: map[string]string{"name": "source", "email": "source", "cf.regio": "source"},
+			want: model.Customer{ID: 1, Name: "Jansen B.V.", Email: "info@jansen.nl", Phone: "020-1234567", Address: "Dorpsstraat 1", KvKNumber: "12345678",
+				CustomFields: model.CustomFields{"regio": "noord", "klantnummer": "K-1", "omzet": 1000, "contact": "Piet"}},
+		},
+		{
+			name:  "lege waarde van de bron kiezen wist het veld",
+			picks: map[string]string{"address": "source", "cf.klantnummer": "source", "phone": "target", "cf.omzet": "target"},
+			want: model.Customer{ID: 1, Name: "Bakkerij Jansen", Email: "bakkerij@jansen.nl", KvKNumber: "12345678",
+				CustomFields: model.CustomFields{"regio": "zuid", "contact": "Piet"}},
+		},
+		{
+			name:    "ongeldige keuzes",
+			picks:   map[string]string{"name": "beide", "website": "source", "cf.onbekend": "target"},
+			wantErr: "keuze voor 'name' moet 'source' of 'target' zijn; onbekend veld 'website'; vrij veld 'onbekend' komt bij geen van beide klanten voor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := mergeCustomerFields(source, target, tt.picks)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("mergeCustomerFields gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeCustomerFields gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(*merged, tt.want) {
+				t.Errorf("samengevoegd = %+v, verwacht %+v", *merged, tt.want)
+			}
+		})
+	}
+
+	// De klanten zelf blijven ongewijzigd
+	if len(target.CustomFields) != 2 || target.Phone != "" || len(source.CustomFields) != 3 {
+		t.Errorf("klanten gewijzigd: bron %+v, doel %+v", source, target)
+	}
+}
+
+func TestNormalizeKvKNumber(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"12345678", "12345678", false},
+		{"1234 5678", "12345678", false},
+		{"12.34.56.78", "12345678", false},
+		{"1234567", "", true},
+		{"123456789", "", true},
+		{"1234567a", "", true},
+	}
+
+	for _, tt := range tests {
+		value := tt.value
+		err := normalizeKvKNumber(&value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeKvKNumber(%q) gaf %v", tt.value, err)
+			continue
+		}
+		if !tt.wantErr && value != tt.want {
+			t.Errorf("normalizeKvKNumber(%q) = %q, verwacht %q", tt.value, value, tt.want)
+		}
+	}
+}
+
+func TestGetDuplicatePairs(t *testing.T) {
+	pairs := make([]model.DuplicatePair, 25)
+	for i := range pairs {
+		pairs[i].Customer.ID = uint(i + 1)
+	}
+	service := NewCustomerService(&fakeCustomerRepository{pairs: pairs}, nil, nil)
+
+	tests := []struct {
+		name        string
+		threshold   float64
+		page        int
+		pageSize    int
+		wantFirst   uint
+		wantCount   int
+		wantHasMore bool
+		wantErr     bool
+	}{
+		{name: "standaard", wantFirst: 1, wantCount: 10, wantHasMore: true},
+		{name: "laatste pagina", threshold: 0.8, page: 3, pageSize: 10, wantFirst: 21, wantCount: 5},
+		{name: "precies vol", threshold: 0.3, page: 1, pageSize: 25, wantFirst: 1, wantCount: 25},
+		{name: "te grote pagina", threshold: 1, page: 2, pageSize: 500, wantFirst: 11, wantCount: 10, wantHasMore: true},
+		{name: "drempel te laag", threshold: 0.2, wantErr: true},
+		{name: "drempel te hoog", threshold: 1.5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasMore, err := service.GetDuplicatePairs(tt.threshold, tt.page, tt.pageSize)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("GetDuplicatePairs gaf geen fout")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetDuplicatePairs gaf fout: %v", err)
+			}
+			if len(got) != tt.wantCount || got[0].Customer.ID != tt.wantFirst || hasMore != tt.wantHasMore {
+				t.Errorf("%d paren vanaf %d, meer: %v; verwacht %d vanaf %d, meer: %v",
+					len(got), got[0].Customer.ID, hasMore, tt.wantCount, tt.wantFirst, tt.wantHasMore)
+			}
+		})
+	}
+}
+
+func TestMergeCustomersWithItself(t *testing.T) {
+	repo := &fakeCustomerRepository{}
+	_, _, err := NewCustomerService(repo, nil, nil).MergeCustomers(model.MergeRequest{SourceID: 3, TargetID: 3})
+	if err == nil || err.Error() != "een klant kan niet met zichzelf samengevoegd worden" {
+		t.Errorf("MergeCustomers gaf %v", err)
+	}
+	if len(repo.calls) != 0 {
+		t.Errorf("repository aangeroepen: %v", repo.calls)
+	}
+}
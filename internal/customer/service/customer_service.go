@@ -1,86 +1,312 @@
 package service
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/mail"
 	"odomosml/internal/customer/model"
 	"odomosml/internal/customer/repository"
+	customerStatusService "odomosml/internal/customerstatus/service"
+	customFieldService "odomosml/internal/customfield/service"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/patch"
+	"odomosml/pkg/validation"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Instellingen voor het zoeken naar dubbele klanten
+const (
+	// duplicateNameThreshold is de minimale pg_trgm similarity waarop twee namen als dubbel gelden
+	duplicateNameThreshold = 0.6
+	// minDuplicateThreshold is de pg_trgm standaard; lager kan de trigram index niet gebruiken
+	minDuplicateThreshold = 0.3
+	// maxDuplicateCandidates is het maximum aantal kandidaten bij het aanmaken van een klant
+	maxDuplicateCandidates = 10
+	// maxSubtreeDepth is het maximum (en standaard) aantal niveaus onder een klant in een deelboom
+	maxSubtreeDepth = 20
+	// defaultBoardLimit en maxBoardLimit begrenzen het aantal klanten per kolom van het pipelinebord
+	defaultBoardLimit = 25
+	maxBoardLimit     = 100
+)
+
+// kvkPattern valideert een KvK nummer (8 cijfers)
+var kvkPattern = regexp.MustCompile(`^[0-9]{8}$`)
+
+// vatPattern valideert een BTW-identificatienummer: landcode en 2 tot 12 cijfers of letters.
+// Voor Nederlandse nummers geldt het strengere nlVATPattern.
+var (
+	vatPattern   = regexp.MustCompile(`^[A-Z]{2}[0-9A-Z]{2,12}$`)
+	nlVATPattern = regexp.MustCompile(`^NL[0-9]{9}B[0-9]{2}$`)
+)
+
+// mergeFields zijn de klantvelden die bij het samenvoegen per veld gekozen kunnen worden
+var mergeFields = []struct {
+	name  string
+	field func(c *model.Customer) *string
+}{
+	{"name", func(c *model.Customer) *string { return &c.Name }},
+	{"email", func(c *model.Customer) *string { return &c.Email }},
+	{"phone", func(c *model.Customer) *string { return &c.Phone }},
+	{"address", func(c *model.Customer) *string { return &c.Address }},
+	{"kvk_number", func(c *model.Customer) *string { return &c.KvKNumber }},
+	{"vat_number", func(c *model.Customer) *string { return &c.VATNumber }},
+}
+
 // CustomerService definieert de interface voor customer service
 type CustomerService interface {
-	GetAllCustomers(filter model.CustomerFilter) ([]model.Customer, int64, error)
+	GetAllCustomers(filter model.CustomerFilter) ([]model.Customer, pagination.Info, error)
+	ResolveExportColumns(requested []string) ([]string, error)
+	ExportCustomers(filter model.CustomerFilter, fn func(customer *model.Customer) error) error
 	GetCustomerByID(id string) (*model.Customer, error)
 	CreateCustomer(customer *model.Customer) (*model.Customer, error)
 	UpdateCustomer(customer *model.Customer) (*model.Customer, error)
-	PartialUpdateCustomer(id string, updates map[string]interface{}) (*model.Customer, error)
-	DeleteCustomer(id string) (map[string]interface{}, error)
+	PatchCustomer(id string, version uint, mediaType string, body []byte) (*model.Customer, error)
+	DeleteCustomer(id string, version uint) (map[string]interface{}, error)
+	FindDuplicates(customer *model.Customer) ([]model.DuplicateCandidate, error)
+	GetDuplicatePairs(threshold float64, page, pageSize int) ([]model.DuplicatePair, bool, error)
+	MergeCustomers(request model.MergeRequest) (*model.Customer, map[string]interface{}, error)
+	ValidateCustomer(customer *model.Customer) error
+	FindByEmail(email string) (*model.Customer, error)
+	FindByKvKNumber(kvkNumber string) (*model.Customer, error)
+	GetAncestors(id string) ([]model.Ancestor, error)
+	GetSubtree(id string, maxDepth int) ([]model.HierarchyNode, error)
+	ChangeStatus(id string, version uint, change *model.StatusChange) (*model.Customer, error)
+	GetStatusHistory(id string) ([]model.StatusChange, error)
+	GetBoard(filter model.CustomerFilter, limit int) ([]model.BoardColumn, error)
+	ValidateFilterExpression(expression string) error
+	SetLegalHold(id string, version uint, request model.LegalHoldRequest) (*model.Customer, error)
 }
 
 // customerService implementeert de CustomerService interface
 type customerService struct {
-	repo repository.CustomerRepository
+	repo         repository.CustomerRepository
+	customFields customFieldService.CustomFieldService
+	statuses     customerStatusService.CustomerStatusService
 }
 
 // NewCustomerService maakt een nieuwe CustomerService instantie
-func NewCustomerService(repo repository.CustomerRepository) CustomerService {
+func NewCustomerService(repo repository.CustomerRepository, customFields customFieldService.CustomFieldService, statuses customerStatusService.CustomerStatusService) CustomerService {
 	return &customerService{
-		repo: repo,
+		repo:         repo,
+		customFields: customFields,
+		statuses:     statuses,
 	}
 }
 
 // GetAllCustomers haalt alle klanten op met filters
-func (s *customerService) GetAllCustomers(filter model.CustomerFilter) ([]model.Customer, int64, error) {
+func (s *customerService) GetAllCustomers(filter model.CustomerFilter) ([]model.Customer, pagination.Info, error) {
+	if err := s.validateFilter(&filter); err != nil {
+		return nil, pagination.Info{}, err
+	}
+
 	return s.repo.FindAll(filter)
 }
 
+// ResolveExportColumns controleert de gevraagde exportkolommen. Zonder kolommen worden alle vaste
+// kolommen en alle vrije velden geëxporteerd.
+func (s *customerService) ResolveExportColumns(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		columns := append([]string{}, model.ExportColumns...)
+		definitions, err := s.customFields.GetAllDefinitions()
+		if err != nil {
+			return nil, err
+		}
+		for _, definition := range definitions {
+			columns = append(columns, model.CustomFieldPrefix+definition.Key)
+		}
+		return columns, nil
+	}
+
+	known := make(map[string]bool, len(model.ExportColumns))
+	for _, column := range model.ExportColumns {
+		known[column] = true
+	}
+
+	seen := make(map[string]bool, len(requested))
+	columns := make([]string, 0, len(requested))
+	for _, column := range requested {
+		column = strings.TrimSpace(column)
+		if column == "" || seen[column] {
+			continue
+		}
+		if key, ok := strings.CutPrefix(column, model.CustomFieldPrefix); ok {
+			if _, err := s.customFields.GetDefinitionByKey(key); err != nil {
+				return nil, fmt.Errorf("onbekend vrij veld '%s'", key)
+			}
+		} else if !known[column] {
+			return nil, fmt.Errorf("onbekende kolom '%s'", column)
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		return nil, errors.New("geen kolommen opgegeven")
+	}
+	return columns, nil
+}
+
+// ExportCustomers roept fn aan voor elke klant die aan het filter voldoet, zonder paginering
+func (s *customerService) ExportCustomers(filter model.CustomerFilter, fn func(customer *model.Customer) error) error {
+	if err := s.validateFilter(&filter); err != nil {
+		return err
+	}
+
+	return s.repo.Stream(filter, fn)
+}
+
+// validateFilter controleert dat alleen bestaande vrije velden gebruikt worden om op te filteren of sorteren,
+// normaliseert de waarden van de cf.<key> parameters volgens de definitie van het veld (bijv. ja wordt
+// true en 12,0 wordt 12) en vult het type van de vrije velden in de filterexpressie in
+func (s *customerService) validateFilter(filter *model.CustomerFilter) error {
+	filter.FieldValues = make(map[string]string, len(filter.CustomFields))
+	for key, value := range filter.CustomFields {
+		definition, err := s.customFields.GetDefinitionByKey(key)
+		if err != nil {
+			return validation.New(model.CustomFieldPrefix+key, "onbekend vrij veld '%s'", key)
+		}
+		normalized, err := definition.Normalize(value)
+		if err != nil {
+			return validation.New(model.CustomFieldPrefix+key, "vrij veld '%s' %v", key, err)
+		}
+		encoded, err := json.Marshal(normalized)
+		if err != nil {
+			return err
+		}
+		filter.FieldValues[key] = string(encoded)
+	}
+
+	for _, field := range filter.Sort {
+		if key, ok := strings.CutPrefix(field.Name, model.CustomFieldPrefix); ok {
+			if _, err := s.customFields.GetDefinitionByKey(key); err != nil {
+				return fmt.Errorf("onbekend vrij veld '%s'", key)
+			}
+		}
+	}
+
+	filter.FieldTypes = make(map[string]string)
+	for _, comparison := range filterExpr.Fields(filter.Expression) {
+		if key, ok := strings.CutPrefix(comparison.Field.Text, model.CustomFieldPrefix); ok {
+			definition, err := s.customFields.GetDefinitionByKey(key)
+			if err != nil {
+				return &filterExpr.Error{Pos: comparison.Field.Pos, Token: comparison.Field.Text, Message: "onbekend vrij veld"}
+			}
+			filter.FieldTypes[key] = definition.Type.JSONType()
+		}
+	}
+
+	return model.CheckEncryptedFilter(filter.Expression)
+}
+
+// ValidateFilterExpression controleert een filterexpressie voor de klantenlijst zonder hem uit te voeren,
+// bijv. voor het opslaan in een weergave
+func (s *customerService) ValidateFilterExpression(expression string) error {
+	node, err := filterExpr.Parse(expression)
+	if err != nil {
+		return err
+	}
+	return s.validateFilter(&model.CustomerFilter{Expression: node})
+}
+
 // GetCustomerByID haalt een klant op op basis van ID
 func (s *customerService) GetCustomerByID(id string) (*model.Customer, error) {
 	return s.repo.FindByID(id)
 }
 
-// CreateCustomer maakt een nieuwe klant aan
+// CreateCustomer maakt een nieuwe klant aan. Zonder status krijgt de klant de initiële status van de workflow.
 func (s *customerService) CreateCustomer(customer *model.Customer) (*model.Customer, error) {
-	// Validatie
-	if customer.Name == "" {
-		return nil, errors.New("naam is verplicht")
+	if err := s.ValidateCustomer(customer); err != nil {
+		return nil, err
+	}
+	if err := s.initialStatus(customer); err != nil {
+		return nil, err
 	}
 
 	return s.repo.Create(customer)
 }
 
-// UpdateCustomer werkt een bestaande klant bij
+// initialStatus controleert de status van een nieuwe klant of vult de initiële status in
+func (s *customerService) initialStatus(customer *model.Customer) error {
+	customer.Status = strings.TrimSpace(customer.Status)
+	if customer.Status != "" {
+		if _, err := s.statuses.GetStatusByKey(customer.Status); err != nil {
+			return validation.New("status", "onbekende status '%s'", customer.Status)
+		}
+	} else {
+		statuses, err := s.statuses.GetAllStatuses()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			if status.Initial {
+				customer.Status = status.Key
+				break
+			}
+		}
+	}
+
+	if customer.Status != "" {
+		now := time.Now()
+		customer.StatusChangedAt = &now
+	} else {
+		customer.StatusChangedAt = nil
+	}
+	return nil
+}
+
+// UpdateCustomer werkt een bestaande klant bij, als de klant in de database nog customer.Version heeft
 func (s *customerService) UpdateCustomer(customer *model.Customer) (*model.Customer, error) {
 	// Validatie
 	if customer.ID == 0 {
 		return nil, errors.New("klant ID is verplicht")
 	}
 
-	if customer.Name == "" {
-		return nil, errors.New("naam is verplicht")
+	if err := s.ValidateCustomer(customer); err != nil {
+		return nil, err
 	}
 
 	return s.repo.Update(customer)
 }
 
-// PartialUpdateCustomer werkt een deel van een bestaande klant bij
-func (s *customerService) PartialUpdateCustomer(id string, updates map[string]interface{}) (*model.Customer, error) {
-	// Validatie
-	if id == "" {
-		return nil, errors.New("klant ID is verplicht")
+// PatchCustomer past een JSON Merge Patch of JSON Patch toe op een bestaande klant en werkt de klant bij,
+// als deze nog de opgegeven versie heeft. Alleen de velden in model.WritableFields mogen veranderen; daarna
+// volgt dezelfde validatie als bij het bijwerken van een volledige klant.
+func (s *customerService) PatchCustomer(id string, version uint, mediaType string, body []byte) (*model.Customer, error) {
+	customer, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
 	}
 
-	// Controleer of klant bestaat
-	customer, err := s.repo.FindByID(id)
+	original, err := json.Marshal(customer)
 	if err != nil {
 		return nil, err
 	}
+	patched, err := patch.Apply(mediaType, original, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := patch.CheckFields(original, patched, model.WritableFields...); err != nil {
+		return nil, err
+	}
 
-	// Update velden
-	return s.repo.PartialUpdate(customer.ID, updates)
+	var updated model.Customer
+	if err := patch.Decode(patched, &updated); err != nil {
+		return nil, err
+	}
+	updated.ID = customer.ID
+	updated.Version = version
+
+	return s.UpdateCustomer(&updated)
 }
 
-// DeleteCustomer verwijdert een klant
-func (s *customerService) DeleteCustomer(id string) (map[string]interface{}, error) {
+// DeleteCustomer verwijdert een klant, als de klant nog de opgegeven versie heeft
+func (s *customerService) DeleteCustomer(id string, version uint) (map[string]interface{}, error) {
 	// Haal klant op voor audit logging
 	customer, err := s.repo.FindByID(id)
 	if err != nil {
@@ -88,16 +314,456 @@ func (s *customerService) DeleteCustomer(id string) (map[string]interface{}, err
 	}
 
 	// Converteer naar map voor audit logging
-	customerData := map[string]interface{}{
-		"id":    customer.ID,
-		"name":  customer.Name,
-		"email": customer.Email,
-	}
+	customerData := customer.ToAuditMap()
 
 	// Verwijder klant
-	if err := s.repo.Delete(id); err != nil {
+	if err := s.repo.Delete(id, version); err != nil {
 		return nil, err
 	}
 
 	return customerData, nil
 }
+
+// FindDuplicates zoekt bestaande klanten die waarschijnlijk dezelfde klant zijn:
+// hetzelfde email adres, hetzelfde KvK nummer of een sterk gelijkende naam
+func (s *customerService) FindDuplicates(customer *model.Customer) ([]model.DuplicateCandidate, error) {
+	probe := *customer
+	probe.Name = strings.TrimSpace(probe.Name)
+	probe.Email = strings.TrimSpace(probe.Email)
+	if err := normalizeKvKNumber(&probe.KvKNumber); err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindDuplicateCandidates(&probe, duplicateNameThreshold, maxDuplicateCandidates)
+}
+
+// GetDuplicatePairs haalt een pagina met paren van waarschijnlijk dubbele klanten op.
+// Een threshold van 0 gebruikt de standaard; has_more geeft aan of er nog een pagina is.
+func (s *customerService) GetDuplicatePairs(threshold float64, page, pageSize int) ([]model.DuplicatePair, bool, error) {
+	if threshold == 0 {
+		threshold = duplicateNameThreshold
+	}
+	if threshold < minDuplicateThreshold || threshold > 1 {
+		return nil, false, fmt.Errorf("threshold moet tussen %.1f en 1 liggen", minDuplicateThreshold)
+	}
+
+	// Valideer paginering
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10 // Default page size
+	}
+
+	pairs, err := s.repo.FindDuplicatePairs(threshold, (page-1)*pageSize, pageSize+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(pairs) > pageSize
+	if hasMore {
+		pairs = pairs[:pageSize]
+	}
+
+	return pairs, hasMore, nil
+}
+
+// MergeCustomers voegt de bronklant samen in de doelklant en retourneert de samengevoegde klant
+// en de gegevens van beide klanten van voor het samenvoegen voor audit logging
+func (s *customerService) MergeCustomers(request model.MergeRequest) (*model.Customer, map[string]interface{}, error) {
+	if request.SourceID == request.TargetID {
+		return nil, nil, errors.New("een klant kan niet met zichzelf samengevoegd worden")
+	}
+
+	source, err := s.repo.FindByID(fmt.Sprint(request.SourceID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("bronklant: %w", err)
+	}
+	target, err := s.repo.FindByID(fmt.Sprint(request.TargetID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("doelklant: %w", err)
+	}
+
+	merged, err := mergeCustomerFields(source, target, request.Fields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.ValidateCustomer(merged); err != nil {
+		return nil, nil, err
+	}
+
+	// Onderliggende klanten van de bronklant verhuizen naar de doelklant; valt de doelklant zelf onder
+	// de bronklant, dan zou dat een kring opleveren
+	ancestors, err := s.repo.FindAncestors(target.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.ID == source.ID {
+			return nil, nil, errors.New("de doelklant valt onder de bronklant; voeg de klanten in omgekeerde richting samen")
+		}
+	}
+
+	oldData := map[string]interface{}{
+		"source": source.ToAuditMap(),
+		"target": target.ToAuditMap(),
+	}
+
+	result, err := s.repo.Merge(merged, source.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, oldData, nil
+}
+
+// mergeCustomerFields bepaalt per veld de waarde van de samengevoegde klant.
+// Zonder keuze wint de doelklant, tenzij die voor het veld geen waarde heeft.
+func mergeCustomerFields(source, target *model.Customer, picks map[string]string) (*model.Customer, error) {
+	merged := *target
+	merged.CustomFields = model.CustomFields{}
+	for key, value := range target.CustomFields {
+		merged.CustomFields[key] = value
+	}
+
+	known := make(map[string]bool, len(mergeFields))
+	for _, field := range mergeFields {
+		known[field.name] = true
+	}
+
+	var problems []string
+	for field, pick := range picks {
+		if pick != model.MergePickSource && pick != model.MergePickTarget {
+			problems = append(problems, fmt.Sprintf("keuze voor '%s' moet 'source' of 'target' zijn", field))
+			continue
+		}
+		if key, ok := strings.CutPrefix(field, model.CustomFieldPrefix); ok {
+			_, inSource := source.CustomFields[key]
+			_, inTarget := target.CustomFields[key]
+			if !inSource && !inTarget {
+				problems = append(problems, fmt.Sprintf("vrij veld '%s' komt bij geen van beide klanten voor", key))
+			}
+			continue
+		}
+		if !known[field] {
+			problems = append(problems, fmt.Sprintf("onbekend veld '%s'", field))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+
+	for _, field := range mergeFields {
+		value := field.field(&merged)
+		pick := picks[field.name]
+		if pick == model.MergePickSource || (pick == "" && *value == "") {
+			*value = *field.field(source)
+		}
+	}
+
+	for key, sourceValue := range source.CustomFields {
+		_, inTarget := merged.CustomFields[key]
+		pick := picks[model.CustomFieldPrefix+key]
+		if pick == model.MergePickSource || (pick == "" && !inTarget) {
+			merged.CustomFields[key] = sourceValue
+		}
+	}
+	// Kiezen voor een lege waarde van de bronklant verwijdert de waarde
+	for key := range target.CustomFields {
+		if _, inSource := source.CustomFields[key]; !inSource && picks[model.CustomFieldPrefix+key] == model.MergePickSource {
+			delete(merged.CustomFields, key)
+		}
+	}
+
+	return &merged, nil
+}
+
+// ValidateCustomer controleert en normaliseert de velden van een klant zonder deze op te slaan
+func (s *customerService) ValidateCustomer(customer *model.Customer) error {
+	customer.Name = strings.TrimSpace(customer.Name)
+	if customer.Name == "" {
+		return validation.New("name", "naam is verplicht")
+	}
+
+	customer.Email = strings.TrimSpace(customer.Email)
+	if customer.Email == "" {
+		return validation.New("email", "email is verplicht")
+	}
+	if address, err := mail.ParseAddress(customer.Email); err != nil || address.Address != customer.Email {
+		return validation.New("email", "ongeldig email adres")
+	}
+
+	if err := normalizeKvKNumber(&customer.KvKNumber); err != nil {
+		return validation.Wrap("kvk_number", err)
+	}
+
+	if err := normalizeVATNumber(&customer.VATNumber); err != nil {
+		return validation.Wrap("vat_number", err)
+	}
+
+	if err := s.validateParent(customer); err != nil {
+		return err
+	}
+
+	return validation.Wrap("custom_fields", s.normalizeCustomFields(customer))
+}
+
+// validateParent controleert de moederklant: die moet bestaan en mag niet de klant zelf zijn of
+// (indirect) onder de klant vallen, want dan ontstaat een kring
+func (s *customerService) validateParent(customer *model.Customer) error {
+	if customer.ParentID == nil {
+		return nil
+	}
+	if *customer.ParentID == customer.ID {
+		return validation.New("parent_id", "een klant kan niet onder zichzelf vallen")
+	}
+
+	parent, err := s.repo.FindByID(strconv.FormatUint(uint64(*customer.ParentID), 10))
+	if err != nil || parent == nil {
+		return validation.New("parent_id", "moederklant %d bestaat niet", *customer.ParentID)
+	}
+	if customer.ID == 0 {
+		return nil
+	}
+
+	ancestors, err := s.repo.FindAncestors(parent.ID)
+	if err != nil {
+		return err
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.ID == customer.ID {
+			return validation.New("parent_id", "moederklant %d valt onder deze klant; dat zou een kring opleveren", parent.ID)
+		}
+	}
+	return nil
+}
+
+// GetAncestors haalt de klanten boven een klant op, van de moederklant tot de bovenste klant
+func (s *customerService) GetAncestors(id string) ([]model.Ancestor, error) {
+	customer, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.repo.FindAncestors(customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ancestors == nil {
+		ancestors = []model.Ancestor{}
+	}
+	return ancestors, nil
+}
+
+// GetSubtree haalt een klant en de klanten daaronder op, met tellingen over de volledige deelboom.
+// maxDepth beperkt het aantal niveaus (0 of meer dan maxSubtreeDepth geeft maxSubtreeDepth).
+func (s *customerService) GetSubtree(id string, maxDepth int) ([]model.HierarchyNode, error) {
+	customer, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxDepth <= 0 || maxDepth > maxSubtreeDepth {
+		maxDepth = maxSubtreeDepth
+	}
+	return s.repo.FindSubtree(customer.ID, maxDepth)
+}
+
+// ChangeStatus verplaatst een klant naar change.ToStatus, als de workflow die overgang vanuit de huidige
+// status toestaat en de klant nog de opgegeven versie heeft. Een klant zonder (bestaande) status mag naar
+// elke status, zodat klanten van een verwijderde of nog niet ingestelde status weer in de workflow komen.
+func (s *customerService) ChangeStatus(id string, version uint, change *model.StatusChange) (*model.Customer, error) {
+	customer, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	change.ToStatus = strings.TrimSpace(change.ToStatus)
+	change.Note = strings.TrimSpace(change.Note)
+	target, err := s.statuses.GetStatusByKey(change.ToStatus)
+	if err != nil {
+		return nil, validation.New("status", "onbekende status '%s'", change.ToStatus)
+	}
+	if customer.Status == target.Key {
+		return nil, validation.New("status", "klant heeft al status '%s'", target.Key)
+	}
+
+	if customer.Status != "" {
+		current, err := s.statuses.GetStatusByKey(customer.Status)
+		if err == nil && !current.Allows(target.Key) {
+			if len(current.Transitions) == 0 {
+				return nil, validation.New("status", "vanuit status '%s' zijn geen overgangen toegestaan", current.Key)
+			}
+			return nil, validation.New("status", "overgang van '%s' naar '%s' is niet toegestaan; toegestaan: %s",
+				current.Key, target.Key, strings.Join(current.Transitions, ", "))
+		}
+	}
+
+	change.FromStatus = customer.Status
+	change.ChangedAt = time.Now()
+	return s.repo.ChangeStatus(customer.ID, version, change)
+}
+
+// SetLegalHold zet of verwijdert de legal hold van een klant. Een klant met een legal hold wordt na
+// verwijderen niet opgeschoond en zijn audit log blijft bewaard; bij het zetten is een reden verplicht.
+func (s *customerService) SetLegalHold(id string, version uint, request model.LegalHoldRequest) (*model.Customer, error) {
+	customer, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := strings.TrimSpace(request.Reason)
+	if *request.LegalHold && reason == "" {
+		return nil, validation.New("reason", "reden is verplicht bij een legal hold")
+	}
+	if len(reason) > 500 {
+		return nil, validation.New("reason", "reden mag maximaal 500 tekens zijn")
+	}
+	if !*request.LegalHold {
+		reason = ""
+	}
+
+	return s.repo.SetLegalHold(customer.ID, version, *request.LegalHold, reason)
+}
+
+// GetStatusHistory haalt de statusgeschiedenis van een klant op, nieuwste overgang eerst
+func (s *customerService) GetStatusHistory(id string) ([]model.StatusChange, error) {
+	customer, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.FindStatusHistory(customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if changes == nil {
+		changes = []model.StatusChange{}
+	}
+	return changes, nil
+}
+
+// GetBoard groepeert de klanten die aan het filter voldoen per status, in de volgorde van de workflow.
+// Elke kolom bevat het aantal klanten en hoogstens limit klanten (0 geeft defaultBoardLimit).
+// Klanten met een lege of niet meer bestaande status komen in extra kolommen achteraan.
+func (s *customerService) GetBoard(filter model.CustomerFilter, limit int) ([]model.BoardColumn, error) {
+	if err := s.validateFilter(&filter); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultBoardLimit
+	}
+	if limit > maxBoardLimit {
+		limit = maxBoardLimit
+	}
+
+	statuses, err := s.statuses.GetAllStatuses()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CountByStatus(filter)
+	if err != nil {
+		return nil, err
+	}
+	customers, err := s.repo.FindBoard(filter, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]model.BoardColumn, 0, len(statuses))
+	index := make(map[string]int, len(statuses))
+	for _, status := range statuses {
+		// Met een statusfilter tonen we alleen de gevraagde kolommen
+		if len(filter.Statuses) > 0 && !contains(filter.Statuses, status.Key) {
+			continue
+		}
+		index[status.Key] = len(columns)
+		columns = append(columns, model.BoardColumn{Status: status.Key, Label: status.Label, Count: counts[status.Key]})
+	}
+
+	unknown := make([]string, 0)
+	for key := range counts {
+		if _, ok := index[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		label := key
+		if key == "" {
+			label = "Zonder status"
+		}
+		index[key] = len(columns)
+		columns = append(columns, model.BoardColumn{Status: key, Label: label, Count: counts[key]})
+	}
+
+	for i := range columns {
+		columns[i].Customers = []model.Customer{}
+	}
+	for _, customer := range customers {
+		if i, ok := index[customer.Status]; ok {
+			columns[i].Customers = append(columns[i].Customers, customer)
+		}
+	}
+
+	return columns, nil
+}
+
+// contains geeft aan of value in values voorkomt
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByEmail zoekt een klant op email adres (hoofdletterongevoelig); nil als die er niet is
+func (s *customerService) FindByEmail(email string) (*model.Customer, error) {
+	return s.repo.FindByEmail(strings.TrimSpace(email))
+}
+
+// FindByKvKNumber zoekt een klant op KvK nummer; nil als die er niet is
+func (s *customerService) FindByKvKNumber(kvkNumber string) (*model.Customer, error) {
+	if err := normalizeKvKNumber(&kvkNumber); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByKvKNumber(kvkNumber)
+}
+
+// normalizeKvKNumber haalt spaties en punten uit een KvK nummer en controleert het formaat
+func normalizeKvKNumber(kvkNumber *string) error {
+	*kvkNumber = strings.NewReplacer(" ", "", ".", "").Replace(*kvkNumber)
+	if *kvkNumber != "" && !kvkPattern.MatchString(*kvkNumber) {
+		return errors.New("KvK nummer moet uit 8 cijfers bestaan")
+	}
+	return nil
+}
+
+// normalizeVATNumber haalt spaties en punten uit een BTW-nummer, zet het in hoofdletters en controleert het formaat
+func normalizeVATNumber(vatNumber *string) error {
+	*vatNumber = strings.ToUpper(strings.NewReplacer(" ", "", ".", "").Replace(*vatNumber))
+	if *vatNumber == "" {
+		return nil
+	}
+	if !vatPattern.MatchString(*vatNumber) {
+		return errors.New("BTW-nummer moet met een landcode beginnen, bijv. NL123456789B01")
+	}
+	if strings.HasPrefix(*vatNumber, "NL") && !nlVATPattern.MatchString(*vatNumber) {
+		return errors.New("Nederlands BTW-nummer moet het formaat NL123456789B01 hebben")
+	}
+	return nil
+}
+
+// normalizeCustomFields valideert de vrije velden van een klant tegen de definities
+func (s *customerService) normalizeCustomFields(customer *model.Customer) error {
+	normalized, err := s.customFields.NormalizeValues(customer.CustomFields)
+	if err != nil {
+		return err
+	}
+	customer.CustomFields = normalized
+	return nil
+}
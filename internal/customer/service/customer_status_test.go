@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+	"odomosml/internal/customer/model"
+	statusModel "odomosml/internal/customerstatus/model"
+	customerStatusService "odomosml/internal/customerstatus/service"
+	"odomosml/pkg/validation"
+	"testing"
+)
+
+func (r *fakeCustomerRepository) ChangeStatus(id uint, version uint, change *model.StatusChange) (*model.Customer, error) {
+	r.calls = append(r.calls, "ChangeStatus")
+	return &model.Customer{ID: id, Version: version + 1, Status: change.ToStatus, StatusChangedAt: &change.ChangedAt}, nil
+}
+
+func (r *fakeCustomerRepository) Create(customer *model.Customer) (*model.Customer, error) {
+	r.calls = append(r.calls, "Create")
+	return customer, nil
+}
+
+// fakeStatuses is de standaard workflow van lead via prospect naar actief
+type fakeStatuses struct {
+	customerStatusService.CustomerStatusService
+}
+
+func (fakeStatuses) GetAllStatuses() ([]statusModel.StatusDefinition, error) {
+	return statusModel.DefaultStatuses(), nil
+}
+
+func (fakeStatuses) GetStatusByKey(key string) (*statusModel.StatusDefinition, error) {
+	for _, status := range statusModel.DefaultStatuses() {
+		if status.Key == key {
+			return &status, nil
+		}
+	}
+	return nil, errors.New("status niet gevonden")
+}
+
+func TestChangeStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr string
+	}{
+		{name: "lead naar prospect", from: "lead", to: " prospect "},
+		{name: "prospect naar actief", from: "prospect", to: "active"},
+		{name: "opgezegd weer actief", from: "churned", to: "active"},
+		{name: "verloren lead terug", from: "lost", to: "lead"},
+		{name: "zonder status naar elke status", from: "", to: "churned"},
+		{name: "verwijderde status naar elke status", from: "archived", to: "active"},
+		{name: "lead direct naar actief", from: "lead", to: "active", wantErr: "overgang van 'lead' naar 'active' is niet toegestaan; toegestaan: prospect, lost"},
+		{name: "actief terug naar lead", from: "active", to: "lead", wantErr: "overgang van 'active' naar 'lead' is niet toegestaan; toegestaan: churned"},
+		{name: "dezelfde status", from: "active", to: "active", wantErr: "klant heeft al status 'active'"},
+		{name: "onbekende status", from: "lead", to: "vip", wantErr: "onbekende status 'vip'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeCustomerRepository{customers: []model.Customer{{ID: 1, Name: "Jansen", Status: tt.from, Version: 3}}}
+			service := NewCustomerService(repo, fakeCustomFields{}, fakeStatuses{})
+
+			change := &model.StatusChange{ToStatus: tt.to, Note: " offerte getekend ", UserID: 2}
+			customer, err := service.ChangeStatus("1", 3, change)
+			if tt.wantErr != "" {
+				fields, _ := validation.Fields(err)
+				if err == nil || err.Error() != tt.wantErr || len(fields) != 1 || fields[0].Field != "status" {
+					t.Errorf("ChangeStatus gaf %v, verwacht %q op status", err, tt.wantErr)
+				}
+				if repo.calls[len(repo.calls)-1] == "ChangeStatus" {
+					t.Error("status toch gewijzigd")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangeStatus gaf fout: %v", err)
+			}
+			if change.FromStatus != tt.from || change.ToStatus != customer.Status || change.Note != "offerte getekend" || change.ChangedAt.IsZero() {
+				t.Errorf("overgang = %+v", change)
+			}
+			if customer.Version != 4 {
+				t.Errorf("versie %d, verwacht 4", customer.Version)
+			}
+		})
+	}
+}
+
+func TestCreateCustomerStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		want    string
+		wantErr string
+	}{
+		{name: "initiële status", want: "lead"},
+		{name: "opgegeven status", status: " active ", want: "active"},
+		{name: "onbekende status", status: "vip", wantErr: "onbekende status 'vip'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewCustomerService(&fakeCustomerRepository{}, fakeCustomFields{}, fakeStatuses{})
+			customer, err := service.CreateCustomer(&model.Customer{Name: "Jansen", Email: "info@jansen.nl", Status: tt.status})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("CreateCustomer gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateCustomer gaf fout: %v", err)
+			}
+			if customer.Status != tt.want {
+				t.Errorf("status %q, verwacht %q", customer.Status, tt.want)
+			}
+		})
+	}
+}
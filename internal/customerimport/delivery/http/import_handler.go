@@ -0,0 +1,237 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	auditModel "odomosml/internal/audit/model"
+	"odomosml/internal/customerimport/model"
+	"odomosml/internal/customerimport/service"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler handles HTTP requests for customer imports
+type ImportHandler struct {
+	service   service.ImportService
+	maxSizeMB int
+}
+
+// NewImportHandler maakt een nieuwe ImportHandler instantie
+func NewImportHandler(service service.ImportService, maxSizeMB int) *ImportHandler {
+	return &ImportHandler{
+		service:   service,
+		maxSizeMB: maxSizeMB,
+	}
+}
+
+// @Summary      Klanten importeren
+// @Description  Importeert klanten uit een CSV of XLSX bestand, of uit vCards (.vcf, versie 2.1, 3.0 of 4.0). Zonder mapping worden kolomkoppen herkend (naam, email, telefoon, adres, kvk, cf.<key>). Een vCard wordt een klant met de organisatie (ORG) als naam, of anders de naam van de persoon; een vCard met het e-mailadres van een bestaande klant of van een eerdere vCard in het bestand wordt overgeslagen en als fout gemeld. Met dry_run=true worden de rijen alleen gevalideerd. Met upsert_by worden bestaande klanten met hetzelfde email adres of KvK nummer bijgewerkt. Grote bestanden worden op de achtergrond verwerkt (202); volg de status via /klanten/import/{jobId}.
+// @Tags         imports
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file formData file true "CSV, XLSX of vCard bestand"
+// @Param        format formData string false "csv, xlsx of vcf (default: op basis van de extensie)"
+// @Param        delimiter formData string false "Scheidingsteken voor CSV, bijv. ; of tab (default: automatisch)"
+// @Param        encoding formData string false "Tekencodering voor CSV en vCard: utf-8 of windows-1252 (default: utf-8)"
+// @Param        sheet formData string false "Werkblad in een XLSX bestand (default: het eerste)"
+// @Param        mapping formData string false "JSON object van kolomkop naar veld, bijv. {\"Bedrijfsnaam\":\"name\",\"Sector\":\"cf.branche\"}"
+// @Param        upsert_by formData string false "Bestaande klanten bijwerken op email of kvk_number"
+// @Param        dry_run formData bool false "Alleen valideren, niets opslaan"
+// @Success      200  {object}  model.ImportJob "Import verwerkt"
+// @Success      202  {object}  model.ImportJob "Import wordt op de achtergrond verwerkt"
+// @Failure      400  {object}  map[string]string "Ongeldig bestand of ongeldige mapping"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      413  {object}  map[string]string "Bestand te groot"
+// @Security     Bearer
+// @Router       /klanten/import [post]
+func (h *ImportHandler) Import(c *gin.Context) {
+	maxBytes := int64(h.maxSizeMB) << 20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"success": false,
+				"error":   fmt.Sprintf("Bestand is te groot: maximaal %d MB", h.maxSizeMB),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geen bestand gevonden in veld 'file'",
+		})
+		return
+	}
+	if fileHeader.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"success": false,
+			"error":   fmt.Sprintf("Bestand is te groot: maximaal %d MB", h.maxSizeMB),
+		})
+		return
+	}
+
+	options, err := parseOptions(c, fileHeader.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Kan bestand niet lezen",
+		})
+		return
+	}
+	defer file.Close()
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+	uid, _ := userID.(uint)
+	name, _ := username.(string)
+
+	job, err := h.service.StartImport(file, options, uid, name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	description := fmt.Sprintf("Klantenimport '%s' gestart: %d rijen", job.FileName, job.TotalRows)
+	if job.DryRun {
+		description = fmt.Sprintf("Klantenimport '%s' gevalideerd (dry-run): %d rijen", job.FileName, job.TotalRows)
+	}
+	newData, _ := json.Marshal(gin.H{"mapping": job.Mapping, "upsert_by": job.UpsertBy, "dry_run": job.DryRun})
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		EntityType:  auditModel.EntityImport,
+		EntityID:    strconv.FormatUint(uint64(job.ID), 10),
+		Description: description,
+		NewData:     string(newData),
+	})
+
+	status := http.StatusOK
+	if job.Status == model.JobStatusPending {
+		status = http.StatusAccepted
+		c.Header("Location", fmt.Sprintf("/api/klanten/import/%d", job.ID))
+	}
+
+	c.JSON(status, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// @Summary      Status van een import
+// @Description  Haalt de voortgang en het resultaat van een klantenimport op, inclusief de fouten per rij
+// @Tags         imports
+// @Accept       json
+// @Produce      json
+// @Param        jobId path string true "Import ID"
+// @Success      200  {object}  model.ImportJob "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Import niet gevonden"
+// @Security     Bearer
+// @Router       /klanten/import/{jobId} [get]
+func (h *ImportHandler) GetJob(c *gin.Context) {
+	job, err := h.service.GetJob(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// @Summary      Foutenrapport van een import
+// @Description  Downloadt de fouten per rij van een klantenimport als CSV bestand
+// @Tags         imports
+// @Produce      text/csv
+// @Param        jobId path string true "Import ID"
+// @Success      200  {file}  file "Foutenrapport"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Import niet gevonden"
+// @Security     Bearer
+// @Router       /klanten/import/{jobId}/errors [get]
+func (h *ImportHandler) ErrorReport(c *gin.Context) {
+	job, err := h.service.GetJob(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fileName := fmt.Sprintf("import-%d-fouten.csv", job.ID)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	if job.Delimiter != "" {
+		writer.Comma, _ = utf8.DecodeRuneInString(job.Delimiter)
+	}
+	writer.Write([]string{"regel", "veld", "melding"})
+	for _, rowError := range job.Errors {
+		writer.Write([]string{strconv.Itoa(rowError.Row), rowError.Field, rowError.Message})
+	}
+	writer.Flush()
+}
+
+// parseOptions leest de import instellingen uit het formulier
+func parseOptions(c *gin.Context, fileName string) (model.ImportOptions, error) {
+	options := model.ImportOptions{
+		FileName: filepath.Base(fileName),
+		Format:   strings.ToLower(c.PostForm("format")),
+		Encoding: strings.ToLower(c.PostForm("encoding")),
+		Sheet:    c.PostForm("sheet"),
+		UpsertBy: c.PostForm("upsert_by"),
+		DryRun:   c.PostForm("dry_run") == "true",
+	}
+
+	if options.Format == "" {
+		options.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	}
+
+	switch delimiter := c.PostForm("delimiter"); delimiter {
+	case "":
+	case "tab", "\\t", "\t":
+		options.Delimiter = '\t'
+	default:
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return options, fmt.Errorf("ongeldig scheidingsteken '%s'", delimiter)
+		}
+		options.Delimiter = r
+	}
+
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &options.Mapping); err != nil {
+			return options, fmt.Errorf("mapping moet een JSON object van kolomkop naar veld zijn: %w", err)
+		}
+	}
+
+	return options, nil
+}
@@ -0,0 +1,164 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JobStatus is de status van een import
+type JobStatus string
+
+// Beschikbare statussen
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+// Ondersteunde bestandsformaten
+const (
+	FormatCSV   = "csv"
+	FormatXLSX  = "xlsx"
+	FormatVCard = "vcf"
+)
+
+// Ondersteunde tekencoderingen voor CSV
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingWindows1252 = "windows-1252"
+)
+
+// Velden waarop bestaande klanten gevonden kunnen worden (upsert)
+const (
+	UpsertByEmail = "email"
+	UpsertByKvK   = "kvk_number"
+)
+
+// MaxStoredErrors is het maximum aantal rijfouten dat bij een import wordt bewaard
+const MaxStoredErrors = 5000
+
+// ColumnMapping koppelt kolomkoppen uit het bestand aan klantvelden (name, email, phone,
+// address, kvk_number of cf.<key>). Wordt als JSONB opgeslagen.
+type ColumnMapping map[string]string
+
+// Value implementeert driver.Valuer
+func (m ColumnMapping) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]string(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (m *ColumnMapping) Scan(value interface{}) error {
+	data, err := jsonBytes(value)
+	if err != nil || data == nil {
+		*m = ColumnMapping{}
+		return err
+	}
+	return json.Unmarshal(data, (*map[string]string)(m))
+}
+
+// RowError is een validatie- of verwerkingsfout in een rij van het bestand
+type RowError struct {
+	Row     int    `json:"row" example:"12"`
+	Field   string `json:"field,omitempty" example:"email"`
+	Message string `json:"message" example:"ongeldig email adres"`
+}
+
+// RowErrors is een lijst rijfouten die als JSONB wordt opgeslagen
+type RowErrors []RowError
+
+// Value implementeert driver.Valuer
+func (e RowErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]RowError(e))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (e *RowErrors) Scan(value interface{}) error {
+	data, err := jsonBytes(value)
+	if err != nil || data == nil {
+		*e = RowErrors{}
+		return err
+	}
+	return json.Unmarshal(data, (*[]RowError)(e))
+}
+
+// ImportJob beschrijft een import van klanten uit een CSV of XLSX bestand
+// @Description Status en resultaat van een klantenimport
+type ImportJob struct {
+	ID            uint          `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	Status        JobStatus     `json:"status" gorm:"size:20;not null;index" example:"completed" swaggertype:"string"`
+	FileName      string        `json:"file_name" gorm:"size:255;not null" example:"klanten.csv" swaggertype:"string"`
+	Format        string        `json:"format" gorm:"size:10;not null" example:"csv" swaggertype:"string"`
+	Delimiter     string        `json:"delimiter,omitempty" gorm:"size:4" example:";" swaggertype:"string"`
+	Encoding      string        `json:"encoding,omitempty" gorm:"size:20" example:"windows-1252" swaggertype:"string"`
+	Mapping       ColumnMapping `json:"mapping" gorm:"type:jsonb;not null;default:'{}'" swaggertype:"object"`
+	UpsertBy      string        `json:"upsert_by,omitempty" gorm:"size:20" example:"email" swaggertype:"string"`
+	DryRun        bool          `json:"dry_run" gorm:"not null;default:false" example:"false" swaggertype:"boolean"`
+	TotalRows     int           `json:"total_rows" gorm:"not null;default:0" example:"1200" swaggertype:"integer"`
+	ProcessedRows int           `json:"processed_rows" gorm:"not null;default:0" example:"1200" swaggertype:"integer"`
+	CreatedCount  int           `json:"created_count" gorm:"not null;default:0" example:"1100" swaggertype:"integer"`
+	UpdatedCount  int           `json:"updated_count" gorm:"not null;default:0" example:"80" swaggertype:"integer"`
+	ErrorCount    int           `json:"error_count" gorm:"not null;default:0" example:"20" swaggertype:"integer"`
+	Errors        RowErrors     `json:"errors,omitempty" gorm:"type:jsonb;not null;default:'[]'"`
+	Message       string        `json:"message,omitempty" gorm:"size:500" example:"" swaggertype:"string"`
+	CreatedByID   uint          `json:"created_by_id" gorm:"not null" example:"1" swaggertype:"integer"`
+	CreatedByName string        `json:"created_by_name" gorm:"size:100" example:"johndoe" swaggertype:"string"`
+	CreatedAt     time.Time     `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt     time.Time     `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	FinishedAt    *time.Time    `json:"finished_at,omitempty" example:"2024-02-25T20:31:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (ImportJob) TableName() string {
+	return "import_jobs"
+}
+
+// AddError legt een rijfout vast; boven MaxStoredErrors wordt alleen nog geteld
+func (j *ImportJob) AddError(rowError RowError) {
+	j.ErrorCount++
+	if len(j.Errors) < MaxStoredErrors {
+		j.Errors = append(j.Errors, rowError)
+	}
+}
+
+// ImportOptions zijn de instellingen waarmee een bestand wordt ingelezen en verwerkt
+type ImportOptions struct {
+	FileName  string
+	Format    string
+	Delimiter rune
+	Encoding  string
+	Sheet     string
+	Mapping   ColumnMapping
+	UpsertBy  string
+	DryRun    bool
+}
+
+// jsonBytes zet een database waarde om naar bytes voor json.Unmarshal
+func jsonBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("kan %T niet converteren naar JSON", value)
+	}
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/customerimport/model"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ImportJobRepository definieert de interface voor import job repository
+type ImportJobRepository interface {
+	FindByID(id string) (*model.ImportJob, error)
+	Create(job *model.ImportJob) (*model.ImportJob, error)
+	Save(job *model.ImportJob) error
+	UpdateProgress(job *model.ImportJob) error
+	FailUnfinished(message string) (int64, error)
+}
+
+// importJobRepository implementeert de ImportJobRepository interface
+type importJobRepository struct {
+	db *gorm.DB
+}
+
+// NewImportJobRepository maakt een nieuwe ImportJobRepository instantie
+func NewImportJobRepository(db *gorm.DB) ImportJobRepository {
+	return &importJobRepository{
+		db: db,
+	}
+}
+
+// FindByID haalt een import op op basis van ID
+func (r *importJobRepository) FindByID(id string) (*model.ImportJob, error) {
+	var job model.ImportJob
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&job, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("import niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Create maakt een nieuwe import aan
+func (r *importJobRepository) Create(job *model.ImportJob) (*model.ImportJob, error) {
+	if err := r.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Save slaat de voortgang van een import op
+func (r *importJobRepository) Save(job *model.ImportJob) error {
+	return r.db.Save(job).Error
+}
+
+// UpdateProgress slaat alleen status en tellers op; de foutenlijst wordt pas aan het eind opgeslagen
+func (r *importJobRepository) UpdateProgress(job *model.ImportJob) error {
+	return r.db.Model(job).
+		Select("status", "total_rows", "processed_rows", "created_count", "updated_count", "error_count").
+		Updates(job).Error
+}
+
+// FailUnfinished markeert imports die nog wachten of bezig zijn als mislukt.
+// Wordt bij het opstarten aangeroepen: na een herstart worden deze nooit meer afgerond.
+func (r *importJobRepository) FailUnfinished(message string) (int64, error) {
+	result := r.db.Model(&model.ImportJob{}).
+		Where("status IN ?", []model.JobStatus{model.JobStatusPending, model.JobStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":      model.JobStatusFailed,
+			"message":     message,
+			"finished_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
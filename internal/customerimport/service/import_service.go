@@ -0,0 +1,437 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	customerModel "odomosml/internal/customer/model"
+	customerService "odomosml/internal/customer/service"
+	"odomosml/internal/customerimport/model"
+	"odomosml/internal/customerimport/repository"
+	customFieldService "odomosml/internal/customfield/service"
+	"sort"
+	"strings"
+	"time"
+)
+
+// progressInterval is het aantal rijen waarna de voortgang van een import wordt opgeslagen
+const progressInterval = 200
+
+// maxConcurrentImports is het maximum aantal imports dat tegelijk op de achtergrond draait
+const maxConcurrentImports = 2
+
+// Klantvelden waarop een kolom gemapt kan worden (naast cf.<key>)
+const (
+	fieldName      = "name"
+	fieldEmail     = "email"
+	fieldPhone     = "phone"
+	fieldAddress   = "address"
+	fieldKvKNumber = "kvk_number"
+	fieldVATNumber = "vat_number"
+)
+
+// headerAliases koppelt veelgebruikte kolomkoppen aan klantvelden als er geen mapping is opgegeven
+var headerAliases = map[string]string{
+	"name":                 fieldName,
+	"naam":                 fieldName,
+	"bedrijfsnaam":         fieldName,
+	"klantnaam":            fieldName,
+	"email":                fieldEmail,
+	"e-mail":               fieldEmail,
+	"emailadres":           fieldEmail,
+	"e-mailadres":          fieldEmail,
+	"phone":                fieldPhone,
+	"telefoon":             fieldPhone,
+	"telefoonnummer":       fieldPhone,
+	"address":              fieldAddress,
+	"adres":                fieldAddress,
+	"kvk":                  fieldKvKNumber,
+	"kvk_number":           fieldKvKNumber,
+	"kvk-nummer":           fieldKvKNumber,
+	"kvknummer":            fieldKvKNumber,
+	"kvk nummer":           fieldKvKNumber,
+	"kamer van koophandel": fieldKvKNumber,
+	"btw":                  fieldVATNumber,
+	"btw-nummer":           fieldVATNumber,
+	"btwnummer":            fieldVATNumber,
+	"btw nummer":           fieldVATNumber,
+	"vat_number":           fieldVATNumber,
+}
+
+// ImportService definieert de interface voor de klantenimport
+type ImportService interface {
+	StartImport(content io.Reader, options model.ImportOptions, userID uint, username string) (*model.ImportJob, error)
+	GetJob(id string) (*model.ImportJob, error)
+	FailInterruptedJobs() error
+}
+
+// importService implementeert de ImportService interface
+type importService struct {
+	repo         repository.ImportJobRepository
+	customers    customerService.CustomerService
+	customFields customFieldService.CustomFieldService
+	syncRowLimit int
+	slots        chan struct{}
+}
+
+// NewImportService maakt een nieuwe ImportService instantie. Bestanden met meer dan
+// syncRowLimit rijen worden op de achtergrond verwerkt.
+func NewImportService(repo repository.ImportJobRepository, customers customerService.CustomerService, customFields customFieldService.CustomFieldService, syncRowLimit int) ImportService {
+	return &importService{
+		repo:         repo,
+		customers:    customers,
+		customFields: customFields,
+		syncRowLimit: syncRowLimit,
+		slots:        make(chan struct{}, maxConcurrentImports),
+	}
+}
+
+// StartImport leest het bestand in, controleert de mapping en verwerkt de rijen.
+// Kleine bestanden worden direct verwerkt (status completed), grote bestanden op de
+// achtergrond (status pending); de voortgang is dan via GetJob te volgen.
+func (s *importService) StartImport(content io.Reader, options model.ImportOptions, userID uint, username string) (*model.ImportJob, error) {
+	if options.UpsertBy != "" && options.UpsertBy != model.UpsertByEmail && options.UpsertBy != model.UpsertByKvK {
+		return nil, errors.New("upsert_by moet email of kvk_number zijn")
+	}
+
+	header, rows, err := readFile(content, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, columns, err := s.resolveMapping(header, options.Mapping, options.UpsertBy)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &model.ImportJob{
+		Status:        model.JobStatusPending,
+		FileName:      options.FileName,
+		Format:        options.Format,
+		Encoding:      options.Encoding,
+		Mapping:       mapping,
+		UpsertBy:      options.UpsertBy,
+		DryRun:        options.DryRun,
+		TotalRows:     len(rows),
+		Errors:        model.RowErrors{},
+		CreatedByID:   userID,
+		CreatedByName: username,
+	}
+	if options.Format == model.FormatCSV {
+		job.Delimiter = string(options.Delimiter)
+	}
+
+	if _, err := s.repo.Create(job); err != nil {
+		return nil, err
+	}
+
+	if len(rows) <= s.syncRowLimit {
+		s.run(job, columns, rows)
+		return job, nil
+	}
+
+	// Kopie voor de aanroeper, zodat de achtergrondverwerking de job vrij kan wijzigen
+	pending := *job
+	go func() {
+		s.slots <- struct{}{}
+		defer func() { <-s.slots }()
+		s.run(job, columns, rows)
+	}()
+
+	return &pending, nil
+}
+
+// GetJob haalt de status van een import op
+func (s *importService) GetJob(id string) (*model.ImportJob, error) {
+	return s.repo.FindByID(id)
+}
+
+// FailInterruptedJobs markeert imports die door een herstart nooit afgerond zullen worden als mislukt
+func (s *importService) FailInterruptedJobs() error {
+	count, err := s.repo.FailUnfinished("import onderbroken door herstart van de server")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("%d onderbroken import(s) als mislukt gemarkeerd", count)
+	}
+	return nil
+}
+
+// run verwerkt alle rijen van een import en slaat het resultaat op
+func (s *importService) run(job *model.ImportJob, columns map[string]int, rows []importRow) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Import %d afgebroken: %v", job.ID, r)
+			job.Status = model.JobStatusFailed
+			job.Message = "interne fout tijdens het verwerken van de import"
+			s.finish(job)
+		}
+	}()
+
+	job.Status = model.JobStatusRunning
+	if err := s.repo.UpdateProgress(job); err != nil {
+		log.Printf("Kan voortgang van import %d niet opslaan: %v", job.ID, err)
+	}
+
+	// Bij een dry-run bestaan eerder in het bestand gevonden klanten nog niet in de database
+	seen := make(map[string]bool)
+	// Regel waarop een e-mailadres voor het eerst voorkwam, voor de controle op dubbele vCards
+	emails := make(map[string]int)
+
+	for i, row := range rows {
+		s.processRow(job, columns, row, seen, emails)
+		job.ProcessedRows++
+
+		if (i+1)%progressInterval == 0 {
+			if err := s.repo.UpdateProgress(job); err != nil {
+				log.Printf("Kan voortgang van import %d niet opslaan: %v", job.ID, err)
+			}
+		}
+	}
+
+	job.Status = model.JobStatusCompleted
+	s.finish(job)
+}
+
+// finish slaat de eindstatus en de foutenlijst van een import op
+func (s *importService) finish(job *model.ImportJob) {
+	now := time.Now()
+	job.FinishedAt = &now
+	if err := s.repo.Save(job); err != nil {
+		log.Printf("Kan resultaat van import %d niet opslaan: %v", job.ID, err)
+	}
+}
+
+// processRow valideert een rij en maakt de klant aan of werkt een bestaande klant bij
+func (s *importService) processRow(job *model.ImportJob, columns map[string]int, row importRow, seen map[string]bool, emails map[string]int) {
+	values := make(map[string]string, len(columns))
+	for field, index := range columns {
+		if index < len(row.values) {
+			values[field] = strings.TrimSpace(row.values[index])
+		}
+	}
+
+	existing, err := s.findExisting(job.UpsertBy, values)
+	if err != nil {
+		job.AddError(model.RowError{Row: row.number, Field: job.UpsertBy, Message: err.Error()})
+		return
+	}
+
+	upsertKey := ""
+	if job.UpsertBy != "" && values[job.UpsertBy] != "" {
+		upsertKey = strings.ToLower(values[job.UpsertBy])
+	}
+
+	if existing == nil && job.Format == model.FormatVCard {
+		if message, err := s.checkDuplicateEmail(values[fieldEmail], row.number, emails); err != nil || message != "" {
+			if err != nil {
+				message = err.Error()
+			}
+			job.AddError(model.RowError{Row: row.number, Field: fieldEmail, Message: message})
+			return
+		}
+	}
+
+	if existing == nil {
+		customer := &customerModel.Customer{CustomFields: customerModel.CustomFields{}}
+		applyValues(customer, values)
+
+		if job.DryRun {
+			err = s.customers.ValidateCustomer(customer)
+		} else {
+			_, err = s.customers.CreateCustomer(customer)
+		}
+		if err != nil {
+			job.AddError(model.RowError{Row: row.number, Message: err.Error()})
+			return
+		}
+
+		if job.DryRun && seen[upsertKey] {
+			job.UpdatedCount++
+			return
+		}
+		if upsertKey != "" {
+			seen[upsertKey] = true
+		}
+		job.CreatedCount++
+		return
+	}
+
+	// Alleen gevulde kolommen overschrijven bestaande waarden
+	applyValues(existing, values)
+
+	if job.DryRun {
+		err = s.customers.ValidateCustomer(existing)
+	} else {
+		_, err = s.customers.UpdateCustomer(existing)
+	}
+	if err != nil {
+		job.AddError(model.RowError{Row: row.number, Message: err.Error()})
+		return
+	}
+	job.UpdatedCount++
+}
+
+// checkDuplicateEmail controleert of een vCard een klant zou worden met het e-mailadres van een
+// bestaande klant of van een eerdere vCard in het bestand. Visitekaartjes worden vaak dubbel
+// aangeleverd; die worden overgeslagen en gemeld, tenzij ze met upsert_by=email een klant bijwerken.
+func (s *importService) checkDuplicateEmail(email string, row int, emails map[string]int) (string, error) {
+	if email == "" {
+		return "", nil
+	}
+
+	key := strings.ToLower(email)
+	if first, ok := emails[key]; ok {
+		return fmt.Sprintf("e-mailadres %s staat ook in de vCard op regel %d", email, first), nil
+	}
+	emails[key] = row
+
+	existing, err := s.customers.FindByEmail(email)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return fmt.Sprintf("er is al een klant met e-mailadres %s (klant %d, %s)", email, existing.ID, existing.Name), nil
+	}
+	return "", nil
+}
+
+// findExisting zoekt de bestaande klant voor een upsert, of nil
+func (s *importService) findExisting(upsertBy string, values map[string]string) (*customerModel.Customer, error) {
+	switch upsertBy {
+	case model.UpsertByEmail:
+		if values[fieldEmail] == "" {
+			return nil, nil
+		}
+		return s.customers.FindByEmail(values[fieldEmail])
+	case model.UpsertByKvK:
+		if values[fieldKvKNumber] == "" {
+			return nil, nil
+		}
+		return s.customers.FindByKvKNumber(values[fieldKvKNumber])
+	}
+	return nil, nil
+}
+
+// applyValues zet de gevulde waarden uit een rij op een klant
+func applyValues(customer *customerModel.Customer, values map[string]string) {
+	if customer.CustomFields == nil {
+		customer.CustomFields = customerModel.CustomFields{}
+	}
+
+	for field, value := range values {
+		if value == "" {
+			continue
+		}
+		switch field {
+		case fieldName:
+			customer.Name = value
+		case fieldEmail:
+			customer.Email = value
+		case fieldPhone:
+			customer.Phone = value
+		case fieldAddress:
+			customer.Address = value
+		case fieldKvKNumber:
+			customer.KvKNumber = value
+		case fieldVATNumber:
+			customer.VATNumber = value
+		default:
+			customer.CustomFields[strings.TrimPrefix(field, customerModel.CustomFieldPrefix)] = value
+		}
+	}
+}
+
+// resolveMapping bepaalt welke kolom bij welk klantveld hoort. Zonder opgegeven mapping
+// worden kolomkoppen herkend op veldnaam, bekende Nederlandse namen en cf.<key>.
+// Retourneert de gebruikte mapping (kolomkop -> veld) en per veld de kolomindex.
+func (s *importService) resolveMapping(header []string, mapping model.ColumnMapping, upsertBy string) (model.ColumnMapping, map[string]int, error) {
+	indexes := make(map[string]int, len(header))
+	for i, column := range header {
+		if _, exists := indexes[column]; !exists && column != "" {
+			indexes[column] = i
+		}
+	}
+
+	if len(mapping) == 0 {
+		mapping = model.ColumnMapping{}
+		for _, column := range header {
+			lower := strings.ToLower(column)
+			if field, ok := headerAliases[lower]; ok {
+				mapping[column] = field
+			} else if strings.HasPrefix(lower, customerModel.CustomFieldPrefix) {
+				mapping[column] = lower
+			}
+		}
+	}
+
+	var problems []string
+	columns := make(map[string]int, len(mapping))
+	for column, field := range mapping {
+		index, ok := indexes[column]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("kolom '%s' staat niet in het bestand", column))
+			continue
+		}
+		if field == "" {
+			continue
+		}
+		if _, duplicate := columns[field]; duplicate {
+			problems = append(problems, fmt.Sprintf("veld '%s' is aan meerdere kolommen gekoppeld", field))
+			continue
+		}
+		if err := s.validateField(field); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		columns[field] = index
+	}
+
+	for _, required := range []string{fieldName, fieldEmail} {
+		if _, ok := columns[required]; !ok {
+			problems = append(problems, fmt.Sprintf("geen kolom gekoppeld aan verplicht veld '%s'", required))
+		}
+	}
+	if upsertBy != "" {
+		if _, ok := columns[upsertBy]; !ok {
+			problems = append(problems, fmt.Sprintf("geen kolom gekoppeld aan '%s' voor upsert", upsertBy))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, nil, errors.New(strings.Join(sortedUnique(problems), "; "))
+	}
+
+	return mapping, columns, nil
+}
+
+// validateField controleert of een kolom aan dit klantveld gekoppeld kan worden
+func (s *importService) validateField(field string) error {
+	switch field {
+	case fieldName, fieldEmail, fieldPhone, fieldAddress, fieldKvKNumber, fieldVATNumber:
+		return nil
+	}
+
+	if key, ok := strings.CutPrefix(field, customerModel.CustomFieldPrefix); ok {
+		if _, err := s.customFields.GetDefinitionByKey(key); err != nil {
+			return fmt.Errorf("onbekend vrij veld '%s'", key)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("onbekend veld '%s'", field)
+}
+
+// sortedUnique sorteert meldingen en verwijdert dubbele
+func sortedUnique(messages []string) []string {
+	sort.Strings(messages)
+	unique := messages[:0]
+	for i, message := range messages {
+		if i == 0 || message != messages[i-1] {
+			unique = append(unique, message)
+		}
+	}
+	return unique
+}
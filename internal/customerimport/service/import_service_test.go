@@ -0,0 +1,351 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	customerModel "odomosml/internal/customer/model"
+	customerService "odomosml/internal/customer/service"
+	"odomosml/internal/customerimport/model"
+	"odomosml/internal/customerimport/repository"
+	customFieldModel "odomosml/internal/customfield/model"
+	customFieldService "odomosml/internal/customfield/service"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// fakeJobRepository bewaart de laatst opgeslagen job
+type fakeJobRepository struct {
+	repository.ImportJobRepository
+	saved *model.ImportJob
+}
+
+func (r *fakeJobRepository) Create(job *model.ImportJob) (*model.ImportJob, error) {
+	job.ID = 1
+	return job, nil
+}
+
+func (r *fakeJobRepository) UpdateProgress(job *model.ImportJob) error {
+	return nil
+}
+
+func (r *fakeJobRepository) Save(job *model.ImportJob) error {
+	saved := *job
+	r.saved = &saved
+	return nil
+}
+
+// fakeCustomers kent de bestaande klanten op e-mailadres en KvK nummer en valideert naam en e-mail
+type fakeCustomers struct {
+	customerService.CustomerService
+	existing []customerModel.Customer
+	created  []customerModel.Customer
+	updated  []customerModel.Customer
+}
+
+func (s *fakeCustomers) ValidateCustomer(customer *customerModel.Customer) error {
+	if customer.Name == "" {
+		return errors.New("naam is verplicht")
+	}
+	if !strings.Contains(customer.Email, "@") {
+		return errors.New("ongeldig email adres")
+	}
+	return nil
+}
+
+func (s *fakeCustomers) CreateCustomer(customer *customerModel.Customer) (*customerModel.Customer, error) {
+	if err := s.ValidateCustomer(customer); err != nil {
+		return nil, err
+	}
+	s.created = append(s.created, *customer)
+	s.existing = append(s.existing, *customer)
+	return customer, nil
+}
+
+func (s *fakeCustomers) UpdateCustomer(customer *customerModel.Customer) (*customerModel.Customer, error) {
+	if err := s.ValidateCustomer(customer); err != nil {
+		return nil, err
+	}
+	s.updated = append(s.updated, *customer)
+	return customer, nil
+}
+
+func (s *fakeCustomers) FindByEmail(email string) (*customerModel.Customer, error) {
+	for _, customer := range s.existing {
+		if strings.EqualFold(customer.Email, email) {
+			found := customer
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeCustomers) FindByKvKNumber(kvkNumber string) (*customerModel.Customer, error) {
+	for _, customer := range s.existing {
+		if customer.KvKNumber == kvkNumber {
+			found := customer
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// fakeCustomFields kent alleen het vrije veld branche
+type fakeCustomFields struct {
+	customFieldService.CustomFieldService
+}
+
+func (fakeCustomFields) GetDefinitionByKey(key string) (*customFieldModel.CustomFieldDefinition, error) {
+	if key != "branche" {
+		return nil, errors.New("velddefinitie niet gevonden")
+	}
+	return &customFieldModel.CustomFieldDefinition{Key: key, Type: customFieldModel.FieldTypeText}, nil
+}
+
+func TestResolveMapping(t *testing.T) {
+	service := &importService{customFields: fakeCustomFields{}}
+
+	tests := []struct {
+		name        string
+		header      []string
+		mapping     model.ColumnMapping
+		upsertBy    string
+		wantMapping model.ColumnMapping
+		wantColumns map[string]int
+		wantErr     string
+	}{
+		{
+			name:        "kolomkoppen herkend",
+			header:      []string{"Bedrijfsnaam", "E-mailadres", "Telefoon", "KvK-nummer", "BTW nummer", "cf.Branche", "Opmerking"},
+			wantMapping: model.ColumnMapping{"Bedrijfsnaam": "name", "E-mailadres": "email", "Telefoon": "phone", "KvK-nummer": "kvk_number", "BTW nummer": "vat_number", "cf.Branche": "cf.branche"},
+			wantColumns: map[string]int{"name": 0, "email": 1, "phone": 2, "kvk_number": 3, "vat_number": 4, "cf.branche": 5},
+		},
+		{
+			name:        "opgegeven mapping, kolom overslaan met een leeg veld",
+			header:      []string{"Klant", "Mail", "Naam"},
+			mapping:     model.ColumnMapping{"Klant": "name", "Mail": "email", "Naam": ""},
+			upsertBy:    model.UpsertByEmail,
+			wantMapping: model.ColumnMapping{"Klant": "name", "Mail": "email", "Naam": ""},
+			wantColumns: map[string]int{"name": 0, "email": 1},
+		},
+		{
+			name:        "dubbele kolomkop gebruikt de eerste",
+			header:      []string{"naam", "email", "naam"},
+			wantMapping: model.ColumnMapping{"naam": "name", "email": "email"},
+			wantColumns: map[string]int{"name": 0, "email": 1},
+		},
+		{
+			name:    "fouten in de mapping",
+			header:  []string{"Klant", "Mail", "Web", "Extra"},
+			mapping: model.ColumnMapping{"Klant": "name", "Mail": "name", "Web": "website", "Extra": "cf.onbekend", "Fax": "phone"},
+			wantErr: "geen kolom gekoppeld aan verplicht veld 'email'; kolom 'Fax' staat niet in het bestand; onbekend veld 'website'; onbekend vrij veld 'onbekend'; veld 'name' is aan meerdere kolommen gekoppeld",
+		},
+		{
+			name:     "upsert zonder kolom",
+			header:   []string{"naam", "email"},
+			upsertBy: model.UpsertByKvK,
+			wantErr:  "geen kolom gekoppeld aan 'kvk_number' voor upsert",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, columns, err := service.resolveMapping(tt.header, tt.mapping, tt.upsertBy)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("resolveMapping gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveMapping gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(mapping, tt.wantMapping) {
+				t.Errorf("mapping = %v, verwacht %v", mapping, tt.wantMapping)
+			}
+			if !reflect.DeepEqual(columns, tt.wantColumns) {
+				t.Errorf("kolommen = %v, verwacht %v", columns, tt.wantColumns)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	windows1252, _ := charmap.Windows1252.NewEncoder().String("naam;email\nCafé 't Hoekje;info@hoekje.nl\n")
+
+	tests := []struct {
+		name          string
+		content       string
+		options       model.ImportOptions
+		wantHeader    []string
+		wantRows      []importRow
+		wantDelimiter rune
+		wantErr       string
+	}{
+		{
+			name:          "puntkomma herkend, BOM en lege regels overgeslagen",
+			content:       "\xEF\xBB\xBFnaam; email\nJansen;info@jansen.nl\n;\n\nDe Vries;\"info@devries.nl\"\n",
+			wantHeader:    []string{"naam", "email"},
+			wantRows:      []importRow{{2, []string{"Jansen", "info@jansen.nl"}}, {5, []string{"De Vries", "info@devries.nl"}}},
+			wantDelimiter: ';',
+		},
+		{
+			name:          "adres over meerdere regels",
+			content:       "naam,adres\nJansen,\"Dorpsstraat 1\n1234 AB Utrecht\"\nDe Vries,Kerkplein 3\n",
+			wantHeader:    []string{"naam", "adres"},
+			wantRows:      []importRow{{2, []string{"Jansen", "Dorpsstraat 1\n1234 AB Utrecht"}}, {4, []string{"De Vries", "Kerkplein 3"}}},
+			wantDelimiter: ',',
+		},
+		{
+			name:          "tab",
+			content:       "naam\temail\ttelefoon\nJansen, Bakkerij\tinfo@jansen.nl\n",
+			wantHeader:    []string{"naam", "email", "telefoon"},
+			wantRows:      []importRow{{2, []string{"Jansen, Bakkerij", "info@jansen.nl"}}},
+			wantDelimiter: '\t',
+		},
+		{
+			name:          "opgegeven scheidingsteken",
+			content:       "naam|email;adres\nJansen|info@jansen.nl;Dorpsstraat 1\n",
+			options:       model.ImportOptions{Delimiter: ';'},
+			wantHeader:    []string{"naam|email", "adres"},
+			wantRows:      []importRow{{2, []string{"Jansen|info@jansen.nl", "Dorpsstraat 1"}}},
+			wantDelimiter: ';',
+		},
+		{
+			name:          "windows-1252",
+			content:       windows1252,
+			options:       model.ImportOptions{Encoding: model.EncodingWindows1252},
+			wantHeader:    []string{"naam", "email"},
+			wantRows:      []importRow{{2, []string{"Café 't Hoekje", "info@hoekje.nl"}}},
+			wantDelimiter: ';',
+		},
+		{name: "windows-1252 als utf-8", content: windows1252, wantErr: "regel 2 is geen geldige UTF-8; kies encoding windows-1252"},
+		{name: "alleen een kopregel", content: "naam,email\n,\n", wantErr: "bestand bevat geen rijen onder de kopregel"},
+		{name: "leeg", content: "", wantErr: "bestand is leeg"},
+		{name: "onbekende encoding", content: "naam\n", options: model.ImportOptions{Encoding: "latin-9"}, wantErr: "onbekende encoding 'latin-9', gebruik utf-8 of windows-1252"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			options.Format = model.FormatCSV
+			header, rows, err := readFile(strings.NewReader(tt.content), &options)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("readFile gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readFile gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(header, tt.wantHeader) || !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("gelezen %q, %v; verwacht %q, %v", header, rows, tt.wantHeader, tt.wantRows)
+			}
+			if options.Delimiter != tt.wantDelimiter {
+				t.Errorf("scheidingsteken %q, verwacht %q", options.Delimiter, tt.wantDelimiter)
+			}
+		})
+	}
+}
+
+func TestStartImport(t *testing.T) {
+	content := strings.Join([]string{
+		"naam;email;kvk;cf.branche",
+		"Bakkerij Jansen;info@jansen.nl;12345678;bakker",     // regel 2: bestaat al, bijwerken
+		"De Vries;info@devries.nl;;",                         // regel 3: nieuw
+		";zonder-naam@example.nl;;",                          // regel 4: naam ontbreekt
+		"Pietersen;geen-email;;",                             // regel 5: ongeldig e-mailadres
+		"De Vries Installatie;INFO@devries.nl;87654321;bouw", // regel 6: zelfde e-mail als regel 3
+	}, "\n")
+
+	tests := []struct {
+		name        string
+		upsertBy    string
+		dryRun      bool
+		wantCreated int
+		wantUpdated int
+		wantErrors  model.RowErrors
+		wantSaved   []string
+	}{
+		{
+			name:        "upsert op e-mail",
+			upsertBy:    model.UpsertByEmail,
+			wantCreated: 1,
+			wantUpdated: 2,
+			wantErrors:  model.RowErrors{{Row: 4, Message: "naam is verplicht"}, {Row: 5, Message: "ongeldig email adres"}},
+			wantSaved:   []string{"created De Vries", "updated Bakkerij Jansen", "updated De Vries Installatie"},
+		},
+		{
+			// Bij een dry-run bestaat De Vries van regel 3 nog niet; regel 6 telt toch als bijwerken
+			name:        "dry-run",
+			upsertBy:    model.UpsertByEmail,
+			dryRun:      true,
+			wantCreated: 1,
+			wantUpdated: 2,
+			wantErrors:  model.RowErrors{{Row: 4, Message: "naam is verplicht"}, {Row: 5, Message: "ongeldig email adres"}},
+		},
+		{
+			name:        "zonder upsert",
+			wantCreated: 3,
+			wantErrors:  model.RowErrors{{Row: 4, Message: "naam is verplicht"}, {Row: 5, Message: "ongeldig email adres"}},
+			wantSaved:   []string{"created Bakkerij Jansen", "created De Vries", "created De Vries Installatie"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeJobRepository{}
+			customers := &fakeCustomers{existing: []customerModel.Customer{{ID: 1, Name: "Jansen", Email: "info@jansen.nl", KvKNumber: "12345678"}}}
+			service := NewImportService(repo, customers, fakeCustomFields{}, 100)
+
+			job, err := service.StartImport(strings.NewReader(content), model.ImportOptions{Format: model.FormatCSV, UpsertBy: tt.upsertBy, DryRun: tt.dryRun}, 1, "johndoe")
+			if err != nil {
+				t.Fatalf("StartImport gaf fout: %v", err)
+			}
+			if job.Status != model.JobStatusCompleted || repo.saved == nil || repo.saved.Status != model.JobStatusCompleted {
+				t.Fatalf("import niet afgerond: %+v", job)
+			}
+			if job.TotalRows != 5 || job.ProcessedRows != 5 || job.Delimiter != ";" {
+				t.Errorf("%d van %d rijen verwerkt met scheidingsteken %q", job.ProcessedRows, job.TotalRows, job.Delimiter)
+			}
+			if job.CreatedCount != tt.wantCreated || job.UpdatedCount != tt.wantUpdated {
+				t.Errorf("%d aangemaakt, %d bijgewerkt; verwacht %d, %d", job.CreatedCount, job.UpdatedCount, tt.wantCreated, tt.wantUpdated)
+			}
+			if job.ErrorCount != len(tt.wantErrors) || !reflect.DeepEqual(job.Errors, tt.wantErrors) {
+				t.Errorf("fouten = %+v, verwacht %+v", job.Errors, tt.wantErrors)
+			}
+
+			var saved []string
+			for _, customer := range customers.created {
+				saved = append(saved, "created "+customer.Name)
+			}
+			for _, customer := range customers.updated {
+				saved = append(saved, "updated "+customer.Name)
+			}
+			if !reflect.DeepEqual(saved, tt.wantSaved) {
+				t.Errorf("opgeslagen %v, verwacht %v", saved, tt.wantSaved)
+			}
+		})
+	}
+
+	// Een bijgewerkte klant houdt waarden die in het bestand leeg zijn, en krijgt de vrije velden erbij
+	customers := &fakeCustomers{existing: []customerModel.Customer{{ID: 1, Name: "Jansen", Email: "info@jansen.nl", Phone: "020-1234567"}}}
+	_, err := NewImportService(&fakeJobRepository{}, customers, fakeCustomFields{}, 100).StartImport(
+		bytes.NewBufferString("naam,email,telefoon,cf.branche\nBakkerij Jansen,info@jansen.nl,,bakker\n"),
+		model.ImportOptions{Format: model.FormatCSV, UpsertBy: model.UpsertByEmail}, 1, "johndoe")
+	if err != nil {
+		t.Fatalf("StartImport gaf fout: %v", err)
+	}
+	want := customerModel.Customer{ID: 1, Name: "Bakkerij Jansen", Email: "info@jansen.nl", Phone: "020-1234567", CustomFields: customerModel.CustomFields{"branche": "bakker"}}
+	if len(customers.updated) != 1 || !reflect.DeepEqual(customers.updated[0], want) {
+		t.Errorf("bijgewerkt %+v, verwacht %+v", customers.updated, want)
+	}
+
+	if _, err := NewImportService(&fakeJobRepository{}, customers, fakeCustomFields{}, 100).StartImport(
+		strings.NewReader(content), model.ImportOptions{Format: model.FormatCSV, UpsertBy: "phone"}, 1, "johndoe"); err == nil {
+		t.Error("upsert_by=phone gaf geen fout")
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"odomosml/internal/customerimport/model"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// maxImportRows is het maximum aantal rijen (exclusief kopregel) per import
+const maxImportRows = 100000
+
+// delimiterCandidates zijn de scheidingstekens die herkend worden als er geen is opgegeven
+var delimiterCandidates = []rune{',', ';', '\t', '|'}
+
+// utf8BOM is de byte order mark die Excel voor UTF-8 CSV bestanden schrijft
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// importRow is een rij uit het bestand met het regelnummer zoals de gebruiker het ziet
+type importRow struct {
+	number int
+	values []string
+}
+
+// readFile leest de kopregel en de rijen van een CSV of XLSX bestand, of de vCards uit een .vcf bestand.
+// Lege rijen worden overgeslagen; regelnummers tellen de kopregel als regel 1.
+func readFile(content io.Reader, options *model.ImportOptions) ([]string, []importRow, error) {
+	if options.Format == model.FormatVCard {
+		return readVCard(content, options)
+	}
+
+	var (
+		records [][]string
+		lines   []int
+		err     error
+	)
+
+	switch options.Format {
+	case model.FormatCSV:
+		records, lines, err = readCSV(content, options)
+	case model.FormatXLSX:
+		records, err = readXLSX(content, options.Sheet)
+	default:
+		return nil, nil, fmt.Errorf("onbekend bestandsformaat '%s', gebruik csv, xlsx of vcf", options.Format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, nil, errors.New("bestand is leeg")
+	}
+
+	header := make([]string, len(records[0]))
+	for i, column := range records[0] {
+		header[i] = strings.TrimSpace(column)
+	}
+
+	var rows []importRow
+	for i, record := range records[1:] {
+		if isEmptyRecord(record) {
+			continue
+		}
+		number := i + 2
+		if lines != nil {
+			number = lines[i+1]
+		}
+		rows = append(rows, importRow{number: number, values: record})
+	}
+
+	if len(rows) == 0 {
+		return nil, nil, errors.New("bestand bevat geen rijen onder de kopregel")
+	}
+	if len(rows) > maxImportRows {
+		return nil, nil, fmt.Errorf("bestand bevat %d rijen, maximaal %d per import", len(rows), maxImportRows)
+	}
+
+	return header, rows, nil
+}
+
+// readCSV leest een CSV bestand in de opgegeven tekencodering. Retourneert ook per record het regelnummer
+// waarop het begint: de CSV reader slaat lege regels over en een waarde tussen aanhalingstekens kan
+// over meerdere regels lopen, dus de index van een record is niet zijn regel in het bestand.
+func readCSV(content io.Reader, options *model.ImportOptions) ([][]string, []int, error) {
+	var reader io.Reader
+	switch options.Encoding {
+	case "", model.EncodingUTF8:
+		options.Encoding = model.EncodingUTF8
+		buffered := bufio.NewReader(content)
+		if bom, _ := buffered.Peek(len(utf8BOM)); bytes.Equal(bom, utf8BOM) {
+			buffered.Discard(len(utf8BOM))
+		}
+		reader = buffered
+	case model.EncodingWindows1252:
+		reader = transform.NewReader(content, charmap.Windows1252.NewDecoder())
+	default:
+		return nil, nil, fmt.Errorf("onbekende encoding '%s', gebruik utf-8 of windows-1252", options.Encoding)
+	}
+
+	buffered := bufio.NewReader(reader)
+	if options.Delimiter == 0 {
+		options.Delimiter = detectDelimiter(buffered)
+	}
+
+	csvReader := csv.NewReader(buffered)
+	csvReader.Comma = options.Delimiter
+	csvReader.FieldsPerRecord = -1
+
+	var (
+		records [][]string
+		lines   []int
+	)
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("ongeldig CSV bestand: %w", err)
+		}
+
+		line, _ := csvReader.FieldPos(0)
+		for _, value := range record {
+			if !utf8.ValidString(value) {
+				return nil, nil, fmt.Errorf("regel %d is geen geldige UTF-8; kies encoding windows-1252", line)
+			}
+		}
+
+		records = append(records, record)
+		lines = append(lines, line)
+		if len(records) > maxImportRows+1 {
+			return nil, nil, fmt.Errorf("bestand bevat meer dan %d rijen", maxImportRows)
+		}
+	}
+
+	return records, lines, nil
+}
+
+// detectDelimiter kiest het scheidingsteken dat het vaakst in de kopregel voorkomt
+func detectDelimiter(reader *bufio.Reader) rune {
+	line, _ := reader.Peek(4096)
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	best, bestCount := ',', 0
+	for _, candidate := range delimiterCandidates {
+		if count := bytes.Count(line, []byte(string(candidate))); count > bestCount {
+			best, bestCount = candidate, count
+		}
+	}
+	return best
+}
+
+// readXLSX leest een werkblad uit een XLSX bestand (standaard het eerste)
+func readXLSX(content io.Reader, sheet string) ([][]string, error) {
+	file, err := excelize.OpenReader(content)
+	if err != nil {
+		return nil, fmt.Errorf("ongeldig XLSX bestand: %w", err)
+	}
+	defer file.Close()
+
+	if sheet == "" {
+		sheets := file.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("XLSX bestand bevat geen werkbladen")
+		}
+		sheet = sheets[0]
+	}
+
+	rows, err := file.Rows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("werkblad '%s' niet gevonden", sheet)
+	}
+	defer rows.Close()
+
+	var records [][]string
+	for rows.Next() {
+		record, err := rows.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("ongeldig XLSX bestand: %w", err)
+		}
+
+		records = append(records, record)
+		if len(records) > maxImportRows+1 {
+			return nil, fmt.Errorf("bestand bevat meer dan %d rijen", maxImportRows)
+		}
+	}
+
+	return records, rows.Error()
+}
+
+// isEmptyRecord controleert of alle waarden in een rij leeg zijn
+func isEmptyRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
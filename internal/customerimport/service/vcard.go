@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	customerModel "odomosml/internal/customer/model"
+	"odomosml/internal/customerimport/model"
+	"odomosml/pkg/vcard"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// vcardColumns zijn de kolommen waarin de gegevens van een vCard worden aangeboden, zodat een
+// vCard als rij door de gewone import kan
+var vcardColumns = []string{fieldName, fieldEmail, fieldPhone, fieldAddress, fieldKvKNumber, fieldVATNumber}
+
+// readVCard leest de vCards uit een .vcf bestand als rijen onder vcardColumns. Het regelnummer van
+// een rij is de regel van BEGIN:VCARD.
+func readVCard(content io.Reader, options *model.ImportOptions) ([]string, []importRow, error) {
+	switch options.Encoding {
+	case "", model.EncodingUTF8:
+		options.Encoding = model.EncodingUTF8
+	case model.EncodingWindows1252:
+		content = transform.NewReader(content, charmap.Windows1252.NewDecoder())
+	default:
+		return nil, nil, fmt.Errorf("onbekende encoding '%s', gebruik utf-8 of windows-1252", options.Encoding)
+	}
+
+	cards, err := vcard.Decode(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ongeldig vCard bestand: %w", err)
+	}
+	if len(cards) > maxImportRows {
+		return nil, nil, fmt.Errorf("bestand bevat %d vCards, maximaal %d per import", len(cards), maxImportRows)
+	}
+
+	rows := make([]importRow, len(cards))
+	for i := range cards {
+		rows[i] = importRow{number: cards[i].Line, values: cardValues(&cards[i])}
+	}
+	return vcardColumns, rows, nil
+}
+
+// cardValues zet een vCard om in een waarde per kolom van vcardColumns. De naam van de klant is de
+// organisatie (ORG); een vCard van een persoon zonder organisatie wordt een klant op naam van die persoon.
+func cardValues(card *vcard.Card) []string {
+	name := ""
+	if org, ok := card.Preferred("ORG"); ok {
+		name = org.Components()[0]
+	}
+	if name == "" {
+		name = card.Text("FN")
+	}
+	if n, ok := card.Preferred("N"); ok && name == "" {
+		// N is familienaam;voornaam;extra namen;prefix;suffix
+		parts := n.Components()
+		var names []string
+		for _, i := range []int{3, 1, 2, 0, 4} {
+			if i < len(parts) && parts[i] != "" {
+				names = append(names, parts[i])
+			}
+		}
+		name = strings.Join(names, " ")
+	}
+
+	email := card.Text("EMAIL")
+	if len(email) >= len("mailto:") && strings.EqualFold(email[:len("mailto:")], "mailto:") {
+		email = email[len("mailto:"):]
+	}
+
+	phone := strings.TrimPrefix(card.Text("TEL"), "tel:")
+
+	address := ""
+	if adr, ok := card.Preferred("ADR"); ok {
+		address = formatAddress(adr.Components())
+	}
+	if address == "" {
+		address = strings.Join(strings.Fields(card.Text("LABEL")), " ")
+	}
+
+	return []string{
+		name,
+		strings.TrimSpace(email),
+		strings.TrimSpace(phone),
+		address,
+		card.Text(customerModel.VCardKvKNumber),
+		card.Text(customerModel.VCardVATNumber),
+	}
+}
+
+// formatAddress maakt één regel van de delen van ADR, op de Nederlandse manier:
+// straat, postcode plaats, provincie, land
+func formatAddress(parts []string) string {
+	part := func(i int) string {
+		if i < len(parts) {
+			return strings.Join(strings.Fields(parts[i]), " ")
+		}
+		return ""
+	}
+
+	street := strings.TrimSpace(part(vcard.AdrStreet) + " " + part(vcard.AdrExtended))
+	if street == "" {
+		street = part(vcard.AdrPOBox)
+	}
+	city := strings.TrimSpace(part(vcard.AdrPostalCode) + " " + part(vcard.AdrLocality))
+
+	var lines []string
+	for _, line := range []string{street, city, part(vcard.AdrRegion), part(vcard.AdrCountry)} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, ", ")
+}
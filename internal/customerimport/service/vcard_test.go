@@ -0,0 +1,111 @@
+package service
+
+import (
+	"bytes"
+	customerModel "odomosml/internal/customer/model"
+	"odomosml/internal/customerimport/model"
+	tagModel "odomosml/internal/tag/model"
+	"odomosml/pkg/vcard"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVCardExportImportRoundTrip(t *testing.T) {
+	customers := []customerModel.Customer{
+		{
+			ID:        1,
+			Name:      "Bakkerij Jansen & Zonen B.V.",
+			Email:     "info@bakkerijjansen.nl",
+			Phone:     "+31 20 123 4567",
+			Address:   "Dorpsstraat 1, 1234 AB Amsterdam",
+			KvKNumber: "12345678",
+			VATNumber: "NL123456789B01",
+			Tags:      []tagModel.Tag{{Name: "bakker"}, {Name: "noord, west"}},
+			UpdatedAt: time.Date(2024, 2, 25, 20, 30, 0, 0, time.UTC),
+		},
+		{
+			ID:      2,
+			Name:    "Café 't Hoekje; vof",
+			Address: "Kerkplein 3\\4, 9999 ZZ Ons Dorp",
+		},
+		{
+			ID:   3,
+			Name: strings.Repeat("Lange naam met één accent ", 5),
+		},
+	}
+
+	for _, version := range []string{vcard.Version3, vcard.Version4} {
+		t.Run(version, func(t *testing.T) {
+			var buf bytes.Buffer
+			for i := range customers {
+				if err := vcard.Encode(&buf, customers[i].VCard(version), version); err != nil {
+					t.Fatalf("Encode gaf fout: %v", err)
+				}
+			}
+
+			columns, rows, err := readVCard(&buf, &model.ImportOptions{})
+			if err != nil {
+				t.Fatalf("readVCard gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(columns, vcardColumns) {
+				t.Errorf("kolommen = %v", columns)
+			}
+			if len(rows) != len(customers) {
+				t.Fatalf("%d rijen gelezen, verwacht %d", len(rows), len(customers))
+			}
+
+			for i, customer := range customers {
+				want := []string{strings.TrimSpace(customer.Name), customer.Email, customer.Phone, customer.Address, customer.KvKNumber, customer.VATNumber}
+				if !reflect.DeepEqual(rows[i].values, want) {
+					t.Errorf("klant %d: %q, verwacht %q", customer.ID, rows[i].values, want)
+				}
+			}
+			if rows[0].number != 1 || rows[1].number <= rows[0].number {
+				t.Errorf("regelnummers %d en %d", rows[0].number, rows[1].number)
+			}
+		})
+	}
+}
+
+func TestCardValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "persoon zonder organisatie",
+			input: "BEGIN:VCARD\nVERSION:3.0\nN:Jansen;Piet;van;dhr.;\nEMAIL:mailto:piet@example.nl\nTEL:tel:+31612345678\nEND:VCARD\n",
+			want:  []string{"dhr. Piet van Jansen", "piet@example.nl", "+31612345678", "", "", ""},
+		},
+		{
+			name:  "FN zonder ORG",
+			input: "BEGIN:VCARD\nVERSION:4.0\nFN:Piet Jansen\nEND:VCARD\n",
+			want:  []string{"Piet Jansen", "", "", "", "", ""},
+		},
+		{
+			name:  "adres in delen",
+			input: "BEGIN:VCARD\nVERSION:3.0\nORG:Jansen\nADR;TYPE=work:;;Dorpsstraat 1;Amsterdam;Noord-Holland;1234 AB;Nederland\nEND:VCARD\n",
+			want:  []string{"Jansen", "", "", "Dorpsstraat 1, 1234 AB Amsterdam, Noord-Holland, Nederland", "", ""},
+		},
+		{
+			name:  "LABEL als er geen ADR is",
+			input: "BEGIN:VCARD\nVERSION:3.0\nORG:Jansen\nLABEL:Dorpsstraat 1\\n1234 AB  Amsterdam\nEND:VCARD\n",
+			want:  []string{"Jansen", "", "", "Dorpsstraat 1 1234 AB Amsterdam", "", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cards, err := vcard.Decode(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Decode gaf fout: %v", err)
+			}
+			if got := cardValues(&cards[0]); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cardValues = %q, verwacht %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"net/http"
+	"odomosml/internal/customerstatus/model"
+	"odomosml/internal/customerstatus/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomerStatusHandler handles HTTP requests for customer status definitions
+type CustomerStatusHandler struct {
+	service service.CustomerStatusService
+}
+
+// NewCustomerStatusHandler maakt een nieuwe CustomerStatusHandler instantie
+func NewCustomerStatusHandler(service service.CustomerStatusService) *CustomerStatusHandler {
+	return &CustomerStatusHandler{
+		service: service,
+	}
+}
+
+// @Summary      Klantstatussen ophalen
+// @Description  Haalt de statussen van de klantlevenscyclus op in de volgorde van het bord, met per status de toegestane overgangen
+// @Tags         customer-statuses
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      500  {object}  map[string]string "Server error"
+// @Security     Bearer
+// @Router       /customer-statuses [get]
+func (h *CustomerStatusHandler) GetAll(c *gin.Context) {
+	statuses, err := h.service.GetAllStatuses()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    statuses,
+	})
+}
+
+// @Summary      Klantstatus aanmaken
+// @Description  Voegt een status aan de klantlevenscyclus toe (alleen admin). Overgangen verwijzen naar keys van bestaande statussen.
+// @Tags         customer-statuses
+// @Accept       json
+// @Produce      json
+// @Param        status body model.StatusDefinition true "Status"
+// @Success      201  {object}  model.StatusDefinition "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Onvoldoende rechten"
+// @Security     Bearer
+// @Router       /customer-statuses [post]
+func (h *CustomerStatusHandler) Create(c *gin.Context) {
+	var status model.StatusDefinition
+	if err := c.ShouldBindJSON(&status); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	created, err := h.service.CreateStatus(&status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Klantstatus bijwerken
+// @Description  Werkt label, volgorde, initiële status of overgangen van een status bij (alleen admin)
+// @Tags         customer-statuses
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Status ID"
+// @Param        status body model.StatusDefinition true "Status"
+// @Success      200  {object}  model.StatusDefinition "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Onvoldoende rechten"
+// @Security     Bearer
+// @Router       /customer-statuses/{id} [put]
+func (h *CustomerStatusHandler) Update(c *gin.Context) {
+	idInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldig ID",
+		})
+		return
+	}
+
+	var status model.StatusDefinition
+	if err := c.ShouldBindJSON(&status); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	status.ID = uint(idInt)
+
+	updated, err := h.service.UpdateStatus(&status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Klantstatus verwijderen
+// @Description  Verwijdert een status waar geen klanten meer in staan (alleen admin); de status verdwijnt ook uit de overgangen van andere statussen
+// @Tags         customer-statuses
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Status ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      400  {object}  map[string]string "Status in gebruik of ongeldig ID"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Onvoldoende rechten"
+// @Security     Bearer
+// @Router       /customer-statuses/{id} [delete]
+func (h *CustomerStatusHandler) Delete(c *gin.Context) {
+	statusData, err := h.service.DeleteStatus(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Sla statusData op in context voor audit logging
+	c.Set("statusData", statusData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Status succesvol verwijderd",
+	})
+}
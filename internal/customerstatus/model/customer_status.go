@@ -0,0 +1,84 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Keys is een lijst status keys die als JSONB wordt opgeslagen
+type Keys []string
+
+// Value implementeert driver.Valuer
+func (k Keys) Value() (driver.Value, error) {
+	if k == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(k))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (k *Keys) Scan(value interface{}) error {
+	if value == nil {
+		*k = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("kan %T niet converteren naar Keys", value)
+	}
+
+	return json.Unmarshal(data, (*[]string)(k))
+}
+
+// StatusDefinition beschrijft een status in de levenscyclus van een klant (een fase in de pipeline),
+// met de statussen waar een klant vanuit deze status naartoe mag
+// @Description Definitie van een klantstatus
+type StatusDefinition struct {
+	ID          uint      `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	Key         string    `json:"key" gorm:"size:50;not null;uniqueIndex" binding:"required" example:"prospect" swaggertype:"string"`
+	Label       string    `json:"label" gorm:"size:100;not null" binding:"required" example:"Prospect" swaggertype:"string"`
+	Position    int       `json:"position" gorm:"not null;default:0" example:"2" swaggertype:"integer"`           // Volgorde van de kolommen op het bord
+	Initial     bool      `json:"initial" gorm:"not null;default:false" example:"false" swaggertype:"boolean"`    // Status van nieuwe klanten; hoogstens één status is initieel
+	Transitions Keys      `json:"transitions" gorm:"type:jsonb;not null;default:'[]'" swaggertype:"array,string"` // Keys van de statussen waar een klant vanuit deze status naartoe mag
+	CreatedAt   time.Time `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (StatusDefinition) TableName() string {
+	return "customer_statuses"
+}
+
+// Allows geeft aan of een klant vanuit deze status naar de opgegeven status mag
+func (d *StatusDefinition) Allows(key string) bool {
+	for _, transition := range d.Transitions {
+		if transition == key {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultStatuses is de workflow waarmee een nieuwe database begint: van lead via prospect naar actieve
+// klant, met verloren leads en opgezegde klanten die later weer kunnen terugkomen
+func DefaultStatuses() []StatusDefinition {
+	return []StatusDefinition{
+		{Key: "lead", Label: "Lead", Position: 1, Initial: true, Transitions: Keys{"prospect", "lost"}},
+		{Key: "prospect", Label: "Prospect", Position: 2, Transitions: Keys{"active", "lost", "lead"}},
+		{Key: "active", Label: "Actief", Position: 3, Transitions: Keys{"churned"}},
+		{Key: "churned", Label: "Opgezegd", Position: 4, Transitions: Keys{"active", "prospect"}},
+		{Key: "lost", Label: "Verloren", Position: 5, Transitions: Keys{"lead"}},
+	}
+}
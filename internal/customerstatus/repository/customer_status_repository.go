@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/customerstatus/model"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// CustomerStatusRepository definieert de interface voor de klantstatus repository
+type CustomerStatusRepository interface {
+	FindAll() ([]model.StatusDefinition, error)
+	FindByID(id string) (*model.StatusDefinition, error)
+	FindByKey(key string) (*model.StatusDefinition, error)
+	CountCustomers(key string) (int64, error)
+	Create(status *model.StatusDefinition) (*model.StatusDefinition, error)
+	Update(status *model.StatusDefinition) (*model.StatusDefinition, error)
+	Delete(status *model.StatusDefinition) error
+}
+
+// customerStatusRepository implementeert de CustomerStatusRepository interface
+type customerStatusRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomerStatusRepository maakt een nieuwe CustomerStatusRepository instantie
+func NewCustomerStatusRepository(db *gorm.DB) CustomerStatusRepository {
+	return &customerStatusRepository{
+		db: db,
+	}
+}
+
+// FindAll haalt alle statussen op in de volgorde van het bord
+func (r *customerStatusRepository) FindAll() ([]model.StatusDefinition, error) {
+	var statuses []model.StatusDefinition
+	if err := r.db.Order("position ASC, key ASC").Find(&statuses).Error; err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// FindByID haalt een status op op basis van ID
+func (r *customerStatusRepository) FindByID(id string) (*model.StatusDefinition, error) {
+	var status model.StatusDefinition
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&status, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("status niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// FindByKey haalt een status op op basis van key
+func (r *customerStatusRepository) FindByKey(key string) (*model.StatusDefinition, error) {
+	var status model.StatusDefinition
+
+	if err := r.db.Where("key = ?", key).First(&status).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("status niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// CountCustomers telt het aantal (niet verwijderde) klanten met de opgegeven status
+func (r *customerStatusRepository) CountCustomers(key string) (int64, error) {
+	var count int64
+	if err := r.db.Table("customers").Where("status = ? AND deleted_at IS NULL", key).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Create maakt een nieuwe status aan; is de status initieel, dan is geen andere status dat meer
+func (r *customerStatusRepository) Create(status *model.StatusDefinition) (*model.StatusDefinition, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearInitial(tx, status); err != nil {
+			return err
+		}
+		return tx.Create(status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Update werkt een bestaande status bij (de key is onveranderlijk)
+func (r *customerStatusRepository) Update(status *model.StatusDefinition) (*model.StatusDefinition, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearInitial(tx, status); err != nil {
+			return err
+		}
+		return tx.Model(status).
+			Select("label", "position", "initial", "transitions").
+			Updates(status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Delete verwijdert een status en haalt de status uit de overgangen van de andere statussen
+func (r *customerStatusRepository) Delete(status *model.StatusDefinition) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE customer_statuses SET transitions = transitions - ? WHERE jsonb_exists(transitions, ?)",
+			status.Key, status.Key).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(status).Error
+	})
+}
+
+// clearInitial maakt de andere statussen niet-initieel als de status initieel wordt
+func clearInitial(tx *gorm.DB, status *model.StatusDefinition) error {
+	if !status.Initial {
+		return nil
+	}
+	return tx.Model(&model.StatusDefinition{}).Where("initial AND id <> ?", status.ID).Update("initial", false).Error
+}
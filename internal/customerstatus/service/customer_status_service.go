@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"odomosml/internal/customerstatus/model"
+	"odomosml/internal/customerstatus/repository"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// keyPattern valideert status keys: kleine letters, cijfers en underscores
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// CustomerStatusService definieert de interface voor de klantstatus service
+type CustomerStatusService interface {
+	GetAllStatuses() ([]model.StatusDefinition, error)
+	GetStatusByKey(key string) (*model.StatusDefinition, error)
+	CreateStatus(status *model.StatusDefinition) (*model.StatusDefinition, error)
+	UpdateStatus(status *model.StatusDefinition) (*model.StatusDefinition, error)
+	DeleteStatus(id string) (map[string]interface{}, error)
+}
+
+// customerStatusService implementeert de CustomerStatusService interface
+type customerStatusService struct {
+	repo repository.CustomerStatusRepository
+}
+
+// NewCustomerStatusService maakt een nieuwe CustomerStatusService instantie
+func NewCustomerStatusService(repo repository.CustomerStatusRepository) CustomerStatusService {
+	return &customerStatusService{
+		repo: repo,
+	}
+}
+
+// GetAllStatuses haalt alle statussen op in de volgorde van het bord
+func (s *customerStatusService) GetAllStatuses() ([]model.StatusDefinition, error) {
+	return s.repo.FindAll()
+}
+
+// GetStatusByKey haalt een status op op basis van key
+func (s *customerStatusService) GetStatusByKey(key string) (*model.StatusDefinition, error) {
+	return s.repo.FindByKey(key)
+}
+
+// CreateStatus maakt een nieuwe status aan
+func (s *customerStatusService) CreateStatus(status *model.StatusDefinition) (*model.StatusDefinition, error) {
+	status.Key = strings.TrimSpace(status.Key)
+	if !keyPattern.MatchString(status.Key) {
+		return nil, errors.New("key mag alleen kleine letters, cijfers en underscores bevatten en moet met een letter beginnen")
+	}
+
+	if existing, _ := s.repo.FindByKey(status.Key); existing != nil {
+		return nil, errors.New("status met deze key bestaat al")
+	}
+
+	if err := s.validate(status); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(status)
+}
+
+// UpdateStatus werkt label, volgorde, initiële status en overgangen van een status bij
+func (s *customerStatusService) UpdateStatus(status *model.StatusDefinition) (*model.StatusDefinition, error) {
+	if status.ID == 0 {
+		return nil, errors.New("status ID is verplicht")
+	}
+
+	existing, err := s.repo.FindByID(strconv.FormatUint(uint64(status.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	// De key is onveranderlijk omdat klanten en de statusgeschiedenis ernaar verwijzen
+	if status.Key != "" && status.Key != existing.Key {
+		return nil, errors.New("de key van een status kan niet gewijzigd worden")
+	}
+	status.Key = existing.Key
+
+	if err := s.validate(status); err != nil {
+		return nil, err
+	}
+
+	status.CreatedAt = existing.CreatedAt
+	return s.repo.Update(status)
+}
+
+// DeleteStatus verwijdert een status die geen klant meer heeft en retourneert de data voor audit logging
+func (s *customerStatusService) DeleteStatus(id string) (map[string]interface{}, error) {
+	status, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountCustomers(status.Key)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("status kan niet verwijderd worden: %d klant(en) hebben deze status", count)
+	}
+
+	statusData := map[string]interface{}{
+		"id":          status.ID,
+		"key":         status.Key,
+		"label":       status.Label,
+		"transitions": status.Transitions,
+	}
+
+	if err := s.repo.Delete(status); err != nil {
+		return nil, err
+	}
+
+	return statusData, nil
+}
+
+// validate controleert het label en de overgangen; een overgang moet naar een andere, bestaande status gaan
+func (s *customerStatusService) validate(status *model.StatusDefinition) error {
+	status.Label = strings.TrimSpace(status.Label)
+	if status.Label == "" {
+		return errors.New("label is verplicht")
+	}
+
+	statuses, err := s.repo.FindAll()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(statuses))
+	for _, existing := range statuses {
+		known[existing.Key] = true
+	}
+
+	seen := make(map[string]bool, len(status.Transitions))
+	transitions := make(model.Keys, 0, len(status.Transitions))
+	for _, key := range status.Transitions {
+		key = strings.TrimSpace(key)
+		if seen[key] {
+			continue
+		}
+		if key == status.Key {
+			return fmt.Errorf("status '%s' kan geen overgang naar zichzelf hebben", key)
+		}
+		if !known[key] {
+			return fmt.Errorf("overgang naar onbekende status '%s'", key)
+		}
+		seen[key] = true
+		transitions = append(transitions, key)
+	}
+	status.Transitions = transitions
+
+	return nil
+}
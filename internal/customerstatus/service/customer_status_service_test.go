@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"odomosml/internal/customerstatus/model"
+	"odomosml/internal/customerstatus/repository"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeStatusRepository bevat de standaard workflow, met één klant in status "active"
+type fakeStatusRepository struct {
+	repository.CustomerStatusRepository
+	statuses []model.StatusDefinition
+	deleted  []string
+}
+
+func newFakeStatusRepository() *fakeStatusRepository {
+	statuses := model.DefaultStatuses()
+	for i := range statuses {
+		statuses[i].ID = uint(i + 1)
+	}
+	return &fakeStatusRepository{statuses: statuses}
+}
+
+func (r *fakeStatusRepository) FindAll() ([]model.StatusDefinition, error) {
+	return r.statuses, nil
+}
+
+func (r *fakeStatusRepository) FindByID(id string) (*model.StatusDefinition, error) {
+	for _, status := range r.statuses {
+		if strconv.FormatUint(uint64(status.ID), 10) == id {
+			return &status, nil
+		}
+	}
+	return nil, errors.New("status niet gevonden")
+}
+
+func (r *fakeStatusRepository) FindByKey(key string) (*model.StatusDefinition, error) {
+	for _, status := range r.statuses {
+		if status.Key == key {
+			return &status, nil
+		}
+	}
+	return nil, errors.New("status niet gevonden")
+}
+
+func (r *fakeStatusRepository) CountCustomers(key string) (int64, error) {
+	if key == "active" {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (r *fakeStatusRepository) Create(status *model.StatusDefinition) (*model.StatusDefinition, error) {
+	return status, nil
+}
+
+func (r *fakeStatusRepository) Update(status *model.StatusDefinition) (*model.StatusDefinition, error) {
+	return status, nil
+}
+
+func (r *fakeStatusRepository) Delete(status *model.StatusDefinition) error {
+	r.deleted = append(r.deleted, status.Key)
+	return nil
+}
+
+func TestCreateStatus(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          model.StatusDefinition
+		wantLabel       string
+		wantTransitions model.Keys
+		wantErr         string
+	}{
+		{
+			name:            "overgangen getrimd en ontdubbeld",
+			status:          model.StatusDefinition{Key: " on_hold ", Label: " In de wacht ", Transitions: model.Keys{"active", " lost", "active"}},
+			wantLabel:       "In de wacht",
+			wantTransitions: model.Keys{"active", "lost"},
+		},
+		{name: "zonder overgangen", status: model.StatusDefinition{Key: "archived", Label: "Archief"}, wantLabel: "Archief", wantTransitions: model.Keys{}},
+		{name: "ongeldige key", status: model.StatusDefinition{Key: "On Hold", Label: "In de wacht"}, wantErr: "key mag alleen kleine letters, cijfers en underscores bevatten en moet met een letter beginnen"},
+		{name: "key met een cijfer vooraan", status: model.StatusDefinition{Key: "1st", Label: "Eerste"}, wantErr: "key mag alleen kleine letters, cijfers en underscores bevatten en moet met een letter beginnen"},
+		{name: "bestaande key", status: model.StatusDefinition{Key: "lead", Label: "Lead"}, wantErr: "status met deze key bestaat al"},
+		{name: "zonder label", status: model.StatusDefinition{Key: "on_hold", Label: " "}, wantErr: "label is verplicht"},
+		{name: "overgang naar zichzelf", status: model.StatusDefinition{Key: "on_hold", Label: "In de wacht", Transitions: model.Keys{"on_hold"}}, wantErr: "status 'on_hold' kan geen overgang naar zichzelf hebben"},
+		{name: "onbekende overgang", status: model.StatusDefinition{Key: "on_hold", Label: "In de wacht", Transitions: model.Keys{"vip"}}, wantErr: "overgang naar onbekende status 'vip'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.status
+			created, err := NewCustomerStatusService(newFakeStatusRepository()).CreateStatus(&status)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("CreateStatus gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateStatus gaf fout: %v", err)
+			}
+			if created.Key != strings.TrimSpace(tt.status.Key) || created.Label != tt.wantLabel {
+				t.Errorf("status = %+v", created)
+			}
+			if !reflect.DeepEqual(created.Transitions, tt.wantTransitions) {
+				t.Errorf("overgangen = %v, verwacht %v", created.Transitions, tt.wantTransitions)
+			}
+		})
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	service := NewCustomerStatusService(newFakeStatusRepository())
+
+	// Zonder key blijft de bestaande key gelden, ook voor de controle op overgangen naar zichzelf
+	updated, err := service.UpdateStatus(&model.StatusDefinition{ID: 3, Label: "Klant", Transitions: model.Keys{"churned", "lost"}})
+	if err != nil {
+		t.Fatalf("UpdateStatus gaf fout: %v", err)
+	}
+	if updated.Key != "active" || !reflect.DeepEqual(updated.Transitions, model.Keys{"churned", "lost"}) {
+		t.Errorf("status = %+v", updated)
+	}
+
+	if _, err := service.UpdateStatus(&model.StatusDefinition{ID: 3, Label: "Klant", Transitions: model.Keys{"active"}}); err == nil {
+		t.Error("overgang naar zichzelf toegestaan")
+	}
+	if _, err := service.UpdateStatus(&model.StatusDefinition{ID: 3, Key: "customer", Label: "Klant"}); err == nil || err.Error() != "de key van een status kan niet gewijzigd worden" {
+		t.Errorf("UpdateStatus met andere key gaf %v", err)
+	}
+	if _, err := service.UpdateStatus(&model.StatusDefinition{Label: "Klant"}); err == nil {
+		t.Error("UpdateStatus zonder ID gaf geen fout")
+	}
+}
+
+func TestDeleteStatus(t *testing.T) {
+	repo := newFakeStatusRepository()
+	service := NewCustomerStatusService(repo)
+
+	if _, err := service.DeleteStatus("3"); err == nil || err.Error() != "status kan niet verwijderd worden: 1 klant(en) hebben deze status" {
+		t.Errorf("DeleteStatus van een status met klanten gaf %v", err)
+	}
+	data, err := service.DeleteStatus("5")
+	if err != nil {
+		t.Fatalf("DeleteStatus gaf fout: %v", err)
+	}
+	if data["key"] != "lost" || !reflect.DeepEqual(repo.deleted, []string{"lost"}) {
+		t.Errorf("verwijderd: %v, audit data %v", repo.deleted, data)
+	}
+}
+
+func TestDefaultStatuses(t *testing.T) {
+	// De standaard workflow moet zelf geldig zijn: één initiële status en alleen overgangen naar bestaande statussen
+	statuses := model.DefaultStatuses()
+	known := make(map[string]bool)
+	initial := 0
+	for _, status := range statuses {
+		known[status.Key] = true
+		if status.Initial {
+			initial++
+		}
+	}
+	if initial != 1 {
+		t.Errorf("%d initiële statussen, verwacht 1", initial)
+	}
+	for _, status := range statuses {
+		for _, transition := range status.Transitions {
+			if !known[transition] || transition == status.Key {
+				t.Errorf("ongeldige overgang van '%s' naar '%s'", status.Key, transition)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"net/http"
+	"odomosml/internal/customfield/model"
+	"odomosml/internal/customfield/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomFieldHandler handles HTTP requests for custom field definitions
+type CustomFieldHandler struct {
+	service service.CustomFieldService
+}
+
+// NewCustomFieldHandler maakt een nieuwe CustomFieldHandler instantie
+func NewCustomFieldHandler(service service.CustomFieldService) *CustomFieldHandler {
+	return &CustomFieldHandler{
+		service: service,
+	}
+}
+
+// @Summary      Vrije velden ophalen
+// @Description  Haalt alle definities van vrije velden op klanten op
+// @Tags         custom-fields
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      500  {object}  map[string]string "Server error"
+// @Security     Bearer
+// @Router       /custom-fields [get]
+func (h *CustomFieldHandler) GetAll(c *gin.Context) {
+	definitions, err := h.service.GetAllDefinitions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    definitions,
+	})
+}
+
+// @Summary      Vrij veld aanmaken
+// @Description  Definieert een nieuw vrij veld op klanten (alleen admin)
+// @Tags         custom-fields
+// @Accept       json
+// @Produce      json
+// @Param        definition body model.CustomFieldDefinition true "Velddefinitie"
+// @Success      201  {object}  model.CustomFieldDefinition "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Onvoldoende rechten"
+// @Security     Bearer
+// @Router       /custom-fields [post]
+func (h *CustomFieldHandler) Create(c *gin.Context) {
+	var definition model.CustomFieldDefinition
+	if err := c.ShouldBindJSON(&definition); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	created, err := h.service.CreateDefinition(&definition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Vrij veld bijwerken
+// @Description  Werkt label, type, verplichting of enum waarden van een vrij veld bij (alleen admin)
+// @Tags         custom-fields
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Veld ID"
+// @Param        definition body model.CustomFieldDefinition true "Velddefinitie"
+// @Success      200  {object}  model.CustomFieldDefinition "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Onvoldoende rechten"
+// @Security     Bearer
+// @Router       /custom-fields/{id} [put]
+func (h *CustomFieldHandler) Update(c *gin.Context) {
+	idInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldig ID",
+		})
+		return
+	}
+
+	var definition model.CustomFieldDefinition
+	if err := c.ShouldBindJSON(&definition); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	definition.ID = uint(idInt)
+
+	updated, err := h.service.UpdateDefinition(&definition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Vrij veld verwijderen
+// @Description  Verwijdert een vrij veld inclusief de waarden bij alle klanten (alleen admin)
+// @Tags         custom-fields
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Veld ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      400  {object}  map[string]string "Ongeldig ID"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Onvoldoende rechten"
+// @Security     Bearer
+// @Router       /custom-fields/{id} [delete]
+func (h *CustomFieldHandler) Delete(c *gin.Context) {
+	definitionData, err := h.service.DeleteDefinition(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Sla definitionData op in context voor audit logging
+	c.Set("customFieldData", definitionData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Veld succesvol verwijderd",
+	})
+}
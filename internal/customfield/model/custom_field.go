@@ -0,0 +1,183 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldType definieert het type waarde van een vrij veld
+type FieldType string
+
+// Beschikbare veldtypes
+const (
+	FieldTypeText    FieldType = "text"
+	FieldTypeNumber  FieldType = "number"
+	FieldTypeDate    FieldType = "date"
+	FieldTypeEnum    FieldType = "enum"
+	FieldTypeBoolean FieldType = "boolean"
+)
+
+// DateLayout is het formaat waarin datumvelden worden opgeslagen
+const DateLayout = "2006-01-02"
+
+// maxTextLength is de maximale lengte van een tekstveld
+const maxTextLength = 1000
+
+// IsValid controleert of het veldtype bekend is
+func (t FieldType) IsValid() bool {
+	switch t {
+	case FieldTypeText, FieldTypeNumber, FieldTypeDate, FieldTypeEnum, FieldTypeBoolean:
+		return true
+	}
+	return false
+}
+
+// JSONType geeft het JSON type waarin waarden van dit veldtype worden opgeslagen: "number", "boolean"
+// of "string" (tekst, datums als JJJJ-MM-DD en keuzelijsten)
+func (t FieldType) JSONType() string {
+	switch t {
+	case FieldTypeNumber:
+		return "number"
+	case FieldTypeBoolean:
+		return "boolean"
+	}
+	return "string"
+}
+
+// StringList is een lijst strings die als JSONB wordt opgeslagen
+type StringList []string
+
+// Value implementeert driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("kan %T niet converteren naar StringList", value)
+	}
+
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// CustomFieldDefinition beschrijft een door een admin gedefinieerd vrij veld op klanten
+// @Description Definitie van een vrij veld op klanten
+type CustomFieldDefinition struct {
+	ID         uint       `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	Key        string     `json:"key" gorm:"size:50;not null;uniqueIndex" binding:"required" example:"branche" swaggertype:"string"`
+	Label      string     `json:"label" gorm:"size:100;not null" binding:"required" example:"Branche" swaggertype:"string"`
+	Type       FieldType  `json:"type" gorm:"size:20;not null" binding:"required" example:"enum" swaggertype:"string"`
+	Required   bool       `json:"required" gorm:"not null;default:false" example:"false" swaggertype:"boolean"`
+	EnumValues StringList `json:"enum_values" gorm:"type:jsonb;not null;default:'[]'" swaggertype:"array,string"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt  time.Time  `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (CustomFieldDefinition) TableName() string {
+	return "custom_field_definitions"
+}
+
+// Normalize valideert een waarde tegen de definitie en zet deze om naar de opslagvorm.
+// Strings worden geaccepteerd voor elk type zodat ook CSV-invoer gevalideerd kan worden.
+func (d *CustomFieldDefinition) Normalize(value interface{}) (interface{}, error) {
+	switch d.Type {
+	case FieldTypeText:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("moet tekst zijn")
+		}
+		if utf8.RuneCountInString(s) > maxTextLength {
+			return nil, fmt.Errorf("mag maximaal %d tekens bevatten", maxTextLength)
+		}
+		return s, nil
+
+	case FieldTypeNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case json.Number:
+			return v.Float64()
+		case string:
+			// ParseFloat accepteert ook NaN en Inf, die niet als JSON op te slaan zijn
+			f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", "."), 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, errors.New("moet een getal zijn")
+			}
+			return f, nil
+		}
+		return nil, errors.New("moet een getal zijn")
+
+	case FieldTypeDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("moet een datum zijn (JJJJ-MM-DD)")
+		}
+		s = strings.TrimSpace(s)
+		if t, err := time.Parse(DateLayout, s); err == nil {
+			return t.Format(DateLayout), nil
+		}
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t.Format(DateLayout), nil
+		}
+		return nil, errors.New("moet een datum zijn (JJJJ-MM-DD)")
+
+	case FieldTypeEnum:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("moet een van de toegestane waarden zijn")
+		}
+		for _, allowed := range d.EnumValues {
+			if s == allowed {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("moet een van de volgende waarden zijn: %s", strings.Join(d.EnumValues, ", "))
+
+	case FieldTypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "1", "ja":
+				return true, nil
+			case "false", "0", "nee":
+				return false, nil
+			}
+		}
+		return nil, errors.New("moet true of false zijn")
+	}
+
+	return nil, fmt.Errorf("onbekend veldtype '%s'", d.Type)
+}
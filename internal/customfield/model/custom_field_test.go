@@ -0,0 +1,86 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	text := &CustomFieldDefinition{Key: "notitie", Type: FieldTypeText}
+	number := &CustomFieldDefinition{Key: "omzet", Type: FieldTypeNumber}
+	date := &CustomFieldDefinition{Key: "klant_sinds", Type: FieldTypeDate}
+	enum := &CustomFieldDefinition{Key: "regio", Type: FieldTypeEnum, EnumValues: StringList{"noord", "zuid"}}
+	boolean := &CustomFieldDefinition{Key: "nieuwsbrief", Type: FieldTypeBoolean}
+
+	tests := []struct {
+		name       string
+		definition *CustomFieldDefinition
+		value      interface{}
+		want       interface{}
+		wantErr    string
+	}{
+		{"tekst", text, "Vaste klant", "Vaste klant", ""},
+		{"tekst van 1000 tekens met accenten", text, strings.Repeat("é", 1000), strings.Repeat("é", 1000), ""},
+		{"te lange tekst", text, strings.Repeat("a", 1001), nil, "mag maximaal 1000 tekens bevatten"},
+		{"getal als tekst", text, 12, nil, "moet tekst zijn"},
+
+		{"getal", number, 1250.5, 1250.5, ""},
+		{"geheel getal", number, 3, float64(3), ""},
+		{"JSON getal", number, json.Number("42"), float64(42), ""},
+		{"getal met komma", number, " 1250,5 ", 1250.5, ""},
+		{"geen getal", number, "veel", nil, "moet een getal zijn"},
+		{"NaN", number, "NaN", nil, "moet een getal zijn"},
+		{"oneindig", number, "Inf", nil, "moet een getal zijn"},
+		{"ja als getal", number, true, nil, "moet een getal zijn"},
+
+		{"datum", date, "2024-02-25", "2024-02-25", ""},
+		{"datum met tijd", date, "2024-02-25T20:30:00+01:00", "2024-02-25", ""},
+		{"ongeldige datum", date, "25-02-2024", nil, "moet een datum zijn (JJJJ-MM-DD)"},
+		{"31 februari", date, "2024-02-31", nil, "moet een datum zijn (JJJJ-MM-DD)"},
+
+		{"keuze", enum, "noord", "noord", ""},
+		{"andere hoofdletters", enum, "Noord", nil, "moet een van de volgende waarden zijn: noord, zuid"},
+		{"keuze als getal", enum, 1, nil, "moet een van de toegestane waarden zijn"},
+
+		{"ja/nee", boolean, true, true, ""},
+		{"ja", boolean, " Ja ", true, ""},
+		{"0", boolean, "0", false, ""},
+		{"misschien", boolean, "misschien", nil, "moet true of false zijn"},
+
+		{"onbekend type", &CustomFieldDefinition{Type: "kleur"}, "rood", nil, "onbekend veldtype 'kleur'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.definition.Normalize(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Normalize(%v) gaf %v, %v; verwacht %q", tt.value, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%v) gaf fout: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%v) = %#v, verwacht %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringList(t *testing.T) {
+	value, err := StringList(nil).Value()
+	if err != nil || value != "[]" {
+		t.Errorf("Value van een lege lijst = %v, %v", value, err)
+	}
+
+	var list StringList
+	if err := list.Scan([]byte(`["noord","zuid"]`)); err != nil || len(list) != 2 || list[1] != "zuid" {
+		t.Errorf("Scan = %v, %v", list, err)
+	}
+	if err := list.Scan(12); err == nil {
+		t.Error("Scan van een getal gaf geen fout")
+	}
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/customfield/model"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// CustomFieldRepository definieert de interface voor de vrije velden repository
+type CustomFieldRepository interface {
+	FindAll() ([]model.CustomFieldDefinition, error)
+	FindByID(id string) (*model.CustomFieldDefinition, error)
+	FindByKey(key string) (*model.CustomFieldDefinition, error)
+	CountValues(key string) (int64, error)
+	Create(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error)
+	Update(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error)
+	Delete(definition *model.CustomFieldDefinition) error
+}
+
+// customFieldRepository implementeert de CustomFieldRepository interface
+type customFieldRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomFieldRepository maakt een nieuwe CustomFieldRepository instantie
+func NewCustomFieldRepository(db *gorm.DB) CustomFieldRepository {
+	return &customFieldRepository{
+		db: db,
+	}
+}
+
+// FindAll haalt alle velddefinities op, gesorteerd op key
+func (r *customFieldRepository) FindAll() ([]model.CustomFieldDefinition, error) {
+	var definitions []model.CustomFieldDefinition
+	if err := r.db.Order("key ASC").Find(&definitions).Error; err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// FindByID haalt een velddefinitie op op basis van ID
+func (r *customFieldRepository) FindByID(id string) (*model.CustomFieldDefinition, error) {
+	var definition model.CustomFieldDefinition
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&definition, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("veld niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &definition, nil
+}
+
+// FindByKey haalt een velddefinitie op op basis van key
+func (r *customFieldRepository) FindByKey(key string) (*model.CustomFieldDefinition, error) {
+	var definition model.CustomFieldDefinition
+
+	if err := r.db.Where("key = ?", key).First(&definition).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("veld niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &definition, nil
+}
+
+// CountValues telt het aantal (niet verwijderde) klanten met een waarde voor het opgegeven veld
+func (r *customFieldRepository) CountValues(key string) (int64, error) {
+	var count int64
+	err := r.db.Table("customers").
+		Where("jsonb_exists(custom_fields, ?) AND deleted_at IS NULL", key).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Create maakt een nieuwe velddefinitie aan
+func (r *customFieldRepository) Create(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error) {
+	if err := r.db.Create(definition).Error; err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// Update werkt een bestaande velddefinitie bij (de key is onveranderlijk)
+func (r *customFieldRepository) Update(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error) {
+	err := r.db.Model(definition).
+		Select("label", "type", "required", "enum_values").
+		Updates(definition).Error
+	if err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// Delete verwijdert een velddefinitie en de bijbehorende waarden bij alle klanten
+func (r *customFieldRepository) Delete(definition *model.CustomFieldDefinition) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE customers SET custom_fields = custom_fields - ? WHERE jsonb_exists(custom_fields, ?)",
+			definition.Key, definition.Key).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(definition).Error
+	})
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"odomosml/internal/customfield/model"
+	"odomosml/internal/customfield/repository"
+	"odomosml/pkg/validation"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keyPattern valideert veld keys: kleine letters, cijfers en underscores
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// CustomFieldService definieert de interface voor de vrije velden service
+type CustomFieldService interface {
+	GetAllDefinitions() ([]model.CustomFieldDefinition, error)
+	GetDefinitionByID(id string) (*model.CustomFieldDefinition, error)
+	CreateDefinition(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error)
+	UpdateDefinition(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error)
+	DeleteDefinition(id string) (map[string]interface{}, error)
+	NormalizeValues(values map[string]interface{}) (map[string]interface{}, error)
+	GetDefinitionByKey(key string) (*model.CustomFieldDefinition, error)
+}
+
+// customFieldService implementeert de CustomFieldService interface
+type customFieldService struct {
+	repo repository.CustomFieldRepository
+}
+
+// NewCustomFieldService maakt een nieuwe CustomFieldService instantie
+func NewCustomFieldService(repo repository.CustomFieldRepository) CustomFieldService {
+	return &customFieldService{
+		repo: repo,
+	}
+}
+
+// GetAllDefinitions haalt alle velddefinities op
+func (s *customFieldService) GetAllDefinitions() ([]model.CustomFieldDefinition, error) {
+	return s.repo.FindAll()
+}
+
+// GetDefinitionByID haalt een velddefinitie op op basis van ID
+func (s *customFieldService) GetDefinitionByID(id string) (*model.CustomFieldDefinition, error) {
+	return s.repo.FindByID(id)
+}
+
+// CreateDefinition maakt een nieuwe velddefinitie aan
+func (s *customFieldService) CreateDefinition(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error) {
+	definition.Key = strings.TrimSpace(definition.Key)
+	if !keyPattern.MatchString(definition.Key) {
+		return nil, errors.New("key mag alleen kleine letters, cijfers en underscores bevatten en moet met een letter beginnen")
+	}
+
+	if err := s.validate(definition); err != nil {
+		return nil, err
+	}
+
+	if existing, _ := s.repo.FindByKey(definition.Key); existing != nil {
+		return nil, errors.New("veld met deze key bestaat al")
+	}
+
+	return s.repo.Create(definition)
+}
+
+// UpdateDefinition werkt label, type, verplichting en enum waarden van een veld bij
+func (s *customFieldService) UpdateDefinition(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error) {
+	if definition.ID == 0 {
+		return nil, errors.New("veld ID is verplicht")
+	}
+
+	existing, err := s.repo.FindByID(strconv.FormatUint(uint64(definition.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	// De key is onveranderlijk omdat bestaande waarden eraan gekoppeld zijn
+	if definition.Key != "" && definition.Key != existing.Key {
+		return nil, errors.New("de key van een veld kan niet gewijzigd worden")
+	}
+	definition.Key = existing.Key
+
+	if err := s.validate(definition); err != nil {
+		return nil, err
+	}
+
+	// Een typewijziging is alleen toegestaan zolang er nog geen waarden zijn
+	if definition.Type != existing.Type {
+		count, err := s.repo.CountValues(existing.Key)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("type kan niet gewijzigd worden: %d klant(en) hebben al een waarde voor dit veld", count)
+		}
+	}
+
+	definition.CreatedAt = existing.CreatedAt
+	return s.repo.Update(definition)
+}
+
+// DeleteDefinition verwijdert een velddefinitie en retourneert de data voor audit logging
+func (s *customFieldService) DeleteDefinition(id string) (map[string]interface{}, error) {
+	definition, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	definitionData := map[string]interface{}{
+		"id":    definition.ID,
+		"key":   definition.Key,
+		"label": definition.Label,
+		"type":  definition.Type,
+	}
+
+	if err := s.repo.Delete(definition); err != nil {
+		return nil, err
+	}
+
+	return definitionData, nil
+}
+
+// NormalizeValues valideert vrije veldwaarden tegen de definities en retourneert de genormaliseerde waarden.
+// Onbekende velden worden geweigerd, verplichte velden moeten een waarde hebben.
+func (s *customFieldService) NormalizeValues(values map[string]interface{}) (map[string]interface{}, error) {
+	definitions, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*model.CustomFieldDefinition, len(definitions))
+	for i := range definitions {
+		byKey[definitions[i].Key] = &definitions[i]
+	}
+
+	var problems validation.Errors
+	normalized := make(map[string]interface{}, len(values))
+
+	for key, value := range values {
+		definition, ok := byKey[key]
+		if !ok {
+			problems = append(problems, fieldProblem(key, "vrij veld '%s' bestaat niet", key))
+			continue
+		}
+
+		// Lege waarden gelden als "niet ingevuld"
+		if value == nil || value == "" {
+			continue
+		}
+
+		normalizedValue, err := definition.Normalize(value)
+		if err != nil {
+			problems = append(problems, fieldProblem(key, "vrij veld '%s' %s", key, err.Error()))
+			continue
+		}
+		normalized[key] = normalizedValue
+	}
+
+	for _, definition := range definitions {
+		if _, ok := normalized[definition.Key]; definition.Required && !ok {
+			problems = append(problems, fieldProblem(definition.Key, "vrij veld '%s' is verplicht", definition.Key))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Slice(problems, func(i, j int) bool { return problems[i].Message < problems[j].Message })
+		return nil, problems
+	}
+
+	return normalized, nil
+}
+
+// fieldProblem is de validatiefout van een vrij veld, met als veldnaam custom_fields.<key>
+func fieldProblem(key, format string, args ...interface{}) validation.FieldError {
+	return validation.FieldError{Field: "custom_fields." + key, Message: fmt.Sprintf(format, args...)}
+}
+
+// GetDefinitionByKey haalt een velddefinitie op op basis van key
+func (s *customFieldService) GetDefinitionByKey(key string) (*model.CustomFieldDefinition, error) {
+	return s.repo.FindByKey(key)
+}
+
+// validate controleert label, type en enum waarden van een definitie
+func (s *customFieldService) validate(definition *model.CustomFieldDefinition) error {
+	definition.Label = strings.TrimSpace(definition.Label)
+	if definition.Label == "" {
+		return errors.New("label is verplicht")
+	}
+
+	if !definition.Type.IsValid() {
+		return errors.New("type moet text, number, date, enum of boolean zijn")
+	}
+
+	if definition.Type != model.FieldTypeEnum {
+		definition.EnumValues = model.StringList{}
+		return nil
+	}
+
+	if len(definition.EnumValues) == 0 {
+		return errors.New("een enum veld heeft minstens één waarde nodig")
+	}
+
+	seen := make(map[string]bool, len(definition.EnumValues))
+	for _, value := range definition.EnumValues {
+		if strings.TrimSpace(value) == "" {
+			return errors.New("enum waarden mogen niet leeg zijn")
+		}
+		if seen[value] {
+			return fmt.Errorf("enum waarde '%s' komt dubbel voor", value)
+		}
+		seen[value] = true
+	}
+
+	return nil
+}
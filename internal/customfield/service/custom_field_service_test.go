@@ -0,0 +1,191 @@
+package service
+
+import (
+	"errors"
+	"odomosml/internal/customfield/model"
+	"odomosml/internal/customfield/repository"
+	"odomosml/pkg/validation"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type fakeCustomFieldRepository struct {
+	repository.CustomFieldRepository
+	definitions []model.CustomFieldDefinition
+	values      map[string]int64
+	calls       []string
+}
+
+func (r *fakeCustomFieldRepository) FindAll() ([]model.CustomFieldDefinition, error) {
+	return append([]model.CustomFieldDefinition(nil), r.definitions...), nil
+}
+
+func (r *fakeCustomFieldRepository) FindByID(id string) (*model.CustomFieldDefinition, error) {
+	for _, definition := range r.definitions {
+		if strconv.FormatUint(uint64(definition.ID), 10) == id {
+			return &definition, nil
+		}
+	}
+	return nil, errors.New("veld niet gevonden")
+}
+
+func (r *fakeCustomFieldRepository) FindByKey(key string) (*model.CustomFieldDefinition, error) {
+	for _, definition := range r.definitions {
+		if definition.Key == key {
+			return &definition, nil
+		}
+	}
+	return nil, errors.New("veld niet gevonden")
+}
+
+func (r *fakeCustomFieldRepository) CountValues(key string) (int64, error) {
+	return r.values[key], nil
+}
+
+func (r *fakeCustomFieldRepository) Create(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error) {
+	r.calls = append(r.calls, "Create")
+	return definition, nil
+}
+
+func (r *fakeCustomFieldRepository) Update(definition *model.CustomFieldDefinition) (*model.CustomFieldDefinition, error) {
+	r.calls = append(r.calls, "Update")
+	return definition, nil
+}
+
+func newTestRepository() *fakeCustomFieldRepository {
+	return &fakeCustomFieldRepository{
+		definitions: []model.CustomFieldDefinition{
+			{ID: 1, Key: "branche", Label: "Branche", Type: model.FieldTypeEnum, Required: true, EnumValues: model.StringList{"bakkerij", "horeca"}},
+			{ID: 2, Key: "omzet", Label: "Omzet", Type: model.FieldTypeNumber},
+			{ID: 3, Key: "notitie", Label: "Notitie", Type: model.FieldTypeText},
+		},
+		values: map[string]int64{"omzet": 4},
+	}
+}
+
+func TestCreateDefinition(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition model.CustomFieldDefinition
+		want       model.CustomFieldDefinition
+		wantErr    string
+	}{
+		{
+			name:       "tekstveld zonder keuzes",
+			definition: model.CustomFieldDefinition{Key: " klant_sinds ", Label: " Klant sinds ", Type: model.FieldTypeDate, EnumValues: model.StringList{"a"}},
+			want:       model.CustomFieldDefinition{Key: "klant_sinds", Label: "Klant sinds", Type: model.FieldTypeDate, EnumValues: model.StringList{}},
+		},
+		{
+			name:       "keuzelijst",
+			definition: model.CustomFieldDefinition{Key: "regio", Label: "Regio", Type: model.FieldTypeEnum, EnumValues: model.StringList{"noord", "zuid"}},
+			want:       model.CustomFieldDefinition{Key: "regio", Label: "Regio", Type: model.FieldTypeEnum, EnumValues: model.StringList{"noord", "zuid"}},
+		},
+		{name: "hoofdletters in de key", definition: model.CustomFieldDefinition{Key: "Regio", Label: "Regio", Type: model.FieldTypeText},
+			wantErr: "key mag alleen kleine letters, cijfers en underscores bevatten en moet met een letter beginnen"},
+		{name: "key met een cijfer vooraan", definition: model.CustomFieldDefinition{Key: "1regio", Label: "Regio", Type: model.FieldTypeText},
+			wantErr: "key mag alleen kleine letters, cijfers en underscores bevatten en moet met een letter beginnen"},
+		{name: "bestaande key", definition: model.CustomFieldDefinition{Key: "omzet", Label: "Omzet", Type: model.FieldTypeNumber},
+			wantErr: "veld met deze key bestaat al"},
+		{name: "zonder label", definition: model.CustomFieldDefinition{Key: "regio", Label: " ", Type: model.FieldTypeText},
+			wantErr: "label is verplicht"},
+		{name: "onbekend type", definition: model.CustomFieldDefinition{Key: "regio", Label: "Regio", Type: "kleur"},
+			wantErr: "type moet text, number, date, enum of boolean zijn"},
+		{name: "keuzelijst zonder keuzes", definition: model.CustomFieldDefinition{Key: "regio", Label: "Regio", Type: model.FieldTypeEnum},
+			wantErr: "een enum veld heeft minstens één waarde nodig"},
+		{name: "lege keuze", definition: model.CustomFieldDefinition{Key: "regio", Label: "Regio", Type: model.FieldTypeEnum, EnumValues: model.StringList{"noord", " "}},
+			wantErr: "enum waarden mogen niet leeg zijn"},
+		{name: "dubbele keuze", definition: model.CustomFieldDefinition{Key: "regio", Label: "Regio", Type: model.FieldTypeEnum, EnumValues: model.StringList{"noord", "noord"}},
+			wantErr: "enum waarde 'noord' komt dubbel voor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository()
+			definition := tt.definition
+			created, err := NewCustomFieldService(repo).CreateDefinition(&definition)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("CreateDefinition gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				if len(repo.calls) != 0 {
+					t.Errorf("repository aangeroepen: %v", repo.calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateDefinition gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(*created, tt.want) {
+				t.Errorf("CreateDefinition = %+v, verwacht %+v", *created, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateDefinition(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition model.CustomFieldDefinition
+		wantErr    string
+	}{
+		{name: "label wijzigen", definition: model.CustomFieldDefinition{ID: 2, Label: "Jaaromzet", Type: model.FieldTypeNumber}},
+		{name: "type wijzigen zonder waarden", definition: model.CustomFieldDefinition{ID: 3, Key: "notitie", Label: "Notitie", Type: model.FieldTypeDate}},
+		{name: "type wijzigen met waarden", definition: model.CustomFieldDefinition{ID: 2, Label: "Omzet", Type: model.FieldTypeText},
+			wantErr: "type kan niet gewijzigd worden: 4 klant(en) hebben al een waarde voor dit veld"},
+		{name: "key wijzigen", definition: model.CustomFieldDefinition{ID: 2, Key: "jaaromzet", Label: "Omzet", Type: model.FieldTypeNumber},
+			wantErr: "de key van een veld kan niet gewijzigd worden"},
+		{name: "onbekend veld", definition: model.CustomFieldDefinition{ID: 9, Label: "Omzet", Type: model.FieldTypeNumber}, wantErr: "veld niet gevonden"},
+		{name: "zonder ID", definition: model.CustomFieldDefinition{Label: "Omzet", Type: model.FieldTypeNumber}, wantErr: "veld ID is verplicht"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository()
+			definition := tt.definition
+			updated, err := NewCustomFieldService(repo).UpdateDefinition(&definition)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("UpdateDefinition gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				if len(repo.calls) != 0 {
+					t.Errorf("repository aangeroepen: %v", repo.calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateDefinition gaf fout: %v", err)
+			}
+			if existing, _ := repo.FindByID(strconv.FormatUint(uint64(tt.definition.ID), 10)); updated.Key != existing.Key {
+				t.Errorf("key = %q, verwacht %q", updated.Key, existing.Key)
+			}
+		})
+	}
+}
+
+func TestNormalizeValues(t *testing.T) {
+	service := NewCustomFieldService(newTestRepository())
+
+	got, err := service.NormalizeValues(map[string]interface{}{"branche": "horeca", "omzet": "1250,5", "notitie": ""})
+	if err != nil {
+		t.Fatalf("NormalizeValues gaf fout: %v", err)
+	}
+	// Een lege waarde geldt als niet ingevuld en wordt weggelaten
+	if want := map[string]interface{}{"branche": "horeca", "omzet": 1250.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeValues = %v, verwacht %v", got, want)
+	}
+
+	_, err = service.NormalizeValues(map[string]interface{}{"branche": nil, "omzet": "veel", "website": "jansen.nl"})
+	fields, ok := validation.Fields(err)
+	if !ok {
+		t.Fatalf("NormalizeValues gaf %v, verwacht validatiefouten", err)
+	}
+	want := []validation.FieldError{
+		{Field: "custom_fields.branche", Message: "vrij veld 'branche' is verplicht"},
+		{Field: "custom_fields.omzet", Message: "vrij veld 'omzet' moet een getal zijn"},
+		{Field: "custom_fields.website", Message: "vrij veld 'website' bestaat niet"},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("validatiefouten = %+v, verwacht %+v", fields, want)
+	}
+}
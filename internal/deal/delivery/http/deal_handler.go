@@ -0,0 +1,366 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"odomosml/internal/deal/model"
+	"odomosml/internal/deal/service"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
+	"odomosml/pkg/validation"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DealHandler handles HTTP requests for deals
+type DealHandler struct {
+	service service.DealService
+}
+
+// NewDealHandler maakt een nieuwe DealHandler instantie
+func NewDealHandler(service service.DealService) *DealHandler {
+	return &DealHandler{
+		service: service,
+	}
+}
+
+// parseFilter leest de filter-, sorteer- en pagineringsparameters van de deals lijst
+func parseFilter(query url.Values) (model.DealFilter, error) {
+	page, err := pagination.FromQuery(query, "page", "page_size", 10, 100)
+	if err != nil {
+		return model.DealFilter{}, err
+	}
+
+	sort, err := sorting.Parse(query.Get("sort"), model.SortFields...)
+	if err != nil {
+		return model.DealFilter{}, err
+	}
+
+	expression, err := filterExpr.Parse(query.Get("filter"))
+	if err != nil {
+		return model.DealFilter{}, err
+	}
+
+	customerID, err := parseIDParam(query, "customer_id")
+	if err != nil {
+		return model.DealFilter{}, err
+	}
+	ownerID, err := parseIDParam(query, "owner_id")
+	if err != nil {
+		return model.DealFilter{}, err
+	}
+
+	var stages []model.Stage
+	for _, stage := range strings.Split(query.Get("stage"), ",") {
+		if stage = strings.TrimSpace(stage); stage != "" {
+			stages = append(stages, model.Stage(stage))
+		}
+	}
+
+	return model.DealFilter{
+		SearchTerm: query.Get("zoekterm"),
+		CustomerID: customerID,
+		OwnerID:    ownerID,
+		Stages:     stages,
+		Currency:   strings.TrimSpace(query.Get("currency")),
+		Page:       page.Page,
+		PageSize:   page.Size,
+		After:      page.After,
+		Before:     page.Before,
+		Count:      page.Count,
+		Sort:       sort,
+		Expression: expression,
+	}, nil
+}
+
+// parseIDParam parst een optionele ID parameter; een lege parameter geeft 0
+func parseIDParam(query url.Values, param string) (uint, error) {
+	value := query.Get(param)
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%s moet een ID zijn", param)
+	}
+	return uint(id), nil
+}
+
+// respondFilterError stuurt een fout bij het ophalen van deals terug. Een fout in de filterexpressie
+// of een cursor van een andere sortering is een 400, met bij een filterfout de positie van het foute token.
+func respondFilterError(c *gin.Context, status int, err error) {
+	body := gin.H{
+		"success": false,
+		"error":   err.Error(),
+	}
+
+	var expressionErr *filterExpr.Error
+	if errors.As(err, &expressionErr) {
+		status = http.StatusBadRequest
+		body["position"] = expressionErr.Pos + 1
+	} else if errors.Is(err, pagination.ErrSortMismatch) {
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, body)
+}
+
+// respondWriteError stuurt een fout bij het aanmaken of wijzigen van een deal terug als 400,
+// met validatiefouten per veld in fields
+func respondWriteError(c *gin.Context, err error) {
+	response := gin.H{
+		"success": false,
+		"error":   err.Error(),
+	}
+	if fields, ok := validation.Fields(err); ok {
+		response["fields"] = fields
+	}
+	c.JSON(http.StatusBadRequest, response)
+}
+
+// @Summary      Lijst van deals ophalen
+// @Description  Haalt deals op met optionele filters, gesorteerd op verwachte sluitingsdatum
+// @Tags         deals
+// @Accept       json
+// @Produce      json
+// @Param        page query int false "Paginanummer (default: 1)"
+// @Param        page_size query int false "Aantal items per pagina (default: 10, max: 100)"
+// @Param        after query string false "Cursor: de pagina na deze cursor (next_cursor)"
+// @Param        before query string false "Cursor: de pagina voor deze cursor (prev_cursor)"
+// @Param        count query string false "Totaal aantal bepalen: exact, estimate of none (default: exact)"
+// @Param        zoekterm query string false "Zoekterm voor de titel"
+// @Param        customer_id query int false "Alleen deals van deze klant"
+// @Param        owner_id query int false "Alleen deals van deze eigenaar"
+// @Param        stage query string false "Komma-gescheiden fasen: qualification, proposal, negotiation, won, lost"
+// @Param        currency query string false "Alleen deals in deze valuta, bijv. EUR"
+// @Param        filter query string false "Filterexpressie, bijv. amount_cents >= 100000 and expected_close_date < 2025-01-01"
+// @Param        sort query string false "Komma-gescheiden sorteervelden, - voor aflopend: id, title, amount_cents, currency, expected_close_date, probability, stage, owner_id, customer_id, closed_at, created_at, updated_at (default: expected_close_date)"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald, met Link header naar andere pagina's"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      500  {object}  map[string]string "Server error"
+// @Security     Bearer
+// @Router       /deals [get]
+func (h *DealHandler) GetAll(c *gin.Context) {
+	filter, err := parseFilter(c.Request.URL.Query())
+	if err != nil {
+		respondFilterError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	deals, info, err := h.service.GetDeals(filter)
+	if err != nil {
+		respondFilterError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	request := pagination.Request{Page: filter.Page, Size: filter.PageSize, After: filter.After, Before: filter.Before}
+	if link := pagination.Links(c.Request.URL, request, info, "page"); link != "" {
+		c.Header("Link", link)
+	}
+
+	meta := gin.H{
+		"page_size":   filter.PageSize,
+		"total_items": info.Total,
+		"has_more":    info.HasMore,
+		"next_cursor": info.Next,
+	}
+	if request.Keyset() {
+		meta["prev_cursor"] = info.Prev
+	} else {
+		meta["current_page"] = filter.Page
+	}
+	if info.Total != nil {
+		meta["total_pages"] = pagination.TotalPages(*info.Total, filter.PageSize)
+		meta["total_estimated"] = info.Estimated
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       deals,
+		"pagination": meta,
+	})
+}
+
+// @Summary      Pipeline totalen
+// @Description  Geeft per fase en valuta het aantal deals, het totaalbedrag en het bedrag gewogen naar de kans van slagen, plus de totalen van de open deals per valuta. Accepteert dezelfde filters als de deals lijst.
+// @Tags         deals
+// @Accept       json
+// @Produce      json
+// @Param        customer_id query int false "Alleen deals van deze klant"
+// @Param        owner_id query int false "Alleen deals van deze eigenaar"
+// @Param        stage query string false "Komma-gescheiden fasen"
+// @Param        currency query string false "Alleen deals in deze valuta"
+// @Param        filter query string false "Filterexpressie, bijv. expected_close_date < 2025-07-01"
+// @Success      200  {object}  model.Pipeline "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /deals/pipeline [get]
+func (h *DealHandler) Pipeline(c *gin.Context) {
+	filter, err := parseFilter(c.Request.URL.Query())
+	if err != nil {
+		respondFilterError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	pipeline, err := h.service.GetPipeline(filter)
+	if err != nil {
+		respondFilterError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    pipeline,
+	})
+}
+
+// @Summary      Deal ophalen op ID
+// @Description  Haalt een specifieke deal op basis van ID
+// @Tags         deals
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Deal ID"
+// @Success      200  {object}  model.Deal "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Deal niet gevonden"
+// @Security     Bearer
+// @Router       /deals/{id} [get]
+func (h *DealHandler) GetByID(c *gin.Context) {
+	deal, err := h.service.GetDeal(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    deal,
+	})
+}
+
+// @Summary      Deal aanmaken
+// @Description  Legt een verkoopkans vast bij een klant. Zonder eigenaar is de ingelogde gebruiker eigenaar, zonder fase begint de deal in qualification en zonder valuta is het bedrag in EUR.
+// @Tags         deals
+// @Accept       json
+// @Produce      json
+// @Param        deal body model.Deal true "Deal gegevens"
+// @Success      201  {object}  model.Deal "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /deals [post]
+func (h *DealHandler) Create(c *gin.Context) {
+	var deal model.Deal
+	if err := c.ShouldBindJSON(&deal); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	if deal.OwnerID == 0 {
+		userID, _ := c.Get("userID")
+		deal.OwnerID, _ = userID.(uint)
+	}
+
+	created, err := h.service.CreateDeal(&deal)
+	if err != nil {
+		respondWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Deal bijwerken
+// @Description  Werkt een deal bij. Bij won wordt de kans 100%, bij lost 0%, en wordt het sluitmoment vastgelegd.
+// @Tags         deals
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Deal ID"
+// @Param        deal body model.Deal true "Deal gegevens"
+// @Success      200  {object}  model.Deal "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Deal niet gevonden"
+// @Security     Bearer
+// @Router       /deals/{id} [put]
+func (h *DealHandler) Update(c *gin.Context) {
+	existing, err := h.service.GetDeal(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var deal model.Deal
+	if err := c.ShouldBindJSON(&deal); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	// Bewaar de huidige gegevens voor de audit diff
+	c.Set("dealOldData", existing.ToAuditMap())
+
+	deal.ID = existing.ID
+	updated, err := h.service.UpdateDeal(&deal)
+	if err != nil {
+		respondWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Deal verwijderen
+// @Description  Verwijdert een deal
+// @Tags         deals
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Deal ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Deal niet gevonden"
+// @Security     Bearer
+// @Router       /deals/{id} [delete]
+func (h *DealHandler) Delete(c *gin.Context) {
+	dealData, err := h.service.DeleteDeal(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Sla dealData op in context voor audit logging
+	c.Set("dealData", dealData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Deal succesvol verwijderd",
+	})
+}
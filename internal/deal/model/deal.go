@@ -0,0 +1,159 @@
+package model
+
+import (
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
+	"time"
+)
+
+// Stage is de fase van een deal in de verkooppipeline
+type Stage string
+
+// Fasen van de verkooppipeline, in volgorde; won en lost zijn afgesloten deals
+const (
+	StageQualification Stage = "qualification"
+	StageProposal      Stage = "proposal"
+	StageNegotiation   Stage = "negotiation"
+	StageWon           Stage = "won"
+	StageLost          Stage = "lost"
+)
+
+// Stages zijn alle fasen in de volgorde van de pipeline
+var Stages = []Stage{StageQualification, StageProposal, StageNegotiation, StageWon, StageLost}
+
+// IsValid controleert of de fase bekend is
+func (s Stage) IsValid() bool {
+	for _, stage := range Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClosed geeft aan of een deal in deze fase is afgesloten (gewonnen of verloren)
+func (s Stage) IsClosed() bool {
+	return s == StageWon || s == StageLost
+}
+
+// DefaultCurrency is de valuta van een deal zonder opgegeven valuta
+const DefaultCurrency = "EUR"
+
+// Deal is een verkoopkans bij een klant
+// @Description Een verkoopkans (deal) bij een klant
+type Deal struct {
+	ID                uint       `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	CustomerID        uint       `json:"customer_id" gorm:"not null;index" binding:"required" example:"1" swaggertype:"integer"`
+	Title             string     `json:"title" gorm:"size:200;not null" binding:"required" example:"Onderhoudscontract 2025" swaggertype:"string"`
+	AmountCents       int64      `json:"amount_cents" gorm:"not null;default:0" example:"1250000" swaggertype:"integer"`   // Bedrag in centen, zodat er geen afrondingsverschillen ontstaan
+	Currency          string     `json:"currency" gorm:"size:3;not null;default:'EUR'" example:"EUR" swaggertype:"string"` // ISO 4217 valutacode
+	ExpectedCloseDate *time.Time `json:"expected_close_date" gorm:"type:date;index" example:"2025-03-31T00:00:00Z" swaggertype:"string" format:"date-time"`
+	Probability       int        `json:"probability" gorm:"not null;default:0" example:"40" swaggertype:"integer"` // Kans van slagen in procenten (0-100)
+	Stage             Stage      `json:"stage" gorm:"size:20;not null;index" example:"proposal" swaggertype:"string"`
+	OwnerID           uint       `json:"owner_id" gorm:"not null;index" example:"1" swaggertype:"integer"` // Verkoper die de deal beheert
+	OwnerName         string     `json:"owner_name" gorm:"size:100" example:"johndoe" swaggertype:"string"`
+	ClosedAt          *time.Time `json:"closed_at" example:"2025-03-28T14:00:00Z" swaggertype:"string" format:"date-time"` // Moment waarop de deal gewonnen of verloren is
+	CreatedAt         time.Time  `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt         time.Time  `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Deal) TableName() string {
+	return "deals"
+}
+
+// ToAuditMap converteert een deal naar een map voor audit logging
+func (d *Deal) ToAuditMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                  d.ID,
+		"customer_id":         d.CustomerID,
+		"title":               d.Title,
+		"amount_cents":        d.AmountCents,
+		"currency":            d.Currency,
+		"expected_close_date": d.ExpectedCloseDate,
+		"probability":         d.Probability,
+		"stage":               d.Stage,
+		"owner_id":            d.OwnerID,
+	}
+}
+
+// SortFields zijn de velden waarop deals gesorteerd en gefilterd kunnen worden
+var SortFields = []string{"id", "title", "amount_cents", "currency", "expected_close_date", "probability", "stage", "owner_id", "customer_id", "closed_at", "created_at", "updated_at"}
+
+// SortValue geeft de waarde van een sorteerveld (zie SortFields), voor het maken van een cursor
+func (d *Deal) SortValue(field string) interface{} {
+	switch field {
+	case "id":
+		return d.ID
+	case "title":
+		return d.Title
+	case "amount_cents":
+		return d.AmountCents
+	case "currency":
+		return d.Currency
+	case "expected_close_date":
+		if d.ExpectedCloseDate == nil {
+			return nil
+		}
+		return *d.ExpectedCloseDate
+	case "probability":
+		return d.Probability
+	case "stage":
+		return string(d.Stage)
+	case "owner_id":
+		return d.OwnerID
+	case "customer_id":
+		return d.CustomerID
+	case "closed_at":
+		if d.ClosedAt == nil {
+			return nil
+		}
+		return *d.ClosedAt
+	case "created_at":
+		return d.CreatedAt
+	case "updated_at":
+		return d.UpdatedAt
+	}
+	return nil
+}
+
+// DealFilter definieert filters voor het ophalen van deals
+type DealFilter struct {
+	SearchTerm string  // Deel van de titel
+	CustomerID uint    // Alleen deals van deze klant
+	OwnerID    uint    // Alleen deals van deze verkoper
+	Stages     []Stage // Alleen deals in een van deze fasen
+	Currency   string  // Alleen deals in deze valuta
+	Page       int
+	PageSize   int
+	After      *pagination.Cursor // Keyset paginering: de rijen na deze cursor
+	Before     *pagination.Cursor // Keyset paginering: de rijen voor deze cursor
+	Count      string             // pagination.CountExact, CountEstimate of CountNone
+	Sort       []sorting.Field    // Sorteervelden; leeg sorteert op verwachte sluitingsdatum
+	Expression filterExpr.Node    // Filterexpressie uit de filter parameter, bijv. amount_cents >= 100000
+}
+
+// PipelineStage is het totaal van de deals in één fase en valuta
+type PipelineStage struct {
+	Stage               Stage  `json:"stage"`
+	Currency            string `json:"currency"`
+	Count               int64  `json:"count"`
+	AmountCents         int64  `json:"amount_cents"`
+	WeightedAmountCents int64  `json:"weighted_amount_cents"` // Som van bedrag × kans van slagen
+}
+
+// PipelineTotal is het totaal van de open deals (niet gewonnen of verloren) in één valuta
+type PipelineTotal struct {
+	Currency            string `json:"currency"`
+	Count               int64  `json:"count"`
+	AmountCents         int64  `json:"amount_cents"`
+	WeightedAmountCents int64  `json:"weighted_amount_cents"`
+}
+
+// Pipeline bevat de totalen per fase en de totalen van de open pipeline per valuta.
+// Bedragen in verschillende valuta worden nooit bij elkaar opgeteld.
+type Pipeline struct {
+	Stages []PipelineStage `json:"stages"`
+	Open   []PipelineTotal `json:"open"`
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/deal/model"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// DealRepository definieert de interface voor deal repository
+type DealRepository interface {
+	FindAll(filter model.DealFilter) ([]model.Deal, pagination.Info, error)
+	FindByID(id string) (*model.Deal, error)
+	Create(deal *model.Deal) (*model.Deal, error)
+	Update(deal *model.Deal) (*model.Deal, error)
+	Delete(id uint) error
+	SumByStage(filter model.DealFilter) ([]model.PipelineStage, error)
+}
+
+// dealRepository implementeert de DealRepository interface
+type dealRepository struct {
+	db *gorm.DB
+}
+
+// NewDealRepository maakt een nieuwe DealRepository instantie
+func NewDealRepository(db *gorm.DB) DealRepository {
+	return &dealRepository{
+		db: db,
+	}
+}
+
+// FindAll haalt deals op met filters, op paginanummer of vanaf een cursor
+func (r *dealRepository) FindAll(filter model.DealFilter) ([]model.Deal, pagination.Info, error) {
+	var deals []model.Deal
+
+	query, err := applyFilter(r.db.Model(&model.Deal{}), filter)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+
+	// Tel (of schat) het totaal aantal records
+	total, estimated, err := pagination.Count(query, filter.Count)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+
+	// Paginering en sortering toepassen, met het ID als tiebreaker voor een stabiele paginering
+	sort := filter.Sort
+	if len(sort) == 0 {
+		sort = []sorting.Field{{Name: "expected_close_date"}}
+	}
+	request := pagination.Request{Page: filter.Page, Size: filter.PageSize, After: filter.After, Before: filter.Before}
+	column := sorting.Columns(dealColumns)
+	query, err = pagination.Apply(query, request, sort, column, "id")
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+
+	if err := query.Find(&deals).Error; err != nil {
+		return nil, pagination.Info{}, err
+	}
+
+	deals, info := pagination.Window(deals, request, func(deal *model.Deal) pagination.Cursor {
+		return pagination.NewCursor(sort, column, deal.ID, deal.SortValue)
+	})
+	info.Total, info.Estimated = total, estimated
+
+	return deals, info, nil
+}
+
+// FindByID haalt een deal op op basis van ID
+func (r *dealRepository) FindByID(id string) (*model.Deal, error) {
+	var deal model.Deal
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&deal, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("deal niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &deal, nil
+}
+
+// Create maakt een nieuwe deal aan
+func (r *dealRepository) Create(deal *model.Deal) (*model.Deal, error) {
+	if err := r.db.Create(deal).Error; err != nil {
+		return nil, err
+	}
+	return deal, nil
+}
+
+// Update werkt alle wijzigbare velden van een deal bij
+func (r *dealRepository) Update(deal *model.Deal) (*model.Deal, error) {
+	err := r.db.Model(deal).
+		Select("customer_id", "title", "amount_cents", "currency", "expected_close_date", "probability",
+			"stage", "owner_id", "owner_name", "closed_at", "updated_at").
+		Updates(deal).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(strconv.FormatUint(uint64(deal.ID), 10))
+}
+
+// Delete verwijdert een deal
+func (r *dealRepository) Delete(id uint) error {
+	return r.db.Delete(&model.Deal{}, id).Error
+}
+
+// SumByStage telt per fase en valuta het aantal deals, het totaalbedrag en het bedrag gewogen naar de
+// kans van slagen. Het gewogen bedrag wordt per groep op hele centen afgerond.
+func (r *dealRepository) SumByStage(filter model.DealFilter) ([]model.PipelineStage, error) {
+	query, err := applyFilter(r.db.Model(&model.Deal{}), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var stages []model.PipelineStage
+	err = query.Select("stage, currency, COUNT(*) AS count, COALESCE(SUM(amount_cents), 0)::bigint AS amount_cents, " +
+		"COALESCE(ROUND(SUM(amount_cents * probability) / 100.0), 0)::bigint AS weighted_amount_cents").
+		Group("stage, currency").Order("currency, stage").
+		Scan(&stages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stages, nil
+}
+
+// applyFilter past de zoekterm, vaste filters en filterexpressie toe op een deals query
+func applyFilter(query *gorm.DB, filter model.DealFilter) (*gorm.DB, error) {
+	if filter.SearchTerm != "" {
+		query = query.Where("title ILIKE ?", "%"+filter.SearchTerm+"%")
+	}
+
+	if filter.CustomerID != 0 {
+		query = query.Where("customer_id = ?", filter.CustomerID)
+	}
+
+	if filter.OwnerID != 0 {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+
+	if len(filter.Stages) > 0 {
+		query = query.Where("stage IN ?", filter.Stages)
+	}
+
+	if filter.Currency != "" {
+		query = query.Where("currency = ?", filter.Currency)
+	}
+
+	if filter.Expression != nil {
+		condition, vars, err := filterExpr.Compile(filter.Expression, dealFilterColumn)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(condition, vars...)
+	}
+
+	return query, nil
+}
+
+// dealColumns koppelt de velden waarop gesorteerd (zie model.SortFields) en gefilterd kan worden aan hun kolom
+var dealColumns = map[string]sorting.Column{
+	"id":                  {SQL: "id", Type: "bigint"},
+	"title":               {SQL: "title", Type: "text"},
+	"amount_cents":        {SQL: "amount_cents", Type: "bigint"},
+	"currency":            {SQL: "currency", Type: "text"},
+	"expected_close_date": {SQL: "expected_close_date", Type: "timestamptz", Nullable: true},
+	"probability":         {SQL: "probability", Type: "integer"},
+	"stage":               {SQL: "stage", Type: "text"},
+	"owner_id":            {SQL: "owner_id", Type: "bigint"},
+	"customer_id":         {SQL: "customer_id", Type: "bigint"},
+	"closed_at":           {SQL: "closed_at", Type: "timestamptz", Nullable: true},
+	"created_at":          {SQL: "created_at", Type: "timestamptz"},
+	"updated_at":          {SQL: "updated_at", Type: "timestamptz"},
+}
+
+// dealFilterColumn geeft de kolom van een veld in een filterexpressie
+func dealFilterColumn(name string) (sorting.Column, bool) {
+	column, ok := dealColumns[name]
+	return column, ok
+}
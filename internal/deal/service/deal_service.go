@@ -0,0 +1,238 @@
+package service
+
+import (
+	"fmt"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/internal/deal/model"
+	"odomosml/internal/deal/repository"
+	userRepo "odomosml/internal/user/repository"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/validation"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxTitleLength is de maximale lengte van de titel van een deal
+const maxTitleLength = 200
+
+// currencyPattern valideert een ISO 4217 valutacode
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// DealService definieert de interface voor deal service
+type DealService interface {
+	GetDeals(filter model.DealFilter) ([]model.Deal, pagination.Info, error)
+	GetDeal(id string) (*model.Deal, error)
+	CreateDeal(deal *model.Deal) (*model.Deal, error)
+	UpdateDeal(deal *model.Deal) (*model.Deal, error)
+	DeleteDeal(id string) (map[string]interface{}, error)
+	GetPipeline(filter model.DealFilter) (*model.Pipeline, error)
+}
+
+// dealService implementeert de DealService interface
+type dealService struct {
+	repo         repository.DealRepository
+	customerRepo customerRepo.CustomerRepository
+	userRepo     userRepo.UserRepository
+}
+
+// NewDealService maakt een nieuwe DealService instantie
+func NewDealService(repo repository.DealRepository, customerRepo customerRepo.CustomerRepository, userRepo userRepo.UserRepository) DealService {
+	return &dealService{
+		repo:         repo,
+		customerRepo: customerRepo,
+		userRepo:     userRepo,
+	}
+}
+
+// GetDeals haalt deals op met filters
+func (s *dealService) GetDeals(filter model.DealFilter) ([]model.Deal, pagination.Info, error) {
+	if err := validateFilter(&filter); err != nil {
+		return nil, pagination.Info{}, err
+	}
+	return s.repo.FindAll(filter)
+}
+
+// GetDeal haalt een deal op op basis van ID
+func (s *dealService) GetDeal(id string) (*model.Deal, error) {
+	return s.repo.FindByID(id)
+}
+
+// CreateDeal maakt een nieuwe deal aan; zonder fase begint de deal in de eerste fase van de pipeline
+func (s *dealService) CreateDeal(deal *model.Deal) (*model.Deal, error) {
+	deal.ID = 0
+	if deal.Stage == "" {
+		deal.Stage = model.StageQualification
+	}
+
+	if err := s.validate(deal, nil); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(deal)
+}
+
+// UpdateDeal werkt een bestaande deal bij
+func (s *dealService) UpdateDeal(deal *model.Deal) (*model.Deal, error) {
+	existing, err := s.repo.FindByID(strconv.FormatUint(uint64(deal.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	if deal.Stage == "" {
+		deal.Stage = existing.Stage
+	}
+	if deal.OwnerID == 0 {
+		deal.OwnerID = existing.OwnerID
+	}
+
+	if err := s.validate(deal, existing); err != nil {
+		return nil, err
+	}
+
+	deal.CreatedAt = existing.CreatedAt
+	return s.repo.Update(deal)
+}
+
+// DeleteDeal verwijdert een deal en retourneert de data voor audit logging
+func (s *dealService) DeleteDeal(id string) (map[string]interface{}, error) {
+	deal, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	dealData := deal.ToAuditMap()
+
+	if err := s.repo.Delete(deal.ID); err != nil {
+		return nil, err
+	}
+
+	return dealData, nil
+}
+
+// GetPipeline geeft per fase en valuta het aantal deals met het totale en gewogen bedrag, in de
+// volgorde van de pipeline, en de totalen van de open deals per valuta
+func (s *dealService) GetPipeline(filter model.DealFilter) (*model.Pipeline, error) {
+	if err := validateFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	sums, err := s.repo.SumByStage(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := &model.Pipeline{
+		Stages: make([]model.PipelineStage, 0, len(sums)),
+		Open:   []model.PipelineTotal{},
+	}
+	for _, stage := range model.Stages {
+		for _, sum := range sums {
+			if sum.Stage == stage {
+				pipeline.Stages = append(pipeline.Stages, sum)
+			}
+		}
+	}
+
+	// De repository levert de sommen op valuta gesorteerd, dus elke valuta komt één keer in Open
+	for _, sum := range sums {
+		if sum.Stage.IsClosed() {
+			continue
+		}
+		last := len(pipeline.Open) - 1
+		if last < 0 || pipeline.Open[last].Currency != sum.Currency {
+			pipeline.Open = append(pipeline.Open, model.PipelineTotal{Currency: sum.Currency})
+			last++
+		}
+		pipeline.Open[last].Count += sum.Count
+		pipeline.Open[last].AmountCents += sum.AmountCents
+		pipeline.Open[last].WeightedAmountCents += sum.WeightedAmountCents
+	}
+
+	return pipeline, nil
+}
+
+// validate controleert en normaliseert de velden van een deal. existing is de huidige deal bij
+// bijwerken, of nil bij aanmaken. Een gewonnen deal heeft altijd een kans van 100%, een verloren deal 0%.
+func (s *dealService) validate(deal *model.Deal, existing *model.Deal) error {
+	var problems validation.Errors
+
+	deal.Title = strings.TrimSpace(deal.Title)
+	if deal.Title == "" {
+		problems = append(problems, validation.FieldError{Field: "title", Message: "titel is verplicht"})
+	} else if utf8.RuneCountInString(deal.Title) > maxTitleLength {
+		problems = append(problems, validation.FieldError{Field: "title", Message: fmt.Sprintf("titel mag maximaal %d tekens bevatten", maxTitleLength)})
+	}
+
+	if deal.AmountCents < 0 {
+		problems = append(problems, validation.FieldError{Field: "amount_cents", Message: "bedrag mag niet negatief zijn"})
+	}
+
+	deal.Currency = strings.ToUpper(strings.TrimSpace(deal.Currency))
+	if deal.Currency == "" {
+		deal.Currency = model.DefaultCurrency
+	}
+	if !currencyPattern.MatchString(deal.Currency) {
+		problems = append(problems, validation.FieldError{Field: "currency", Message: "valuta moet een ISO 4217 code zijn, bijv. EUR"})
+	}
+
+	if !deal.Stage.IsValid() {
+		problems = append(problems, validation.FieldError{Field: "stage", Message: "fase moet qualification, proposal, negotiation, won of lost zijn"})
+	}
+
+	if deal.Probability < 0 || deal.Probability > 100 {
+		problems = append(problems, validation.FieldError{Field: "probability", Message: "kans moet tussen 0 en 100 liggen"})
+	}
+
+	if customer, err := s.customerRepo.FindByID(strconv.FormatUint(uint64(deal.CustomerID), 10)); err != nil || customer == nil {
+		problems = append(problems, validation.FieldError{Field: "customer_id", Message: fmt.Sprintf("klant %d bestaat niet", deal.CustomerID)})
+	}
+
+	if existing == nil || deal.OwnerID != existing.OwnerID {
+		owner, err := s.userRepo.FindByID(strconv.FormatUint(uint64(deal.OwnerID), 10))
+		if err != nil || owner == nil || !owner.Active {
+			problems = append(problems, validation.FieldError{Field: "owner_id", Message: fmt.Sprintf("eigenaar %d is geen actieve gebruiker", deal.OwnerID)})
+		} else {
+			deal.OwnerName = owner.Username
+		}
+	} else {
+		deal.OwnerName = existing.OwnerName
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	switch deal.Stage {
+	case model.StageWon:
+		deal.Probability = 100
+	case model.StageLost:
+		deal.Probability = 0
+	}
+
+	// Het sluitmoment blijft staan zolang de deal in dezelfde afgesloten fase blijft
+	switch {
+	case !deal.Stage.IsClosed():
+		deal.ClosedAt = nil
+	case existing != nil && existing.Stage == deal.Stage && existing.ClosedAt != nil:
+		deal.ClosedAt = existing.ClosedAt
+	default:
+		now := time.Now()
+		deal.ClosedAt = &now
+	}
+
+	return nil
+}
+
+// validateFilter controleert de fasen en normaliseert de valuta van een filter
+func validateFilter(filter *model.DealFilter) error {
+	for _, stage := range filter.Stages {
+		if !stage.IsValid() {
+			return fmt.Errorf("onbekende fase '%s'", stage)
+		}
+	}
+	filter.Currency = strings.ToUpper(filter.Currency)
+	return nil
+}
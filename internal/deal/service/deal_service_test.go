@@ -0,0 +1,266 @@
+package service
+
+import (
+	"errors"
+	customerModel "odomosml/internal/customer/model"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/internal/deal/model"
+	"odomosml/internal/deal/repository"
+	userModel "odomosml/internal/user/model"
+	userRepo "odomosml/internal/user/repository"
+	"odomosml/pkg/validation"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDealRepository bewaart deals in het geheugen en geeft vaste sommen voor de pipeline
+type fakeDealRepository struct {
+	repository.DealRepository
+	deals map[uint]model.Deal
+	sums  []model.PipelineStage
+}
+
+func (r *fakeDealRepository) FindByID(id string) (*model.Deal, error) {
+	dealID, _ := strconv.ParseUint(id, 10, 64)
+	deal, ok := r.deals[uint(dealID)]
+	if !ok {
+		return nil, errors.New("deal niet gevonden")
+	}
+	return &deal, nil
+}
+
+func (r *fakeDealRepository) Create(deal *model.Deal) (*model.Deal, error) {
+	deal.ID = uint(len(r.deals) + 1)
+	r.deals[deal.ID] = *deal
+	return deal, nil
+}
+
+func (r *fakeDealRepository) Update(deal *model.Deal) (*model.Deal, error) {
+	r.deals[deal.ID] = *deal
+	return deal, nil
+}
+
+func (r *fakeDealRepository) SumByStage(filter model.DealFilter) ([]model.PipelineStage, error) {
+	return r.sums, nil
+}
+
+// fakeCustomerRepository kent alleen klant 1
+type fakeCustomerRepository struct {
+	customerRepo.CustomerRepository
+}
+
+func (fakeCustomerRepository) FindByID(id string) (*customerModel.Customer, error) {
+	if id != "1" {
+		return nil, errors.New("klant niet gevonden")
+	}
+	return &customerModel.Customer{ID: 1}, nil
+}
+
+// fakeUserRepository kent de actieve gebruikers 1 (johndoe) en 2 (janedoe) en de inactieve gebruiker 3
+type fakeUserRepository struct {
+	userRepo.UserRepository
+}
+
+func (fakeUserRepository) FindByID(id string) (*userModel.User, error) {
+	switch id {
+	case "1":
+		return &userModel.User{ID: 1, Username: "johndoe", Active: true}, nil
+	case "2":
+		return &userModel.User{ID: 2, Username: "janedoe", Active: true}, nil
+	case "3":
+		return &userModel.User{ID: 3, Username: "piet"}, nil
+	}
+	return nil, errors.New("gebruiker niet gevonden")
+}
+
+func newTestService(deals ...model.Deal) (DealService, *fakeDealRepository) {
+	repo := &fakeDealRepository{deals: make(map[uint]model.Deal)}
+	for _, deal := range deals {
+		repo.deals[deal.ID] = deal
+	}
+	return NewDealService(repo, fakeCustomerRepository{}, fakeUserRepository{}), repo
+}
+
+func TestCreateDeal(t *testing.T) {
+	tests := []struct {
+		name       string
+		deal       model.Deal
+		wantStage  model.Stage
+		wantChance int
+		wantClosed bool
+		wantFields []string
+	}{
+		{
+			name:      "standaardwaarden",
+			deal:      model.Deal{Title: " Onderhoud ", CustomerID: 1, OwnerID: 1, AmountCents: 125000, Currency: " eur ", Probability: 40},
+			wantStage: model.StageQualification, wantChance: 40,
+		},
+		{
+			name:      "gewonnen deal heeft een kans van 100%",
+			deal:      model.Deal{Title: "Onderhoud", CustomerID: 1, OwnerID: 1, Stage: model.StageWon, Probability: 40},
+			wantStage: model.StageWon, wantChance: 100, wantClosed: true,
+		},
+		{
+			name:      "verloren deal heeft een kans van 0%",
+			deal:      model.Deal{Title: "Onderhoud", CustomerID: 1, OwnerID: 1, Stage: model.StageLost, Probability: 40},
+			wantStage: model.StageLost, wantChance: 0, wantClosed: true,
+		},
+		{
+			name:      "titel van 200 tekens met accenten",
+			deal:      model.Deal{Title: strings.Repeat("é", 200), CustomerID: 1, OwnerID: 1},
+			wantStage: model.StageQualification,
+		},
+		{
+			name:       "alle fouten tegelijk",
+			deal:       model.Deal{Title: " ", CustomerID: 9, OwnerID: 3, AmountCents: -1, Currency: "euro", Stage: "closed", Probability: 101},
+			wantFields: []string{"title", "amount_cents", "currency", "stage", "probability", "customer_id", "owner_id"},
+		},
+		{
+			name:       "te lange titel",
+			deal:       model.Deal{Title: strings.Repeat("a", 201), CustomerID: 1, OwnerID: 1},
+			wantFields: []string{"title"},
+		},
+		{
+			name:       "onbekende eigenaar",
+			deal:       model.Deal{Title: "Onderhoud", CustomerID: 1, OwnerID: 9, Probability: -1},
+			wantFields: []string{"probability", "owner_id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService()
+			deal := tt.deal
+			created, err := service.CreateDeal(&deal)
+			if tt.wantFields != nil {
+				fields, _ := validation.Fields(err)
+				var got []string
+				for _, field := range fields {
+					got = append(got, field.Field)
+				}
+				if !reflect.DeepEqual(got, tt.wantFields) {
+					t.Errorf("fouten op %v (%v), verwacht %v", got, err, tt.wantFields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateDeal gaf fout: %v", err)
+			}
+			if created.Stage != tt.wantStage || created.Probability != tt.wantChance || (created.ClosedAt != nil) != tt.wantClosed {
+				t.Errorf("fase %s, kans %d, gesloten %v", created.Stage, created.Probability, created.ClosedAt)
+			}
+			if created.Currency != "EUR" || created.OwnerName != "johndoe" || strings.TrimSpace(created.Title) != created.Title {
+				t.Errorf("deal = %+v", created)
+			}
+		})
+	}
+}
+
+func TestUpdateDeal(t *testing.T) {
+	closedAt := time.Date(2024, 3, 28, 14, 0, 0, 0, time.UTC)
+	open := model.Deal{ID: 1, Title: "Onderhoud", CustomerID: 1, OwnerID: 1, OwnerName: "johndoe", Stage: model.StageProposal, Probability: 40, Currency: "EUR"}
+	won := model.Deal{ID: 2, Title: "Licenties", CustomerID: 1, OwnerID: 3, OwnerName: "piet", Stage: model.StageWon, Probability: 100, Currency: "EUR", ClosedAt: &closedAt}
+
+	tests := []struct {
+		name       string
+		update     model.Deal
+		wantStage  model.Stage
+		wantOwner  string
+		wantChance int
+		wantClosed *time.Time
+		wantErr    bool
+	}{
+		{name: "fase en eigenaar blijven staan", update: model.Deal{ID: 1, Title: "Onderhoud 2025", CustomerID: 1, Probability: 60},
+			wantStage: model.StageProposal, wantOwner: "johndoe", wantChance: 60},
+		{name: "andere eigenaar", update: model.Deal{ID: 1, Title: "Onderhoud", CustomerID: 1, OwnerID: 2},
+			wantStage: model.StageProposal, wantOwner: "janedoe"},
+		{name: "gewonnen blijft gewonnen op hetzelfde moment", update: model.Deal{ID: 2, Title: "Licenties 2025", CustomerID: 1},
+			wantStage: model.StageWon, wantOwner: "piet", wantChance: 100, wantClosed: &closedAt},
+		{name: "heropend", update: model.Deal{ID: 2, Title: "Licenties", CustomerID: 1, Stage: model.StageNegotiation, Probability: 80},
+			wantStage: model.StageNegotiation, wantOwner: "piet", wantChance: 80},
+		{name: "naar een inactieve eigenaar", update: model.Deal{ID: 1, Title: "Onderhoud", CustomerID: 1, OwnerID: 3}, wantErr: true},
+		{name: "onbekende deal", update: model.Deal{ID: 9, Title: "Onderhoud", CustomerID: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(open, won)
+			update := tt.update
+			updated, err := service.UpdateDeal(&update)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("UpdateDeal gaf geen fout")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateDeal gaf fout: %v", err)
+			}
+			if updated.Stage != tt.wantStage || updated.OwnerName != tt.wantOwner || updated.Probability != tt.wantChance {
+				t.Errorf("fase %s, eigenaar %s, kans %d", updated.Stage, updated.OwnerName, updated.Probability)
+			}
+			if !reflect.DeepEqual(updated.ClosedAt, tt.wantClosed) {
+				t.Errorf("gesloten op %v, verwacht %v", updated.ClosedAt, tt.wantClosed)
+			}
+		})
+	}
+
+	// Van gewonnen naar verloren is een nieuw sluitmoment
+	service, _ := newTestService(won)
+	lost, err := service.UpdateDeal(&model.Deal{ID: 2, Title: "Licenties", CustomerID: 1, Stage: model.StageLost})
+	if err != nil {
+		t.Fatalf("UpdateDeal gaf fout: %v", err)
+	}
+	if lost.ClosedAt == nil || !lost.ClosedAt.After(closedAt) || lost.Probability != 0 {
+		t.Errorf("verloren deal gesloten op %v met kans %d", lost.ClosedAt, lost.Probability)
+	}
+}
+
+func TestGetPipeline(t *testing.T) {
+	// De repository levert op valuta en daarna alfabetisch op fase
+	service, repo := newTestService()
+	repo.sums = []model.PipelineStage{
+		{Stage: model.StageNegotiation, Currency: "EUR", Count: 1, AmountCents: 10000, WeightedAmountCents: 8000},
+		{Stage: model.StageProposal, Currency: "EUR", Count: 2, AmountCents: 5000, WeightedAmountCents: 2000},
+		{Stage: model.StageQualification, Currency: "EUR", Count: 3, AmountCents: 3000, WeightedAmountCents: 300},
+		{Stage: model.StageWon, Currency: "EUR", Count: 4, AmountCents: 40000, WeightedAmountCents: 40000},
+		{Stage: model.StageLost, Currency: "USD", Count: 1, AmountCents: 700, WeightedAmountCents: 0},
+		{Stage: model.StageProposal, Currency: "USD", Count: 1, AmountCents: 900, WeightedAmountCents: 450},
+	}
+
+	pipeline, err := service.GetPipeline(model.DealFilter{Currency: "eur"})
+	if err != nil {
+		t.Fatalf("GetPipeline gaf fout: %v", err)
+	}
+
+	var order []string
+	for _, stage := range pipeline.Stages {
+		order = append(order, string(stage.Stage)+"/"+stage.Currency)
+	}
+	wantOrder := []string{"qualification/EUR", "proposal/EUR", "proposal/USD", "negotiation/EUR", "won/EUR", "lost/USD"}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("fasen = %v, verwacht %v", order, wantOrder)
+	}
+
+	// Gewonnen en verloren deals tellen niet mee in de open pipeline, en valuta worden niet opgeteld
+	wantOpen := []model.PipelineTotal{
+		{Currency: "EUR", Count: 6, AmountCents: 18000, WeightedAmountCents: 10300},
+		{Currency: "USD", Count: 1, AmountCents: 900, WeightedAmountCents: 450},
+	}
+	if !reflect.DeepEqual(pipeline.Open, wantOpen) {
+		t.Errorf("open = %+v, verwacht %+v", pipeline.Open, wantOpen)
+	}
+
+	if _, err := service.GetPipeline(model.DealFilter{Stages: []model.Stage{model.StageWon, "closed"}}); err == nil || err.Error() != "onbekende fase 'closed'" {
+		t.Errorf("GetPipeline met onbekende fase gaf %v", err)
+	}
+
+	// Zonder deals lege lijsten, zodat de JSON [] is en geen null
+	repo.sums = nil
+	if empty, err := service.GetPipeline(model.DealFilter{}); err != nil || empty.Stages == nil || empty.Open == nil {
+		t.Errorf("GetPipeline zonder deals = %+v, %v", empty, err)
+	}
+}
@@ -0,0 +1,386 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"odomosml/internal/invoice/model"
+	"odomosml/internal/invoice/repository"
+	"odomosml/internal/invoice/service"
+	"odomosml/pkg/validation"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvoiceHandler handles HTTP requests for quotes or invoices; elk soort document heeft zijn eigen handler
+type InvoiceHandler struct {
+	service service.InvoiceService
+	docType model.DocumentType
+}
+
+// NewInvoiceHandler maakt een nieuwe InvoiceHandler instantie voor offertes of facturen
+func NewInvoiceHandler(service service.InvoiceService, docType model.DocumentType) *InvoiceHandler {
+	return &InvoiceHandler{
+		service: service,
+		docType: docType,
+	}
+}
+
+// respondError stuurt een fout terug: 409 als het document niet (meer) in de juiste status is,
+// 400 met validatiefouten per veld in fields, en anders status
+func respondError(c *gin.Context, status int, err error) {
+	response := gin.H{
+		"success": false,
+		"error":   err.Error(),
+	}
+	if errors.Is(err, repository.ErrNotDraft) || errors.Is(err, repository.ErrStatusChanged) {
+		status = http.StatusConflict
+	} else if fields, ok := validation.Fields(err); ok {
+		status = http.StatusBadRequest
+		response["fields"] = fields
+	}
+	c.JSON(status, response)
+}
+
+// parsePositiveParam parst een optionele positieve getalparameter; een lege parameter geeft 0
+func parsePositiveParam(c *gin.Context, param string) (int, error) {
+	value := c.Query(param)
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("%s moet een positief getal zijn", param)
+	}
+	return number, nil
+}
+
+// @Summary      Lijst van offertes of facturen ophalen
+// @Description  Haalt offertes (/quotes) of facturen (/invoices) op zonder regels; concepten eerst, daarna op aflopend nummer. Verstuurde facturen over de vervaldatum krijgen door een achtergrondjob status overdue.
+// @Tags         offertes en facturen
+// @Accept       json
+// @Produce      json
+// @Param        page query int false "Paginanummer (default: 1)"
+// @Param        page_size query int false "Aantal items per pagina (default: 10, max: 100)"
+// @Param        status query string false "Komma-gescheiden statussen: draft, sent, paid, overdue (facturen), accepted, declined (offertes)"
+// @Param        customer_id query int false "Alleen documenten van deze klant"
+// @Param        year query int false "Alleen documenten met een nummer uit dit jaar"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /quotes [get]
+// @Router       /invoices [get]
+func (h *InvoiceHandler) GetAll(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	filter := model.InvoiceFilter{
+		Type:     h.docType,
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	for _, status := range strings.Split(c.Query("status"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter.Statuses = append(filter.Statuses, model.Status(status))
+		}
+	}
+
+	customerID, err := parsePositiveParam(c, "customer_id")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	year, err := parsePositiveParam(c, "year")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	filter.CustomerID = uint(customerID)
+	filter.Year = year
+
+	invoices, total, err := h.service.GetInvoices(filter)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    invoices,
+		"pagination": gin.H{
+			"current_page": filter.Page,
+			"page_size":    filter.PageSize,
+			"total_items":  total,
+			"total_pages":  (total + int64(filter.PageSize) - 1) / int64(filter.PageSize),
+		},
+	})
+}
+
+// @Summary      Offerte of factuur ophalen op ID
+// @Description  Haalt een offerte of factuur op met de regels en de BTW per tarief
+// @Tags         offertes en facturen
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Document ID"
+// @Success      200  {object}  model.Invoice "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Document niet gevonden"
+// @Security     Bearer
+// @Router       /quotes/{id} [get]
+// @Router       /invoices/{id} [get]
+func (h *InvoiceHandler) GetByID(c *gin.Context) {
+	invoice, err := h.service.GetInvoice(h.docType, c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    invoice,
+	})
+}
+
+// @Summary      Offerte of factuur aanmaken
+// @Description  Maakt een concept aan. De klantgegevens (naam, adres, KvK- en BTW-nummer) worden van de klant overgenomen, de bedragen en de BTW per tarief (21, 9 of 0) worden berekend. Zonder termijn gelden de standaard betaaltermijn of geldigheid.
+// @Tags         offertes en facturen
+// @Accept       json
+// @Produce      json
+// @Param        document body model.Invoice true "Klant, regels en optioneel kenmerk, notities, valuta en termijn in dagen"
+// @Success      201  {object}  model.Invoice "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /quotes [post]
+// @Router       /invoices [post]
+func (h *InvoiceHandler) Create(c *gin.Context) {
+	var invoice model.Invoice
+	if err := c.ShouldBindJSON(&invoice); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+	invoice.Type = h.docType
+	invoice.CreatedByID, _ = userID.(uint)
+	invoice.CreatedByName, _ = username.(string)
+
+	created, err := h.service.CreateInvoice(&invoice)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Concept bijwerken
+// @Description  Werkt een concept bij; de regels worden vervangen door de meegestuurde regels. Een verstuurd document kan niet meer gewijzigd worden.
+// @Tags         offertes en facturen
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Document ID"
+// @Param        document body model.Invoice true "Klant, regels en optioneel kenmerk, notities, valuta en termijn in dagen"
+// @Success      200  {object}  model.Invoice "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Document niet gevonden"
+// @Failure      409  {object}  map[string]string "Document is geen concept"
+// @Security     Bearer
+// @Router       /quotes/{id} [put]
+// @Router       /invoices/{id} [put]
+func (h *InvoiceHandler) Update(c *gin.Context) {
+	existing, err := h.service.GetInvoice(h.docType, c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	var invoice model.Invoice
+	if err := c.ShouldBindJSON(&invoice); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	// Bewaar de huidige gegevens voor de audit diff
+	c.Set("invoiceOldData", existing.ToAuditMap())
+
+	invoice.ID = existing.ID
+	invoice.Type = h.docType
+	if invoice.PaymentTermDays == 0 {
+		invoice.PaymentTermDays = existing.PaymentTermDays
+	}
+
+	updated, err := h.service.UpdateInvoice(&invoice)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Concept verwijderen
+// @Description  Verwijdert een concept. Verstuurde offertes en facturen worden bewaard, zodat de nummering geen gaten krijgt.
+// @Tags         offertes en facturen
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Document ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Document niet gevonden"
+// @Failure      409  {object}  map[string]string "Document is geen concept"
+// @Security     Bearer
+// @Router       /quotes/{id} [delete]
+// @Router       /invoices/{id} [delete]
+func (h *InvoiceHandler) Delete(c *gin.Context) {
+	invoiceData, err := h.service.DeleteInvoice(h.docType, c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// Sla invoiceData op in context voor audit logging
+	c.Set("invoiceData", invoiceData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": h.docType.Label() + " succesvol verwijderd",
+	})
+}
+
+// @Summary      Status wijzigen
+// @Description  Zet een offerte of factuur in een andere status. Facturen: draft → sent → paid of overdue, overdue → paid. Offertes: draft → sent → accepted of declined. Bij het versturen krijgt het document het volgende nummer van het jaar (F2025-0001 of OF2025-0001), de datum van vandaag en de vervaldatum.
+// @Tags         offertes en facturen
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Document ID"
+// @Param        status body model.StatusRequest true "Nieuwe status"
+// @Success      200  {object}  model.Invoice "Status gewijzigd"
+// @Failure      400  {object}  map[string]interface{} "Overgang niet toegestaan"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Document niet gevonden"
+// @Failure      409  {object}  map[string]string "Status is intussen gewijzigd"
+// @Security     Bearer
+// @Router       /quotes/{id}/status [put]
+// @Router       /invoices/{id}/status [put]
+func (h *InvoiceHandler) ChangeStatus(c *gin.Context) {
+	var request model.StatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	existing, err := h.service.GetInvoice(h.docType, c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// Bewaar de huidige gegevens voor de audit diff
+	c.Set("invoiceOldData", existing.ToAuditMap())
+
+	updated, err := h.service.ChangeStatus(h.docType, c.Param("id"), request.Status)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      PDF downloaden
+// @Description  Maakt de offerte of factuur als PDF, met logo, bedrijfsgegevens, de KvK- en BTW-nummers van de klant, de regels, de BTW per tarief en de teksten uit het sjabloon
+// @Tags         offertes en facturen
+// @Produce      application/pdf
+// @Param        id path string true "Document ID"
+// @Success      200  {file}    file "PDF bestand"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Document niet gevonden"
+// @Security     Bearer
+// @Router       /quotes/{id}/pdf [get]
+// @Router       /invoices/{id}/pdf [get]
+func (h *InvoiceHandler) PDF(c *gin.Context) {
+	var buf bytes.Buffer
+	invoice, err := h.service.RenderPDF(h.docType, c.Param("id"), &buf)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	name := invoice.Number
+	if name == "" {
+		name = fmt.Sprintf("concept-%d", invoice.ID)
+	}
+	fileName := strings.ToLower(invoice.Type.Label()) + "-" + name + ".pdf"
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// @Summary      Factuur maken van offerte
+// @Description  Maakt een conceptfactuur met de klant, het kenmerk en de regels van een geaccepteerde offerte
+// @Tags         offertes en facturen
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Offerte ID"
+// @Success      201  {object}  model.Invoice "Conceptfactuur aangemaakt"
+// @Failure      400  {object}  map[string]interface{} "Offerte is niet geaccepteerd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Offerte niet gevonden"
+// @Security     Bearer
+// @Router       /quotes/{id}/invoice [post]
+func (h *InvoiceHandler) CreateFromQuote(c *gin.Context) {
+	if _, err := h.service.GetInvoice(model.TypeQuote, c.Param("id")); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+	id, _ := userID.(uint)
+	name, _ := username.(string)
+
+	created, err := h.service.CreateInvoiceFromQuote(c.Param("id"), id, name)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
@@ -0,0 +1,231 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DocumentType onderscheidt offertes en facturen; beide hebben hun eigen nummering en statussen
+type DocumentType string
+
+// Soorten documenten
+const (
+	TypeQuote   DocumentType = "quote"
+	TypeInvoice DocumentType = "invoice"
+)
+
+// Label geeft de Nederlandse naam van het soort document, zoals op de PDF
+func (t DocumentType) Label() string {
+	if t == TypeQuote {
+		return "Offerte"
+	}
+	return "Factuur"
+}
+
+// NumberPrefix is het voorvoegsel van het documentnummer; offertes en facturen worden apart genummerd
+func (t DocumentType) NumberPrefix() string {
+	if t == TypeQuote {
+		return "OF"
+	}
+	return "F"
+}
+
+// Number geeft het documentnummer bij een volgnummer in een jaar, bijv. F2025-0001 of OF2025-0012
+func (t DocumentType) Number(year, sequence int) string {
+	return fmt.Sprintf("%s%d-%04d", t.NumberPrefix(), year, sequence)
+}
+
+// Status is de status van een offerte of factuur
+type Status string
+
+// Statussen. Concepten zijn nog te wijzigen; bij het versturen krijgt een document zijn nummer
+// en liggen de regels en klantgegevens vast.
+const (
+	StatusDraft    Status = "draft"
+	StatusSent     Status = "sent"
+	StatusPaid     Status = "paid"     // Alleen facturen
+	StatusOverdue  Status = "overdue"  // Alleen facturen: verstuurd en de vervaldatum is verstreken
+	StatusAccepted Status = "accepted" // Alleen offertes
+	StatusDeclined Status = "declined" // Alleen offertes
+)
+
+// transitions zijn per soort document de toegestane statusovergangen
+var transitions = map[DocumentType]map[Status][]Status{
+	TypeInvoice: {
+		StatusDraft:   {StatusSent},
+		StatusSent:    {StatusPaid, StatusOverdue},
+		StatusOverdue: {StatusPaid},
+	},
+	TypeQuote: {
+		StatusDraft: {StatusSent},
+		StatusSent:  {StatusAccepted, StatusDeclined},
+	},
+}
+
+// Transitions geeft de statussen waar een document van dit soort vanuit from naartoe mag
+func (t DocumentType) Transitions(from Status) []Status {
+	return transitions[t][from]
+}
+
+// Allows geeft aan of een document van dit soort van status from naar status to mag
+func (t DocumentType) Allows(from, to Status) bool {
+	for _, status := range transitions[t][from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// VATRates zijn de toegestane BTW-tarieven in procenten: hoog, laag en nul
+var VATRates = []int{21, 9, 0}
+
+// IsValidVATRate controleert of een BTW-tarief is toegestaan
+func IsValidVATRate(rate int) bool {
+	for _, valid := range VATRates {
+		if rate == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Invoice is een offerte of factuur voor een klant. De klantgegevens worden bij het aanmaken en bij
+// elke wijziging van het concept van de klant overgenomen, en liggen vast zodra het document verstuurd is.
+// @Description Een offerte of factuur
+type Invoice struct {
+	ID                uint          `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	Type              DocumentType  `json:"type" gorm:"size:10;not null;index" example:"invoice" swaggertype:"string"`
+	Status            Status        `json:"status" gorm:"size:20;not null;index" example:"draft" swaggertype:"string"`
+	Number            string        `json:"number" gorm:"size:20;not null;default:''" example:"F2025-0001" swaggertype:"string"` // Leeg zolang het document een concept is
+	Year              int           `json:"year" gorm:"not null;default:0" example:"2025" swaggertype:"integer"`
+	Sequence          int           `json:"sequence" gorm:"not null;default:0" example:"1" swaggertype:"integer"` // Volgnummer binnen het jaar, zonder gaten
+	CustomerID        uint          `json:"customer_id" gorm:"not null;index" binding:"required" example:"1" swaggertype:"integer"`
+	CustomerName      string        `json:"customer_name" gorm:"size:255" example:"Bakkerij Jansen" swaggertype:"string"`
+	CustomerAddress   string        `json:"customer_address" gorm:"serializer:encrypted" example:"Dorpsstraat 1, Utrecht" swaggertype:"string"` // Versleuteld opgeslagen, net als bij de klant
+	CustomerEmail     string        `json:"customer_email" gorm:"serializer:encrypted" example:"info@jansen.nl" swaggertype:"string"`
+	CustomerKvK       string        `json:"customer_kvk" gorm:"column:customer_kvk;size:8" example:"12345678" swaggertype:"string"`
+	CustomerVATNumber string        `json:"customer_vat_number" gorm:"size:14" example:"NL123456789B01" swaggertype:"string"`
+	Reference         string        `json:"reference" gorm:"size:100" example:"PO-4711" swaggertype:"string"` // Kenmerk van de klant
+	Notes             string        `json:"notes" example:"Levering in week 12" swaggertype:"string"`
+	Currency          string        `json:"currency" gorm:"size:3;not null;default:'EUR'" example:"EUR" swaggertype:"string"`
+	PaymentTermDays   int           `json:"payment_term_days" gorm:"not null;default:0" example:"30" swaggertype:"integer"` // Betaaltermijn van een factuur of geldigheid van een offerte
+	IssueDate         *time.Time    `json:"issue_date" gorm:"type:date" example:"2025-03-01T00:00:00Z" swaggertype:"string" format:"date-time"`
+	DueDate           *time.Time    `json:"due_date" gorm:"type:date;index" example:"2025-03-31T00:00:00Z" swaggertype:"string" format:"date-time"` // Vervaldatum van een factuur of einde geldigheid van een offerte
+	SubtotalCents     int64         `json:"subtotal_cents" gorm:"not null;default:0" example:"10000" swaggertype:"integer"`
+	VATCents          int64         `json:"vat_cents" gorm:"column:vat_cents;not null;default:0" example:"2100" swaggertype:"integer"`
+	TotalCents        int64         `json:"total_cents" gorm:"not null;default:0" example:"12100" swaggertype:"integer"`
+	VATBreakdown      []VATLine     `json:"vat_breakdown" gorm:"-"`
+	Lines             []InvoiceLine `json:"lines" gorm:"foreignKey:InvoiceID;constraint:OnDelete:CASCADE"`
+	QuoteID           *uint         `json:"quote_id" gorm:"index" swaggertype:"integer"` // De offerte waaruit deze factuur is gemaakt
+	CreatedByID       uint          `json:"created_by_id" example:"1" swaggertype:"integer"`
+	CreatedByName     string        `json:"created_by_name" gorm:"size:100" example:"johndoe" swaggertype:"string"`
+	SentAt            *time.Time    `json:"sent_at" swaggertype:"string" format:"date-time"`
+	ClosedAt          *time.Time    `json:"closed_at" swaggertype:"string" format:"date-time"` // Betaald, geaccepteerd of afgewezen
+	CreatedAt         time.Time     `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt         time.Time     `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Invoice) TableName() string {
+	return "invoices"
+}
+
+// InvoiceLine is een regel van een offerte of factuur
+// @Description Een regel van een offerte of factuur
+type InvoiceLine struct {
+	ID             uint    `json:"id" gorm:"primaryKey" swaggertype:"integer"`
+	InvoiceID      uint    `json:"-" gorm:"not null;index"`
+	Position       int     `json:"position" gorm:"not null" example:"1" swaggertype:"integer"`
+	ProductID      *uint   `json:"product_id" gorm:"index" swaggertype:"integer"` // Product uit de prijslijst; vult omschrijving, prijs en BTW-tarief aan
+	Description    string  `json:"description" gorm:"not null" example:"Onderhoud maart" swaggertype:"string"`
+	Quantity       float64 `json:"quantity" gorm:"type:numeric(12,3);not null" example:"2.5" swaggertype:"number"`
+	UnitPriceCents int64   `json:"unit_price_cents" gorm:"not null" example:"8500" swaggertype:"integer"` // Prijs per eenheid exclusief BTW, in centen
+	VATRate        int     `json:"vat_rate" gorm:"column:vat_rate;not null" example:"21" swaggertype:"integer"`
+	NetCents       int64   `json:"net_cents" gorm:"not null" example:"21250" swaggertype:"integer"` // Aantal × prijs, afgerond op hele centen
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (InvoiceLine) TableName() string {
+	return "invoice_lines"
+}
+
+// VATLine is het totaal van de regels met hetzelfde BTW-tarief
+type VATLine struct {
+	Rate     int   `json:"rate"`
+	NetCents int64 `json:"net_cents"`
+	VATCents int64 `json:"vat_cents"`
+}
+
+// Calculate berekent de regelbedragen, de BTW per tarief en de totalen. De BTW wordt per tarief over
+// het totaal van de regels berekend en daarna afgerond, zodat afrondingsverschillen per regel niet optellen.
+func (i *Invoice) Calculate() {
+	net := make(map[int]int64)
+	for index := range i.Lines {
+		line := &i.Lines[index]
+		line.Position = index + 1
+		line.NetCents = int64(math.Round(line.Quantity * float64(line.UnitPriceCents)))
+		net[line.VATRate] += line.NetCents
+	}
+
+	i.VATBreakdown = make([]VATLine, 0, len(net))
+	for rate, amount := range net {
+		i.VATBreakdown = append(i.VATBreakdown, VATLine{
+			Rate:     rate,
+			NetCents: amount,
+			VATCents: int64(math.Round(float64(amount) * float64(rate) / 100)),
+		})
+	}
+	sort.Slice(i.VATBreakdown, func(a, b int) bool {
+		return i.VATBreakdown[a].Rate > i.VATBreakdown[b].Rate
+	})
+
+	i.SubtotalCents, i.VATCents = 0, 0
+	for _, line := range i.VATBreakdown {
+		i.SubtotalCents += line.NetCents
+		i.VATCents += line.VATCents
+	}
+	i.TotalCents = i.SubtotalCents + i.VATCents
+}
+
+// ToAuditMap converteert een offerte of factuur naar een map voor audit logging
+func (i *Invoice) ToAuditMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":          i.ID,
+		"type":        i.Type,
+		"status":      i.Status,
+		"number":      i.Number,
+		"customer_id": i.CustomerID,
+		"total_cents": i.TotalCents,
+		"lines":       len(i.Lines),
+	}
+}
+
+// DocumentSequence houdt per soort document en jaar het laatst uitgegeven volgnummer bij
+type DocumentSequence struct {
+	Type       DocumentType `gorm:"primaryKey;size:10"`
+	Year       int          `gorm:"primaryKey;autoIncrement:false"`
+	LastNumber int          `gorm:"not null"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (DocumentSequence) TableName() string {
+	return "document_sequences"
+}
+
+// InvoiceFilter definieert filters voor het ophalen van offertes of facturen
+type InvoiceFilter struct {
+	Type       DocumentType
+	Statuses   []Status
+	CustomerID uint
+	Year       int
+	Page       int
+	PageSize   int
+}
+
+// StatusRequest is het verzoek om een offerte of factuur naar een andere status te zetten
+type StatusRequest struct {
+	Status Status `json:"status" binding:"required"`
+}
@@ -0,0 +1,156 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []InvoiceLine
+		wantNet      []int64
+		wantVAT      []VATLine
+		wantSubtotal int64
+		wantVATCents int64
+	}{
+		{
+			name:         "zonder regels",
+			wantVAT:      []VATLine{},
+			wantSubtotal: 0,
+			wantVATCents: 0,
+		},
+		{
+			name:         "aantal met decimalen",
+			lines:        []InvoiceLine{{Quantity: 2.5, UnitPriceCents: 8500, VATRate: 21}},
+			wantNet:      []int64{21250},
+			wantVAT:      []VATLine{{Rate: 21, NetCents: 21250, VATCents: 4463}}, // 4462,5 rondt af naar boven
+			wantSubtotal: 21250,
+			wantVATCents: 4463,
+		},
+		{
+			name:         "regelbedrag afgerond op hele centen",
+			lines:        []InvoiceLine{{Quantity: 0.333, UnitPriceCents: 100, VATRate: 21}, {Quantity: 1.005, UnitPriceCents: 1000, VATRate: 21}},
+			wantNet:      []int64{33, 1005},
+			wantVAT:      []VATLine{{Rate: 21, NetCents: 1038, VATCents: 218}},
+			wantSubtotal: 1038,
+			wantVATCents: 218,
+		},
+		{
+			// Per regel afgerond zou dit 3 × 1 cent BTW zijn; over het totaal is het 4,41 cent
+			name:         "BTW over het totaal per tarief",
+			lines:        []InvoiceLine{{Quantity: 1, UnitPriceCents: 7, VATRate: 21}, {Quantity: 1, UnitPriceCents: 7, VATRate: 21}, {Quantity: 1, UnitPriceCents: 7, VATRate: 21}},
+			wantNet:      []int64{7, 7, 7},
+			wantVAT:      []VATLine{{Rate: 21, NetCents: 21, VATCents: 4}},
+			wantSubtotal: 21,
+			wantVATCents: 4,
+		},
+		{
+			name: "meerdere tarieven, hoogste eerst",
+			lines: []InvoiceLine{
+				{Quantity: 3, UnitPriceCents: 250, VATRate: 9},
+				{Quantity: 1, UnitPriceCents: 5000, VATRate: 0},
+				{Quantity: 2, UnitPriceCents: 1999, VATRate: 21},
+				{Quantity: 1, UnitPriceCents: 1000, VATRate: 9},
+			},
+			wantNet: []int64{750, 5000, 3998, 1000},
+			wantVAT: []VATLine{
+				{Rate: 21, NetCents: 3998, VATCents: 840}, // 839,58
+				{Rate: 9, NetCents: 1750, VATCents: 158},  // 157,5
+				{Rate: 0, NetCents: 5000, VATCents: 0},
+			},
+			wantSubtotal: 10748,
+			wantVATCents: 998,
+		},
+		{
+			name:         "creditregel",
+			lines:        []InvoiceLine{{Quantity: 1, UnitPriceCents: 1000, VATRate: 9}, {Quantity: -1, UnitPriceCents: 250, VATRate: 9}},
+			wantNet:      []int64{1000, -250},
+			wantVAT:      []VATLine{{Rate: 9, NetCents: 750, VATCents: 68}}, // 67,5
+			wantSubtotal: 750,
+			wantVATCents: 68,
+		},
+		{
+			name:         "negatief totaal rondt af van nul af",
+			lines:        []InvoiceLine{{Quantity: -1, UnitPriceCents: 250, VATRate: 9}},
+			wantNet:      []int64{-250},
+			wantVAT:      []VATLine{{Rate: 9, NetCents: -250, VATCents: -23}}, // -22,5
+			wantSubtotal: -250,
+			wantVATCents: -23,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &Invoice{Lines: tt.lines, SubtotalCents: 99, VATCents: 99, TotalCents: 99}
+			invoice.Calculate()
+
+			for i, line := range invoice.Lines {
+				if line.Position != i+1 {
+					t.Errorf("regel %d heeft positie %d", i, line.Position)
+				}
+				if line.NetCents != tt.wantNet[i] {
+					t.Errorf("regel %d: bedrag %d, verwacht %d", i, line.NetCents, tt.wantNet[i])
+				}
+			}
+			if !reflect.DeepEqual(invoice.VATBreakdown, tt.wantVAT) {
+				t.Errorf("BTW per tarief = %+v, verwacht %+v", invoice.VATBreakdown, tt.wantVAT)
+			}
+			if invoice.SubtotalCents != tt.wantSubtotal || invoice.VATCents != tt.wantVATCents {
+				t.Errorf("subtotaal %d, BTW %d; verwacht %d, %d", invoice.SubtotalCents, invoice.VATCents, tt.wantSubtotal, tt.wantVATCents)
+			}
+			if invoice.TotalCents != tt.wantSubtotal+tt.wantVATCents {
+				t.Errorf("totaal %d, verwacht %d", invoice.TotalCents, tt.wantSubtotal+tt.wantVATCents)
+			}
+		})
+	}
+}
+
+func TestDocumentNumber(t *testing.T) {
+	tests := []struct {
+		docType  DocumentType
+		year     int
+		sequence int
+		want     string
+	}{
+		{TypeInvoice, 2025, 1, "F2025-0001"},
+		{TypeInvoice, 2026, 42, "F2026-0042"},
+		{TypeQuote, 2025, 12, "OF2025-0012"},
+		{TypeInvoice, 2025, 10000, "F2025-10000"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.docType.Number(tt.year, tt.sequence); got != tt.want {
+			t.Errorf("%s.Number(%d, %d) = %s, verwacht %s", tt.docType, tt.year, tt.sequence, got, tt.want)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		docType DocumentType
+		from    Status
+		to      Status
+		want    bool
+	}{
+		{TypeInvoice, StatusDraft, StatusSent, true},
+		{TypeInvoice, StatusDraft, StatusPaid, false},
+		{TypeInvoice, StatusSent, StatusPaid, true},
+		{TypeInvoice, StatusSent, StatusOverdue, true},
+		{TypeInvoice, StatusSent, StatusDraft, false},
+		{TypeInvoice, StatusOverdue, StatusPaid, true},
+		{TypeInvoice, StatusPaid, StatusSent, false},
+		{TypeInvoice, StatusSent, StatusAccepted, false},
+		{TypeQuote, StatusDraft, StatusSent, true},
+		{TypeQuote, StatusSent, StatusAccepted, true},
+		{TypeQuote, StatusSent, StatusDeclined, true},
+		{TypeQuote, StatusSent, StatusPaid, false},
+		{TypeQuote, StatusAccepted, StatusDeclined, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.docType.Allows(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: %s → %s = %v, verwacht %v", tt.docType, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
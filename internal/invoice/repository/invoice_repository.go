@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/invoice/model"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Fouten die de service naar een melding voor de gebruiker vertaalt
+var (
+	ErrNotDraft      = errors.New("alleen een concept kan gewijzigd of verwijderd worden")
+	ErrStatusChanged = errors.New("de status is intussen gewijzigd")
+)
+
+// InvoiceRepository definieert de interface voor invoice repository
+type InvoiceRepository interface {
+	FindAll(filter model.InvoiceFilter) ([]model.Invoice, int64, error)
+	FindByID(id string) (*model.Invoice, error)
+	Create(invoice *model.Invoice) (*model.Invoice, error)
+	Update(invoice *model.Invoice) (*model.Invoice, error)
+	Delete(id uint) error
+	Finalize(id uint, issueDate time.Time) (*model.Invoice, error)
+	UpdateStatus(id uint, from, to model.Status, closedAt *time.Time) (*model.Invoice, error)
+	MarkOverdue(today time.Time) (int64, error)
+	SetQuote(id, quoteID uint) error
+}
+
+// invoiceRepository implementeert de InvoiceRepository interface
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+// NewInvoiceRepository maakt een nieuwe InvoiceRepository instantie
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{
+		db: db,
+	}
+}
+
+// FindAll haalt offertes of facturen op zonder regels, nieuwste eerst
+func (r *invoiceRepository) FindAll(filter model.InvoiceFilter) ([]model.Invoice, int64, error) {
+	var invoices []model.Invoice
+	var total int64
+
+	query := r.db.Model(&model.Invoice{}).Where("type = ?", filter.Type)
+
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+
+	if filter.CustomerID != 0 {
+		query = query.Where("customer_id = ?", filter.CustomerID)
+	}
+
+	if filter.Year != 0 {
+		query = query.Where("year = ?", filter.Year)
+	}
+
+	// Tel totaal aantal records (voor paginering)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Paginering toepassen
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query = query.Offset(offset).Limit(filter.PageSize)
+	}
+
+	// Concepten (zonder nummer) eerst, daarna op aflopend nummer
+	if err := query.Order("year = 0 DESC, year DESC, sequence DESC, id DESC").Find(&invoices).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return invoices, total, nil
+}
+
+// FindByID haalt een offerte of factuur met de regels op op basis van ID
+func (r *invoiceRepository) FindByID(id string) (*model.Invoice, error) {
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	return r.find(r.db, uint(idInt))
+}
+
+// find haalt een offerte of factuur met de regels en de BTW per tarief op
+func (r *invoiceRepository) find(db *gorm.DB, id uint) (*model.Invoice, error) {
+	var invoice model.Invoice
+
+	err := db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).First(&invoice, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("document niet gevonden")
+		}
+		return nil, err
+	}
+
+	// De opgeslagen totalen blijven leidend; Calculate levert dezelfde bedragen en vult de BTW per tarief
+	invoice.Calculate()
+	return &invoice, nil
+}
+
+// Create maakt een nieuwe offerte of factuur met de regels aan
+func (r *invoiceRepository) Create(invoice *model.Invoice) (*model.Invoice, error) {
+	if err := r.db.Create(invoice).Error; err != nil {
+		return nil, err
+	}
+	return r.find(r.db, invoice.ID)
+}
+
+// Update werkt een concept bij en vervangt de regels
+func (r *invoiceRepository) Update(invoice *model.Invoice) (*model.Invoice, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(invoice).Omit(clause.Associations).Where("status = ?", model.StatusDraft).
+			Select("customer_id", "customer_name", "customer_address", "customer_email", "customer_kvk",
+				"customer_vat_number", "reference", "notes", "currency", "payment_term_days",
+				"subtotal_cents", "vat_cents", "total_cents", "updated_at").
+			Updates(invoice)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotDraft
+		}
+
+		if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&model.InvoiceLine{}).Error; err != nil {
+			return err
+		}
+		for i := range invoice.Lines {
+			invoice.Lines[i].ID = 0
+			invoice.Lines[i].InvoiceID = invoice.ID
+		}
+		if len(invoice.Lines) == 0 {
+			return nil
+		}
+		return tx.Create(&invoice.Lines).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.find(r.db, invoice.ID)
+}
+
+// Delete verwijdert een concept met de regels
+func (r *invoiceRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("status = ?", model.StatusDraft).Delete(&model.Invoice{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotDraft
+		}
+		return tx.Where("invoice_id = ?", id).Delete(&model.InvoiceLine{}).Error
+	})
+}
+
+// Finalize verstuurt een concept: het document krijgt het volgende nummer van zijn soort in het jaar
+// van issueDate en de vervaldatum volgt uit de betaaltermijn. Het volgnummer wordt in dezelfde transactie
+// opgehoogd als het document wordt bijgewerkt; de rij in document_sequences blijft tot de commit
+// vergrendeld, zodat gelijktijdige documenten op elkaar wachten en een mislukte transactie geen gat achterlaat.
+func (r *invoiceRepository) Finalize(id uint, issueDate time.Time) (*model.Invoice, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var invoice model.Invoice
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Omit(clause.Associations).Take(&invoice, id).Error
+		if err != nil {
+			return err
+		}
+		if invoice.Status != model.StatusDraft {
+			return ErrStatusChanged
+		}
+
+		year := issueDate.Year()
+		var sequence int
+		err = tx.Raw(`INSERT INTO document_sequences (type, year, last_number) VALUES (?, ?, 1)
+			ON CONFLICT (type, year) DO UPDATE SET last_number = document_sequences.last_number + 1
+			RETURNING last_number`, invoice.Type, year).Scan(&sequence).Error
+		if err != nil {
+			return err
+		}
+
+		dueDate := issueDate.AddDate(0, 0, invoice.PaymentTermDays)
+		now := time.Now()
+		return tx.Model(&invoice).Updates(map[string]interface{}{
+			"status":     model.StatusSent,
+			"number":     invoice.Type.Number(year, sequence),
+			"year":       year,
+			"sequence":   sequence,
+			"issue_date": issueDate,
+			"due_date":   dueDate,
+			"sent_at":    now,
+			"updated_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.find(r.db, id)
+}
+
+// UpdateStatus zet een document van status from naar status to, als het nog status from heeft
+func (r *invoiceRepository) UpdateStatus(id uint, from, to model.Status, closedAt *time.Time) (*model.Invoice, error) {
+	result := r.db.Model(&model.Invoice{}).Where("id = ? AND status = ?", id, from).Updates(map[string]interface{}{
+		"status":     to,
+		"closed_at":  closedAt,
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrStatusChanged
+	}
+
+	return r.find(r.db, id)
+}
+
+// MarkOverdue zet verstuurde facturen waarvan de vervaldatum voor today ligt op overdue
+func (r *invoiceRepository) MarkOverdue(today time.Time) (int64, error) {
+	result := r.db.Model(&model.Invoice{}).
+		Where("type = ? AND status = ? AND due_date < ?", model.TypeInvoice, model.StatusSent, today.Format("2006-01-02")).
+		Updates(map[string]interface{}{"status": model.StatusOverdue, "updated_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
+
+// SetQuote legt vast uit welke offerte een factuur is gemaakt
+func (r *invoiceRepository) SetQuote(id, quoteID uint) error {
+	return r.db.Model(&model.Invoice{}).Where("id = ?", id).Update("quote_id", quoteID).Error
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"odomosml/internal/invoice/model"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	_ "odomosml/pkg/fieldcrypt" // registreert de serializer voor versleutelde velden
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testYear is het jaar van de testdocumenten, zodat de nummering van echte documenten niet geraakt wordt
+const testYear = 2099
+
+// newTestRepository maakt een InvoiceRepository tegen de database in TEST_DATABASE_DSN, bijv.
+// "host=localhost user=postgres password=postgres dbname=odomosml_test sslmode=disable". Zonder die
+// variabele wordt de test overgeslagen.
+func newTestRepository(t *testing.T) InvoiceRepository {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is niet gezet")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("kon niet verbinden met de database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Invoice{}, &model.InvoiceLine{}, &model.DocumentSequence{}); err != nil {
+		t.Fatalf("kon schema niet migreren: %v", err)
+	}
+
+	cleanup := func() {
+		db.Where("year IN ?", []int{testYear, testYear - 1}).Delete(&model.DocumentSequence{})
+		db.Where("customer_name = ?", "Nummeringstest").Delete(&model.Invoice{})
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	return NewInvoiceRepository(db)
+}
+
+// createDraft maakt een concept met één regel aan
+func createDraft(t *testing.T, repo InvoiceRepository, docType model.DocumentType) *model.Invoice {
+	t.Helper()
+	invoice, err := repo.Create(&model.Invoice{
+		Type:            docType,
+		Status:          model.StatusDraft,
+		CustomerID:      1,
+		CustomerName:    "Nummeringstest",
+		Currency:        "EUR",
+		PaymentTermDays: 30,
+		Lines:           []model.InvoiceLine{{Description: "Onderhoud", Quantity: 1, UnitPriceCents: 1000, VATRate: 21, NetCents: 1000}},
+	})
+	if err != nil {
+		t.Fatalf("Create gaf fout: %v", err)
+	}
+	return invoice
+}
+
+func TestFinalizeNumbering(t *testing.T) {
+	repo := newTestRepository(t)
+	issueDate := time.Date(testYear, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Gelijktijdig versturen geeft elk document een eigen nummer, zonder gaten
+	const count = 20
+	drafts := make([]*model.Invoice, count)
+	for i := range drafts {
+		drafts[i] = createDraft(t, repo, model.TypeInvoice)
+	}
+
+	var wg sync.WaitGroup
+	sequences := make([]int, count)
+	errs := make([]error, count)
+	for i, draft := range drafts {
+		wg.Add(1)
+		go func(i int, id uint) {
+			defer wg.Done()
+			invoice, err := repo.Finalize(id, issueDate)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			sequences[i] = invoice.Sequence
+			if invoice.Number != model.TypeInvoice.Number(testYear, invoice.Sequence) || invoice.Year != testYear {
+				errs[i] = fmt.Errorf("nummer %s in jaar %d bij volgnummer %d", invoice.Number, invoice.Year, invoice.Sequence)
+			}
+		}(i, draft.ID)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("Finalize gaf fout: %v", err)
+		}
+	}
+	sort.Ints(sequences)
+	for i, sequence := range sequences {
+		if sequence != i+1 {
+			t.Fatalf("volgnummers %v, verwacht 1 t/m %d", sequences, count)
+		}
+	}
+
+	// Een document dat al verstuurd is krijgt geen tweede nummer en laat geen gat achter
+	if _, err := repo.Finalize(drafts[0].ID, issueDate); !errors.Is(err, ErrStatusChanged) {
+		t.Errorf("Finalize van een verstuurd document gaf %v, verwacht ErrStatusChanged", err)
+	}
+	next, err := repo.Finalize(createDraft(t, repo, model.TypeInvoice).ID, issueDate)
+	if err != nil {
+		t.Fatalf("Finalize gaf fout: %v", err)
+	}
+	if next.Number != fmt.Sprintf("F%d-%04d", testYear, count+1) {
+		t.Errorf("volgende nummer %s, verwacht F%d-%04d", next.Number, testYear, count+1)
+	}
+	if want := issueDate.AddDate(0, 0, 30); next.DueDate == nil || !next.DueDate.Equal(want) {
+		t.Errorf("vervaldatum %v, verwacht %v", next.DueDate, want)
+	}
+
+	// Offertes en andere jaren hebben hun eigen reeks
+	tests := []struct {
+		docType model.DocumentType
+		date    time.Time
+		want    string
+	}{
+		{model.TypeQuote, issueDate, fmt.Sprintf("OF%d-0001", testYear)},
+		{model.TypeQuote, issueDate, fmt.Sprintf("OF%d-0002", testYear)},
+		{model.TypeInvoice, issueDate.AddDate(-1, 0, 0), fmt.Sprintf("F%d-0001", testYear-1)},
+		{model.TypeInvoice, issueDate, fmt.Sprintf("F%d-%04d", testYear, count+2)},
+	}
+	for _, tt := range tests {
+		invoice, err := repo.Finalize(createDraft(t, repo, tt.docType).ID, tt.date)
+		if err != nil {
+			t.Fatalf("Finalize gaf fout: %v", err)
+		}
+		if invoice.Number != tt.want {
+			t.Errorf("%s op %s kreeg nummer %s, verwacht %s", tt.docType, tt.date.Format("2006-01-02"), invoice.Number, tt.want)
+		}
+	}
+}
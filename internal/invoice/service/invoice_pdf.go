@@ -0,0 +1,475 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"odomosml/internal/invoice/model"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Opmaak van de PDF in millimeters op A4
+const (
+	pageMargin   = 20.0
+	footerMargin = 25.0
+	lineHeight   = 5.0
+)
+
+// Kolommen van de regeltabel: breedte samen 170 mm, de breedte tussen de marges
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Omschrijving", 80, "L"},
+	{"Aantal", 20, "R"},
+	{"Prijs", 25, "R"},
+	{"BTW", 15, "R"},
+	{"Bedrag", 30, "R"},
+}
+
+// Company zijn de gegevens van het eigen bedrijf op offertes en facturen
+type Company struct {
+	Name      string
+	Address   string
+	KvK       string
+	VATNumber string
+	IBAN      string
+	Email     string
+}
+
+// PDFTemplate is de configureerbare opmaak van offertes en facturen. De teksten zijn text/template
+// sjablonen met de velden Number, Total, IssueDate, DueDate, Reference en Company.
+type PDFTemplate struct {
+	AccentColor   string `json:"accent_color"`
+	Intro         string `json:"intro"`
+	InvoiceFooter string `json:"invoice_footer"`
+	QuoteFooter   string `json:"quote_footer"`
+}
+
+// defaultTemplate wordt gebruikt voor elk veld dat het sjabloonbestand leeg laat
+var defaultTemplate = PDFTemplate{
+	AccentColor:   "#1F4E79",
+	InvoiceFooter: "Wij verzoeken u het bedrag van {{.Total}} vóór {{.DueDate}} over te maken op {{.Company.IBAN}} onder vermelding van {{.Number}}.",
+	QuoteFooter:   "Deze offerte is geldig tot en met {{.DueDate}}.",
+}
+
+// templateData zijn de velden die in de teksten van het sjabloon beschikbaar zijn
+type templateData struct {
+	Number    string
+	Total     string
+	IssueDate string
+	DueDate   string
+	Reference string
+	Company   Company
+}
+
+// PDFRenderer maakt PDF's van offertes en facturen
+type PDFRenderer struct {
+	company  Company
+	logo     []byte
+	logoType string
+	accent   [3]int
+	intro    *template.Template
+	footers  map[model.DocumentType]*template.Template
+}
+
+// NewPDFRenderer maakt een PDFRenderer. Het logo en het sjabloon worden één keer ingelezen; een
+// ontbrekend of ongeldig bestand wordt gelogd en de PDF wordt dan zonder logo of met de standaardteksten gemaakt.
+func NewPDFRenderer(company Company, logoPath, templatePath string) *PDFRenderer {
+	r := &PDFRenderer{
+		company: company,
+		footers: make(map[model.DocumentType]*template.Template),
+	}
+
+	if logoPath != "" {
+		if err := r.loadLogo(logoPath); err != nil {
+			log.Printf("Waarschuwing: Kon logo voor offertes en facturen niet laden: %v", err)
+		}
+	}
+
+	tmpl := loadTemplate(templatePath)
+
+	accent, err := parseColor(tmpl.AccentColor)
+	if err != nil {
+		log.Printf("Waarschuwing: Ongeldige accentkleur in sjabloon: %v", err)
+		accent, _ = parseColor(defaultTemplate.AccentColor)
+	}
+	r.accent = accent
+
+	r.intro = parseText("intro", tmpl.Intro, defaultTemplate.Intro)
+	r.footers[model.TypeInvoice] = parseText("invoice_footer", tmpl.InvoiceFooter, defaultTemplate.InvoiceFooter)
+	r.footers[model.TypeQuote] = parseText("quote_footer", tmpl.QuoteFooter, defaultTemplate.QuoteFooter)
+
+	return r
+}
+
+// Render schrijft een offerte of factuur als PDF naar w
+func (r *PDFRenderer) Render(invoice *model.Invoice, w io.Writer) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("cp1252")
+
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, footerMargin)
+	// Een vaste aanmaakdatum maakt dezelfde versie van een document byte voor byte gelijk
+	pdf.SetCreationDate(invoice.UpdatedAt)
+	pdf.SetTitle(documentTitle(invoice), true)
+	pdf.SetAuthor(r.company.Name, true)
+
+	data := r.templateData(invoice)
+	// Een concept heeft nog geen nummer en datums, dus ook geen betaal- of geldigheidstekst
+	footer := ""
+	if invoice.Number != "" {
+		footer = execute(r.footers[invoice.Type], data)
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-footerMargin + 5)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(100, 100, 100)
+		if footer != "" {
+			pdf.MultiCell(0, 4, tr(footer), "", "C", false)
+		}
+		pdf.CellFormat(0, 4, fmt.Sprintf("Pagina %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	r.writeHeader(pdf, tr)
+	r.writeAddresses(pdf, tr, invoice)
+
+	if intro := execute(r.intro, data); intro != "" {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, lineHeight, tr(intro), "", "L", false)
+		pdf.Ln(4)
+	}
+
+	r.writeLines(pdf, tr, invoice)
+	r.writeTotals(pdf, tr, invoice)
+
+	if invoice.Notes != "" {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, lineHeight, tr(invoice.Notes), "", "L", false)
+	}
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("kon PDF niet maken: %v", err)
+	}
+	return pdf.Output(w)
+}
+
+// writeHeader schrijft het logo links en de bedrijfsgegevens rechts bovenaan de eerste pagina
+func (r *PDFRenderer) writeHeader(pdf *gofpdf.Fpdf, tr func(string) string) {
+	if r.logo != nil {
+		options := gofpdf.ImageOptions{ImageType: r.logoType, ReadDpi: true}
+		pdf.RegisterImageOptionsReader("logo", options, bytes.NewReader(r.logo))
+		pdf.ImageOptions("logo", pageMargin, pageMargin, 0, 20, false, options, 0, "")
+	}
+
+	pdf.SetXY(120, pageMargin)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(70, lineHeight, tr(r.company.Name), "", 2, "R", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range []string{
+		r.company.Address,
+		labeled("KvK", r.company.KvK),
+		labeled("BTW", r.company.VATNumber),
+		labeled("IBAN", r.company.IBAN),
+		r.company.Email,
+	} {
+		if line != "" {
+			pdf.CellFormat(70, 4.5, tr(line), "", 2, "R", false, 0, "")
+		}
+	}
+}
+
+// writeAddresses schrijft de titel, de klantgegevens en de documentgegevens
+func (r *PDFRenderer) writeAddresses(pdf *gofpdf.Fpdf, tr func(string) string, invoice *model.Invoice) {
+	pdf.SetXY(pageMargin, 55)
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.SetTextColor(r.accent[0], r.accent[1], r.accent[2])
+	pdf.CellFormat(0, 10, tr(documentTitle(invoice)), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+
+	top := pdf.GetY() + 4
+
+	// Klant links
+	pdf.SetXY(pageMargin, top)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(90, lineHeight, tr(invoice.CustomerName), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range []string{
+		invoice.CustomerAddress,
+		labeled("KvK", invoice.CustomerKvK),
+		labeled("BTW", invoice.CustomerVATNumber),
+	} {
+		if line != "" {
+			pdf.MultiCell(90, 4.5, tr(line), "", "L", false)
+		}
+	}
+	customerBottom := pdf.GetY()
+
+	// Documentgegevens rechts
+	dueLabel := "Vervaldatum"
+	if invoice.Type == model.TypeQuote {
+		dueLabel = "Geldig tot"
+	}
+	pdf.SetXY(120, top)
+	for _, row := range [][2]string{
+		{invoice.Type.Label() + "nummer", invoice.Number},
+		{"Datum", formatDate(invoice.IssueDate)},
+		{dueLabel, formatDate(invoice.DueDate)},
+		{"Uw kenmerk", invoice.Reference},
+	} {
+		if row[1] == "" {
+			continue
+		}
+		pdf.SetX(120)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(30, 4.5, tr(row[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(40, 4.5, tr(row[1]), "", 1, "R", false, 0, "")
+	}
+
+	if customerBottom > pdf.GetY() {
+		pdf.SetY(customerBottom)
+	}
+	pdf.Ln(10)
+}
+
+// writeLines schrijft de regeltabel; bij een nieuwe pagina wordt de kop herhaald
+func (r *PDFRenderer) writeLines(pdf *gofpdf.Fpdf, tr func(string) string, invoice *model.Invoice) {
+	_, pageHeight := pdf.GetPageSize()
+
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(r.accent[0], r.accent[1], r.accent[2])
+		pdf.SetTextColor(255, 255, 255)
+		for _, column := range pdfColumns {
+			pdf.CellFormat(column.width, 7, tr(column.title), "", 0, column.align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Helvetica", "", 9)
+	}
+	header()
+
+	for _, line := range invoice.Lines {
+		description := pdf.SplitLines([]byte(tr(line.Description)), pdfColumns[0].width-2)
+		height := float64(len(description))*lineHeight + 1
+		if pdf.GetY()+height > pageHeight-footerMargin {
+			pdf.AddPage()
+			header()
+		}
+
+		x, y := pdf.GetXY()
+		pdf.MultiCell(pdfColumns[0].width, lineHeight, tr(line.Description), "", "L", false)
+		pdf.SetXY(x+pdfColumns[0].width, y)
+
+		values := []string{
+			formatQuantity(line.Quantity),
+			formatMoney(line.UnitPriceCents, invoice.Currency),
+			fmt.Sprintf("%d%%", line.VATRate),
+			formatMoney(line.NetCents, invoice.Currency),
+		}
+		for i, value := range values {
+			column := pdfColumns[i+1]
+			pdf.CellFormat(column.width, lineHeight, tr(value), "", 0, column.align, false, 0, "")
+		}
+
+		pdf.SetXY(x, y+height)
+		pdf.SetDrawColor(220, 220, 220)
+		pdf.Line(pageMargin, y+height-0.5, pageMargin+170, y+height-0.5)
+	}
+}
+
+// writeTotals schrijft het subtotaal, de BTW per tarief en het totaal rechts onder de regels
+func (r *PDFRenderer) writeTotals(pdf *gofpdf.Fpdf, tr func(string) string, invoice *model.Invoice) {
+	pdf.Ln(4)
+
+	row := func(label, amount string, bold bool) {
+		style := ""
+		if bold {
+			style = "B"
+		}
+		pdf.SetX(pageMargin + 80)
+		pdf.SetFont("Helvetica", style, 9)
+		pdf.CellFormat(60, lineHeight+1, tr(label), "", 0, "L", false, 0, "")
+		pdf.CellFormat(30, lineHeight+1, tr(amount), "", 1, "R", false, 0, "")
+	}
+
+	row("Subtotaal", formatMoney(invoice.SubtotalCents, invoice.Currency), false)
+	for _, vat := range invoice.VATBreakdown {
+		row(fmt.Sprintf("BTW %d%% over %s", vat.Rate, formatMoney(vat.NetCents, invoice.Currency)),
+			formatMoney(vat.VATCents, invoice.Currency), false)
+	}
+
+	pdf.SetDrawColor(r.accent[0], r.accent[1], r.accent[2])
+	pdf.Line(pageMargin+80, pdf.GetY(), pageMargin+170, pdf.GetY())
+	row("Totaal", formatMoney(invoice.TotalCents, invoice.Currency), true)
+}
+
+// templateData vult de velden voor de teksten van het sjabloon
+func (r *PDFRenderer) templateData(invoice *model.Invoice) templateData {
+	return templateData{
+		Number:    invoice.Number,
+		Total:     formatMoney(invoice.TotalCents, invoice.Currency),
+		IssueDate: formatDate(invoice.IssueDate),
+		DueDate:   formatDate(invoice.DueDate),
+		Reference: invoice.Reference,
+		Company:   r.company,
+	}
+}
+
+// loadLogo leest het logo in; alleen PNG en JPEG worden ondersteund
+func (r *PDFRenderer) loadLogo(path string) error {
+	logo, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch http.DetectContentType(logo) {
+	case "image/png":
+		r.logoType = "PNG"
+	case "image/jpeg":
+		r.logoType = "JPG"
+	default:
+		return fmt.Errorf("%s is geen PNG of JPEG", path)
+	}
+	r.logo = logo
+	return nil
+}
+
+// loadTemplate leest het sjabloon; lege velden krijgen de standaardwaarde
+func loadTemplate(path string) PDFTemplate {
+	tmpl := defaultTemplate
+	if path == "" {
+		return tmpl
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Waarschuwing: Kon sjabloon voor offertes en facturen niet laden: %v", err)
+		return tmpl
+	}
+
+	var custom PDFTemplate
+	if err := json.Unmarshal(content, &custom); err != nil {
+		log.Printf("Waarschuwing: Ongeldig sjabloon voor offertes en facturen %s: %v", path, err)
+		return tmpl
+	}
+
+	if custom.AccentColor != "" {
+		tmpl.AccentColor = custom.AccentColor
+	}
+	if custom.Intro != "" {
+		tmpl.Intro = custom.Intro
+	}
+	if custom.InvoiceFooter != "" {
+		tmpl.InvoiceFooter = custom.InvoiceFooter
+	}
+	if custom.QuoteFooter != "" {
+		tmpl.QuoteFooter = custom.QuoteFooter
+	}
+	return tmpl
+}
+
+// parseText parseert een tekst uit het sjabloon, of bij een fout de standaardtekst
+func parseText(name, text, fallback string) *template.Template {
+	parsed, err := template.New(name).Parse(text)
+	if err != nil {
+		log.Printf("Waarschuwing: Ongeldige tekst '%s' in sjabloon: %v", name, err)
+		parsed = template.Must(template.New(name).Parse(fallback))
+	}
+	return parsed
+}
+
+// execute vult een tekst uit het sjabloon; een fout wordt gelogd en levert een lege tekst op
+func execute(tmpl *template.Template, data templateData) string {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Printf("Waarschuwing: Kon tekst '%s' uit sjabloon niet vullen: %v", tmpl.Name(), err)
+		return ""
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// parseColor parseert een kleur in de vorm #RRGGBB
+func parseColor(color string) ([3]int, error) {
+	var rgb [3]int
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) != 6 {
+		return rgb, fmt.Errorf("'%s' is geen kleur in de vorm #RRGGBB", color)
+	}
+	for i := range rgb {
+		value, err := strconv.ParseUint(hex[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return rgb, fmt.Errorf("'%s' is geen kleur in de vorm #RRGGBB", color)
+		}
+		rgb[i] = int(value)
+	}
+	return rgb, nil
+}
+
+// documentTitle geeft de titel van een document, bijv. "Factuur F2025-0001" of "Offerte (concept)"
+func documentTitle(invoice *model.Invoice) string {
+	if invoice.Number == "" {
+		return invoice.Type.Label() + " (concept)"
+	}
+	return invoice.Type.Label() + " " + invoice.Number
+}
+
+// labeled geeft "label: value", of een lege tekst als value leeg is
+func labeled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + ": " + value
+}
+
+// formatDate formatteert een datum als dd-mm-jjjj
+func formatDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("02-01-2006")
+}
+
+// formatQuantity formatteert een aantal met een decimale komma en zonder overbodige nullen
+func formatQuantity(quantity float64) string {
+	return strings.Replace(strconv.FormatFloat(quantity, 'f', -1, 64), ".", ",", 1)
+}
+
+// formatMoney formatteert een bedrag in centen op zijn Nederlands, bijv. "€ 1.234,56"
+func formatMoney(cents int64, currency string) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	whole := strconv.FormatInt(cents/100, 10)
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	symbol := currency
+	if currency == "EUR" {
+		symbol = "€"
+	}
+	return fmt.Sprintf("%s %s%s,%02d", symbol, sign, grouped.String(), cents%100)
+}
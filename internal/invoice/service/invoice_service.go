@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/internal/invoice/model"
+	"odomosml/internal/invoice/repository"
+	productService "odomosml/internal/product/service"
+	"odomosml/pkg/validation"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Grenzen aan een offerte of factuur
+const (
+	maxLines           = 200
+	maxPaymentTermDays = 365
+)
+
+// currencyPattern valideert een ISO 4217 valutacode
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// InvoiceService definieert de interface voor de offerte- en factuurservice
+type InvoiceService interface {
+	GetInvoices(filter model.InvoiceFilter) ([]model.Invoice, int64, error)
+	GetInvoice(docType model.DocumentType, id string) (*model.Invoice, error)
+	CreateInvoice(invoice *model.Invoice) (*model.Invoice, error)
+	UpdateInvoice(invoice *model.Invoice) (*model.Invoice, error)
+	DeleteInvoice(docType model.DocumentType, id string) (map[string]interface{}, error)
+	ChangeStatus(docType model.DocumentType, id string, status model.Status) (*model.Invoice, error)
+	CreateInvoiceFromQuote(quoteID string, userID uint, username string) (*model.Invoice, error)
+	RenderPDF(docType model.DocumentType, id string, w io.Writer) (*model.Invoice, error)
+	MarkOverdue(ctx context.Context) error
+}
+
+// invoiceService implementeert de InvoiceService interface
+type invoiceService struct {
+	repo              repository.InvoiceRepository
+	customerRepo      customerRepo.CustomerRepository
+	prices            productService.PriceService
+	renderer          *PDFRenderer
+	paymentTermDays   int
+	quoteValidityDays int
+}
+
+// NewInvoiceService maakt een nieuwe InvoiceService instantie. paymentTermDays en quoteValidityDays zijn
+// de standaard betaaltermijn van facturen en geldigheid van offertes.
+func NewInvoiceService(repo repository.InvoiceRepository, customerRepo customerRepo.CustomerRepository, prices productService.PriceService,
+	renderer *PDFRenderer, paymentTermDays, quoteValidityDays int) InvoiceService {
+	return &invoiceService{
+		repo:              repo,
+		customerRepo:      customerRepo,
+		prices:            prices,
+		renderer:          renderer,
+		paymentTermDays:   paymentTermDays,
+		quoteValidityDays: quoteValidityDays,
+	}
+}
+
+// GetInvoices haalt offertes of facturen op
+func (s *invoiceService) GetInvoices(filter model.InvoiceFilter) ([]model.Invoice, int64, error) {
+	for _, status := range filter.Statuses {
+		if !isKnownStatus(filter.Type, status) {
+			return nil, 0, fmt.Errorf("onbekende status '%s'", status)
+		}
+	}
+
+	// Valideer paginering
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 10 // Default page size
+	}
+
+	return s.repo.FindAll(filter)
+}
+
+// GetInvoice haalt een offerte of factuur met de regels op
+func (s *invoiceService) GetInvoice(docType model.DocumentType, id string) (*model.Invoice, error) {
+	return s.find(docType, id)
+}
+
+// CreateInvoice maakt een concept aan met de actuele gegevens van de klant
+func (s *invoiceService) CreateInvoice(invoice *model.Invoice) (*model.Invoice, error) {
+	invoice.ID = 0
+	invoice.Status = model.StatusDraft
+	invoice.Number, invoice.Year, invoice.Sequence = "", 0, 0
+	invoice.IssueDate, invoice.DueDate, invoice.SentAt, invoice.ClosedAt = nil, nil, nil, nil
+	if invoice.Type == model.TypeInvoice {
+		invoice.QuoteID = nil
+	}
+	if invoice.PaymentTermDays == 0 {
+		invoice.PaymentTermDays = s.defaultTerm(invoice.Type)
+	}
+
+	if err := s.prepare(invoice); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(invoice)
+}
+
+// UpdateInvoice werkt een concept bij; de regels worden vervangen door invoice.Lines
+func (s *invoiceService) UpdateInvoice(invoice *model.Invoice) (*model.Invoice, error) {
+	existing, err := s.find(invoice.Type, strconv.FormatUint(uint64(invoice.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status != model.StatusDraft {
+		return nil, repository.ErrNotDraft
+	}
+
+	if err := s.prepare(invoice); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Update(invoice)
+}
+
+// DeleteInvoice verwijdert een concept en retourneert de data voor audit logging. Verstuurde
+// documenten blijven altijd bewaard, zodat de nummering geen gaten krijgt.
+func (s *invoiceService) DeleteInvoice(docType model.DocumentType, id string) (map[string]interface{}, error) {
+	invoice, err := s.find(docType, id)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.Status != model.StatusDraft {
+		return nil, repository.ErrNotDraft
+	}
+
+	invoiceData := invoice.ToAuditMap()
+
+	if err := s.repo.Delete(invoice.ID); err != nil {
+		return nil, err
+	}
+
+	return invoiceData, nil
+}
+
+// ChangeStatus zet een document in een andere status volgens de toegestane overgangen. Bij het
+// versturen van een concept krijgt het document zijn nummer, factuurdatum en vervaldatum.
+func (s *invoiceService) ChangeStatus(docType model.DocumentType, id string, status model.Status) (*model.Invoice, error) {
+	invoice, err := s.GetInvoice(docType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !docType.Allows(invoice.Status, status) {
+		allowed := docType.Transitions(invoice.Status)
+		if len(allowed) == 0 {
+			return nil, validation.New("status", "vanuit status '%s' zijn geen overgangen toegestaan", invoice.Status)
+		}
+		names := make([]string, len(allowed))
+		for i, next := range allowed {
+			names[i] = string(next)
+		}
+		return nil, validation.New("status", "overgang van '%s' naar '%s' is niet toegestaan; toegestaan: %s",
+			invoice.Status, status, strings.Join(names, ", "))
+	}
+
+	if status == model.StatusSent {
+		if len(invoice.Lines) == 0 {
+			return nil, validation.New("lines", "een %s zonder regels kan niet verstuurd worden", strings.ToLower(docType.Label()))
+		}
+		return s.repo.Finalize(invoice.ID, today())
+	}
+
+	var closedAt *time.Time
+	switch status {
+	case model.StatusPaid, model.StatusAccepted, model.StatusDeclined:
+		now := time.Now()
+		closedAt = &now
+	}
+	return s.repo.UpdateStatus(invoice.ID, invoice.Status, status, closedAt)
+}
+
+// CreateInvoiceFromQuote maakt een conceptfactuur met de regels van een geaccepteerde offerte
+func (s *invoiceService) CreateInvoiceFromQuote(quoteID string, userID uint, username string) (*model.Invoice, error) {
+	quote, err := s.find(model.TypeQuote, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if quote.Status != model.StatusAccepted {
+		return nil, errors.New("alleen van een geaccepteerde offerte kan een factuur gemaakt worden")
+	}
+
+	invoice := &model.Invoice{
+		Type:          model.TypeInvoice,
+		CustomerID:    quote.CustomerID,
+		Reference:     quote.Reference,
+		Notes:         quote.Notes,
+		Currency:      quote.Currency,
+		CreatedByID:   userID,
+		CreatedByName: username,
+	}
+	for _, line := range quote.Lines {
+		invoice.Lines = append(invoice.Lines, model.InvoiceLine{
+			ProductID:      line.ProductID,
+			Description:    line.Description,
+			Quantity:       line.Quantity,
+			UnitPriceCents: line.UnitPriceCents,
+			VATRate:        line.VATRate,
+		})
+	}
+
+	created, err := s.CreateInvoice(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	// CreateInvoice wist de offerte van een factuur, zodat alleen deze route de koppeling legt
+	created.QuoteID = &quote.ID
+	if err := s.repo.SetQuote(created.ID, quote.ID); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// RenderPDF schrijft een offerte of factuur als PDF naar w
+func (s *invoiceService) RenderPDF(docType model.DocumentType, id string, w io.Writer) (*model.Invoice, error) {
+	invoice, err := s.GetInvoice(docType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.renderer.Render(invoice, w); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// find haalt een document op en controleert dat het van het gevraagde soort is
+func (s *invoiceService) find(docType model.DocumentType, id string) (*model.Invoice, error) {
+	invoice, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.Type != docType {
+		return nil, errors.New("document niet gevonden")
+	}
+	return invoice, nil
+}
+
+// prepare valideert een concept, neemt de gegevens van de klant over en berekent de bedragen
+func (s *invoiceService) prepare(invoice *model.Invoice) error {
+	var problems validation.Errors
+
+	customer, err := s.customerRepo.FindByID(strconv.FormatUint(uint64(invoice.CustomerID), 10))
+	if err != nil || customer == nil {
+		problems = append(problems, validation.FieldError{Field: "customer_id", Message: fmt.Sprintf("klant %d bestaat niet", invoice.CustomerID)})
+	} else {
+		invoice.CustomerName = customer.Name
+		invoice.CustomerAddress = customer.Address
+		invoice.CustomerEmail = customer.Email
+		invoice.CustomerKvK = customer.KvKNumber
+		invoice.CustomerVATNumber = customer.VATNumber
+	}
+
+	invoice.Currency = strings.ToUpper(strings.TrimSpace(invoice.Currency))
+	if invoice.Currency == "" {
+		invoice.Currency = "EUR"
+	}
+	if !currencyPattern.MatchString(invoice.Currency) {
+		problems = append(problems, validation.FieldError{Field: "currency", Message: "valuta moet een ISO 4217 code zijn, bijv. EUR"})
+	}
+
+	if invoice.PaymentTermDays < 0 || invoice.PaymentTermDays > maxPaymentTermDays {
+		problems = append(problems, validation.FieldError{Field: "payment_term_days", Message: fmt.Sprintf("termijn moet tussen 0 en %d dagen liggen", maxPaymentTermDays)})
+	}
+
+	invoice.Reference = strings.TrimSpace(invoice.Reference)
+	if len(invoice.Reference) > 100 {
+		problems = append(problems, validation.FieldError{Field: "reference", Message: "kenmerk mag maximaal 100 tekens bevatten"})
+	}
+
+	if len(invoice.Lines) > maxLines {
+		problems = append(problems, validation.FieldError{Field: "lines", Message: fmt.Sprintf("maximaal %d regels", maxLines)})
+	}
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		field := fmt.Sprintf("lines.%d", i)
+
+		if line.ProductID != nil {
+			if err := s.applyProduct(line, invoice.CustomerID); err != nil {
+				problems = append(problems, validation.FieldError{Field: field + ".product_id", Message: err.Error()})
+				continue
+			}
+		}
+
+		line.Description = strings.TrimSpace(line.Description)
+		if line.Description == "" {
+			problems = append(problems, validation.FieldError{Field: field + ".description", Message: "omschrijving is verplicht"})
+		}
+		if line.Quantity == 0 {
+			problems = append(problems, validation.FieldError{Field: field + ".quantity", Message: "aantal mag niet 0 zijn"})
+		}
+		if !model.IsValidVATRate(line.VATRate) {
+			problems = append(problems, validation.FieldError{Field: field + ".vat_rate", Message: "BTW-tarief moet 21, 9 of 0 zijn"})
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	invoice.Calculate()
+	return nil
+}
+
+// applyProduct vult een regel met een actief product aan: het BTW-tarief komt altijd van het product, de
+// omschrijving en de prijs alleen als de regel ze niet heeft. De prijs is de prijs die vandaag voor de
+// klant geldt, dus een prijsafspraak gaat voor de standaardprijs.
+func (s *invoiceService) applyProduct(line *model.InvoiceLine, customerID uint) error {
+	price, err := s.prices.ResolvePrice(*line.ProductID, customerID, today())
+	if err != nil {
+		return fmt.Errorf("product %d: %v", *line.ProductID, err)
+	}
+	if !price.Active {
+		return fmt.Errorf("product %s is niet actief", price.SKU)
+	}
+
+	if strings.TrimSpace(line.Description) == "" {
+		line.Description = price.Description
+	}
+	if line.UnitPriceCents == 0 {
+		line.UnitPriceCents = price.PriceCents
+	}
+	line.VATRate = price.VATRate
+	return nil
+}
+
+// MarkOverdue zet verstuurde facturen over de vervaldatum op overdue. Draait als achtergrondjob,
+// zodat het ophalen van documenten geen schrijfactie is.
+func (s *invoiceService) MarkOverdue(ctx context.Context) error {
+	marked, err := s.repo.MarkOverdue(today())
+	if err != nil {
+		return fmt.Errorf("kon verlopen facturen niet bijwerken: %w", err)
+	}
+	if marked > 0 {
+		log.Printf("%d facturen over de vervaldatum op overdue gezet", marked)
+	}
+	return nil
+}
+
+// defaultTerm geeft de standaard betaaltermijn of geldigheid in dagen
+func (s *invoiceService) defaultTerm(docType model.DocumentType) int {
+	if docType == model.TypeQuote {
+		return s.quoteValidityDays
+	}
+	return s.paymentTermDays
+}
+
+// isKnownStatus controleert of een status bij dit soort document voorkomt
+func isKnownStatus(docType model.DocumentType, status model.Status) bool {
+	if status == model.StatusDraft {
+		return true
+	}
+	for _, from := range []model.Status{model.StatusDraft, model.StatusSent, model.StatusOverdue} {
+		if docType.Allows(from, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// today geeft de huidige datum zonder tijd
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	customerModel "odomosml/internal/customer/model"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/internal/invoice/model"
+	"odomosml/internal/invoice/repository"
+	productModel "odomosml/internal/product/model"
+	"odomosml/pkg/validation"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeInvoiceRepository bewaart documenten in het geheugen en nummert ze bij Finalize per soort en jaar
+type fakeInvoiceRepository struct {
+	repository.InvoiceRepository
+	invoices  map[uint]*model.Invoice
+	sequences map[string]int
+	quotes    map[uint]uint
+	finalized []time.Time
+}
+
+func newFakeInvoiceRepository(invoices ...*model.Invoice) *fakeInvoiceRepository {
+	repo := &fakeInvoiceRepository{invoices: map[uint]*model.Invoice{}, sequences: map[string]int{}, quotes: map[uint]uint{}}
+	for _, invoice := range invoices {
+		repo.invoices[invoice.ID] = invoice
+	}
+	return repo
+}
+
+func (r *fakeInvoiceRepository) FindByID(id string) (*model.Invoice, error) {
+	idInt, _ := strconv.Atoi(id)
+	invoice, ok := r.invoices[uint(idInt)]
+	if !ok {
+		return nil, errors.New("document niet gevonden")
+	}
+	copied := *invoice
+	return &copied, nil
+}
+
+func (r *fakeInvoiceRepository) Create(invoice *model.Invoice) (*model.Invoice, error) {
+	invoice.ID = uint(len(r.invoices) + 100)
+	r.invoices[invoice.ID] = invoice
+	return invoice, nil
+}
+
+func (r *fakeInvoiceRepository) Finalize(id uint, issueDate time.Time) (*model.Invoice, error) {
+	invoice := r.invoices[id]
+	key := fmt.Sprintf("%s-%d", invoice.Type, issueDate.Year())
+	r.sequences[key]++
+	invoice.Status = model.StatusSent
+	invoice.Year, invoice.Sequence = issueDate.Year(), r.sequences[key]
+	invoice.Number = invoice.Type.Number(invoice.Year, invoice.Sequence)
+	r.finalized = append(r.finalized, issueDate)
+	return invoice, nil
+}
+
+func (r *fakeInvoiceRepository) UpdateStatus(id uint, from, to model.Status, closedAt *time.Time) (*model.Invoice, error) {
+	invoice := r.invoices[id]
+	if invoice.Status != from {
+		return nil, repository.ErrStatusChanged
+	}
+	invoice.Status, invoice.ClosedAt = to, closedAt
+	return invoice, nil
+}
+
+func (r *fakeInvoiceRepository) SetQuote(id, quoteID uint) error {
+	r.quotes[id] = quoteID
+	return nil
+}
+
+// fakeCustomerRepository kent alleen klant 1
+type fakeCustomerRepository struct {
+	customerRepo.CustomerRepository
+}
+
+func (fakeCustomerRepository) FindByID(id string) (*customerModel.Customer, error) {
+	if id != "1" {
+		return nil, errors.New("klant niet gevonden")
+	}
+	return &customerModel.Customer{ID: 1, Name: "Bakkerij Jansen", Address: "Dorpsstraat 1, Utrecht", Email: "info@jansen.nl", KvKNumber: "12345678", VATNumber: "NL123456789B01"}, nil
+}
+
+// fakePrices geeft de prijzen van producten op ID
+type fakePrices map[uint]productModel.Price
+
+func (p fakePrices) ResolvePrice(productID, customerID uint, date time.Time) (*productModel.Price, error) {
+	price, ok := p[productID]
+	if !ok {
+		return nil, errors.New("product niet gevonden")
+	}
+	price.CustomerID = customerID
+	return &price, nil
+}
+
+func newTestInvoiceService(repo *fakeInvoiceRepository) *invoiceService {
+	prices := fakePrices{
+		1: {SKU: "ONDH-UUR", Description: "Onderhoud per uur", PriceCents: 6500, VATRate: 21, Active: true, Source: "agreement"},
+		2: {SKU: "BROOD", Description: "Brood", PriceCents: 350, VATRate: 9, Active: true, Source: "default"},
+		3: {SKU: "OUD", Description: "Vervallen", PriceCents: 100, VATRate: 21},
+	}
+	return NewInvoiceService(repo, fakeCustomerRepository{}, prices, nil, 30, 14).(*invoiceService)
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func TestCreateInvoice(t *testing.T) {
+	tests := []struct {
+		name       string
+		invoice    model.Invoice
+		wantFields []string
+		want       func(t *testing.T, created *model.Invoice)
+	}{
+		{
+			name: "klantgegevens, standaardtermijn en bedragen",
+			invoice: model.Invoice{Type: model.TypeInvoice, CustomerID: 1, Currency: " eur ", Number: "F2025-0099", Sequence: 99, QuoteID: uintPtr(5), Lines: []model.InvoiceLine{
+				{Description: "Onderhoud maart", Quantity: 2.5, UnitPriceCents: 8500, VATRate: 21},
+			}},
+			want: func(t *testing.T, created *model.Invoice) {
+				if created.Status != model.StatusDraft || created.Number != "" || created.Sequence != 0 || created.QuoteID != nil {
+					t.Errorf("concept = status %s, nummer %q, volgnummer %d, offerte %v", created.Status, created.Number, created.Sequence, created.QuoteID)
+				}
+				if created.CustomerName != "Bakkerij Jansen" || created.CustomerEmail != "info@jansen.nl" || created.CustomerVATNumber != "NL123456789B01" {
+					t.Errorf("klantgegevens niet overgenomen: %+v", created)
+				}
+				if created.Currency != "EUR" || created.PaymentTermDays != 30 {
+					t.Errorf("valuta %s, termijn %d; verwacht EUR, 30", created.Currency, created.PaymentTermDays)
+				}
+				if created.SubtotalCents != 21250 || created.VATCents != 4463 || created.TotalCents != 25713 {
+					t.Errorf("bedragen %d + %d = %d", created.SubtotalCents, created.VATCents, created.TotalCents)
+				}
+			},
+		},
+		{
+			name:    "offerte krijgt de standaard geldigheid",
+			invoice: model.Invoice{Type: model.TypeQuote, CustomerID: 1},
+			want: func(t *testing.T, created *model.Invoice) {
+				if created.PaymentTermDays != 14 {
+					t.Errorf("geldigheid %d, verwacht 14", created.PaymentTermDays)
+				}
+			},
+		},
+		{
+			name: "producten: prijs van de klant, tarief altijd van het product",
+			invoice: model.Invoice{Type: model.TypeInvoice, CustomerID: 1, Lines: []model.InvoiceLine{
+				{ProductID: uintPtr(1), Quantity: 2},
+				{ProductID: uintPtr(2), Description: "Krentenbollen", Quantity: 10, UnitPriceCents: 300, VATRate: 21},
+			}},
+			want: func(t *testing.T, created *model.Invoice) {
+				want := []model.InvoiceLine{
+					{Position: 1, ProductID: uintPtr(1), Description: "Onderhoud per uur", Quantity: 2, UnitPriceCents: 6500, VATRate: 21, NetCents: 13000},
+					{Position: 2, ProductID: uintPtr(2), Description: "Krentenbollen", Quantity: 10, UnitPriceCents: 300, VATRate: 9, NetCents: 3000},
+				}
+				if !reflect.DeepEqual(created.Lines, want) {
+					t.Errorf("regels = %+v, verwacht %+v", created.Lines, want)
+				}
+				if created.VATCents != 2730+270 {
+					t.Errorf("BTW %d, verwacht 3000", created.VATCents)
+				}
+			},
+		},
+		{
+			name: "ongeldige gegevens",
+			invoice: model.Invoice{Type: model.TypeInvoice, CustomerID: 7, Currency: "euro", PaymentTermDays: 400, Lines: []model.InvoiceLine{
+				{Description: " ", Quantity: 0, VATRate: 6},
+				{ProductID: uintPtr(3), Quantity: 1},
+				{ProductID: uintPtr(9), Quantity: 1},
+			}},
+			wantFields: []string{"customer_id", "currency", "payment_term_days", "lines.0.description", "lines.0.quantity", "lines.0.vat_rate", "lines.1.product_id", "lines.2.product_id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeInvoiceRepository()
+			invoice := tt.invoice
+			created, err := newTestInvoiceService(repo).CreateInvoice(&invoice)
+
+			if tt.wantFields != nil {
+				fields, _ := validation.Fields(err)
+				got := make([]string, len(fields))
+				for i, field := range fields {
+					got[i] = field.Field
+				}
+				if !reflect.DeepEqual(got, tt.wantFields) {
+					t.Errorf("fouten in %v, verwacht %v (%v)", got, tt.wantFields, err)
+				}
+				if len(repo.invoices) != 0 {
+					t.Error("ongeldig document toch opgeslagen")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateInvoice gaf fout: %v", err)
+			}
+			tt.want(t, created)
+		})
+	}
+}
+
+func TestChangeStatus(t *testing.T) {
+	line := []model.InvoiceLine{{Description: "Onderhoud", Quantity: 1, UnitPriceCents: 1000, VATRate: 21}}
+	repo := newFakeInvoiceRepository(
+		&model.Invoice{ID: 1, Type: model.TypeInvoice, Status: model.StatusDraft, Lines: line},
+		&model.Invoice{ID: 2, Type: model.TypeInvoice, Status: model.StatusDraft},
+		&model.Invoice{ID: 3, Type: model.TypeInvoice, Status: model.StatusSent},
+		&model.Invoice{ID: 4, Type: model.TypeInvoice, Status: model.StatusPaid},
+		&model.Invoice{ID: 5, Type: model.TypeQuote, Status: model.StatusDraft, Lines: line},
+		&model.Invoice{ID: 6, Type: model.TypeInvoice, Status: model.StatusDraft, Lines: line},
+		&model.Invoice{ID: 7, Type: model.TypeInvoice, Status: model.StatusSent},
+	)
+	service := newTestInvoiceService(repo)
+
+	tests := []struct {
+		name       string
+		docType    model.DocumentType
+		id         string
+		status     model.Status
+		wantNumber string
+		wantClosed bool
+		wantErr    string
+	}{
+		{name: "eerste factuur van het jaar", docType: model.TypeInvoice, id: "1", status: model.StatusSent, wantNumber: "F%d-0001"},
+		{name: "offertes hebben een eigen reeks", docType: model.TypeQuote, id: "5", status: model.StatusSent, wantNumber: "OF%d-0001"},
+		{name: "volgende factuur sluit aan", docType: model.TypeInvoice, id: "6", status: model.StatusSent, wantNumber: "F%d-0002"},
+		{name: "zonder regels krijgt geen nummer", docType: model.TypeInvoice, id: "2", status: model.StatusSent, wantErr: "een factuur zonder regels kan niet verstuurd worden"},
+		{name: "betaald sluit af", docType: model.TypeInvoice, id: "3", status: model.StatusPaid, wantClosed: true},
+		{name: "niet toegestane overgang", docType: model.TypeInvoice, id: "7", status: model.StatusDraft, wantErr: "overgang van 'sent' naar 'draft' is niet toegestaan; toegestaan: paid, overdue"},
+		{name: "geen overgangen meer", docType: model.TypeInvoice, id: "4", status: model.StatusSent, wantErr: "vanuit status 'paid' zijn geen overgangen toegestaan"},
+		{name: "ander soort document", docType: model.TypeQuote, id: "1", status: model.StatusSent, wantErr: "document niet gevonden"},
+	}
+
+	year := today().Year()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice, err := service.ChangeStatus(tt.docType, tt.id, tt.status)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ChangeStatus gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangeStatus gaf fout: %v", err)
+			}
+			if invoice.Status != tt.status {
+				t.Errorf("status %s, verwacht %s", invoice.Status, tt.status)
+			}
+			if tt.wantNumber != "" && invoice.Number != fmt.Sprintf(tt.wantNumber, year) {
+				t.Errorf("nummer %s, verwacht %s", invoice.Number, fmt.Sprintf(tt.wantNumber, year))
+			}
+			if (invoice.ClosedAt != nil) != tt.wantClosed {
+				t.Errorf("afgesloten op %v", invoice.ClosedAt)
+			}
+		})
+	}
+
+	// Alleen de documenten met regels zijn genummerd, met de datum van vandaag zonder tijd
+	if len(repo.finalized) != 3 {
+		t.Fatalf("%d keer genummerd, verwacht 3", len(repo.finalized))
+	}
+	for _, date := range repo.finalized {
+		if !date.Equal(today()) {
+			t.Errorf("genummerd met datum %v, verwacht %v", date, today())
+		}
+	}
+}
+
+func TestCreateInvoiceFromQuote(t *testing.T) {
+	lines := []model.InvoiceLine{
+		{ID: 11, InvoiceID: 1, Position: 1, Description: "Onderhoud maart", Quantity: 2.5, UnitPriceCents: 8500, VATRate: 21, NetCents: 21250},
+		{ID: 12, InvoiceID: 1, Position: 2, Description: "Voorrijkosten", Quantity: 1, UnitPriceCents: 3500, VATRate: 21, NetCents: 3500},
+	}
+	repo := newFakeInvoiceRepository(
+		&model.Invoice{ID: 1, Type: model.TypeQuote, Status: model.StatusAccepted, Number: "OF2025-0003", CustomerID: 1, Reference: "PO-4711", Notes: "Levering in week 12", Currency: "EUR", PaymentTermDays: 14, Lines: lines},
+		&model.Invoice{ID: 2, Type: model.TypeQuote, Status: model.StatusSent, CustomerID: 1, Lines: lines},
+		&model.Invoice{ID: 3, Type: model.TypeInvoice, Status: model.StatusDraft, CustomerID: 1, Lines: lines},
+	)
+	service := newTestInvoiceService(repo)
+
+	invoice, err := service.CreateInvoiceFromQuote("1", 3, "johndoe")
+	if err != nil {
+		t.Fatalf("CreateInvoiceFromQuote gaf fout: %v", err)
+	}
+	if invoice.Type != model.TypeInvoice || invoice.Status != model.StatusDraft || invoice.Number != "" {
+		t.Errorf("factuur = %s %s %q, verwacht een conceptfactuur zonder nummer", invoice.Type, invoice.Status, invoice.Number)
+	}
+	if invoice.QuoteID == nil || *invoice.QuoteID != 1 || repo.quotes[invoice.ID] != 1 {
+		t.Errorf("factuur niet aan offerte 1 gekoppeld: %v, %v", invoice.QuoteID, repo.quotes)
+	}
+	if invoice.Reference != "PO-4711" || invoice.Notes != "Levering in week 12" || invoice.CreatedByName != "johndoe" {
+		t.Errorf("gegevens niet overgenomen: %+v", invoice)
+	}
+	// De factuur krijgt de betaaltermijn, niet de geldigheid van de offerte
+	if invoice.PaymentTermDays != 30 {
+		t.Errorf("termijn %d, verwacht 30", invoice.PaymentTermDays)
+	}
+	want := []model.InvoiceLine{
+		{Position: 1, Description: "Onderhoud maart", Quantity: 2.5, UnitPriceCents: 8500, VATRate: 21, NetCents: 21250},
+		{Position: 2, Description: "Voorrijkosten", Quantity: 1, UnitPriceCents: 3500, VATRate: 21, NetCents: 3500},
+	}
+	if !reflect.DeepEqual(invoice.Lines, want) {
+		t.Errorf("regels = %+v, verwacht %+v", invoice.Lines, want)
+	}
+	if invoice.TotalCents != 24750+5198 {
+		t.Errorf("totaal %d, verwacht %d", invoice.TotalCents, 24750+5198)
+	}
+
+	for id, wantErr := range map[string]string{
+		"2": "alleen van een geaccepteerde offerte kan een factuur gemaakt worden",
+		"3": "document niet gevonden",
+	} {
+		if _, err := service.CreateInvoiceFromQuote(id, 3, "johndoe"); err == nil || err.Error() != wantErr {
+			t.Errorf("document %s: fout %v, verwacht %q", id, err, wantErr)
+		}
+	}
+}
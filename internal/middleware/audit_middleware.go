@@ -1,13 +1,16 @@
 package middleware
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"odomosml/internal/audit/model"
 	"odomosml/internal/audit/service"
 	"odomosml/internal/customer/repository"
 	userRepo "odomosml/internal/user/repository"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -32,30 +35,77 @@ func getEntityName(entityType model.EntityType) string {
 		return "Klant"
 	case model.EntityUser:
 		return "Gebruiker"
+	case model.EntityTag:
+		return "Tag"
+	case model.EntityCustomField:
+		return "Vrij veld"
+	case model.EntityActivity:
+		return "Activiteit"
+	case model.EntityAttachment:
+		return "Bijlage"
+	case model.EntityImport:
+		return "Import"
+	case model.EntitySavedView:
+		return "Weergave"
+	case model.EntityStatus:
+		return "Klantstatus"
+	case model.EntityDeal:
+		return "Deal"
+	case model.EntityQuote:
+		return "Offerte"
+	case model.EntityInvoice:
+		return "Factuur"
+	case model.EntityProduct:
+		return "Product"
+	case model.EntityTask:
+		return "Taak"
+	case model.EntityAppointment:
+		return "Afspraak"
 	default:
 		return string(entityType)
 	}
 }
 
-// compareAndGetChanges vergelijkt oude en nieuwe waardes en geeft de verschillen terug
+// confidentialFields worden versleuteld opgeslagen; hun waarden komen niet in de (onversleutelde) omschrijving
+var confidentialFields = map[string]bool{
+	"email":   true,
+	"phone":   true,
+	"address": true,
+}
+
+// compareAndGetChanges vergelijkt oude en nieuwe waardes en geeft de verschillen terug.
+// Geneste objecten (zoals custom_fields) worden per veld vergeleken.
 func compareAndGetChanges(old, new map[string]interface{}) []string {
 	if old == nil || new == nil {
 		return []string{}
 	}
 
+	old = flattenData(old, "")
+	new = flattenData(new, "")
+
 	var changes []string
-	for key, oldValue := range old {
-		if newValue, exists := new[key]; exists {
-			// Skip empty values en password
-			if key == "password" || oldValue == nil || newValue == nil {
+	for key, newValue := range new {
+		oldValue, exists := old[key]
+		if !exists {
+			// Een nieuw genest veld (bijv. een vrij veld dat voor het eerst gevuld wordt)
+			if !strings.Contains(key, ".") {
 				continue
 			}
-			// Converteer waardes naar strings voor vergelijking
-			oldStr := fmt.Sprintf("%v", oldValue)
-			newStr := fmt.Sprintf("%v", newValue)
-			if oldStr != newStr {
-				changes = append(changes, fmt.Sprintf("%s: '%v' → '%v'", key, oldValue, newValue))
+			oldValue = ""
+		}
+		// Skip empty values en password
+		if key == "password" || oldValue == nil || newValue == nil {
+			continue
+		}
+		// Converteer waardes naar strings voor vergelijking
+		oldStr := fmt.Sprintf("%v", oldValue)
+		newStr := fmt.Sprintf("%v", newValue)
+		if oldStr != newStr {
+			if confidentialFields[key] {
+				changes = append(changes, fmt.Sprintf("%s gewijzigd", key))
+				continue
 			}
+			changes = append(changes, fmt.Sprintf("%s: '%v' → '%v'", key, oldValue, newValue))
 		}
 	}
 
@@ -63,9 +113,25 @@ func compareAndGetChanges(old, new map[string]interface{}) []string {
 		return []string{"geen wijzigingen"}
 	}
 
+	sort.Strings(changes)
 	return changes
 }
 
+// flattenData zet geneste objecten om naar platte keys, bijv. custom_fields.branche
+func flattenData(data map[string]interface{}, prefix string) map[string]interface{} {
+	flat := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if nested, ok := value.(map[string]interface{}); ok {
+			for nestedKey, nestedValue := range flattenData(nested, prefix+key+".") {
+				flat[nestedKey] = nestedValue
+			}
+			continue
+		}
+		flat[prefix+key] = value
+	}
+	return flat
+}
+
 // AuditMiddleware is een middleware die acties logt voor audit doeleinden
 type AuditMiddleware struct {
 	service        service.AuditService
@@ -100,16 +166,23 @@ func (m *AuditMiddleware) Handle(c *gin.Context) {
 		return
 	}
 
-	// Skip GET requests tenzij expliciet ingeschakeld
+	// Haal gebruikersinformatie uit de context
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	// Skip GET requests tenzij expliciet ingeschakeld; een door de handler
+	// aangeleverde entry (bijv. het downloaden van een bijlage) wordt wel gelogd
 	if c.Request.Method == "GET" && !m.logGetRequests {
 		c.Next()
+		if entry, exists := c.Get(model.ContextKey); exists {
+			if auditLog, ok := entry.(*model.AuditLog); ok {
+				m.logEntry(auditLog, userID, username, model.ActionRead,
+					getEntityTypeFromPath(c.Request.URL.Path), c.Writer.Status())
+			}
+		}
 		return
 	}
 
-	// Haal gebruikersinformatie uit de context
-	userID, _ := c.Get("userID")
-	username, _ := c.Get("username")
-
 	// Bepaal actie type op basis van HTTP methode
 	var actionType model.ActionType
 	switch c.Request.Method {
@@ -131,10 +204,9 @@ func (m *AuditMiddleware) Handle(c *gin.Context) {
 	// Haal entity ID uit URL als die er is
 	entityID := getEntityIDFromPath(c.Request.URL.Path)
 
-	// Maak een kopie van de context voor de response
+	// Houd de status code van de response bij
 	responseBodyWriter := &responseBodyWriter{
 		ResponseWriter: c.Writer,
-		body:           nil,
 	}
 	c.Writer = responseBodyWriter
 
@@ -142,26 +214,52 @@ func (m *AuditMiddleware) Handle(c *gin.Context) {
 	var oldData map[string]interface{}
 	var newData map[string]interface{}
 
-	// Bij DELETE of UPDATE, haal de oude data op uit de context
-	if actionType == model.ActionDelete {
-		if userData, exists := c.Get("userData"); exists {
-			if userDataMap, ok := userData.(map[string]interface{}); ok {
-				oldData = userDataMap
-			}
-		}
+	// Lees een JSON request body vooraf in, zodat deze na de handler nog beschikbaar is
+	var requestBody []byte
+	if (actionType == model.ActionCreate || actionType == model.ActionUpdate) && c.Request.Body != nil &&
+		strings.Contains(c.GetHeader("Content-Type"), "json") {
+		requestBody, _ = io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(requestBody))
 	}
 
 	// Voer de request uit
 	c.Next()
 
+	// Heeft de handler zelf een audit entry aangeleverd, log dan die entry
+	if entry, exists := c.Get(model.ContextKey); exists {
+		if auditLog, ok := entry.(*model.AuditLog); ok {
+			m.logEntry(auditLog, userID, username, actionType, entityType, responseBodyWriter.status)
+			return
+		}
+	}
+
+	// Bij DELETE of UPDATE, haal de oude data op uit de context (gezet door de handler)
+	var oldDataKeys []string
+	switch actionType {
+	case model.ActionDelete:
+		oldDataKeys = []string{"userData", "customerData", "tagData", "customFieldData", "viewData", "statusData", "dealData", "invoiceData", "productData", "taskData", "appointmentData"}
+	case model.ActionUpdate:
+		oldDataKeys = []string{"customerOldData", "dealOldData", "invoiceOldData", "productOldData", "taskOldData", "appointmentOldData"}
+	}
+	for _, key := range oldDataKeys {
+		if data, exists := c.Get(key); exists {
+			if dataMap, ok := data.(map[string]interface{}); ok {
+				oldData = dataMap
+				break
+			}
+		}
+	}
+
 	// Haal de nieuwe data op uit de request body (bij POST/PUT/PATCH)
-	if actionType == model.ActionCreate || actionType == model.ActionUpdate {
-		if c.Request.ContentLength > 0 {
-			var requestBody map[string]interface{}
-			if c.Request.Body != nil {
-				if err := c.ShouldBindJSON(&requestBody); err == nil {
-					newData = requestBody
-				}
+	if len(requestBody) > 0 {
+		var body interface{}
+		if err := json.Unmarshal(requestBody, &body); err == nil {
+			switch value := body.(type) {
+			case map[string]interface{}:
+				newData = value
+			case []interface{}:
+				// Een JSON Patch is een lijst van operaties
+				newData = map[string]interface{}{"patch": value}
 			}
 		}
 	}
@@ -189,21 +287,41 @@ func (m *AuditMiddleware) Handle(c *gin.Context) {
 	}
 }
 
+// logEntry vult een door de handler aangeleverde audit entry aan en slaat deze op
+func (m *AuditMiddleware) logEntry(auditLog *model.AuditLog, userID, username interface{}, actionType model.ActionType, entityType model.EntityType, status int) {
+	if auditLog.UserID == 0 {
+		auditLog.UserID = getUintValue(userID)
+	}
+	if auditLog.Username == "" {
+		auditLog.Username = getStringValue(username)
+	}
+	if auditLog.ActionType == "" {
+		auditLog.ActionType = actionType
+	}
+	if auditLog.EntityType == "" {
+		auditLog.EntityType = entityType
+	}
+	if auditLog.StatusCode == 0 {
+		auditLog.StatusCode = status
+	}
+	if auditLog.CreatedAt.IsZero() {
+		auditLog.CreatedAt = time.Now()
+	}
+
+	if err := m.service.Create(auditLog); err != nil {
+		log.Printf("Fout bij het loggen van audit: %v", err)
+	}
+}
+
 // Helper functies
 
-// responseBodyWriter is een wrapper rond gin.ResponseWriter die de response body opslaat
+// responseBodyWriter is een wrapper rond gin.ResponseWriter die de status code opslaat.
+// De body wordt niet gebufferd, zodat grote downloads gestreamd blijven.
 type responseBodyWriter struct {
 	gin.ResponseWriter
-	body   []byte
 	status int
 }
 
-// Write implementeert de io.Writer interface
-func (w *responseBodyWriter) Write(b []byte) (int, error) {
-	w.body = append(w.body, b...)
-	return w.ResponseWriter.Write(b)
-}
-
 // WriteHeader overschrijft de WriteHeader methode om de status code op te slaan
 func (w *responseBodyWriter) WriteHeader(code int) {
 	w.status = code
@@ -219,6 +337,26 @@ func getEntityTypeFromPath(path string) model.EntityType {
 			return model.EntityUser
 		case "klanten":
 			return model.EntityCustomer
+		case "tags":
+			return model.EntityTag
+		case "custom-fields":
+			return model.EntityCustomField
+		case "views":
+			return model.EntitySavedView
+		case "customer-statuses":
+			return model.EntityStatus
+		case "deals":
+			return model.EntityDeal
+		case "quotes":
+			return model.EntityQuote
+		case "invoices":
+			return model.EntityInvoice
+		case "products":
+			return model.EntityProduct
+		case "tasks":
+			return model.EntityTask
+		case "appointments":
+			return model.EntityAppointment
 		case "auth":
 			return model.EntityAuth
 		}
@@ -248,13 +386,12 @@ func buildDescription(actionType model.ActionType, entityType model.EntityType,
 		switch entityType {
 		case model.EntityUser:
 			if newData != nil {
-				return fmt.Sprintf("Nieuwe %s aangemaakt: %s (%s)",
+				return fmt.Sprintf("Nieuwe %s aangemaakt: %s",
 					entityName,
-					getStringFromMap(newData, "username"),
-					getStringFromMap(newData, "email"))
+					getStringFromMap(newData, "username"))
 			}
 			return fmt.Sprintf("Nieuwe %s aangemaakt", entityName)
-		case model.EntityCustomer:
+		case model.EntityCustomer, model.EntityTag:
 			if newData != nil {
 				return fmt.Sprintf("Nieuwe %s aangemaakt: %s",
 					entityName,
@@ -273,7 +410,7 @@ func buildDescription(actionType model.ActionType, entityType model.EntityType,
 					entityName, entityID, strings.Join(changes, ", "))
 			}
 			return fmt.Sprintf("%s bijgewerkt (ID: %s)", entityName, entityID)
-		case model.EntityCustomer:
+		case model.EntityCustomer, model.EntityTag:
 			changes := compareAndGetChanges(oldData, newData)
 			if len(changes) > 0 {
 				return fmt.Sprintf("%s bijgewerkt (ID: %s): %s",
@@ -285,14 +422,13 @@ func buildDescription(actionType model.ActionType, entityType model.EntityType,
 		switch entityType {
 		case model.EntityUser:
 			if oldData != nil {
-				return fmt.Sprintf("%s verwijderd (ID: %s) - gebruiker: %s (%s)",
+				return fmt.Sprintf("%s verwijderd (ID: %s) - gebruiker: %s",
 					entityName,
 					entityID,
-					getStringFromMap(oldData, "username"),
-					getStringFromMap(oldData, "email"))
+					getStringFromMap(oldData, "username"))
 			}
 			return fmt.Sprintf("%s verwijderd (ID: %s)", entityName, entityID)
-		case model.EntityCustomer:
+		case model.EntityCustomer, model.EntityTag:
 			if oldData != nil {
 				return fmt.Sprintf("%s verwijderd (ID: %s) - naam: %s",
 					entityName,
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	auditModel "odomosml/internal/audit/model"
+	"odomosml/internal/privacy/model"
+	"odomosml/internal/privacy/repository"
+	"odomosml/internal/privacy/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrivacyHandler handles HTTP requests for data subject requests (AVG)
+type PrivacyHandler struct {
+	service service.PrivacyService
+}
+
+// NewPrivacyHandler maakt een nieuwe PrivacyHandler instantie
+func NewPrivacyHandler(service service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		service: service,
+	}
+}
+
+// @Summary      Persoonsgegevens van een klant exporteren (AVG inzage)
+// @Description  Geeft alles wat over een klant is vastgelegd als ZIP met JSON bestanden: de klant, statusgeschiedenis, notities, gegevens van bijlagen, deals, taken, afspraken, offertes en facturen, prijsafspraken en de audit log van de klant en van zijn deals, taken, afspraken, notities en bijlagen. Alleen voor admins; de export zelf komt in de audit log.
+// @Tags         privacy
+// @Produce      application/zip
+// @Param        id path string true "Klant ID"
+// @Success      200  {file}    file "ZIP met JSON bestanden"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Failure      500  {object}  map[string]string "Server error"
+// @Security     Bearer
+// @Router       /klanten/{id}/avg-export [get]
+func (h *PrivacyHandler) Export(c *gin.Context) {
+	export, err := h.service.GetExport(c.Param("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrNotFound) || errors.Is(err, service.ErrInvalidID) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.service.WriteExport(export, &buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Leg vast wie de gegevens heeft opgevraagd, zonder de gegevens zelf
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		ActionType:  auditModel.ActionRead,
+		EntityType:  auditModel.EntityCustomer,
+		EntityID:    strconv.FormatUint(uint64(export.Customer.ID), 10),
+		Description: fmt.Sprintf("AVG-inzage: gegevens van klant %d geëxporteerd", export.Customer.ID),
+	})
+
+	fileName := fmt.Sprintf("avg-export-klant-%d-%s.zip", export.Customer.ID, export.GeneratedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// @Summary      Persoonsgegevens van een klant wissen (AVG)
+// @Description  Anonimiseert de klant: naam, e-mailadres, telefoonnummer, adres, KvK, BTW-nummer en vrije velden worden gewist. Notities en bijlagen worden verwijderd, notities bij statusovergangen leeggemaakt en persoonsgegevens uit de audit log van de klant en van zijn deals, taken, afspraken, notities en bijlagen gehaald. Deelnemers van buiten aan zijn afspraken krijgen een geanonimiseerd e-mailadres. Deals, taken en afspraken blijven bestaan bij de geanonimiseerde klant. Offertes en facturen vallen onder de fiscale bewaarplicht en blijven ongewijzigd. Kan niet ongedaan gemaakt worden; stuur {"confirm": true} mee. Alleen voor admins.
+// @Tags         privacy
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Klant ID"
+// @Param        request body model.EraseRequest true "Bevestiging"
+// @Success      200  {object}  model.ErasureResult "Persoonsgegevens gewist"
+// @Failure      400  {object}  map[string]string "Niet bevestigd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      404  {object}  map[string]string "Klant niet gevonden"
+// @Failure      409  {object}  map[string]string "Klant heeft een legal hold"
+// @Security     Bearer
+// @Router       /klanten/{id}/anonimiseren [post]
+func (h *PrivacyHandler) Erase(c *gin.Context) {
+	var request model.EraseRequest
+	if err := c.ShouldBindJSON(&request); err != nil || !request.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Wissen kan niet ongedaan gemaakt worden; bevestig met {\"confirm\": true}",
+		})
+		return
+	}
+
+	result, err := h.service.EraseCustomer(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrNotFound) || errors.Is(err, service.ErrInvalidID) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, repository.ErrLegalHold) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// De audit entry van het wissen bevat alleen aantallen, geen persoonsgegevens
+	newData, _ := json.Marshal(result.ToAuditMap())
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		ActionType:  auditModel.ActionUpdate,
+		EntityType:  auditModel.EntityCustomer,
+		EntityID:    strconv.FormatUint(uint64(result.CustomerID), 10),
+		Description: fmt.Sprintf("AVG: persoonsgegevens van klant %d gewist", result.CustomerID),
+		NewData:     string(newData),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
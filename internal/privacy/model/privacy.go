@@ -0,0 +1,67 @@
+package model
+
+import (
+	activityModel "odomosml/internal/activity/model"
+	appointmentModel "odomosml/internal/appointment/model"
+	attachmentModel "odomosml/internal/attachment/model"
+	auditModel "odomosml/internal/audit/model"
+	customerModel "odomosml/internal/customer/model"
+	dealModel "odomosml/internal/deal/model"
+	invoiceModel "odomosml/internal/invoice/model"
+	productModel "odomosml/internal/product/model"
+	taskModel "odomosml/internal/task/model"
+	"time"
+)
+
+// Redacted vervangt gewiste persoonsgegevens
+const Redacted = "[verwijderd]"
+
+// Export bevat alles wat over een klant is vastgelegd, voor een inzageverzoek (AVG artikel 15).
+// Elk deel wordt als eigen JSON bestand in de ZIP gezet.
+type Export struct {
+	GeneratedAt     time.Time
+	Customer        *customerModel.Customer
+	StatusHistory   []customerModel.StatusChange
+	Activities      []activityModel.Activity
+	Attachments     []attachmentModel.Attachment
+	Deals           []dealModel.Deal
+	Tasks           []taskModel.Task
+	Appointments    []appointmentModel.Appointment
+	Documents       []invoiceModel.Invoice
+	PriceAgreements []productModel.PriceAgreement
+	AuditLogs       []auditModel.AuditLog
+}
+
+// EraseRequest bevestigt het wissen van de persoonsgegevens van een klant
+// @Description Bevestiging voor het wissen van persoonsgegevens
+type EraseRequest struct {
+	Confirm bool `json:"confirm" example:"true" swaggertype:"boolean"` // Moet true zijn; wissen kan niet ongedaan gemaakt worden
+}
+
+// ErasureResult beschrijft wat er bij het wissen van een klant is gebeurd. Bevat zelf geen persoonsgegevens.
+// @Description Resultaat van het wissen van persoonsgegevens
+type ErasureResult struct {
+	CustomerID          uint      `json:"customer_id" example:"1" swaggertype:"integer"`
+	ActivitiesDeleted   int64     `json:"activities_deleted" example:"12" swaggertype:"integer"`
+	AttachmentsDeleted  int64     `json:"attachments_deleted" example:"3" swaggertype:"integer"`
+	StatusNotesCleared  int64     `json:"status_notes_cleared" example:"2" swaggertype:"integer"`
+	AttendeesAnonymized int64     `json:"attendees_anonymized" example:"4" swaggertype:"integer"` // Deelnemers van buiten aan afspraken van de klant
+	AuditLogsScrubbed   int64     `json:"audit_logs_scrubbed" example:"40" swaggertype:"integer"`
+	DocumentsRetained   int64     `json:"documents_retained" example:"5" swaggertype:"integer"` // Offertes en facturen vallen onder de fiscale bewaarplicht en blijven ongewijzigd
+	ErasedAt            time.Time `json:"erased_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	StorageKeys         []string  `json:"-"` // Bestanden van de verwijderde bijlagen, om na de transactie uit de opslag te halen
+}
+
+// ToAuditMap converteert het resultaat naar een map voor audit logging
+func (r *ErasureResult) ToAuditMap() map[string]interface{} {
+	return map[string]interface{}{
+		"customer_id":          r.CustomerID,
+		"activities_deleted":   r.ActivitiesDeleted,
+		"attachments_deleted":  r.AttachmentsDeleted,
+		"status_notes_cleared": r.StatusNotesCleared,
+		"attendees_anonymized": r.AttendeesAnonymized,
+		"audit_logs_scrubbed":  r.AuditLogsScrubbed,
+		"documents_retained":   r.DocumentsRetained,
+		"erased_at":            r.ErasedAt,
+	}
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	activityModel "odomosml/internal/activity/model"
+	appointmentModel "odomosml/internal/appointment/model"
+	attachmentModel "odomosml/internal/attachment/model"
+	auditModel "odomosml/internal/audit/model"
+	customerModel "odomosml/internal/customer/model"
+	invoiceModel "odomosml/internal/invoice/model"
+	"odomosml/internal/privacy/model"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// auditBatchSize is het aantal audit logs dat per keer wordt opgeschoond
+const auditBatchSize = 500
+
+// ErrNotFound betekent dat de klant niet bestaat
+var ErrNotFound = errors.New("klant niet gevonden")
+
+// ErrLegalHold betekent dat de klant een legal hold heeft en zijn gegevens daarom niet gewist mogen worden
+var ErrLegalHold = errors.New("klant heeft een legal hold; de gegevens mogen niet gewist worden")
+
+// PrivacyRepository definieert de interface voor inzage en wissen van persoonsgegevens (AVG)
+type PrivacyRepository interface {
+	FindExport(customerID uint) (*model.Export, error)
+	Erase(customerID uint, erasure Erasure) (*model.ErasureResult, error)
+}
+
+// Erasure bevat de stappen waarmee de service de persoonsgegevens van een klant wist
+type Erasure struct {
+	Anonymize         func(customer *customerModel.Customer)    // Past de klant aan
+	AnonymizeAttendee func(attendee *appointmentModel.Attendee) // Past een deelnemer van buiten aan een afspraak van de klant aan
+	Collect           func(entries []auditModel.AuditLog)       // Krijgt eerst alle audit logs van de klant te zien
+	Scrub             func(entries []auditModel.AuditLog) []auditModel.AuditLog
+}
+
+// privacyRepository implementeert de PrivacyRepository interface
+type privacyRepository struct {
+	db *gorm.DB
+}
+
+// NewPrivacyRepository maakt een nieuwe PrivacyRepository instantie
+func NewPrivacyRepository(db *gorm.DB) PrivacyRepository {
+	return &privacyRepository{
+		db: db,
+	}
+}
+
+// FindExport haalt alles op wat over een klant is vastgelegd
+func (r *privacyRepository) FindExport(customerID uint) (*model.Export, error) {
+	export := &model.Export{GeneratedAt: time.Now()}
+
+	// Ook een verwijderde klant staat tot het opschonen nog in de database
+	var customer customerModel.Customer
+	if err := r.db.Unscoped().Preload("Tags").First(&customer, customerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	export.Customer = &customer
+
+	queries := []struct {
+		query *gorm.DB
+		dest  interface{}
+	}{
+		{r.db.Order("changed_at ASC, id ASC"), &export.StatusHistory},
+		{r.db.Order("occurred_at ASC, id ASC"), &export.Activities},
+		{r.db.Order("id ASC"), &export.Attachments},
+		{r.db.Order("id ASC"), &export.Deals},
+		{r.db.Order("id ASC"), &export.Tasks},
+		{r.db.Preload("Attendees").Order("start_at ASC, id ASC"), &export.Appointments},
+		{r.db.Preload("Lines").Order("id ASC"), &export.Documents},
+		{r.db.Order("id ASC"), &export.PriceAgreements},
+	}
+	for _, q := range queries {
+		if err := q.query.Where("customer_id = ?", customerID).Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	err := r.db.Scopes(customerAuditLogs(customerID)).Order("created_at ASC, id ASC").Find(&export.AuditLogs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// customerAuditLogs beperkt een query tot de audit logs van een klant: die van de klant zelf en die van
+// zijn deals, taken, afspraken, notities en bijlagen
+func customerAuditLogs(customerID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		related := func(table string) string {
+			return "entity_id IN (SELECT CAST(id AS TEXT) FROM " + table + " WHERE customer_id = @customer)"
+		}
+		return db.Where(`(entity_type = @customerType AND entity_id = @customerID)
+			OR (entity_type = @dealType AND `+related("deals")+`)
+			OR (entity_type = @taskType AND `+related("tasks")+`)
+			OR (entity_type = @appointmentType AND `+related("appointments")+`)
+			OR (entity_type = @activityType AND `+related("activities")+`)
+			OR (entity_type = @attachmentType AND `+related("attachments")+`)`,
+			map[string]interface{}{
+				"customer":        customerID,
+				"customerID":      strconv.FormatUint(uint64(customerID), 10),
+				"customerType":    auditModel.EntityCustomer,
+				"dealType":        auditModel.EntityDeal,
+				"taskType":        auditModel.EntityTask,
+				"appointmentType": auditModel.EntityAppointment,
+				"activityType":    auditModel.EntityActivity,
+				"attachmentType":  auditModel.EntityAttachment,
+			})
+	}
+}
+
+// Erase wist in één transactie de persoonsgegevens van een klant: Anonymize past de klant aan en
+// AnonymizeAttendee de deelnemers van buiten aan zijn afspraken. De audit logs van de klant en zijn
+// deals, taken, afspraken, notities en bijlagen gaan twee keer per batch langs: eerst krijgt Collect
+// ze allemaal te zien, daarna schoont Scrub ze op en geeft de gewijzigde entries terug. Zo worden ook
+// eerdere waarden die pas in een latere entry voorkomen overal gewist. Daarna worden notities en
+// bijlagen verwijderd en notities bij statusovergangen leeggemaakt.
+// Offertes en facturen blijven ongewijzigd. De bestanden van de bijlagen staan in StorageKeys
+// van het resultaat; die moeten na afloop uit de opslag gehaald worden.
+func (r *privacyRepository) Erase(customerID uint, erasure Erasure) (*model.ErasureResult, error) {
+	result := &model.ErasureResult{CustomerID: customerID, ErasedAt: time.Now()}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Vergrendel de klant, zodat hij niet tegelijk gewijzigd of samengevoegd wordt. Ook een verwijderde
+		// klant wordt gewist, zodat de gegevens niet tot het opschonen blijven staan.
+		var customer customerModel.Customer
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&customer, customerID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if customer.LegalHold {
+			return ErrLegalHold
+		}
+
+		var attachments []attachmentModel.Attachment
+		if err := tx.Where("customer_id = ?", customerID).Find(&attachments).Error; err != nil {
+			return err
+		}
+		for _, attachment := range attachments {
+			result.StorageKeys = append(result.StorageKeys, attachment.StorageKey)
+		}
+
+		if err := tx.Model(&invoiceModel.Invoice{}).Where("customer_id = ?", customerID).Count(&result.DocumentsRetained).Error; err != nil {
+			return err
+		}
+
+		erasure.Anonymize(&customer)
+		customer.ErasedAt = &result.ErasedAt
+		customer.Version++
+		err := tx.Unscoped().Model(&customer).Omit(clause.Associations).
+			Select("name", "email", "email_index", "phone", "address", "kvk_number", "vat_number", "custom_fields", "erased_at", "version", "updated_at").
+			Updates(&customer).Error
+		if err != nil {
+			return err
+		}
+
+		// Deelnemers die gebruiker zijn horen niet bij de klant en blijven staan
+		var attendees []appointmentModel.Attendee
+		err = tx.Where("user_id IS NULL AND appointment_id IN (?)", tx.Model(&appointmentModel.Appointment{}).Select("id").Where("customer_id = ?", customerID)).
+			Find(&attendees).Error
+		if err != nil {
+			return err
+		}
+		for _, attendee := range attendees {
+			erasure.AnonymizeAttendee(&attendee)
+			if err := tx.Model(&attendee).Select("name", "email").Updates(&attendee).Error; err != nil {
+				return err
+			}
+			result.AttendeesAnonymized++
+		}
+
+		// De audit logs van notities en bijlagen worden opgeschoond voordat die zelf verwijderd worden
+		var entries []auditModel.AuditLog
+		err = tx.Scopes(customerAuditLogs(customerID)).FindInBatches(&entries, auditBatchSize, func(*gorm.DB, int) error {
+			erasure.Collect(entries)
+			return nil
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Scopes(customerAuditLogs(customerID)).FindInBatches(&entries, auditBatchSize, func(*gorm.DB, int) error {
+			for _, entry := range erasure.Scrub(entries) {
+				// Met een struct en niet met een map, zodat de data weer versleuteld wordt
+				err := tx.Model(&entry).Select("description", "old_data", "new_data").Updates(&entry).Error
+				if err != nil {
+					return fmt.Errorf("kan audit log %d niet opschonen: %w", entry.ID, err)
+				}
+				result.AuditLogsScrubbed++
+			}
+			return nil
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		deleted := tx.Where("customer_id = ?", customerID).Delete(&attachmentModel.Attachment{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		result.AttachmentsDeleted = deleted.RowsAffected
+
+		deleted = tx.Where("customer_id = ?", customerID).Delete(&activityModel.Activity{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		result.ActivitiesDeleted = deleted.RowsAffected
+
+		cleared := tx.Model(&customerModel.StatusChange{}).Where("customer_id = ? AND note <> ''", customerID).Update("note", "")
+		if cleared.Error != nil {
+			return cleared.Error
+		}
+		result.StatusNotesCleared = cleared.RowsAffected
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"encoding/json"
+	auditModel "odomosml/internal/audit/model"
+	customerModel "odomosml/internal/customer/model"
+	"odomosml/internal/privacy/model"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// minScrubLength is de minimale lengte van een waarde die in vrije tekst vervangen wordt; kortere
+// waarden zouden ook in woorden zonder persoonsgegevens gevonden worden
+const minScrubLength = 3
+
+// personalKeys zijn de velden in de data van audit logs die persoonsgegevens bevatten, van de klant
+// zelf, van notities, bijlagen en statusovergangen, en van momentopnamen van de klant op documenten
+var personalKeys = map[string]bool{
+	"name":                true,
+	"email":               true,
+	"phone":               true,
+	"address":             true,
+	"kvk_number":          true,
+	"vat_number":          true,
+	"custom_fields":       true,
+	"body":                true,
+	"note":                true,
+	"file_name":           true,
+	"customer_name":       true,
+	"customer_email":      true,
+	"customer_address":    true,
+	"customer_kvk":        true,
+	"customer_vat_number": true,
+}
+
+// auditScrubber wist persoonsgegevens uit audit logs. Het verzamelt eerst alle waarden die een klant
+// ooit had (ook eerdere namen en e-mailadressen uit de logs zelf) en vervangt die daarna overal,
+// ook in omschrijvingen en in velden die niet als persoonlijk bekend zijn.
+type auditScrubber struct {
+	values  map[string]bool
+	pattern *regexp.Regexp
+}
+
+// newAuditScrubber maakt een auditScrubber
+func newAuditScrubber() *auditScrubber {
+	return &auditScrubber{values: map[string]bool{}}
+}
+
+// addCustomer verzamelt de persoonsgegevens van een klant
+func (s *auditScrubber) addCustomer(customer *customerModel.Customer) {
+	for _, value := range []string{customer.Name, customer.Email, customer.Phone, customer.Address, customer.KvKNumber, customer.VATNumber} {
+		s.add(value)
+	}
+	for _, value := range customer.CustomFields {
+		s.addValue(value)
+	}
+}
+
+// collect verzamelt de waarden van persoonlijke velden uit de data van audit logs
+func (s *auditScrubber) collect(entries []auditModel.AuditLog) {
+	for _, entry := range entries {
+		for _, data := range []string{entry.OldData, entry.NewData} {
+			var decoded interface{}
+			if json.Unmarshal([]byte(data), &decoded) == nil {
+				s.collectValue(decoded)
+			}
+		}
+	}
+}
+
+// collectValue zoekt in JSON data naar persoonlijke velden en verzamelt hun waarden
+func (s *auditScrubber) collectValue(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if personalKeys[key] {
+				s.addValue(field)
+			} else {
+				s.collectValue(field)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			s.collectValue(item)
+		}
+	}
+}
+
+// addValue verzamelt alle tekst in een waarde, ook in geneste objecten zoals vrije velden
+func (s *auditScrubber) addValue(value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.add(v)
+	case map[string]interface{}:
+		for _, field := range v {
+			s.addValue(field)
+		}
+	case []interface{}:
+		for _, item := range v {
+			s.addValue(item)
+		}
+	}
+}
+
+// add verzamelt één waarde
+func (s *auditScrubber) add(value string) {
+	value = strings.TrimSpace(value)
+	if len([]rune(value)) >= minScrubLength && value != model.Redacted {
+		s.values[value] = true
+		s.pattern = nil
+	}
+}
+
+// scrub wist de persoonsgegevens uit audit logs en geeft de entries terug die gewijzigd zijn
+func (s *auditScrubber) scrub(entries []auditModel.AuditLog) []auditModel.AuditLog {
+	var changed []auditModel.AuditLog
+	for _, entry := range entries {
+		scrubbed := entry
+		scrubbed.Description = s.replace(entry.Description)
+		scrubbed.OldData = s.scrubData(entry.OldData)
+		scrubbed.NewData = s.scrubData(entry.NewData)
+
+		if scrubbed.Description != entry.Description || scrubbed.OldData != entry.OldData || scrubbed.NewData != entry.NewData {
+			changed = append(changed, scrubbed)
+		}
+	}
+	return changed
+}
+
+// scrubData wist persoonlijke velden uit JSON data en vervangt bekende waarden in de overige tekst.
+// Data die geen JSON is wordt als tekst behandeld.
+func (s *auditScrubber) scrubData(data string) string {
+	if data == "" {
+		return data
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		return s.replace(data)
+	}
+
+	encoded, err := json.Marshal(s.scrubValue(decoded))
+	if err != nil {
+		return s.replace(data)
+	}
+	if string(encoded) == data {
+		return data
+	}
+	return string(encoded)
+}
+
+// scrubValue wist persoonlijke velden uit een JSON waarde
+func (s *auditScrubber) scrubValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if personalKeys[key] && !isEmpty(field) {
+				v[key] = model.Redacted
+			} else {
+				v[key] = s.scrubValue(field)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = s.scrubValue(item)
+		}
+		return v
+	case string:
+		return s.replace(v)
+	}
+	return value
+}
+
+// replace vervangt de verzamelde waarden in een tekst, hoofdletterongevoelig en langste eerst
+func (s *auditScrubber) replace(text string) string {
+	if text == "" || len(s.values) == 0 {
+		return text
+	}
+
+	if s.pattern == nil {
+		values := make([]string, 0, len(s.values))
+		for value := range s.values {
+			values = append(values, value)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if len(values[i]) != len(values[j]) {
+				return len(values[i]) > len(values[j])
+			}
+			return values[i] < values[j]
+		})
+		for i, value := range values {
+			values[i] = regexp.QuoteMeta(value)
+		}
+		s.pattern = regexp.MustCompile("(?i)" + strings.Join(values, "|"))
+	}
+
+	return s.pattern.ReplaceAllLiteralString(text, model.Redacted)
+}
+
+// isEmpty geeft aan of een JSON waarde leeg is; lege velden blijven staan
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"encoding/json"
+	auditModel "odomosml/internal/audit/model"
+	customerModel "odomosml/internal/customer/model"
+	"odomosml/internal/privacy/model"
+	"reflect"
+	"testing"
+)
+
+// sameJSON vergelijkt twee JSON documenten los van de volgorde van de velden
+func sameJSON(t *testing.T, got, want string) bool {
+	t.Helper()
+	var a, b interface{}
+	if err := json.Unmarshal([]byte(got), &a); err != nil {
+		t.Fatalf("resultaat is geen JSON: %s", got)
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatalf("verwachting is geen JSON: %s", want)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func TestAuditScrubberCollect(t *testing.T) {
+	scrubber := newAuditScrubber()
+	scrubber.addCustomer(&customerModel.Customer{
+		Name:         "Bakkerij Jansen",
+		Email:        "info@jansen.nl",
+		CustomFields: customerModel.CustomFields{"contact": "Piet Jansen", "omzet": 1000, "adressen": []interface{}{"Dorpsstraat 1"}},
+	})
+	scrubber.collect([]auditModel.AuditLog{
+		{OldData: `{"name":"Jansen & Co","email":"oud@jansen.nl","status":"lead"}`, NewData: `{"name":"Bakkerij Jansen"}`},
+		{NewData: `{"changes":[{"customer_email":"factuur@jansen.nl","amount":"12.50"}]}`},
+		{NewData: `{"custom_fields":{"regio":{"naam":"Noord-Holland"}}}`},
+		{NewData: `{"note":"ok","body":"  ","file_name":"[verwijderd]"}`},
+		{NewData: `geen JSON met info@nergens.nl`},
+	})
+
+	want := map[string]bool{
+		"Bakkerij Jansen":   true,
+		"info@jansen.nl":    true,
+		"Piet Jansen":       true,
+		"Dorpsstraat 1":     true,
+		"Jansen & Co":       true,
+		"oud@jansen.nl":     true,
+		"factuur@jansen.nl": true,
+		"Noord-Holland":     true,
+	}
+	if !reflect.DeepEqual(scrubber.values, want) {
+		t.Errorf("verzamelde waarden = %v, verwacht %v", scrubber.values, want)
+	}
+}
+
+func TestAuditScrubberScrub(t *testing.T) {
+	scrubber := newAuditScrubber()
+	scrubber.addCustomer(&customerModel.Customer{Name: "Jansen B.V.", Email: "info@jansen.nl", Phone: "020-1234567"})
+	scrubber.add("Jansen")
+
+	tests := []struct {
+		name        string
+		entry       auditModel.AuditLog
+		wantDesc    string
+		wantOldData string
+		wantNewData string
+	}{
+		{
+			name:        "persoonlijke velden",
+			entry:       auditModel.AuditLog{Description: "Klant bijgewerkt", OldData: `{"name":"Jansen B.V.","phone":"","status":"lead"}`, NewData: `{"email":"nieuw@example.nl","status":"klant"}`},
+			wantDesc:    "Klant bijgewerkt",
+			wantOldData: `{"name":"[verwijderd]","phone":"","status":"lead"}`,
+			wantNewData: `{"email":"[verwijderd]","status":"klant"}`,
+		},
+		{
+			name:     "waarden in omschrijving, langste eerst en hoofdletterongevoelig",
+			entry:    auditModel.AuditLog{Description: "Klant JANSEN B.V. (info@jansen.nl) samengevoegd met Jansen"},
+			wantDesc: "Klant [verwijderd] ([verwijderd]) samengevoegd met [verwijderd]",
+		},
+		{
+			name:        "waarden in overige velden",
+			entry:       auditModel.AuditLog{Description: "Taak aangemaakt", NewData: `{"title":"Bel 020-1234567","items":[{"omschrijving":"Brood voor Jansen"}]}`},
+			wantDesc:    "Taak aangemaakt",
+			wantNewData: `{"title":"Bel [verwijderd]","items":[{"omschrijving":"Brood voor [verwijderd]"}]}`,
+		},
+		{
+			name:        "geneste vrije velden",
+			entry:       auditModel.AuditLog{Description: "Vrije velden", NewData: `{"custom_fields":{"regio":"noord"},"changes":{"customer_name":"Jansen B.V."}}`},
+			wantDesc:    "Vrije velden",
+			wantNewData: `{"custom_fields":"[verwijderd]","changes":{"customer_name":"[verwijderd]"}}`,
+		},
+		{
+			name:        "data die geen JSON is",
+			entry:       auditModel.AuditLog{Description: "Import", OldData: "regel 3: info@jansen.nl bestaat al"},
+			wantDesc:    "Import",
+			wantOldData: "regel 3: [verwijderd] bestaat al",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := scrubber.scrub([]auditModel.AuditLog{tt.entry})
+			if len(changed) != 1 {
+				t.Fatalf("%d entries gewijzigd, verwacht 1", len(changed))
+			}
+			got := changed[0]
+			if got.Description != tt.wantDesc {
+				t.Errorf("omschrijving = %q, verwacht %q", got.Description, tt.wantDesc)
+			}
+			for _, data := range []struct{ got, want string }{{got.OldData, tt.wantOldData}, {got.NewData, tt.wantNewData}} {
+				if !json.Valid([]byte(data.want)) {
+					if data.got != data.want {
+						t.Errorf("data = %q, verwacht %q", data.got, data.want)
+					}
+				} else if !sameJSON(t, data.got, data.want) {
+					t.Errorf("data = %s, verwacht %s", data.got, data.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditScrubberUnchanged(t *testing.T) {
+	scrubber := newAuditScrubber()
+	scrubber.addCustomer(&customerModel.Customer{Name: "Jansen", Email: "info@jansen.nl"})
+
+	entries := []auditModel.AuditLog{
+		{ID: 1, Description: "Gebruiker ingelogd", NewData: `{"role":"ADMIN","user_id":3}`},
+		{ID: 2, Description: "Klant verwijderd", OldData: `{"name":"[verwijderd]","note":""}`},
+		{ID: 3, Description: "Factuur voor Jansen"},
+	}
+	changed := scrubber.scrub(entries)
+	if len(changed) != 1 || changed[0].ID != 3 {
+		t.Fatalf("gewijzigde entries = %+v, verwacht alleen entry 3", changed)
+	}
+	if entries[2].Description != "Factuur voor Jansen" {
+		t.Error("scrub heeft de originele entry gewijzigd")
+	}
+}
+
+func TestAuditScrubberMinLength(t *testing.T) {
+	scrubber := newAuditScrubber()
+	for _, value := range []string{"", "  ", "ab", " é ", model.Redacted} {
+		scrubber.add(value)
+	}
+	if len(scrubber.values) != 0 {
+		t.Errorf("te korte waarden verzameld: %v", scrubber.values)
+	}
+	if got := scrubber.replace("ab en é"); got != "ab en é" {
+		t.Errorf("replace zonder waarden = %q", got)
+	}
+
+	// Een waarde van drie tekens wordt wel vervangen, ook met speciale tekens voor reguliere expressies
+	scrubber.add("a+b")
+	if got := scrubber.replace("a+b en aab"); got != model.Redacted+" en aab" {
+		t.Errorf("replace = %q", got)
+	}
+}
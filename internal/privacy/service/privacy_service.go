@@ -0,0 +1,202 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	appointmentModel "odomosml/internal/appointment/model"
+	attachmentRepo "odomosml/internal/attachment/repository"
+	customerModel "odomosml/internal/customer/model"
+	"odomosml/internal/privacy/model"
+	"odomosml/internal/privacy/repository"
+	"odomosml/pkg/storage"
+	"reflect"
+	"strconv"
+)
+
+// exportReadme staat als LEESMIJ.txt in elke export
+const exportReadme = `Inzage in persoonsgegevens (AVG artikel 15)
+
+Deze export bevat alle gegevens die over de klant zijn vastgelegd, als JSON:
+
+klant.json                  De klantgegevens, met tags en vrije velden
+statusgeschiedenis.json     Statusovergangen met notities
+notities.json               Notities, gesprekken, afspraken en e-mails (activiteiten)
+bijlagen.json               Gegevens van de bijlagen; de bestanden zelf zijn op te vragen via de bijlagen van de klant
+deals.json                  Verkoopkansen
+taken.json                  Taken
+afspraken.json              Afspraken met deelnemers
+offertes-en-facturen.json   Offertes en facturen met regels
+prijsafspraken.json         Prijsafspraken
+audit-log.json              Alle wijzigingen aan de klant en aan zijn deals, taken, afspraken,
+                            notities en bijlagen, en wie ze deed
+`
+
+// ErrInvalidID betekent dat het klant ID geen geldig nummer is
+var ErrInvalidID = errors.New("ongeldig ID formaat")
+
+// PrivacyService definieert de interface voor inzage en wissen van persoonsgegevens (AVG)
+type PrivacyService interface {
+	GetExport(customerID string) (*model.Export, error)
+	WriteExport(export *model.Export, w io.Writer) error
+	EraseCustomer(ctx context.Context, customerID string) (*model.ErasureResult, error)
+}
+
+// privacyService implementeert de PrivacyService interface
+type privacyService struct {
+	repo           repository.PrivacyRepository
+	attachmentRepo attachmentRepo.AttachmentRepository
+	storage        storage.Storage
+}
+
+// NewPrivacyService maakt een nieuwe PrivacyService instantie
+func NewPrivacyService(repo repository.PrivacyRepository, attachmentRepo attachmentRepo.AttachmentRepository, storage storage.Storage) PrivacyService {
+	return &privacyService{
+		repo:           repo,
+		attachmentRepo: attachmentRepo,
+		storage:        storage,
+	}
+}
+
+// GetExport haalt alles op wat over een klant is vastgelegd
+func (s *privacyService) GetExport(customerID string) (*model.Export, error) {
+	id, err := parseID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindExport(id)
+}
+
+// WriteExport schrijft een export als ZIP met een JSON bestand per soort gegevens
+func (s *privacyService) WriteExport(export *model.Export, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"klant.json", export.Customer},
+		{"statusgeschiedenis.json", export.StatusHistory},
+		{"notities.json", export.Activities},
+		{"bijlagen.json", export.Attachments},
+		{"deals.json", export.Deals},
+		{"taken.json", export.Tasks},
+		{"afspraken.json", export.Appointments},
+		{"offertes-en-facturen.json", export.Documents},
+		{"prijsafspraken.json", export.PriceAgreements},
+		{"audit-log.json", export.AuditLogs},
+	}
+
+	header := &zip.FileHeader{Name: "LEESMIJ.txt", Method: zip.Deflate, Modified: export.GeneratedAt}
+	file, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(file, exportReadme); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: export.GeneratedAt})
+		if err != nil {
+			return err
+		}
+
+		// Een lege lijst als [] en niet als null
+		data := f.data
+		if isNilSlice(data) {
+			data = []struct{}{}
+		}
+
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(data); err != nil {
+			return fmt.Errorf("kan %s niet schrijven: %w", f.name, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+// EraseCustomer wist de persoonsgegevens van een klant (AVG artikel 17). De klant blijft bestaan,
+// geanonimiseerd, zodat deals, taken en omzet bewaard blijven; notities en bijlagen worden verwijderd
+// en de audit logs van de klant opgeschoond. Offertes en facturen vallen onder de fiscale bewaarplicht
+// en blijven ongewijzigd. Deelnemers van buiten aan afspraken van de klant krijgen een geanonimiseerd
+// e-mailadres en geen naam meer.
+func (s *privacyService) EraseCustomer(ctx context.Context, customerID string) (*model.ErasureResult, error) {
+	id, err := parseID(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	scrubber := newAuditScrubber()
+	anonymize := func(customer *customerModel.Customer) {
+		scrubber.addCustomer(customer)
+
+		customer.Name = fmt.Sprintf("Geanonimiseerde klant %d", customer.ID)
+		customer.Email = fmt.Sprintf("klant-%d@geanonimiseerd.invalid", customer.ID)
+		customer.Phone = ""
+		customer.Address = ""
+		customer.KvKNumber = ""
+		customer.VATNumber = ""
+		customer.CustomFields = customerModel.CustomFields{}
+	}
+
+	anonymizeAttendee := func(attendee *appointmentModel.Attendee) {
+		scrubber.add(attendee.Name)
+		scrubber.add(attendee.Email)
+
+		attendee.Name = ""
+		attendee.Email = fmt.Sprintf("deelnemer-%d@geanonimiseerd.invalid", attendee.ID)
+	}
+
+	result, err := s.repo.Erase(id, repository.Erasure{
+		Anonymize:         anonymize,
+		AnonymizeAttendee: anonymizeAttendee,
+		Collect:           scrubber.collect,
+		Scrub:             scrubber.scrub,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Verwijder de bestanden waar geen andere bijlage meer naar verwijst; een achtergebleven bestand
+	// is geen reden om te falen, de gegevens zijn al gewist
+	released := map[string]bool{}
+	for _, key := range result.StorageKeys {
+		if released[key] {
+			continue
+		}
+		released[key] = true
+
+		err := s.attachmentRepo.ReleaseStorageKey(key, func() {
+			if err := s.storage.Delete(ctx, key); err != nil {
+				log.Printf("Kan bestand %s niet uit de opslag verwijderen: %v", key, err)
+			}
+		})
+		if err != nil {
+			log.Printf("Kan bestand %s niet vrijgeven: %v", key, err)
+		}
+	}
+
+	return result, nil
+}
+
+// parseID converteert een klant ID
+func parseID(id string) (uint, error) {
+	value, err := strconv.ParseUint(id, 10, 32)
+	if err != nil || value == 0 {
+		return 0, ErrInvalidID
+	}
+	return uint(value), nil
+}
+
+// isNilSlice geeft aan of een waarde een lege lijst is die als null gecodeerd zou worden
+func isNilSlice(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Slice && v.IsNil()
+}
@@ -0,0 +1,491 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"odomosml/internal/product/model"
+	"odomosml/internal/product/repository"
+	"odomosml/internal/product/service"
+	"odomosml/pkg/validation"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProductHandler handles HTTP requests for products and price agreements
+type ProductHandler struct {
+	service   service.ProductService
+	maxSizeMB int
+}
+
+// NewProductHandler maakt een nieuwe ProductHandler instantie. maxSizeMB is de maximale grootte van een importbestand.
+func NewProductHandler(service service.ProductService, maxSizeMB int) *ProductHandler {
+	return &ProductHandler{
+		service:   service,
+		maxSizeMB: maxSizeMB,
+	}
+}
+
+// respondError stuurt een fout terug: 409 als een product nog op offertes of facturen staat,
+// 400 met validatiefouten per veld in fields, en anders status
+func respondError(c *gin.Context, status int, err error) {
+	response := gin.H{
+		"success": false,
+		"error":   err.Error(),
+	}
+	if errors.Is(err, repository.ErrInUse) {
+		status = http.StatusConflict
+	} else if fields, ok := validation.Fields(err); ok {
+		status = http.StatusBadRequest
+		response["fields"] = fields
+	}
+	c.JSON(status, response)
+}
+
+// @Summary      Lijst van producten ophalen
+// @Description  Haalt producten uit de prijslijst op, gesorteerd op SKU
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        page query int false "Paginanummer (default: 1)"
+// @Param        page_size query int false "Aantal items per pagina (default: 10, max: 100)"
+// @Param        zoekterm query string false "Zoekterm voor SKU en omschrijving"
+// @Param        active query bool false "Alleen actieve (true) of inactieve (false) producten"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      500  {object}  map[string]string "Server error"
+// @Security     Bearer
+// @Router       /products [get]
+func (h *ProductHandler) GetAll(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	filter := model.ProductFilter{
+		SearchTerm: c.Query("zoekterm"),
+		Page:       page,
+		PageSize:   pageSize,
+	}
+
+	if value := c.Query("active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errors.New("active moet true of false zijn"))
+			return
+		}
+		filter.Active = &active
+	}
+
+	products, total, err := h.service.GetProducts(filter)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    products,
+		"pagination": gin.H{
+			"current_page": filter.Page,
+			"page_size":    filter.PageSize,
+			"total_items":  total,
+			"total_pages":  (total + int64(filter.PageSize) - 1) / int64(filter.PageSize),
+		},
+	})
+}
+
+// @Summary      Product ophalen op ID
+// @Description  Haalt een product uit de prijslijst op
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Success      200  {object}  model.Product "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Product niet gevonden"
+// @Security     Bearer
+// @Router       /products/{id} [get]
+func (h *ProductHandler) GetByID(c *gin.Context) {
+	product, err := h.service.GetProduct(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    product,
+	})
+}
+
+// @Summary      Product aanmaken
+// @Description  Voegt een product toe aan de prijslijst. De SKU wordt in hoofdletters opgeslagen; zonder eenheid is de eenheid stuk, zonder BTW-categorie high (21%), zonder active is het product actief.
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        product body model.Product true "Product gegevens"
+// @Success      201  {object}  model.Product "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Security     Bearer
+// @Router       /products [post]
+func (h *ProductHandler) Create(c *gin.Context) {
+	// Een product zonder active in de body is actief
+	product := model.Product{Active: true}
+	if err := c.ShouldBindJSON(&product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	created, err := h.service.CreateProduct(&product)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Product bijwerken
+// @Description  Werkt een product bij. Zet active op false om een product uit de prijslijst te halen zonder het te verwijderen.
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Param        product body model.Product true "Product gegevens"
+// @Success      200  {object}  model.Product "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      404  {object}  map[string]string "Product niet gevonden"
+// @Security     Bearer
+// @Router       /products/{id} [put]
+func (h *ProductHandler) Update(c *gin.Context) {
+	existing, err := h.service.GetProduct(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// Velden die niet in de body staan behouden hun huidige waarde
+	product := *existing
+	if err := c.ShouldBindJSON(&product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	// Bewaar de huidige gegevens voor de audit diff
+	c.Set("productOldData", existing.ToAuditMap())
+
+	product.ID = existing.ID
+	updated, err := h.service.UpdateProduct(&product)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Product verwijderen
+// @Description  Verwijdert een product met de prijsafspraken. Een product dat op offertes of facturen staat kan niet verwijderd worden; zet het dan op inactief.
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      404  {object}  map[string]string "Product niet gevonden"
+// @Failure      409  {object}  map[string]string "Product staat op offertes of facturen"
+// @Security     Bearer
+// @Router       /products/{id} [delete]
+func (h *ProductHandler) Delete(c *gin.Context) {
+	productData, err := h.service.DeleteProduct(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// Sla productData op in context voor audit logging
+	c.Set("productData", productData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Product succesvol verwijderd",
+	})
+}
+
+// @Summary      Geldende prijs opvragen
+// @Description  Geeft de prijs van een product voor een klant op een datum: de prijsafspraak met de klant als die op die datum geldt, en anders de standaardprijs
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Param        customer_id query int false "Klant ID (zonder klant geldt de standaardprijs)"
+// @Param        date query string false "Datum in de vorm 2025-03-01 (default: vandaag)"
+// @Success      200  {object}  model.Price "Geldende prijs"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Product niet gevonden"
+// @Security     Bearer
+// @Router       /products/{id}/price [get]
+func (h *ProductHandler) Price(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errors.New("ongeldig ID formaat"))
+		return
+	}
+
+	var customerID uint64
+	if value := c.Query("customer_id"); value != "" {
+		customerID, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errors.New("customer_id moet een ID zijn"))
+			return
+		}
+	}
+
+	date := time.Now()
+	if value := c.Query("date"); value != "" {
+		date, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errors.New("date moet de vorm 2025-03-01 hebben"))
+			return
+		}
+	}
+
+	price, err := h.service.ResolvePrice(uint(productID), uint(customerID), date)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    price,
+	})
+}
+
+// @Summary      Producten importeren
+// @Description  Importeert producten uit een CSV bestand (scheidingsteken , ; of tab, UTF-8 of Windows-1252) met de kolommen sku, omschrijving en prijs (in euro's, bijv. 12,50) en optioneel eenheid, btw (21, 9 of 0) en actief (ja/nee). Bestaande producten worden op SKU bijgewerkt. Rijen met fouten worden overgeslagen en per regel gemeld.
+// @Tags         producten
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file formData file true "CSV bestand"
+// @Param        dry_run formData bool false "Alleen valideren, niets opslaan"
+// @Success      200  {object}  model.ImportResult "Import verwerkt"
+// @Failure      400  {object}  map[string]string "Ongeldig bestand"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      413  {object}  map[string]string "Bestand te groot"
+// @Security     Bearer
+// @Router       /products/import [post]
+func (h *ProductHandler) Import(c *gin.Context) {
+	maxBytes := int64(h.maxSizeMB) << 20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondError(c, http.StatusRequestEntityTooLarge, fmt.Errorf("Bestand is te groot: maximaal %d MB", h.maxSizeMB))
+			return
+		}
+		respondError(c, http.StatusBadRequest, errors.New("Geen bestand gevonden in veld 'file'"))
+		return
+	}
+	if fileHeader.Size > maxBytes {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Errorf("Bestand is te groot: maximaal %d MB", h.maxSizeMB))
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.PostForm("dry_run"))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errors.New("Kan bestand niet lezen"))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errors.New("Kan bestand niet lezen"))
+		return
+	}
+
+	result, err := h.service.ImportProducts(content, dryRun)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// @Summary      Prijsafspraken van een product
+// @Description  Haalt de prijsafspraken van een product op, per klant op begindatum
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Success      200  {array}   model.PriceAgreement "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Product niet gevonden"
+// @Security     Bearer
+// @Router       /products/{id}/agreements [get]
+func (h *ProductHandler) GetAgreements(c *gin.Context) {
+	agreements, err := h.service.GetAgreements(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    agreements,
+	})
+}
+
+// @Summary      Prijsafspraak aanmaken
+// @Description  Legt een afgesproken prijs met een klant vast, optioneel voor een periode. Afspraken met dezelfde klant voor hetzelfde product mogen niet overlappen.
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Param        agreement body model.PriceAgreement true "Klant, prijs en periode"
+// @Success      201  {object}  model.PriceAgreement "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      404  {object}  map[string]string "Product niet gevonden"
+// @Security     Bearer
+// @Router       /products/{id}/agreements [post]
+func (h *ProductHandler) CreateAgreement(c *gin.Context) {
+	if _, err := h.service.GetProduct(c.Param("id")); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	var agreement model.PriceAgreement
+	if err := c.ShouldBindJSON(&agreement); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	created, err := h.service.CreateAgreement(c.Param("id"), &agreement)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Prijsafspraak bijwerken
+// @Description  Werkt de klant, prijs, periode of notitie van een prijsafspraak bij
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Param        agreementId path string true "Prijsafspraak ID"
+// @Param        agreement body model.PriceAgreement true "Klant, prijs en periode"
+// @Success      200  {object}  model.PriceAgreement "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      404  {object}  map[string]string "Prijsafspraak niet gevonden"
+// @Security     Bearer
+// @Router       /products/{id}/agreements/{agreementId} [put]
+func (h *ProductHandler) UpdateAgreement(c *gin.Context) {
+	existing, err := h.service.GetAgreement(c.Param("id"), c.Param("agreementId"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	var agreement model.PriceAgreement
+	if err := c.ShouldBindJSON(&agreement); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	// Bewaar de huidige gegevens voor de audit diff
+	c.Set("productOldData", existing.ToAuditMap())
+
+	agreement.ID = existing.ID
+	updated, err := h.service.UpdateAgreement(c.Param("id"), &agreement)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Prijsafspraak verwijderen
+// @Description  Verwijdert een prijsafspraak; daarna geldt voor de klant weer de standaardprijs
+// @Tags         producten
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Param        agreementId path string true "Prijsafspraak ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      404  {object}  map[string]string "Prijsafspraak niet gevonden"
+// @Security     Bearer
+// @Router       /products/{id}/agreements/{agreementId} [delete]
+func (h *ProductHandler) DeleteAgreement(c *gin.Context) {
+	agreementData, err := h.service.DeleteAgreement(c.Param("id"), c.Param("agreementId"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// Sla agreementData op in context voor audit logging
+	c.Set("productData", agreementData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Prijsafspraak succesvol verwijderd",
+	})
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"time"
+)
+
+// VATCategory is de BTW-categorie van een product
+type VATCategory string
+
+// BTW-categorieën met het tarief in procenten
+const (
+	VATHigh VATCategory = "high" // 21%
+	VATLow  VATCategory = "low"  // 9%
+	VATZero VATCategory = "zero" // 0%, ook voor vrijgestelde en verlegde leveringen
+)
+
+// vatRates koppelt de BTW-categorieën aan hun tarief
+var vatRates = map[VATCategory]int{
+	VATHigh: 21,
+	VATLow:  9,
+	VATZero: 0,
+}
+
+// IsValid controleert of de BTW-categorie bestaat
+func (c VATCategory) IsValid() bool {
+	_, ok := vatRates[c]
+	return ok
+}
+
+// Rate geeft het BTW-tarief van de categorie in procenten
+func (c VATCategory) Rate() int {
+	return vatRates[c]
+}
+
+// Product is een artikel of dienst uit de prijslijst
+// @Description Een product uit de prijslijst
+type Product struct {
+	ID          uint        `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	SKU         string      `json:"sku" gorm:"column:sku;size:50;not null;uniqueIndex" binding:"required" example:"ONDH-UUR" swaggertype:"string"`
+	Description string      `json:"description" gorm:"not null" binding:"required" example:"Onderhoud per uur" swaggertype:"string"`
+	Unit        string      `json:"unit" gorm:"size:20;not null;default:'stuk'" example:"uur" swaggertype:"string"`
+	PriceCents  int64       `json:"price_cents" gorm:"not null;default:0" example:"8500" swaggertype:"integer"` // Standaardprijs per eenheid exclusief BTW, in centen
+	VATCategory VATCategory `json:"vat_category" gorm:"column:vat_category;size:10;not null;default:'high'" example:"high" swaggertype:"string"`
+	Active      bool        `json:"active" gorm:"not null;index" example:"true" swaggertype:"boolean"`
+	CreatedAt   time.Time   `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt   time.Time   `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Product) TableName() string {
+	return "products"
+}
+
+// ToAuditMap converteert een product naar een map voor audit logging
+func (p *Product) ToAuditMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":           p.ID,
+		"sku":          p.SKU,
+		"description":  p.Description,
+		"unit":         p.Unit,
+		"price_cents":  p.PriceCents,
+		"vat_category": p.VATCategory,
+		"active":       p.Active,
+	}
+}
+
+// PriceAgreement is een afgesproken prijs van een product voor een klant. Zonder ValidFrom of ValidUntil
+// is de afspraak aan die kant onbegrensd.
+// @Description Een prijsafspraak met een klant
+type PriceAgreement struct {
+	ID         uint       `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	ProductID  uint       `json:"product_id" gorm:"not null;index" example:"1" swaggertype:"integer"`
+	CustomerID uint       `json:"customer_id" gorm:"not null;index" binding:"required" example:"1" swaggertype:"integer"`
+	PriceCents int64      `json:"price_cents" gorm:"not null" example:"7500" swaggertype:"integer"`
+	ValidFrom  *time.Time `json:"valid_from" gorm:"type:date" example:"2025-01-01T00:00:00Z" swaggertype:"string" format:"date-time"`
+	ValidUntil *time.Time `json:"valid_until" gorm:"type:date" example:"2025-12-31T00:00:00Z" swaggertype:"string" format:"date-time"` // Laatste dag waarop de afspraak geldt
+	Note       string     `json:"note" example:"Raamcontract 2025" swaggertype:"string"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt  time.Time  `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (PriceAgreement) TableName() string {
+	return "price_agreements"
+}
+
+// ToAuditMap converteert een prijsafspraak naar een map voor audit logging
+func (a *PriceAgreement) ToAuditMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":          a.ID,
+		"product_id":  a.ProductID,
+		"customer_id": a.CustomerID,
+		"price_cents": a.PriceCents,
+		"valid_from":  a.ValidFrom,
+		"valid_until": a.ValidUntil,
+	}
+}
+
+// Herkomst van een prijs
+const (
+	PriceSourceDefault   = "default"
+	PriceSourceAgreement = "agreement"
+)
+
+// Price is de prijs die voor een klant op een datum geldt
+// @Description De geldende prijs van een product voor een klant
+type Price struct {
+	ProductID   uint        `json:"product_id" example:"1" swaggertype:"integer"`
+	CustomerID  uint        `json:"customer_id" example:"1" swaggertype:"integer"`
+	Date        string      `json:"date" example:"2025-03-01" swaggertype:"string"`
+	SKU         string      `json:"sku" example:"ONDH-UUR" swaggertype:"string"`
+	Description string      `json:"description" example:"Onderhoud per uur" swaggertype:"string"`
+	Unit        string      `json:"unit" example:"uur" swaggertype:"string"`
+	PriceCents  int64       `json:"price_cents" example:"7500" swaggertype:"integer"`
+	VATCategory VATCategory `json:"vat_category" example:"high" swaggertype:"string"`
+	VATRate     int         `json:"vat_rate" example:"21" swaggertype:"integer"`
+	Active      bool        `json:"active" example:"true" swaggertype:"boolean"`
+	Source      string      `json:"source" example:"agreement" swaggertype:"string"` // default of agreement
+	AgreementID *uint       `json:"agreement_id,omitempty" swaggertype:"integer"`
+}
+
+// ProductFilter definieert filters voor het ophalen van producten
+type ProductFilter struct {
+	SearchTerm string
+	Active     *bool
+	Page       int
+	PageSize   int
+}
+
+// ImportResult is het resultaat van een productimport
+// @Description Resultaat van een productimport
+type ImportResult struct {
+	Created int              `json:"created" example:"12" swaggertype:"integer"`
+	Updated int              `json:"updated" example:"3" swaggertype:"integer"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+// ImportRowError is een fout in een rij van een productimport; de kopregel is regel 1
+type ImportRowError struct {
+	Row     int    `json:"row" example:"4" swaggertype:"integer"`
+	Message string `json:"message" example:"prijs '12,5x' is geen bedrag" swaggertype:"string"`
+}
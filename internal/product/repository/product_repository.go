@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/product/model"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInUse betekent dat een product op offertes of facturen staat en daarom niet verwijderd kan worden
+var ErrInUse = errors.New("product staat op offertes of facturen; zet het product op inactief in plaats van het te verwijderen")
+
+// ProductRepository definieert de interface voor product repository
+type ProductRepository interface {
+	FindAll(filter model.ProductFilter) ([]model.Product, int64, error)
+	FindByID(id string) (*model.Product, error)
+	FindBySKU(sku string) (*model.Product, error)
+	FindExistingSKUs(skus []string) (map[string]bool, error)
+	Create(product *model.Product) (*model.Product, error)
+	Update(product *model.Product) (*model.Product, error)
+	Upsert(products []model.Product) error
+	Delete(id uint) error
+	FindAgreements(productID uint) ([]model.PriceAgreement, error)
+	FindAgreementByID(productID uint, id string) (*model.PriceAgreement, error)
+	FindCustomerAgreements(productID, customerID uint) ([]model.PriceAgreement, error)
+	FindEffectiveAgreement(productID, customerID uint, date time.Time) (*model.PriceAgreement, error)
+	CreateAgreement(agreement *model.PriceAgreement) (*model.PriceAgreement, error)
+	UpdateAgreement(agreement *model.PriceAgreement) (*model.PriceAgreement, error)
+	DeleteAgreement(id uint) error
+}
+
+// productRepository implementeert de ProductRepository interface
+type productRepository struct {
+	db *gorm.DB
+}
+
+// NewProductRepository maakt een nieuwe ProductRepository instantie
+func NewProductRepository(db *gorm.DB) ProductRepository {
+	return &productRepository{
+		db: db,
+	}
+}
+
+// FindAll haalt producten op met filters, gesorteerd op SKU
+func (r *productRepository) FindAll(filter model.ProductFilter) ([]model.Product, int64, error) {
+	var products []model.Product
+	var total int64
+
+	query := r.db.Model(&model.Product{})
+
+	if filter.SearchTerm != "" {
+		searchTerm := "%" + filter.SearchTerm + "%"
+		query = query.Where("sku ILIKE ? OR description ILIKE ?", searchTerm, searchTerm)
+	}
+
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+
+	// Tel totaal aantal records (voor paginering)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Paginering toepassen
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query = query.Offset(offset).Limit(filter.PageSize)
+	}
+
+	if err := query.Order("sku ASC").Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// FindByID haalt een product op op basis van ID
+func (r *productRepository) FindByID(id string) (*model.Product, error) {
+	var product model.Product
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&product, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("product niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// FindBySKU haalt een product op op basis van SKU, of nil als het niet bestaat
+func (r *productRepository) FindBySKU(sku string) (*model.Product, error) {
+	var product model.Product
+
+	if err := r.db.Where("sku = ?", sku).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// FindExistingSKUs geeft aan welke van de SKU's al bestaan
+func (r *productRepository) FindExistingSKUs(skus []string) (map[string]bool, error) {
+	var existing []string
+	if err := r.db.Model(&model.Product{}).Where("sku IN ?", skus).Pluck("sku", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(existing))
+	for _, sku := range existing {
+		found[sku] = true
+	}
+	return found, nil
+}
+
+// Create maakt een nieuw product aan
+func (r *productRepository) Create(product *model.Product) (*model.Product, error) {
+	if err := r.db.Create(product).Error; err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// Update werkt een bestaand product bij
+func (r *productRepository) Update(product *model.Product) (*model.Product, error) {
+	err := r.db.Model(product).
+		Select("sku", "description", "unit", "price_cents", "vat_category", "active", "updated_at").
+		Updates(product).Error
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// Upsert maakt de producten aan of werkt ze bij op basis van de SKU, in één transactie
+func (r *productRepository) Upsert(products []model.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sku"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "unit", "price_cents", "vat_category", "active", "updated_at"}),
+	}).CreateInBatches(&products, 500).Error
+}
+
+// Delete verwijdert een product met de prijsafspraken, als het op geen enkele offerte of factuur staat
+func (r *productRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table("invoice_lines").Where("product_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrInUse
+		}
+
+		if err := tx.Where("product_id = ?", id).Delete(&model.PriceAgreement{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Product{}, id).Error
+	})
+}
+
+// FindAgreements haalt de prijsafspraken van een product op, per klant op begindatum
+func (r *productRepository) FindAgreements(productID uint) ([]model.PriceAgreement, error) {
+	var agreements []model.PriceAgreement
+
+	err := r.db.Where("product_id = ?", productID).
+		Order("customer_id ASC, valid_from ASC NULLS FIRST, id ASC").
+		Find(&agreements).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return agreements, nil
+}
+
+// FindAgreementByID haalt een prijsafspraak van een product op op basis van ID
+func (r *productRepository) FindAgreementByID(productID uint, id string) (*model.PriceAgreement, error) {
+	var agreement model.PriceAgreement
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.Where("product_id = ?", productID).First(&agreement, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("prijsafspraak niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &agreement, nil
+}
+
+// FindCustomerAgreements haalt de prijsafspraken van een product met één klant op
+func (r *productRepository) FindCustomerAgreements(productID, customerID uint) ([]model.PriceAgreement, error) {
+	var agreements []model.PriceAgreement
+
+	err := r.db.Where("product_id = ? AND customer_id = ?", productID, customerID).Find(&agreements).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return agreements, nil
+}
+
+// FindEffectiveAgreement haalt de prijsafspraak op die op date voor de klant geldt, of nil als er geen is
+func (r *productRepository) FindEffectiveAgreement(productID, customerID uint, date time.Time) (*model.PriceAgreement, error) {
+	var agreement model.PriceAgreement
+
+	day := date.Format("2006-01-02")
+	err := r.db.Where("product_id = ? AND customer_id = ?", productID, customerID).
+		Where("(valid_from IS NULL OR valid_from <= ?) AND (valid_until IS NULL OR valid_until >= ?)", day, day).
+		Order("valid_from DESC NULLS LAST, id DESC").
+		First(&agreement).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &agreement, nil
+}
+
+// CreateAgreement maakt een nieuwe prijsafspraak aan
+func (r *productRepository) CreateAgreement(agreement *model.PriceAgreement) (*model.PriceAgreement, error) {
+	if err := r.db.Create(agreement).Error; err != nil {
+		return nil, err
+	}
+	return agreement, nil
+}
+
+// UpdateAgreement werkt een prijsafspraak bij
+func (r *productRepository) UpdateAgreement(agreement *model.PriceAgreement) (*model.PriceAgreement, error) {
+	err := r.db.Model(agreement).
+		Select("customer_id", "price_cents", "valid_from", "valid_until", "note", "updated_at").
+		Updates(agreement).Error
+	if err != nil {
+		return nil, err
+	}
+	return agreement, nil
+}
+
+// DeleteAgreement verwijdert een prijsafspraak
+func (r *productRepository) DeleteAgreement(id uint) error {
+	return r.db.Delete(&model.PriceAgreement{}, id).Error
+}
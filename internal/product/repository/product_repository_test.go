@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"odomosml/internal/product/model"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testSKU is de SKU van het testproduct, zodat echte producten niet geraakt worden
+const testSKU = "TEST-PRIJSAFSPRAAK"
+
+// newTestRepository maakt een ProductRepository tegen de database in TEST_DATABASE_DSN, bijv.
+// "host=localhost user=postgres password=postgres dbname=odomosml_test sslmode=disable". Zonder die
+// variabele wordt de test overgeslagen.
+func newTestRepository(t *testing.T) (ProductRepository, *model.Product) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is niet gezet")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("kon niet verbinden met de database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Product{}, &model.PriceAgreement{}); err != nil {
+		t.Fatalf("kon schema niet migreren: %v", err)
+	}
+
+	cleanup := func() {
+		var product model.Product
+		if db.Where("sku = ?", testSKU).First(&product).Error == nil {
+			db.Where("product_id = ?", product.ID).Delete(&model.PriceAgreement{})
+			db.Delete(&product)
+		}
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	repo := NewProductRepository(db)
+	product, err := repo.Create(&model.Product{SKU: testSKU, Description: "Prijsafspraaktest", Unit: "uur", PriceCents: 8500, VATCategory: model.VATHigh, Active: true})
+	if err != nil {
+		t.Fatalf("Create gaf fout: %v", err)
+	}
+	return repo, product
+}
+
+func date(year int, month time.Month, day int) *time.Time {
+	d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &d
+}
+
+func TestFindEffectiveAgreement(t *testing.T) {
+	repo, product := newTestRepository(t)
+
+	// De service voorkomt overlappende afspraken, maar oudere gegevens kunnen ze bevatten; dan wint de
+	// afspraak met de laatste begindatum, en een afspraak zonder begindatum als laatste
+	agreements := []model.PriceAgreement{
+		{CustomerID: 1, PriceCents: 8000},
+		{CustomerID: 1, PriceCents: 7500, ValidFrom: date(2025, 1, 1), ValidUntil: date(2025, 12, 31)},
+		{CustomerID: 1, PriceCents: 7200, ValidFrom: date(2025, 7, 1), ValidUntil: date(2025, 7, 31)},
+		{CustomerID: 2, PriceCents: 6000, ValidUntil: date(2024, 12, 31)},
+	}
+	ids := make([]uint, len(agreements))
+	for i := range agreements {
+		agreements[i].ProductID = product.ID
+		created, err := repo.CreateAgreement(&agreements[i])
+		if err != nil {
+			t.Fatalf("CreateAgreement gaf fout: %v", err)
+		}
+		ids[i] = created.ID
+	}
+
+	tests := []struct {
+		name       string
+		customerID uint
+		date       time.Time
+		want       uint
+	}{
+		{"alleen de onbegrensde afspraak", 1, *date(2024, 6, 1), ids[0]},
+		{"begindatum gaat voor onbegrensd", 1, *date(2025, 1, 1), ids[1]},
+		{"latere begindatum gaat voor", 1, *date(2025, 7, 15), ids[2]},
+		{"laatste dag telt mee", 1, *date(2025, 7, 31), ids[2]},
+		{"na de kortere afspraak", 1, *date(2025, 8, 1), ids[1]},
+		{"na alle afspraken met een einde", 1, *date(2026, 1, 1), ids[0]},
+		{"tot en met de einddatum", 2, *date(2024, 12, 31), ids[3]},
+		{"na de einddatum", 2, *date(2025, 1, 1), 0},
+		{"andere klant", 3, *date(2025, 1, 1), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agreement, err := repo.FindEffectiveAgreement(product.ID, tt.customerID, tt.date)
+			if err != nil {
+				t.Fatalf("FindEffectiveAgreement gaf fout: %v", err)
+			}
+			var got uint
+			if agreement != nil {
+				got = agreement.ID
+			}
+			if got != tt.want {
+				t.Errorf("afspraak %d, verwacht %d", got, tt.want)
+			}
+		})
+	}
+}
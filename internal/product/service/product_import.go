@@ -0,0 +1,268 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"odomosml/internal/product/model"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// maxImportRows is het maximum aantal producten per import
+const maxImportRows = 10000
+
+// Velden van een productimport
+const (
+	columnSKU         = "sku"
+	columnDescription = "description"
+	columnUnit        = "unit"
+	columnPrice       = "price"
+	columnVAT         = "vat"
+	columnActive      = "active"
+)
+
+// importColumns koppelt herkende kolomkoppen (in kleine letters) aan de velden van een product
+var importColumns = map[string]string{
+	"sku":           columnSKU,
+	"artikelnummer": columnSKU,
+	"code":          columnSKU,
+	"omschrijving":  columnDescription,
+	"description":   columnDescription,
+	"naam":          columnDescription,
+	"eenheid":       columnUnit,
+	"unit":          columnUnit,
+	"prijs":         columnPrice,
+	"price":         columnPrice,
+	"btw":           columnVAT,
+	"vat":           columnVAT,
+	"vat_category":  columnVAT,
+	"btw-tarief":    columnVAT,
+	"actief":        columnActive,
+	"active":        columnActive,
+}
+
+// vatValues koppelt de waarden van de BTW kolom aan een categorie
+var vatValues = map[string]model.VATCategory{
+	"21": model.VATHigh, "21%": model.VATHigh, "high": model.VATHigh, "hoog": model.VATHigh,
+	"9": model.VATLow, "9%": model.VATLow, "low": model.VATLow, "laag": model.VATLow,
+	"0": model.VATZero, "0%": model.VATZero, "zero": model.VATZero, "nul": model.VATZero,
+}
+
+// activeValues koppelt de waarden van de actief kolom aan een boolean
+var activeValues = map[string]bool{
+	"ja": true, "j": true, "true": true, "1": true, "yes": true, "y": true,
+	"nee": false, "n": false, "false": false, "0": false, "no": false,
+}
+
+// amountPattern herkent een bedrag in euro's, met of zonder scheiding van duizendtallen
+var amountPattern = regexp.MustCompile(`^-?\d{1,3}([.,\s]?\d{3})*([.,]\d{1,2})?$`)
+
+// ImportProducts importeert producten uit een CSV bestand met een kopregel. Bestaande producten worden
+// op SKU bijgewerkt. Rijen met fouten worden overgeslagen en in het resultaat gemeld; met dryRun
+// wordt niets opgeslagen. Zonder actief kolom zijn de producten actief.
+func (s *productService) ImportProducts(content []byte, dryRun bool) (*model.ImportResult, error) {
+	records, lines, err := readProductCSV(content)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, header := range records[0] {
+		if field, ok := importColumns[strings.ToLower(strings.TrimSpace(header))]; ok {
+			if _, duplicate := columns[field]; duplicate {
+				return nil, fmt.Errorf("kolom voor %s komt meer dan eens voor", field)
+			}
+			columns[field] = i
+		}
+	}
+	for _, required := range []string{columnSKU, columnDescription, columnPrice} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("kolom %s ontbreekt; herkende kolommen: sku, omschrijving, eenheid, prijs, btw, actief", required)
+		}
+	}
+
+	result := &model.ImportResult{Errors: []model.ImportRowError{}}
+	var products []model.Product
+	seen := make(map[string]int)
+
+	for i, record := range records[1:] {
+		row := lines[i+1]
+		value := func(field string) string {
+			if index, ok := columns[field]; ok && index < len(record) {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		product, problems := parseProductRow(value)
+		if len(problems) == 0 {
+			problems = validateFields(&product).Error()
+			if problems == "" {
+				if first, ok := seen[product.SKU]; ok {
+					problems = fmt.Sprintf("SKU '%s' staat ook op regel %d", product.SKU, first)
+				}
+			}
+		}
+		if problems != "" {
+			result.Errors = append(result.Errors, model.ImportRowError{Row: row, Message: problems})
+			continue
+		}
+
+		seen[product.SKU] = row
+		products = append(products, product)
+	}
+
+	if len(products) > 0 {
+		skus := make([]string, len(products))
+		for i, product := range products {
+			skus[i] = product.SKU
+		}
+		existing, err := s.repo.FindExistingSKUs(skus)
+		if err != nil {
+			return nil, err
+		}
+		result.Updated = len(existing)
+		result.Created = len(products) - len(existing)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := s.repo.Upsert(products); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseProductRow zet de waarden van een rij om in een product; de velden worden daarna gevalideerd
+func parseProductRow(value func(field string) string) (model.Product, string) {
+	product := model.Product{
+		SKU:         value(columnSKU),
+		Description: value(columnDescription),
+		Unit:        value(columnUnit),
+		Active:      true,
+	}
+
+	price, err := parseAmount(value(columnPrice))
+	if err != nil {
+		return product, err.Error()
+	}
+	product.PriceCents = price
+
+	if vat := strings.ToLower(value(columnVAT)); vat != "" {
+		category, ok := vatValues[vat]
+		if !ok {
+			return product, fmt.Sprintf("BTW '%s' is onbekend; gebruik 21, 9 of 0", vat)
+		}
+		product.VATCategory = category
+	}
+
+	if active := strings.ToLower(value(columnActive)); active != "" {
+		flag, ok := activeValues[active]
+		if !ok {
+			return product, fmt.Sprintf("actief '%s' is onbekend; gebruik ja of nee", active)
+		}
+		product.Active = flag
+	}
+
+	return product, ""
+}
+
+// parseAmount zet een bedrag in euro's ("12,50", "1.234,50", "€ 12.5") om in centen. Het laatste
+// scheidingsteken met één of twee cijfers erachter scheidt de centen; andere punten, komma's en
+// spaties scheiden duizendtallen.
+func parseAmount(text string) (int64, error) {
+	cleaned := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "€"))
+	if cleaned == "" {
+		return 0, errors.New("prijs is verplicht")
+	}
+	if !amountPattern.MatchString(cleaned) {
+		return 0, fmt.Errorf("prijs '%s' is geen bedrag", text)
+	}
+
+	euros, cents := cleaned, "00"
+	if i := strings.LastIndexAny(cleaned, ".,"); i >= 0 && len(cleaned)-i-1 <= 2 {
+		euros, cents = cleaned[:i], cleaned[i+1:]
+		if len(cents) == 1 {
+			cents += "0"
+		}
+	}
+	euros = strings.NewReplacer(".", "", ",", "", " ", "").Replace(euros)
+
+	negative := strings.HasPrefix(euros, "-")
+	whole, err := strconv.ParseInt(strings.TrimPrefix(euros, "-"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("prijs '%s' is geen bedrag", text)
+	}
+	fraction, _ := strconv.ParseInt(cents, 10, 64)
+
+	amount := whole*100 + fraction
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// readProductCSV leest een CSV bestand met scheidingsteken , ; of tab. Een bestand dat geen geldige
+// UTF-8 is, wordt gelezen als Windows-1252 (zoals Excel het opslaat). Retourneert ook per record de
+// regel in het bestand waarop het begint, want de CSV reader slaat lege regels over.
+func readProductCSV(content []byte) ([][]string, []int, error) {
+	content = bytes.TrimPrefix(content, []byte{0xEF, 0xBB, 0xBF})
+	if !utf8.Valid(content) {
+		decoded, err := charmap.Windows1252.NewDecoder().Bytes(content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("kan bestand niet lezen: %w", err)
+		}
+		content = decoded
+	}
+
+	header := content
+	if i := bytes.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+	delimiter, best := ',', 0
+	for _, candidate := range []rune{',', ';', '\t'} {
+		if count := bytes.Count(header, []byte(string(candidate))); count > best {
+			delimiter, best = candidate, count
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+
+	var (
+		records [][]string
+		lines   []int
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("ongeldig CSV bestand: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+		if len(records) > maxImportRows+1 {
+			return nil, nil, fmt.Errorf("bestand bevat meer dan %d rijen", maxImportRows)
+		}
+	}
+
+	if len(records) < 2 {
+		return nil, nil, errors.New("bestand bevat geen rijen onder de kopregel")
+	}
+	return records, lines, nil
+}
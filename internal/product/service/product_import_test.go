@@ -0,0 +1,124 @@
+package service
+
+import (
+	"odomosml/internal/product/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    int64
+		wantErr string
+	}{
+		{"12", 1200, ""},
+		{"12,50", 1250, ""},
+		{"12.5", 1250, ""},
+		{"€ 0,05", 5, ""},
+		{"1.234,56", 123456, ""},
+		{"1,234.56", 123456, ""},
+		{"1 234,5", 123450, ""},
+		{"1.234", 123400, ""},
+		{"1.234.567", 123456700, ""},
+		{"-3,10", -310, ""},
+		{"", 0, "prijs is verplicht"},
+		{"€", 0, "prijs is verplicht"},
+		{"12,5x", 0, "prijs '12,5x' is geen bedrag"},
+		{"12,345", 1234500, ""},
+		{"1,2,3", 0, "prijs '1,2,3' is geen bedrag"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAmount(tt.text)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseAmount(%q) gaf %v, verwacht %q", tt.text, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseAmount(%q) = %d, %v; verwacht %d", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestImportProducts(t *testing.T) {
+	content := "Artikelnummer;Omschrijving;Eenheid;Prijs;BTW;Actief\r\n" +
+		"ondh-uur;Onderhoud per uur;uur;85,00;21%;ja\r\n" +
+		"\r\n" +
+		"LIC-1;\"Licentie\nper jaar\";;1.200;hoog;\r\n" +
+		"BOEK;Handboek;;12,5x;9;\r\n" +
+		"KABEL;Kabel;meter;1,25;6;ja\r\n" +
+		"ONDH-UUR;Onderhoud dubbel;;90;;nee\r\n" +
+		"OUD;Oud product;;10;0;nee\r\n"
+
+	repo := newFakeProductRepository()
+	repo.skus = map[string]bool{"LIC-1": true}
+	result, err := NewProductService(repo, nil).ImportProducts([]byte(content), false)
+	if err != nil {
+		t.Fatalf("ImportProducts gaf fout: %v", err)
+	}
+
+	// De regelnummers zijn die van het bestand: na de lege regel 3 en de regel die over 4 en 5 loopt
+	wantErrors := []model.ImportRowError{
+		{Row: 6, Message: "prijs '12,5x' is geen bedrag"},
+		{Row: 7, Message: "BTW '6' is onbekend; gebruik 21, 9 of 0"},
+		{Row: 8, Message: "SKU 'ONDH-UUR' staat ook op regel 2"},
+	}
+	if !reflect.DeepEqual(result.Errors, wantErrors) {
+		t.Errorf("fouten = %+v, verwacht %+v", result.Errors, wantErrors)
+	}
+	if result.Created != 2 || result.Updated != 1 {
+		t.Errorf("%d aangemaakt, %d bijgewerkt; verwacht 2 en 1", result.Created, result.Updated)
+	}
+
+	wantProducts := []model.Product{
+		{SKU: "ONDH-UUR", Description: "Onderhoud per uur", Unit: "uur", PriceCents: 8500, VATCategory: model.VATHigh, Active: true},
+		{SKU: "LIC-1", Description: "Licentie\nper jaar", Unit: "stuk", PriceCents: 120000, VATCategory: model.VATHigh, Active: true},
+		{SKU: "OUD", Description: "Oud product", Unit: "stuk", PriceCents: 1000, VATCategory: model.VATZero, Active: false},
+	}
+	if !reflect.DeepEqual(repo.upserted, wantProducts) {
+		t.Errorf("opgeslagen = %+v, verwacht %+v", repo.upserted, wantProducts)
+	}
+
+	// Een proefimport geeft hetzelfde resultaat maar slaat niets op
+	repo.upserted = nil
+	dryRun, err := NewProductService(repo, nil).ImportProducts([]byte(content), true)
+	if err != nil || !reflect.DeepEqual(dryRun, result) || repo.upserted != nil {
+		t.Errorf("proefimport = %+v, %v; opgeslagen %v", dryRun, err, repo.upserted)
+	}
+}
+
+func TestImportProductsInvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"alleen een kopregel", "sku,omschrijving,prijs\n", "bestand bevat geen rijen onder de kopregel"},
+		{"zonder prijs", "sku,omschrijving\nA,B\n", "kolom price ontbreekt"},
+		{"dubbele kolom", "sku,code,omschrijving,prijs\nA,A,B,1\n", "kolom voor sku komt meer dan eens voor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProductService(newFakeProductRepository(), nil).ImportProducts([]byte(tt.content), true)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("ImportProducts gaf %v, verwacht %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadProductCSVWindows1252(t *testing.T) {
+	// "Café" in Windows-1252, met een tab als scheidingsteken
+	records, _, err := readProductCSV([]byte("sku\tomschrijving\tprijs\nKOF\tCaf\xe9\t2,50\n"))
+	if err != nil {
+		t.Fatalf("readProductCSV gaf fout: %v", err)
+	}
+	if records[1][1] != "Café" {
+		t.Errorf("omschrijving = %q, verwacht Café", records[1][1])
+	}
+}
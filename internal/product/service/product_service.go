@@ -0,0 +1,342 @@
+package service
+
+import (
+	"fmt"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/internal/product/model"
+	"odomosml/internal/product/repository"
+	"odomosml/pkg/validation"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Grenzen aan de velden van een product
+const (
+	maxSKULength  = 50
+	maxUnitLength = 20
+	defaultUnit   = "stuk"
+)
+
+// skuPattern beperkt een SKU tot letters, cijfers en . _ / -
+var skuPattern = regexp.MustCompile(`^[A-Z0-9._/-]+$`)
+
+// PriceService bepaalt de geldende prijs van een product; andere modules gebruiken alleen deze interface
+type PriceService interface {
+	ResolvePrice(productID, customerID uint, date time.Time) (*model.Price, error)
+}
+
+// ProductService definieert de interface voor product service
+type ProductService interface {
+	PriceService
+	GetProducts(filter model.ProductFilter) ([]model.Product, int64, error)
+	GetProduct(id string) (*model.Product, error)
+	CreateProduct(product *model.Product) (*model.Product, error)
+	UpdateProduct(product *model.Product) (*model.Product, error)
+	DeleteProduct(id string) (map[string]interface{}, error)
+	GetAgreements(productID string) ([]model.PriceAgreement, error)
+	GetAgreement(productID, id string) (*model.PriceAgreement, error)
+	CreateAgreement(productID string, agreement *model.PriceAgreement) (*model.PriceAgreement, error)
+	UpdateAgreement(productID string, agreement *model.PriceAgreement) (*model.PriceAgreement, error)
+	DeleteAgreement(productID, id string) (map[string]interface{}, error)
+	ImportProducts(content []byte, dryRun bool) (*model.ImportResult, error)
+}
+
+// productService implementeert de ProductService interface
+type productService struct {
+	repo         repository.ProductRepository
+	customerRepo customerRepo.CustomerRepository
+}
+
+// NewProductService maakt een nieuwe ProductService instantie
+func NewProductService(repo repository.ProductRepository, customerRepo customerRepo.CustomerRepository) ProductService {
+	return &productService{
+		repo:         repo,
+		customerRepo: customerRepo,
+	}
+}
+
+// GetProducts haalt producten op met filters
+func (s *productService) GetProducts(filter model.ProductFilter) ([]model.Product, int64, error) {
+	// Valideer paginering
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 10 // Default page size
+	}
+
+	return s.repo.FindAll(filter)
+}
+
+// GetProduct haalt een product op op basis van ID
+func (s *productService) GetProduct(id string) (*model.Product, error) {
+	return s.repo.FindByID(id)
+}
+
+// CreateProduct maakt een nieuw product aan
+func (s *productService) CreateProduct(product *model.Product) (*model.Product, error) {
+	product.ID = 0
+	if err := s.validate(product); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(product)
+}
+
+// UpdateProduct werkt een bestaand product bij
+func (s *productService) UpdateProduct(product *model.Product) (*model.Product, error) {
+	existing, err := s.repo.FindByID(strconv.FormatUint(uint64(product.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(product); err != nil {
+		return nil, err
+	}
+
+	product.CreatedAt = existing.CreatedAt
+	return s.repo.Update(product)
+}
+
+// DeleteProduct verwijdert een product met de prijsafspraken en retourneert de data voor audit logging
+func (s *productService) DeleteProduct(id string) (map[string]interface{}, error) {
+	product, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	productData := product.ToAuditMap()
+
+	if err := s.repo.Delete(product.ID); err != nil {
+		return nil, err
+	}
+
+	return productData, nil
+}
+
+// GetAgreements haalt de prijsafspraken van een product op
+func (s *productService) GetAgreements(productID string) ([]model.PriceAgreement, error) {
+	product, err := s.repo.FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindAgreements(product.ID)
+}
+
+// GetAgreement haalt een prijsafspraak van een product op
+func (s *productService) GetAgreement(productID, id string) (*model.PriceAgreement, error) {
+	product, err := s.repo.FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindAgreementByID(product.ID, id)
+}
+
+// CreateAgreement legt een prijsafspraak met een klant vast
+func (s *productService) CreateAgreement(productID string, agreement *model.PriceAgreement) (*model.PriceAgreement, error) {
+	product, err := s.repo.FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	agreement.ID = 0
+	agreement.ProductID = product.ID
+	if err := s.validateAgreement(agreement); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateAgreement(agreement)
+}
+
+// UpdateAgreement werkt een prijsafspraak bij
+func (s *productService) UpdateAgreement(productID string, agreement *model.PriceAgreement) (*model.PriceAgreement, error) {
+	existing, err := s.GetAgreement(productID, strconv.FormatUint(uint64(agreement.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	agreement.ProductID = existing.ProductID
+	if err := s.validateAgreement(agreement); err != nil {
+		return nil, err
+	}
+
+	agreement.CreatedAt = existing.CreatedAt
+	return s.repo.UpdateAgreement(agreement)
+}
+
+// DeleteAgreement verwijdert een prijsafspraak en retourneert de data voor audit logging
+func (s *productService) DeleteAgreement(productID, id string) (map[string]interface{}, error) {
+	agreement, err := s.GetAgreement(productID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	agreementData := agreement.ToAuditMap()
+
+	if err := s.repo.DeleteAgreement(agreement.ID); err != nil {
+		return nil, err
+	}
+
+	return agreementData, nil
+}
+
+// ResolvePrice bepaalt de prijs die op date voor de klant geldt: de prijsafspraak met de klant als die
+// er op die dag is, en anders de standaardprijs. Bij customerID 0 geldt altijd de standaardprijs.
+func (s *productService) ResolvePrice(productID, customerID uint, date time.Time) (*model.Price, error) {
+	product, err := s.repo.FindByID(strconv.FormatUint(uint64(productID), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	price := &model.Price{
+		ProductID:   product.ID,
+		CustomerID:  customerID,
+		Date:        date.Format("2006-01-02"),
+		SKU:         product.SKU,
+		Description: product.Description,
+		Unit:        product.Unit,
+		PriceCents:  product.PriceCents,
+		VATCategory: product.VATCategory,
+		VATRate:     product.VATCategory.Rate(),
+		Active:      product.Active,
+		Source:      model.PriceSourceDefault,
+	}
+
+	if customerID == 0 {
+		return price, nil
+	}
+
+	agreement, err := s.repo.FindEffectiveAgreement(product.ID, customerID, date)
+	if err != nil {
+		return nil, err
+	}
+	if agreement != nil {
+		price.PriceCents = agreement.PriceCents
+		price.Source = model.PriceSourceAgreement
+		price.AgreementID = &agreement.ID
+	}
+
+	return price, nil
+}
+
+// validate controleert en normaliseert de velden van een product. De SKU wordt in hoofdletters
+// opgeslagen, zodat "ondh-uur" en "ONDH-UUR" hetzelfde product zijn.
+func (s *productService) validate(product *model.Product) error {
+	problems := validateFields(product)
+
+	if product.SKU != "" {
+		existing, err := s.repo.FindBySKU(product.SKU)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.ID != product.ID {
+			problems = append(problems, validation.FieldError{Field: "sku", Message: fmt.Sprintf("SKU '%s' is al in gebruik", product.SKU)})
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// validateFields controleert en normaliseert de velden van een product zonder de database te raadplegen
+func validateFields(product *model.Product) validation.Errors {
+	var problems validation.Errors
+
+	product.SKU = strings.ToUpper(strings.TrimSpace(product.SKU))
+	switch {
+	case product.SKU == "":
+		problems = append(problems, validation.FieldError{Field: "sku", Message: "SKU is verplicht"})
+	case len(product.SKU) > maxSKULength:
+		problems = append(problems, validation.FieldError{Field: "sku", Message: fmt.Sprintf("SKU mag maximaal %d tekens bevatten", maxSKULength)})
+	case !skuPattern.MatchString(product.SKU):
+		problems = append(problems, validation.FieldError{Field: "sku", Message: "SKU mag alleen letters, cijfers en . _ / - bevatten"})
+	}
+
+	product.Description = strings.TrimSpace(product.Description)
+	if product.Description == "" {
+		problems = append(problems, validation.FieldError{Field: "description", Message: "omschrijving is verplicht"})
+	}
+
+	product.Unit = strings.TrimSpace(product.Unit)
+	if product.Unit == "" {
+		product.Unit = defaultUnit
+	}
+	if len(product.Unit) > maxUnitLength {
+		problems = append(problems, validation.FieldError{Field: "unit", Message: fmt.Sprintf("eenheid mag maximaal %d tekens bevatten", maxUnitLength)})
+	}
+
+	if product.PriceCents < 0 {
+		problems = append(problems, validation.FieldError{Field: "price_cents", Message: "prijs mag niet negatief zijn"})
+	}
+
+	if product.VATCategory == "" {
+		product.VATCategory = model.VATHigh
+	}
+	if !product.VATCategory.IsValid() {
+		problems = append(problems, validation.FieldError{Field: "vat_category", Message: "BTW-categorie moet high, low of zero zijn"})
+	}
+
+	return problems
+}
+
+// validateAgreement controleert een prijsafspraak. Afspraken van hetzelfde product met dezelfde klant
+// mogen elkaar niet overlappen, zodat er op elke dag hoogstens één afspraak geldt.
+func (s *productService) validateAgreement(agreement *model.PriceAgreement) error {
+	var problems validation.Errors
+
+	if customer, err := s.customerRepo.FindByID(strconv.FormatUint(uint64(agreement.CustomerID), 10)); err != nil || customer == nil {
+		problems = append(problems, validation.FieldError{Field: "customer_id", Message: fmt.Sprintf("klant %d bestaat niet", agreement.CustomerID)})
+	}
+
+	if agreement.PriceCents < 0 {
+		problems = append(problems, validation.FieldError{Field: "price_cents", Message: "prijs mag niet negatief zijn"})
+	}
+
+	agreement.ValidFrom = truncateDate(agreement.ValidFrom)
+	agreement.ValidUntil = truncateDate(agreement.ValidUntil)
+	if agreement.ValidFrom != nil && agreement.ValidUntil != nil && agreement.ValidUntil.Before(*agreement.ValidFrom) {
+		problems = append(problems, validation.FieldError{Field: "valid_until", Message: "einddatum ligt voor de begindatum"})
+	}
+
+	agreement.Note = strings.TrimSpace(agreement.Note)
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	others, err := s.repo.FindCustomerAgreements(agreement.ProductID, agreement.CustomerID)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if other.ID != agreement.ID && overlaps(agreement, &other) {
+			return validation.New("valid_from", "de periode overlapt met prijsafspraak %d met deze klant", other.ID)
+		}
+	}
+
+	return nil
+}
+
+// overlaps controleert of de periodes van twee prijsafspraken een dag gemeen hebben; een ontbrekende
+// begin- of einddatum is onbegrensd
+func overlaps(a, b *model.PriceAgreement) bool {
+	startsBeforeEnd := func(from, until *time.Time) bool {
+		return from == nil || until == nil || !from.After(*until)
+	}
+	return startsBeforeEnd(a.ValidFrom, b.ValidUntil) && startsBeforeEnd(b.ValidFrom, a.ValidUntil)
+}
+
+// truncateDate haalt de tijd van een datum af
+func truncateDate(date *time.Time) *time.Time {
+	if date == nil {
+		return nil
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return &day
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"errors"
+	customerModel "odomosml/internal/customer/model"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/internal/product/model"
+	"odomosml/internal/product/repository"
+	"odomosml/pkg/validation"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProductRepository kent product 1 (ONDH-UUR) met de prijsafspraken in agreements
+type fakeProductRepository struct {
+	repository.ProductRepository
+	agreements []model.PriceAgreement
+	skus       map[string]bool
+	upserted   []model.Product
+	lookups    []string
+}
+
+func newFakeProductRepository() *fakeProductRepository {
+	return &fakeProductRepository{skus: map[string]bool{}}
+}
+
+func (r *fakeProductRepository) FindByID(id string) (*model.Product, error) {
+	if id != "1" {
+		return nil, errors.New("product niet gevonden")
+	}
+	return &model.Product{ID: 1, SKU: "ONDH-UUR", Description: "Onderhoud per uur", Unit: "uur", PriceCents: 8500, VATCategory: model.VATLow, Active: true}, nil
+}
+
+func (r *fakeProductRepository) FindBySKU(sku string) (*model.Product, error) {
+	if sku == "ONDH-UUR" {
+		return r.FindByID("1")
+	}
+	return nil, nil
+}
+
+func (r *fakeProductRepository) FindExistingSKUs(skus []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	for _, sku := range skus {
+		if r.skus[sku] {
+			existing[sku] = true
+		}
+	}
+	return existing, nil
+}
+
+func (r *fakeProductRepository) Upsert(products []model.Product) error {
+	r.upserted = products
+	return nil
+}
+
+func (r *fakeProductRepository) CreateAgreement(agreement *model.PriceAgreement) (*model.PriceAgreement, error) {
+	agreement.ID = uint(len(r.agreements) + 10)
+	return agreement, nil
+}
+
+func (r *fakeProductRepository) FindCustomerAgreements(productID, customerID uint) ([]model.PriceAgreement, error) {
+	var agreements []model.PriceAgreement
+	for _, agreement := range r.agreements {
+		if agreement.ProductID == productID && agreement.CustomerID == customerID {
+			agreements = append(agreements, agreement)
+		}
+	}
+	return agreements, nil
+}
+
+// FindEffectiveAgreement geeft de eerste afspraak van de klant die op de dag geldt; de volgorde van
+// meerdere geldige afspraken test de repository zelf
+func (r *fakeProductRepository) FindEffectiveAgreement(productID, customerID uint, date time.Time) (*model.PriceAgreement, error) {
+	r.lookups = append(r.lookups, strconv.FormatUint(uint64(customerID), 10)+"@"+date.Format("2006-01-02"))
+	agreements, _ := r.FindCustomerAgreements(productID, customerID)
+	day := truncateDate(&date)
+	for _, agreement := range agreements {
+		if (agreement.ValidFrom == nil || !agreement.ValidFrom.After(*day)) && (agreement.ValidUntil == nil || !agreement.ValidUntil.Before(*day)) {
+			return &agreement, nil
+		}
+	}
+	return nil, nil
+}
+
+// fakeCustomerRepository kent de klanten 1 en 2
+type fakeCustomerRepository struct {
+	customerRepo.CustomerRepository
+}
+
+func (fakeCustomerRepository) FindByID(id string) (*customerModel.Customer, error) {
+	if id != "1" && id != "2" {
+		return nil, errors.New("klant niet gevonden")
+	}
+	return &customerModel.Customer{}, nil
+}
+
+func day(year int, month time.Month, d int) *time.Time {
+	date := time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	return &date
+}
+
+func TestResolvePrice(t *testing.T) {
+	repo := newFakeProductRepository()
+	repo.agreements = []model.PriceAgreement{
+		{ID: 10, ProductID: 1, CustomerID: 1, PriceCents: 7500, ValidFrom: day(2025, 1, 1), ValidUntil: day(2025, 12, 31)},
+		{ID: 11, ProductID: 1, CustomerID: 1, PriceCents: 7000, ValidFrom: day(2026, 1, 1)},
+		{ID: 12, ProductID: 1, CustomerID: 2, PriceCents: 6000},
+	}
+	service := NewProductService(repo, fakeCustomerRepository{})
+
+	tests := []struct {
+		name          string
+		customerID    uint
+		date          time.Time
+		wantCents     int64
+		wantAgreement uint
+	}{
+		{name: "zonder klant de standaardprijs", customerID: 0, date: *day(2025, 6, 1), wantCents: 8500},
+		{name: "afspraak op de eerste dag", customerID: 1, date: *day(2025, 1, 1), wantCents: 7500, wantAgreement: 10},
+		{name: "afspraak op de laatste dag, laat op de dag", customerID: 1, date: time.Date(2025, 12, 31, 23, 30, 0, 0, time.UTC), wantCents: 7500, wantAgreement: 10},
+		{name: "voor de eerste afspraak", customerID: 1, date: *day(2024, 12, 31), wantCents: 8500},
+		{name: "open einde", customerID: 1, date: *day(2030, 1, 1), wantCents: 7000, wantAgreement: 11},
+		{name: "onbegrensde afspraak", customerID: 2, date: *day(2000, 1, 1), wantCents: 6000, wantAgreement: 12},
+		{name: "klant zonder afspraak", customerID: 3, date: *day(2025, 6, 1), wantCents: 8500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := service.ResolvePrice(1, tt.customerID, tt.date)
+			if err != nil {
+				t.Fatalf("ResolvePrice gaf fout: %v", err)
+			}
+			if price.PriceCents != tt.wantCents {
+				t.Errorf("prijs %d, verwacht %d", price.PriceCents, tt.wantCents)
+			}
+
+			wantSource := model.PriceSourceDefault
+			if tt.wantAgreement != 0 {
+				wantSource = model.PriceSourceAgreement
+			}
+			var agreementID uint
+			if price.AgreementID != nil {
+				agreementID = *price.AgreementID
+			}
+			if price.Source != wantSource || agreementID != tt.wantAgreement {
+				t.Errorf("herkomst %s (afspraak %d), verwacht %s (afspraak %d)", price.Source, agreementID, wantSource, tt.wantAgreement)
+			}
+
+			// De rest komt altijd van het product
+			if price.SKU != "ONDH-UUR" || price.Unit != "uur" || price.VATCategory != model.VATLow || price.VATRate != 9 ||
+				price.Date != tt.date.Format("2006-01-02") || price.CustomerID != tt.customerID {
+				t.Errorf("prijs = %+v", price)
+			}
+		})
+	}
+
+	// Zonder klant wordt er geen afspraak gezocht
+	for _, lookup := range repo.lookups {
+		if strings.HasPrefix(lookup, "0@") {
+			t.Errorf("afspraak gezocht zonder klant: %v", repo.lookups)
+		}
+	}
+	if _, err := service.ResolvePrice(9, 1, *day(2025, 6, 1)); err == nil {
+		t.Error("ResolvePrice van een onbekend product gaf geen fout")
+	}
+}
+
+func TestCreateAgreement(t *testing.T) {
+	repo := newFakeProductRepository()
+	repo.agreements = []model.PriceAgreement{
+		{ID: 10, ProductID: 1, CustomerID: 1, ValidFrom: day(2025, 1, 1), ValidUntil: day(2025, 12, 31)},
+		{ID: 11, ProductID: 1, CustomerID: 2, ValidFrom: day(2025, 1, 1)},
+	}
+
+	tests := []struct {
+		name      string
+		agreement model.PriceAgreement
+		wantField string
+		wantErr   string
+	}{
+		{name: "aansluitend na een afspraak", agreement: model.PriceAgreement{CustomerID: 1, ValidFrom: day(2026, 1, 1)}},
+		{name: "aansluitend voor een afspraak", agreement: model.PriceAgreement{CustomerID: 1, ValidUntil: day(2024, 12, 31)}},
+		{name: "voor een open afspraak", agreement: model.PriceAgreement{CustomerID: 2, ValidFrom: day(2024, 1, 1), ValidUntil: day(2024, 12, 31)}},
+		{
+			name:      "overlapt op de laatste dag",
+			agreement: model.PriceAgreement{CustomerID: 1, ValidFrom: day(2025, 12, 31)},
+			wantField: "valid_from", wantErr: "de periode overlapt met prijsafspraak 10 met deze klant",
+		},
+		{
+			name:      "tijd op de begindatum telt niet",
+			agreement: model.PriceAgreement{CustomerID: 1, ValidUntil: func() *time.Time { d := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC); return &d }()},
+			wantField: "valid_from", wantErr: "de periode overlapt met prijsafspraak 10 met deze klant",
+		},
+		{
+			name:      "onbegrensd overlapt altijd",
+			agreement: model.PriceAgreement{CustomerID: 2},
+			wantField: "valid_from", wantErr: "de periode overlapt met prijsafspraak 11 met deze klant",
+		},
+		{
+			name:      "einde voor begin",
+			agreement: model.PriceAgreement{CustomerID: 3, PriceCents: -1, ValidFrom: day(2025, 2, 1), ValidUntil: day(2025, 1, 31)},
+			wantField: "customer_id", wantErr: "klant 3 bestaat niet; prijs mag niet negatief zijn; einddatum ligt voor de begindatum",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agreement := tt.agreement
+			_, err := NewProductService(repo, fakeCustomerRepository{}).CreateAgreement("1", &agreement)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CreateAgreement gaf fout: %v", err)
+				}
+				return
+			}
+			fields, _ := validation.Fields(err)
+			if err == nil || len(fields) == 0 || fields[0].Field != tt.wantField {
+				t.Errorf("CreateAgreement gaf %v, verwacht een fout op %s", err, tt.wantField)
+			}
+			if err != nil && err.Error() != tt.wantErr {
+				t.Errorf("fout = %q, verwacht %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateProduct(t *testing.T) {
+	tests := []struct {
+		name       string
+		product    model.Product
+		want       model.Product
+		wantFields []string
+	}{
+		{
+			name:    "standaardwaarden",
+			product: model.Product{SKU: " kabel-1m ", Description: " Kabel ", PriceCents: 250},
+			want:    model.Product{SKU: "KABEL-1M", Description: "Kabel", Unit: "stuk", PriceCents: 250, VATCategory: model.VATHigh},
+		},
+		{
+			name:       "SKU van een ander product",
+			product:    model.Product{SKU: "ondh-uur", Description: "Onderhoud"},
+			wantFields: []string{"sku"},
+		},
+		{
+			name:       "alle fouten",
+			product:    model.Product{SKU: "KABEL 1M", Unit: "strekkende meter lang", PriceCents: -1, VATCategory: "hoog"},
+			wantFields: []string{"sku", "description", "unit", "price_cents", "vat_category"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := tt.product
+			err := NewProductService(newFakeProductRepository(), nil).(*productService).validate(&product)
+			if tt.wantFields != nil {
+				fields, _ := validation.Fields(err)
+				var got []string
+				for _, field := range fields {
+					got = append(got, field.Field)
+				}
+				if !reflect.DeepEqual(got, tt.wantFields) {
+					t.Errorf("fouten op %v (%v), verwacht %v", got, err, tt.wantFields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(product, tt.want) {
+				t.Errorf("product = %+v, verwacht %+v", product, tt.want)
+			}
+		})
+	}
+
+	// Een product mag zijn eigen SKU houden
+	own := model.Product{ID: 1, SKU: "ONDH-UUR", Description: "Onderhoud"}
+	if err := NewProductService(newFakeProductRepository(), nil).(*productService).validate(&own); err != nil {
+		t.Errorf("validate van de eigen SKU gaf %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+	"odomosml/internal/retention/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetentionHandler handles HTTP requests for the retention policies
+type RetentionHandler struct {
+	service service.RetentionService
+}
+
+// NewRetentionHandler maakt een nieuwe RetentionHandler instantie
+func NewRetentionHandler(service service.RetentionService) *RetentionHandler {
+	return &RetentionHandler{
+		service: service,
+	}
+}
+
+// @Summary      Bewaartermijnen
+// @Description  Geeft per soort gegevens de bewaartermijn en hoeveel er bij de volgende run opgeschoond zou worden (dry run), en hoeveel vanwege een legal hold bewaard blijft. Er wordt niets verwijderd; dat doet de opschoonjob van de scheduler.
+// @Tags         retention
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "{ data: model.Run }"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Geen toegang"
+// @Failure      500  {object}  map[string]string "Server error"
+// @Security     Bearer
+// @Router       /retention [get]
+func (h *RetentionHandler) Report(c *gin.Context) {
+	run, err := h.service.Report()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
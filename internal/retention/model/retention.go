@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Entity is de soort gegevens waar een bewaarbeleid voor geldt
+type Entity string
+
+// Soorten gegevens met een bewaartermijn
+const (
+	EntityAuditLogs        Entity = "audit_logs"
+	EntityLoginEvents      Entity = "login_events"
+	EntityDeletedCustomers Entity = "deleted_customers"
+)
+
+// Policy is het bewaarbeleid voor één soort gegevens: gegevens ouder dan Days dagen worden verwijderd
+type Policy struct {
+	Entity      Entity `json:"entity"`
+	Days        int    `json:"days"` // 0 schakelt het opschonen uit
+	Description string `json:"description"`
+}
+
+// Report is het resultaat van één bewaarbeleid in een run
+type Report struct {
+	Entity      Entity     `json:"entity"`
+	Description string     `json:"description"`
+	Days        int        `json:"days"`
+	Disabled    bool       `json:"disabled,omitempty"` // Er is geen bewaartermijn ingesteld
+	Cutoff      *time.Time `json:"cutoff,omitempty"`   // Gegevens van voor dit moment vallen buiten de bewaartermijn
+	Eligible    int64      `json:"eligible"`           // Rijen buiten de bewaartermijn die verwijderd mogen worden
+	Held        int64      `json:"held"`               // Rijen buiten de bewaartermijn die vanwege een legal hold blijven staan
+	Purged      int64      `json:"purged"`             // Rijen die deze run verwijderd zijn; 0 bij een dry run
+}
+
+// Run is het resultaat van het toepassen van alle bewaarbeleid
+type Run struct {
+	DryRun    bool      `json:"dry_run"`
+	StartedAt time.Time `json:"started_at"`
+	Reports   []Report  `json:"reports"`
+}
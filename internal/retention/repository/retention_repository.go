@@ -0,0 +1,75 @@
+package repository
+
+import (
+	auditModel "odomosml/internal/audit/model"
+	authModel "odomosml/internal/auth/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// heldAuditLogs is de voorwaarde voor audit log entries van klanten met een legal hold
+const heldAuditLogs = "entity_type = @customer AND entity_id IN (SELECT id::text FROM customers WHERE legal_hold)"
+
+// RetentionRepository definieert de interface voor het tellen en opschonen van gegevens buiten de bewaartermijn.
+// Het opschonen van verwijderde klanten zit in de CustomerRepository.
+type RetentionRepository interface {
+	CountAuditLogs(before time.Time) (eligible int64, held int64, err error)
+	PurgeAuditLogs(before time.Time, limit int) (int64, error)
+	CountLoginEvents(before time.Time) (int64, error)
+	PurgeLoginEvents(before time.Time, limit int) (int64, error)
+}
+
+// retentionRepository implementeert de RetentionRepository interface
+type retentionRepository struct {
+	db *gorm.DB
+}
+
+// NewRetentionRepository maakt een nieuwe RetentionRepository instantie
+func NewRetentionRepository(db *gorm.DB) RetentionRepository {
+	return &retentionRepository{
+		db: db,
+	}
+}
+
+// CountAuditLogs telt de audit log entries van voor before, en hoeveel daarvan bij een klant met een legal hold horen
+func (r *retentionRepository) CountAuditLogs(before time.Time) (int64, int64, error) {
+	params := map[string]interface{}{"before": before, "customer": auditModel.EntityCustomer}
+
+	var eligible, held int64
+	err := r.db.Model(&auditModel.AuditLog{}).Where("created_at < @before AND NOT ("+heldAuditLogs+")", params).
+		Count(&eligible).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	err = r.db.Model(&auditModel.AuditLog{}).Where("created_at < @before AND "+heldAuditLogs, params).
+		Count(&held).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return eligible, held, nil
+}
+
+// PurgeAuditLogs verwijdert hoogstens limit audit log entries van voor before, oudste eerst.
+// Entries van klanten met een legal hold blijven staan.
+func (r *retentionRepository) PurgeAuditLogs(before time.Time, limit int) (int64, error) {
+	result := r.db.Exec(`DELETE FROM audit_logs WHERE id IN (
+			SELECT id FROM audit_logs WHERE created_at < @before AND NOT (`+heldAuditLogs+`)
+			ORDER BY id LIMIT @limit)`,
+		map[string]interface{}{"before": before, "customer": auditModel.EntityCustomer, "limit": limit})
+	return result.RowsAffected, result.Error
+}
+
+// CountLoginEvents telt de inlogpogingen van voor before
+func (r *retentionRepository) CountLoginEvents(before time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&authModel.LoginEvent{}).Where("created_at < ?", before).Count(&count).Error
+	return count, err
+}
+
+// PurgeLoginEvents verwijdert hoogstens limit inlogpogingen van voor before, oudste eerst
+func (r *retentionRepository) PurgeLoginEvents(before time.Time, limit int) (int64, error) {
+	result := r.db.Exec(`DELETE FROM login_events WHERE id IN (
+			SELECT id FROM login_events WHERE created_at < ? ORDER BY id LIMIT ?)`, before, limit)
+	return result.RowsAffected, result.Error
+}
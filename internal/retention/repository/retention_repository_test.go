@@ -0,0 +1,107 @@
+package repository
+
+import (
+	auditModel "odomosml/internal/audit/model"
+	_ "odomosml/pkg/fieldcrypt" // registreert de serializer voor versleutelde velden
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// capturedQuery is een query met zijn parameters
+type capturedQuery struct {
+	sql  string
+	vars []interface{}
+}
+
+// captureQueries maakt een repository zonder database die de queries en parameters bewaart
+func captureQueries(t *testing.T) (*retentionRepository, *[]capturedQuery) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("gorm.Open gaf fout: %v", err)
+	}
+
+	var captured []capturedQuery
+	capture := func(tx *gorm.DB) {
+		captured = append(captured, capturedQuery{sql: tx.Statement.SQL.String(), vars: tx.Statement.Vars})
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("test:capture", capture); err != nil {
+		t.Fatalf("callback registreren gaf fout: %v", err)
+	}
+	if err := db.Callback().Raw().After("gorm:raw").Register("test:capture", capture); err != nil {
+		t.Fatalf("callback registreren gaf fout: %v", err)
+	}
+	return &retentionRepository{db: db}, &captured
+}
+
+func TestRetentionQueries(t *testing.T) {
+	before := time.Date(2024, 2, 25, 20, 30, 0, 0, time.UTC)
+	held := "entity_type = $2 AND entity_id IN (SELECT id::text FROM customers WHERE legal_hold)"
+
+	tests := []struct {
+		name     string
+		query    func(repo *retentionRepository) error
+		contains [][]string
+		wantVars [][]interface{}
+	}{
+		{
+			name:  "audit logs tellen",
+			query: func(repo *retentionRepository) error { _, _, err := repo.CountAuditLogs(before); return err },
+			contains: [][]string{
+				{"FROM \"audit_logs\"", "created_at < $1 AND NOT (" + held + ")"},
+				{"FROM \"audit_logs\"", "created_at < $1 AND " + held},
+			},
+			wantVars: [][]interface{}{{before, auditModel.EntityCustomer}, {before, auditModel.EntityCustomer}},
+		},
+		{
+			name:     "audit logs verwijderen",
+			query:    func(repo *retentionRepository) error { _, err := repo.PurgeAuditLogs(before, 500); return err },
+			contains: [][]string{{"DELETE FROM audit_logs", "created_at < $1 AND NOT (" + held + ")", "ORDER BY id LIMIT $3"}},
+			wantVars: [][]interface{}{{before, auditModel.EntityCustomer, 500}},
+		},
+		{
+			name:     "inlogpogingen tellen",
+			query:    func(repo *retentionRepository) error { _, err := repo.CountLoginEvents(before); return err },
+			contains: [][]string{{"FROM \"login_events\"", "created_at < $1"}},
+			wantVars: [][]interface{}{{before}},
+		},
+		{
+			name:     "inlogpogingen verwijderen",
+			query:    func(repo *retentionRepository) error { _, err := repo.PurgeLoginEvents(before, 500); return err },
+			contains: [][]string{{"DELETE FROM login_events", "created_at < $1 ORDER BY id LIMIT $2"}},
+			wantVars: [][]interface{}{{before, 500}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, captured := captureQueries(t)
+			if err := tt.query(repo); err != nil {
+				t.Fatalf("query gaf fout: %v", err)
+			}
+			if len(*captured) != len(tt.contains) {
+				t.Fatalf("%d queries, verwacht %d", len(*captured), len(tt.contains))
+			}
+			for i, query := range *captured {
+				sql := query.sql
+				for _, want := range tt.contains[i] {
+					if !strings.Contains(sql, want) {
+						t.Errorf("query %d bevat geen %q:\n%s", i, want, sql)
+					}
+				}
+				if strings.Contains(sql, "@") {
+					t.Errorf("query %d bevat een niet vervangen parameter:\n%s", i, sql)
+				}
+				if !reflect.DeepEqual(query.vars, tt.wantVars[i]) {
+					t.Errorf("parameters van query %d = %v, verwacht %v", i, query.vars, tt.wantVars[i])
+				}
+			}
+		})
+	}
+}
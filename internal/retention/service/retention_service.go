@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"odomosml/config"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/internal/retention/model"
+	"odomosml/internal/retention/repository"
+	"time"
+)
+
+// defaultBatchSize is het aantal rijen per delete als RETENTION_BATCH_SIZE niet geldig is
+const defaultBatchSize = 1000
+
+// RetentionService definieert de interface voor het toepassen van de bewaartermijnen
+type RetentionService interface {
+	Report() (*model.Run, error)
+	Purge(ctx context.Context) error
+}
+
+// purger telt en verwijdert de gegevens van één bewaarbeleid
+type purger struct {
+	count func(before time.Time) (eligible int64, held int64, err error)
+	purge func(before time.Time, limit int) (int64, error)
+}
+
+// retentionService implementeert de RetentionService interface
+type retentionService struct {
+	policies  []model.Policy
+	purgers   map[model.Entity]purger
+	batchSize int
+	dryRun    bool
+}
+
+// NewRetentionService maakt een nieuwe RetentionService instantie met de bewaartermijnen uit de configuratie
+func NewRetentionService(repo repository.RetentionRepository, customers customerRepo.CustomerRepository, cfg *config.Config) RetentionService {
+	batchSize := cfg.RetentionBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return &retentionService{
+		policies: []model.Policy{
+			{Entity: model.EntityAuditLogs, Days: cfg.RetentionAuditLogDays,
+				Description: "Audit log entries; entries van klanten met een legal hold blijven bewaard"},
+			{Entity: model.EntityLoginEvents, Days: cfg.RetentionLoginEventDays,
+				Description: "Inlogpogingen"},
+			{Entity: model.EntityDeletedCustomers, Days: cfg.RetentionDeletedCustomerDays,
+				Description: "Verwijderde klanten met hun activiteiten, taken, afspraken, bijlagen en deals; klanten met een legal hold blijven bewaard"},
+		},
+		purgers: map[model.Entity]purger{
+			model.EntityAuditLogs: {count: repo.CountAuditLogs, purge: repo.PurgeAuditLogs},
+			model.EntityLoginEvents: {
+				count: func(before time.Time) (int64, int64, error) {
+					count, err := repo.CountLoginEvents(before)
+					return count, 0, err
+				},
+				purge: repo.PurgeLoginEvents,
+			},
+			model.EntityDeletedCustomers: {count: customers.CountPurgeable, purge: customers.PurgeDeleted},
+		},
+		batchSize: batchSize,
+		dryRun:    cfg.RetentionDryRun,
+	}
+}
+
+// Report geeft per bewaarbeleid aan wat er nu opgeschoond zou worden, zonder iets te verwijderen
+func (s *retentionService) Report() (*model.Run, error) {
+	return s.run(context.Background(), true)
+}
+
+// Purge past alle bewaartermijnen toe en logt per bewaarbeleid het resultaat; met RETENTION_DRY_RUN
+// wordt alleen gelogd wat er opgeschoond zou worden. Bedoeld als job voor de scheduler.
+func (s *retentionService) Purge(ctx context.Context) error {
+	run, err := s.run(ctx, s.dryRun)
+	for _, report := range run.Reports {
+		switch {
+		case report.Disabled:
+		case run.DryRun:
+			log.Printf("Bewaartermijn %s (%d dagen), dry run: %d zouden verwijderd worden, %d blijven vanwege een legal hold",
+				report.Entity, report.Days, report.Eligible, report.Held)
+		case report.Purged > 0 || report.Held > 0:
+			log.Printf("Bewaartermijn %s (%d dagen): %d verwijderd, %d blijven vanwege een legal hold",
+				report.Entity, report.Days, report.Purged, report.Held)
+		}
+	}
+	return err
+}
+
+// run telt per bewaarbeleid de gegevens buiten de bewaartermijn en verwijdert ze, tenzij dryRun, in
+// batches van batchSize rijen. Elke batch is een eigen statement, zodat er geen lange locks ontstaan;
+// een onderbroken run gaat de volgende keer verder.
+func (s *retentionService) run(ctx context.Context, dryRun bool) (*model.Run, error) {
+	run := &model.Run{DryRun: dryRun, StartedAt: time.Now(), Reports: []model.Report{}}
+
+	for _, policy := range s.policies {
+		report := model.Report{Entity: policy.Entity, Description: policy.Description, Days: policy.Days}
+		if policy.Days <= 0 {
+			report.Disabled = true
+			run.Reports = append(run.Reports, report)
+			continue
+		}
+
+		cutoff := run.StartedAt.AddDate(0, 0, -policy.Days)
+		report.Cutoff = &cutoff
+
+		purger := s.purgers[policy.Entity]
+		eligible, held, err := purger.count(cutoff)
+		if err != nil {
+			return run, fmt.Errorf("%s: %w", policy.Entity, err)
+		}
+		report.Eligible, report.Held = eligible, held
+
+		for !dryRun && report.Purged < report.Eligible {
+			if err := ctx.Err(); err != nil {
+				run.Reports = append(run.Reports, report)
+				return run, err
+			}
+
+			purged, err := purger.purge(cutoff, s.batchSize)
+			if err != nil {
+				run.Reports = append(run.Reports, report)
+				return run, fmt.Errorf("%s: %w", policy.Entity, err)
+			}
+			report.Purged += purged
+			if purged < int64(s.batchSize) {
+				break
+			}
+		}
+
+		run.Reports = append(run.Reports, report)
+	}
+
+	return run, nil
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"odomosml/config"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/internal/retention/model"
+	"odomosml/internal/retention/repository"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeRows zijn rijen met een tijdstip in het geheugen; rijen met een legal hold worden niet verwijderd
+type fakeRows struct {
+	at      []time.Time
+	held    []bool
+	batches []int
+	err     error
+}
+
+// add voegt rijen toe die days dagen voor nu liggen
+func (r *fakeRows) add(held bool, days ...int) {
+	for _, d := range days {
+		r.at = append(r.at, time.Now().AddDate(0, 0, -d))
+		r.held = append(r.held, held)
+	}
+}
+
+func (r *fakeRows) count(before time.Time) (int64, int64, error) {
+	var eligible, held int64
+	for i, at := range r.at {
+		switch {
+		case !at.Before(before):
+		case r.held[i]:
+			held++
+		default:
+			eligible++
+		}
+	}
+	return eligible, held, nil
+}
+
+func (r *fakeRows) purge(before time.Time, limit int) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	var purged int64
+	for i := 0; i < len(r.at) && purged < int64(limit); {
+		if r.at[i].Before(before) && !r.held[i] {
+			r.at = append(r.at[:i], r.at[i+1:]...)
+			r.held = append(r.held[:i], r.held[i+1:]...)
+			purged++
+			continue
+		}
+		i++
+	}
+	r.batches = append(r.batches, int(purged))
+	return purged, nil
+}
+
+type fakeRetentionRepository struct {
+	repository.RetentionRepository
+	auditLogs, loginEvents *fakeRows
+}
+
+func (r *fakeRetentionRepository) CountAuditLogs(before time.Time) (int64, int64, error) {
+	return r.auditLogs.count(before)
+}
+
+func (r *fakeRetentionRepository) PurgeAuditLogs(before time.Time, limit int) (int64, error) {
+	return r.auditLogs.purge(before, limit)
+}
+
+func (r *fakeRetentionRepository) CountLoginEvents(before time.Time) (int64, error) {
+	count, _, err := r.loginEvents.count(before)
+	return count, err
+}
+
+func (r *fakeRetentionRepository) PurgeLoginEvents(before time.Time, limit int) (int64, error) {
+	return r.loginEvents.purge(before, limit)
+}
+
+type fakeCustomerRepository struct {
+	customerRepo.CustomerRepository
+	deleted *fakeRows
+}
+
+func (r *fakeCustomerRepository) CountPurgeable(deletedBefore time.Time) (int64, int64, error) {
+	return r.deleted.count(deletedBefore)
+}
+
+func (r *fakeCustomerRepository) PurgeDeleted(deletedBefore time.Time, limit int) (int64, error) {
+	return r.deleted.purge(deletedBefore, limit)
+}
+
+// newTestService maakt een RetentionService met 30 dagen voor audit logs, 7 dagen voor inlogpogingen
+// en 10 dagen voor verwijderde klanten
+func newTestService(cfg config.Config) (RetentionService, *fakeRows, *fakeRows, *fakeRows) {
+	auditLogs, loginEvents, deleted := &fakeRows{}, &fakeRows{}, &fakeRows{}
+	auditLogs.add(false, 1, 29, 31, 40, 400)
+	auditLogs.add(true, 35, 500)
+	loginEvents.add(false, 1, 6, 8, 9, 10, 11, 12)
+	deleted.add(false, 5, 11)
+	deleted.add(true, 20)
+
+	repo := &fakeRetentionRepository{auditLogs: auditLogs, loginEvents: loginEvents}
+	return NewRetentionService(repo, &fakeCustomerRepository{deleted: deleted}, &cfg), auditLogs, loginEvents, deleted
+}
+
+var testConfig = config.Config{RetentionAuditLogDays: 30, RetentionLoginEventDays: 7, RetentionDeletedCustomerDays: 10, RetentionBatchSize: 2}
+
+// summary geeft per bewaarbeleid de tellingen als [eligible, held, purged], of nil als het uitgeschakeld is
+func summary(run *model.Run) map[model.Entity][]int64 {
+	result := make(map[model.Entity][]int64)
+	for _, report := range run.Reports {
+		if report.Disabled {
+			result[report.Entity] = nil
+			continue
+		}
+		result[report.Entity] = []int64{report.Eligible, report.Held, report.Purged}
+	}
+	return result
+}
+
+func TestReport(t *testing.T) {
+	service, auditLogs, loginEvents, deleted := newTestService(testConfig)
+
+	run, err := service.Report()
+	if err != nil {
+		t.Fatalf("Report gaf fout: %v", err)
+	}
+	if !run.DryRun {
+		t.Error("Report is geen dry run")
+	}
+
+	want := map[model.Entity][]int64{
+		model.EntityAuditLogs:        {3, 2, 0},
+		model.EntityLoginEvents:      {5, 0, 0},
+		model.EntityDeletedCustomers: {1, 1, 0},
+	}
+	if got := summary(run); !reflect.DeepEqual(got, want) {
+		t.Errorf("rapport = %v, verwacht %v", got, want)
+	}
+
+	// De grens ligt precies het aantal dagen voor het begin van de run
+	days := map[model.Entity]int{model.EntityAuditLogs: 30, model.EntityLoginEvents: 7, model.EntityDeletedCustomers: 10}
+	for _, report := range run.Reports {
+		if report.Cutoff == nil || !report.Cutoff.Equal(run.StartedAt.AddDate(0, 0, -days[report.Entity])) {
+			t.Errorf("%s: grens %v, verwacht %d dagen voor %v", report.Entity, report.Cutoff, days[report.Entity], run.StartedAt)
+		}
+	}
+
+	if len(auditLogs.batches)+len(loginEvents.batches)+len(deleted.batches) > 0 {
+		t.Error("Report heeft gegevens verwijderd")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	service, auditLogs, loginEvents, deleted := newTestService(testConfig)
+
+	if err := service.Purge(context.Background()); err != nil {
+		t.Fatalf("Purge gaf fout: %v", err)
+	}
+
+	// Verwijderd in batches van twee, tot er niets meer buiten de bewaartermijn valt
+	if want := []int{2, 1}; !reflect.DeepEqual(auditLogs.batches, want) {
+		t.Errorf("audit log batches = %v, verwacht %v", auditLogs.batches, want)
+	}
+	if want := []int{2, 2, 1}; !reflect.DeepEqual(loginEvents.batches, want) {
+		t.Errorf("inlogpoging batches = %v, verwacht %v", loginEvents.batches, want)
+	}
+	if want := []int{1}; !reflect.DeepEqual(deleted.batches, want) {
+		t.Errorf("klant batches = %v, verwacht %v", deleted.batches, want)
+	}
+
+	// Wat binnen de bewaartermijn valt of een legal hold heeft blijft staan
+	for name, rows := range map[string]*fakeRows{"audit logs": auditLogs, "inlogpogingen": loginEvents, "klanten": deleted} {
+		var ages []int
+		for _, at := range rows.at {
+			ages = append(ages, int(time.Since(at).Hours()/24+0.5))
+		}
+		sort.Ints(ages)
+		want := map[string][]int{"audit logs": {1, 29, 35, 500}, "inlogpogingen": {1, 6}, "klanten": {5, 20}}[name]
+		if !reflect.DeepEqual(ages, want) {
+			t.Errorf("%s over: %v dagen oud, verwacht %v", name, ages, want)
+		}
+	}
+
+	// Een tweede run heeft niets meer te doen
+	run, err := service.Report()
+	if err != nil {
+		t.Fatalf("Report gaf fout: %v", err)
+	}
+	want := map[model.Entity][]int64{
+		model.EntityAuditLogs:        {0, 2, 0},
+		model.EntityLoginEvents:      {0, 0, 0},
+		model.EntityDeletedCustomers: {0, 1, 0},
+	}
+	if got := summary(run); !reflect.DeepEqual(got, want) {
+		t.Errorf("rapport na opschonen = %v, verwacht %v", got, want)
+	}
+}
+
+func TestPurgeSettings(t *testing.T) {
+	t.Run("uitgeschakeld", func(t *testing.T) {
+		cfg := testConfig
+		cfg.RetentionAuditLogDays, cfg.RetentionLoginEventDays = 0, -1
+		service, auditLogs, loginEvents, _ := newTestService(cfg)
+
+		run, err := service.(*retentionService).run(context.Background(), false)
+		if err != nil {
+			t.Fatalf("run gaf fout: %v", err)
+		}
+		want := map[model.Entity][]int64{
+			model.EntityAuditLogs:        nil,
+			model.EntityLoginEvents:      nil,
+			model.EntityDeletedCustomers: {1, 1, 1},
+		}
+		if got := summary(run); !reflect.DeepEqual(got, want) {
+			t.Errorf("rapport = %v, verwacht %v", got, want)
+		}
+		if run.Reports[0].Cutoff != nil || len(auditLogs.batches)+len(loginEvents.batches) > 0 {
+			t.Error("uitgeschakeld bewaarbeleid toch toegepast")
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		cfg := testConfig
+		cfg.RetentionDryRun = true
+		service, auditLogs, loginEvents, deleted := newTestService(cfg)
+
+		if err := service.Purge(context.Background()); err != nil {
+			t.Fatalf("Purge gaf fout: %v", err)
+		}
+		if len(auditLogs.batches)+len(loginEvents.batches)+len(deleted.batches) > 0 {
+			t.Error("dry run heeft gegevens verwijderd")
+		}
+	})
+
+	t.Run("standaard batchgrootte", func(t *testing.T) {
+		cfg := testConfig
+		cfg.RetentionBatchSize = 0
+		service, _, loginEvents, _ := newTestService(cfg)
+
+		if err := service.Purge(context.Background()); err != nil {
+			t.Fatalf("Purge gaf fout: %v", err)
+		}
+		if want := []int{5}; !reflect.DeepEqual(loginEvents.batches, want) {
+			t.Errorf("inlogpoging batches = %v, verwacht %v", loginEvents.batches, want)
+		}
+	})
+}
+
+func TestPurgeInterrupted(t *testing.T) {
+	t.Run("geannuleerd", func(t *testing.T) {
+		service, auditLogs, _, _ := newTestService(testConfig)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		run, err := service.(*retentionService).run(ctx, false)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("run gaf %v, verwacht %v", err, context.Canceled)
+		}
+		if len(run.Reports) != 1 || run.Reports[0].Purged != 0 || len(auditLogs.batches) > 0 {
+			t.Errorf("rapport na annuleren = %+v", run.Reports)
+		}
+	})
+
+	t.Run("fout bij verwijderen", func(t *testing.T) {
+		service, _, loginEvents, deleted := newTestService(testConfig)
+		loginEvents.err = errors.New("verbinding verbroken")
+
+		run, err := service.(*retentionService).run(context.Background(), false)
+		if err == nil || err.Error() != "login_events: verbinding verbroken" {
+			t.Fatalf("run gaf %v", err)
+		}
+		// Het eerdere bewaarbeleid is toegepast, het latere niet meer
+		if len(run.Reports) != 2 || run.Reports[0].Purged != 3 || len(deleted.batches) > 0 {
+			t.Errorf("rapport na fout = %+v", run.Reports)
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"odomosml/internal/savedview/model"
+	"odomosml/internal/savedview/service"
+	userModel "odomosml/internal/user/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SavedViewHandler handles HTTP requests for saved views
+type SavedViewHandler struct {
+	service service.SavedViewService
+}
+
+// NewSavedViewHandler maakt een nieuwe SavedViewHandler instantie
+func NewSavedViewHandler(service service.SavedViewService) *SavedViewHandler {
+	return &SavedViewHandler{
+		service: service,
+	}
+}
+
+// viewer geeft de ingelogde gebruiker
+func viewer(c *gin.Context) model.Viewer {
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+	role, _ := c.Get("userRole")
+
+	v := model.Viewer{}
+	v.UserID, _ = userID.(uint)
+	v.Username, _ = username.(string)
+	roleName, _ := role.(string)
+	v.Admin = userModel.Role(roleName) == userModel.RoleAdmin
+	return v
+}
+
+// respondError vertaalt een fout van de service naar een HTTP status
+func respondError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, service.ErrViewNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrViewForbidden):
+		status = http.StatusForbidden
+	}
+
+	c.JSON(status, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+// @Summary      Lijst van weergaven ophalen
+// @Description  Haalt de eigen weergaven en de met het team gedeelde weergaven op
+// @Tags         views
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      500  {object}  map[string]string "Server error"
+// @Security     Bearer
+// @Router       /views [get]
+func (h *SavedViewHandler) GetAll(c *gin.Context) {
+	views, err := h.service.GetViews(viewer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    views,
+	})
+}
+
+// @Summary      Weergave ophalen
+// @Description  Haalt een eigen of met het team gedeelde weergave op
+// @Tags         views
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Weergave ID"
+// @Success      200  {object}  model.SavedView "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Weergave niet gevonden"
+// @Security     Bearer
+// @Router       /views/{id} [get]
+func (h *SavedViewHandler) GetByID(c *gin.Context) {
+	view, err := h.service.GetView(c.Param("id"), viewer(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    view,
+	})
+}
+
+// @Summary      Nieuwe weergave opslaan
+// @Description  Slaat filters, sortering en kolommen van de klantenlijst op onder een naam, persoonlijk of gedeeld met het eigen team
+// @Tags         views
+// @Accept       json
+// @Produce      json
+// @Param        view body model.SavedViewRequest true "Weergave gegevens"
+// @Success      201  {object}  model.SavedView "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /views [post]
+func (h *SavedViewHandler) Create(c *gin.Context) {
+	var request model.SavedViewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	created, err := h.service.CreateView(request, viewer(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Weergave bijwerken
+// @Description  Werkt een eigen weergave bij; admins kunnen elke weergave bijwerken
+// @Tags         views
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Weergave ID"
+// @Param        view body model.SavedViewRequest true "Weergave gegevens"
+// @Success      200  {object}  model.SavedView "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Niet de eigenaar"
+// @Failure      404  {object}  map[string]string "Weergave niet gevonden"
+// @Security     Bearer
+// @Router       /views/{id} [put]
+func (h *SavedViewHandler) Update(c *gin.Context) {
+	var request model.SavedViewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	updated, err := h.service.UpdateView(c.Param("id"), request, viewer(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Weergave verwijderen
+// @Description  Verwijdert een eigen weergave; admins kunnen elke weergave verwijderen
+// @Tags         views
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Weergave ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      403  {object}  map[string]string "Niet de eigenaar"
+// @Failure      404  {object}  map[string]string "Weergave niet gevonden"
+// @Security     Bearer
+// @Router       /views/{id} [delete]
+func (h *SavedViewHandler) Delete(c *gin.Context) {
+	viewData, err := h.service.DeleteView(c.Param("id"), viewer(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Sla viewData op in context voor audit logging
+	c.Set("viewData", viewData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Weergave succesvol verwijderd",
+	})
+}
+
+// @Summary      Standaard weergave instellen
+// @Description  Maakt de weergave de standaard weergave van de ingelogde gebruiker; deze wordt toegepast op de klantenlijst en export zolang er geen filters of sortering zijn opgegeven
+// @Tags         views
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Weergave ID"
+// @Success      200  {object}  model.SavedView "Standaard weergave ingesteld"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Weergave niet gevonden"
+// @Security     Bearer
+// @Router       /views/{id}/default [put]
+func (h *SavedViewHandler) SetDefault(c *gin.Context) {
+	view, err := h.service.SetDefaultView(c.Param("id"), viewer(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    view,
+	})
+}
+
+// @Summary      Standaard weergave verwijderen
+// @Description  De klantenlijst wordt weer zonder weergave getoond
+// @Tags         views
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "Standaard weergave verwijderd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /views/default [delete]
+func (h *SavedViewHandler) ClearDefault(c *gin.Context) {
+	if err := h.service.ClearDefaultView(viewer(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Standaard weergave verwijderd",
+	})
+}
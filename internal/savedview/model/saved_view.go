@@ -0,0 +1,139 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ViewNone is de waarde van de view parameter waarmee de standaard weergave niet wordt toegepast
+const ViewNone = "none"
+
+// ParamKeys zijn de parameters van de klantenlijst die in een weergave opgeslagen kunnen worden,
+// naast filters op vrije velden (cf.<key>)
+var ParamKeys = []string{"zoekterm", "tags", "filter", "group", "status", "min_days_in_status", "max_days_in_status"}
+
+// Params bevat de opgeslagen filterparameters van een weergave, opgeslagen als JSONB
+type Params map[string]string
+
+// Value implementeert driver.Valuer
+func (p Params) Value() (driver.Value, error) {
+	if p == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]string(p))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (p *Params) Scan(value interface{}) error {
+	data, err := scanBytes(value)
+	if err != nil || data == nil {
+		*p = Params{}
+		return err
+	}
+	return json.Unmarshal(data, (*map[string]string)(p))
+}
+
+// Columns is de kolomselectie van een weergave, opgeslagen als JSONB
+type Columns []string
+
+// Value implementeert driver.Valuer
+func (c Columns) Value() (driver.Value, error) {
+	if c == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(c))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (c *Columns) Scan(value interface{}) error {
+	data, err := scanBytes(value)
+	if err != nil || data == nil {
+		*c = Columns{}
+		return err
+	}
+	return json.Unmarshal(data, (*[]string)(c))
+}
+
+// scanBytes leest een JSONB waarde uit de database
+func scanBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("kan %T niet converteren naar JSON", value)
+}
+
+// SavedView is een opgeslagen weergave van de klantenlijst: filters, sortering en kolommen onder een naam.
+// Een weergave is persoonlijk, of gedeeld met het team van de eigenaar.
+// @Description Opgeslagen filters, sortering en kolommen voor de klantenlijst
+type SavedView struct {
+	ID        uint      `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	Name      string    `json:"name" gorm:"size:100;not null" example:"VIP klanten in Utrecht" swaggertype:"string"`
+	OwnerID   uint      `json:"owner_id" gorm:"not null;index" example:"1" swaggertype:"integer"`
+	OwnerName string    `json:"owner_name" gorm:"size:100" example:"johndoe" swaggertype:"string"`
+	Team      string    `json:"team" gorm:"size:50;not null;default:'';index" example:"verkoop" swaggertype:"string"` // Leeg voor een persoonlijke weergave
+	Params    Params    `json:"params" gorm:"type:jsonb;not null;default:'{}'" swaggertype:"object"`
+	Sort      string    `json:"sort" gorm:"size:200;not null;default:''" example:"-created_at,name" swaggertype:"string"`
+	Columns   Columns   `json:"columns" gorm:"type:jsonb;not null;default:'[]'" swaggertype:"array,string"`
+	IsDefault bool      `json:"is_default" gorm:"-" example:"false" swaggertype:"boolean"`
+	CreatedAt time.Time `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (SavedView) TableName() string {
+	return "saved_views"
+}
+
+// ToAuditMap converteert een weergave naar een map voor audit logging
+func (v *SavedView) ToAuditMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":      v.ID,
+		"name":    v.Name,
+		"team":    v.Team,
+		"params":  map[string]string(v.Params),
+		"sort":    v.Sort,
+		"columns": []string(v.Columns),
+	}
+}
+
+// DefaultView legt de standaard weergave van een gebruiker vast
+type DefaultView struct {
+	UserID uint `gorm:"primaryKey;autoIncrement:false"`
+	ViewID uint `gorm:"not null;index"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (DefaultView) TableName() string {
+	return "saved_view_defaults"
+}
+
+// SavedViewRequest bevat de gegevens voor het aanmaken of bijwerken van een weergave
+type SavedViewRequest struct {
+	Name    string            `json:"name" binding:"required" example:"VIP klanten in Utrecht"`
+	Params  map[string]string `json:"params"`
+	Sort    string            `json:"sort" example:"-created_at,name"`
+	Columns []string          `json:"columns"`
+	Shared  bool              `json:"shared" example:"false"` // Deel de weergave met het eigen team
+}
+
+// Viewer is de ingelogde gebruiker die weergaven opvraagt, toepast of beheert
+type Viewer struct {
+	UserID   uint
+	Username string
+	Admin    bool
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/savedview/model"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SavedViewRepository definieert de interface voor de weergaven repository
+type SavedViewRepository interface {
+	FindVisible(userID uint, team string) ([]model.SavedView, error)
+	FindByID(id string) (*model.SavedView, error)
+	Create(view *model.SavedView) (*model.SavedView, error)
+	Update(view *model.SavedView) (*model.SavedView, error)
+	Delete(id uint) error
+	FindDefaultID(userID uint) (uint, error)
+	SetDefault(userID, viewID uint) error
+	ClearDefault(userID uint) error
+}
+
+// savedViewRepository implementeert de SavedViewRepository interface
+type savedViewRepository struct {
+	db *gorm.DB
+}
+
+// NewSavedViewRepository maakt een nieuwe SavedViewRepository instantie
+func NewSavedViewRepository(db *gorm.DB) SavedViewRepository {
+	return &savedViewRepository{
+		db: db,
+	}
+}
+
+// FindVisible haalt de eigen weergaven van een gebruiker op en de weergaven die met zijn of haar team gedeeld zijn,
+// gesorteerd op naam
+func (r *savedViewRepository) FindVisible(userID uint, team string) ([]model.SavedView, error) {
+	var views []model.SavedView
+
+	query := r.db.Where("owner_id = ?", userID)
+	if team != "" {
+		query = query.Or("team = ?", team)
+	}
+	if err := query.Order("LOWER(name) ASC, id ASC").Find(&views).Error; err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+// FindByID haalt een weergave op op basis van ID
+func (r *savedViewRepository) FindByID(id string) (*model.SavedView, error) {
+	var view model.SavedView
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&view, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("weergave niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &view, nil
+}
+
+// Create maakt een nieuwe weergave aan
+func (r *savedViewRepository) Create(view *model.SavedView) (*model.SavedView, error) {
+	if err := r.db.Create(view).Error; err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// Update werkt naam, team, filters, sortering en kolommen van een weergave bij
+func (r *savedViewRepository) Update(view *model.SavedView) (*model.SavedView, error) {
+	if err := r.db.Model(view).Select("name", "team", "params", "sort", "columns").Updates(view).Error; err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// Delete verwijdert een weergave; gebruikers die deze als standaard hadden vallen terug op geen weergave
+func (r *savedViewRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("view_id = ?", id).Delete(&model.DefaultView{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.SavedView{}, id).Error
+	})
+}
+
+// FindDefaultID geeft het ID van de standaard weergave van een gebruiker, of 0 als er geen is
+func (r *savedViewRepository) FindDefaultID(userID uint) (uint, error) {
+	var defaultView model.DefaultView
+	if err := r.db.Where("user_id = ?", userID).Take(&defaultView).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return defaultView.ViewID, nil
+}
+
+// SetDefault maakt een weergave de standaard weergave van een gebruiker
+func (r *savedViewRepository) SetDefault(userID, viewID uint) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"view_id"}),
+	}).Create(&model.DefaultView{UserID: userID, ViewID: viewID}).Error
+}
+
+// ClearDefault verwijdert de standaard weergave van een gebruiker
+func (r *savedViewRepository) ClearDefault(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.DefaultView{}).Error
+}
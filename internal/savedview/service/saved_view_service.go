@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	customerModel "odomosml/internal/customer/model"
+	customerService "odomosml/internal/customer/service"
+	"odomosml/internal/savedview/model"
+	"odomosml/internal/savedview/repository"
+	userRepo "odomosml/internal/user/repository"
+	"odomosml/pkg/sorting"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Grenzen aan de inhoud van een weergave
+const (
+	maxNameLength  = 100
+	maxParams      = 20
+	maxParamLength = 2000
+)
+
+// Fouten die de handler naar een specifieke HTTP status vertaalt
+var (
+	ErrViewNotFound  = errors.New("weergave niet gevonden")
+	ErrViewForbidden = errors.New("alleen de eigenaar kan deze weergave wijzigen")
+)
+
+// SavedViewService definieert de interface voor de weergaven service
+type SavedViewService interface {
+	GetViews(viewer model.Viewer) ([]model.SavedView, error)
+	GetView(id string, viewer model.Viewer) (*model.SavedView, error)
+	ResolveView(view string, viewer model.Viewer) (*model.SavedView, error)
+	CreateView(request model.SavedViewRequest, viewer model.Viewer) (*model.SavedView, error)
+	UpdateView(id string, request model.SavedViewRequest, viewer model.Viewer) (*model.SavedView, error)
+	DeleteView(id string, viewer model.Viewer) (map[string]interface{}, error)
+	SetDefaultView(id string, viewer model.Viewer) (*model.SavedView, error)
+	ClearDefaultView(viewer model.Viewer) error
+}
+
+// savedViewService implementeert de SavedViewService interface
+type savedViewService struct {
+	repo      repository.SavedViewRepository
+	userRepo  userRepo.UserRepository
+	customers customerService.CustomerService
+}
+
+// NewSavedViewService maakt een nieuwe SavedViewService instantie
+func NewSavedViewService(repo repository.SavedViewRepository, userRepo userRepo.UserRepository, customers customerService.CustomerService) SavedViewService {
+	return &savedViewService{
+		repo:      repo,
+		userRepo:  userRepo,
+		customers: customers,
+	}
+}
+
+// GetViews haalt de eigen en met het team gedeelde weergaven op; de standaard weergave is gemarkeerd
+func (s *savedViewService) GetViews(viewer model.Viewer) ([]model.SavedView, error) {
+	team, err := s.team(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	views, err := s.repo.FindVisible(viewer.UserID, team)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultID, err := s.repo.FindDefaultID(viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range views {
+		views[i].IsDefault = views[i].ID == defaultID
+	}
+
+	return views, nil
+}
+
+// GetView haalt een weergave op die de gebruiker mag zien: een eigen weergave of een weergave van het eigen team
+func (s *savedViewService) GetView(id string, viewer model.Viewer) (*model.SavedView, error) {
+	view, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrViewNotFound
+	}
+
+	team, err := s.team(viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !visible(view, viewer, team) {
+		return nil, ErrViewNotFound
+	}
+
+	defaultID, err := s.repo.FindDefaultID(viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	view.IsDefault = view.ID == defaultID
+
+	return view, nil
+}
+
+// ResolveView bepaalt welke weergave op de klantenlijst wordt toegepast: de weergave met het opgegeven ID,
+// geen weergave bij model.ViewNone, of zonder ID de standaard weergave van de gebruiker. Een standaard
+// weergave die niet meer zichtbaar is (bijv. na het wisselen van team) wordt genegeerd.
+func (s *savedViewService) ResolveView(view string, viewer model.Viewer) (*model.SavedView, error) {
+	switch view {
+	case model.ViewNone:
+		return nil, nil
+	case "":
+		defaultID, err := s.repo.FindDefaultID(viewer.UserID)
+		if err != nil || defaultID == 0 {
+			return nil, err
+		}
+		resolved, err := s.GetView(strconv.FormatUint(uint64(defaultID), 10), viewer)
+		if errors.Is(err, ErrViewNotFound) {
+			return nil, nil
+		}
+		return resolved, err
+	}
+	return s.GetView(view, viewer)
+}
+
+// CreateView slaat een nieuwe weergave op voor de gebruiker, eventueel gedeeld met het eigen team
+func (s *savedViewService) CreateView(request model.SavedViewRequest, viewer model.Viewer) (*model.SavedView, error) {
+	view := &model.SavedView{
+		OwnerID:   viewer.UserID,
+		OwnerName: viewer.Username,
+	}
+	if err := s.apply(view, request, viewer); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(view)
+}
+
+// UpdateView werkt een weergave bij; alleen de eigenaar (of een admin) mag dat
+func (s *savedViewService) UpdateView(id string, request model.SavedViewRequest, viewer model.Viewer) (*model.SavedView, error) {
+	view, err := s.owned(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bij een admin die de weergave van een ander bijwerkt blijft het team van de eigenaar gelden
+	owner := model.Viewer{UserID: view.OwnerID, Username: view.OwnerName}
+	if err := s.apply(view, request, owner); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Update(view)
+}
+
+// DeleteView verwijdert een weergave en retourneert de gegevens voor audit logging
+func (s *savedViewService) DeleteView(id string, viewer model.Viewer) (map[string]interface{}, error) {
+	view, err := s.owned(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Delete(view.ID); err != nil {
+		return nil, err
+	}
+
+	return view.ToAuditMap(), nil
+}
+
+// SetDefaultView maakt een zichtbare weergave de standaard weergave van de gebruiker
+func (s *savedViewService) SetDefaultView(id string, viewer model.Viewer) (*model.SavedView, error) {
+	view, err := s.GetView(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetDefault(viewer.UserID, view.ID); err != nil {
+		return nil, err
+	}
+	view.IsDefault = true
+
+	return view, nil
+}
+
+// ClearDefaultView verwijdert de standaard weergave van de gebruiker
+func (s *savedViewService) ClearDefaultView(viewer model.Viewer) error {
+	return s.repo.ClearDefault(viewer.UserID)
+}
+
+// owned haalt een weergave op die de gebruiker mag wijzigen: een eigen weergave, of als admin elke weergave
+func (s *savedViewService) owned(id string, viewer model.Viewer) (*model.SavedView, error) {
+	view, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrViewNotFound
+	}
+	if view.OwnerID == viewer.UserID || viewer.Admin {
+		return view, nil
+	}
+
+	// Teamleden zien een gedeelde weergave wel, maar mogen deze niet wijzigen
+	team, err := s.team(viewer)
+	if err != nil {
+		return nil, err
+	}
+	if visible(view, viewer, team) {
+		return nil, ErrViewForbidden
+	}
+	return nil, ErrViewNotFound
+}
+
+// apply valideert een request en neemt de gegevens over in de weergave. Filters, sortering en kolommen
+// worden gecontroleerd zoals de klantenlijst ze zou lezen; vrije velden in een filter worden pas bij
+// het toepassen gecontroleerd, omdat velddefinities later nog kunnen veranderen.
+func (s *savedViewService) apply(view *model.SavedView, request model.SavedViewRequest, owner model.Viewer) error {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return errors.New("naam is verplicht")
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return fmt.Errorf("naam mag maximaal %d tekens bevatten", maxNameLength)
+	}
+
+	if len(request.Params) > maxParams {
+		return fmt.Errorf("een weergave mag maximaal %d filters bevatten", maxParams)
+	}
+	params := make(model.Params, len(request.Params))
+	for key, value := range request.Params {
+		if !allowedParam(key) {
+			return fmt.Errorf("filter '%s' kan niet in een weergave opgeslagen worden", key)
+		}
+		if len(value) > maxParamLength {
+			return fmt.Errorf("filter '%s' mag maximaal %d tekens bevatten", key, maxParamLength)
+		}
+		if key == "filter" {
+			if err := s.customers.ValidateFilterExpression(value); err != nil {
+				return err
+			}
+		}
+		if value != "" {
+			params[key] = value
+		}
+	}
+
+	sort, err := sorting.Parse(request.Sort, customerModel.SortFields...)
+	if err != nil {
+		return err
+	}
+
+	var columns model.Columns
+	if len(request.Columns) > 0 {
+		if _, err := s.customers.ResolveExportColumns(request.Columns); err != nil {
+			return err
+		}
+		columns = request.Columns
+	}
+
+	team := ""
+	if request.Shared {
+		if team, err = s.team(owner); err != nil {
+			return err
+		}
+		if team == "" {
+			return errors.New("een weergave kan alleen gedeeld worden door een gebruiker die lid is van een team")
+		}
+	}
+
+	view.Name = name
+	view.Params = params
+	view.Sort = sorting.Format(sort)
+	view.Columns = columns
+	view.Team = team
+	return nil
+}
+
+// team geeft het team van de gebruiker
+func (s *savedViewService) team(viewer model.Viewer) (string, error) {
+	user, err := s.userRepo.FindByID(strconv.FormatUint(uint64(viewer.UserID), 10))
+	if err != nil {
+		return "", err
+	}
+	return user.Team, nil
+}
+
+// visible geeft aan of de gebruiker de weergave mag zien en toepassen
+func visible(view *model.SavedView, viewer model.Viewer, team string) bool {
+	return view.OwnerID == viewer.UserID || (view.Team != "" && view.Team == team)
+}
+
+// allowedParam geeft aan of een parameter van de klantenlijst in een weergave opgeslagen mag worden
+func allowedParam(key string) bool {
+	if strings.HasPrefix(key, customerModel.CustomFieldPrefix) {
+		return len(key) > len(customerModel.CustomFieldPrefix)
+	}
+	for _, allowed := range model.ParamKeys {
+		if key == allowed {
+			return true
+		}
+	}
+	return false
+}
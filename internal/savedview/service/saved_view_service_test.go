@@ -0,0 +1,290 @@
+package service
+
+import (
+	"errors"
+	customerService "odomosml/internal/customer/service"
+	"odomosml/internal/savedview/model"
+	"odomosml/internal/savedview/repository"
+	userModel "odomosml/internal/user/model"
+	userRepo "odomosml/internal/user/repository"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeSavedViewRepository bewaart weergaven en standaard weergaven in het geheugen
+type fakeSavedViewRepository struct {
+	repository.SavedViewRepository
+	views    map[uint]*model.SavedView
+	defaults map[uint]uint
+	nextID   uint
+}
+
+func newFakeSavedViewRepository() *fakeSavedViewRepository {
+	return &fakeSavedViewRepository{views: make(map[uint]*model.SavedView), defaults: make(map[uint]uint)}
+}
+
+func (r *fakeSavedViewRepository) FindByID(id string) (*model.SavedView, error) {
+	viewID, _ := strconv.ParseUint(id, 10, 64)
+	view, ok := r.views[uint(viewID)]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	copied := *view
+	return &copied, nil
+}
+
+func (r *fakeSavedViewRepository) Create(view *model.SavedView) (*model.SavedView, error) {
+	r.nextID++
+	view.ID = r.nextID
+	copied := *view
+	r.views[view.ID] = &copied
+	return view, nil
+}
+
+func (r *fakeSavedViewRepository) Update(view *model.SavedView) (*model.SavedView, error) {
+	copied := *view
+	r.views[view.ID] = &copied
+	return view, nil
+}
+
+func (r *fakeSavedViewRepository) Delete(id uint) error {
+	delete(r.views, id)
+	return nil
+}
+
+func (r *fakeSavedViewRepository) FindDefaultID(userID uint) (uint, error) {
+	return r.defaults[userID], nil
+}
+
+func (r *fakeSavedViewRepository) SetDefault(userID, viewID uint) error {
+	r.defaults[userID] = viewID
+	return nil
+}
+
+// fakeUserRepository kent gebruikers 1 en 2 (team verkoop), 3 (team support) en 4 (geen team)
+type fakeUserRepository struct {
+	userRepo.UserRepository
+	teams map[string]string
+}
+
+func (r *fakeUserRepository) FindByID(id string) (*userModel.User, error) {
+	team, ok := r.teams[id]
+	if !ok {
+		return nil, errors.New("gebruiker niet gevonden")
+	}
+	return &userModel.User{Team: team}, nil
+}
+
+// fakeCustomers controleert filters en kolommen zoals de klantenservice, met "status" als enige kolom
+type fakeCustomers struct {
+	customerService.CustomerService
+}
+
+func (fakeCustomers) ValidateFilterExpression(expression string) error {
+	if strings.Contains(expression, "(") {
+		return errors.New("ongeldig filter")
+	}
+	return nil
+}
+
+func (fakeCustomers) ResolveExportColumns(requested []string) ([]string, error) {
+	for _, column := range requested {
+		if column != "name" && column != "status" {
+			return nil, errors.New("onbekende kolom '" + column + "'")
+		}
+	}
+	return requested, nil
+}
+
+var (
+	owner    = model.Viewer{UserID: 1, Username: "johndoe"}
+	teammate = model.Viewer{UserID: 2, Username: "janedoe"}
+	outsider = model.Viewer{UserID: 3, Username: "piet"}
+	loner    = model.Viewer{UserID: 4, Username: "klaas"}
+	admin    = model.Viewer{UserID: 3, Username: "piet", Admin: true}
+)
+
+func newTestService() (SavedViewService, *fakeSavedViewRepository) {
+	repo := newFakeSavedViewRepository()
+	users := &fakeUserRepository{teams: map[string]string{"1": "verkoop", "2": "verkoop", "3": "support", "4": ""}}
+	return NewSavedViewService(repo, users, fakeCustomers{}), repo
+}
+
+func TestCreateView(t *testing.T) {
+	tests := []struct {
+		name    string
+		viewer  model.Viewer
+		request model.SavedViewRequest
+		want    model.SavedView
+		wantErr string
+	}{
+		{
+			name:   "persoonlijke weergave",
+			viewer: owner,
+			request: model.SavedViewRequest{Name: "  VIP klanten ", Params: map[string]string{"zoekterm": "jansen", "cf.regio": "noord", "tags": ""},
+				Sort: " -created_at , name", Columns: []string{"name", "status"}},
+			want: model.SavedView{ID: 1, Name: "VIP klanten", OwnerID: 1, OwnerName: "johndoe",
+				Params: model.Params{"zoekterm": "jansen", "cf.regio": "noord"}, Sort: "-created_at,name", Columns: model.Columns{"name", "status"}},
+		},
+		{
+			name:    "gedeeld met het team",
+			viewer:  owner,
+			request: model.SavedViewRequest{Name: "Verkoop", Shared: true},
+			want:    model.SavedView{ID: 1, Name: "Verkoop", OwnerID: 1, OwnerName: "johndoe", Team: "verkoop", Params: model.Params{}},
+		},
+		{name: "zonder naam", viewer: owner, request: model.SavedViewRequest{Name: " "}, wantErr: "naam is verplicht"},
+		{name: "te lange naam", viewer: owner, request: model.SavedViewRequest{Name: strings.Repeat("a", 101)}, wantErr: "naam mag maximaal 100 tekens bevatten"},
+		{
+			name:    "onbekende parameter",
+			viewer:  owner,
+			request: model.SavedViewRequest{Name: "Test", Params: map[string]string{"page": "2"}},
+			wantErr: "filter 'page' kan niet in een weergave opgeslagen worden",
+		},
+		{
+			name:    "alleen het prefix van een vrij veld",
+			viewer:  owner,
+			request: model.SavedViewRequest{Name: "Test", Params: map[string]string{"cf.": "noord"}},
+			wantErr: "filter 'cf.' kan niet in een weergave opgeslagen worden",
+		},
+		{
+			name:    "te lang filter",
+			viewer:  owner,
+			request: model.SavedViewRequest{Name: "Test", Params: map[string]string{"zoekterm": strings.Repeat("a", 2001)}},
+			wantErr: "filter 'zoekterm' mag maximaal 2000 tekens bevatten",
+		},
+		{name: "ongeldig filter", viewer: owner, request: model.SavedViewRequest{Name: "Test", Params: map[string]string{"filter": "(status"}}, wantErr: "ongeldig filter"},
+		{name: "ongeldige sortering", viewer: owner, request: model.SavedViewRequest{Name: "Test", Sort: "email"}, wantErr: "er kan niet gesorteerd worden op 'email'"},
+		{name: "onbekende kolom", viewer: owner, request: model.SavedViewRequest{Name: "Test", Columns: []string{"website"}}, wantErr: "onbekende kolom 'website'"},
+		{
+			name:    "delen zonder team",
+			viewer:  loner,
+			request: model.SavedViewRequest{Name: "Test", Shared: true},
+			wantErr: "een weergave kan alleen gedeeld worden door een gebruiker die lid is van een team",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService()
+			view, err := service.CreateView(tt.request, tt.viewer)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("CreateView gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateView gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(*view, tt.want) {
+				t.Errorf("weergave = %+v, verwacht %+v", *view, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewAccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		shared     bool
+		viewer     model.Viewer
+		wantGet    error
+		wantUpdate error
+	}{
+		{name: "eigenaar", viewer: owner},
+		{name: "teamlid, persoonlijke weergave", viewer: teammate, wantGet: ErrViewNotFound, wantUpdate: ErrViewNotFound},
+		{name: "teamlid, gedeelde weergave", shared: true, viewer: teammate, wantUpdate: ErrViewForbidden},
+		{name: "ander team", shared: true, viewer: outsider, wantGet: ErrViewNotFound, wantUpdate: ErrViewNotFound},
+		{name: "admin van een ander team", shared: true, viewer: admin, wantGet: ErrViewNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo := newTestService()
+			created, err := service.CreateView(model.SavedViewRequest{Name: "Verkoop", Shared: tt.shared}, owner)
+			if err != nil {
+				t.Fatalf("CreateView gaf fout: %v", err)
+			}
+			id := strconv.FormatUint(uint64(created.ID), 10)
+
+			if _, err := service.GetView(id, tt.viewer); !errors.Is(err, tt.wantGet) {
+				t.Errorf("GetView gaf %v, verwacht %v", err, tt.wantGet)
+			}
+
+			// Ook bij het delen door een admin blijft het team van de eigenaar gelden
+			updated, err := service.UpdateView(id, model.SavedViewRequest{Name: "Bijgewerkt", Shared: true}, tt.viewer)
+			if !errors.Is(err, tt.wantUpdate) {
+				t.Fatalf("UpdateView gaf %v, verwacht %v", err, tt.wantUpdate)
+			}
+			if err == nil && (updated.Team != "verkoop" || updated.OwnerID != owner.UserID || repo.views[created.ID].Name != "Bijgewerkt") {
+				t.Errorf("bijgewerkte weergave = %+v", updated)
+			}
+
+			if _, err := service.DeleteView(id, tt.viewer); !errors.Is(err, tt.wantUpdate) {
+				t.Errorf("DeleteView gaf %v, verwacht %v", err, tt.wantUpdate)
+			}
+			if _, exists := repo.views[created.ID]; exists != (tt.wantUpdate != nil) {
+				t.Errorf("weergave bestaat nog: %v", exists)
+			}
+		})
+	}
+
+	service, _ := newTestService()
+	if _, err := service.GetView("99", owner); !errors.Is(err, ErrViewNotFound) {
+		t.Errorf("GetView van een onbekende weergave gaf %v", err)
+	}
+}
+
+func TestResolveView(t *testing.T) {
+	service, repo := newTestService()
+	shared, _ := service.CreateView(model.SavedViewRequest{Name: "Verkoop", Shared: true}, owner)
+	personal, _ := service.CreateView(model.SavedViewRequest{Name: "Eigen"}, teammate)
+
+	if _, err := service.SetDefaultView("1", teammate); err != nil {
+		t.Fatalf("SetDefaultView gaf fout: %v", err)
+	}
+	if _, err := service.SetDefaultView("2", owner); !errors.Is(err, ErrViewNotFound) {
+		t.Errorf("standaard weergave van een ander ingesteld: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		view   string
+		viewer model.Viewer
+		want   uint
+	}{
+		{"standaard weergave", "", teammate, shared.ID},
+		{"expliciete weergave", "2", teammate, personal.ID},
+		{"geen weergave", model.ViewNone, teammate, 0},
+		{"zonder standaard weergave", "", owner, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view, err := service.ResolveView(tt.view, tt.viewer)
+			if err != nil {
+				t.Fatalf("ResolveView gaf fout: %v", err)
+			}
+			var got uint
+			if view != nil {
+				got = view.ID
+				if got == shared.ID && !view.IsDefault {
+					t.Error("standaard weergave niet gemarkeerd")
+				}
+			}
+			if got != tt.want {
+				t.Errorf("weergave %d, verwacht %d", got, tt.want)
+			}
+		})
+	}
+
+	// Een standaard weergave die niet meer gedeeld is wordt genegeerd, een expliciete niet
+	repo.views[shared.ID].Team = ""
+	if view, err := service.ResolveView("", teammate); view != nil || err != nil {
+		t.Errorf("ResolveView = %+v, %v; verwacht geen weergave", view, err)
+	}
+	if _, err := service.ResolveView("1", teammate); !errors.Is(err, ErrViewNotFound) {
+		t.Errorf("ResolveView(1) gaf %v, verwacht ErrViewNotFound", err)
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"odomosml/internal/search/model"
+	"odomosml/internal/search/service"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchHandler handles HTTP requests for the global search
+type SearchHandler struct {
+	service service.SearchService
+}
+
+// NewSearchHandler maakt een nieuwe SearchHandler instantie
+func NewSearchHandler(service service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		service: service,
+	}
+}
+
+// @Summary      Zoeken in klanten en activiteiten
+// @Description  Zoekt met full-text search (Nederlandse woordvormen) en trigrammen (typefouten) in klanten en de tekst van activiteiten. Resultaten zijn gesorteerd op relevantie; de snippet is HTML met treffers in <mark>.
+// @Tags         search
+// @Accept       json
+// @Produce      json
+// @Param        q query string true "Zoekterm; ondersteunt \"exacte woordgroep\", or en -uitsluiten"
+// @Param        types query string false "Komma-gescheiden soorten: customer, activity (default: alle)"
+// @Param        limit query int false "Maximaal aantal resultaten (default: 20, max: 50)"
+// @Success      200  {object}  map[string]interface{} "{ data: []model.Result }"
+// @Failure      400  {object}  map[string]string "Ongeldige zoekopdracht"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      500  {object}  map[string]string "Server error"
+// @Security     Bearer
+// @Router       /search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := model.Query{Term: c.Query("q")}
+	if value := c.Query("types"); value != "" {
+		for _, typ := range strings.Split(value, ",") {
+			if typ = strings.TrimSpace(typ); typ != "" {
+				query.Types = append(query.Types, typ)
+			}
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "limit moet een positief getal zijn",
+			})
+			return
+		}
+		query.Limit = limit
+	}
+
+	results, err := h.service.Search(query)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidQuery) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    results,
+	})
+}
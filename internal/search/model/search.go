@@ -0,0 +1,29 @@
+package model
+
+// Soorten zoekresultaten
+const (
+	TypeCustomer = "customer"
+	TypeActivity = "activity"
+)
+
+// Types zijn alle soorten resultaten, in de volgorde waarin ze bij gelijke relevantie getoond worden
+var Types = []string{TypeCustomer, TypeActivity}
+
+// Result is een klant of activiteit die bij een zoekopdracht gevonden is
+// @Description Zoekresultaat: een klant of een activiteit (notitie, gesprek, afspraak, e-mail) bij een klant
+type Result struct {
+	Type         string  `json:"type" example:"customer" swaggertype:"string"`
+	ID           uint    `json:"id" example:"1" swaggertype:"integer"`
+	CustomerID   uint    `json:"customer_id" example:"1" swaggertype:"integer"`
+	Title        string  `json:"title" example:"Bakkerij Jansen" swaggertype:"string"`
+	ActivityType string  `json:"activity_type,omitempty" example:"note" swaggertype:"string"`
+	Snippet      string  `json:"snippet" example:"<mark>Bakkerij</mark> Jansen · info@jansen.nl" swaggertype:"string"` // HTML: de tekst is ge-escaped, treffers staan in <mark>
+	Rank         float64 `json:"rank" example:"0.42" swaggertype:"number"`
+}
+
+// Query is een zoekopdracht over klanten en activiteiten
+type Query struct {
+	Term  string
+	Types []string
+	Limit int
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"odomosml/internal/search/model"
+	"odomosml/pkg/fieldcrypt"
+
+	"gorm.io/gorm"
+)
+
+// headlineOptions bepalen hoe ts_headline de snippet opbouwt: treffers in <mark>, maximaal twee fragmenten
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=8, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// SearchRepository definieert de interface voor de zoek repository
+type SearchRepository interface {
+	SearchCustomers(term string, limit int) ([]model.Result, error)
+	SearchActivities(term string, limit int) ([]model.Result, error)
+}
+
+// searchRepository implementeert de SearchRepository interface
+type searchRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchRepository maakt een nieuwe SearchRepository instantie
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return &searchRepository{
+		db: db,
+	}
+}
+
+// SearchCustomers zoekt klanten via de full-text kolom (naam, KvK nummer, BTW-nummer en vrije velden), via
+// trigrammen op de naam, zodat ook een zoekterm met een typefout iets vindt, en op een volledig e-mailadres
+// via de blind index. E-mail, telefoon en adres zijn versleuteld en staan niet in de full-text kolom.
+// De relevantie combineert de full-text rank met de gelijkenis van de naam.
+func (r *searchRepository) SearchCustomers(term string, limit int) ([]model.Result, error) {
+	var results []model.Result
+
+	err := r.db.Raw(`SELECT 'customer' AS type, c.id, c.id AS customer_id, c.name AS title,
+			ts_headline('dutch', `+escapeHTML(`concat_ws(' · ', c.name, nullif(c.kvk_number, ''), nullif(c.vat_number, ''))`)+`,
+				query, @options) AS snippet,
+			ts_rank_cd(c.search_vector, query, 32) + 0.5 * word_similarity(@term, c.name) + (c.email_index = @email_index)::int AS rank
+		FROM customers c
+		CROSS JOIN websearch_to_tsquery('dutch', @term) AS query
+		WHERE c.deleted_at IS NULL AND (c.search_vector @@ query OR @term <% c.name OR c.email_index = @email_index)
+		ORDER BY rank DESC, c.id ASC
+		LIMIT @limit`,
+		map[string]interface{}{"term": term, "email_index": fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, term), "options": headlineOptions, "limit": limit}).
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SearchActivities zoekt in de tekst van activiteiten; de titel is de naam van de klant
+func (r *searchRepository) SearchActivities(term string, limit int) ([]model.Result, error) {
+	var results []model.Result
+
+	err := r.db.Raw(`SELECT 'activity' AS type, a.id, a.customer_id, c.name AS title, a.type AS activity_type,
+			ts_headline('dutch', `+escapeHTML("a.body")+`, query, @options) AS snippet,
+			ts_rank_cd(a.search_vector, query, 32) AS rank
+		FROM activities a
+		JOIN customers c ON c.id = a.customer_id AND c.deleted_at IS NULL
+		CROSS JOIN websearch_to_tsquery('dutch', @term) AS query
+		WHERE a.search_vector @@ query
+		ORDER BY rank DESC, a.occurred_at DESC, a.id DESC
+		LIMIT @limit`,
+		map[string]interface{}{"term": term, "options": headlineOptions, "limit": limit}).
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// escapeHTML escapet een SQL tekstexpressie voor HTML, zodat in de snippet alleen de <mark> tags van ts_headline HTML zijn
+func escapeHTML(expr string) string {
+	return "replace(replace(replace(" + expr + ", '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"odomosml/pkg/fieldcrypt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// captureQuery maakt een repository zonder database die de laatste query en parameters bewaart
+func captureQuery(t *testing.T) (*searchRepository, *gorm.Statement) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("gorm.Open gaf fout: %v", err)
+	}
+
+	captured := &gorm.Statement{}
+	err = db.Callback().Row().Register("test:capture", func(tx *gorm.DB) {
+		captured.SQL.Reset()
+		captured.SQL.WriteString(tx.Statement.SQL.String())
+		captured.Vars = tx.Statement.Vars
+	})
+	if err != nil {
+		t.Fatalf("callback registreren gaf fout: %v", err)
+	}
+	return &searchRepository{db: db}, captured
+}
+
+func TestSearchQueries(t *testing.T) {
+	// Een zoekterm met SQL en HTML gaat alleen als parameter mee
+	term := "jansen'); DROP TABLE customers; --<b>"
+	emailIndex := fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, term)
+
+	tests := []struct {
+		name     string
+		search   func(repo *searchRepository) error
+		contains []string
+		wantVars []interface{}
+	}{
+		{
+			name:   "klanten",
+			search: func(repo *searchRepository) error { _, err := repo.SearchCustomers(term, 20); return err },
+			contains: []string{
+				"websearch_to_tsquery('dutch', $",
+				"word_similarity($",
+				"replace(replace(replace(concat_ws(' · ', c.name, nullif(c.kvk_number, ''), nullif(c.vat_number, '')), '&', '&amp;'), '<', '&lt;'), '>', '&gt;')",
+				"c.deleted_at IS NULL",
+			},
+			wantVars: []interface{}{headlineOptions, term, emailIndex, term, term, emailIndex, 20},
+		},
+		{
+			name:     "activiteiten",
+			search:   func(repo *searchRepository) error { _, err := repo.SearchActivities(term, 5); return err },
+			contains: []string{"websearch_to_tsquery('dutch', $", "replace(replace(replace(a.body, '&', '&amp;')", "c.deleted_at IS NULL"},
+			wantVars: []interface{}{headlineOptions, term, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, captured := captureQuery(t)
+			tt.search(repo)
+
+			sql := captured.SQL.String()
+			for _, part := range tt.contains {
+				if !strings.Contains(sql, part) {
+					t.Errorf("query bevat geen %q:\n%s", part, sql)
+				}
+			}
+			if strings.Contains(sql, "DROP TABLE") || strings.ContainsAny(strings.ReplaceAll(sql, "@@", ""), "@") {
+				t.Errorf("zoekterm of benoemde parameter in de query:\n%s", sql)
+			}
+			if len(captured.Vars) != len(tt.wantVars) {
+				t.Fatalf("vars = %v, verwacht %v", captured.Vars, tt.wantVars)
+			}
+			for i := range tt.wantVars {
+				if captured.Vars[i] != tt.wantVars[i] {
+					t.Errorf("var %d = %v, verwacht %v", i+1, captured.Vars[i], tt.wantVars[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"odomosml/internal/search/model"
+	"odomosml/internal/search/repository"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Grenzen aan een zoekopdracht
+const (
+	minTermLength = 2
+	maxTermLength = 200
+	defaultLimit  = 20
+	maxLimit      = 50
+)
+
+// ErrInvalidQuery is de fout bij een ongeldige zoekopdracht; de handler vertaalt deze naar een 400
+var ErrInvalidQuery = errors.New("ongeldige zoekopdracht")
+
+// SearchService definieert de interface voor de zoek service
+type SearchService interface {
+	Search(query model.Query) ([]model.Result, error)
+}
+
+// searchService implementeert de SearchService interface
+type searchService struct {
+	repo repository.SearchRepository
+}
+
+// NewSearchService maakt een nieuwe SearchService instantie
+func NewSearchService(repo repository.SearchRepository) SearchService {
+	return &searchService{
+		repo: repo,
+	}
+}
+
+// Search zoekt in de gevraagde soorten (standaard alle) en geeft de meest relevante resultaten eerst
+func (s *searchService) Search(query model.Query) ([]model.Result, error) {
+	term := strings.TrimSpace(query.Term)
+	if utf8.RuneCountInString(term) < minTermLength {
+		return nil, fmt.Errorf("%w: de zoekterm moet minstens %d tekens bevatten", ErrInvalidQuery, minTermLength)
+	}
+	if utf8.RuneCountInString(term) > maxTermLength {
+		return nil, fmt.Errorf("%w: de zoekterm mag maximaal %d tekens bevatten", ErrInvalidQuery, maxTermLength)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	types := query.Types
+	if len(types) == 0 {
+		types = model.Types
+	}
+	for _, typ := range types {
+		if !contains(model.Types, typ) {
+			return nil, fmt.Errorf("%w: onbekend soort resultaat '%s', gebruik %s", ErrInvalidQuery, typ, strings.Join(model.Types, " of "))
+		}
+	}
+
+	// Per soort de beste resultaten ophalen en daarna samen op relevantie sorteren
+	var results []model.Result
+	for _, typ := range model.Types {
+		if !contains(types, typ) {
+			continue
+		}
+
+		var found []model.Result
+		var err error
+		switch typ {
+		case model.TypeCustomer:
+			found, err = s.repo.SearchCustomers(term, limit)
+		case model.TypeActivity:
+			found, err = s.repo.SearchActivities(term, limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	if results == nil {
+		results = []model.Result{}
+	}
+
+	return results, nil
+}
+
+// contains geeft aan of de lijst de waarde bevat
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
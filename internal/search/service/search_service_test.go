@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"odomosml/internal/search/model"
+	"odomosml/internal/search/repository"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeSearchRepository geeft vaste resultaten per soort en onthoudt met welke limiet gezocht is
+type fakeSearchRepository struct {
+	repository.SearchRepository
+	customers  []model.Result
+	activities []model.Result
+	calls      []string
+	limit      int
+}
+
+func (r *fakeSearchRepository) SearchCustomers(term string, limit int) ([]model.Result, error) {
+	r.calls = append(r.calls, "customers:"+term)
+	r.limit = limit
+	return r.customers[:min(limit, len(r.customers))], nil
+}
+
+func (r *fakeSearchRepository) SearchActivities(term string, limit int) ([]model.Result, error) {
+	r.calls = append(r.calls, "activities:"+term)
+	r.limit = limit
+	return r.activities[:min(limit, len(r.activities))], nil
+}
+
+// resultKeys geeft de resultaten als "customer:1" voor het vergelijken
+func resultKeys(results []model.Result) []string {
+	keys := make([]string, len(results))
+	for i, result := range results {
+		keys[i] = fmt.Sprintf("%s:%d", result.Type, result.ID)
+	}
+	return keys
+}
+
+func TestSearch(t *testing.T) {
+	customers := []model.Result{
+		{Type: model.TypeCustomer, ID: 1, Rank: 0.9},
+		{Type: model.TypeCustomer, ID: 2, Rank: 0.5},
+		{Type: model.TypeCustomer, ID: 3, Rank: 0.1},
+	}
+	activities := []model.Result{
+		{Type: model.TypeActivity, ID: 4, Rank: 0.7},
+		{Type: model.TypeActivity, ID: 5, Rank: 0.5},
+	}
+
+	tests := []struct {
+		name      string
+		query     model.Query
+		want      []string
+		wantCalls []string
+		wantLimit int
+	}{
+		{
+			name:      "alle soorten op relevantie, bij gelijke relevantie eerst klanten",
+			query:     model.Query{Term: "  jansen "},
+			want:      []string{"customer:1", "activity:4", "customer:2", "activity:5", "customer:3"},
+			wantCalls: []string{"customers:jansen", "activities:jansen"},
+			wantLimit: 20,
+		},
+		{
+			name:      "limiet over alle soorten samen",
+			query:     model.Query{Term: "jansen", Limit: 2},
+			want:      []string{"customer:1", "activity:4"},
+			wantCalls: []string{"customers:jansen", "activities:jansen"},
+			wantLimit: 2,
+		},
+		{
+			name:      "alleen activiteiten",
+			query:     model.Query{Term: "offerte", Types: []string{model.TypeActivity}},
+			want:      []string{"activity:4", "activity:5"},
+			wantCalls: []string{"activities:offerte"},
+			wantLimit: 20,
+		},
+		{
+			name:      "te hoge limiet",
+			query:     model.Query{Term: "jansen", Limit: 500, Types: []string{model.TypeActivity, model.TypeCustomer}},
+			want:      []string{"customer:1", "activity:4", "customer:2", "activity:5", "customer:3"},
+			wantCalls: []string{"customers:jansen", "activities:jansen"},
+			wantLimit: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeSearchRepository{customers: customers, activities: activities}
+			results, err := NewSearchService(repo).Search(tt.query)
+			if err != nil {
+				t.Fatalf("Search gaf fout: %v", err)
+			}
+			if got := resultKeys(results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resultaten = %v, verwacht %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(repo.calls, tt.wantCalls) || repo.limit != tt.wantLimit {
+				t.Errorf("gezocht met %v (limiet %d), verwacht %v (limiet %d)", repo.calls, repo.limit, tt.wantCalls, tt.wantLimit)
+			}
+		})
+	}
+
+	// Zonder treffers een lege lijst, zodat de JSON [] is en geen null
+	results, err := NewSearchService(&fakeSearchRepository{}).Search(model.Query{Term: "xyz"})
+	if err != nil || results == nil || len(results) != 0 {
+		t.Errorf("Search zonder treffers = %#v, %v", results, err)
+	}
+}
+
+func TestSearchInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   model.Query
+		wantErr string
+	}{
+		{"te kort", model.Query{Term: " a "}, "de zoekterm moet minstens 2 tekens bevatten"},
+		{"te lang", model.Query{Term: strings.Repeat("é", 201)}, "de zoekterm mag maximaal 200 tekens bevatten"},
+		{"onbekende soort", model.Query{Term: "jansen", Types: []string{"customer", "invoice"}}, "onbekend soort resultaat 'invoice', gebruik customer of activity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeSearchRepository{}
+			_, err := NewSearchService(repo).Search(tt.query)
+			if !errors.Is(err, ErrInvalidQuery) || !strings.HasSuffix(err.Error(), tt.wantErr) {
+				t.Errorf("Search gaf %v, verwacht %q", err, tt.wantErr)
+			}
+			if len(repo.calls) != 0 {
+				t.Errorf("repository aangeroepen: %v", repo.calls)
+			}
+		})
+	}
+
+	// De lengte telt in tekens, niet in bytes: 200 keer é is 400 bytes maar nog toegestaan
+	if _, err := NewSearchService(&fakeSearchRepository{}).Search(model.Query{Term: strings.Repeat("é", 200)}); err != nil {
+		t.Errorf("Search met 200 tekens gaf %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	auditModel "odomosml/internal/audit/model"
+	"odomosml/internal/tag/model"
+	"odomosml/internal/tag/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TagHandler handles HTTP requests for tags
+type TagHandler struct {
+	service service.TagService
+}
+
+// NewTagHandler maakt een nieuwe TagHandler instantie
+func NewTagHandler(service service.TagService) *TagHandler {
+	return &TagHandler{
+		service: service,
+	}
+}
+
+// @Summary      Lijst van tags ophalen
+// @Description  Haalt alle tags op met het aantal klanten per tag
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      500  {object}  map[string]string "Server error"
+// @Security     Bearer
+// @Router       /tags [get]
+func (h *TagHandler) GetAll(c *gin.Context) {
+	tags, err := h.service.GetAllTags()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tags,
+	})
+}
+
+// @Summary      Nieuwe tag aanmaken
+// @Description  Maakt een nieuwe tag aan
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        tag body model.Tag true "Tag gegevens"
+// @Success      201  {object}  model.Tag "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /tags [post]
+func (h *TagHandler) Create(c *gin.Context) {
+	var tag model.Tag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	created, err := h.service.CreateTag(&tag)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Tag bijwerken
+// @Description  Hernoemt een tag en/of wijzigt de kleur
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Tag ID"
+// @Param        tag body model.Tag true "Tag gegevens"
+// @Success      200  {object}  model.Tag "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Tag niet gevonden"
+// @Security     Bearer
+// @Router       /tags/{id} [put]
+func (h *TagHandler) Update(c *gin.Context) {
+	idInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldig ID",
+		})
+		return
+	}
+
+	var tag model.Tag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	tag.ID = uint(idInt)
+
+	updated, err := h.service.UpdateTag(&tag)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// @Summary      Tag verwijderen
+// @Description  Verwijdert een tag en ontkoppelt deze van alle klanten
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Tag ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      400  {object}  map[string]string "Ongeldig ID"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /tags/{id} [delete]
+func (h *TagHandler) Delete(c *gin.Context) {
+	tagData, err := h.service.DeleteTag(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Sla tagData op in context voor audit logging
+	c.Set("tagData", tagData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Tag succesvol verwijderd",
+	})
+}
+
+// @Summary      Tags samenvoegen
+// @Description  Verplaatst alle klanten van deze tag naar de doel-tag en verwijdert deze tag
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Bron tag ID"
+// @Param        merge body model.MergeRequest true "Doel-tag"
+// @Success      200  {object}  map[string]interface{} "Succesvol samengevoegd"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /tags/{id}/merge [post]
+func (h *TagHandler) Merge(c *gin.Context) {
+	var req model.MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	sourceID := c.Param("id")
+	target, moved, err := h.service.MergeTags(sourceID, req.TargetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		ActionType:  auditModel.ActionUpdate,
+		EntityType:  auditModel.EntityTag,
+		EntityID:    strconv.FormatUint(uint64(target.ID), 10),
+		Description: fmt.Sprintf("Tag %s samengevoegd met tag %s (%s)", sourceID, target.Name, strconv.FormatUint(uint64(target.ID), 10)),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    target,
+		"moved":   moved,
+	})
+}
+
+// @Summary      Tags koppelen aan klanten
+// @Description  Koppelt een of meer tags aan een selectie klanten
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        request body model.BulkTagRequest true "Klanten en tags"
+// @Success      200  {object}  map[string]interface{} "Succesvol gekoppeld"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/tags [post]
+func (h *TagHandler) BulkTag(c *gin.Context) {
+	h.bulk(c, true)
+}
+
+// @Summary      Tags ontkoppelen van klanten
+// @Description  Ontkoppelt een of meer tags van een selectie klanten
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        request body model.BulkTagRequest true "Klanten en tags"
+// @Success      200  {object}  map[string]interface{} "Succesvol ontkoppeld"
+// @Failure      400  {object}  map[string]string "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /klanten/tags [delete]
+func (h *TagHandler) BulkUntag(c *gin.Context) {
+	h.bulk(c, false)
+}
+
+// bulk voert een bulk (ont)koppel actie uit
+func (h *TagHandler) bulk(c *gin.Context, tag bool) {
+	var req model.BulkTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	var affected int64
+	var err error
+	var description string
+	if tag {
+		affected, err = h.service.TagCustomers(req)
+		description = fmt.Sprintf("Tags %v gekoppeld aan %d klant(en)", req.TagIDs, len(req.CustomerIDs))
+	} else {
+		affected, err = h.service.UntagCustomers(req)
+		description = fmt.Sprintf("Tags %v ontkoppeld van %d klant(en)", req.TagIDs, len(req.CustomerIDs))
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Set(auditModel.ContextKey, &auditModel.AuditLog{
+		ActionType:  auditModel.ActionUpdate,
+		EntityType:  auditModel.EntityCustomer,
+		Description: description,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"affected": affected,
+	})
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// DefaultColor is de kleur die een tag krijgt als er geen kleur is opgegeven
+const DefaultColor = "#9E9E9E"
+
+// Tag representeert een label waarmee klanten gecategoriseerd worden (bijv. "prospect", "VIP")
+// @Description Een label voor het categoriseren van klanten
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	Name      string    `json:"name" gorm:"size:50;not null" binding:"required" example:"VIP" swaggertype:"string"` // Uniek zonder op hoofdletters te letten (idx_tags_name_lower)
+	Color     string    `json:"color" gorm:"size:7;not null;default:'#9E9E9E'" example:"#FF9800" swaggertype:"string"`
+	CreatedAt time.Time `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// TagWithCount is een tag met het aantal klanten dat de tag heeft
+// @Description Tag met het aantal gekoppelde klanten
+type TagWithCount struct {
+	Tag
+	CustomerCount int64 `json:"customer_count" example:"12" swaggertype:"integer"`
+}
+
+// MergeRequest bevat de doel-tag waarin een tag wordt samengevoegd
+type MergeRequest struct {
+	TargetID uint `json:"target_id" binding:"required"`
+}
+
+// BulkTagRequest bevat een selectie klanten en de tags die (ont)koppeld moeten worden
+type BulkTagRequest struct {
+	CustomerIDs []uint `json:"customer_ids" binding:"required,min=1"`
+	TagIDs      []uint `json:"tag_ids" binding:"required,min=1"`
+}
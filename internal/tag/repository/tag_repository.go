@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/tag/model"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// customerTagsTable is de koppeltabel tussen klanten en tags
+const customerTagsTable = "customer_tags"
+
+// ErrDuplicateName betekent dat er al een tag met deze naam is, zonder op hoofdletters te letten
+var ErrDuplicateName = errors.New("tag met deze naam bestaat al")
+
+// nameConflict is de unieke index op LOWER(name) als doel van ON CONFLICT
+var nameConflict = clause.OnConflict{Columns: []clause.Column{{Name: "(LOWER(name))", Raw: true}}, DoNothing: true}
+
+// TagRepository definieert de interface voor tag repository
+type TagRepository interface {
+	FindAllWithCounts() ([]model.TagWithCount, error)
+	FindByID(id string) (*model.Tag, error)
+	FindByName(name string) (*model.Tag, error)
+	CountExisting(ids []uint) (int64, error)
+	Create(tag *model.Tag) (*model.Tag, error)
+	Update(tag *model.Tag) (*model.Tag, error)
+	Delete(id uint) error
+	Merge(sourceID, targetID uint) (int64, error)
+	TagCustomers(customerIDs, tagIDs []uint) (int64, error)
+	UntagCustomers(customerIDs, tagIDs []uint) (int64, error)
+}
+
+// tagRepository implementeert de TagRepository interface
+type tagRepository struct {
+	db *gorm.DB
+}
+
+// NewTagRepository maakt een nieuwe TagRepository instantie
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{
+		db: db,
+	}
+}
+
+// FindAllWithCounts haalt alle tags op met het aantal klanten per tag
+func (r *tagRepository) FindAllWithCounts() ([]model.TagWithCount, error) {
+	var tags []model.TagWithCount
+
+	err := r.db.Model(&model.Tag{}).
+		Select("tags.*, COUNT(ct.customer_id) AS customer_count").
+		Joins("LEFT JOIN " + customerTagsTable + " ct ON ct.tag_id = tags.id").
+		Group("tags.id").
+		Order("tags.name ASC").
+		Scan(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// FindByID haalt een tag op op basis van ID
+func (r *tagRepository) FindByID(id string) (*model.Tag, error) {
+	var tag model.Tag
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&tag, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tag niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// FindByName haalt een tag op op basis van naam (hoofdletterongevoelig)
+func (r *tagRepository) FindByName(name string) (*model.Tag, error) {
+	var tag model.Tag
+
+	if err := r.db.Where("LOWER(name) = LOWER(?)", name).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tag niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// CountExisting telt hoeveel van de opgegeven tag IDs bestaan
+func (r *tagRepository) CountExisting(ids []uint) (int64, error) {
+	var count int64
+	if err := r.db.Model(&model.Tag{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Create maakt een nieuwe tag aan. Bestaat de naam al (ook met andere hoofdletters, bijv. door een
+// gelijktijdig verzoek), dan is de fout ErrDuplicateName.
+func (r *tagRepository) Create(tag *model.Tag) (*model.Tag, error) {
+	result := r.db.Clauses(nameConflict).Create(tag)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDuplicateName
+	}
+	return tag, nil
+}
+
+// Update werkt naam en kleur van een bestaande tag bij. Gebruikt een andere tag de naam al, dan houdt
+// de unieke index de wijziging tegen en is de fout ErrDuplicateName.
+func (r *tagRepository) Update(tag *model.Tag) (*model.Tag, error) {
+	if err := r.db.Model(tag).Select("name", "color").Updates(tag).Error; err != nil {
+		if other, _ := r.FindByName(tag.Name); other != nil && other.ID != tag.ID {
+			return nil, ErrDuplicateName
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+// Delete verwijdert een tag inclusief alle koppelingen met klanten
+func (r *tagRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM "+customerTagsTable+" WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Tag{}, id).Error
+	})
+}
+
+// Merge verplaatst alle koppelingen van de bron-tag naar de doel-tag en verwijdert de bron-tag.
+// Retourneert het aantal klanten dat de doel-tag erbij heeft gekregen.
+func (r *tagRepository) Merge(sourceID, targetID uint) (int64, error) {
+	var moved int64
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Klanten die beide tags al hebben worden door ON CONFLICT overgeslagen
+		result := tx.Exec("INSERT INTO "+customerTagsTable+" (customer_id, tag_id) "+
+			"SELECT customer_id, ? FROM "+customerTagsTable+" WHERE tag_id = ? "+
+			"ON CONFLICT DO NOTHING", targetID, sourceID)
+		if result.Error != nil {
+			return result.Error
+		}
+		moved = result.RowsAffected
+
+		if err := tx.Exec("DELETE FROM "+customerTagsTable+" WHERE tag_id = ?", sourceID).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&model.Tag{}, sourceID).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return moved, nil
+}
+
+// TagCustomers koppelt alle opgegeven tags aan alle opgegeven klanten.
+// Bestaande koppelingen en onbekende IDs worden overgeslagen; retourneert het aantal nieuwe koppelingen.
+func (r *tagRepository) TagCustomers(customerIDs, tagIDs []uint) (int64, error) {
+	result := r.db.Exec("INSERT INTO "+customerTagsTable+" (customer_id, tag_id) "+
+		"SELECT c.id, t.id FROM customers c CROSS JOIN tags t "+
+		"WHERE c.id IN ? AND c.deleted_at IS NULL AND t.id IN ? "+
+		"ON CONFLICT DO NOTHING", customerIDs, tagIDs)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// UntagCustomers ontkoppelt de opgegeven tags van de opgegeven klanten.
+// Retourneert het aantal verwijderde koppelingen.
+func (r *tagRepository) UntagCustomers(customerIDs, tagIDs []uint) (int64, error) {
+	result := r.db.Exec("DELETE FROM "+customerTagsTable+" WHERE customer_id IN ? AND tag_id IN ?", customerIDs, tagIDs)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"odomosml/internal/tag/model"
+	"odomosml/internal/tag/repository"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// colorPattern valideert hex kleuren in het formaat #RRGGBB
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// TagService definieert de interface voor tag service
+type TagService interface {
+	GetAllTags() ([]model.TagWithCount, error)
+	GetTagByID(id string) (*model.Tag, error)
+	CreateTag(tag *model.Tag) (*model.Tag, error)
+	UpdateTag(tag *model.Tag) (*model.Tag, error)
+	DeleteTag(id string) (map[string]interface{}, error)
+	MergeTags(sourceID string, targetID uint) (*model.Tag, int64, error)
+	TagCustomers(req model.BulkTagRequest) (int64, error)
+	UntagCustomers(req model.BulkTagRequest) (int64, error)
+}
+
+// tagService implementeert de TagService interface
+type tagService struct {
+	repo repository.TagRepository
+}
+
+// NewTagService maakt een nieuwe TagService instantie
+func NewTagService(repo repository.TagRepository) TagService {
+	return &tagService{
+		repo: repo,
+	}
+}
+
+// GetAllTags haalt alle tags op inclusief het aantal klanten per tag
+func (s *tagService) GetAllTags() ([]model.TagWithCount, error) {
+	return s.repo.FindAllWithCounts()
+}
+
+// GetTagByID haalt een tag op op basis van ID
+func (s *tagService) GetTagByID(id string) (*model.Tag, error) {
+	return s.repo.FindByID(id)
+}
+
+// CreateTag maakt een nieuwe tag aan
+func (s *tagService) CreateTag(tag *model.Tag) (*model.Tag, error) {
+	if err := s.validate(tag); err != nil {
+		return nil, err
+	}
+
+	// Controleer of de naam al bestaat
+	if existing, _ := s.repo.FindByName(tag.Name); existing != nil {
+		return nil, repository.ErrDuplicateName
+	}
+
+	return s.repo.Create(tag)
+}
+
+// UpdateTag hernoemt een tag en/of wijzigt de kleur
+func (s *tagService) UpdateTag(tag *model.Tag) (*model.Tag, error) {
+	if tag.ID == 0 {
+		return nil, errors.New("tag ID is verplicht")
+	}
+
+	existing, err := s.repo.FindByID(strconv.FormatUint(uint64(tag.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(tag); err != nil {
+		return nil, err
+	}
+
+	// Controleer of de nieuwe naam al door een andere tag gebruikt wordt
+	if other, _ := s.repo.FindByName(tag.Name); other != nil && other.ID != tag.ID {
+		return nil, repository.ErrDuplicateName
+	}
+
+	tag.CreatedAt = existing.CreatedAt
+	return s.repo.Update(tag)
+}
+
+// DeleteTag verwijdert een tag en retourneert de tag data voor audit logging
+func (s *tagService) DeleteTag(id string) (map[string]interface{}, error) {
+	tag, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tagData := map[string]interface{}{
+		"id":    tag.ID,
+		"name":  tag.Name,
+		"color": tag.Color,
+	}
+
+	if err := s.repo.Delete(tag.ID); err != nil {
+		return nil, err
+	}
+
+	return tagData, nil
+}
+
+// MergeTags voegt de bron-tag samen met de doel-tag; de bron-tag wordt verwijderd
+func (s *tagService) MergeTags(sourceID string, targetID uint) (*model.Tag, int64, error) {
+	source, err := s.repo.FindByID(sourceID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	target, err := s.repo.FindByID(strconv.FormatUint(uint64(targetID), 10))
+	if err != nil {
+		return nil, 0, errors.New("doel-tag niet gevonden")
+	}
+
+	if source.ID == target.ID {
+		return nil, 0, errors.New("een tag kan niet met zichzelf worden samengevoegd")
+	}
+
+	moved, err := s.repo.Merge(source.ID, target.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return target, moved, nil
+}
+
+// TagCustomers koppelt tags aan een selectie klanten
+func (s *tagService) TagCustomers(req model.BulkTagRequest) (int64, error) {
+	if err := s.validateBulk(req); err != nil {
+		return 0, err
+	}
+	return s.repo.TagCustomers(req.CustomerIDs, req.TagIDs)
+}
+
+// UntagCustomers ontkoppelt tags van een selectie klanten
+func (s *tagService) UntagCustomers(req model.BulkTagRequest) (int64, error) {
+	if err := s.validateBulk(req); err != nil {
+		return 0, err
+	}
+	return s.repo.UntagCustomers(req.CustomerIDs, req.TagIDs)
+}
+
+// validate normaliseert en valideert naam en kleur van een tag
+func (s *tagService) validate(tag *model.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		return errors.New("naam is verplicht")
+	}
+	if utf8.RuneCountInString(tag.Name) > 50 {
+		return errors.New("naam mag maximaal 50 tekens bevatten")
+	}
+	if strings.Contains(tag.Name, ",") || strings.Contains(tag.Name, ":") {
+		return errors.New("naam mag geen ',' of ':' bevatten")
+	}
+
+	if tag.Color == "" {
+		tag.Color = model.DefaultColor
+	}
+	if !colorPattern.MatchString(tag.Color) {
+		return errors.New("kleur moet het formaat #RRGGBB hebben")
+	}
+	tag.Color = strings.ToUpper(tag.Color)
+
+	return nil
+}
+
+// validateBulk controleert of een bulk-verzoek geldig is en alle tags bestaan
+func (s *tagService) validateBulk(req model.BulkTagRequest) error {
+	if len(req.CustomerIDs) == 0 {
+		return errors.New("selecteer minstens één klant")
+	}
+	if len(req.TagIDs) == 0 {
+		return errors.New("selecteer minstens één tag")
+	}
+	if len(req.CustomerIDs) > 1000 {
+		return errors.New("maximaal 1000 klanten per bulk-actie")
+	}
+
+	count, err := s.repo.CountExisting(req.TagIDs)
+	if err != nil {
+		return err
+	}
+	if count != int64(len(uniqueIDs(req.TagIDs))) {
+		return errors.New("een of meer tags bestaan niet")
+	}
+
+	return nil
+}
+
+// uniqueIDs verwijdert dubbele IDs
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"errors"
+	"odomosml/internal/tag/model"
+	"odomosml/internal/tag/repository"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type fakeTagRepository struct {
+	repository.TagRepository
+	tags  []model.Tag
+	calls []string
+}
+
+func (r *fakeTagRepository) FindByID(id string) (*model.Tag, error) {
+	for _, tag := range r.tags {
+		if strconv.FormatUint(uint64(tag.ID), 10) == id {
+			return &tag, nil
+		}
+	}
+	return nil, errors.New("tag niet gevonden")
+}
+
+func (r *fakeTagRepository) FindByName(name string) (*model.Tag, error) {
+	for _, tag := range r.tags {
+		if strings.EqualFold(tag.Name, name) {
+			return &tag, nil
+		}
+	}
+	return nil, errors.New("tag niet gevonden")
+}
+
+func (r *fakeTagRepository) CountExisting(ids []uint) (int64, error) {
+	var count int64
+	for _, tag := range r.tags {
+		for _, id := range ids {
+			if tag.ID == id {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeTagRepository) Create(tag *model.Tag) (*model.Tag, error) {
+	r.calls = append(r.calls, "Create")
+	tag.ID = uint(len(r.tags) + 1)
+	r.tags = append(r.tags, *tag)
+	return tag, nil
+}
+
+func (r *fakeTagRepository) Update(tag *model.Tag) (*model.Tag, error) {
+	r.calls = append(r.calls, "Update")
+	return tag, nil
+}
+
+func (r *fakeTagRepository) Merge(sourceID, targetID uint) (int64, error) {
+	r.calls = append(r.calls, "Merge")
+	return 3, nil
+}
+
+func (r *fakeTagRepository) TagCustomers(customerIDs, tagIDs []uint) (int64, error) {
+	r.calls = append(r.calls, "TagCustomers")
+	return int64(len(customerIDs) * len(tagIDs)), nil
+}
+
+func newTestRepository() *fakeTagRepository {
+	return &fakeTagRepository{tags: []model.Tag{{ID: 1, Name: "VIP", Color: "#FF9800"}, {ID: 2, Name: "prospect", Color: model.DefaultColor}}}
+}
+
+func TestCreateTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     model.Tag
+		want    model.Tag
+		wantErr string
+	}{
+		{name: "standaardkleur", tag: model.Tag{Name: " groothandel "}, want: model.Tag{ID: 3, Name: "groothandel", Color: model.DefaultColor}},
+		{name: "kleur in hoofdletters", tag: model.Tag{Name: "horeca", Color: "#ff9800"}, want: model.Tag{ID: 3, Name: "horeca", Color: "#FF9800"}},
+		{name: "naam van 50 tekens met accenten", tag: model.Tag{Name: strings.Repeat("é", 50)}, want: model.Tag{ID: 3, Name: strings.Repeat("é", 50), Color: model.DefaultColor}},
+		{name: "zonder naam", tag: model.Tag{Name: "  "}, wantErr: "naam is verplicht"},
+		{name: "te lange naam", tag: model.Tag{Name: strings.Repeat("a", 51)}, wantErr: "naam mag maximaal 50 tekens bevatten"},
+		{name: "komma in de naam", tag: model.Tag{Name: "vip,prospect"}, wantErr: "naam mag geen ',' of ':' bevatten"},
+		{name: "dubbele punt in de naam", tag: model.Tag{Name: "all:vip"}, wantErr: "naam mag geen ',' of ':' bevatten"},
+		{name: "ongeldige kleur", tag: model.Tag{Name: "horeca", Color: "oranje"}, wantErr: "kleur moet het formaat #RRGGBB hebben"},
+		{name: "korte kleur", tag: model.Tag{Name: "horeca", Color: "#F90"}, wantErr: "kleur moet het formaat #RRGGBB hebben"},
+		{name: "bestaande naam", tag: model.Tag{Name: "vip"}, wantErr: repository.ErrDuplicateName.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository()
+			tag := tt.tag
+			created, err := NewTagService(repo).CreateTag(&tag)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("CreateTag gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				if len(repo.calls) != 0 {
+					t.Errorf("repository aangeroepen: %v", repo.calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateTag gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(*created, tt.want) {
+				t.Errorf("CreateTag = %+v, verwacht %+v", *created, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     model.Tag
+		wantErr string
+	}{
+		{name: "hernoemen", tag: model.Tag{ID: 1, Name: "Topklant"}},
+		{name: "alleen hoofdletters wijzigen", tag: model.Tag{ID: 1, Name: "vip"}},
+		{name: "naam van een andere tag", tag: model.Tag{ID: 1, Name: "Prospect"}, wantErr: repository.ErrDuplicateName.Error()},
+		{name: "onbekende tag", tag: model.Tag{ID: 9, Name: "Topklant"}, wantErr: "tag niet gevonden"},
+		{name: "zonder ID", tag: model.Tag{Name: "Topklant"}, wantErr: "tag ID is verplicht"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository()
+			tag := tt.tag
+			_, err := NewTagService(repo).UpdateTag(&tag)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("UpdateTag gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateTag gaf fout: %v", err)
+			}
+			if !reflect.DeepEqual(repo.calls, []string{"Update"}) {
+				t.Errorf("repository aanroepen %v", repo.calls)
+			}
+		})
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	repo := newTestRepository()
+	service := NewTagService(repo)
+
+	target, moved, err := service.MergeTags("2", 1)
+	if err != nil {
+		t.Fatalf("MergeTags gaf fout: %v", err)
+	}
+	if target.ID != 1 || moved != 3 {
+		t.Errorf("MergeTags = tag %d, %d verplaatst", target.ID, moved)
+	}
+
+	for _, tt := range []struct {
+		source  string
+		target  uint
+		wantErr string
+	}{
+		{"1", 1, "een tag kan niet met zichzelf worden samengevoegd"},
+		{"1", 9, "doel-tag niet gevonden"},
+		{"9", 1, "tag niet gevonden"},
+	} {
+		if _, _, err := service.MergeTags(tt.source, tt.target); err == nil || err.Error() != tt.wantErr {
+			t.Errorf("MergeTags(%s, %d) gaf %v, verwacht %q", tt.source, tt.target, err, tt.wantErr)
+		}
+	}
+	if len(repo.calls) != 1 {
+		t.Errorf("repository aanroepen %v", repo.calls)
+	}
+}
+
+func TestTagCustomers(t *testing.T) {
+	manyCustomers := make([]uint, 1001)
+
+	tests := []struct {
+		name    string
+		req     model.BulkTagRequest
+		want    int64
+		wantErr string
+	}{
+		{name: "geldig", req: model.BulkTagRequest{CustomerIDs: []uint{1, 2, 3}, TagIDs: []uint{1, 2}}, want: 6},
+		{name: "dubbele tags", req: model.BulkTagRequest{CustomerIDs: []uint{1}, TagIDs: []uint{1, 1, 2}}, want: 3},
+		{name: "1000 klanten", req: model.BulkTagRequest{CustomerIDs: manyCustomers[:1000], TagIDs: []uint{1}}, want: 1000},
+		{name: "zonder klanten", req: model.BulkTagRequest{TagIDs: []uint{1}}, wantErr: "selecteer minstens één klant"},
+		{name: "zonder tags", req: model.BulkTagRequest{CustomerIDs: []uint{1}}, wantErr: "selecteer minstens één tag"},
+		{name: "te veel klanten", req: model.BulkTagRequest{CustomerIDs: manyCustomers, TagIDs: []uint{1}}, wantErr: "maximaal 1000 klanten per bulk-actie"},
+		{name: "onbekende tag", req: model.BulkTagRequest{CustomerIDs: []uint{1}, TagIDs: []uint{1, 9}}, wantErr: "een of meer tags bestaan niet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository()
+			got, err := NewTagService(repo).TagCustomers(tt.req)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("TagCustomers gaf %v, verwacht %q", err, tt.wantErr)
+				}
+				if len(repo.calls) != 0 {
+					t.Errorf("repository aangeroepen: %v", repo.calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TagCustomers gaf fout: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TagCustomers = %d, verwacht %d", got, tt.want)
+			}
+		})
+	}
+}
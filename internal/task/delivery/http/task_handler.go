@@ -0,0 +1,383 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"odomosml/internal/task/model"
+	"odomosml/internal/task/repository"
+	"odomosml/internal/task/service"
+	"odomosml/pkg/validation"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaskHandler handles HTTP requests for tasks
+type TaskHandler struct {
+	service service.TaskService
+}
+
+// NewTaskHandler maakt een nieuwe TaskHandler instantie
+func NewTaskHandler(service service.TaskService) *TaskHandler {
+	return &TaskHandler{
+		service: service,
+	}
+}
+
+// respondError stuurt een fout terug: 409 als de taak intussen is afgesloten, 400 met validatiefouten
+// per veld in fields, en anders status
+func respondError(c *gin.Context, status int, err error) {
+	response := gin.H{
+		"success": false,
+		"error":   err.Error(),
+	}
+	if errors.Is(err, repository.ErrAlreadyClosed) {
+		status = http.StatusConflict
+	} else if fields, ok := validation.Fields(err); ok {
+		status = http.StatusBadRequest
+		response["fields"] = fields
+	}
+	c.JSON(status, response)
+}
+
+// respondSaved stuurt een bijgewerkte taak terug, met in next de volgende taak van de reeks als die is aangemaakt
+func respondSaved(c *gin.Context, task, next *model.Task) {
+	response := gin.H{
+		"success": true,
+		"data":    task,
+	}
+	if next != nil {
+		response["next"] = next
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// parseFilter leest de filter- en pagineringsparameters van de takenlijst
+func parseFilter(c *gin.Context) (model.TaskFilter, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	filter := model.TaskFilter{
+		SearchTerm: c.Query("zoekterm"),
+		Page:       page,
+		PageSize:   pageSize,
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *uint
+	}{{"assignee_id", &filter.AssigneeID}, {"customer_id", &filter.CustomerID}} {
+		if value := c.Query(param.name); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || id == 0 {
+				return filter, fmt.Errorf("%s moet een ID zijn", param.name)
+			}
+			*param.value = uint(id)
+		}
+	}
+
+	for _, status := range strings.Split(c.Query("status"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter.Statuses = append(filter.Statuses, model.Status(status))
+		}
+	}
+	for _, priority := range strings.Split(c.Query("priority"), ",") {
+		if priority = strings.TrimSpace(priority); priority != "" {
+			filter.Priorities = append(filter.Priorities, model.Priority(priority))
+		}
+	}
+
+	for _, param := range []struct {
+		name  string
+		value **time.Time
+	}{{"due_before", &filter.DueBefore}, {"due_after", &filter.DueAfter}} {
+		if value := c.Query(param.name); value != "" {
+			moment, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("%s moet een datum en tijd zijn (RFC 3339), bijv. 2025-03-14T00:00:00Z", param.name)
+			}
+			*param.value = &moment
+		}
+	}
+
+	if value := c.Query("overdue"); value != "" {
+		overdue, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("overdue moet true of false zijn")
+		}
+		filter.Overdue = overdue
+	}
+
+	return filter, nil
+}
+
+// list haalt taken op met een filter en stuurt ze met de paginering terug
+func (h *TaskHandler) list(c *gin.Context, filter model.TaskFilter) {
+	tasks, total, err := h.service.GetTasks(filter)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tasks,
+		"pagination": gin.H{
+			"current_page": filter.Page,
+			"page_size":    filter.PageSize,
+			"total_items":  total,
+			"total_pages":  (total + int64(filter.PageSize) - 1) / int64(filter.PageSize),
+		},
+	})
+}
+
+// @Summary      Lijst van taken ophalen
+// @Description  Haalt taken op met optionele filters, gesorteerd op deadline
+// @Tags         taken
+// @Accept       json
+// @Produce      json
+// @Param        page query int false "Paginanummer (default: 1)"
+// @Param        page_size query int false "Aantal items per pagina (default: 10, max: 100)"
+// @Param        zoekterm query string false "Zoekterm voor de titel"
+// @Param        assignee_id query int false "Alleen taken van deze gebruiker"
+// @Param        customer_id query int false "Alleen taken bij deze klant"
+// @Param        status query string false "Komma-gescheiden statussen: open, in_progress, done, cancelled"
+// @Param        priority query string false "Komma-gescheiden prioriteiten: low, normal, high, urgent"
+// @Param        due_before query string false "Alleen taken met een deadline voor dit moment (RFC 3339)"
+// @Param        due_after query string false "Alleen taken met een deadline vanaf dit moment (RFC 3339)"
+// @Param        overdue query bool false "Alleen open taken waarvan de deadline verstreken is"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /tasks [get]
+func (h *TaskHandler) GetAll(c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	h.list(c, filter)
+}
+
+// @Summary      Mijn taken ophalen
+// @Description  Haalt de taken van de ingelogde gebruiker op, gesorteerd op deadline. Zonder status filter alleen de open taken (open en in_progress).
+// @Tags         taken
+// @Accept       json
+// @Produce      json
+// @Param        page query int false "Paginanummer (default: 1)"
+// @Param        page_size query int false "Aantal items per pagina (default: 10, max: 100)"
+// @Param        zoekterm query string false "Zoekterm voor de titel"
+// @Param        customer_id query int false "Alleen taken bij deze klant"
+// @Param        status query string false "Komma-gescheiden statussen (default: open,in_progress)"
+// @Param        priority query string false "Komma-gescheiden prioriteiten"
+// @Param        due_before query string false "Alleen taken met een deadline voor dit moment (RFC 3339)"
+// @Param        due_after query string false "Alleen taken met een deadline vanaf dit moment (RFC 3339)"
+// @Param        overdue query bool false "Alleen taken waarvan de deadline verstreken is"
+// @Success      200  {object}  map[string]interface{} "Succesvol opgehaald"
+// @Failure      400  {object}  map[string]string "Ongeldige parameters"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /tasks/mine [get]
+func (h *TaskHandler) Mine(c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	filter.AssigneeID, _ = userID.(uint)
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = model.OpenStatuses
+	}
+
+	h.list(c, filter)
+}
+
+// @Summary      Taak ophalen op ID
+// @Description  Haalt een specifieke taak op basis van ID
+// @Tags         taken
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Taak ID"
+// @Success      200  {object}  model.Task "Succesvol opgehaald"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Taak niet gevonden"
+// @Security     Bearer
+// @Router       /tasks/{id} [get]
+func (h *TaskHandler) GetByID(c *gin.Context) {
+	task, err := h.service.GetTask(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    task,
+	})
+}
+
+// @Summary      Taak aanmaken
+// @Description  Maakt een taak aan. Zonder assignee_id is de taak voor de ingelogde gebruiker. Met rrule (RFC 5545, bijv. FREQ=WEEKLY;BYDAY=MO) herhaalt de taak vanaf de deadline; met remind_minutes krijgt de gebruiker zoveel minuten voor de deadline een herinnering.
+// @Tags         taken
+// @Accept       json
+// @Produce      json
+// @Param        task body model.Task true "Taak gegevens"
+// @Success      201  {object}  model.Task "Succesvol aangemaakt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Security     Bearer
+// @Router       /tasks [post]
+func (h *TaskHandler) Create(c *gin.Context) {
+	var task model.Task
+	if err := c.ShouldBindJSON(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+	task.CreatedByID, _ = userID.(uint)
+	task.CreatedByName, _ = username.(string)
+	if task.AssigneeID == 0 {
+		task.AssigneeID = task.CreatedByID
+	}
+
+	created, err := h.service.CreateTask(&task)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// @Summary      Taak bijwerken
+// @Description  Werkt een taak bij. Wordt een terugkerende taak afgerond of geannuleerd, dan wordt de volgende taak van de reeks aangemaakt en in next teruggegeven; stuur een lege rrule mee om de reeks te beëindigen.
+// @Tags         taken
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Taak ID"
+// @Param        task body model.Task true "Taak gegevens"
+// @Success      200  {object}  map[string]interface{} "Succesvol bijgewerkt"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige invoer"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Taak niet gevonden"
+// @Failure      409  {object}  map[string]string "Taak is intussen afgesloten"
+// @Security     Bearer
+// @Router       /tasks/{id} [put]
+func (h *TaskHandler) Update(c *gin.Context) {
+	existing, err := h.service.GetTask(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	var task model.Task
+	if err := c.ShouldBindJSON(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	// Bewaar de huidige gegevens voor de audit diff
+	c.Set("taskOldData", existing.ToAuditMap())
+
+	task.ID = existing.ID
+	updated, next, err := h.service.UpdateTask(&task)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondSaved(c, updated, next)
+}
+
+// @Summary      Status wijzigen
+// @Description  Zet een taak op open, in_progress, done of cancelled. Bij het afsluiten van een terugkerende taak wordt de volgende taak van de reeks aangemaakt en in next teruggegeven.
+// @Tags         taken
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Taak ID"
+// @Param        status body model.StatusRequest true "Nieuwe status"
+// @Success      200  {object}  map[string]interface{} "Status gewijzigd"
+// @Failure      400  {object}  map[string]interface{} "Ongeldige status"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Taak niet gevonden"
+// @Failure      409  {object}  map[string]string "Taak is intussen afgesloten"
+// @Security     Bearer
+// @Router       /tasks/{id}/status [put]
+func (h *TaskHandler) ChangeStatus(c *gin.Context) {
+	var request model.StatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Ongeldige request: " + err.Error(),
+		})
+		return
+	}
+
+	existing, err := h.service.GetTask(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// Bewaar de huidige gegevens voor de audit diff
+	c.Set("taskOldData", existing.ToAuditMap())
+
+	updated, next, err := h.service.ChangeStatus(c.Param("id"), request.Status)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondSaved(c, updated, next)
+}
+
+// @Summary      Taak verwijderen
+// @Description  Verwijdert een taak. Bij een terugkerende taak wordt geen volgende taak aangemaakt.
+// @Tags         taken
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Taak ID"
+// @Success      200  {object}  map[string]interface{} "Succesvol verwijderd"
+// @Failure      401  {object}  map[string]string "Niet geautoriseerd"
+// @Failure      404  {object}  map[string]string "Taak niet gevonden"
+// @Security     Bearer
+// @Router       /tasks/{id} [delete]
+func (h *TaskHandler) Delete(c *gin.Context) {
+	taskData, err := h.service.DeleteTask(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// Sla taskData op in context voor audit logging
+	c.Set("taskData", taskData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Taak succesvol verwijderd",
+	})
+}
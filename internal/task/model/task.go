@@ -0,0 +1,126 @@
+package model
+
+import (
+	"time"
+)
+
+// Priority is de prioriteit van een taak
+type Priority string
+
+// Prioriteiten van laag naar hoog
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Priorities zijn alle prioriteiten van laag naar hoog
+var Priorities = []Priority{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+// IsValid controleert of de prioriteit bekend is
+func (p Priority) IsValid() bool {
+	for _, priority := range Priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// Status is de status van een taak
+type Status string
+
+// Statussen van een taak; done en cancelled zijn afgesloten taken
+const (
+	StatusOpen       Status = "open"
+	StatusInProgress Status = "in_progress"
+	StatusDone       Status = "done"
+	StatusCancelled  Status = "cancelled"
+)
+
+// Statuses zijn alle statussen van een taak
+var Statuses = []Status{StatusOpen, StatusInProgress, StatusDone, StatusCancelled}
+
+// IsValid controleert of de status bekend is
+func (s Status) IsValid() bool {
+	for _, status := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClosed geeft aan of een taak met deze status is afgesloten (afgerond of geannuleerd)
+func (s Status) IsClosed() bool {
+	return s == StatusDone || s == StatusCancelled
+}
+
+// OpenStatuses zijn de statussen van taken die nog gedaan moeten worden
+var OpenStatuses = []Status{StatusOpen, StatusInProgress}
+
+// Task is een taak voor een gebruiker, eventueel bij een klant. Een taak met een RRule is een
+// terugkerende taak: bij het afsluiten wordt de volgende taak uit de reeks aangemaakt.
+// @Description Een taak voor een gebruiker
+type Task struct {
+	ID             uint       `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	Title          string     `json:"title" gorm:"size:200;not null" binding:"required" example:"Klant terugbellen over offerte" swaggertype:"string"`
+	Description    string     `json:"description" gorm:"type:text" example:"Vragen of de offerte duidelijk is" swaggertype:"string"`
+	DueAt          time.Time  `json:"due_at" gorm:"not null;index" binding:"required" example:"2025-03-14T10:00:00Z" swaggertype:"string" format:"date-time"`
+	AssigneeID     uint       `json:"assignee_id" gorm:"not null;index" example:"1" swaggertype:"integer"`
+	AssigneeName   string     `json:"assignee_name" gorm:"size:100" example:"johndoe" swaggertype:"string"`
+	CustomerID     *uint      `json:"customer_id" gorm:"index" example:"1" swaggertype:"integer"`
+	Priority       Priority   `json:"priority" gorm:"size:10;not null;default:'normal'" example:"normal" swaggertype:"string"`
+	Status         Status     `json:"status" gorm:"size:20;not null;index" example:"open" swaggertype:"string"`
+	RRule          string     `json:"rrule" gorm:"column:rrule;size:255" example:"FREQ=WEEKLY;BYDAY=MO" swaggertype:"string"` // Herhaling volgens RFC 5545, leeg voor een eenmalige taak
+	SeriesID       *uint      `json:"series_id" gorm:"index" example:"1" swaggertype:"integer"`                               // Eerste taak van de reeks bij een terugkerende taak
+	SeriesStart    *time.Time `json:"series_start" example:"2025-03-03T10:00:00Z" swaggertype:"string" format:"date-time"`    // Begin van de herhaling (DTSTART)
+	RemindMinutes  *int       `json:"remind_minutes" example:"30" swaggertype:"integer"`                                      // Herinnering zoveel minuten voor de deadline; leeg voor geen herinnering
+	RemindAt       *time.Time `json:"remind_at" example:"2025-03-14T09:30:00Z" swaggertype:"string" format:"date-time"`
+	ReminderSentAt *time.Time `json:"reminder_sent_at" example:"2025-03-14T09:30:05Z" swaggertype:"string" format:"date-time"`
+	CompletedAt    *time.Time `json:"completed_at" example:"2025-03-14T11:00:00Z" swaggertype:"string" format:"date-time"` // Moment waarop de taak is afgerond of geannuleerd
+	CreatedByID    uint       `json:"created_by_id" example:"1" swaggertype:"integer"`
+	CreatedByName  string     `json:"created_by_name" gorm:"size:100" example:"johndoe" swaggertype:"string"`
+	CreatedAt      time.Time  `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt      time.Time  `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Task) TableName() string {
+	return "tasks"
+}
+
+// ToAuditMap converteert een taak naar een map voor audit logging
+func (t *Task) ToAuditMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":             t.ID,
+		"title":          t.Title,
+		"due_at":         t.DueAt,
+		"assignee_id":    t.AssigneeID,
+		"customer_id":    t.CustomerID,
+		"priority":       t.Priority,
+		"status":         t.Status,
+		"rrule":          t.RRule,
+		"remind_minutes": t.RemindMinutes,
+	}
+}
+
+// TaskFilter definieert filters voor het ophalen van taken
+type TaskFilter struct {
+	SearchTerm string     // Deel van de titel
+	AssigneeID uint       // Alleen taken van deze gebruiker
+	CustomerID uint       // Alleen taken bij deze klant
+	Statuses   []Status   // Alleen taken met een van deze statussen
+	Priorities []Priority // Alleen taken met een van deze prioriteiten
+	DueBefore  *time.Time // Alleen taken met een deadline voor dit moment
+	DueAfter   *time.Time // Alleen taken met een deadline vanaf dit moment
+	Overdue    bool       // Alleen open taken waarvan de deadline verstreken is
+	Page       int
+	PageSize   int
+}
+
+// StatusRequest is de body voor het wijzigen van de status van een taak
+type StatusRequest struct {
+	Status Status `json:"status" binding:"required" example:"done" swaggertype:"string"`
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"errors"
+	"odomosml/internal/task/model"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyClosed betekent dat de taak al is afgesloten, bijvoorbeeld door een gelijktijdig verzoek
+var ErrAlreadyClosed = errors.New("taak is al afgerond of geannuleerd")
+
+// TaskRepository definieert de interface voor taak repository
+type TaskRepository interface {
+	FindAll(filter model.TaskFilter) ([]model.Task, int64, error)
+	FindByID(id string) (*model.Task, error)
+	Create(task *model.Task) (*model.Task, error)
+	Update(task *model.Task, next *model.Task) (*model.Task, error)
+	Delete(id uint) error
+	ClaimDueReminders(now time.Time, limit int) ([]model.Task, error)
+	ReleaseReminder(id uint) error
+}
+
+// taskRepository implementeert de TaskRepository interface
+type taskRepository struct {
+	db *gorm.DB
+}
+
+// NewTaskRepository maakt een nieuwe TaskRepository instantie
+func NewTaskRepository(db *gorm.DB) TaskRepository {
+	return &taskRepository{
+		db: db,
+	}
+}
+
+// FindAll haalt taken op met filters, gesorteerd op deadline
+func (r *taskRepository) FindAll(filter model.TaskFilter) ([]model.Task, int64, error) {
+	var tasks []model.Task
+	var total int64
+
+	query := r.db.Model(&model.Task{})
+
+	if filter.SearchTerm != "" {
+		query = query.Where("title ILIKE ?", "%"+filter.SearchTerm+"%")
+	}
+
+	if filter.AssigneeID != 0 {
+		query = query.Where("assignee_id = ?", filter.AssigneeID)
+	}
+
+	if filter.CustomerID != 0 {
+		query = query.Where("customer_id = ?", filter.CustomerID)
+	}
+
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+
+	if len(filter.Priorities) > 0 {
+		query = query.Where("priority IN ?", filter.Priorities)
+	}
+
+	if filter.DueBefore != nil {
+		query = query.Where("due_at < ?", *filter.DueBefore)
+	}
+
+	if filter.DueAfter != nil {
+		query = query.Where("due_at >= ?", *filter.DueAfter)
+	}
+
+	if filter.Overdue {
+		query = query.Where("due_at < ? AND status IN ?", time.Now(), model.OpenStatuses)
+	}
+
+	// Tel totaal aantal records (voor paginering)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Paginering toepassen
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query = query.Offset(offset).Limit(filter.PageSize)
+	}
+
+	if err := query.Order("due_at ASC, id ASC").Find(&tasks).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return tasks, total, nil
+}
+
+// FindByID haalt een taak op op basis van ID
+func (r *taskRepository) FindByID(id string) (*model.Task, error) {
+	var task model.Task
+
+	// Converteer string ID naar uint
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("ongeldig ID formaat")
+	}
+
+	if err := r.db.First(&task, idInt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("taak niet gevonden")
+		}
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// Create maakt een nieuwe taak aan. De eerste taak van een terugkerende reeks is zelf de reeks.
+func (r *taskRepository) Create(task *model.Task) (*model.Task, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		if task.RRule != "" && task.SeriesID == nil {
+			task.SeriesID = &task.ID
+			return tx.Model(task).Update("series_id", task.ID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// Update werkt alle wijzigbare velden van een taak bij. Met next wordt een open taak afgesloten en in
+// dezelfde transactie de volgende taak van de reeks aangemaakt; sluiten twee verzoeken tegelijk dezelfde
+// taak af, dan krijgt het tweede ErrAlreadyClosed en ontstaat er geen dubbele vervolgtaak.
+func (r *taskRepository) Update(task *model.Task, next *model.Task) (*model.Task, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(task)
+		if next != nil {
+			query = query.Where("status IN ?", model.OpenStatuses)
+		}
+
+		result := query.
+			Select("title", "description", "due_at", "assignee_id", "assignee_name", "customer_id", "priority",
+				"status", "rrule", "series_start", "remind_minutes", "remind_at", "reminder_sent_at", "completed_at", "updated_at").
+			Updates(task)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if next != nil {
+				return ErrAlreadyClosed
+			}
+			return errors.New("taak niet gevonden")
+		}
+
+		if next != nil {
+			return tx.Create(next).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(strconv.FormatUint(uint64(task.ID), 10))
+}
+
+// Delete verwijdert een taak
+func (r *taskRepository) Delete(id uint) error {
+	return r.db.Delete(&model.Task{}, id).Error
+}
+
+// ClaimDueReminders markeert maximaal limit open taken waarvan de herinnering verschuldigd is als
+// verstuurd en geeft ze terug. Rijen die een andere instantie op dat moment claimt worden overgeslagen,
+// zodat een herinnering nooit twee keer wordt geclaimd.
+func (r *taskRepository) ClaimDueReminders(now time.Time, limit int) ([]model.Task, error) {
+	var tasks []model.Task
+
+	err := r.db.Raw(`UPDATE tasks SET reminder_sent_at = ?
+		WHERE id IN (
+			SELECT id FROM tasks
+			WHERE remind_at <= ? AND reminder_sent_at IS NULL AND status IN ?
+			ORDER BY remind_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now, now, model.OpenStatuses, limit).Scan(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// ReleaseReminder zet een geclaimde herinnering terug, zodat die bij de volgende run opnieuw wordt verstuurd
+func (r *taskRepository) ReleaseReminder(id uint) error {
+	return r.db.Model(&model.Task{}).Where("id = ?", id).Update("reminder_sent_at", nil).Error
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"odomosml/internal/task/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// at geeft een moment in de lokale tijdzone, net als de deadlines van taken
+func at(year int, month time.Month, day, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.Local)
+}
+
+func TestNextTask(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        string
+		seriesStart time.Time
+		dueAt       time.Time
+		want        time.Time // Nul als de reeks is afgelopen
+	}{
+		{"dagelijks", "FREQ=DAILY", at(2099, 3, 2, 9), at(2099, 3, 2, 9), at(2099, 3, 3, 9)},
+		{"wekelijks op maandag en donderdag", "FREQ=WEEKLY;BYDAY=MO,TH", at(2099, 3, 2, 9), at(2099, 3, 2, 9), at(2099, 3, 5, 9)},
+		{"van donderdag naar maandag", "FREQ=WEEKLY;BYDAY=MO,TH", at(2099, 3, 2, 9), at(2099, 3, 5, 9), at(2099, 3, 9, 9)},
+		{"om de twee weken", "FREQ=WEEKLY;INTERVAL=2", at(2099, 3, 2, 9), at(2099, 3, 16, 9), at(2099, 3, 30, 9)},
+		{"maandelijks op de 31e slaat korte maanden over", "FREQ=MONTHLY;BYMONTHDAY=31", at(2099, 1, 31, 9), at(2099, 1, 31, 9), at(2099, 3, 31, 9)},
+		{"laatste werkdag van de maand", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", at(2099, 1, 30, 9), at(2099, 1, 30, 9), at(2099, 2, 27, 9)},
+		{"jaarlijks", "FREQ=YEARLY", at(2099, 3, 2, 9), at(2099, 3, 2, 9), at(2100, 3, 2, 9)},
+		{"verschoven deadline telt vanaf de reeks", "FREQ=DAILY", at(2099, 3, 2, 9), at(2099, 3, 4, 15), at(2099, 3, 5, 9)},
+		{"COUNT bereikt", "FREQ=DAILY;COUNT=3", at(2099, 3, 2, 9), at(2099, 3, 4, 9), time.Time{}},
+		{"COUNT nog niet bereikt", "FREQ=DAILY;COUNT=3", at(2099, 3, 2, 9), at(2099, 3, 3, 9), at(2099, 3, 4, 9)},
+		{"UNTIL bereikt", "FREQ=WEEKLY;UNTIL=20990310T000000Z", at(2099, 3, 2, 9), at(2099, 3, 9, 9), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seriesStart := tt.seriesStart
+			task := &model.Task{ID: 7, Title: "Voorraad tellen", RRule: tt.rule, SeriesStart: &seriesStart, DueAt: tt.dueAt, Status: model.StatusDone}
+
+			next, err := nextTask(task)
+			if err != nil {
+				t.Fatalf("nextTask gaf fout: %v", err)
+			}
+			if tt.want.IsZero() {
+				if next != nil {
+					t.Errorf("volgende taak op %v, verwacht einde van de reeks", next.DueAt)
+				}
+				return
+			}
+			if next == nil {
+				t.Fatalf("geen volgende taak, verwacht %v", tt.want)
+			}
+			if !next.DueAt.Equal(tt.want) {
+				t.Errorf("volgende deadline %v, verwacht %v", next.DueAt, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextTaskClosedLate(t *testing.T) {
+	// Een taak die pas na een paar jaar is afgesloten gaat door met de eerste deadline na nu
+	seriesStart := at(2020, 1, 6, 9)
+	task := &model.Task{ID: 7, RRule: "FREQ=DAILY", SeriesStart: &seriesStart, DueAt: seriesStart}
+
+	next, err := nextTask(task)
+	if err != nil || next == nil {
+		t.Fatalf("nextTask = %v, %v", next, err)
+	}
+	now := time.Now()
+	if !next.DueAt.After(now) || next.DueAt.After(now.Add(24*time.Hour)) {
+		t.Errorf("volgende deadline %v, verwacht binnen een dag na %v", next.DueAt, now)
+	}
+	if next.DueAt.Hour() != 9 || next.DueAt.Minute() != 0 {
+		t.Errorf("volgende deadline %v, verwacht om 9:00", next.DueAt)
+	}
+}
+
+func TestNextTaskCopiesSeries(t *testing.T) {
+	seriesStart := at(2099, 3, 2, 9)
+	remind := 30
+	first := &model.Task{
+		ID: 7, Title: "Voorraad tellen", Description: "Magazijn", Priority: model.PriorityHigh, Status: model.StatusDone,
+		RRule: "FREQ=DAILY", SeriesStart: &seriesStart, DueAt: seriesStart, AssigneeID: 3, RemindMinutes: &remind,
+	}
+
+	next, err := nextTask(first)
+	if err != nil || next == nil {
+		t.Fatalf("nextTask = %v, %v", next, err)
+	}
+	if next.ID != 0 || next.Status != model.StatusOpen || next.Title != first.Title || next.Priority != first.Priority || next.AssigneeID != first.AssigneeID {
+		t.Errorf("volgende taak = %+v", next)
+	}
+	if next.SeriesID == nil || *next.SeriesID != 7 {
+		t.Errorf("reeks %v, verwacht de eerste taak 7", next.SeriesID)
+	}
+	if want := at(2099, 3, 3, 9).Add(-30 * time.Minute); next.RemindAt == nil || !next.RemindAt.Equal(want) {
+		t.Errorf("herinnering %v, verwacht %v", next.RemindAt, want)
+	}
+
+	// Een latere taak van de reeks houdt de eerste taak als reeks
+	second, err := nextTask(next)
+	if err != nil || second == nil {
+		t.Fatalf("nextTask = %v, %v", second, err)
+	}
+	if second.SeriesID == nil || *second.SeriesID != 7 || !second.DueAt.Equal(at(2099, 3, 4, 9)) {
+		t.Errorf("tweede taak in reeks %v op %v", second.SeriesID, second.DueAt)
+	}
+}
+
+func TestValidateRRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		wantErr string
+	}{
+		{"FREQ=DAILY", ""},
+		{"FREQ=WEEKLY;BYDAY=MO", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=1;COUNT=12", ""},
+		{"FREQ=HOURLY", "hoogstens dagelijks"},
+		{"FREQ=MINUTELY;INTERVAL=30", "hoogstens dagelijks"},
+		{"DTSTART:20250303T100000Z\nRRULE:FREQ=DAILY", "DTSTART"},
+		{"FREQ=DAILY;DTSTART=20250303T100000Z", "DTSTART"},
+		{"FREQ=ELKE_DAG", "ongeldige herhaling"},
+		{"FREQ=DAILY;" + strings.Repeat("BYDAY=MO;", 30), "maximaal 255 tekens"},
+	}
+
+	for _, tt := range tests {
+		err := validateRRule(tt.rule)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("validateRRule(%q) gaf fout: %v", tt.rule, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("validateRRule(%q) gaf %v, verwacht een fout met %q", tt.rule, err, tt.wantErr)
+		}
+	}
+}
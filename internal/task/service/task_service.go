@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	customerRepo "odomosml/internal/customer/repository"
+	"odomosml/internal/task/model"
+	"odomosml/internal/task/repository"
+	userRepo "odomosml/internal/user/repository"
+	"odomosml/pkg/notify"
+	"odomosml/pkg/validation"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/teambition/rrule-go"
+)
+
+// Grenzen aan de velden van een taak
+const (
+	maxTitleLength   = 200
+	maxRRuleLength   = 255
+	maxRemindMinutes = 60 * 24 * 30 // Een herinnering kan maximaal 30 dagen voor de deadline
+)
+
+// reminderBatchSize is het aantal herinneringen dat per keer wordt geclaimd en verstuurd
+const reminderBatchSize = 100
+
+// TaskService definieert de interface voor taak service
+type TaskService interface {
+	GetTasks(filter model.TaskFilter) ([]model.Task, int64, error)
+	GetTask(id string) (*model.Task, error)
+	CreateTask(task *model.Task) (*model.Task, error)
+	UpdateTask(task *model.Task) (*model.Task, *model.Task, error)
+	ChangeStatus(id string, status model.Status) (*model.Task, *model.Task, error)
+	DeleteTask(id string) (map[string]interface{}, error)
+	SendDueReminders(ctx context.Context) error
+}
+
+// taskService implementeert de TaskService interface
+type taskService struct {
+	repo         repository.TaskRepository
+	customerRepo customerRepo.CustomerRepository
+	userRepo     userRepo.UserRepository
+	notifier     notify.Notifier
+}
+
+// NewTaskService maakt een nieuwe TaskService instantie
+func NewTaskService(repo repository.TaskRepository, customerRepo customerRepo.CustomerRepository, userRepo userRepo.UserRepository, notifier notify.Notifier) TaskService {
+	return &taskService{
+		repo:         repo,
+		customerRepo: customerRepo,
+		userRepo:     userRepo,
+		notifier:     notifier,
+	}
+}
+
+// GetTasks haalt taken op met filters
+func (s *taskService) GetTasks(filter model.TaskFilter) ([]model.Task, int64, error) {
+	for _, status := range filter.Statuses {
+		if !status.IsValid() {
+			return nil, 0, fmt.Errorf("onbekende status '%s'", status)
+		}
+	}
+	for _, priority := range filter.Priorities {
+		if !priority.IsValid() {
+			return nil, 0, fmt.Errorf("onbekende prioriteit '%s'", priority)
+		}
+	}
+
+	// Valideer paginering
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 10 // Default page size
+	}
+
+	return s.repo.FindAll(filter)
+}
+
+// GetTask haalt een taak op op basis van ID
+func (s *taskService) GetTask(id string) (*model.Task, error) {
+	return s.repo.FindByID(id)
+}
+
+// CreateTask maakt een nieuwe taak aan; zonder status is de taak open en zonder prioriteit normaal
+func (s *taskService) CreateTask(task *model.Task) (*model.Task, error) {
+	task.ID = 0
+	task.SeriesID = nil
+	task.SeriesStart = nil
+	if task.Status == "" {
+		task.Status = model.StatusOpen
+	}
+
+	if err := s.validate(task, nil); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(task)
+}
+
+// UpdateTask werkt een bestaande taak bij. Wordt een terugkerende taak daarbij afgesloten, dan wordt
+// de volgende taak van de reeks aangemaakt en als tweede waarde teruggegeven.
+func (s *taskService) UpdateTask(task *model.Task) (*model.Task, *model.Task, error) {
+	existing, err := s.repo.FindByID(strconv.FormatUint(uint64(task.ID), 10))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if task.Status == "" {
+		task.Status = existing.Status
+	}
+	if task.AssigneeID == 0 {
+		task.AssigneeID = existing.AssigneeID
+	}
+	task.SeriesID = existing.SeriesID
+	task.SeriesStart = existing.SeriesStart
+	task.CreatedByID = existing.CreatedByID
+	task.CreatedByName = existing.CreatedByName
+
+	if err := s.validate(task, existing); err != nil {
+		return nil, nil, err
+	}
+
+	return s.save(task, existing)
+}
+
+// ChangeStatus wijzigt alleen de status van een taak
+func (s *taskService) ChangeStatus(id string, status model.Status) (*model.Task, *model.Task, error) {
+	existing, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	task := *existing
+	task.Status = status
+	if err := s.validate(&task, existing); err != nil {
+		return nil, nil, err
+	}
+
+	return s.save(&task, existing)
+}
+
+// DeleteTask verwijdert een taak en retourneert de data voor audit logging. Bij een terugkerende taak
+// verdwijnt alleen deze taak; er wordt geen volgende taak aangemaakt.
+func (s *taskService) DeleteTask(id string) (map[string]interface{}, error) {
+	task, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	taskData := task.ToAuditMap()
+
+	if err := s.repo.Delete(task.ID); err != nil {
+		return nil, err
+	}
+
+	return taskData, nil
+}
+
+// SendDueReminders verstuurt de herinneringen die verschuldigd zijn. Een herinnering wordt eerst
+// geclaimd en daarna verstuurd; mislukt het versturen, dan wordt de claim teruggezet en volgt een
+// nieuwe poging bij de volgende run.
+func (s *taskService) SendDueReminders(ctx context.Context) error {
+	for ctx.Err() == nil {
+		tasks, err := s.repo.ClaimDueReminders(time.Now(), reminderBatchSize)
+		if err != nil {
+			return err
+		}
+
+		var failed error
+		for _, task := range tasks {
+			if err := s.sendReminder(ctx, &task); err != nil {
+				failed = fmt.Errorf("herinnering voor taak %d: %w", task.ID, err)
+				if err := s.repo.ReleaseReminder(task.ID); err != nil {
+					log.Printf("Waarschuwing: kon herinnering voor taak %d niet terugzetten: %v", task.ID, err)
+				}
+			}
+		}
+
+		// Stop na een fout, anders worden de teruggezette herinneringen direct opnieuw geclaimd
+		if failed != nil {
+			return failed
+		}
+		if len(tasks) < reminderBatchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// sendReminder stuurt de herinnering van een taak naar de toegewezen gebruiker. Een gebruiker die niet
+// meer bestaat of inactief is krijgt geen herinnering.
+func (s *taskService) sendReminder(ctx context.Context, task *model.Task) error {
+	user, err := s.userRepo.FindByID(strconv.FormatUint(uint64(task.AssigneeID), 10))
+	if err != nil || user == nil || !user.Active {
+		log.Printf("Herinnering voor taak %d overgeslagen: gebruiker %d is niet actief", task.ID, task.AssigneeID)
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"task_id":  task.ID,
+		"due_at":   task.DueAt,
+		"priority": task.Priority,
+	}
+	if task.CustomerID != nil {
+		data["customer_id"] = *task.CustomerID
+	}
+
+	return s.notifier.Notify(ctx, notify.Notification{
+		Type:      "task.reminder",
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Subject:   "Herinnering: " + task.Title,
+		Message:   fmt.Sprintf("De taak '%s' moet af zijn op %s.", task.Title, task.DueAt.In(time.Local).Format("02-01-2006 15:04")),
+		Data:      data,
+		CreatedAt: time.Now(),
+	})
+}
+
+// save slaat een gevalideerde taak op. Gaat een terugkerende taak van open naar afgesloten, dan
+// wordt in dezelfde transactie de volgende taak van de reeks aangemaakt.
+func (s *taskService) save(task, existing *model.Task) (*model.Task, *model.Task, error) {
+	var next *model.Task
+	if task.Status.IsClosed() && !existing.Status.IsClosed() && task.RRule != "" {
+		var err error
+		if next, err = nextTask(task); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	task.CreatedAt = existing.CreatedAt
+	if next == nil {
+		updated, err := s.repo.Update(task, nil)
+		return updated, nil, err
+	}
+
+	updated, err := s.repo.Update(task, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, next, nil
+}
+
+// validate controleert en normaliseert de velden van een taak. existing is de huidige taak bij
+// bijwerken, of nil bij aanmaken.
+func (s *taskService) validate(task *model.Task, existing *model.Task) error {
+	var problems validation.Errors
+
+	task.Title = strings.TrimSpace(task.Title)
+	if task.Title == "" {
+		problems = append(problems, validation.FieldError{Field: "title", Message: "titel is verplicht"})
+	} else if utf8.RuneCountInString(task.Title) > maxTitleLength {
+		problems = append(problems, validation.FieldError{Field: "title", Message: fmt.Sprintf("titel mag maximaal %d tekens bevatten", maxTitleLength)})
+	}
+	task.Description = strings.TrimSpace(task.Description)
+
+	if task.DueAt.IsZero() {
+		problems = append(problems, validation.FieldError{Field: "due_at", Message: "deadline is verplicht"})
+	}
+
+	if task.Priority == "" {
+		task.Priority = model.PriorityNormal
+	}
+	if !task.Priority.IsValid() {
+		problems = append(problems, validation.FieldError{Field: "priority", Message: "prioriteit moet low, normal, high of urgent zijn"})
+	}
+
+	if !task.Status.IsValid() {
+		problems = append(problems, validation.FieldError{Field: "status", Message: "status moet open, in_progress, done of cancelled zijn"})
+	}
+
+	if task.CustomerID != nil {
+		if customer, err := s.customerRepo.FindByID(strconv.FormatUint(uint64(*task.CustomerID), 10)); err != nil || customer == nil {
+			problems = append(problems, validation.FieldError{Field: "customer_id", Message: fmt.Sprintf("klant %d bestaat niet", *task.CustomerID)})
+		}
+	}
+
+	if existing == nil || task.AssigneeID != existing.AssigneeID {
+		assignee, err := s.userRepo.FindByID(strconv.FormatUint(uint64(task.AssigneeID), 10))
+		if err != nil || assignee == nil || !assignee.Active {
+			problems = append(problems, validation.FieldError{Field: "assignee_id", Message: fmt.Sprintf("gebruiker %d is geen actieve gebruiker", task.AssigneeID)})
+		} else {
+			task.AssigneeName = assignee.Username
+		}
+	} else {
+		task.AssigneeName = existing.AssigneeName
+	}
+
+	if task.RemindMinutes != nil && (*task.RemindMinutes < 0 || *task.RemindMinutes > maxRemindMinutes) {
+		problems = append(problems, validation.FieldError{Field: "remind_minutes", Message: fmt.Sprintf("herinnering moet tussen 0 en %d minuten voor de deadline liggen", maxRemindMinutes)})
+	}
+
+	task.RRule = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(task.RRule), "RRULE:"))
+	if task.RRule != "" {
+		if err := validateRRule(task.RRule); err != nil {
+			problems = append(problems, validation.FieldError{Field: "rrule", Message: err.Error()})
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	// De herhaling begint bij de deadline van de taak waarop de regel is ingesteld
+	switch {
+	case task.RRule == "":
+		task.SeriesStart = nil
+	case existing == nil || task.RRule != existing.RRule || task.SeriesStart == nil:
+		start := task.DueAt
+		task.SeriesStart = &start
+	}
+
+	// Het afsluitmoment blijft staan zolang de taak afgesloten blijft
+	switch {
+	case !task.Status.IsClosed():
+		task.CompletedAt = nil
+	case existing != nil && existing.Status.IsClosed() && existing.CompletedAt != nil:
+		task.CompletedAt = existing.CompletedAt
+	default:
+		now := time.Now()
+		task.CompletedAt = &now
+	}
+
+	setReminder(task, existing)
+	return nil
+}
+
+// setReminder bepaalt het moment van de herinnering. Een herinnering die al verstuurd is blijft als
+// verstuurd staan, tenzij het moment verandert; voor een taak waarvan de deadline al verstreken is
+// wordt geen herinnering gepland.
+func setReminder(task *model.Task, existing *model.Task) {
+	task.RemindAt = nil
+	task.ReminderSentAt = nil
+	if task.RemindMinutes == nil || !task.DueAt.After(time.Now()) {
+		return
+	}
+
+	remindAt := task.DueAt.Add(-time.Duration(*task.RemindMinutes) * time.Minute)
+	task.RemindAt = &remindAt
+	if existing != nil && existing.RemindAt != nil && existing.RemindAt.Equal(remindAt) {
+		task.ReminderSentAt = existing.ReminderSentAt
+	}
+}
+
+// validateRRule controleert een herhalingsregel. DTSTART mag niet in de regel staan, want de herhaling
+// begint bij de deadline, en een taak herhaalt hoogstens dagelijks.
+func validateRRule(rule string) error {
+	if len(rule) > maxRRuleLength {
+		return fmt.Errorf("herhaling mag maximaal %d tekens bevatten", maxRRuleLength)
+	}
+	if strings.Contains(rule, "\n") || strings.Contains(rule, "DTSTART") {
+		return errors.New("herhaling mag geen DTSTART bevatten; de herhaling begint bij de deadline")
+	}
+
+	option, err := rrule.StrToROption(rule)
+	if err != nil {
+		return fmt.Errorf("ongeldige herhaling (RFC 5545 RRULE, bijv. FREQ=WEEKLY;BYDAY=MO): %v", err)
+	}
+	if option.Freq > rrule.DAILY {
+		return errors.New("een taak kan hoogstens dagelijks herhalen")
+	}
+	return nil
+}
+
+// nextTask maakt de volgende taak van de reeks van een afgesloten terugkerende taak, of nil als de
+// reeks is afgelopen (COUNT of UNTIL). Een te laat afgesloten taak gaat door met de eerste deadline
+// na nu, zodat er geen reeks verlopen taken ontstaat.
+func nextTask(task *model.Task) (*model.Task, error) {
+	option, err := rrule.StrToROptionInLocation(task.RRule, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	option.Dtstart = task.SeriesStart.In(time.Local)
+
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, err
+	}
+
+	after := task.DueAt
+	if now := time.Now(); now.After(after) {
+		after = now
+	}
+	dueAt := rule.After(after, false)
+	if dueAt.IsZero() {
+		return nil, nil
+	}
+
+	seriesID := task.SeriesID
+	if seriesID == nil {
+		seriesID = &task.ID
+	}
+
+	next := &model.Task{
+		Title:         task.Title,
+		Description:   task.Description,
+		DueAt:         dueAt,
+		AssigneeID:    task.AssigneeID,
+		AssigneeName:  task.AssigneeName,
+		CustomerID:    task.CustomerID,
+		Priority:      task.Priority,
+		Status:        model.StatusOpen,
+		RRule:         task.RRule,
+		SeriesID:      seriesID,
+		SeriesStart:   task.SeriesStart,
+		RemindMinutes: task.RemindMinutes,
+		CreatedByID:   task.CreatedByID,
+		CreatedByName: task.CreatedByName,
+	}
+	setReminder(next, nil)
+	return next, nil
+}
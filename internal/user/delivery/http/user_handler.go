@@ -1,9 +1,16 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"odomosml/internal/user/model"
 	"odomosml/internal/user/service"
+	"odomosml/pkg/concurrency"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/patch"
+	"odomosml/pkg/sorting"
+	"odomosml/pkg/validation"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -11,16 +18,57 @@ import (
 
 // UserHandler handles HTTP requests for users
 type UserHandler struct {
-	service service.UserService
+	service         service.UserService
+	ifMatchRequired bool
 }
 
-// NewUserHandler creates a new UserHandler instance
-func NewUserHandler(service service.UserService) *UserHandler {
+// NewUserHandler creates a new UserHandler instance. With ifMatchRequired, updates and deletes
+// must send an If-Match header.
+func NewUserHandler(service service.UserService, ifMatchRequired bool) *UserHandler {
 	return &UserHandler{
-		service: service,
+		service:         service,
+		ifMatchRequired: ifMatchRequired,
 	}
 }
 
+// checkIfMatch haalt de actuele gebruiker op en controleert de If-Match header daartegen. Bij een fout
+// is de response al verstuurd en is het resultaat nil.
+func (h *UserHandler) checkIfMatch(c *gin.Context, id string) *model.User {
+	existing, err := h.service.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Gebruiker niet gevonden"})
+		return nil
+	}
+
+	if err := concurrency.CheckIfMatch(c.GetHeader("If-Match"), existing.ETag(), h.ifMatchRequired); err != nil {
+		respondWriteError(c, err)
+		return nil
+	}
+	return existing
+}
+
+// respondWriteError vertaalt een fout bij het aanmaken of wijzigen van een gebruiker naar een HTTP status:
+// 412 of 428 bij een versieconflict, 409 of 415 bij een patch, 400 bij validatiefouten (per veld in fields)
+// en anders 500
+func respondWriteError(c *gin.Context, err error) {
+	status, ok := concurrency.Status(err)
+	if !ok {
+		status, ok = patch.Status(err)
+	}
+
+	response := gin.H{"error": err.Error()}
+	if fields, isValidation := validation.Fields(err); isValidation {
+		response["fields"] = fields
+		if !ok {
+			status, ok = http.StatusBadRequest, true
+		}
+	}
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, response)
+}
+
 // @Summary      Lijst van gebruikers ophalen
 // @Description  Haalt een lijst van alle gebruikers op met optionele filters
 // @Tags         users
@@ -28,8 +76,13 @@ func NewUserHandler(service service.UserService) *UserHandler {
 // @Produce      json
 // @Param        page query int false "Paginanummer (default: 1)"
 // @Param        pageSize query int false "Aantal items per pagina (default: 10, max: 100)"
-// @Param        searchTerm query string false "Zoekterm voor gebruikersnaam of email"
+// @Param        searchTerm query string false "Zoekterm voor gebruikersnaam, of een volledig e-mailadres"
 // @Param        role query string false "Filter op rol (ADMIN/USER)"
+// @Param        after query string false "Cursor: de pagina na deze cursor (nextCursor)"
+// @Param        before query string false "Cursor: de pagina voor deze cursor (prevCursor)"
+// @Param        count query string false "Totaal aantal bepalen: exact, estimate of none (default: exact)"
+// @Param        sort query string false "Komma-gescheiden sorteervelden, - voor aflopend: id, username, role, active, team, created_at, updated_at (default: username)"
+// @Param        filter query string false "Filterexpressie, bijv. role = ADMIN and not active = true"
 // @Success      200  {object}  map[string]interface{} "{ data: []model.UserResponse, pagination: object }"
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
@@ -37,43 +90,99 @@ func NewUserHandler(service service.UserService) *UserHandler {
 // @Security     Bearer
 // @Router       /users [get]
 func (h *UserHandler) GetAll(c *gin.Context) {
+	page, err := pagination.FromQuery(c.Request.URL.Query(), "page", "pageSize", 10, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sort, err := sorting.Parse(c.Query("sort"), model.SortFields...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expression, err := filterExpr.Parse(c.Query("filter"))
+	if err != nil {
+		respondFilterError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	filter := model.UserFilter{
 		SearchTerm: c.Query("searchTerm"),
 		Role:       model.Role(c.Query("role")),
-		Page:       parseIntParam(c, "page", 1),
-		PageSize:   parseIntParam(c, "pageSize", 10),
+		Page:       page.Page,
+		PageSize:   page.Size,
+		After:      page.After,
+		Before:     page.Before,
+		Count:      page.Count,
+		Sort:       sort,
+		Expression: expression,
 	}
 
-	users, total, err := h.service.GetAllUsers(filter)
+	users, info, err := h.service.GetAllUsers(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFilterError(c, http.StatusInternalServerError, err)
 		return
 	}
 
+	if link := pagination.Links(c.Request.URL, page, info, "page"); link != "" {
+		c.Header("Link", link)
+	}
+
 	// Convert users to response objects
 	userResponses := make([]model.UserResponse, len(users))
 	for i, user := range users {
 		userResponses[i] = user.ToResponse()
 	}
 
+	meta := gin.H{
+		"total":      info.Total,
+		"pageSize":   filter.PageSize,
+		"hasMore":    info.HasMore,
+		"nextCursor": info.Next,
+	}
+	if page.Keyset() {
+		meta["prevCursor"] = info.Prev
+	} else {
+		meta["page"] = filter.Page
+	}
+	if info.Total != nil {
+		meta["lastPage"] = (*info.Total + int64(filter.PageSize) - 1) / int64(filter.PageSize)
+		meta["totalEstimated"] = info.Estimated
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"data": userResponses,
-		"pagination": gin.H{
-			"total":    total,
-			"page":     filter.Page,
-			"pageSize": filter.PageSize,
-			"lastPage": (total + int64(filter.PageSize) - 1) / int64(filter.PageSize),
-		},
+		"data":       userResponses,
+		"pagination": meta,
 	})
 }
 
+// respondFilterError stuurt een fout bij het ophalen van gebruikers terug. Een fout in de filterexpressie
+// of een cursor van een andere sortering is een 400, met bij een filterfout de positie van het foute token.
+func respondFilterError(c *gin.Context, status int, err error) {
+	body := gin.H{"error": err.Error()}
+
+	var expressionErr *filterExpr.Error
+	if errors.As(err, &expressionErr) {
+		status = http.StatusBadRequest
+		body["position"] = expressionErr.Pos + 1
+	} else if errors.Is(err, pagination.ErrSortMismatch) {
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, body)
+}
+
 // @Summary      Gebruiker ophalen op ID
 // @Description  Haalt een specifieke gebruiker op basis van ID
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Gebruiker ID"
+// @Param        If-None-Match header string false "ETag van een eerder opgehaalde versie"
 // @Success      200  {object}  model.UserResponse
+// @Success      304
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
@@ -88,6 +197,13 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 		return
 	}
 
+	etag := user.ETag()
+	c.Header("ETag", etag)
+	if concurrency.Matches(c.GetHeader("If-None-Match"), etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	c.JSON(http.StatusOK, user.ToResponse())
 }
 
@@ -113,7 +229,7 @@ func (h *UserHandler) Create(c *gin.Context) {
 
 	createdUser, err := h.service.CreateUser(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWriteError(c, err)
 		return
 	}
 
@@ -126,11 +242,14 @@ func (h *UserHandler) Create(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Gebruiker ID"
+// @Param        If-Match header string false "ETag van de versie die gewijzigd wordt (verplicht tenzij IF_MATCH_REQUIRED=false)"
 // @Param        user body model.User true "Gebruiker gegevens"
 // @Success      200  {object}  model.UserResponse
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
+// @Failure      428  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Security     Bearer
 // @Router       /users/{id} [put]
@@ -148,14 +267,64 @@ func (h *UserHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldig ID formaat"})
 		return
 	}
+	existing := h.checkIfMatch(c, id)
+	if existing == nil {
+		return
+	}
 	user.ID = uint(parsedID)
+	user.Version = existing.Version
 
 	updatedUser, err := h.service.UpdateUser(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWriteError(c, err)
+		return
+	}
+
+	c.Header("ETag", updatedUser.ETag())
+	c.JSON(http.StatusOK, updatedUser.ToResponse())
+}
+
+// @Summary      Gebruiker gedeeltelijk bijwerken
+// @Description  Werkt velden van een gebruiker bij met een JSON Merge Patch (application/merge-patch+json, ook voor application/json) of een JSON Patch (application/json-patch+json). Alleen username, email, role, active, team en password kunnen gewijzigd worden; de gebruiker wordt daarna gevalideerd als bij bijwerken. Validatiefouten staan per veld in fields.
+// @Tags         users
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id path string true "Gebruiker ID"
+// @Param        If-Match header string false "ETag van de versie die gewijzigd wordt (verplicht tenzij IF_MATCH_REQUIRED=false)"
+// @Param        user body object true "Merge patch of lijst van JSON Patch operaties"
+// @Success      200  {object}  model.UserResponse
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
+// @Failure      415  {object}  map[string]string
+// @Failure      428  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     Bearer
+// @Router       /users/{id} [patch]
+func (h *UserHandler) PartialUpdate(c *gin.Context) {
+	id := c.Param("id")
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	existing := h.checkIfMatch(c, id)
+	if existing == nil {
+		return
+	}
+
+	updatedUser, err := h.service.PatchUser(id, existing.Version, c.ContentType(), body)
+	if err != nil {
+		respondWriteError(c, err)
+		return
+	}
+
+	c.Header("ETag", updatedUser.ETag())
 	c.JSON(http.StatusOK, updatedUser.ToResponse())
 }
 
@@ -165,18 +334,26 @@ func (h *UserHandler) Update(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Gebruiker ID"
+// @Param        If-Match header string false "ETag van de versie die gewijzigd wordt (verplicht tenzij IF_MATCH_REQUIRED=false)"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
+// @Failure      428  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Security     Bearer
 // @Router       /users/{id} [delete]
 func (h *UserHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
-	userData, err := h.service.DeleteUser(id)
+	existing := h.checkIfMatch(c, id)
+	if existing == nil {
+		return
+	}
+
+	userData, err := h.service.DeleteUser(id, existing.Version)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWriteError(c, err)
 		return
 	}
 
@@ -188,16 +365,3 @@ func (h *UserHandler) Delete(c *gin.Context) {
 		"data":    userData,
 	})
 }
-
-// Helper function to parse integer parameters
-func parseIntParam(c *gin.Context, key string, defaultValue int) int {
-	valueStr := c.Query(key)
-	if valueStr == "" {
-		return defaultValue
-	}
-	value, err := strconv.Atoi(valueStr)
-	if err != nil || value < 1 {
-		return defaultValue
-	}
-	return value
-}
@@ -2,6 +2,11 @@ package model
 
 import (
 	"errors"
+	"odomosml/pkg/concurrency"
+	"odomosml/pkg/fieldcrypt"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -21,19 +26,24 @@ const (
 // User represents a user in the system
 // @Description Een gebruiker in het systeem
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
-	Username  string    `json:"username" gorm:"size:50;not null;unique" example:"johndoe" swaggertype:"string"`
-	Email     string    `json:"email" gorm:"size:100;not null;unique" example:"john@example.com" swaggertype:"string"`
-	Password  string    `json:"password,omitempty" gorm:"size:255;not null" example:"password123" swaggertype:"string"`
-	Role      Role      `json:"role" gorm:"size:20;not null;default:'USER'" example:"USER" swaggertype:"string"`
-	Active    bool      `json:"active" gorm:"default:true" example:"true" swaggertype:"boolean"`
-	CreatedAt time.Time `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
-	UpdatedAt time.Time `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	ID         uint      `json:"id" gorm:"primaryKey" example:"1" swaggertype:"integer"`
+	Username   string    `json:"username" gorm:"size:50;not null;unique" example:"johndoe" swaggertype:"string"`
+	Email      string    `json:"email" gorm:"type:text;not null;serializer:encrypted" example:"john@example.com" swaggertype:"string"` // Versleuteld opgeslagen; uniek en op te zoeken via EmailIndex
+	EmailIndex string    `json:"-" gorm:"size:64;not null;default:''"`                                                                 // Blind index van het e-mailadres, met een unieke index
+	Password   string    `json:"password,omitempty" gorm:"size:255;not null" example:"password123" swaggertype:"string"`
+	Role       Role      `json:"role" gorm:"size:20;not null;default:'USER'" example:"USER" swaggertype:"string"`
+	Active     bool      `json:"active" gorm:"default:true" example:"true" swaggertype:"boolean"`
+	Team       string    `json:"team" gorm:"size:50;not null;default:'';index" example:"verkoop" swaggertype:"string"`
+	Version    uint      `json:"version" gorm:"not null;default:1" example:"1" swaggertype:"integer"` // Wordt bij elke wijziging opgehoogd, voor optimistic concurrency
+	CreatedAt  time.Time `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
+	UpdatedAt  time.Time `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
 }
 
 // BeforeSave wordt aangeroepen voordat een gebruiker wordt opgeslagen
-// Dit zorgt ervoor dat wachtwoorden altijd worden gehasht
+// Dit zorgt ervoor dat wachtwoorden altijd worden gehasht en de blind index van het e-mailadres klopt
 func (u *User) BeforeSave(tx *gorm.DB) error {
+	u.EmailIndex = fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, u.Email)
+
 	// Als het wachtwoord leeg is, doe niets (bijv. bij updates waar wachtwoord niet wordt gewijzigd)
 	if u.Password == "" {
 		return nil
@@ -55,6 +65,15 @@ func (u *User) BeforeSave(tx *gorm.DB) error {
 	return nil
 }
 
+// WritableFields zijn de velden van een gebruiker die met een PATCH gewijzigd kunnen worden;
+// password staat niet in de response en kan alleen gezet worden
+var WritableFields = []string{"username", "email", "role", "active", "team", "password"}
+
+// ETag geeft de ETag van de gebruiker zoals de API deze teruggeeft
+func (u *User) ETag() string {
+	return concurrency.ETag(u.Version, u.ToResponse())
+}
+
 // ComparePassword vergelijkt een plaintext wachtwoord met het gehashte wachtwoord
 func (u *User) ComparePassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
@@ -73,6 +92,8 @@ type UserResponse struct {
 	Email     string    `json:"email" example:"john@example.com" swaggertype:"string"`
 	Role      Role      `json:"role" example:"USER" swaggertype:"string"`
 	Active    bool      `json:"active" example:"true" swaggertype:"boolean"`
+	Team      string    `json:"team" example:"verkoop" swaggertype:"string"`
+	Version   uint      `json:"version" example:"1" swaggertype:"integer"`
 	CreatedAt time.Time `json:"created_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
 	UpdatedAt time.Time `json:"updated_at" example:"2024-02-25T20:30:00Z" swaggertype:"string" format:"date-time"`
 }
@@ -85,6 +106,8 @@ func (u *User) ToResponse() UserResponse {
 		Email:     u.Email,
 		Role:      u.Role,
 		Active:    u.Active,
+		Team:      u.Team,
+		Version:   u.Version,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
 	}
@@ -93,9 +116,38 @@ func (u *User) ToResponse() UserResponse {
 // UserFilter definieert filters voor het ophalen van gebruikers
 // @Description Filter opties voor gebruikerslijsten
 type UserFilter struct {
-	SearchTerm string `json:"search_term" form:"search_term" example:"john" swaggertype:"string"`
-	Role       Role   `json:"role" form:"role" example:"USER" swaggertype:"string"`
-	Active     *bool  `json:"active" form:"active" example:"true" swaggertype:"boolean"`
-	Page       int    `json:"page" form:"page" example:"1" swaggertype:"integer"`
-	PageSize   int    `json:"page_size" form:"page_size" example:"10" swaggertype:"integer"`
+	SearchTerm string             `json:"search_term" form:"search_term" example:"john" swaggertype:"string"`
+	Role       Role               `json:"role" form:"role" example:"USER" swaggertype:"string"`
+	Active     *bool              `json:"active" form:"active" example:"true" swaggertype:"boolean"`
+	Page       int                `json:"page" form:"page" example:"1" swaggertype:"integer"`
+	PageSize   int                `json:"page_size" form:"page_size" example:"10" swaggertype:"integer"`
+	After      *pagination.Cursor `json:"-" form:"-"` // Keyset paginering: de rijen na deze cursor
+	Before     *pagination.Cursor `json:"-" form:"-"` // Keyset paginering: de rijen voor deze cursor
+	Count      string             `json:"-" form:"-"` // pagination.CountExact, CountEstimate of CountNone
+	Sort       []sorting.Field    `json:"-" form:"-"` // Sorteervelden; leeg sorteert op gebruikersnaam
+	Expression filterExpr.Node    `json:"-" form:"-"` // Filterexpressie uit de filter parameter
+}
+
+// SortFields zijn de velden waarop gebruikers gesorteerd kunnen worden; het e-mailadres is versleuteld en niet sorteerbaar
+var SortFields = []string{"id", "username", "role", "active", "team", "created_at", "updated_at"}
+
+// SortValue geeft de waarde van een sorteerveld (zie SortFields), voor het maken van een cursor
+func (u *User) SortValue(field string) interface{} {
+	switch field {
+	case "id":
+		return u.ID
+	case "username":
+		return u.Username
+	case "role":
+		return string(u.Role)
+	case "active":
+		return u.Active
+	case "team":
+		return u.Team
+	case "created_at":
+		return u.CreatedAt
+	case "updated_at":
+		return u.UpdatedAt
+	}
+	return nil
 }
@@ -3,6 +3,11 @@ package repository
 import (
 	"errors"
 	"odomosml/internal/user/model"
+	"odomosml/pkg/concurrency"
+	"odomosml/pkg/fieldcrypt"
+	filterExpr "odomosml/pkg/filter"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/sorting"
 
 	"gorm.io/gorm"
 )
@@ -15,12 +20,12 @@ const (
 
 // UserRepository interface definieert de methodes voor gebruikersbeheer
 type UserRepository interface {
-	FindAll(filter model.UserFilter) ([]model.User, int64, error)
+	FindAll(filter model.UserFilter) ([]model.User, pagination.Info, error)
 	FindByID(id string) (*model.User, error)
 	FindByEmail(email string) (*model.User, error)
 	Create(user *model.User) error
 	Update(user *model.User) error
-	Delete(id string) error
+	Delete(id string, version uint) error
 	DeleteUser(id string) (map[string]interface{}, error)
 }
 
@@ -36,10 +41,9 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	}
 }
 
-// FindAll haalt alle gebruikers op met filters
-func (r *userRepository) FindAll(filter model.UserFilter) ([]model.User, int64, error) {
+// FindAll haalt alle gebruikers op met filters, op paginanummer of vanaf een cursor
+func (r *userRepository) FindAll(filter model.UserFilter) ([]model.User, pagination.Info, error) {
 	var users []model.User
-	var total int64
 
 	// Bouw query op
 	query := r.db.Model(&model.User{})
@@ -47,7 +51,7 @@ func (r *userRepository) FindAll(filter model.UserFilter) ([]model.User, int64,
 	// Filters toepassen
 	if filter.SearchTerm != "" {
 		searchTerm := "%" + filter.SearchTerm + "%"
-		query = query.Where("username ILIKE ? OR email ILIKE ?", searchTerm, searchTerm)
+		query = query.Where("username ILIKE ? OR email_index = ?", searchTerm, fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, filter.SearchTerm))
 	}
 
 	if filter.Role != "" {
@@ -58,23 +62,60 @@ func (r *userRepository) FindAll(filter model.UserFilter) ([]model.User, int64,
 		query = query.Where("active = ?", *filter.Active)
 	}
 
-	// Tel totaal aantal records
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	if filter.Expression != nil {
+		condition, vars, err := filterExpr.Compile(filter.Expression, userFilterColumn)
+		if err != nil {
+			return nil, pagination.Info{}, err
+		}
+		query = query.Where(condition, vars...)
 	}
 
-	// Paginering toepassen
-	if filter.Page > 0 && filter.PageSize > 0 {
-		offset := (filter.Page - 1) * filter.PageSize
-		query = query.Offset(offset).Limit(filter.PageSize)
+	// Tel (of schat) het totaal aantal records
+	total, estimated, err := pagination.Count(query, filter.Count)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+
+	// Paginering en sortering toepassen, met het ID als tiebreaker voor een stabiele paginering
+	sort := filter.Sort
+	if len(sort) == 0 {
+		sort = []sorting.Field{{Name: "username"}}
+	}
+	request := pagination.Request{Page: filter.Page, Size: filter.PageSize, After: filter.After, Before: filter.Before}
+	column := sorting.Columns(userColumns)
+	query, err = pagination.Apply(query, request, sort, column, "id")
+	if err != nil {
+		return nil, pagination.Info{}, err
 	}
 
 	// Voer query uit
 	if err := query.Find(&users).Error; err != nil {
-		return nil, 0, err
+		return nil, pagination.Info{}, err
 	}
 
-	return users, total, nil
+	users, info := pagination.Window(users, request, func(user *model.User) pagination.Cursor {
+		return pagination.NewCursor(sort, column, user.ID, user.SortValue)
+	})
+	info.Total, info.Estimated = total, estimated
+
+	return users, info, nil
+}
+
+// userColumns koppelt de velden waarop gesorteerd en gefilterd kan worden aan hun kolom
+var userColumns = map[string]sorting.Column{
+	"id":         {SQL: "id", Type: "bigint"},
+	"username":   {SQL: "username", Type: "text"},
+	"role":       {SQL: "role", Type: "text"},
+	"active":     {SQL: "active", Type: "boolean"},
+	"team":       {SQL: "team", Type: "text"},
+	"created_at": {SQL: "created_at", Type: "timestamptz"},
+	"updated_at": {SQL: "updated_at", Type: "timestamptz"},
+}
+
+// userFilterColumn geeft de kolom van een veld in een filterexpressie
+func userFilterColumn(name string) (sorting.Column, bool) {
+	column, ok := userColumns[name]
+	return column, ok
 }
 
 // FindByID haalt een gebruiker op op basis van ID
@@ -91,11 +132,12 @@ func (r *userRepository) FindByID(id string) (*model.User, error) {
 	return &user, nil
 }
 
-// FindByEmail haalt een gebruiker op op basis van email
+// FindByEmail haalt een gebruiker op op basis van email (hoofdletterongevoelig, via de blind index)
 func (r *userRepository) FindByEmail(email string) (*model.User, error) {
 	var user model.User
 
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	index := fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, email)
+	if err := r.db.Where("email_index = ? AND email_index <> ''", index).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("gebruiker niet gevonden")
 		}
@@ -110,15 +152,38 @@ func (r *userRepository) Create(user *model.User) error {
 	return r.db.Create(user).Error
 }
 
-// Update werkt een bestaande gebruiker bij
+// Update werkt een bestaande gebruiker bij, als de gebruiker in de database nog user.Version heeft, en hoogt
+// de versie op. Het wachtwoord wordt alleen gewijzigd als er een nieuw wachtwoord is opgegeven.
 func (r *userRepository) Update(user *model.User) error {
-	return r.db.Save(user).Error
+	expected := user.Version
+	user.Version = expected + 1
+
+	columns := []string{"username", "email", "email_index", "role", "active", "team", "version", "updated_at"}
+	if user.Password != "" {
+		columns = append(columns, "password")
+	}
+
+	result := r.db.Model(user).Where("version = ?", expected).Select(columns).Updates(user)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = concurrency.ErrConflict
+	}
+	if result.Error != nil {
+		user.Version = expected
+	}
+	return result.Error
 }
 
-// Delete verwijdert een gebruiker
+// Delete verwijdert een gebruiker, als de gebruiker nog de opgegeven versie heeft
 // DEPRECATED: Gebruik DeleteUser in plaats hiervan
-func (r *userRepository) Delete(id string) error {
-	return r.db.Delete(&model.User{}, "id = ?", id).Error
+func (r *userRepository) Delete(id string, version uint) error {
+	result := r.db.Where("version = ?", version).Delete(&model.User{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return concurrency.ErrConflict
+	}
+	return nil
 }
 
 // DeleteUser verwijdert een gebruiker en retourneert de gebruikersdata voor audit logging
@@ -1,19 +1,29 @@
 package service
 
 import (
-	"errors"
+	"encoding/json"
+	"fmt"
 	"odomosml/internal/user/model"
 	"odomosml/internal/user/repository"
+	"odomosml/pkg/pagination"
+	"odomosml/pkg/patch"
+	"odomosml/pkg/validation"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
+// maxTeamLength is de lengte van de team kolom
+const maxTeamLength = 50
+
 // UserService interface definieert de methodes voor gebruikersbeheer
 type UserService interface {
-	GetAllUsers(filter model.UserFilter) ([]model.User, int64, error)
+	GetAllUsers(filter model.UserFilter) ([]model.User, pagination.Info, error)
 	GetUserByID(id string) (*model.User, error)
 	CreateUser(user *model.User) (*model.User, error)
 	UpdateUser(user *model.User) (*model.User, error)
-	DeleteUser(id string) (map[string]interface{}, error)
+	PatchUser(id string, version uint, mediaType string, body []byte) (*model.User, error)
+	DeleteUser(id string, version uint) (map[string]interface{}, error)
 }
 
 // userService implementeert de UserService interface
@@ -29,7 +39,7 @@ func NewUserService(repo repository.UserRepository) UserService {
 }
 
 // GetAllUsers haalt alle gebruikers op met filters
-func (s *userService) GetAllUsers(filter model.UserFilter) ([]model.User, int64, error) {
+func (s *userService) GetAllUsers(filter model.UserFilter) ([]model.User, pagination.Info, error) {
 	// Valideer paginering
 	if filter.Page < 1 {
 		filter.Page = 1
@@ -50,21 +60,17 @@ func (s *userService) GetUserByID(id string) (*model.User, error) {
 // CreateUser maakt een nieuwe gebruiker aan
 func (s *userService) CreateUser(user *model.User) (*model.User, error) {
 	// Valideer gebruiker
-	if user.Username == "" {
-		return nil, errors.New("gebruikersnaam is verplicht")
-	}
-
-	if user.Email == "" {
-		return nil, errors.New("email is verplicht")
+	if err := validateUser(user); err != nil {
+		return nil, err
 	}
 
 	if user.Password == "" {
-		return nil, errors.New("wachtwoord is verplicht")
+		return nil, validation.New("password", "wachtwoord is verplicht")
 	}
 
 	// Controleer of email al bestaat
 	if existing, _ := s.repo.FindByEmail(user.Email); existing != nil {
-		return nil, errors.New("email is al in gebruik")
+		return nil, validation.New("email", "email is al in gebruik")
 	}
 
 	// Maak gebruiker aan
@@ -75,7 +81,7 @@ func (s *userService) CreateUser(user *model.User) (*model.User, error) {
 	return user, nil
 }
 
-// UpdateUser werkt een bestaande gebruiker bij
+// UpdateUser werkt een bestaande gebruiker bij, als de gebruiker in de database nog user.Version heeft
 func (s *userService) UpdateUser(user *model.User) (*model.User, error) {
 	// Controleer of gebruiker bestaat
 	existing, err := s.repo.FindByID(strconv.FormatUint(uint64(user.ID), 10))
@@ -83,10 +89,14 @@ func (s *userService) UpdateUser(user *model.User) (*model.User, error) {
 		return nil, err
 	}
 
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+
 	// Controleer of email al in gebruik is door een andere gebruiker
 	if user.Email != existing.Email {
 		if existingWithEmail, _ := s.repo.FindByEmail(user.Email); existingWithEmail != nil {
-			return nil, errors.New("email is al in gebruik")
+			return nil, validation.New("email", "email is al in gebruik")
 		}
 	}
 
@@ -95,11 +105,42 @@ func (s *userService) UpdateUser(user *model.User) (*model.User, error) {
 		return nil, err
 	}
 
-	return user, nil
+	return s.repo.FindByID(strconv.FormatUint(uint64(user.ID), 10))
 }
 
-// DeleteUser verwijdert een gebruiker
-func (s *userService) DeleteUser(id string) (map[string]interface{}, error) {
+// PatchUser past een JSON Merge Patch of JSON Patch toe op een bestaande gebruiker en werkt de gebruiker bij,
+// als deze nog de opgegeven versie heeft. Alleen de velden in model.WritableFields mogen veranderen; daarna
+// volgt dezelfde validatie als bij het bijwerken van een volledige gebruiker.
+func (s *userService) PatchUser(id string, version uint, mediaType string, body []byte) (*model.User, error) {
+	user, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := json.Marshal(user.ToResponse())
+	if err != nil {
+		return nil, err
+	}
+	patched, err := patch.Apply(mediaType, original, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := patch.CheckFields(original, patched, model.WritableFields...); err != nil {
+		return nil, err
+	}
+
+	var updated model.User
+	if err := patch.Decode(patched, &updated); err != nil {
+		return nil, err
+	}
+	updated.ID = user.ID
+	updated.Version = version
+
+	return s.UpdateUser(&updated)
+}
+
+// DeleteUser verwijdert een gebruiker, als de gebruiker nog de opgegeven versie heeft
+func (s *userService) DeleteUser(id string, version uint) (map[string]interface{}, error) {
 	// Haal gebruiker op voor audit logging
 	user, err := s.repo.FindByID(id)
 	if err != nil {
@@ -115,9 +156,42 @@ func (s *userService) DeleteUser(id string) (map[string]interface{}, error) {
 	}
 
 	// Verwijder gebruiker
-	if err := s.repo.Delete(id); err != nil {
+	if err := s.repo.Delete(id, version); err != nil {
 		return nil, err
 	}
 
 	return userData, nil
 }
+
+// validateUser controleert de velden die bij aanmaken en bijwerken gelden; een gebruiker zonder rol wordt USER
+func validateUser(user *model.User) error {
+	var problems validation.Errors
+
+	user.Username = strings.TrimSpace(user.Username)
+	if user.Username == "" {
+		problems = append(problems, validation.FieldError{Field: "username", Message: "gebruikersnaam is verplicht"})
+	}
+
+	user.Email = strings.TrimSpace(user.Email)
+	if user.Email == "" {
+		problems = append(problems, validation.FieldError{Field: "email", Message: "email is verplicht"})
+	}
+
+	switch user.Role {
+	case "":
+		user.Role = model.RoleUser
+	case model.RoleAdmin, model.RoleUser:
+	default:
+		problems = append(problems, validation.FieldError{Field: "role", Message: "rol moet ADMIN of USER zijn"})
+	}
+
+	user.Team = strings.TrimSpace(user.Team)
+	if utf8.RuneCountInString(user.Team) > maxTeamLength {
+		problems = append(problems, validation.FieldError{Field: "team", Message: fmt.Sprintf("team mag maximaal %d tekens bevatten", maxTeamLength)})
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package concurrency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Fouten bij het controleren van een versie
+var (
+	ErrConflict             = errors.New("de gegevens zijn intussen door iemand anders gewijzigd; haal de actuele versie op en probeer het opnieuw")
+	ErrPreconditionRequired = errors.New("de If-Match header is verplicht; stuur de ETag van de actuele versie mee")
+)
+
+// ETag geeft de (sterke) ETag van een representatie: het versienummer plus een hash van de JSON. Door de hash
+// verandert de ETag ook als gekoppelde gegevens wijzigen zonder dat het record een nieuwe versie krijgt
+// (bijv. de tags van een klant).
+func ETag(version uint, representation interface{}) string {
+	data, _ := json.Marshal(representation)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"%d-%s"`, version, hex.EncodeToString(sum[:8]))
+}
+
+// Matches geeft aan of een If-Match of If-None-Match header de ETag bevat. "*" past altijd. Bij weak
+// (If-None-Match) tellen zwakke ETags (W/"...") mee, bij If-Match alleen sterke.
+func Matches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIfMatch controleert een If-Match header tegen de ETag van de actuele versie. Zonder header
+// is het resultaat ErrPreconditionRequired als de header verplicht is, en anders geen fout.
+func CheckIfMatch(header, etag string, required bool) error {
+	if strings.TrimSpace(header) == "" {
+		if required {
+			return ErrPreconditionRequired
+		}
+		return nil
+	}
+	if !Matches(header, etag, false) {
+		return ErrConflict
+	}
+	return nil
+}
+
+// Status geeft de HTTP status voor een fout uit dit package: 412 Precondition Failed bij een andere
+// versie, 428 Precondition Required bij een ontbrekende If-Match header
+func Status(err error) (int, bool) {
+	switch {
+	case errors.Is(err, ErrConflict):
+		return http.StatusPreconditionFailed, true
+	case errors.Is(err, ErrPreconditionRequired):
+		return http.StatusPreconditionRequired, true
+	}
+	return 0, false
+}
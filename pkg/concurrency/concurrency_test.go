@@ -0,0 +1,91 @@
+package concurrency
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	type customer struct {
+		Name string `json:"name"`
+	}
+
+	etag := ETag(3, customer{Name: "Jansen"})
+	if !regexp.MustCompile(`^"3-[0-9a-f]{16}"$`).MatchString(etag) {
+		t.Errorf("ETag = %s, verwacht \"3-<hash>\"", etag)
+	}
+	if again := ETag(3, customer{Name: "Jansen"}); again != etag {
+		t.Errorf("ETag niet stabiel: %s en %s", etag, again)
+	}
+	if other := ETag(4, customer{Name: "Jansen"}); other == etag {
+		t.Error("ETag gelijk bij een andere versie")
+	}
+	if other := ETag(3, customer{Name: "Pietersen"}); other == etag {
+		t.Error("ETag gelijk bij andere gegevens met dezelfde versie")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	const etag = `"3-abc"`
+
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"3-abc"`, false, true},
+		{`"2-abc"`, false, false},
+		{`*`, false, true},
+		{`"1-def", "3-abc"`, false, true},
+		{` "1-def" ,"3-abc" `, true, true},
+		{`W/"3-abc"`, false, false},
+		{`W/"3-abc"`, true, true},
+		{`3-abc`, true, false},
+		{``, true, false},
+	}
+
+	for _, tt := range tests {
+		if got := Matches(tt.header, etag, tt.weak); got != tt.want {
+			t.Errorf("Matches(%q, weak %v) = %v, verwacht %v", tt.header, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	const etag = `"3-abc"`
+
+	tests := []struct {
+		name       string
+		header     string
+		required   bool
+		wantErr    error
+		wantStatus int
+	}{
+		{name: "actuele versie", header: etag, required: true},
+		{name: "andere versie", header: `"2-abc"`, wantErr: ErrConflict, wantStatus: http.StatusPreconditionFailed},
+		{name: "zwakke ETag telt niet", header: `W/"3-abc"`, wantErr: ErrConflict, wantStatus: http.StatusPreconditionFailed},
+		{name: "zonder header, optioneel", header: " "},
+		{name: "zonder header, verplicht", required: true, wantErr: ErrPreconditionRequired, wantStatus: http.StatusPreconditionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckIfMatch(tt.header, etag, tt.required)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("CheckIfMatch gaf %v, verwacht %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			if status, ok := Status(err); !ok || status != tt.wantStatus {
+				t.Errorf("Status = %d, %v; verwacht %d", status, ok, tt.wantStatus)
+			}
+		})
+	}
+
+	if _, ok := Status(errors.New("andere fout")); ok {
+		t.Error("Status herkent een fout buiten dit package")
+	}
+}
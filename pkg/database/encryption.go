@@ -0,0 +1,240 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	customerModel "odomosml/internal/customer/model"
+	savedViewModel "odomosml/internal/savedview/model"
+	userModel "odomosml/internal/user/model"
+	"odomosml/pkg/fieldcrypt"
+	filterExpr "odomosml/pkg/filter"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// encryptionBatchSize is het aantal rijen dat per keer van een blind index voorzien wordt
+const encryptionBatchSize = 500
+
+// encryptedTable beschrijft de versleutelde kolommen van een tabel en de blind index die bij een
+// van die kolommen hoort
+type encryptedTable struct {
+	Table       string
+	Columns     []string
+	IndexColumn string // Kolom met de blind index, of leeg
+	IndexSource string // Versleutelde kolom waar de blind index van berekend wordt
+}
+
+// encryptedTables zijn alle tabellen met kolommen die met de serializer fieldcrypt.SerializerName
+// worden opgeslagen; bij een nieuwe versleutelde kolom hoort die hier ook bij
+var encryptedTables = []encryptedTable{
+	{Table: "customers", Columns: []string{"email", "phone", "address"}, IndexColumn: "email_index", IndexSource: "email"},
+	{Table: "users", Columns: []string{"email"}, IndexColumn: "email_index", IndexSource: "email"},
+	{Table: "audit_logs", Columns: []string{"old_data", "new_data"}},
+	{Table: "invoices", Columns: []string{"customer_address", "customer_email"}},
+}
+
+// ReencryptStats geeft per tabel aan hoeveel rijen er bekeken en (opnieuw) versleuteld zijn
+type ReencryptStats struct {
+	Table   string
+	Rows    int64
+	Updated int64
+}
+
+// Reencrypt versleutelt alle versleutelde kolommen opnieuw met de primaire sleutel van de keyring:
+// waarden die nog niet versleuteld zijn of met een andere sleutel, en rijen waarvan de blind index niet
+// meer klopt. Elke batch is een eigen transactie, zodat er geen lange locks ontstaan en een
+// onderbroken run later verder kan. Met dryRun wordt alleen geteld wat er zou veranderen.
+// De versie en updated_at van de rijen blijven ongewijzigd: de gegevens zelf veranderen niet.
+func Reencrypt(db *gorm.DB, keyring *fieldcrypt.Keyring, batchSize int, dryRun bool, progress func(ReencryptStats)) ([]ReencryptStats, error) {
+	if !keyring.Enabled() {
+		return nil, fmt.Errorf("er zijn geen sleutels geconfigureerd")
+	}
+	if batchSize <= 0 {
+		batchSize = encryptionBatchSize
+	}
+
+	var results []ReencryptStats
+	for _, table := range encryptedTables {
+		stats := ReencryptStats{Table: table.Table}
+
+		var lastID int64
+		for {
+			// De batch wordt binnen de transactie gelezen en vergrendeld, zodat een wijziging via de API
+			// tussen lezen en schrijven niet wordt teruggedraaid; die wacht tot de batch klaar is
+			var rows []map[string]interface{}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				err := tx.Table(table.Table).Select(append([]string{"id"}, table.selectColumns()...)).
+					Where("id > ?", lastID).Order("id ASC").Limit(batchSize).
+					Clauses(clause.Locking{Strength: "UPDATE"}).Find(&rows).Error
+				if err != nil {
+					return err
+				}
+
+				for _, row := range rows {
+					lastID = toInt64(row["id"])
+					stats.Rows++
+
+					updates, err := table.reencryptRow(keyring, row)
+					if err != nil {
+						return fmt.Errorf("%s %d: %w", table.Table, lastID, err)
+					}
+					if len(updates) == 0 {
+						continue
+					}
+
+					stats.Updated++
+					if dryRun {
+						continue
+					}
+					if err := tx.Table(table.Table).Where("id = ?", lastID).Updates(updates).Error; err != nil {
+						return fmt.Errorf("%s %d: %w", table.Table, lastID, err)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return results, err
+			}
+			if len(rows) == 0 {
+				break
+			}
+
+			if progress != nil {
+				progress(stats)
+			}
+		}
+
+		results = append(results, stats)
+	}
+
+	return results, nil
+}
+
+// selectColumns geeft de kolommen die voor het opnieuw versleutelen gelezen worden
+func (t encryptedTable) selectColumns() []string {
+	if t.IndexColumn == "" {
+		return t.Columns
+	}
+	return append(append([]string{}, t.Columns...), t.IndexColumn)
+}
+
+// reencryptRow geeft de kolommen van een rij die opnieuw geschreven moeten worden
+func (t encryptedTable) reencryptRow(keyring *fieldcrypt.Keyring, row map[string]interface{}) (map[string]interface{}, error) {
+	updates := map[string]interface{}{}
+
+	for _, column := range t.Columns {
+		value, _ := row[column].(string)
+		plaintext, err := keyring.Decrypt(value)
+		if err != nil {
+			return nil, fmt.Errorf("kolom %s: %w", column, err)
+		}
+
+		if keyring.NeedsRotation(value) {
+			encrypted, err := keyring.Encrypt(plaintext)
+			if err != nil {
+				return nil, fmt.Errorf("kolom %s: %w", column, err)
+			}
+			updates[column] = encrypted
+		}
+
+		if column == t.IndexSource {
+			index := keyring.BlindIndex(fieldcrypt.PurposeEmail, plaintext)
+			if current, _ := row[t.IndexColumn].(string); current != index {
+				updates[t.IndexColumn] = index
+			}
+		}
+	}
+
+	return updates, nil
+}
+
+// toInt64 converteert een ID uit een map naar int64
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case uint:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	}
+	return 0
+}
+
+// backfillBlindIndexes vult de blind index van klanten (ook verwijderde) en gebruikers die er nog geen
+// hebben, zoals rijen van voor de versleuteling. Een gewijzigde sleutel voor blind indexes vraagt om Reencrypt.
+func backfillBlindIndexes(db *gorm.DB) error {
+	var customers []customerModel.Customer
+	err := db.Unscoped().Select("id", "email").Where("email_index = '' AND email <> ''").
+		FindInBatches(&customers, encryptionBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, customer := range customers {
+				index := fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, customer.Email)
+				if err := db.Unscoped().Model(&customerModel.Customer{ID: customer.ID}).UpdateColumn("email_index", index).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("klanten: %w", err)
+	}
+
+	var users []userModel.User
+	err = db.Select("id", "email").Where("email_index = '' AND email <> ''").
+		FindInBatches(&users, encryptionBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, user := range users {
+				index := fieldcrypt.BlindIndex(fieldcrypt.PurposeEmail, user.Email)
+				if err := db.Model(&userModel.User{ID: user.ID}).UpdateColumn("email_index", index).Error; err != nil {
+					return fmt.Errorf("gebruiker %d: %w", user.ID, err)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("gebruikers: %w", err)
+	}
+
+	return nil
+}
+
+// migrateSavedViews haalt de versleutelde velden uit de sortering van opgeslagen weergaven, omdat daar
+// niet meer op gesorteerd kan worden. Een filter dat meer dan de aanwezigheid van zo'n veld gebruikt
+// (bijv. address ~ utrecht) is niet om te zetten: de weergave geeft bij gebruik een 400 en wordt hier gemeld.
+func migrateSavedViews(db *gorm.DB) error {
+	var views []savedViewModel.SavedView
+	if err := db.Select("id", "name", "sort", "params").Find(&views).Error; err != nil {
+		return err
+	}
+
+	for _, view := range views {
+		var kept []string
+		for _, part := range strings.Split(view.Sort, ",") {
+			name := strings.TrimLeft(strings.TrimSpace(part), "+-")
+			if name != "" && !customerModel.IsEncryptedField(name) {
+				kept = append(kept, strings.TrimSpace(part))
+			}
+		}
+		if sort := strings.Join(kept, ","); sort != view.Sort {
+			if err := db.Model(&savedViewModel.SavedView{ID: view.ID}).UpdateColumn("sort", sort).Error; err != nil {
+				return fmt.Errorf("weergave %d: %w", view.ID, err)
+			}
+			log.Printf("Weergave %d (%s): sortering op versleutelde velden verwijderd, nu '%s'", view.ID, view.Name, sort)
+		}
+
+		if expression := view.Params["filter"]; expression != "" {
+			if node, err := filterExpr.Parse(expression); err == nil {
+				if err := customerModel.CheckEncryptedFilter(node); err != nil {
+					log.Printf("Waarschuwing: weergave %d (%s) heeft een filter dat niet meer werkt: %v", view.ID, view.Name, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -4,8 +4,21 @@ import (
 	"fmt"
 	"log"
 	"odomosml/config"
+	activityModel "odomosml/internal/activity/model"
+	appointmentModel "odomosml/internal/appointment/model"
+	attachmentModel "odomosml/internal/attachment/model"
 	auditModel "odomosml/internal/audit/model"
+	authModel "odomosml/internal/auth/model"
 	customerModel "odomosml/internal/customer/model"
+	importModel "odomosml/internal/customerimport/model"
+	customerStatusModel "odomosml/internal/customerstatus/model"
+	customFieldModel "odomosml/internal/customfield/model"
+	dealModel "odomosml/internal/deal/model"
+	invoiceModel "odomosml/internal/invoice/model"
+	productModel "odomosml/internal/product/model"
+	savedViewModel "odomosml/internal/savedview/model"
+	tagModel "odomosml/internal/tag/model"
+	taskModel "odomosml/internal/task/model"
 	userModel "odomosml/internal/user/model"
 	"time"
 
@@ -14,30 +27,12 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-// NewPostgresDB initialiseert een nieuwe PostgreSQL database connectie
+// NewPostgresDB initialiseert een nieuwe PostgreSQL database connectie en migreert het schema
 func NewPostgresDB(cfg *config.Config) (*gorm.DB, error) {
-	// Configureer GORM logger op basis van de applicatie log level
-	gormLogLevel := logger.Info
-	if cfg.IsProduction() {
-		gormLogLevel = logger.Error
-	}
-
-	// Maak connectie met de database
-	db, err := gorm.Open(postgres.Open(cfg.GetDSN()), &gorm.Config{
-		Logger: logger.Default.LogMode(gormLogLevel),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
-	}
-
-	// Configureer connection pool
-	sqlDB, err := db.DB()
+	db, err := Open(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get database connection: %w", err)
+		return nil, err
 	}
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
 
 	// Drop tabellen indien nodig (alleen in development!)
 	if cfg.DropTables {
@@ -55,19 +50,60 @@ func NewPostgresDB(cfg *config.Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
 
+	// Vul de blind indexes van klanten en gebruikers van voor de versleuteling
+	if err := backfillBlindIndexes(db); err != nil {
+		log.Printf("Waarschuwing: Kon blind indexes niet bijwerken: %v", err)
+	}
+	if err := migrateSavedViews(db); err != nil {
+		log.Printf("Waarschuwing: Kon opgeslagen weergaven niet bijwerken: %v", err)
+	}
+
 	// Maak admin gebruiker aan indien nodig
 	if err := ensureAdminExists(db); err != nil {
 		return nil, fmt.Errorf("failed to ensure admin exists: %w", err)
 	}
 
+	// Maak de standaard klantstatussen aan indien nodig
+	if err := ensureDefaultStatuses(db); err != nil {
+		return nil, fmt.Errorf("failed to ensure default statuses: %w", err)
+	}
+
 	log.Println("Database initialized successfully")
 	return db, nil
 }
 
+// Open maakt een connectie met de database, zonder het schema te migreren
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	// Configureer GORM logger op basis van de applicatie log level
+	gormLogLevel := logger.Info
+	if cfg.IsProduction() {
+		gormLogLevel = logger.Error
+	}
+
+	// Maak connectie met de database
+	db, err := gorm.Open(postgres.Open(cfg.GetDSN()), &gorm.Config{
+		Logger: logger.Default.LogMode(gormLogLevel),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Configureer connection pool
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	return db, nil
+}
+
 // dropTables verwijdert alle tabellen uit de database
 func dropTables(db *gorm.DB) error {
-	log.Println("Dropping tables: audit_logs, customers, users")
-	if err := db.Migrator().DropTable(&auditModel.AuditLog{}, "customers", &userModel.User{}); err != nil {
+	log.Println("Dropping tables: login_events, audit_logs, saved_view_defaults, saved_views, import_jobs, invoice_lines, invoices, document_sequences, price_agreements, products, calendar_tokens, appointment_attendees, appointments, tasks, deals, attachments, activities, customer_status_changes, customer_tags, customers, customer_statuses, tags, custom_field_definitions, users")
+	if err := db.Migrator().DropTable(&authModel.LoginEvent{}, &auditModel.AuditLog{}, &savedViewModel.DefaultView{}, &savedViewModel.SavedView{}, &importModel.ImportJob{}, &invoiceModel.InvoiceLine{}, &invoiceModel.Invoice{}, &invoiceModel.DocumentSequence{}, &productModel.PriceAgreement{}, &productModel.Product{}, &appointmentModel.CalendarToken{}, &appointmentModel.Attendee{}, &appointmentModel.Appointment{}, &taskModel.Task{}, &dealModel.Deal{}, &attachmentModel.Attachment{}, &activityModel.Activity{}, &customerModel.StatusChange{}, "customer_tags", "customers", &customerStatusModel.StatusDefinition{}, &tagModel.Tag{}, &customFieldModel.CustomFieldDefinition{}, &userModel.User{}); err != nil {
 		return fmt.Errorf("failed to drop tables: %w", err)
 	}
 	return nil
@@ -81,8 +117,19 @@ func createIndexes(db *gorm.DB) error {
 		// Ga door, dit is niet kritiek
 	}
 
-	// Indexen voor User model
-	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);").Error; err != nil {
+	// E-mail, telefoon en adres worden versleuteld opgeslagen; indexen op die kolommen zijn zinloos.
+	// Opzoeken en uniciteit van e-mailadressen gaan via de blind index in email_index.
+	for _, index := range []string{"idx_users_email", "idx_users_email_trgm", "idx_customers_email", "idx_customers_email_lower", "idx_customers_email_trgm", "idx_customers_address_trgm"} {
+		if err := db.Exec("DROP INDEX IF EXISTS " + index + ";").Error; err != nil {
+			return err
+		}
+	}
+	if err := db.Exec("ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;").Error; err != nil {
+		return err
+	}
+
+	// Indexen voor User model; gebruikers zonder blind index (nog niet bijgewerkt) tellen niet mee voor de uniciteit
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_index ON users(email_index) WHERE email_index <> '';").Error; err != nil {
 		return err
 	}
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);").Error; err != nil {
@@ -93,33 +140,57 @@ func createIndexes(db *gorm.DB) error {
 	}
 
 	// Trigram indexen voor User model (voor ILIKE zoekopdrachten)
-	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING gin (email gin_trgm_ops);").Error; err != nil {
-		log.Printf("Waarschuwing: Kon trigram index voor users.email niet aanmaken: %v", err)
-		// Ga door, dit is niet kritiek
-	}
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (username gin_trgm_ops);").Error; err != nil {
 		log.Printf("Waarschuwing: Kon trigram index voor users.username niet aanmaken: %v", err)
 		// Ga door, dit is niet kritiek
 	}
 
 	// Indexen voor Customer model
-	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_customers_email ON customers(email);").Error; err != nil {
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_customers_name ON customers(name);").Error; err != nil {
 		return err
 	}
-	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_customers_name ON customers(name);").Error; err != nil {
+
+	// Hiërarchie van klanten: een verwijderde moederklant maakt de klanten eronder zelfstandig
+	if err := db.Exec(`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_customers_parent') THEN
+			ALTER TABLE customers ADD CONSTRAINT fk_customers_parent
+				FOREIGN KEY (parent_id) REFERENCES customers(id) ON DELETE SET NULL;
+		END IF;
+	END $$;`).Error; err != nil {
 		return err
 	}
 
 	// Trigram indexen voor Customer model (voor ILIKE zoekopdrachten)
-	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_customers_email_trgm ON customers USING gin (email gin_trgm_ops);").Error; err != nil {
-		log.Printf("Waarschuwing: Kon trigram index voor customers.email niet aanmaken: %v", err)
-		// Ga door, dit is niet kritiek
-	}
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_customers_name_trgm ON customers USING gin (name gin_trgm_ops);").Error; err != nil {
 		log.Printf("Waarschuwing: Kon trigram index voor customers.name niet aanmaken: %v", err)
 		// Ga door, dit is niet kritiek
 	}
 
+	// Full-text indexen voor de zoekkolommen, en trigram indexen voor zoeken met typefouten
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_customers_search ON customers USING gin (search_vector);").Error; err != nil {
+		log.Printf("Waarschuwing: Kon zoekindex voor customers niet aanmaken: %v", err)
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_activities_search ON activities USING gin (search_vector);").Error; err != nil {
+		log.Printf("Waarschuwing: Kon zoekindex voor activities niet aanmaken: %v", err)
+	}
+
+	// Tagnamen zijn uniek zonder op hoofdletters te letten, net als het zoeken op naam; de oude
+	// hoofdlettergevoelige index van GORM gaat weg
+	if err := mergeCaseDuplicateTags(db); err != nil {
+		return err
+	}
+	if err := db.Exec("DROP INDEX IF EXISTS idx_tags_name;").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name_lower ON tags(LOWER(name));").Error; err != nil {
+		return err
+	}
+
+	// Index voor het filteren van klanten op tag (de primary key dekt customer_id al)
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_customer_tags_tag_id ON customer_tags(tag_id);").Error; err != nil {
+		return err
+	}
+
 	// Indexen voor AuditLog model
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs(user_id);").Error; err != nil {
 		return err
@@ -133,10 +204,102 @@ func createIndexes(db *gorm.DB) error {
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);").Error; err != nil {
 		return err
 	}
+	// Index voor keyset paginering in de standaard volgorde (nieuwste eerst, ID als tiebreaker)
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at_id ON audit_logs(created_at, id);").Error; err != nil {
+		return err
+	}
+
+	// Samengestelde indexen voor de tijdlijn van een klant
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_logs_entity_timeline ON audit_logs(entity_type, entity_id, created_at DESC, id DESC);").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_activities_customer_timeline ON activities(customer_id, occurred_at DESC, id DESC);").Error; err != nil {
+		return err
+	}
+
+	// Prijsafspraken van een klant voor een product, voor het bepalen van de geldende prijs
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_price_agreements_lookup ON price_agreements(product_id, customer_id, valid_from);").Error; err != nil {
+		return err
+	}
+
+	// Herinneringen die nog verstuurd moeten worden, voor de reminder job
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_pending_reminders ON tasks(remind_at) WHERE reminder_sent_at IS NULL;").Error; err != nil {
+		return err
+	}
+
+	// Elk nummer wordt per soort document één keer uitgegeven; concepten hebben nog geen nummer
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_type_number ON invoices(type, number) WHERE number <> '';").Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// mergeCaseDuplicateTags voegt tags die alleen in hoofdletters verschillen (van voor de unieke index op
+// LOWER(name)) samen in de oudste, zodat de index aangemaakt kan worden
+func mergeCaseDuplicateTags(db *gorm.DB) error {
+	const duplicates = `SELECT id, MIN(id) OVER (PARTITION BY LOWER(name)) AS keep_id FROM tags`
+
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM (" + duplicates + ") d WHERE d.id <> d.keep_id").Scan(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO customer_tags (customer_id, tag_id)
+			SELECT ct.customer_id, d.keep_id FROM customer_tags ct JOIN (` + duplicates + `) d ON d.id = ct.tag_id
+			WHERE d.id <> d.keep_id
+			ON CONFLICT DO NOTHING`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM customer_tags WHERE tag_id IN (
+			SELECT d.id FROM (` + duplicates + `) d WHERE d.id <> d.keep_id)`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DELETE FROM tags WHERE id IN (
+			SELECT d.id FROM (` + duplicates + `) d WHERE d.id <> d.keep_id)`).Error
+	})
+	if err != nil {
+		return err
+	}
 
+	log.Printf("%d tags die alleen in hoofdletters verschilden zijn samengevoegd", count)
 	return nil
 }
 
+// createSearchColumns voegt de tsvector kolommen voor full-text zoeken toe. Het zijn generated columns,
+// zodat Postgres ze bij elke insert en update bijwerkt. De Nederlandse configuratie zorgt ervoor dat
+// woordvormen (bijv. "bakkerij" en "bakkerijen") elkaar vinden; e-mailadressen en getallen blijven heel.
+func createSearchColumns(db *gorm.DB) error {
+	// E-mail, telefoon en adres zijn versleuteld en horen niet (meer) in de zoekkolom; een zoekkolom
+	// van voor de versleuteling wordt opnieuw aangemaakt
+	if err := db.Exec(`DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'customers'
+			AND column_name = 'search_vector' AND generation_expression LIKE '%email%') THEN
+			ALTER TABLE customers DROP COLUMN search_vector;
+		END IF;
+	END $$;`).Error; err != nil {
+		return err
+	}
+
+	// Klanten: de naam weegt het zwaarst, daarna KvK en BTW-nummer, dan de vrije velden
+	if err := db.Exec(`ALTER TABLE customers ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('dutch', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('dutch', kvk_number || ' ' || vat_number), 'B') ||
+		setweight(jsonb_to_tsvector('dutch', custom_fields, '["string", "numeric"]'), 'C')
+	) STORED;`).Error; err != nil {
+		return err
+	}
+
+	// Activiteiten: de tekst van notities, gesprekken, afspraken en e-mails
+	return db.Exec(`ALTER TABLE activities ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		to_tsvector('dutch', coalesce(body, ''))
+	) STORED;`).Error
+}
+
 // migrateSchema migreert het database schema
 func migrateSchema(db *gorm.DB) error {
 	log.Println("Migrating database schema...")
@@ -144,12 +307,38 @@ func migrateSchema(db *gorm.DB) error {
 	// Migreer modellen
 	if err := db.AutoMigrate(
 		&userModel.User{},
+		&tagModel.Tag{},
+		&customFieldModel.CustomFieldDefinition{},
+		&customerStatusModel.StatusDefinition{},
 		&customerModel.Customer{},
+		&customerModel.StatusChange{},
+		&activityModel.Activity{},
+		&attachmentModel.Attachment{},
+		&dealModel.Deal{},
+		&taskModel.Task{},
+		&appointmentModel.Appointment{},
+		&appointmentModel.Attendee{},
+		&appointmentModel.CalendarToken{},
+		&productModel.Product{},
+		&productModel.PriceAgreement{},
+		&invoiceModel.Invoice{},
+		&invoiceModel.InvoiceLine{},
+		&invoiceModel.DocumentSequence{},
+		&importModel.ImportJob{},
+		&savedViewModel.SavedView{},
+		&savedViewModel.DefaultView{},
 		&auditModel.AuditLog{},
+		&authModel.LoginEvent{},
 	); err != nil {
 		return err
 	}
 
+	// Maak de zoekkolommen aan (na de tabellen, voor de indexen)
+	if err := createSearchColumns(db); err != nil {
+		log.Printf("Waarschuwing: Kon zoekkolommen niet aanmaken: %v", err)
+		// Ga door, zoeken valt dan terug op een fout in plaats van resultaten
+	}
+
 	// Maak indexen aan
 	if err := createIndexes(db); err != nil {
 		log.Printf("Waarschuwing: Kon sommige indexen niet aanmaken: %v", err)
@@ -179,3 +368,20 @@ func ensureAdminExists(db *gorm.DB) error {
 	}
 	return nil
 }
+
+// ensureDefaultStatuses maakt de standaard workflow voor klantstatussen aan als er nog geen statussen zijn.
+// Bestaande klanten houden een lege status en mogen vandaar naar elke status.
+func ensureDefaultStatuses(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&customerStatusModel.StatusDefinition{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		log.Println("Creating default customer statuses...")
+		statuses := customerStatusModel.DefaultStatuses()
+		if err := db.Create(&statuses).Error; err != nil {
+			return fmt.Errorf("failed to create default customer statuses: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Ondersteunde exportformaten
+const (
+	FormatCSV    = "csv"
+	FormatXLSX   = "xlsx"
+	FormatNDJSON = "ndjson"
+)
+
+// Writer schrijft rijen met een vaste set kolommen naar een exportformaat
+type Writer interface {
+	// WriteHeader schrijft de kolomnamen; moet als eerste aangeroepen worden
+	WriteHeader(columns []string) error
+	// WriteRow schrijft één rij met een waarde per kolom
+	WriteRow(values []interface{}) error
+	// Close rondt de export af en schrijft eventueel gebufferde data weg
+	Close() error
+}
+
+// NewWriter maakt een Writer voor het opgegeven formaat
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	default:
+		return nil, fmt.Errorf("onbekend exportformaat '%s', gebruik csv, xlsx of ndjson", format)
+	}
+}
+
+// ContentType geeft het MIME type van een exportformaat terug
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// csvWriter schrijft rijen als CSV
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) WriteHeader(columns []string) error {
+	return w.writer.Write(columns)
+}
+
+func (w *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = formatValue(value)
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// ndjsonWriter schrijft elke rij als JSON object op een eigen regel
+type ndjsonWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+	columns  []string
+}
+
+func (w *ndjsonWriter) WriteHeader(columns []string) error {
+	w.columns = columns
+	return nil
+}
+
+func (w *ndjsonWriter) WriteRow(values []interface{}) error {
+	// Een geordende lijst van key/value paren houdt de kolomvolgorde aan
+	object := make(orderedObject, len(values))
+	for i, value := range values {
+		object[i] = keyValue{key: w.columns[i], value: value}
+	}
+	return w.encoder.Encode(object)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buffered.Flush()
+}
+
+// xlsxWriter schrijft rijen naar een XLSX werkblad. De rijen worden door excelize naar een
+// tijdelijk bestand geschreven; het werkboek zelf wordt bij Close in één keer weggeschreven.
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXWriter(out io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &xlsxWriter{out: out, file: file, stream: stream}, nil
+}
+
+func (w *xlsxWriter) WriteHeader(columns []string) error {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = column
+	}
+	return w.WriteRow(values)
+}
+
+func (w *xlsxWriter) WriteRow(values []interface{}) error {
+	w.row++
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+
+	row := make([]interface{}, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+			row[i] = nil
+		case string, bool, int, int64, uint, float64:
+			row[i] = v
+		default:
+			row[i] = formatValue(v)
+		}
+	}
+	return w.stream.SetRow(cell, row)
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	return w.file.Write(w.out)
+}
+
+// formatValue zet een waarde om naar tekst voor formaten zonder types
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		return fmt.Sprintf("%g", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// keyValue is een veld van een orderedObject
+type keyValue struct {
+	key   string
+	value interface{}
+}
+
+// orderedObject is een JSON object dat de volgorde van de velden behoudt
+type orderedObject []keyValue
+
+// MarshalJSON implementeert json.Marshaler
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, kv := range o {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(kv.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(kv.value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, key...)
+		buf = append(buf, ':')
+		buf = append(buf, value...)
+	}
+	return append(buf, '}'), nil
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	testColumns = []string{"id", "name", "active", "score", "created_at", "cf.regio"}
+	testRows    = [][]interface{}{
+		{uint(1), "Bakkerij Jansen, Zonen & Co", true, 7.5, time.Date(2024, 2, 25, 20, 30, 0, 0, time.UTC), "noord"},
+		{uint(2), "Café \"'t Hoekje\"", false, float64(12), time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), nil},
+	}
+)
+
+// writeAll schrijft de testrijen in een formaat
+func writeAll(t *testing.T, format string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatalf("NewWriter(%s) gaf fout: %v", format, err)
+	}
+	if err := writer.WriteHeader(testColumns); err != nil {
+		t.Fatalf("WriteHeader gaf fout: %v", err)
+	}
+	for _, row := range testRows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow gaf fout: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close gaf fout: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	want := "id,name,active,score,created_at,cf.regio\n" +
+		"1,\"Bakkerij Jansen, Zonen & Co\",true,7.5,2024-02-25T20:30:00Z,noord\n" +
+		"2,\"Café \"\"'t Hoekje\"\"\",false,12,2024-03-01T09:00:00Z,\n"
+	if got := string(writeAll(t, FormatCSV)); got != want {
+		t.Errorf("CSV =\n%s\nverwacht\n%s", got, want)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	// De velden staan in de volgorde van de kolommen, niet alfabetisch
+	want := `{"id":1,"name":"Bakkerij Jansen, Zonen \u0026 Co","active":true,"score":7.5,"created_at":"2024-02-25T20:30:00Z","cf.regio":"noord"}` + "\n" +
+		`{"id":2,"name":"Café \"'t Hoekje\"","active":false,"score":12,"created_at":"2024-03-01T09:00:00Z","cf.regio":null}` + "\n"
+	if got := string(writeAll(t, FormatNDJSON)); got != want {
+		t.Errorf("NDJSON =\n%s\nverwacht\n%s", got, want)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	file, err := excelize.OpenReader(bytes.NewReader(writeAll(t, FormatXLSX)))
+	if err != nil {
+		t.Fatalf("XLSX niet te openen: %v", err)
+	}
+	defer file.Close()
+
+	rows, err := file.GetRows("Sheet1")
+	if err != nil {
+		t.Fatalf("GetRows gaf fout: %v", err)
+	}
+	want := [][]string{
+		testColumns,
+		{"1", "Bakkerij Jansen, Zonen & Co", "TRUE", "7.5", "2024-02-25T20:30:00Z", "noord"},
+		{"2", "Café \"'t Hoekje\"", "FALSE", "12", "2024-03-01T09:00:00Z"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("XLSX rijen = %q, verwacht %q", rows, want)
+	}
+
+	// Getallen blijven getallen, zodat ze in Excel op te tellen zijn
+	if cellType, _ := file.GetCellType("Sheet1", "D2"); cellType == excelize.CellTypeSharedString || cellType == excelize.CellTypeInlineString {
+		t.Errorf("score is als tekst opgeslagen (%v)", cellType)
+	}
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	if _, err := NewWriter("pdf", &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "onbekend exportformaat 'pdf'") {
+		t.Errorf("NewWriter(pdf) gaf %v", err)
+	}
+	if got := ContentType("pdf"); got != "application/octet-stream" {
+		t.Errorf("ContentType(pdf) = %s", got)
+	}
+}
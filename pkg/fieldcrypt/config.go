@@ -0,0 +1,98 @@
+package fieldcrypt
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"odomosml/config"
+	"os"
+	"strings"
+)
+
+// New maakt de Keyring uit de configuratie. De KEK's komen uit ENCRYPTION_KEYS_FILE (één sleutel per
+// regel) en/of ENCRYPTION_KEYS (komma gescheiden), elk als <sleutel ID>:<base64 sleutel van 32 bytes>.
+// De primaire sleutel is ENCRYPTION_PRIMARY_KEY_ID, of anders de laatst genoemde sleutel.
+// Zonder sleutels versleutelt de Keyring niets.
+func New(cfg *config.Config) (*Keyring, error) {
+	var entries []string
+	if cfg.EncryptionKeysFile != "" {
+		file, err := os.Open(cfg.EncryptionKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("kan sleutelbestand niet openen: %w", err)
+		}
+		defer file.Close()
+
+		lines, err := readKeyLines(file)
+		if err != nil {
+			return nil, fmt.Errorf("kan sleutelbestand niet lezen: %w", err)
+		}
+		entries = append(entries, lines...)
+	}
+	for _, entry := range strings.Split(cfg.EncryptionKeys, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	keys := make(map[string][]byte, len(entries))
+	primary := cfg.EncryptionPrimaryKeyID
+	for _, entry := range entries {
+		id, encoded, found := strings.Cut(entry, ":")
+		id = strings.TrimSpace(id)
+		if !found || id == "" {
+			return nil, fmt.Errorf("sleutel moet de vorm <sleutel ID>:<base64 sleutel> hebben")
+		}
+		if _, exists := keys[id]; exists {
+			return nil, fmt.Errorf("sleutel '%s' komt dubbel voor", id)
+		}
+
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("sleutel '%s': %w", id, err)
+		}
+		keys[id] = key
+		if cfg.EncryptionPrimaryKeyID == "" {
+			primary = id
+		}
+	}
+
+	var indexKey []byte
+	if cfg.EncryptionIndexKey != "" {
+		key, err := decodeKey(cfg.EncryptionIndexKey)
+		if err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_INDEX_KEY: %w", err)
+		}
+		indexKey = key
+	}
+
+	return NewKeyring(keys, primary, indexKey)
+}
+
+// readKeyLines leest de sleutels uit een sleutelbestand; lege regels en regels met # worden overgeslagen
+func readKeyLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// decodeKey decodeert een base64 sleutel (standaard of URL alfabet, met of zonder padding)
+func decodeKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := encoding.DecodeString(encoded); err == nil {
+			if len(key) != KeySize {
+				return nil, fmt.Errorf("een sleutel moet %d bytes zijn, niet %d", KeySize, len(key))
+			}
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("sleutel is geen geldige base64")
+}
@@ -0,0 +1,274 @@
+// Package fieldcrypt versleutelt losse velden met persoonsgegevens voordat ze in de database komen.
+//
+// Elke waarde krijgt een eigen willekeurige datasleutel (AES-256-GCM); die datasleutel wordt met een
+// key-encryption key (KEK) uit de configuratie versleuteld en naast de waarde opgeslagen (envelope
+// encryption). Een versleutelde waarde ziet er zo uit:
+//
+//	enc:v1:<sleutel ID>:<versleutelde datasleutel>:<versleutelde waarde>
+//
+// Nieuwe waarden worden met de primaire KEK versleuteld; oudere KEK's blijven nodig om bestaande
+// waarden te lezen tot alles opnieuw versleuteld is. Waarden zonder prefix zijn nog niet versleuteld
+// en worden ongewijzigd gelezen.
+//
+// Omdat versleutelde waarden niet met elkaar te vergelijken zijn, krijgen velden waarop gezocht wordt
+// een blind index: een HMAC-SHA256 van de genormaliseerde waarde met een aparte sleutel.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Prefix staat voor elke versleutelde waarde
+const Prefix = "enc:v1:"
+
+// KeySize is de lengte van een KEK en van de sleutel voor blind indexes in bytes (AES-256)
+const KeySize = 32
+
+// PurposeEmail is het doel van de blind index op e-mailadressen
+const PurposeEmail = "email"
+
+// defaultIndexKey is de sleutel voor blind indexes zonder versleuteling (development): de gegevens staan
+// dan onversleuteld in de database. Met een bekende sleutel is een blind index niet meer dan een hash,
+// dus met versleuteling is een eigen sleutel verplicht.
+var defaultIndexKey = sha256.Sum256([]byte("odomosml-blind-index"))
+
+// keyIDPattern bepaalt welke sleutel ID's zijn toegestaan; een dubbele punt zou het formaat breken
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+// ErrNoKeys wordt teruggegeven bij het lezen van een versleutelde waarde zonder geconfigureerde sleutels
+var ErrNoKeys = errors.New("versleutelde waarde gevonden, maar er zijn geen sleutels geconfigureerd")
+
+// ErrNoIndexKey betekent dat er sleutels zijn ingesteld maar geen sleutel voor blind indexes
+var ErrNoIndexKey = errors.New("ENCRYPTION_INDEX_KEY is verplicht als er sleutels zijn ingesteld")
+
+// Keyring bevat de KEK's per sleutel ID, welke daarvan nieuwe waarden versleutelt en de sleutel voor
+// blind indexes. Een Keyring zonder KEK's versleutelt niets.
+type Keyring struct {
+	primary  string
+	keys     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewKeyring maakt een Keyring. keys bevat de KEK's per sleutel ID (elk KeySize bytes), primary het ID
+// van de sleutel voor nieuwe waarden. Zonder keys versleutelt de Keyring niets en mag indexKey leeg zijn;
+// met keys is een indexKey verplicht, anders zijn de blind indexes met een woordenlijst terug te rekenen.
+func NewKeyring(keys map[string][]byte, primary string, indexKey []byte) (*Keyring, error) {
+	keyring := &Keyring{keys: map[string]cipher.AEAD{}, indexKey: indexKey}
+	if len(indexKey) == 0 {
+		if len(keys) > 0 {
+			return nil, ErrNoIndexKey
+		}
+		keyring.indexKey = defaultIndexKey[:]
+	} else if len(indexKey) < KeySize {
+		return nil, fmt.Errorf("de sleutel voor blind indexes moet minstens %d bytes zijn", KeySize)
+	}
+
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("ongeldig sleutel ID '%s': alleen letters, cijfers, punt, streepje en underscore", id)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("sleutel '%s': %w", id, err)
+		}
+		keyring.keys[id] = aead
+	}
+
+	if len(keys) > 0 {
+		if _, ok := keys[primary]; !ok {
+			return nil, fmt.Errorf("primaire sleutel '%s' bestaat niet", primary)
+		}
+		keyring.primary = primary
+	}
+
+	return keyring, nil
+}
+
+// newAEAD maakt een AES-256-GCM cipher voor een sleutel
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("een sleutel moet %d bytes zijn, niet %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Enabled geeft aan of de Keyring waarden versleutelt
+func (k *Keyring) Enabled() bool {
+	return k.primary != ""
+}
+
+// PrimaryKeyID geeft het ID van de sleutel waarmee nieuwe waarden versleuteld worden
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// Encrypt versleutelt een waarde met de primaire sleutel. Een lege waarde blijft leeg, en zonder
+// sleutels wordt de waarde ongewijzigd teruggegeven.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || !k.Enabled() {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(data, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	return Prefix + k.primary + ":" + encoding.EncodeToString(wrappedKey) + ":" + encoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt ontsleutelt een waarde. Waarden zonder prefix zijn (nog) niet versleuteld en worden
+// ongewijzigd teruggegeven.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if len(k.keys) == 0 {
+		return "", ErrNoKeys
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("ongeldige versleutelde waarde")
+	}
+	kek, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("onbekende sleutel '%s'", parts[0])
+	}
+
+	wrappedKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("ongeldige versleutelde waarde")
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("ongeldige versleutelde waarde")
+	}
+
+	dataKey, err := open(kek, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("kan datasleutel niet ontsleutelen met sleutel '%s'", parts[0])
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(data, ciphertext)
+	if err != nil {
+		return "", errors.New("kan waarde niet ontsleutelen")
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsRotation geeft aan of een waarde (opnieuw) versleuteld moet worden: omdat ze nog niet
+// versleuteld is, of met een andere dan de primaire sleutel
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" || !k.Enabled() {
+		return false
+	}
+	keyID, ok := KeyID(value)
+	return !ok || keyID != k.primary
+}
+
+// BlindIndex berekent de blind index van een waarde voor een doel (bijv. PurposeEmail). De waarde wordt
+// eerst genormaliseerd (spaties eromheen weg, kleine letters), zodat zoeken hoofdletterongevoelig is.
+// Een lege waarde heeft een lege index.
+func (k *Keyring) BlindIndex(purpose, value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted geeft aan of een waarde versleuteld is
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// KeyID geeft het ID van de sleutel waarmee een waarde versleuteld is
+func KeyID(value string) (string, bool) {
+	if !IsEncrypted(value) {
+		return "", false
+	}
+	keyID, _, found := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	return keyID, found
+}
+
+// seal versleutelt met een willekeurige nonce, die voor de ciphertext komt
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open ontsleutelt een waarde van seal
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("te kort")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
+
+// defaultKeyring is de Keyring van de GORM serializer en de blind indexes in de modellen
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring = &Keyring{keys: map[string]cipher.AEAD{}, indexKey: defaultIndexKey[:]}
+)
+
+// SetDefault stelt de Keyring in die de modellen gebruiken; aan te roepen bij het opstarten,
+// voordat de database gebruikt wordt
+func SetDefault(keyring *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = keyring
+}
+
+// Default geeft de Keyring die de modellen gebruiken
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
+
+// BlindIndex berekent een blind index met de standaard Keyring
+func BlindIndex(purpose, value string) string {
+	return Default().BlindIndex(purpose, value)
+}
@@ -0,0 +1,267 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey maakt een sleutel van KeySize bytes gevuld met b
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+// newTestKeyring maakt een Keyring met de sleutels op ID en primary als primaire sleutel
+func newTestKeyring(t *testing.T, primary string, ids ...string) *Keyring {
+	t.Helper()
+	keys := make(map[string][]byte, len(ids))
+	for i, id := range ids {
+		keys[id] = testKey(byte(i + 1))
+	}
+	keyring, err := NewKeyring(keys, primary, testKey(0xff))
+	if err != nil {
+		t.Fatalf("NewKeyring gaf fout: %v", err)
+	}
+	return keyring
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	keyring := newTestKeyring(t, "k1", "k1")
+
+	for _, plaintext := range []string{"a", "info@bakkerijjansen.nl", "Café 't Hoekje: één, twee; drie", strings.Repeat("x", 10000)} {
+		encrypted, err := keyring.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q) gaf fout: %v", plaintext, err)
+		}
+		// Een enkel teken kan toevallig in de base64 staan
+		if !IsEncrypted(encrypted) || (len(plaintext) > 1 && strings.Contains(encrypted, plaintext)) {
+			t.Errorf("Encrypt(%q) = %q is niet versleuteld", plaintext, encrypted)
+		}
+		if keyID, ok := KeyID(encrypted); !ok || keyID != "k1" {
+			t.Errorf("KeyID = %q, %v; verwacht k1", keyID, ok)
+		}
+
+		decrypted, err := keyring.Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("Decrypt gaf fout: %v", err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("Decrypt = %q, verwacht %q", decrypted, plaintext)
+		}
+	}
+}
+
+func TestEncryptIsRandom(t *testing.T) {
+	keyring := newTestKeyring(t, "k1", "k1")
+
+	first, _ := keyring.Encrypt("Jansen")
+	second, _ := keyring.Encrypt("Jansen")
+	if first == second {
+		t.Error("dezelfde waarde is twee keer gelijk versleuteld")
+	}
+}
+
+func TestEncryptPassthrough(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		value   string
+	}{
+		{"lege waarde", newTestKeyring(t, "k1", "k1"), ""},
+		{"zonder sleutels", newTestKeyring(t, ""), "Jansen"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := tt.keyring.Encrypt(tt.value)
+			if err != nil || encrypted != tt.value {
+				t.Errorf("Encrypt(%q) = %q, %v; verwacht de waarde ongewijzigd", tt.value, encrypted, err)
+			}
+		})
+	}
+
+	// Een waarde zonder prefix is nog niet versleuteld en wordt ongewijzigd gelezen
+	for _, keyring := range []*Keyring{newTestKeyring(t, "k1", "k1"), newTestKeyring(t, "")} {
+		if decrypted, err := keyring.Decrypt("Jansen"); err != nil || decrypted != "Jansen" {
+			t.Errorf("Decrypt(Jansen) = %q, %v", decrypted, err)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := newTestKeyring(t, "2023", "2023")
+	encrypted, err := old.Encrypt("info@jansen.nl")
+	if err != nil {
+		t.Fatalf("Encrypt gaf fout: %v", err)
+	}
+
+	rotated := newTestKeyring(t, "2024", "2023", "2024")
+	if rotated.PrimaryKeyID() != "2024" {
+		t.Errorf("PrimaryKeyID = %s, verwacht 2024", rotated.PrimaryKeyID())
+	}
+	if decrypted, err := rotated.Decrypt(encrypted); err != nil || decrypted != "info@jansen.nl" {
+		t.Fatalf("Decrypt met de oude sleutel = %q, %v", decrypted, err)
+	}
+
+	reencrypted, err := rotated.Encrypt("info@jansen.nl")
+	if err != nil {
+		t.Fatalf("Encrypt gaf fout: %v", err)
+	}
+	if keyID, _ := KeyID(reencrypted); keyID != "2024" {
+		t.Errorf("opnieuw versleuteld met %s, verwacht 2024", keyID)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"oude sleutel", encrypted, true},
+		{"primaire sleutel", reencrypted, false},
+		{"niet versleuteld", "info@jansen.nl", true},
+		{"leeg", "", false},
+	}
+	for _, tt := range tests {
+		if got := rotated.NeedsRotation(tt.value); got != tt.want {
+			t.Errorf("NeedsRotation(%s) = %v, verwacht %v", tt.name, got, tt.want)
+		}
+	}
+	if newTestKeyring(t, "").NeedsRotation("info@jansen.nl") {
+		t.Error("NeedsRotation zonder sleutels gaf true")
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	keyring := newTestKeyring(t, "k1", "k1")
+	encrypted, err := keyring.Encrypt("Jansen")
+	if err != nil {
+		t.Fatalf("Encrypt gaf fout: %v", err)
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, Prefix), ":")
+
+	// tamper verandert het eerste teken van een base64 deel, zodat het nog steeds geldige base64 is
+	tamper := func(part string) string {
+		if part[0] == 'A' {
+			return "B" + part[1:]
+		}
+		return "A" + part[1:]
+	}
+
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		value   string
+		wantErr error
+	}{
+		{"zonder sleutels", newTestKeyring(t, ""), encrypted, ErrNoKeys},
+		{"onbekende sleutel", newTestKeyring(t, "k2", "k2"), encrypted, nil},
+		{"andere sleutel met hetzelfde ID", func() *Keyring {
+			other, err := NewKeyring(map[string][]byte{"k1": testKey(0x42)}, "k1", testKey(0xff))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return other
+		}(), encrypted, nil},
+		{"datasleutel gewijzigd", keyring, Prefix + "k1:" + tamper(parts[1]) + ":" + parts[2], nil},
+		{"waarde gewijzigd", keyring, Prefix + "k1:" + parts[1] + ":" + tamper(parts[2]), nil},
+		{"delen verwisseld", keyring, Prefix + "k1:" + parts[2] + ":" + parts[1], nil},
+		{"ontbrekend deel", keyring, Prefix + "k1:" + parts[1], nil},
+		{"geen base64", keyring, Prefix + "k1:!!:" + parts[2], nil},
+		{"te kort", keyring, Prefix + "k1:AA:AA", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decrypted, err := tt.keyring.Decrypt(tt.value)
+			if err == nil {
+				t.Fatalf("Decrypt = %q, verwacht een fout", decrypted)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decrypt gaf %v, verwacht %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewKeyringErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     map[string][]byte
+		primary  string
+		indexKey []byte
+		wantErr  error
+		want     string
+	}{
+		{"geen sleutel voor blind indexes", map[string][]byte{"k1": testKey(1)}, "k1", nil, ErrNoIndexKey, ""},
+		{"te korte sleutel voor blind indexes", nil, "", testKey(1)[:16], nil, "blind indexes"},
+		{"sleutel ID met dubbele punt", map[string][]byte{"k:1": testKey(1)}, "k:1", testKey(0xff), nil, "ongeldig sleutel ID"},
+		{"leeg sleutel ID", map[string][]byte{"": testKey(1)}, "", testKey(0xff), nil, "ongeldig sleutel ID"},
+		{"te korte sleutel", map[string][]byte{"k1": testKey(1)[:16]}, "k1", testKey(0xff), nil, "32 bytes"},
+		{"onbekende primaire sleutel", map[string][]byte{"k1": testKey(1)}, "k2", testKey(0xff), nil, "primaire sleutel"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.keys, tt.primary, tt.indexKey)
+			if err == nil {
+				t.Fatal("NewKeyring gaf geen fout")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewKeyring gaf %v, verwacht %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewKeyring gaf %v, verwacht een fout met %q", err, tt.want)
+			}
+		})
+	}
+
+	// Zonder sleutels is een sleutel voor blind indexes niet nodig
+	keyring, err := NewKeyring(nil, "", nil)
+	if err != nil || keyring.Enabled() {
+		t.Errorf("NewKeyring zonder sleutels = %v, %v; verwacht een Keyring die niets versleutelt", keyring, err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	keyring := newTestKeyring(t, "k1", "k1")
+	index := keyring.BlindIndex(PurposeEmail, "info@jansen.nl")
+
+	if len(index) != 64 {
+		t.Errorf("blind index %q is geen hex SHA-256", index)
+	}
+	if strings.Contains(index, "jansen") {
+		t.Errorf("blind index %q bevat de waarde", index)
+	}
+
+	tests := []struct {
+		name    string
+		purpose string
+		value   string
+		same    bool
+	}{
+		{"dezelfde waarde", PurposeEmail, "info@jansen.nl", true},
+		{"hoofdletters", PurposeEmail, "Info@Jansen.NL", true},
+		{"spaties eromheen", PurposeEmail, "  info@jansen.nl\t", true},
+		{"andere waarde", PurposeEmail, "info@devries.nl", false},
+		{"ander doel", "phone", "info@jansen.nl", false},
+	}
+	for _, tt := range tests {
+		if got := keyring.BlindIndex(tt.purpose, tt.value); (got == index) != tt.same {
+			t.Errorf("%s: BlindIndex = %s, gelijk verwacht: %v", tt.name, got, tt.same)
+		}
+	}
+
+	if got := keyring.BlindIndex(PurposeEmail, "  "); got != "" {
+		t.Errorf("BlindIndex van een lege waarde = %q, verwacht leeg", got)
+	}
+
+	// Met een andere sleutel is de index anders, anders is hij met een woordenlijst terug te rekenen
+	other, err := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1", testKey(0xee))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.BlindIndex(PurposeEmail, "info@jansen.nl") == index {
+		t.Error("een andere sleutel voor blind indexes gaf dezelfde index")
+	}
+}
@@ -0,0 +1,59 @@
+package fieldcrypt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is de naam van de GORM serializer, te gebruiken als gorm:"serializer:encrypted"
+const SerializerName = "encrypted"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Serializer versleutelt string velden met de standaard Keyring bij het schrijven en ontsleutelt ze
+// bij het lezen. Het werkt bij Create, Save, Updates met een struct en het lezen in structs;
+// bij Updates met een map en in ruwe SQL moeten waarden zelf versleuteld worden.
+type Serializer struct{}
+
+// Scan implementeert schema.SerializerInterface
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("kan %T niet ontsleutelen voor veld %s", dbValue, field.Name)
+	}
+
+	plaintext, err := Default().Decrypt(value)
+	if err != nil {
+		return fmt.Errorf("veld %s: %w", field.Name, err)
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+// Value implementeert schema.SerializerValuerInterface
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var value string
+	switch v := fieldValue.(type) {
+	case string:
+		value = v
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		value = *v
+	default:
+		return nil, fmt.Errorf("veld %s: alleen tekst kan versleuteld worden, niet %T", field.Name, fieldValue)
+	}
+
+	return Default().Encrypt(value)
+}
@@ -0,0 +1,240 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"odomosml/pkg/sorting"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts zijn de formaten waarin datums en tijden in een filter opgegeven kunnen worden
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// Compile vertaalt een filterexpressie naar een SQL voorwaarde met parameters, voor gebruik in
+// query.Where(sql, vars...). column geeft de kolom achter een veldnaam en of het veld gefilterd mag worden;
+// het Postgres type van de kolom bepaalt hoe waarden gelezen worden (text, bigint, integer, boolean,
+// timestamptz of jsonb, met het JSON type in ValueType). Fouten zijn van het type *Error.
+func Compile(node Node, column func(name string) (sorting.Column, bool)) (string, []interface{}, error) {
+	switch n := node.(type) {
+	case And:
+		return compileBinary(n.Left, n.Right, "AND", column)
+	case Or:
+		return compileBinary(n.Left, n.Right, "OR", column)
+	case Not:
+		sql, vars, err := Compile(n.Expr, column)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + sql + ")", vars, nil
+	case Comparison:
+		col, ok := column(n.Field.Text)
+		if !ok {
+			return "", nil, &Error{Pos: n.Field.Pos, Token: n.Field.Text, Message: "onbekend veld of er kan niet op dit veld gefilterd worden"}
+		}
+		return compileComparison(n, col)
+	}
+	return "", nil, fmt.Errorf("onbekende filterknoop %T", node)
+}
+
+func compileBinary(left, right Node, operator string, column func(name string) (sorting.Column, bool)) (string, []interface{}, error) {
+	leftSQL, leftVars, err := Compile(left, column)
+	if err != nil {
+		return "", nil, err
+	}
+	rightSQL, rightVars, err := Compile(right, column)
+	if err != nil {
+		return "", nil, err
+	}
+	return "(" + leftSQL + " " + operator + " " + rightSQL + ")", append(leftVars, rightVars...), nil
+}
+
+// compileComparison vertaalt een vergelijking op één kolom
+func compileComparison(n Comparison, col sorting.Column) (string, []interface{}, error) {
+	op := n.Op.Text
+	if op == "<>" {
+		op = "!="
+	}
+
+	// Vars van de kolom (bijv. de key van een vrij veld) gaan voor de waarden
+	with := func(sql string, values ...interface{}) (string, []interface{}, error) {
+		vars := append([]interface{}{}, col.Vars...)
+		return sql, append(vars, values...), nil
+	}
+
+	switch op {
+	case OpIsNull, OpIsNotNull:
+		if col.Type == "text" {
+			// Een lege tekst telt als geen waarde: "phone is null" vindt ook klanten zonder telefoonnummer.
+			// De kolom komt twee keer voor in de voorwaarde, dus ook de vars van de kolom.
+			sql := "(" + col.SQL + " IS NULL OR " + col.SQL + " = '')"
+			if op == OpIsNotNull {
+				sql = "(" + col.SQL + " IS NOT NULL AND " + col.SQL + " <> '')"
+			}
+			return sql, append(append([]interface{}{}, col.Vars...), col.Vars...), nil
+		}
+		if op == OpIsNotNull {
+			return with(col.SQL + " IS NOT NULL")
+		}
+		return with(col.SQL + " IS NULL")
+
+	case OpIn, OpNotIn:
+		placeholders := make([]string, len(n.Values))
+		values := make([]interface{}, len(n.Values))
+		for i, token := range n.Values {
+			value, err := convert(token, col)
+			if err != nil {
+				return "", nil, err
+			}
+			placeholders[i] = placeholder(col.Type)
+			values[i] = value
+		}
+		keyword := " IN "
+		if op == OpNotIn {
+			keyword = " NOT IN "
+		}
+		return with(col.SQL+keyword+"("+strings.Join(placeholders, ", ")+")", values...)
+
+	case "~":
+		if col.Type != "text" && col.Type != "jsonb" {
+			return "", nil, &Error{Pos: n.Op.Pos, Token: n.Op.Text, Message: "~ (bevat) kan alleen op tekstvelden gebruikt worden"}
+		}
+		expr := col.SQL
+		if col.Type == "jsonb" {
+			expr = "(" + col.SQL + " #>> '{}')"
+		}
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(n.Values[0].Text) + "%"
+		return with(expr+" ILIKE ?", pattern)
+
+	case "=", "!=", "<", "<=", ">", ">=":
+		token := n.Values[0]
+		if col.Type == "boolean" && op != "=" && op != "!=" {
+			return "", nil, &Error{Pos: n.Op.Pos, Token: n.Op.Text, Message: "ja/nee velden kunnen alleen met = of != vergeleken worden"}
+		}
+		if col.Type == "timestamptz" && isDate(token.Text) {
+			return compileDate(n, col, op)
+		}
+
+		value, err := convert(token, col)
+		if err != nil {
+			return "", nil, err
+		}
+		sqlOp := op
+		if op == "!=" {
+			sqlOp = "<>"
+		}
+		return with(col.SQL+" "+sqlOp+" "+placeholder(col.Type), value)
+	}
+
+	return "", nil, &Error{Pos: n.Op.Pos, Token: n.Op.Text, Message: "onbekende operator"}
+}
+
+// compileDate vergelijkt een tijdstip met een hele dag: created_at = 2024-01-01 betekent op die dag,
+// created_at > 2024-01-01 na die dag
+func compileDate(n Comparison, col sorting.Column, op string) (string, []interface{}, error) {
+	day, err := time.ParseInLocation("2006-01-02", n.Values[0].Text, time.Local)
+	if err != nil {
+		return "", nil, &Error{Pos: n.Values[0].Pos, Token: n.Values[0].Text, Message: "ongeldige datum, gebruik bijv. 2024-01-31"}
+	}
+	next := day.AddDate(0, 0, 1)
+
+	vars := func(values ...interface{}) []interface{} {
+		var all []interface{}
+		for _, value := range values {
+			all = append(all, col.Vars...)
+			all = append(all, value)
+		}
+		return all
+	}
+
+	switch op {
+	case "=":
+		return "(" + col.SQL + " >= ? AND " + col.SQL + " < ?)", vars(day, next), nil
+	case "!=":
+		return "(" + col.SQL + " < ? OR " + col.SQL + " >= ?)", vars(day, next), nil
+	case "<":
+		return col.SQL + " < ?", vars(day), nil
+	case "<=":
+		return col.SQL + " < ?", vars(next), nil
+	case ">":
+		return col.SQL + " >= ?", vars(next), nil
+	default: // >=
+		return col.SQL + " >= ?", vars(day), nil
+	}
+}
+
+// isDate geeft aan of een waarde een datum zonder tijd is
+func isDate(text string) bool {
+	return len(text) == len("2006-01-02") && strings.Count(text, "-") == 2
+}
+
+// placeholder geeft de parameter voor een waarde van het type; jsonb waarden worden als JSON tekst meegegeven
+func placeholder(typ string) string {
+	if typ == "jsonb" {
+		return "CAST(CAST(? AS text) AS jsonb)"
+	}
+	return "?"
+}
+
+// convert leest een waarde uit het filter als waarde van het kolomtype
+func convert(token Token, col sorting.Column) (interface{}, error) {
+	text := token.Text
+
+	switch col.Type {
+	case "bigint", "integer":
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, &Error{Pos: token.Pos, Token: text, Message: "verwacht een geheel getal"}
+		}
+		return value, nil
+
+	case "numeric":
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, &Error{Pos: token.Pos, Token: text, Message: "verwacht een getal"}
+		}
+		return value, nil
+
+	case "boolean":
+		switch strings.ToLower(text) {
+		case "true", "ja", "1":
+			return true, nil
+		case "false", "nee", "0":
+			return false, nil
+		}
+		return nil, &Error{Pos: token.Pos, Token: text, Message: "verwacht true of false"}
+
+	case "timestamptz":
+		for _, layout := range dateLayouts {
+			if value, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+				return value, nil
+			}
+		}
+		return nil, &Error{Pos: token.Pos, Token: text, Message: "ongeldige datum of tijd, gebruik bijv. 2024-01-31 of 2024-01-31T14:00:00+01:00"}
+
+	case "jsonb":
+		// Vrije velden: de waarde krijgt het type van het veld, zodat een postcode of artikelnummer als
+		// tekst vergeleken wordt, ook zonder aanhalingstekens (datums staan als ISO tekst opgeslagen en
+		// vergelijken dus correct)
+		var value interface{} = text
+		switch col.ValueType {
+		case "number":
+			number, err := convert(token, sorting.Column{Type: "numeric"})
+			if err != nil {
+				return nil, err
+			}
+			value = number
+		case "boolean":
+			b, err := convert(token, sorting.Column{Type: "boolean"})
+			if err != nil {
+				return nil, err
+			}
+			value = b
+		}
+		raw, _ := json.Marshal(value)
+		return string(raw), nil
+	}
+
+	return text, nil
+}
@@ -0,0 +1,145 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"odomosml/pkg/sorting"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testColumns zijn de velden waarop in de tests gefilterd kan worden
+var testColumns = map[string]sorting.Column{
+	"id":          {SQL: "t.id", Type: "bigint"},
+	"name":        {SQL: "t.name", Type: "text"},
+	"active":      {SQL: "t.active", Type: "boolean"},
+	"amount":      {SQL: "t.amount", Type: "numeric"},
+	"created_at":  {SQL: "t.created_at", Type: "timestamptz"},
+	"cf.postcode": {SQL: "t.custom_fields->?", Vars: []interface{}{"postcode"}, Type: "jsonb", ValueType: "string"},
+	"cf.omzet":    {SQL: "t.custom_fields->?", Vars: []interface{}{"omzet"}, Type: "jsonb", ValueType: "number"},
+	"cf.klant":    {SQL: "t.custom_fields->?", Vars: []interface{}{"klant"}, Type: "jsonb", ValueType: "boolean"},
+}
+
+func testColumn(name string) (sorting.Column, bool) {
+	col, ok := testColumns[name]
+	return col, ok
+}
+
+func TestCompile(t *testing.T) {
+	day := time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		input    string
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{"getal", "id = 5", "t.id = ?", []interface{}{int64(5)}},
+		{"niet gelijk", "id != 5", "t.id <> ?", []interface{}{int64(5)}},
+		{"tekst", "name = 'De Vries'", "t.name = ?", []interface{}{"De Vries"}},
+		{"bevat", "name ~ '50%_off'", "t.name ILIKE ?", []interface{}{`%50\%\_off%`}},
+		{"ja/nee", "active = ja", "t.active = ?", []interface{}{true}},
+		{"decimaal", "amount >= 1.5", "t.amount >= ?", []interface{}{1.5}},
+		{"in", "id in (1, 2)", "t.id IN (?, ?)", []interface{}{int64(1), int64(2)}},
+		{"not in", "name not in (a)", "t.name NOT IN (?)", []interface{}{"a"}},
+		{"tekst is null", "name is null", "(t.name IS NULL OR t.name = '')", nil},
+		{"tekst is not null", "name is not null", "(t.name IS NOT NULL AND t.name <> '')", nil},
+		{"is null", "created_at is null", "t.created_at IS NULL", nil},
+		{"datum is hele dag", "created_at = 2024-01-31", "(t.created_at >= ? AND t.created_at < ?)", []interface{}{day, day.AddDate(0, 0, 1)}},
+		{"na een datum", "created_at > 2024-01-31", "t.created_at >= ?", []interface{}{day.AddDate(0, 0, 1)}},
+		{"tot en met een datum", "created_at <= 2024-01-31", "t.created_at < ?", []interface{}{day.AddDate(0, 0, 1)}},
+		{"and en or", "id = 1 or id = 2 and name = x", "(t.id = ? OR (t.id = ? AND t.name = ?))", []interface{}{int64(1), int64(2), "x"}},
+		{"not", "not (id = 1 or id = 2)", "NOT ((t.id = ? OR t.id = ?))", []interface{}{int64(1), int64(2)}},
+
+		// Waarden komen altijd als parameter in de query, nooit in de SQL zelf
+		{"injectie in tekst", "name = 'x'' OR 1=1 --'", "t.name = ?", []interface{}{"x' OR 1=1 --"}},
+		{"injectie in bevat", "name ~ '''; DROP TABLE t; --'", "t.name ILIKE ?", []interface{}{"%'; DROP TABLE t; --%"}},
+		{"injectie in lijst", "name in ('a) OR (1=1', b)", "t.name IN (?, ?)", []interface{}{"a) OR (1=1", "b"}},
+
+		// Vrije velden krijgen het type van het veld, ook zonder aanhalingstekens
+		{"vrij tekstveld met cijfers", "cf.postcode = 01234", "t.custom_fields->? = CAST(CAST(? AS text) AS jsonb)", []interface{}{"postcode", `"01234"`}},
+		{"vrij getalveld", "cf.omzet > 1000", "t.custom_fields->? > CAST(CAST(? AS text) AS jsonb)", []interface{}{"omzet", "1000"}},
+		{"vrij getalveld tussen aanhalingstekens", "cf.omzet = '12.5'", "t.custom_fields->? = CAST(CAST(? AS text) AS jsonb)", []interface{}{"omzet", "12.5"}},
+		{"vrij ja/nee veld", "cf.klant = nee", "t.custom_fields->? = CAST(CAST(? AS text) AS jsonb)", []interface{}{"klant", "false"}},
+		{"vrij veld in lijst", "cf.postcode in (1000, '2000')", "t.custom_fields->? IN (CAST(CAST(? AS text) AS jsonb), CAST(CAST(? AS text) AS jsonb))", []interface{}{"postcode", `"1000"`, `"2000"`}},
+		{"vrij veld bevat", "cf.postcode ~ 12", "(t.custom_fields->? #>> '{}') ILIKE ?", []interface{}{"postcode", "%12%"}},
+		{"vrij veld is null", "cf.omzet is null", "t.custom_fields->? IS NULL", []interface{}{"omzet"}},
+		{"injectie in vrij veld", `cf.postcode = '"} OR 1=1'`, "t.custom_fields->? = CAST(CAST(? AS text) AS jsonb)", []interface{}{"postcode", `"\"} OR 1=1"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) gaf fout: %v", tt.input, err)
+			}
+			sql, vars, err := Compile(node, testColumn)
+			if err != nil {
+				t.Fatalf("Compile(%q) gaf fout: %v", tt.input, err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("Compile(%q) SQL = %s, verwacht %s", tt.input, sql, tt.wantSQL)
+			}
+			if len(vars) != 0 || len(tt.wantVars) != 0 {
+				if !reflect.DeepEqual(vars, tt.wantVars) {
+					t.Errorf("Compile(%q) vars = %#v, verwacht %#v", tt.input, vars, tt.wantVars)
+				}
+			}
+			if placeholders := strings.Count(sql, "?"); placeholders != len(vars) {
+				t.Errorf("Compile(%q) heeft %d placeholders en %d vars", tt.input, placeholders, len(vars))
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantToken string
+	}{
+		{"onbekend veld", "password = x", "password"},
+		{"veldnaam met SQL", "name;DROP = x", ";"},
+		{"geen geheel getal", "id = abc", "abc"},
+		{"geen ja/nee", "active = misschien", "misschien"},
+		{"ja/nee met <", "active < true", "<"},
+		{"bevat op getal", "id ~ 1", "~"},
+		{"ongeldige datum", "created_at > gisteren", "gisteren"},
+		{"ongeldige dag", "created_at = 2024-02-30", "2024-02-30"},
+		{"vrij getalveld met tekst", "cf.omzet > veel", "veel"},
+		{"vrij ja/nee veld met tekst", "cf.klant = misschien", "misschien"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := Parse(tt.input)
+			if err == nil {
+				_, _, err = Compile(node, testColumn)
+			}
+			var filterErr *Error
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("%q gaf %v; verwacht een *Error", tt.input, err)
+			}
+			if filterErr.Token != tt.wantToken {
+				t.Errorf("%q gaf fout bij %q (%v), verwacht bij %q", tt.input, filterErr.Token, err, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestCompileColumnVarsBeforeValues(t *testing.T) {
+	// De key van een vrij veld staat in de SQL voor de waarde, dus ook in de vars
+	node, err := Parse("cf.omzet >= 10 and cf.postcode is not null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vars, err := Compile(node, testColumn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(vars); got != "[omzet 10 postcode]" {
+		t.Errorf("vars = %s, verwacht [omzet 10 postcode]", got)
+	}
+}
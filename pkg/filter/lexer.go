@@ -0,0 +1,112 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TokenKind is het soort van een token in een filterexpressie
+type TokenKind int
+
+// Soorten tokens
+const (
+	TokenEOF      TokenKind = iota
+	TokenWord               // veldnaam, sleutelwoord of waarde zonder aanhalingstekens, bijv. created_at, and, 2024-01-01
+	TokenString             // waarde tussen aanhalingstekens, bijv. 'Den Haag'
+	TokenOperator           // =, !=, <>, <, <=, >, >= of ~
+	TokenLParen             // (
+	TokenRParen             // )
+	TokenComma              // ,
+)
+
+// Token is een stuk van een filterexpressie met de positie (in tekens, vanaf 0) waar het begint
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+// keyword geeft aan of het token het (hoofdletterongevoelige) sleutelwoord is
+func (t Token) keyword(word string) bool {
+	return t.Kind == TokenWord && strings.EqualFold(t.Text, word)
+}
+
+// lex splitst een filterexpressie in tokens
+func lex(input string) ([]Token, error) {
+	runes := []rune(input)
+	var tokens []Token
+
+	for pos := 0; pos < len(runes); {
+		r := runes[pos]
+		switch {
+		case unicode.IsSpace(r):
+			pos++
+
+		case r == '(':
+			tokens = append(tokens, Token{Kind: TokenLParen, Text: "(", Pos: pos})
+			pos++
+		case r == ')':
+			tokens = append(tokens, Token{Kind: TokenRParen, Text: ")", Pos: pos})
+			pos++
+		case r == ',':
+			tokens = append(tokens, Token{Kind: TokenComma, Text: ",", Pos: pos})
+			pos++
+
+		case strings.ContainsRune("=!<>~", r):
+			start := pos
+			pos++
+			if pos < len(runes) && (runes[pos] == '=' || (r == '<' && runes[pos] == '>')) {
+				pos++
+			}
+			text := string(runes[start:pos])
+			if text == "!" {
+				return nil, &Error{Pos: start, Token: text, Message: "onbekende operator, bedoelde je !="}
+			}
+			if text == "==" {
+				text = "="
+			}
+			tokens = append(tokens, Token{Kind: TokenOperator, Text: text, Pos: start})
+
+		case r == '\'' || r == '"':
+			// Een aanhalingsteken binnen de waarde wordt verdubbeld: 'Jansen''s'
+			start := pos
+			var value strings.Builder
+			pos++
+			for {
+				if pos >= len(runes) {
+					return nil, &Error{Pos: start, Token: string(runes[start:]), Message: "aanhalingsteken wordt niet gesloten"}
+				}
+				if runes[pos] == r {
+					if pos+1 < len(runes) && runes[pos+1] == r {
+						value.WriteRune(r)
+						pos += 2
+						continue
+					}
+					pos++
+					break
+				}
+				value.WriteRune(runes[pos])
+				pos++
+			}
+			tokens = append(tokens, Token{Kind: TokenString, Text: value.String(), Pos: start})
+
+		default:
+			start := pos
+			for pos < len(runes) && isWordRune(runes[pos]) {
+				pos++
+			}
+			if pos == start {
+				return nil, &Error{Pos: start, Token: string(r), Message: "onverwacht teken"}
+			}
+			tokens = append(tokens, Token{Kind: TokenWord, Text: string(runes[start:pos]), Pos: start})
+		}
+	}
+
+	return append(tokens, Token{Kind: TokenEOF, Pos: len(runes)}), nil
+}
+
+// isWordRune geeft aan of een teken deel kan zijn van een veldnaam of een waarde zonder aanhalingstekens.
+// Datums en tijden (2024-01-01T10:00:00+01:00), getallen (-1.5) en email adressen passen hierin.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-:+@", r)
+}
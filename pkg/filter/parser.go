@@ -0,0 +1,285 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Grenzen om te voorkomen dat een filter de database (of de parser) overbelast
+const (
+	maxLength = 2000
+	maxDepth  = 20
+)
+
+// Operatoren van een vergelijking, naast de operatoren uit de expressie zelf (=, <, ~, ...)
+const (
+	OpIn        = "in"
+	OpNotIn     = "not in"
+	OpIsNull    = "is null"
+	OpIsNotNull = "is not null"
+)
+
+// Error is een fout in een filterexpressie met de positie van het token waar het misging
+type Error struct {
+	Pos     int // positie in tekens, vanaf 0
+	Token   string
+	Message string
+}
+
+// Error implementeert de error interface
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("ongeldig filter op positie %d: %s", e.Pos+1, e.Message)
+	}
+	return fmt.Sprintf("ongeldig filter op positie %d ('%s'): %s", e.Pos+1, e.Token, e.Message)
+}
+
+// Node is een knoop in de boom van een filterexpressie
+type Node interface {
+	node()
+}
+
+// And is voldaan als beide kanten voldaan zijn
+type And struct {
+	Left, Right Node
+}
+
+// Or is voldaan als een van beide kanten voldaan is
+type Or struct {
+	Left, Right Node
+}
+
+// Not keert een expressie om
+type Not struct {
+	Expr Node
+}
+
+// Comparison vergelijkt een veld met een of meer waarden, bijv. created_at >= 2024-01-01,
+// role in (ADMIN, USER) of phone is null
+type Comparison struct {
+	Field  Token
+	Op     Token // Text is een operator of OpIn, OpNotIn, OpIsNull, OpIsNotNull
+	Values []Token
+}
+
+func (And) node()        {}
+func (Or) node()         {}
+func (Not) node()        {}
+func (Comparison) node() {}
+
+// Parse parst een filterexpressie zoals "created_at >= 2024-01-01 and (phone is null or name ~ bakkerij)".
+// Een lege expressie geeft nil. Fouten zijn van het type *Error.
+func Parse(input string) (Node, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	if len(input) > maxLength {
+		return nil, &Error{Pos: 0, Message: fmt.Sprintf("filter is te lang (maximaal %d tekens)", maxLength)}
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.Kind != TokenEOF {
+		return nil, unexpected(next, "verwacht 'and', 'or' of het einde van het filter")
+	}
+	return node, nil
+}
+
+// Fields geeft alle vergelijkingen in de boom, in volgorde van de expressie
+func Fields(node Node) []Comparison {
+	switch n := node.(type) {
+	case And:
+		return append(Fields(n.Left), Fields(n.Right)...)
+	case Or:
+		return append(Fields(n.Left), Fields(n.Right)...)
+	case Not:
+		return Fields(n.Expr)
+	case Comparison:
+		return []Comparison{n}
+	}
+	return nil
+}
+
+// parser is een recursive descent parser over de tokens van een filterexpressie:
+//
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" or ")" | comparison
+//	comparison = field operator value
+//	           | field [ "not" ] "in" "(" value { "," value } ")"
+//	           | field "is" [ "not" ] "null"
+type parser struct {
+	tokens []Token
+	pos    int
+}
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() Token {
+	token := p.tokens[p.pos]
+	if token.Kind != TokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *parser) parseOr(depth int) (Node, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("or") {
+		p.next()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (Node, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("and") {
+		p.next()
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary(depth int) (Node, error) {
+	token := p.peek()
+	if depth > maxDepth {
+		return nil, &Error{Pos: token.Pos, Token: token.Text, Message: fmt.Sprintf("filter is te diep genest (maximaal %d niveaus)", maxDepth)}
+	}
+
+	switch {
+	case token.keyword("not"):
+		p.next()
+		expr, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+
+	case token.Kind == TokenLParen:
+		p.next()
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.Kind != TokenRParen {
+			return nil, unexpected(closing, "verwacht ')'")
+		}
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	field := p.next()
+	if field.Kind != TokenWord || isKeyword(field) {
+		return nil, unexpected(field, "verwacht een veldnaam")
+	}
+
+	op := p.next()
+	switch {
+	case op.Kind == TokenOperator:
+		value := p.next()
+		if value.Kind != TokenWord && value.Kind != TokenString {
+			return nil, unexpected(value, "verwacht een waarde")
+		}
+		if value.keyword("null") {
+			return nil, &Error{Pos: value.Pos, Token: value.Text, Message: "gebruik 'is null' of 'is not null' om op een lege waarde te filteren"}
+		}
+		return Comparison{Field: field, Op: op, Values: []Token{value}}, nil
+
+	case op.keyword("is"):
+		text := OpIsNull
+		if p.peek().keyword("not") {
+			p.next()
+			text = OpIsNotNull
+		}
+		if null := p.next(); !null.keyword("null") {
+			return nil, unexpected(null, "verwacht 'null'")
+		}
+		return Comparison{Field: field, Op: Token{Kind: TokenWord, Text: text, Pos: op.Pos}}, nil
+
+	case op.keyword("in"), op.keyword("not"):
+		text := OpIn
+		if op.keyword("not") {
+			if in := p.next(); !in.keyword("in") {
+				return nil, unexpected(in, "verwacht 'in'")
+			}
+			text = OpNotIn
+		}
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return Comparison{Field: field, Op: Token{Kind: TokenWord, Text: text, Pos: op.Pos}, Values: values}, nil
+	}
+
+	return nil, unexpected(op, "verwacht een operator (=, !=, <, <=, >, >=, ~, in, is null)")
+}
+
+// parseList parst een lijst van waarden tussen haakjes
+func (p *parser) parseList() ([]Token, error) {
+	if open := p.next(); open.Kind != TokenLParen {
+		return nil, unexpected(open, "verwacht '('")
+	}
+
+	var values []Token
+	for {
+		value := p.next()
+		if value.Kind != TokenWord && value.Kind != TokenString {
+			return nil, unexpected(value, "verwacht een waarde")
+		}
+		values = append(values, value)
+
+		separator := p.next()
+		if separator.Kind == TokenRParen {
+			return values, nil
+		}
+		if separator.Kind != TokenComma {
+			return nil, unexpected(separator, "verwacht ',' of ')'")
+		}
+	}
+}
+
+// isKeyword geeft aan of een token een gereserveerd woord is
+func isKeyword(token Token) bool {
+	for _, word := range []string{"and", "or", "not", "is", "in", "null"} {
+		if token.keyword(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// unexpected maakt een fout voor een onverwacht token
+func unexpected(token Token, message string) *Error {
+	if token.Kind == TokenEOF {
+		return &Error{Pos: token.Pos, Message: "onverwacht einde van het filter, " + message}
+	}
+	return &Error{Pos: token.Pos, Token: token.Text, Message: message}
+}
@@ -0,0 +1,119 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// format schrijft een boom uit met haakjes om elke and, or en not, zodat de voorrang zichtbaar is
+func format(node Node) string {
+	switch n := node.(type) {
+	case And:
+		return "(" + format(n.Left) + " AND " + format(n.Right) + ")"
+	case Or:
+		return "(" + format(n.Left) + " OR " + format(n.Right) + ")"
+	case Not:
+		return "NOT " + format(n.Expr)
+	case Comparison:
+		values := make([]string, len(n.Values))
+		for i, value := range n.Values {
+			values[i] = "[" + value.Text + "]"
+		}
+		return strings.TrimSpace(n.Field.Text + " " + n.Op.Text + " " + strings.Join(values, ","))
+	}
+	return "<nil>"
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"leeg", "   ", "<nil>"},
+		{"vergelijking", "created_at >= 2024-01-01", "created_at >= [2024-01-01]"},
+		{"== is =", "name == x", "name = [x]"},
+		{"<> blijft staan", "name <> x", "name <> [x]"},
+		{"tekst tussen aanhalingstekens", "name ~ 'De Vries'", "name ~ [De Vries]"},
+		{"dubbele aanhalingstekens", `name = "Den Haag"`, "name = [Den Haag]"},
+		{"verdubbeld aanhalingsteken", "name = 'Jansen''s'", "name = [Jansen's]"},
+		{"sleutelwoord als tekst", "name = 'and'", "name = [and]"},
+		{"is null", "phone is null", "phone is null"},
+		{"is not null", "phone IS NOT NULL", "phone is not null"},
+		{"in", "role in (ADMIN, 'USER')", "role in [ADMIN],[USER]"},
+		{"not in", "role not in (ADMIN)", "role not in [ADMIN]"},
+		{"and gaat voor or", "a = 1 or b = 2 and c = 3", "(a = [1] OR (b = [2] AND c = [3]))"},
+		{"or van links", "a = 1 or b = 2 or c = 3", "((a = [1] OR b = [2]) OR c = [3])"},
+		{"haakjes", "(a = 1 or b = 2) and c = 3", "((a = [1] OR b = [2]) AND c = [3])"},
+		{"not bindt sterker dan and", "not a = 1 and b = 2", "(NOT a = [1] AND b = [2])"},
+		{"not met haakjes", "not (a = 1 and b = 2)", "NOT (a = [1] AND b = [2])"},
+		{"sleutelwoorden hoofdletterongevoelig", "a = 1 AND b = 2 Or c = 3", "((a = [1] AND b = [2]) OR c = [3])"},
+		{"tijd met tijdzone", "created_at < 2024-01-31T14:00:00+01:00", "created_at < [2024-01-31T14:00:00+01:00]"},
+		{"e-mailadres", "email = jan@example.nl", "email = [jan@example.nl]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) gaf fout: %v", tt.input, err)
+			}
+			if got := format(node); got != tt.want {
+				t.Errorf("Parse(%q) = %s, verwacht %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantPos int
+	}{
+		{"geen operator", "name", 4},
+		{"geen waarde", "name =", 6},
+		{"onbekende operator", "name ! x", 5},
+		{"null als waarde", "phone = null", 8},
+		{"is zonder null", "phone is leeg", 9},
+		{"haakje niet gesloten", "(a = 1", 6},
+		{"haakje te veel", "a = 1)", 5},
+		{"aanhalingsteken niet gesloten", "name = 'x", 7},
+		{"sleutelwoord als veld", "and = 1", 0},
+		{"lege lijst", "role in ()", 9},
+		{"lijst zonder komma", "role in (a b)", 11},
+		{"onverwacht teken", "name = x; DROP TABLE customers", 8},
+		{"commentaar", "name = x -- y", 9},
+		{"te diep genest", strings.Repeat("(", maxDepth+2) + "a = 1" + strings.Repeat(")", maxDepth+2), maxDepth + 1},
+		{"te lang", "name = '" + strings.Repeat("x", maxLength) + "'", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := Parse(tt.input)
+			var filterErr *Error
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("Parse(%q) = %s, %v; verwacht een *Error", tt.input, format(node), err)
+			}
+			if filterErr.Pos != tt.wantPos {
+				t.Errorf("Parse(%q) gaf fout op positie %d (%v), verwacht %d", tt.input, filterErr.Pos, err, tt.wantPos)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	node, err := Parse("a = 1 and not (b = 2 or c is null)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, comparison := range Fields(node) {
+		names = append(names, comparison.Field.Text)
+	}
+	if got := strings.Join(names, ","); got != "a,b,c" {
+		t.Errorf("Fields = %s, verwacht a,b,c", got)
+	}
+}
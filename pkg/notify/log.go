@@ -0,0 +1,20 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// LogNotifier schrijft meldingen naar het log; bedoeld voor development en als er geen kanaal is ingesteld
+type LogNotifier struct{}
+
+// NewLogNotifier maakt een nieuwe LogNotifier
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify schrijft de melding naar het log
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	log.Printf("Melding aan %s (%d): %s - %s", notification.Username, notification.UserID, notification.Subject, notification.Message)
+	return nil
+}
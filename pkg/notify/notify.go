@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"odomosml/config"
+	"time"
+)
+
+// Notification is een melding aan een gebruiker, bijvoorbeeld een herinnering aan een taak
+type Notification struct {
+	Type      string                 `json:"type"` // Soort melding, bijv. task.reminder
+	UserID    uint                   `json:"user_id"`
+	Username  string                 `json:"username"`
+	Email     string                 `json:"email"`
+	Subject   string                 `json:"subject"`
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Notifier verstuurt meldingen naar gebruikers
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// New maakt de notifier aan die in de configuratie is gekozen
+func New(cfg *config.Config) (Notifier, error) {
+	switch cfg.NotifierDriver {
+	case "", "log":
+		return NewLogNotifier(), nil
+	case "webhook":
+		return NewWebhookNotifier(cfg.NotifierWebhookURL, cfg.NotifierWebhookSecret)
+	default:
+		return nil, fmt.Errorf("onbekende notifier driver '%s'", cfg.NotifierDriver)
+	}
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhookTimeout is de maximale duur van een webhook aanroep
+const webhookTimeout = 10 * time.Second
+
+// WebhookNotifier verstuurt meldingen als JSON POST naar een URL, bijvoorbeeld een mail- of chatkoppeling.
+// Met een secret bevat de header X-Signature de HMAC-SHA256 van de body, zodat de ontvanger de
+// herkomst kan controleren.
+type WebhookNotifier struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookNotifier maakt een nieuwe WebhookNotifier
+func NewWebhookNotifier(target, secret string) (*WebhookNotifier, error) {
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, errors.New("NOTIFIER_WEBHOOK_URL moet een http(s) URL zijn")
+	}
+
+	return &WebhookNotifier{
+		url:    target,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: webhookTimeout},
+	}, nil
+}
+
+// Notify verstuurt de melding; elke status buiten 2xx is een fout
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		mac := hmac.New(sha256.New, n.secret)
+		mac.Write(body)
+		request.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	response, err := n.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("webhook niet bereikbaar: %w", err)
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook antwoordde met status %d", response.StatusCode)
+	}
+	return nil
+}
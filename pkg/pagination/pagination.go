@@ -0,0 +1,430 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"odomosml/pkg/sorting"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Manieren om het totaal aantal rijen van een lijst te bepalen
+const (
+	CountExact    = "exact"    // COUNT(*), nauwkeurig maar traag op grote tabellen
+	CountEstimate = "estimate" // schatting uit het query plan van Postgres
+	CountNone     = "none"     // niet tellen
+)
+
+// ErrSortMismatch geeft aan dat een cursor bij een andere sortering hoort dan gevraagd
+var ErrSortMismatch = errors.New("de cursor hoort bij een andere sortering")
+
+// Request beschrijft welke pagina van een lijst opgehaald wordt: op paginanummer (offset)
+// of vanaf een cursor (keyset). After en Before sluiten elkaar en een paginanummer uit.
+type Request struct {
+	Page   int
+	Size   int
+	After  *Cursor
+	Before *Cursor
+	Count  string
+}
+
+// Keyset geeft aan of er vanaf een cursor gepagineerd wordt
+func (r Request) Keyset() bool {
+	return r.After != nil || r.Before != nil
+}
+
+// Info beschrijft de opgehaalde pagina
+type Info struct {
+	Total     *int64 // nil als er niet geteld is
+	Estimated bool   // Total is een schatting
+	HasMore   bool   // er is een volgende pagina
+	Next      string // cursor naar de volgende pagina
+	Prev      string // cursor naar de vorige pagina (alleen bij keyset paginering)
+}
+
+// Cursor wijst een rij aan in een gesorteerde lijst: de sortering, de waarden van de
+// sorteervelden en het ID van de rij. Waarden zijn tekst in het formaat dat Postgres accepteert.
+type Cursor struct {
+	Sort   string    `json:"s"`
+	Values []*string `json:"v"`
+	ID     uint      `json:"id"`
+}
+
+// Encode zet de cursor om naar een opaque token
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode zet een token terug om naar een cursor
+func Decode(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("ongeldige cursor")
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == 0 {
+		return nil, errors.New("ongeldige cursor")
+	}
+	return &cursor, nil
+}
+
+// NewCursor maakt een cursor voor een rij. value geeft de waarde van een sorteerveld in de rij.
+func NewCursor(fields []sorting.Field, column func(name string) sorting.Column, id uint, value func(name string) interface{}) Cursor {
+	cursor := Cursor{Sort: sorting.Format(fields), ID: id}
+	for _, field := range fields {
+		cursor.Values = append(cursor.Values, formatValue(value(field.Name), column(field.Name).Type))
+	}
+	return cursor
+}
+
+// formatValue zet een waarde om naar tekst die Postgres naar het type van de kolom kan casten
+func formatValue(value interface{}, typ string) *string {
+	if value == nil {
+		return nil
+	}
+
+	var text string
+	if typ == "jsonb" {
+		raw, _ := json.Marshal(value)
+		text = string(raw)
+	} else {
+		switch v := value.(type) {
+		case time.Time:
+			text = v.Format(time.RFC3339Nano)
+		case string:
+			text = v
+		default:
+			text = fmt.Sprint(v)
+		}
+	}
+	return &text
+}
+
+// FromQuery leest de pagineringsparameters uit de query string: het paginanummer en de paginagrootte
+// onder de opgegeven namen, en after, before en count
+func FromQuery(values url.Values, pageParam, sizeParam string, defaultSize, maxSize int) (Request, error) {
+	request := Request{
+		Page:  parsePositive(values.Get(pageParam), 1),
+		Size:  parsePositive(values.Get(sizeParam), defaultSize),
+		Count: strings.ToLower(values.Get("count")),
+	}
+	if request.Size > maxSize {
+		request.Size = maxSize
+	}
+
+	switch request.Count {
+	case "":
+		request.Count = CountExact
+	case CountExact, CountEstimate, CountNone:
+	default:
+		return request, fmt.Errorf("ongeldige waarde voor count '%s', gebruik exact, estimate of none", request.Count)
+	}
+
+	after, before := values.Get("after"), values.Get("before")
+	if after != "" && before != "" {
+		return request, errors.New("gebruik after of before, niet beide")
+	}
+	if (after != "" || before != "") && values.Get(pageParam) != "" {
+		return request, fmt.Errorf("gebruik %s of een cursor (after/before), niet beide", pageParam)
+	}
+
+	var err error
+	if after != "" {
+		request.After, err = Decode(after)
+	} else if before != "" {
+		request.Before, err = Decode(before)
+	}
+	return request, err
+}
+
+// parsePositive parst een positief getal, met een standaardwaarde bij een ontbrekende of ongeldige waarde
+func parsePositive(value string, defaultValue int) int {
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return defaultValue
+	}
+	return parsed
+}
+
+// Count bepaalt het totaal aantal rijen van de (gefilterde, nog niet gesorteerde) query volgens mode.
+// Retourneert nil als er niet geteld wordt en true als het totaal een schatting is.
+func Count(query *gorm.DB, mode string) (*int64, bool, error) {
+	switch mode {
+	case CountNone:
+		return nil, false, nil
+	case CountEstimate:
+		total, err := estimate(query)
+		return &total, true, err
+	default:
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, false, err
+		}
+		return &total, false, nil
+	}
+}
+
+// estimate leest het geschatte aantal rijen uit het query plan van Postgres (EXPLAIN)
+func estimate(query *gorm.DB) (int64, error) {
+	stmt := query.Session(&gorm.Session{DryRun: true}).Find(&[]map[string]interface{}{}).Statement
+	if stmt.Error != nil {
+		return 0, stmt.Error
+	}
+
+	var raw []byte
+	row := stmt.ConnPool.QueryRowContext(stmt.Context, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...)
+	if err := row.Scan(&raw); err != nil {
+		return 0, err
+	}
+
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil || len(plans) == 0 {
+		return 0, errors.New("kan het aantal rijen niet schatten")
+	}
+	return int64(plans[0].Plan.Rows), nil
+}
+
+// Apply pagineert en sorteert de query. Bij een paginanummer wordt een offset gebruikt, bij een
+// cursor alleen de rijen na (of voor) de cursor. Er wordt één rij extra opgehaald om te bepalen
+// of er nog een pagina is; Window knipt die er weer af.
+func Apply(query *gorm.DB, request Request, fields []sorting.Field, column func(name string) sorting.Column, idField string) (*gorm.DB, error) {
+	keys := sorting.Keys(fields, idField)
+
+	if !request.Keyset() {
+		return sorting.Order(query, keys, column, false).Offset((request.Page - 1) * request.Size).Limit(request.Size + 1), nil
+	}
+
+	cursor, backward := request.After, false
+	if request.Before != nil {
+		cursor, backward = request.Before, true
+	}
+	if cursor.Sort != sorting.Format(fields) || len(cursor.Values) != len(fields) {
+		return nil, ErrSortMismatch
+	}
+
+	values := cursor.Values
+	if len(keys) > len(fields) {
+		id := strconv.FormatUint(uint64(cursor.ID), 10)
+		values = append(append([]*string{}, values...), &id)
+	}
+
+	condition, vars := keysetCondition(keys, values, column, backward)
+	query = query.Where(condition, vars...)
+	return sorting.Order(query, keys, column, backward).Limit(request.Size + 1), nil
+}
+
+// keysetCondition bouwt de voorwaarde voor alle rijen na de cursor in de sortering (of ervoor bij
+// backward). Zoals bij sorting.Order komen NULL waarden achteraan.
+func keysetCondition(keys []sorting.Field, values []*string, column func(name string) sorting.Column, backward bool) (string, []interface{}) {
+	operator := func(key sorting.Field) string {
+		if key.Desc != backward {
+			return "<"
+		}
+		return ">"
+	}
+
+	// Alle sleutels in dezelfde richting en zonder NULL waarden: (a, b) > (x, y), wat Postgres
+	// direct met een index op (a, b) kan afhandelen
+	if rowComparable(keys, values, column) {
+		var lhs, rhs []string
+		var lhsVars, rhsVars []interface{}
+		for i, key := range keys {
+			col := column(key.Name)
+			lhs = append(lhs, col.SQL)
+			lhsVars = append(lhsVars, col.Vars...)
+			rhs = append(rhs, castParam(col.Type))
+			rhsVars = append(rhsVars, *values[i])
+		}
+		condition := "(" + strings.Join(lhs, ", ") + ") " + operator(keys[0]) + " (" + strings.Join(rhs, ", ") + ")"
+		return condition, append(lhsVars, rhsVars...)
+	}
+
+	// Anders: (a > x) OR (a = x AND b > y) OR ...
+	var alternatives []string
+	var vars []interface{}
+
+	for i, key := range keys {
+		var parts []string
+		var partVars []interface{}
+
+		for j := 0; j < i; j++ {
+			col := column(keys[j].Name)
+			if values[j] == nil {
+				parts = append(parts, col.SQL+" IS NULL")
+				partVars = append(partVars, col.Vars...)
+			} else {
+				parts = append(parts, col.SQL+" = "+castParam(col.Type))
+				partVars = append(partVars, col.Vars...)
+				partVars = append(partVars, *values[j])
+			}
+		}
+
+		col := column(key.Name)
+		switch {
+		case values[i] == nil && backward:
+			// Voor een NULL waarde staan alle rijen met een waarde
+			parts = append(parts, col.SQL+" IS NOT NULL")
+			partVars = append(partVars, col.Vars...)
+		case values[i] == nil:
+			// Na een NULL waarde komen op dit veld geen rijen meer
+			continue
+		default:
+			comparison := col.SQL + " " + operator(key) + " " + castParam(col.Type)
+			comparisonVars := append(append([]interface{}{}, col.Vars...), *values[i])
+			if col.Nullable && !backward {
+				// NULL waarden komen na alle rijen met een waarde
+				comparison = "(" + comparison + " OR " + col.SQL + " IS NULL)"
+				comparisonVars = append(comparisonVars, col.Vars...)
+			}
+			parts = append(parts, comparison)
+			partVars = append(partVars, comparisonVars...)
+		}
+
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+		vars = append(vars, partVars...)
+	}
+
+	if len(alternatives) == 0 {
+		return "FALSE", nil
+	}
+	condition := "(" + strings.Join(alternatives, " OR ") + ")"
+
+	// Begrens de eerste sleutel ook los (a >= x), zodat een index op die kolom gebruikt kan worden
+	if first := column(keys[0].Name); !first.Nullable && values[0] != nil {
+		bound := first.SQL + " " + operator(keys[0]) + "= " + castParam(first.Type)
+		boundVars := append(append([]interface{}{}, first.Vars...), *values[0])
+		return bound + " AND " + condition, append(boundVars, vars...)
+	}
+	return condition, vars
+}
+
+// rowComparable geeft aan of de sleutels met één row value vergelijking begrensd kunnen worden
+func rowComparable(keys []sorting.Field, values []*string, column func(name string) sorting.Column) bool {
+	for i, key := range keys {
+		if key.Desc != keys[0].Desc || values[i] == nil || column(key.Name).Nullable {
+			return false
+		}
+	}
+	return true
+}
+
+// castParam geeft een parameter die als tekst wordt meegegeven en in SQL naar het kolomtype wordt gecast
+func castParam(typ string) string {
+	return "CAST(CAST(? AS text) AS " + typ + ")"
+}
+
+// Window knipt de extra rij van een opgehaalde pagina af, zet een vorige pagina weer in de juiste
+// volgorde en bepaalt de cursors naar de volgende en vorige pagina. cursor maakt de cursor van een rij.
+func Window[T any](items []T, request Request, cursor func(item *T) Cursor) ([]T, Info) {
+	more := len(items) > request.Size
+	if more {
+		items = items[:request.Size]
+	}
+	if len(items) == 0 {
+		return items, Info{}
+	}
+
+	first, last := cursor(&items[0]).Encode(), cursor(&items[len(items)-1]).Encode()
+
+	if request.Before != nil {
+		// Achterwaarts opgehaald: draai om, de extra rij zegt dan of er nog een vorige pagina is.
+		// Na deze pagina volgt altijd nog de rij van de cursor zelf.
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+		info := Info{HasMore: true, Next: first}
+		if more {
+			info.Prev = last
+		}
+		return items, info
+	}
+
+	info := Info{HasMore: more}
+	if more {
+		info.Next = last
+	}
+	if request.After != nil {
+		info.Prev = first
+	}
+	return items, info
+}
+
+// Link bouwt een Link header (RFC 8288) met relaties naar andere pagina's van dezelfde lijst.
+// links koppelt een relatie (next, prev, first, last) aan de query parameters die afwijken van de huidige URL;
+// een lege waarde verwijdert de parameter.
+func Link(current *url.URL, links map[string]map[string]string) string {
+	var parts []string
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		params, ok := links[rel]
+		if !ok {
+			continue
+		}
+
+		query := current.Query()
+		for key, value := range params {
+			if value == "" {
+				query.Del(key)
+			} else {
+				query.Set(key, value)
+			}
+		}
+
+		target := url.URL{Path: current.Path, RawQuery: query.Encode()}
+		parts = append(parts, fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Links bouwt de Link header voor een opgehaalde pagina. Bij keyset paginering wijzen next en prev
+// naar een cursor, anders naar het volgende of vorige paginanummer (en last alleen bij een exacte telling).
+func Links(current *url.URL, request Request, info Info, pageParam string) string {
+	links := make(map[string]map[string]string)
+	cursorless := map[string]string{"after": "", "before": ""}
+
+	if request.Keyset() {
+		links["first"] = cursorless
+		if info.Next != "" {
+			links["next"] = map[string]string{"after": info.Next, "before": ""}
+		}
+		if info.Prev != "" {
+			links["prev"] = map[string]string{"before": info.Prev, "after": ""}
+		}
+		return Link(current, links)
+	}
+
+	page := func(number int) map[string]string {
+		return map[string]string{pageParam: strconv.Itoa(number), "after": "", "before": ""}
+	}
+	links["first"] = page(1)
+	if request.Page > 1 {
+		links["prev"] = page(request.Page - 1)
+	}
+	if info.HasMore {
+		links["next"] = page(request.Page + 1)
+	}
+	if info.Total != nil && !info.Estimated {
+		links["last"] = page(TotalPages(*info.Total, request.Size))
+	}
+	return Link(current, links)
+}
+
+// TotalPages berekent het aantal pagina's, minimaal één
+func TotalPages(total int64, size int) int {
+	pages := int((total + int64(size) - 1) / int64(size))
+	if pages < 1 {
+		return 1
+	}
+	return pages
+}
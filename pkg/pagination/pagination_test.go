@@ -0,0 +1,313 @@
+package pagination
+
+import (
+	"errors"
+	"net/url"
+	"odomosml/pkg/sorting"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testColumns zijn de sorteervelden in de tests
+var testColumns = map[string]sorting.Column{
+	"id":         {SQL: "t.id", Type: "bigint"},
+	"name":       {SQL: "t.name", Type: "text"},
+	"created_at": {SQL: "t.created_at", Type: "timestamptz"},
+	"closed_at":  {SQL: "t.closed_at", Type: "timestamptz", Nullable: true},
+	"cf.omzet":   {SQL: "t.custom_fields->?", Vars: []interface{}{"omzet"}, Type: "jsonb", Nullable: true},
+}
+
+func text(value string) *string {
+	return &value
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"één waarde", Cursor{Sort: "name", Values: []*string{text("Bakkerij Jansen")}, ID: 7}},
+		{"NULL waarde", Cursor{Sort: "-closed_at,name", Values: []*string{nil, text("x")}, ID: 1}},
+		{"bijzondere tekens", Cursor{Sort: "name", Values: []*string{text(`"'/?&=+ é`)}, ID: 42}},
+		{"alleen ID", Cursor{Sort: "", ID: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.cursor.Encode()
+			if strings.ContainsAny(token, "+/=") {
+				t.Errorf("token %q is niet URL veilig", token)
+			}
+
+			decoded, err := Decode(token)
+			if err != nil {
+				t.Fatalf("Decode gaf fout: %v", err)
+			}
+			if decoded.Sort != tt.cursor.Sort || decoded.ID != tt.cursor.ID || len(decoded.Values) != len(tt.cursor.Values) {
+				t.Fatalf("Decode = %+v, verwacht %+v", decoded, tt.cursor)
+			}
+			for i, value := range tt.cursor.Values {
+				if (value == nil) != (decoded.Values[i] == nil) || (value != nil && *value != *decoded.Values[i]) {
+					t.Errorf("waarde %d = %v, verwacht %v", i, decoded.Values[i], value)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, token := range []string{
+		"",
+		"geen base64!",
+		"bm9nIGdlZW4ganNvbg",                 // "nog geen json"
+		Cursor{Sort: "name"}.Encode(),        // zonder ID
+		"eyJzIjoibmFtZSIsImlkIjoiMSJ9",       // ID als tekst
+		"eyJzIjoibmFtZSIsInYiOjEsImlkIjoxfQ", // waarden geen lijst
+	} {
+		if cursor, err := Decode(token); err == nil {
+			t.Errorf("Decode(%q) = %+v, verwacht een fout", token, cursor)
+		}
+	}
+}
+
+func TestNewCursor(t *testing.T) {
+	created := time.Date(2024, 1, 31, 14, 0, 0, 500, time.UTC)
+	fields := []sorting.Field{{Name: "created_at", Desc: true}, {Name: "closed_at"}, {Name: "cf.omzet"}, {Name: "name"}}
+	row := map[string]interface{}{"created_at": created, "closed_at": nil, "cf.omzet": 12.5, "name": "Jansen"}
+
+	cursor := NewCursor(fields, sorting.Columns(testColumns), 9, func(name string) interface{} { return row[name] })
+
+	if cursor.Sort != "-created_at,closed_at,cf.omzet,name" || cursor.ID != 9 {
+		t.Fatalf("cursor = %+v", cursor)
+	}
+	want := []*string{text("2024-01-31T14:00:00.0000005Z"), nil, text("12.5"), text("Jansen")}
+	if !reflect.DeepEqual(cursor.Values, want) {
+		t.Errorf("waarden = %v, verwacht %v", deref(cursor.Values), deref(want))
+	}
+}
+
+func deref(values []*string) []string {
+	result := make([]string, len(values))
+	for i, value := range values {
+		if value == nil {
+			result[i] = "<nil>"
+		} else {
+			result[i] = *value
+		}
+	}
+	return result
+}
+
+func TestKeysetCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []sorting.Field
+		values   []*string
+		backward bool
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "row value vergelijking",
+			keys:     []sorting.Field{{Name: "name"}, {Name: "id"}},
+			values:   []*string{text("Jansen"), text("7")},
+			wantSQL:  "(t.name, t.id) > (CAST(CAST(? AS text) AS text), CAST(CAST(? AS text) AS bigint))",
+			wantVars: []interface{}{"Jansen", "7"},
+		},
+		{
+			name:     "aflopend",
+			keys:     []sorting.Field{{Name: "created_at", Desc: true}, {Name: "id", Desc: true}},
+			values:   []*string{text("2024-01-31T00:00:00Z"), text("7")},
+			wantSQL:  "(t.created_at, t.id) < (CAST(CAST(? AS text) AS timestamptz), CAST(CAST(? AS text) AS bigint))",
+			wantVars: []interface{}{"2024-01-31T00:00:00Z", "7"},
+		},
+		{
+			name:     "vorige pagina draait de richting om",
+			keys:     []sorting.Field{{Name: "name"}, {Name: "id"}},
+			values:   []*string{text("Jansen"), text("7")},
+			backward: true,
+			wantSQL:  "(t.name, t.id) < (CAST(CAST(? AS text) AS text), CAST(CAST(? AS text) AS bigint))",
+			wantVars: []interface{}{"Jansen", "7"},
+		},
+		{
+			name:   "gemengde richting",
+			keys:   []sorting.Field{{Name: "name", Desc: true}, {Name: "id"}},
+			values: []*string{text("Jansen"), text("7")},
+			wantSQL: "t.name <= CAST(CAST(? AS text) AS text) AND ((t.name < CAST(CAST(? AS text) AS text)) OR " +
+				"(t.name = CAST(CAST(? AS text) AS text) AND t.id > CAST(CAST(? AS text) AS bigint)))",
+			wantVars: []interface{}{"Jansen", "Jansen", "Jansen", "7"},
+		},
+		{
+			name:   "nullable kolom: NULL komt achteraan",
+			keys:   []sorting.Field{{Name: "closed_at"}, {Name: "id"}},
+			values: []*string{text("2024-01-31T00:00:00Z"), text("7")},
+			wantSQL: "(((t.closed_at > CAST(CAST(? AS text) AS timestamptz) OR t.closed_at IS NULL)) OR " +
+				"(t.closed_at = CAST(CAST(? AS text) AS timestamptz) AND t.id > CAST(CAST(? AS text) AS bigint)))",
+			wantVars: []interface{}{"2024-01-31T00:00:00Z", "2024-01-31T00:00:00Z", "7"},
+		},
+		{
+			name:     "na een NULL waarde alleen nog NULL",
+			keys:     []sorting.Field{{Name: "closed_at"}, {Name: "id"}},
+			values:   []*string{nil, text("7")},
+			wantSQL:  "((t.closed_at IS NULL AND t.id > CAST(CAST(? AS text) AS bigint)))",
+			wantVars: []interface{}{"7"},
+		},
+		{
+			name:     "voor een NULL waarde alle waarden",
+			keys:     []sorting.Field{{Name: "closed_at"}, {Name: "id"}},
+			values:   []*string{nil, text("7")},
+			backward: true,
+			wantSQL:  "((t.closed_at IS NOT NULL) OR (t.closed_at IS NULL AND t.id < CAST(CAST(? AS text) AS bigint)))",
+			wantVars: []interface{}{"7"},
+		},
+		{
+			name:   "vars van de kolom gaan voor de waarde",
+			keys:   []sorting.Field{{Name: "cf.omzet"}, {Name: "id"}},
+			values: []*string{text("12.5"), text("7")},
+			wantSQL: "(((t.custom_fields->? > CAST(CAST(? AS text) AS jsonb) OR t.custom_fields->? IS NULL)) OR " +
+				"(t.custom_fields->? = CAST(CAST(? AS text) AS jsonb) AND t.id > CAST(CAST(? AS text) AS bigint)))",
+			wantVars: []interface{}{"omzet", "12.5", "omzet", "omzet", "12.5", "7"},
+		},
+		{
+			name:     "waarden met SQL blijven parameters",
+			keys:     []sorting.Field{{Name: "name"}, {Name: "id"}},
+			values:   []*string{text("x') OR 1=1 --"), text("7")},
+			wantSQL:  "(t.name, t.id) > (CAST(CAST(? AS text) AS text), CAST(CAST(? AS text) AS bigint))",
+			wantVars: []interface{}{"x') OR 1=1 --", "7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, vars := keysetCondition(tt.keys, tt.values, sorting.Columns(testColumns), tt.backward)
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s\nverwacht %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, verwacht %v", vars, tt.wantVars)
+			}
+			if placeholders := strings.Count(sql, "?"); placeholders != len(vars) {
+				t.Errorf("%d placeholders en %d vars", placeholders, len(vars))
+			}
+		})
+	}
+}
+
+func TestApplySortMismatch(t *testing.T) {
+	fields := []sorting.Field{{Name: "name"}}
+	tests := []struct {
+		name    string
+		request Request
+	}{
+		{"andere sortering", Request{Size: 10, After: &Cursor{Sort: "-name", Values: []*string{text("x")}, ID: 1}}},
+		{"te weinig waarden", Request{Size: 10, Before: &Cursor{Sort: "name", ID: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply(nil, tt.request, fields, sorting.Columns(testColumns), "id")
+			if !errors.Is(err, ErrSortMismatch) {
+				t.Errorf("Apply gaf %v, verwacht ErrSortMismatch", err)
+			}
+		})
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	after := Cursor{Sort: "name", Values: []*string{text("x")}, ID: 1}.Encode()
+
+	tests := []struct {
+		name    string
+		query   string
+		want    Request
+		wantErr bool
+	}{
+		{"standaard", "", Request{Page: 1, Size: 10, Count: CountExact}, false},
+		{"pagina en grootte", "page=3&page_size=25&count=NONE", Request{Page: 3, Size: 25, Count: CountNone}, false},
+		{"maximale grootte", "page_size=500", Request{Page: 1, Size: 100, Count: CountExact}, false},
+		{"ongeldige getallen", "page=-1&page_size=abc", Request{Page: 1, Size: 10, Count: CountExact}, false},
+		{"ongeldige count", "count=alles", Request{}, true},
+		{"after en before", "after=" + after + "&before=" + after, Request{}, true},
+		{"cursor en pagina", "after=" + after + "&page=2", Request{}, true},
+		{"ongeldige cursor", "after=xyz", Request{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			got, err := FromQuery(values, "page", "page_size", 10, 100)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("FromQuery(%q) gaf geen fout", tt.query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromQuery(%q) gaf fout: %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromQuery(%q) = %+v, verwacht %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	values, _ := url.ParseQuery("after=" + after)
+	got, err := FromQuery(values, "page", "page_size", 10, 100)
+	if err != nil || got.After == nil || got.After.ID != 1 || !got.Keyset() {
+		t.Errorf("FromQuery met after = %+v, %v", got, err)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	cursor := func(item *int) Cursor { return Cursor{ID: uint(*item)} }
+	token := func(id uint) string { return Cursor{ID: id}.Encode() }
+	from := &Cursor{ID: 100}
+
+	tests := []struct {
+		name      string
+		items     []int
+		request   Request
+		wantItems []int
+		wantInfo  Info
+	}{
+		{"eerste pagina met meer", []int{1, 2, 3}, Request{Size: 2}, []int{1, 2}, Info{HasMore: true, Next: token(2)}},
+		{"laatste pagina", []int{1, 2}, Request{Size: 2}, []int{1, 2}, Info{}},
+		{"na een cursor", []int{3, 4, 5}, Request{Size: 2, After: from}, []int{3, 4}, Info{HasMore: true, Next: token(4), Prev: token(3)}},
+		{"voor een cursor wordt omgedraaid", []int{4, 3, 2}, Request{Size: 2, Before: from}, []int{3, 4}, Info{HasMore: true, Next: token(4), Prev: token(3)}},
+		{"begin bereikt", []int{2, 1}, Request{Size: 2, Before: from}, []int{1, 2}, Info{HasMore: true, Next: token(2)}},
+		{"leeg", nil, Request{Size: 2, After: from}, nil, Info{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, info := Window(tt.items, tt.request, cursor)
+			if len(items) != len(tt.wantItems) || (len(items) > 0 && !reflect.DeepEqual(items, tt.wantItems)) {
+				t.Errorf("items = %v, verwacht %v", items, tt.wantItems)
+			}
+			if info != tt.wantInfo {
+				t.Errorf("info = %+v, verwacht %+v", info, tt.wantInfo)
+			}
+		})
+	}
+}
+
+func TestTotalPages(t *testing.T) {
+	tests := []struct {
+		total int64
+		size  int
+		want  int
+	}{
+		{0, 10, 1},
+		{1, 10, 1},
+		{10, 10, 1},
+		{11, 10, 2},
+		{95, 10, 10},
+	}
+	for _, tt := range tests {
+		if got := TotalPages(tt.total, tt.size); got != tt.want {
+			t.Errorf("TotalPages(%d, %d) = %d, verwacht %d", tt.total, tt.size, got, tt.want)
+		}
+	}
+}
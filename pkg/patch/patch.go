@@ -0,0 +1,502 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"odomosml/pkg/validation"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Media types voor PATCH requests
+const (
+	MediaTypeMergePatch = "application/merge-patch+json" // RFC 7396
+	MediaTypeJSONPatch  = "application/json-patch+json"  // RFC 6902
+	mediaTypeJSON       = "application/json"
+)
+
+// Fouten bij het toepassen van een patch
+var (
+	ErrUnsupportedMediaType = errors.New("gebruik Content-Type " + MediaTypeMergePatch + " of " + MediaTypeJSONPatch)
+	ErrInvalidPatch         = errors.New("ongeldige patch")
+	ErrTestFailed           = errors.New("test operatie mislukt")
+)
+
+// Status geeft de HTTP status voor een fout uit dit package: 415 bij een onbekend media type, 409 bij een
+// mislukte test operatie en 400 bij een ongeldige patch
+func Status(err error) (int, bool) {
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType, true
+	case errors.Is(err, ErrTestFailed):
+		return http.StatusConflict, true
+	case errors.Is(err, ErrInvalidPatch):
+		return http.StatusBadRequest, true
+	}
+	return 0, false
+}
+
+// Apply past een patch toe op een JSON document. Een gewone application/json body geldt als merge patch.
+func Apply(mediaType string, doc, patch []byte) ([]byte, error) {
+	switch mediaType {
+	case MediaTypeMergePatch, mediaTypeJSON:
+		return MergePatch(doc, patch)
+	case MediaTypeJSONPatch:
+		return JSONPatch(doc, patch)
+	}
+	return nil, ErrUnsupportedMediaType
+}
+
+// MergePatch past een JSON Merge Patch (RFC 7396) toe: objecten worden recursief samengevoegd,
+// null verwijdert een veld en elke andere waarde vervangt de bestaande waarde
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+	}
+
+	return json.Marshal(mergeValue(target, changes))
+}
+
+// mergeValue voegt een merge patch samen met een waarde
+func mergeValue(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = mergeValue(object[key], value)
+	}
+	return object
+}
+
+// operation is één operatie van een JSON Patch
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONPatch past een JSON Patch (RFC 6902) toe. De operaties worden in volgorde uitgevoerd; faalt er één,
+// dan wordt niets toegepast.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: een JSON Patch is een lijst van operaties", ErrInvalidPatch)
+	}
+
+	for i, op := range operations {
+		target, err = op.apply(target)
+		if err != nil {
+			return nil, fmt.Errorf("%w (operatie %d, %s)", err, i+1, op.Op)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+// apply voert de operatie uit op het document en geeft het nieuwe document terug
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: path ontbreekt", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: value ontbreekt", ErrInvalidPatch)
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+		}
+
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if _, err := get(doc, path); err != nil || len(path) == 0 {
+				return value, err
+			}
+			if doc, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		}
+
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, fmt.Errorf("%w: %s heeft een andere waarde", ErrTestFailed, *op.Path)
+		}
+		return doc, nil
+
+	case "remove":
+		return remove(doc, path)
+
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: from ontbreekt", ErrInvalidPatch)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "copy" {
+			return add(doc, path, clone(value))
+		}
+		if *op.Path == *op.From {
+			return doc, nil
+		}
+		if strings.HasPrefix(*op.Path, *op.From+"/") {
+			return nil, fmt.Errorf("%w: een waarde kan niet naar een eigen onderdeel verplaatst worden", ErrInvalidPatch)
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+
+	return nil, fmt.Errorf("%w: onbekende operatie '%s'", ErrInvalidPatch, op.Op)
+}
+
+// parsePointer splitst een JSON Pointer (RFC 6901) in de onderdelen; "" is het hele document
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: pad '%s' moet met / beginnen", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// get geeft de waarde op een pad
+func get(doc interface{}, path []string) (interface{}, error) {
+	node := doc
+	for i, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, notFound(path[:i+1])
+			}
+			node = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+		default:
+			return nil, notFound(path[:i+1])
+		}
+	}
+	return node, nil
+}
+
+// add voegt een waarde toe op een pad: in een object wordt een bestaande waarde vervangen, in een lijst
+// wordt de waarde ingevoegd (- voegt achteraan toe)
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			if token == "-" {
+				return append(container, value), nil
+			}
+			index, err := arrayIndex(token, len(container))
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+		return nil, notFound(path)
+	})
+}
+
+// remove verwijdert de waarde op een pad
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: het hele document kan niet verwijderd worden", ErrInvalidPatch)
+	}
+	return updateParent(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := container[token]; !ok {
+				return nil, notFound(path)
+			}
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(container[:index], container[index+1:]...), nil
+		}
+		return nil, notFound(path)
+	})
+}
+
+// updateParent zoekt de container van de laatste waarde op het pad, laat fn die container aanpassen
+// en zet het resultaat terug; een lijst kan door fn langer of korter worden
+func updateParent(node interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, ok := container[path[0]]
+		if !ok {
+			return nil, notFound(path[:1])
+		}
+		updated, err := updateParent(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		container[path[0]] = updated
+		return container, nil
+	case []interface{}:
+		index, err := arrayIndex(path[0], len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := updateParent(container[index], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = updated
+		return container, nil
+	}
+	return nil, notFound(path[:1])
+}
+
+// arrayIndex controleert een index in een lijst; max is de hoogste toegestane index
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: '%s' is geen geldige index", ErrInvalidPatch, token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("%w: index %d bestaat niet", ErrInvalidPatch, index)
+	}
+	return index, nil
+}
+
+// notFound is de fout voor een pad dat niet in het document bestaat
+func notFound(path []string) error {
+	return fmt.Errorf("%w: pad '%s' bestaat niet", ErrInvalidPatch, formatPointer(path))
+}
+
+// formatPointer maakt van de onderdelen weer een JSON Pointer
+func formatPointer(path []string) string {
+	var b strings.Builder
+	for _, token := range path {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
+
+// CheckFields vergelijkt het document voor en na de patch. Alleen de velden in writable mogen veranderen;
+// een veld dat het document niet kent en niet schrijfbaar is, geldt als onbekend veld.
+func CheckFields(original, patched []byte, writable ...string) error {
+	before, err := decode(original)
+	if err != nil {
+		return err
+	}
+	after, err := decode(patched)
+	if err != nil {
+		return err
+	}
+
+	beforeFields, _ := before.(map[string]interface{})
+	afterFields, ok := after.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%w: het resultaat moet een object zijn", ErrInvalidPatch)
+	}
+
+	allowed := make(map[string]bool, len(writable))
+	for _, field := range writable {
+		allowed[field] = true
+	}
+
+	keys := make(map[string]bool, len(afterFields))
+	for key := range beforeFields {
+		keys[key] = true
+	}
+	for key := range afterFields {
+		keys[key] = true
+	}
+
+	var problems validation.Errors
+	for key := range keys {
+		oldValue, existed := beforeFields[key]
+		newValue, exists := afterFields[key]
+		if allowed[key] || (existed == exists && equal(oldValue, newValue)) {
+			continue
+		}
+		if existed {
+			problems = append(problems, validation.FieldError{Field: key, Message: fmt.Sprintf("veld '%s' kan niet gewijzigd worden", key)})
+		} else {
+			problems = append(problems, validation.FieldError{Field: key, Message: fmt.Sprintf("onbekend veld '%s'", key)})
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Slice(problems, func(i, j int) bool { return problems[i].Field < problems[j].Field })
+		return problems
+	}
+	return nil
+}
+
+// Decode leest het gepatchte document in v; een waarde van het verkeerde type wordt een fout van dat veld
+func Decode(data []byte, v interface{}) error {
+	err := json.Unmarshal(data, v)
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return validation.New(typeError.Field, "veld '%s' moet van het type %s zijn", typeError.Field, typeName(typeError.Type.Kind().String()))
+	}
+	return err
+}
+
+// typeName geeft de JSON naam van een Go type
+func typeName(kind string) string {
+	switch {
+	case kind == "string":
+		return "tekst"
+	case kind == "bool":
+		return "boolean"
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "getal"
+	case kind == "map", kind == "struct":
+		return "object"
+	case kind == "slice", kind == "array":
+		return "lijst"
+	}
+	return kind
+}
+
+// decode leest een JSON waarde; getallen blijven exact zoals ze in de JSON staan
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("onverwachte gegevens na de JSON waarde")
+	}
+	return value, nil
+}
+
+// equal vergelijkt twee JSON waarden; getallen zijn gelijk als hun waarde gelijk is, dus 1 is gelijk aan 1.0
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		left, okLeft := new(big.Rat).SetString(x.String())
+		right, okRight := new(big.Rat).SetString(y.String())
+		return okLeft && okRight && left.Cmp(right) == 0
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// clone maakt een diepe kopie van een JSON waarde
+func clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = clone(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = clone(item)
+		}
+		return copied
+	}
+	return value
+}
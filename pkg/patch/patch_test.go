@@ -0,0 +1,190 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"odomosml/pkg/validation"
+	"reflect"
+	"testing"
+)
+
+// sameJSON vergelijkt twee JSON documenten los van de volgorde van de velden
+func sameJSON(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+	var a, b interface{}
+	if err := json.Unmarshal(got, &a); err != nil {
+		t.Fatalf("resultaat is geen JSON: %s", got)
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatalf("verwachting is geen JSON: %s", want)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+const customer = `{"id":1,"name":"Jansen","version":3,"tags":["a","b"],"custom_fields":{"omzet":1000,"regio":"noord"}}`
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"veld vervangen", `{"name":"De Vries"}`, `{"id":1,"name":"De Vries","version":3,"tags":["a","b"],"custom_fields":{"omzet":1000,"regio":"noord"}}`},
+		{"null verwijdert", `{"tags":null}`, `{"id":1,"name":"Jansen","version":3,"custom_fields":{"omzet":1000,"regio":"noord"}}`},
+		{"object samenvoegen", `{"custom_fields":{"regio":null,"branche":"bouw"}}`, `{"id":1,"name":"Jansen","version":3,"tags":["a","b"],"custom_fields":{"omzet":1000,"branche":"bouw"}}`},
+		{"lijst wordt vervangen", `{"tags":["c"]}`, `{"id":1,"name":"Jansen","version":3,"tags":["c"],"custom_fields":{"omzet":1000,"regio":"noord"}}`},
+		{"leeg", `{}`, customer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(MediaTypeMergePatch, []byte(customer), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply gaf fout: %v", err)
+			}
+			if !sameJSON(t, got, tt.want) {
+				t.Errorf("Apply = %s, verwacht %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"replace", `[{"op":"replace","path":"/name","value":"De Vries"}]`, `{"id":1,"name":"De Vries","version":3,"tags":["a","b"],"custom_fields":{"omzet":1000,"regio":"noord"}}`},
+		{"add in lijst", `[{"op":"add","path":"/tags/1","value":"x"}]`, `{"id":1,"name":"Jansen","version":3,"tags":["a","x","b"],"custom_fields":{"omzet":1000,"regio":"noord"}}`},
+		{"add achteraan", `[{"op":"add","path":"/tags/-","value":"c"}]`, `{"id":1,"name":"Jansen","version":3,"tags":["a","b","c"],"custom_fields":{"omzet":1000,"regio":"noord"}}`},
+		{"remove", `[{"op":"remove","path":"/custom_fields/regio"}]`, `{"id":1,"name":"Jansen","version":3,"tags":["a","b"],"custom_fields":{"omzet":1000}}`},
+		{"move", `[{"op":"move","from":"/custom_fields/regio","path":"/custom_fields/gebied"}]`, `{"id":1,"name":"Jansen","version":3,"tags":["a","b"],"custom_fields":{"omzet":1000,"gebied":"noord"}}`},
+		{"copy", `[{"op":"copy","from":"/tags/0","path":"/tags/-"}]`, `{"id":1,"name":"Jansen","version":3,"tags":["a","b","a"],"custom_fields":{"omzet":1000,"regio":"noord"}}`},
+		{"escapes in pad", `[{"op":"add","path":"/custom_fields/a~1b~0c","value":1}]`, `{"id":1,"name":"Jansen","version":3,"tags":["a","b"],"custom_fields":{"omzet":1000,"regio":"noord","a/b~c":1}}`},
+		{"test en replace", `[{"op":"test","path":"/version","value":3},{"op":"replace","path":"/name","value":"X"}]`, `{"id":1,"name":"X","version":3,"tags":["a","b"],"custom_fields":{"omzet":1000,"regio":"noord"}}`},
+		{"test vergelijkt getallen op waarde", `[{"op":"test","path":"/custom_fields/omzet","value":1000.0},{"op":"test","path":"/version","value":3e0}]`, customer},
+		{"test op object", `[{"op":"test","path":"/custom_fields","value":{"regio":"noord","omzet":1e3}}]`, customer},
+		{"test op lijst", `[{"op":"test","path":"/tags","value":["a","b"]}]`, customer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(MediaTypeJSONPatch, []byte(customer), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply gaf fout: %v", err)
+			}
+			if !sameJSON(t, got, tt.want) {
+				t.Errorf("Apply = %s, verwacht %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		patch      string
+		wantErr    error
+		wantStatus int
+	}{
+		{"test met andere waarde", `[{"op":"test","path":"/version","value":2}]`, ErrTestFailed, http.StatusConflict},
+		{"test met ander type", `[{"op":"test","path":"/version","value":"3"}]`, ErrTestFailed, http.StatusConflict},
+		{"test op lijst in andere volgorde", `[{"op":"test","path":"/tags","value":["b","a"]}]`, ErrTestFailed, http.StatusConflict},
+		{"test faalt na een wijziging", `[{"op":"replace","path":"/name","value":"X"},{"op":"test","path":"/name","value":"Jansen"}]`, ErrTestFailed, http.StatusConflict},
+		{"test op onbekend pad", `[{"op":"test","path":"/onbekend","value":1}]`, ErrInvalidPatch, http.StatusBadRequest},
+		{"geen lijst", `{"op":"replace","path":"/name","value":"X"}`, ErrInvalidPatch, http.StatusBadRequest},
+		{"onbekende operatie", `[{"op":"rename","path":"/name"}]`, ErrInvalidPatch, http.StatusBadRequest},
+		{"pad ontbreekt", `[{"op":"remove"}]`, ErrInvalidPatch, http.StatusBadRequest},
+		{"value ontbreekt", `[{"op":"add","path":"/x"}]`, ErrInvalidPatch, http.StatusBadRequest},
+		{"pad zonder /", `[{"op":"remove","path":"name"}]`, ErrInvalidPatch, http.StatusBadRequest},
+		{"index buiten lijst", `[{"op":"remove","path":"/tags/5"}]`, ErrInvalidPatch, http.StatusBadRequest},
+		{"index met voorloopnul", `[{"op":"remove","path":"/tags/01"}]`, ErrInvalidPatch, http.StatusBadRequest},
+		{"hele document verwijderen", `[{"op":"remove","path":""}]`, ErrInvalidPatch, http.StatusBadRequest},
+		{"naar eigen onderdeel verplaatsen", `[{"op":"move","from":"/custom_fields","path":"/custom_fields/x"}]`, ErrInvalidPatch, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(MediaTypeJSONPatch, []byte(customer), []byte(tt.patch))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply = %s, %v; verwacht %v", got, err, tt.wantErr)
+			}
+			if status, ok := Status(err); !ok || status != tt.wantStatus {
+				t.Errorf("Status = %d, verwacht %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestApplyMediaType(t *testing.T) {
+	if _, err := Apply("application/json", []byte(customer), []byte(`{"name":"X"}`)); err != nil {
+		t.Errorf("application/json geldt als merge patch, maar gaf fout: %v", err)
+	}
+	_, err := Apply("text/plain", []byte(customer), []byte(`{"name":"X"}`))
+	if status, _ := Status(err); !errors.Is(err, ErrUnsupportedMediaType) || status != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain gaf %v (%d), verwacht ErrUnsupportedMediaType (415)", err, status)
+	}
+}
+
+func TestCheckFields(t *testing.T) {
+	writable := []string{"name", "tags", "custom_fields"}
+
+	tests := []struct {
+		name       string
+		patched    string
+		wantFields []string
+	}{
+		{"alleen schrijfbare velden", `{"id":1,"name":"X","version":3,"tags":[],"custom_fields":{}}`, nil},
+		{"schrijfbaar veld verwijderd", `{"id":1,"version":3}`, nil},
+		{"ongewijzigd getal in andere notatie", `{"id":1.0,"name":"Jansen","version":3e0,"tags":["a","b"],"custom_fields":{"omzet":1000,"regio":"noord"}}`, nil},
+		{"vast veld gewijzigd", `{"id":2,"name":"Jansen","version":3,"tags":["a","b"],"custom_fields":{"omzet":1000,"regio":"noord"}}`, []string{"id"}},
+		{"vast veld verwijderd", `{"id":1,"name":"Jansen","tags":["a","b"],"custom_fields":{"omzet":1000,"regio":"noord"}}`, []string{"version"}},
+		{"onbekende velden", `{"id":1,"name":"Jansen","version":3,"role":"ADMIN","extra":true}`, []string{"extra", "role"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckFields([]byte(customer), []byte(tt.patched), writable...)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("CheckFields gaf fout: %v", err)
+				}
+				return
+			}
+
+			var problems validation.Errors
+			if !errors.As(err, &problems) {
+				t.Fatalf("CheckFields gaf %v, verwacht validatiefouten", err)
+			}
+			var fields []string
+			for _, problem := range problems {
+				fields = append(fields, problem.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("velden = %v, verwacht %v", fields, tt.wantFields)
+			}
+		})
+	}
+
+	if err := CheckFields([]byte(customer), []byte(`["geen object"]`), writable...); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("een lijst als resultaat gaf %v, verwacht ErrInvalidPatch", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var target struct {
+		Name    string `json:"name"`
+		Version uint   `json:"version"`
+	}
+
+	err := Decode([]byte(`{"name":1,"version":3}`), &target)
+	var problems validation.Errors
+	if !errors.As(err, &problems) || len(problems) != 1 || problems[0].Field != "name" {
+		t.Fatalf("Decode gaf %v, verwacht een fout bij name", err)
+	}
+	if problems[0].Message != "veld 'name' moet van het type tekst zijn" {
+		t.Errorf("melding = %q", problems[0].Message)
+	}
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is een taak die de scheduler met een vaste tussenpoos uitvoert
+type Job struct {
+	Name     string        // Unieke naam; bepaalt ook de advisory lock
+	Interval time.Duration // Tijd tussen twee runs
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler voert jobs op de achtergrond uit. Als er meerdere API instanties draaien, voert steeds
+// maar één instantie een job tegelijk uit: elke run neemt eerst een Postgres advisory lock op de
+// naam van de job, en een instantie die de lock niet krijgt slaat de run over.
+type Scheduler struct {
+	db   *sql.DB
+	jobs []Job
+	wg   sync.WaitGroup
+}
+
+// New maakt een nieuwe Scheduler die de advisory locks op db neemt
+func New(db *sql.DB) *Scheduler {
+	return &Scheduler{db: db}
+}
+
+// Register voegt een job toe; jobs moeten voor Start geregistreerd zijn
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start start alle jobs op de achtergrond. Ze stoppen als ctx wordt geannuleerd; Wait wacht daarop.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		if job.Interval <= 0 {
+			log.Printf("Waarschuwing: job %s heeft geen geldige interval en wordt niet gestart", job.Name)
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+}
+
+// Wait wacht tot alle jobs gestopt zijn
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// loop voert een job direct en daarna na elke interval uit, tot ctx wordt geannuleerd
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx, job); err != nil && ctx.Err() == nil {
+			log.Printf("Waarschuwing: job %s is mislukt: %v", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce voert een job één keer uit als geen andere instantie hem op dit moment uitvoert. De lock
+// is een session lock en hoort bij de verbinding, dus de hele run gebruikt één vaste verbinding uit
+// de pool. Valt de verbinding weg, dan geeft Postgres de lock vanzelf vrij.
+func (s *Scheduler) RunOnce(ctx context.Context, job Job) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	key := lockKey(job.Name)
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	defer func() {
+		// Ook bij een geannuleerde ctx moet de lock worden vrijgegeven voordat de verbinding terug gaat naar de pool
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Printf("Waarschuwing: kon lock van job %s niet vrijgeven: %v", job.Name, err)
+		}
+	}()
+
+	return job.Run(ctx)
+}
+
+// lockKey zet de naam van een job om in de sleutel van de advisory lock
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("scheduler:" + name))
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,260 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLocks speelt de advisory locks van Postgres na: een lock hoort bij een verbinding, en
+// pg_try_advisory_lock geeft false als een andere verbinding hem heeft
+type fakeLocks struct {
+	mu      sync.Mutex
+	holders map[int64]*fakeConn
+	unlocks int
+}
+
+func newFakeDB(t *testing.T) (*sql.DB, *fakeLocks) {
+	locks := &fakeLocks{holders: map[int64]*fakeConn{}}
+	db := sql.OpenDB(locks)
+	t.Cleanup(func() { db.Close() })
+	return db, locks
+}
+
+// holdBy laat een andere instantie de lock van een job vasthouden
+func (l *fakeLocks) holdBy(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.holders[lockKey(name)] = &fakeConn{}
+}
+
+func (l *fakeLocks) held(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.holders[lockKey(name)] != nil
+}
+
+// Connect implementeert driver.Connector
+func (l *fakeLocks) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{locks: l}, nil
+}
+
+// Driver implementeert driver.Connector
+func (l *fakeLocks) Driver() driver.Driver {
+	return nil
+}
+
+// fakeConn is een verbinding die alleen de queries van de scheduler kent
+type fakeConn struct {
+	locks *fakeLocks
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("niet ondersteund")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("niet ondersteund")
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "pg_try_advisory_lock") {
+		return nil, errors.New("onbekende query: " + query)
+	}
+	key := args[0].Value.(int64)
+
+	c.locks.mu.Lock()
+	defer c.locks.mu.Unlock()
+	holder := c.locks.holders[key]
+	if holder == nil {
+		c.locks.holders[key] = c
+	}
+	return &boolRows{value: holder == nil || holder == c}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.Contains(query, "pg_advisory_unlock") {
+		return nil, errors.New("onbekende query: " + query)
+	}
+	key := args[0].Value.(int64)
+
+	c.locks.mu.Lock()
+	defer c.locks.mu.Unlock()
+	if c.locks.holders[key] == c {
+		delete(c.locks.holders, key)
+		c.locks.unlocks++
+	}
+	return driver.RowsAffected(0), nil
+}
+
+// boolRows is het resultaat van één rij met één boolean
+type boolRows struct {
+	value bool
+	done  bool
+}
+
+func (r *boolRows) Columns() []string {
+	return []string{"locked"}
+}
+
+func (r *boolRows) Close() error {
+	return nil
+}
+
+func (r *boolRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+func TestRunOnce(t *testing.T) {
+	errFailed := errors.New("mislukt")
+
+	tests := []struct {
+		name        string
+		heldByOther bool
+		runErr      error
+		wantRun     bool
+		wantErr     error
+	}{
+		{name: "lock vrij", wantRun: true},
+		{name: "lock bij een andere instantie", heldByOther: true, wantRun: false},
+		{name: "mislukte run geeft de lock vrij", runErr: errFailed, wantRun: true, wantErr: errFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, locks := newFakeDB(t)
+			if tt.heldByOther {
+				locks.holdBy("herinneringen")
+			}
+
+			ran := false
+			err := New(db).RunOnce(context.Background(), Job{Name: "herinneringen", Run: func(ctx context.Context) error {
+				ran = true
+				if !locks.held("herinneringen") {
+					t.Error("job draait zonder lock")
+				}
+				return tt.runErr
+			}})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RunOnce gaf %v, verwacht %v", err, tt.wantErr)
+			}
+			if ran != tt.wantRun {
+				t.Errorf("job uitgevoerd: %v, verwacht %v", ran, tt.wantRun)
+			}
+			if tt.heldByOther {
+				// De lock van de andere instantie blijft staan
+				if !locks.held("herinneringen") || locks.unlocks != 0 {
+					t.Error("lock van een andere instantie vrijgegeven")
+				}
+			} else if locks.held("herinneringen") || locks.unlocks != 1 {
+				t.Error("lock niet vrijgegeven na de run")
+			}
+		})
+	}
+}
+
+func TestRunOnceConcurrent(t *testing.T) {
+	db, _ := newFakeDB(t)
+	scheduler := New(db)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	job := Job{Name: "verlopen facturen", Run: func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}}
+
+	done := make(chan error)
+	go func() { done <- scheduler.RunOnce(context.Background(), job) }()
+	<-started
+
+	// Zolang de eerste run loopt slaat een tweede run dezelfde job over, maar een andere job draait wel
+	skipped := true
+	second := job
+	second.Run = func(ctx context.Context) error {
+		skipped = false
+		return nil
+	}
+	if err := scheduler.RunOnce(context.Background(), second); err != nil || !skipped {
+		t.Errorf("tweede run: fout %v, overgeslagen %v", err, skipped)
+	}
+
+	other := false
+	if err := scheduler.RunOnce(context.Background(), Job{Name: "bewaartermijn", Run: func(ctx context.Context) error {
+		other = true
+		return nil
+	}}); err != nil || !other {
+		t.Errorf("andere job: fout %v, uitgevoerd %v", err, other)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("eerste run gaf fout: %v", err)
+	}
+
+	// Daarna is de lock weer vrij
+	if err := scheduler.RunOnce(context.Background(), second); err != nil || skipped {
+		t.Errorf("run na de eerste: fout %v, overgeslagen %v", err, skipped)
+	}
+}
+
+func TestStart(t *testing.T) {
+	db, _ := newFakeDB(t)
+	scheduler := New(db)
+
+	runs := make(chan string, 10)
+	scheduler.Register(Job{Name: "direct", Interval: time.Hour, Run: func(ctx context.Context) error {
+		runs <- "direct"
+		return nil
+	}})
+	scheduler.Register(Job{Name: "zonder interval", Run: func(ctx context.Context) error {
+		runs <- "zonder interval"
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.Start(ctx)
+
+	// Een job draait direct bij het starten, niet pas na de eerste interval
+	select {
+	case name := <-runs:
+		if name != "direct" {
+			t.Errorf("job %s uitgevoerd, verwacht direct", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job niet direct uitgevoerd")
+	}
+
+	cancel()
+	scheduler.Wait()
+	close(runs)
+	for name := range runs {
+		t.Errorf("onverwachte run van %s", name)
+	}
+}
+
+func TestLockKey(t *testing.T) {
+	if lockKey("herinneringen") != lockKey("herinneringen") {
+		t.Error("lockKey is niet stabiel")
+	}
+	if lockKey("herinneringen") == lockKey("bewaartermijn") {
+		t.Error("verschillende jobs hebben dezelfde lock")
+	}
+}
@@ -0,0 +1,149 @@
+package sorting
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Field is een sorteerveld met richting
+type Field struct {
+	Name string
+	Desc bool
+}
+
+// String geeft het veld terug in de notatie van de sort parameter, bijv. "-created_at"
+func (f Field) String() string {
+	if f.Desc {
+		return "-" + f.Name
+	}
+	return f.Name
+}
+
+// Format zet sorteervelden om naar de notatie van de sort parameter, bijv. "-created_at,name"
+func Format(fields []Field) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Parse parst een sort parameter zoals "-created_at,name": komma-gescheiden velden, een minteken
+// voor aflopend sorteren. Alleen de velden in allowed zijn toegestaan; een entry die op een punt
+// eindigt (bijv. "cf.") staat alle velden met dat prefix toe.
+func Parse(value string, allowed ...string) ([]Field, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var fields []Field
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+
+		field := Field{Name: part}
+		if name, ok := strings.CutPrefix(part, "-"); ok {
+			field = Field{Name: name, Desc: true}
+		} else if name, ok := strings.CutPrefix(part, "+"); ok {
+			field = Field{Name: name}
+		}
+
+		if field.Name == "" {
+			return nil, fmt.Errorf("ongeldige sortering '%s'", value)
+		}
+		if !isAllowed(field.Name, allowed) {
+			return nil, fmt.Errorf("er kan niet gesorteerd worden op '%s', toegestaan: %s", field.Name, strings.Join(allowed, ", "))
+		}
+		if seen[field.Name] {
+			return nil, fmt.Errorf("sorteerveld '%s' komt meerdere keren voor", field.Name)
+		}
+		seen[field.Name] = true
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// isAllowed controleert een veldnaam tegen de toegestane velden en prefixes
+func isAllowed(name string, allowed []string) bool {
+	for _, candidate := range allowed {
+		if strings.HasSuffix(candidate, ".") {
+			if strings.HasPrefix(name, candidate) && len(name) > len(candidate) {
+				return true
+			}
+		} else if name == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// Column is de SQL expressie achter een sorteerveld
+type Column struct {
+	SQL  string
+	Vars []interface{}
+	// Type is het Postgres type van de expressie, bijv. text of timestamptz; nodig om cursorwaarden te vergelijken
+	Type string
+	// ValueType is bij jsonb het JSON type van de waarde: "number", "boolean" of "string". Bepaalt hoe
+	// een filter de waarde leest; leeg telt als "string".
+	ValueType string
+	// Nullable geeft aan dat de expressie NULL kan zijn; NULL waarden komen dan altijd achteraan
+	Nullable bool
+}
+
+// Keys geeft de sorteervelden aangevuld met idField als tiebreaker, tenzij daar al op gesorteerd wordt
+func Keys(fields []Field, idField string) []Field {
+	for _, field := range fields {
+		if field.Name == idField {
+			return fields
+		}
+	}
+	return append(append([]Field{}, fields...), Field{Name: idField})
+}
+
+// Apply sorteert de query op de velden en daarna op idField, zodat rijen met gelijke waarden
+// altijd in dezelfde volgorde staan en paginering deterministisch is. column vertaalt een
+// (door Parse gevalideerde) veldnaam naar een SQL expressie.
+func Apply(query *gorm.DB, fields []Field, column func(name string) Column, idField string) *gorm.DB {
+	return Order(query, Keys(fields, idField), column, false)
+}
+
+// Order sorteert de query op de velden, waarbij NULL waarden achteraan komen. Met reverse wordt de
+// volgorde precies omgedraaid (inclusief de NULL waarden), voor het ophalen van een vorige pagina.
+// Voor kolommen die niet NULL kunnen zijn blijft NULLS weg, zodat Postgres een gewone index kan gebruiken.
+//
+// Alle velden gaan in één ORDER BY expressie: gorm negeert een clause.OrderBy in Order, en bij
+// meerdere expressies houdt een ORDER BY clause alleen de laatste over.
+func Order(query *gorm.DB, fields []Field, column func(name string) Column, reverse bool) *gorm.DB {
+	if len(fields) == 0 {
+		return query
+	}
+
+	parts := make([]string, len(fields))
+	var vars []interface{}
+	for i, field := range fields {
+		col := column(field.Name)
+		parts[i] = col.SQL + " ASC"
+		if field.Desc != reverse {
+			parts[i] = col.SQL + " DESC"
+		}
+		if col.Nullable && reverse {
+			parts[i] += " NULLS FIRST"
+		} else if col.Nullable {
+			parts[i] += " NULLS LAST"
+		}
+		vars = append(vars, col.Vars...)
+	}
+	return query.Clauses(clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(parts, ", "), Vars: vars}})
+}
+
+// Columns maakt een column functie voor Apply van een vaste koppeling van veld naar kolom
+func Columns(columns map[string]Column) func(name string) Column {
+	return func(name string) Column {
+		return columns[name]
+	}
+}
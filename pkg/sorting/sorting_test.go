@@ -0,0 +1,140 @@
+package sorting
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestParse(t *testing.T) {
+	allowed := []string{"name", "created_at", "id", "cf."}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    []Field
+		wantErr string
+	}{
+		{name: "leeg", value: "  "},
+		{name: "een veld", value: "name", want: []Field{{Name: "name"}}},
+		{
+			name:  "meerdere velden en richtingen",
+			value: " -created_at, +name ,id",
+			want:  []Field{{Name: "created_at", Desc: true}, {Name: "name"}, {Name: "id"}},
+		},
+		{name: "vrij veld via prefix", value: "-cf.omzet", want: []Field{{Name: "cf.omzet", Desc: true}}},
+		{name: "alleen het prefix", value: "cf.", wantErr: "er kan niet gesorteerd worden op 'cf.', toegestaan: name, created_at, id, cf."},
+		{name: "onbekend veld", value: "name,email", wantErr: "er kan niet gesorteerd worden op 'email', toegestaan: name, created_at, id, cf."},
+		{name: "dubbel veld", value: "name,-name", wantErr: "sorteerveld 'name' komt meerdere keren voor"},
+		{name: "lege entry", value: "name,,id", wantErr: "ongeldige sortering 'name,,id'"},
+		{name: "alleen een minteken", value: "-", wantErr: "ongeldige sortering '-'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.value, allowed...)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Parse(%q) gaf %v, verwacht %q", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) gaf fout: %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, verwacht %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	fields := []Field{{Name: "created_at", Desc: true}, {Name: "name"}}
+	if got := Format(fields); got != "-created_at,name" {
+		t.Errorf("Format = %s, verwacht -created_at,name", got)
+	}
+
+	parsed, err := Parse(Format(fields), "name", "created_at")
+	if err != nil || !reflect.DeepEqual(parsed, fields) {
+		t.Errorf("Parse(Format) = %+v, %v; verwacht %+v", parsed, err, fields)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	fields := []Field{{Name: "name"}}
+	if got, want := Keys(fields, "id"), []Field{{Name: "name"}, {Name: "id"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys = %+v, verwacht %+v", got, want)
+	}
+	if len(fields) != 1 {
+		t.Errorf("Keys heeft de velden aangepast: %+v", fields)
+	}
+
+	// Wie al op ID sorteert krijgt geen tweede ID, ook niet als het niet de laatste sleutel is
+	withID := []Field{{Name: "id", Desc: true}, {Name: "name"}}
+	if got := Keys(withID, "id"); !reflect.DeepEqual(got, withID) {
+		t.Errorf("Keys = %+v, verwacht %+v", got, withID)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open gaf fout: %v", err)
+	}
+
+	column := Columns(map[string]Column{
+		"id":        {SQL: "t.id"},
+		"name":      {SQL: "t.name"},
+		"closed_at": {SQL: "t.closed_at", Nullable: true},
+		"cf.omzet":  {SQL: "t.custom_fields->?", Vars: []interface{}{"omzet"}, Nullable: true},
+	})
+
+	tests := []struct {
+		name     string
+		fields   []Field
+		reverse  bool
+		apply    bool
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:    "ID als tiebreaker",
+			fields:  []Field{{Name: "name", Desc: true}},
+			apply:   true,
+			wantSQL: `SELECT * FROM "t" ORDER BY t.name DESC, t.id ASC`,
+		},
+		{
+			name:     "NULL waarden achteraan en vars van de kolom",
+			fields:   []Field{{Name: "closed_at", Desc: true}, {Name: "cf.omzet"}, {Name: "id"}},
+			wantSQL:  `SELECT * FROM "t" ORDER BY t.closed_at DESC NULLS LAST, t.custom_fields->$1 ASC NULLS LAST, t.id ASC`,
+			wantVars: []interface{}{"omzet"},
+		},
+		{
+			name:    "omgekeerd voor een vorige pagina",
+			fields:  []Field{{Name: "closed_at", Desc: true}, {Name: "id"}},
+			reverse: true,
+			wantSQL: `SELECT * FROM "t" ORDER BY t.closed_at ASC NULLS FIRST, t.id DESC`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := db.Table("t")
+			if tt.apply {
+				query = Apply(query, tt.fields, column, "id")
+			} else {
+				query = Order(query, tt.fields, column, tt.reverse)
+			}
+			stmt := query.Find(&[]map[string]interface{}{}).Statement
+			if sql := stmt.SQL.String(); sql != tt.wantSQL {
+				t.Errorf("SQL = %s\nverwacht %s", sql, tt.wantSQL)
+			}
+			if len(stmt.Vars) != len(tt.wantVars) || (len(tt.wantVars) > 0 && !reflect.DeepEqual(stmt.Vars, tt.wantVars)) {
+				t.Errorf("vars = %v, verwacht %v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"odomosml/config"
+)
+
+// ErrNotFound wordt teruggegeven als een object niet in de opslag bestaat
+var ErrNotFound = errors.New("bestand niet gevonden in opslag")
+
+// Object is een geopend bestand uit de opslag. Seek is nodig voor Range requests.
+type Object interface {
+	io.ReadSeekCloser
+}
+
+// Storage is een opslag voor bestanden op basis van een key
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (Object, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New maakt de opslag aan die in de configuratie is gekozen
+func New(cfg *config.Config) (Storage, error) {
+	switch cfg.StorageDriver {
+	case "", "local":
+		return NewLocalStorage(cfg.StorageLocalPath)
+	case "s3":
+		return NewS3Storage(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			UseSSL:    cfg.S3UseSSL,
+		})
+	default:
+		return nil, fmt.Errorf("onbekende storage driver '%s'", cfg.StorageDriver)
+	}
+}
@@ -0,0 +1,283 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is een minimale S3-compatibele server voor de tests: buckets en objecten in het geheugen,
+// zonder controle van de handtekening. Het ondersteunt precies wat S3Storage gebruikt.
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{buckets: map[string]map[string][]byte{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	objects, bucketExists := f.buckets[bucket]
+
+	if key == "" {
+		switch r.Method {
+		case http.MethodHead:
+			if !bucketExists {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPut:
+			f.buckets[bucket] = map[string][]byte{}
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+		return
+	}
+	if !bucketExists {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, r, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		objects[key] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(body)))
+	case http.MethodHead, http.MethodGet:
+		data, ok := objects[key]
+		if !ok {
+			writeS3Error(w, r, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(data)))
+		w.Header().Set("Last-Modified", time.Date(2024, 2, 25, 20, 30, 0, 0, time.UTC).Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/octet-stream")
+
+		start, end := 0, len(data)
+		if spec := r.Header.Get("Range"); spec != "" {
+			from, to, _ := strings.Cut(strings.TrimPrefix(spec, "bytes="), "-")
+			start, _ = strconv.Atoi(from)
+			if to != "" {
+				last, _ := strconv.Atoi(to)
+				end = min(last+1, len(data))
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(data)))
+			w.Header().Set("Content-Length", strconv.Itoa(end-start))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		}
+		if r.Method == http.MethodGet {
+			w.Write(data[start:end])
+		}
+	case http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// writeS3Error schrijft een S3 foutmelding; bij HEAD zonder body, zoals S3 zelf
+func writeS3Error(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+	}
+}
+
+// readS3Body leest de body van een PUT; zonder TLS stuurt de client die in aws-chunked encoding
+// (<lengte in hex>;chunk-signature=...\r\n<data>\r\n, tot een chunk van lengte 0)
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var body bytes.Buffer
+	reader := bufio.NewReader(r.Body)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return body.Bytes(), nil
+		}
+		if _, err := io.CopyN(&body, reader, size); err != nil {
+			return nil, err
+		}
+		if _, err := reader.Discard(2); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newTestS3Storages maakt een S3Storage tegen de fake server, en als TEST_S3_ENDPOINT gezet is ook
+// tegen een echte S3-compatibele opslag zoals MinIO
+func newTestS3Storages(t *testing.T) map[string]Storage {
+	t.Helper()
+	server := httptest.NewServer(newFakeS3())
+	t.Cleanup(server.Close)
+
+	endpoint, _ := url.Parse(server.URL)
+	fake, err := NewS3Storage(S3Options{Endpoint: endpoint.Host, AccessKey: "test", SecretKey: "testtest", Bucket: "bijlagen", Region: "us-east-1"})
+	if err != nil {
+		t.Fatalf("NewS3Storage gaf fout: %v", err)
+	}
+	storages := map[string]Storage{"s3-fake": fake}
+
+	if endpoint := os.Getenv("TEST_S3_ENDPOINT"); endpoint != "" {
+		external, err := NewS3Storage(S3Options{
+			Endpoint:  endpoint,
+			AccessKey: os.Getenv("TEST_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("TEST_S3_SECRET_KEY"),
+			Bucket:    "odomosml-test",
+			Region:    "us-east-1",
+			UseSSL:    os.Getenv("TEST_S3_USE_SSL") == "true",
+		})
+		if err != nil {
+			t.Fatalf("NewS3Storage(%s) gaf fout: %v", endpoint, err)
+		}
+		storages["s3"] = external
+	}
+	return storages
+}
+
+func TestStorage(t *testing.T) {
+	local, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage gaf fout: %v", err)
+	}
+	storages := newTestS3Storages(t)
+	storages["local"] = local
+	if _, ok := storages["s3"]; !ok {
+		t.Log("TEST_S3_ENDPOINT is niet gezet; S3 alleen getest tegen de fake server")
+	}
+
+	content := []byte(strings.Repeat("0123456789", 1000))
+	const key = "ab/cd/abcdef0123456789"
+
+	for name, store := range storages {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if exists, err := store.Exists(ctx, key); err != nil || exists {
+				t.Fatalf("Exists voor Put = %v, %v; verwacht false", exists, err)
+			}
+			if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Open voor Put gaf %v, verwacht ErrNotFound", err)
+			}
+
+			if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+				t.Fatalf("Put gaf fout: %v", err)
+			}
+			if exists, err := store.Exists(ctx, key); err != nil || !exists {
+				t.Fatalf("Exists na Put = %v, %v; verwacht true", exists, err)
+			}
+
+			object, err := store.Open(ctx, key)
+			if err != nil {
+				t.Fatalf("Open gaf fout: %v", err)
+			}
+			got, err := io.ReadAll(object)
+			if err != nil || !bytes.Equal(got, content) {
+				t.Errorf("gelezen %d bytes (%v), verwacht %d", len(got), err, len(content))
+			}
+			object.Close()
+
+			// Range requests lopen via http.ServeContent, net als bij het downloaden van een bijlage
+			tests := []struct {
+				rangeHeader string
+				want        []byte
+			}{
+				{"bytes=0-9", content[:10]},
+				{"bytes=9995-", content[9995:]},
+				{"bytes=-3", content[len(content)-3:]},
+				{"bytes=5000-5004", content[5000:5005]},
+			}
+			for _, tt := range tests {
+				object, err := store.Open(ctx, key)
+				if err != nil {
+					t.Fatalf("Open gaf fout: %v", err)
+				}
+				request := httptest.NewRequest(http.MethodGet, "/", nil)
+				request.Header.Set("Range", tt.rangeHeader)
+				recorder := httptest.NewRecorder()
+				http.ServeContent(recorder, request, "bijlage.txt", time.Time{}, object)
+				object.Close()
+
+				if recorder.Code != http.StatusPartialContent || !bytes.Equal(recorder.Body.Bytes(), tt.want) {
+					t.Errorf("%s: status %d, %q; verwacht 206, %q", tt.rangeHeader, recorder.Code, recorder.Body.String(), tt.want)
+				}
+			}
+
+			// Overschrijven vervangt de inhoud
+			if err := store.Put(ctx, key, strings.NewReader("nieuw"), 5, "text/plain"); err != nil {
+				t.Fatalf("Put gaf fout: %v", err)
+			}
+			object, err = store.Open(ctx, key)
+			if err != nil {
+				t.Fatalf("Open gaf fout: %v", err)
+			}
+			if got, _ := io.ReadAll(object); string(got) != "nieuw" {
+				t.Errorf("na overschrijven gelezen %q", got)
+			}
+			object.Close()
+
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete gaf fout: %v", err)
+			}
+			if exists, err := store.Exists(ctx, key); err != nil || exists {
+				t.Errorf("Exists na Delete = %v, %v; verwacht false", exists, err)
+			}
+			if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Open na Delete gaf %v, verwacht ErrNotFound", err)
+			}
+			if err := store.Delete(ctx, key); err != nil {
+				t.Errorf("Delete van een niet bestaand object gaf fout: %v", err)
+			}
+		})
+	}
+}
+
+func TestLocalStorageInvalidKeys(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage gaf fout: %v", err)
+	}
+
+	for _, key := range []string{"", "/", "../buiten", "ab/../../buiten"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("Put(%q) gaf geen fout", key)
+		}
+		if _, err := store.Exists(context.Background(), key); err == nil {
+			t.Errorf("Exists(%q) gaf geen fout", key)
+		}
+	}
+}
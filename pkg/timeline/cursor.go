@@ -0,0 +1,94 @@
+package timeline
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Cursor wijst een positie aan in een tijdlijn die aflopend gesorteerd is op tijd, soort en ID.
+// Items uit verschillende bronnen (soorten) kunnen zo stabiel door elkaar gepagineerd worden.
+type Cursor struct {
+	Time time.Time
+	Kind string
+	ID   uint
+}
+
+// Encode zet de cursor om naar een opaque token
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d|%s|%d", c.Time.UnixNano(), c.Kind, c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode zet een token terug om naar een cursor
+func Decode(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("ongeldige cursor")
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, errors.New("ongeldige cursor")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("ongeldige cursor")
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return nil, errors.New("ongeldige cursor")
+	}
+
+	return &Cursor{
+		Time: time.Unix(0, nanos).UTC(),
+		Kind: parts[1],
+		ID:   uint(id),
+	}, nil
+}
+
+// Boundary beschrijft vanaf waar een bron items moet teruggeven (exclusief de cursor zelf)
+type Boundary struct {
+	Time      time.Time
+	ID        uint // Alleen gezet als de bron dezelfde soort is als de cursor
+	Inclusive bool // Items met exact dezelfde tijd horen er nog bij
+}
+
+// BoundaryFor berekent de grens voor een bron van de opgegeven soort.
+// Bij gelijke tijd komt de soort met de hoogste naam eerst, daarna het hoogste ID.
+func (c *Cursor) BoundaryFor(kind string) *Boundary {
+	if c == nil {
+		return nil
+	}
+
+	switch {
+	case kind == c.Kind:
+		return &Boundary{Time: c.Time, ID: c.ID}
+	case kind < c.Kind:
+		return &Boundary{Time: c.Time, Inclusive: true}
+	default:
+		return &Boundary{Time: c.Time}
+	}
+}
+
+// Apply beperkt een query tot de items na de grens in aflopende volgorde
+func (b *Boundary) Apply(query *gorm.DB, timeColumn, idColumn string) *gorm.DB {
+	if b == nil {
+		return query
+	}
+
+	switch {
+	case b.ID > 0:
+		return query.Where("("+timeColumn+" < ? OR ("+timeColumn+" = ? AND "+idColumn+" < ?))", b.Time, b.Time, b.ID)
+	case b.Inclusive:
+		return query.Where(timeColumn+" <= ?", b.Time)
+	default:
+		return query.Where(timeColumn+" < ?", b.Time)
+	}
+}
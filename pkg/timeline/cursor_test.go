@@ -0,0 +1,54 @@
+package timeline
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{Time: time.Date(2024, 2, 25, 20, 30, 0, 123456789, time.UTC), Kind: "activity", ID: 42}
+
+	decoded, err := Decode(cursor.Encode())
+	if err != nil {
+		t.Fatalf("Decode gaf fout: %v", err)
+	}
+	if !reflect.DeepEqual(*decoded, cursor) {
+		t.Errorf("Decode = %+v, verwacht %+v", *decoded, cursor)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, token := range []string{"", "!!", "MTIzfGFjdGl2aXR5", "YWJjfGFjdGl2aXR5fDE", "MTIzfGFjdGl2aXR5fC0x"} {
+		if cursor, err := Decode(token); err == nil {
+			t.Errorf("Decode(%q) = %+v, verwacht een fout", token, cursor)
+		}
+	}
+}
+
+func TestBoundaryFor(t *testing.T) {
+	at := time.Date(2024, 2, 25, 20, 30, 0, 0, time.UTC)
+	cursor := &Cursor{Time: at, Kind: "audit", ID: 7}
+
+	tests := []struct {
+		kind string
+		want *Boundary
+	}{
+		// Dezelfde soort gaat verder na het ID van de cursor
+		{"audit", &Boundary{Time: at, ID: 7}},
+		// Een soort die bij gelijke tijd later komt heeft op dat moment nog niets getoond
+		{"activity", &Boundary{Time: at, Inclusive: true}},
+		// Een soort die bij gelijke tijd eerder komt is op dat moment al helemaal getoond
+		{"task", &Boundary{Time: at}},
+	}
+	for _, tt := range tests {
+		if got := cursor.BoundaryFor(tt.kind); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("BoundaryFor(%s) = %+v, verwacht %+v", tt.kind, got, tt.want)
+		}
+	}
+
+	var none *Cursor
+	if got := none.BoundaryFor("audit"); got != nil {
+		t.Errorf("BoundaryFor zonder cursor = %+v, verwacht nil", got)
+	}
+}
@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldError is een validatiefout van één veld
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors bundelt validatiefouten per veld. De foutmelding is de melding van alle velden samen, zodat
+// een aanroeper die alleen Error() gebruikt dezelfde tekst krijgt als bij een gewone fout.
+type Errors []FieldError
+
+// Error implementeert error
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fieldError.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// New maakt een validatiefout voor één veld
+func New(field, format string, args ...interface{}) error {
+	return Errors{{Field: field, Message: fmt.Sprintf(format, args...)}}
+}
+
+// Wrap koppelt een gewone fout aan een veld; een fout die al per veld is blijft ongewijzigd
+func Wrap(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := Fields(err); ok {
+		return err
+	}
+	return Errors{{Field: field, Message: err.Error()}}
+}
+
+// Fields geeft de fouten per veld als err (of een fout die err omhult) validatiefouten bevat
+func Fields(err error) ([]FieldError, bool) {
+	var fieldErrors Errors
+	if errors.As(err, &fieldErrors) {
+		return fieldErrors, true
+	}
+	return nil, false
+}
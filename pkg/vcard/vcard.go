@@ -0,0 +1,466 @@
+// Package vcard leest en schrijft vCards (RFC 2426 voor 3.0, RFC 6350 voor 4.0). Versie 2.1, zoals
+// oudere telefoons die exporteren, kan ook gelezen worden.
+package vcard
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ondersteunde versies bij het schrijven
+const (
+	Version3 = "3.0"
+	Version4 = "4.0"
+)
+
+// maxLineLength is de maximale lengte van een regel in octets, zonder CRLF (RFC 6350, 3.2)
+const maxLineLength = 75
+
+// Componenten van de gestructureerde waarde van ADR
+const (
+	AdrPOBox = iota
+	AdrExtended
+	AdrStreet
+	AdrLocality
+	AdrRegion
+	AdrPostalCode
+	AdrCountry
+)
+
+// Field is één eigenschap van een vCard, zoals EMAIL;TYPE=work:info@example.nl. Value is de waarde
+// zoals die in het bestand staat, met escapes; gebruik Text of Components om hem te lezen.
+type Field struct {
+	Group  string
+	Name   string
+	Params map[string][]string
+	Value  string
+}
+
+// Card is één vCard met de eigenschappen in de volgorde van het bestand. VERSION, BEGIN en END
+// zijn geen eigenschap; Line is de regel van BEGIN:VCARD bij het lezen.
+type Card struct {
+	Version string
+	Line    int
+	Fields  []Field
+}
+
+// Add voegt een eigenschap toe; params zijn paren van naam en waarde, bijv. "TYPE", "work"
+func (c *Card) Add(name, value string, params ...string) {
+	field := Field{Name: strings.ToUpper(name), Value: value}
+	for i := 0; i+1 < len(params); i += 2 {
+		if field.Params == nil {
+			field.Params = map[string][]string{}
+		}
+		key := strings.ToUpper(params[i])
+		field.Params[key] = append(field.Params[key], params[i+1])
+	}
+	c.Fields = append(c.Fields, field)
+}
+
+// AddText voegt een eigenschap met een tekstwaarde toe, met escapes
+func (c *Card) AddText(name, text string, params ...string) {
+	c.Add(name, Escape(text), params...)
+}
+
+// All geeft alle eigenschappen met deze naam
+func (c *Card) All(name string) []Field {
+	var fields []Field
+	for _, field := range c.Fields {
+		if strings.EqualFold(field.Name, name) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Preferred geeft de voorkeurseigenschap met deze naam: de laagste PREF (4.0), anders TYPE=pref
+// (3.0), anders de eerste. ok is false als de eigenschap niet voorkomt.
+func (c *Card) Preferred(name string) (field Field, ok bool) {
+	fields := c.All(name)
+	if len(fields) == 0 {
+		return Field{}, false
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].preference() < fields[j].preference()
+	})
+	return fields[0], true
+}
+
+// Text geeft de tekst van de voorkeurseigenschap met deze naam, of "" als die ontbreekt
+func (c *Card) Text(name string) string {
+	if field, ok := c.Preferred(name); ok {
+		return field.Text()
+	}
+	return ""
+}
+
+// Param geeft de eerste waarde van een parameter, of ""
+func (f Field) Param(name string) string {
+	if values := f.Params[strings.ToUpper(name)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// HasType geeft aan of de eigenschap het type heeft, bijv. work of pref
+func (f Field) HasType(value string) bool {
+	for _, types := range f.Params["TYPE"] {
+		for _, t := range strings.Split(types, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// preference geeft de voorkeur van een eigenschap; lager gaat voor
+func (f Field) preference() int {
+	if pref := f.Param("PREF"); pref != "" {
+		var n int
+		if _, err := fmt.Sscanf(pref, "%d", &n); err == nil && n >= 1 && n <= 100 {
+			return n
+		}
+	}
+	if f.HasType("pref") {
+		return 1
+	}
+	return 101
+}
+
+// Text geeft de waarde als tekst, zonder escapes
+func (f Field) Text() string {
+	return strings.TrimSpace(Unescape(f.Value))
+}
+
+// Components geeft de delen van een gestructureerde waarde zoals N of ADR, zonder escapes
+func (f Field) Components() []string {
+	parts := splitUnescaped(f.Value, ';')
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(Unescape(part))
+	}
+	return parts
+}
+
+// Escape maakt tekst geschikt als waarde van een eigenschap
+func Escape(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ReplaceAll(text, "\r\n", "\n") {
+		switch r {
+		case '\\', ',', ';':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n', '\r':
+			b.WriteString(`\n`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Structured maakt een gestructureerde waarde van delen tekst, bijv. voor N of ADR
+func Structured(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = Escape(part)
+	}
+	return strings.Join(escaped, ";")
+}
+
+// List maakt een waarde met meerdere teksten, bijv. voor CATEGORIES
+func List(values ...string) string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = Escape(value)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// Unescape haalt de escapes uit een waarde
+func Unescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		if escaped {
+			switch r {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteRune(r)
+			}
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// splitUnescaped splitst een waarde op een scheidingsteken dat niet ge-escaped is
+func splitUnescaped(value string, separator byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case separator:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// Encode schrijft een vCard in de opgegeven versie met CRLF regeleinden; lange regels worden gevouwen
+func Encode(w io.Writer, card *Card, version string) error {
+	if version != Version3 && version != Version4 {
+		return fmt.Errorf("onbekende vCard versie '%s', gebruik %s of %s", version, Version3, Version4)
+	}
+
+	var b bytes.Buffer
+	writeLine(&b, "BEGIN:VCARD")
+	writeLine(&b, "VERSION:"+version)
+	for _, field := range card.Fields {
+		var line strings.Builder
+		if field.Group != "" {
+			line.WriteString(field.Group + ".")
+		}
+		line.WriteString(field.Name)
+
+		keys := make([]string, 0, len(field.Params))
+		for key := range field.Params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, value := range field.Params[key] {
+				line.WriteString(";" + key + "=" + quoteParam(value))
+			}
+		}
+
+		line.WriteString(":" + field.Value)
+		writeLine(&b, line.String())
+	}
+	writeLine(&b, "END:VCARD")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// quoteParam zet een parameterwaarde tussen aanhalingstekens als die : of ; bevat
+func quoteParam(value string) string {
+	value = strings.ReplaceAll(value, `"`, "'")
+	if strings.ContainsAny(value, ":;") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// writeLine schrijft een regel en vouwt hem na maxLineLength octets, zonder een teken te splitsen
+func writeLine(b *bytes.Buffer, line string) {
+	length := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if length+size > maxLineLength {
+			b.WriteString("\r\n ")
+			length = 1
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	b.WriteString("\r\n")
+}
+
+// line is een ontvouwen regel met het nummer van de eerste fysieke regel
+type line struct {
+	number int
+	text   string
+}
+
+// Decode leest alle vCards uit een bestand
+func Decode(r io.Reader) ([]Card, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		cards   []Card
+		current *Card
+	)
+	for _, l := range lines {
+		if strings.TrimSpace(l.text) == "" {
+			continue
+		}
+
+		field, err := parseLine(l.text)
+		if err != nil {
+			return nil, fmt.Errorf("regel %d: %v", l.number, err)
+		}
+
+		switch {
+		case field.Name == "BEGIN" && strings.EqualFold(field.Value, "VCARD"):
+			if current != nil {
+				return nil, fmt.Errorf("regel %d: BEGIN:VCARD binnen een andere vCard", l.number)
+			}
+			current = &Card{Line: l.number}
+		case field.Name == "END" && strings.EqualFold(field.Value, "VCARD"):
+			if current == nil {
+				return nil, fmt.Errorf("regel %d: END:VCARD zonder BEGIN:VCARD", l.number)
+			}
+			cards = append(cards, *current)
+			current = nil
+		case current == nil:
+			return nil, fmt.Errorf("regel %d: eigenschap %s buiten een vCard", l.number, field.Name)
+		case field.Name == "VERSION":
+			current.Version = strings.TrimSpace(field.Value)
+		default:
+			current.Fields = append(current.Fields, field)
+		}
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("regel %d: vCard zonder END:VCARD", current.Line)
+	}
+	if len(cards) == 0 {
+		return nil, errors.New("bestand bevat geen vCards")
+	}
+	return cards, nil
+}
+
+// unfold leest de regels en voegt gevouwen regels samen: een regel die met een spatie of tab begint
+// hoort bij de vorige, en bij quoted-printable (vCard 2.1) loopt een regel die op = eindigt door
+func unfold(r io.Reader) ([]line, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []line
+	number := 0
+	softBreak := false
+	for scanner.Scan() {
+		number++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if number == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if !utf8.ValidString(text) {
+			return nil, fmt.Errorf("regel %d is geen geldige UTF-8", number)
+		}
+
+		switch {
+		case softBreak && len(lines) > 0:
+			last := &lines[len(lines)-1]
+			last.text = strings.TrimSuffix(last.text, "=") + strings.TrimLeft(text, " \t")
+		case (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0:
+			lines[len(lines)-1].text += text[1:]
+		default:
+			lines = append(lines, line{number: number, text: text})
+		}
+
+		last := lines[len(lines)-1].text
+		softBreak = strings.HasSuffix(last, "=") && strings.Contains(strings.ToUpper(last[:strings.Index(last+":", ":")]), "QUOTED-PRINTABLE")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// parseLine leest een ontvouwen regel: [groep.]NAAM[;PARAM=waarde...]:waarde
+func parseLine(text string) (Field, error) {
+	var field Field
+
+	// Zoek de dubbele punt voor de waarde, buiten aanhalingstekens in parameters
+	colon := -1
+	quoted := false
+	for i := 0; i < len(text); i++ {
+		if text[i] == '"' {
+			quoted = !quoted
+		} else if text[i] == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return field, errors.New("ongeldige regel, verwacht NAAM:waarde")
+	}
+
+	head := text[:colon]
+	field.Value = text[colon+1:]
+
+	parts := splitParams(head)
+	name := parts[0]
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		field.Group = name[:dot]
+		name = name[dot+1:]
+	}
+	field.Name = strings.ToUpper(strings.TrimSpace(name))
+	if field.Name == "" {
+		return field, errors.New("eigenschap zonder naam")
+	}
+
+	for _, param := range parts[1:] {
+		if field.Params == nil {
+			field.Params = map[string][]string{}
+		}
+		key, value, found := strings.Cut(param, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if !found {
+			// vCard 2.1 schrijft types zonder TYPE=, bijv. TEL;WORK;VOICE
+			field.Params["TYPE"] = append(field.Params["TYPE"], strings.ToLower(key))
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if key == "TYPE" {
+			value = strings.ToLower(value)
+		}
+		field.Params[key] = append(field.Params[key], value)
+	}
+
+	if strings.EqualFold(field.Param("ENCODING"), "QUOTED-PRINTABLE") {
+		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(field.Value)))
+		if err != nil {
+			return field, fmt.Errorf("ongeldige quoted-printable waarde bij %s", field.Name)
+		}
+		// Een echte regelovergang in de tekst wordt als escape bewaard, zoals in 3.0
+		field.Value = strings.ReplaceAll(strings.ReplaceAll(string(decoded), "\r\n", "\n"), "\n", `\n`)
+		delete(field.Params, "ENCODING")
+	}
+
+	return field, nil
+}
+
+// splitParams splitst de naam en parameters op ; buiten aanhalingstekens
+func splitParams(head string) []string {
+	var parts []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(head); i++ {
+		switch head[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				parts = append(parts, head[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, head[start:])
+}
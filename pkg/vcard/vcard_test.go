@@ -0,0 +1,182 @@
+package vcard
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	long := strings.Repeat("Bakkerij Jansen & Zonen ", 6) + "één café"
+
+	card := &Card{}
+	card.AddText("FN", long)
+	card.Add("ORG", Structured("Jansen; Zonen", "Verkoop"))
+	card.AddText("EMAIL", "info@jansen.nl", "TYPE", "work")
+	card.AddText("TEL", "+31 20 123 4567", "TYPE", "work,voice")
+	card.Add("ADR", Structured("", "", "Dorpsstraat 1, 1234 AB Amsterdam", "", "", "", ""), "TYPE", "work")
+	card.Add("CATEGORIES", List("klant", "bouw, infra"))
+	card.AddText("NOTE", "regel 1\nregel 2\\nog steeds 2")
+	card.AddText("X-KVK-NUMBER", "12345678")
+	card.Add("item1.URL", "https://jansen.nl", "X-LABEL", "site: hoofd;kantoor")
+
+	for _, version := range []string{Version3, Version4} {
+		t.Run(version, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, card, version); err != nil {
+				t.Fatalf("Encode gaf fout: %v", err)
+			}
+
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+				if len(line) > maxLineLength {
+					t.Errorf("regel van %d octets: %q", len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("regel is in een teken gevouwen: %q", line)
+				}
+			}
+
+			cards, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode gaf fout: %v", err)
+			}
+			if len(cards) != 1 {
+				t.Fatalf("%d vCards gelezen, verwacht 1", len(cards))
+			}
+			decoded := cards[0]
+
+			if decoded.Version != version || decoded.Line != 1 {
+				t.Errorf("versie %s op regel %d, verwacht %s op regel 1", decoded.Version, decoded.Line, version)
+			}
+			if decoded.Text("FN") != long {
+				t.Errorf("FN = %q, verwacht %q", decoded.Text("FN"), long)
+			}
+			if org, _ := decoded.Preferred("ORG"); !reflect.DeepEqual(org.Components(), []string{"Jansen; Zonen", "Verkoop"}) {
+				t.Errorf("ORG = %q", org.Components())
+			}
+			if categories, _ := decoded.Preferred("CATEGORIES"); !reflect.DeepEqual(splitUnescaped(categories.Value, ','), []string{"klant", `bouw\, infra`}) {
+				t.Errorf("CATEGORIES = %q", categories.Value)
+			}
+			if note := decoded.Text("NOTE"); note != "regel 1\nregel 2\\nog steeds 2" {
+				t.Errorf("NOTE = %q", note)
+			}
+			if tel, _ := decoded.Preferred("TEL"); !tel.HasType("voice") || tel.Text() != "+31 20 123 4567" {
+				t.Errorf("TEL = %+v", tel)
+			}
+			if adr, _ := decoded.Preferred("ADR"); adr.Components()[AdrStreet] != "Dorpsstraat 1, 1234 AB Amsterdam" {
+				t.Errorf("ADR = %q", adr.Components())
+			}
+			if url, _ := decoded.Preferred("URL"); !strings.EqualFold(url.Group, "item1") || url.Param("X-LABEL") != "site: hoofd;kantoor" || url.Text() != "https://jansen.nl" {
+				t.Errorf("URL = %+v", url)
+			}
+			if len(decoded.Fields) != len(card.Fields) {
+				t.Errorf("%d eigenschappen gelezen, verwacht %d", len(decoded.Fields), len(card.Fields))
+			}
+		})
+	}
+}
+
+func TestEncodeUnknownVersion(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, &Card{}, "2.1"); err == nil {
+		t.Error("Encode in versie 2.1 gaf geen fout")
+	}
+}
+
+func TestDecodeVersion21(t *testing.T) {
+	input := "\ufeffBEGIN:VCARD\r\n" +
+		"VERSION:2.1\r\n" +
+		"N:Jansen;Piet;;;\r\n" +
+		"TEL;WORK;VOICE:020-1234567\r\n" +
+		"TEL;CELL:06-12345678\r\n" +
+		"TEL;PREF;CELL:06-87654321\r\n" +
+		"ADR;WORK;ENCODING=QUOTED-PRINTABLE;CHARSET=UTF-8:;;Caf=C3=A9straat 1=0D=0A=\r\n" +
+		"achter;Amsterdam;;1234 AB;;\r\n" +
+		"END:VCARD\r\n"
+
+	cards, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode gaf fout: %v", err)
+	}
+	card := cards[0]
+
+	if card.Version != "2.1" {
+		t.Errorf("versie = %s", card.Version)
+	}
+	if tel := card.Text("TEL"); tel != "06-87654321" {
+		t.Errorf("voorkeurstelefoon = %s, verwacht 06-87654321", tel)
+	}
+	if tel := card.All("TEL")[0]; !tel.HasType("work") || !tel.HasType("VOICE") {
+		t.Errorf("types = %v", tel.Params["TYPE"])
+	}
+	adr, _ := card.Preferred("ADR")
+	if adr.Param("ENCODING") != "" {
+		t.Error("ENCODING is na het decoderen blijven staan")
+	}
+	if parts := adr.Components(); parts[AdrStreet] != "Caféstraat 1\nachter" || parts[AdrLocality] != "Amsterdam" || parts[AdrPostalCode] != "1234 AB" {
+		t.Errorf("ADR = %q", parts)
+	}
+}
+
+func TestDecodeVersion4Preference(t *testing.T) {
+	input := "BEGIN:VCARD\nVERSION:4.0\nEMAIL;PREF=2:b@x.nl\nEMAIL:c@x.nl\nEMAIL;PREF=1:a@x.nl\nEND:VCARD\n" +
+		"BEGIN:VCARD\nVERSION:3.0\nEMAIL:b@x.nl\nEMAIL;TYPE=work,pref:a@x.nl\nEND:VCARD\n"
+
+	cards, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode gaf fout: %v", err)
+	}
+	if len(cards) != 2 || cards[1].Line != 7 {
+		t.Fatalf("%d vCards, tweede op regel %d", len(cards), cards[len(cards)-1].Line)
+	}
+	for i, card := range cards {
+		if email := card.Text("EMAIL"); email != "a@x.nl" {
+			t.Errorf("vCard %d: voorkeur = %s, verwacht a@x.nl", i+1, email)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"leeg", "\r\n\r\n", "geen vCards"},
+		{"geen END", "BEGIN:VCARD\nFN:x\n", "regel 1"},
+		{"END zonder BEGIN", "END:VCARD\n", "regel 1"},
+		{"geneste vCard", "BEGIN:VCARD\nBEGIN:VCARD\n", "regel 2"},
+		{"eigenschap buiten vCard", "FN:x\n", "regel 1"},
+		{"regel zonder waarde", "BEGIN:VCARD\nFN\nEND:VCARD\n", "regel 2"},
+		{"geen UTF-8", "BEGIN:VCARD\nFN:caf\xe9\nEND:VCARD\n", "UTF-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Decode gaf %v, verwacht een fout met %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		text    string
+		escaped string
+	}{
+		{"gewoon", "gewoon"},
+		{"a,b;c\\d", `a\,b\;c\\d`},
+		{"regel 1\r\nregel 2\nregel 3", `regel 1\nregel 2\nregel 3`},
+	}
+	for _, tt := range tests {
+		if got := Escape(tt.text); got != tt.escaped {
+			t.Errorf("Escape(%q) = %q, verwacht %q", tt.text, got, tt.escaped)
+		}
+		if got := Unescape(tt.escaped); got != strings.ReplaceAll(tt.text, "\r\n", "\n") {
+			t.Errorf("Unescape(%q) = %q", tt.escaped, got)
+		}
+	}
+}